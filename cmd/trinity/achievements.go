@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/natsbus"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// rconAchievementNotifier tells a player in-game when they earn a
+// badge, and a match's players when one of them breaks a map record.
+// Satisfies hub.AchievementNotifier and hub.RecordNotifier. The writer is built before
+// the local manager and the NATS RCON client exist, so those are
+// attached afterwards; announcements before attach are dropped.
+type rconAchievementNotifier struct {
+	ctx   context.Context
+	store *storage.Store
+
+	mu          sync.Mutex
+	localSource string
+	manager     *collector.ServerManager
+	rconClient  *natsbus.RconClient
+}
+
+func newRconAchievementNotifier(ctx context.Context, store *storage.Store) *rconAchievementNotifier {
+	return &rconAchievementNotifier{ctx: ctx, store: store}
+}
+
+// attach supplies the transports: manager for servers on localSource
+// (nil/empty when there is no in-process collector), rconClient for
+// everything else (nil without NATS).
+func (n *rconAchievementNotifier) attach(localSource string, manager *collector.ServerManager, rconClient *natsbus.RconClient) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.localSource = localSource
+	n.manager = manager
+	n.rconClient = rconClient
+}
+
+func (n *rconAchievementNotifier) NotifyAchievement(serverID int64, clientID int, playerID int64, a domain.Achievement) {
+	n.announce(serverID, []int{clientID}, "^3Achievement unlocked: ^7"+a.Name+" ^3- ^7"+a.Description,
+		func(err error) { log.Printf("achievements: announce %s to player %d: %v", a.ID, playerID, err) })
+}
+
+func (n *rconAchievementNotifier) NotifyRecord(serverID int64, clientIDs []int, message string) {
+	n.announce(serverID, clientIDs, message,
+		func(err error) { log.Printf("records: announce on server %d: %v", serverID, err) })
+}
+
+// announce prints message to each client on the server, in the
+// background, reporting failures through logErr.
+func (n *rconAchievementNotifier) announce(serverID int64, clientIDs []int, message string, logErr func(error)) {
+	go func() {
+		srv, err := n.store.GetServerByID(n.ctx, serverID)
+		if err != nil || srv == nil {
+			return
+		}
+
+		n.mu.Lock()
+		localSource, manager, rconClient := n.localSource, n.manager, n.rconClient
+		n.mu.Unlock()
+
+		for _, clientID := range clientIDs {
+			cmd := collector.PrintCommand(clientID, message)
+			switch {
+			case localSource != "" && srv.Source == localSource && manager != nil:
+				_, err = manager.ExecuteRconByKey(srv.Key, cmd)
+			case rconClient != nil:
+				ctx, cancel := context.WithTimeout(n.ctx, 10*time.Second)
+				_, err = rconClient.Exec(ctx, srv.Source, natsbus.RconExecRequest{
+					ServerKey: srv.Key,
+					Command:   cmd,
+					Username:  "hub",
+					Role:      natsbus.RconRoleAnnounce,
+				})
+				cancel()
+			default:
+				return
+			}
+			if err != nil {
+				logErr(fmt.Errorf("%s client %d: %w", srv.Key, clientID, err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/ernie/trinity-tracker/internal/api"
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// apiBranding converts the branding block to what the router serves.
+// A nil block yields the zero value (stock look).
+func apiBranding(b *config.BrandingConfig) api.Branding {
+	if b == nil {
+		return api.Branding{}
+	}
+	out := api.Branding{
+		SiteName:       b.SiteName,
+		LogoURL:        b.LogoURL,
+		FooterLinks:    apiBrandingLinks(b.FooterLinks),
+		CommunityLinks: apiBrandingLinks(b.CommunityLinks),
+	}
+	if b.Theme != nil {
+		out.Theme = &api.BrandingTheme{
+			Background: b.Theme.Background,
+			Card:       b.Theme.Card,
+			Text:       b.Theme.Text,
+			Accent:     b.Theme.Accent,
+		}
+	}
+	return out
+}
+
+func apiBrandingLinks(links []config.BrandingLink) []api.BrandingLink {
+	out := make([]api.BrandingLink, 0, len(links))
+	for _, l := range links {
+		out = append(out, api.BrandingLink{Label: l.Label, URL: l.URL})
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/storage"
+	flag "github.com/spf13/pflag"
+)
+
+// minJWTSecretBits is the least entropy check accepts in a JWT secret.
+// The secret `trinity init` generates carries 256 bits; 128 is the
+// floor for an HMAC key anyone could brute-force offline from one
+// issued token.
+const minJWTSecretBits = 128
+
+// cmdCheck is the preflight for a deploy: unlike `status`, which asks
+// whether the running service is healthy, it asks whether this config
+// can run at all, touching everything the service will need — the
+// database, each server's log, UDP port, and rcon password, the static
+// dir, the JWT secret. Nothing it does changes any state.
+//
+// Exits 1 on any failed check, for CI and Ansible.
+func cmdCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to configuration file")
+	colorMode := addColorFlag(fs)
+	fs.Parse(args)
+	applyColorMode(*colorMode)
+
+	failures := 0
+	// Same layout as `status`: pad the label, then color it.
+	pass := func(label, detail string) {
+		fmt.Printf("  %s %s %s\n", green("✓"), bold(fmt.Sprintf("%-12s", label)), detail)
+	}
+	fail := func(label, detail string) {
+		fmt.Printf("  %s %s %s\n", red("✗"), bold(fmt.Sprintf("%-12s", label)), detail)
+		failures++
+	}
+	skip := func(label, detail string) {
+		fmt.Printf("  %s %s %s\n", dim("–"), bold(fmt.Sprintf("%-12s", label)), dim(detail))
+	}
+
+	fmt.Printf("Trinity %s\n\n", version)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fail("Config", fmt.Sprintf("%s: %v", *configPath, err))
+		fmt.Println("\n1 check failed.")
+		os.Exit(1)
+	}
+	pass("Config", fmt.Sprintf("%s is valid, %d game servers", *configPath, len(cfg.Q3Servers)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if cfg.Database != nil && cfg.Database.Path != "" {
+		checkDatabase(ctx, cfg.Database.Path, pass, fail, skip)
+	}
+
+	if dir := cfg.Server.StaticDir; dir == "" {
+		skip("Static dir", "not set; the web UI is served elsewhere")
+	} else if err := checkWritableDir(dir); err != nil {
+		fail("Static dir", err.Error())
+	} else {
+		pass("Static dir", dir+" is writable")
+	}
+
+	if cfg.Auth != nil {
+		bits := secretEntropyBits(cfg.Auth.JWTSecret)
+		switch {
+		case cfg.Auth.JWTSecret == "":
+			fail("JWT secret", "auth.jwt_secret is empty — anyone can forge a login")
+		case bits < minJWTSecretBits:
+			fail("JWT secret", fmt.Sprintf("about %d bits of entropy, want %d — generate one with: openssl rand -base64 32", bits, minJWTSecretBits))
+		default:
+			pass("JWT secret", fmt.Sprintf("about %d bits of entropy", bits))
+		}
+	}
+
+	if len(cfg.Q3Servers) > 0 {
+		fmt.Println()
+		if checkServers(os.Stdout, cfg.Q3Servers) {
+			failures++
+		}
+	}
+
+	fmt.Println()
+	if failures > 0 {
+		fmt.Printf("%s\n", red(fmt.Sprintf("%d check(s) failed.", failures)))
+		os.Exit(1)
+	}
+	fmt.Println(green("All checks passed."))
+}
+
+// checkDatabase opens the database and checks its schema, integrity,
+// and that it takes writes. A database that doesn't exist yet is fine
+// as long as the service could create it.
+func checkDatabase(ctx context.Context, path string, pass, fail, skip func(label, detail string)) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := checkWritableDir(filepath.Dir(path)); err != nil {
+			fail("Database", fmt.Sprintf("%s doesn't exist and can't be created: %v", path, err))
+			return
+		}
+		pass("Database", path+" will be created on first start")
+		skip("Schema", "no database yet")
+		return
+	}
+
+	store, err := storage.New(path)
+	if err != nil {
+		fail("Database", fmt.Sprintf("%s: %v", path, err))
+		return
+	}
+	defer store.Close()
+	if err := store.QuickCheck(ctx); err != nil {
+		fail("Database", fmt.Sprintf("%s: %v", path, err))
+	} else {
+		pass("Database", path+" opens and passes quick_check")
+	}
+
+	if missing, err := store.MissingColumns(ctx); err != nil {
+		fail("Schema", err.Error())
+	} else if len(missing) > 0 {
+		fail("Schema", fmt.Sprintf("missing %s — apply the ALTERs from the release notes", strings.Join(missing, ", ")))
+	} else {
+		pass("Schema", "up to date")
+	}
+
+	if err := store.WriteCheck(ctx); err != nil {
+		fail("DB write", err.Error())
+	} else {
+		pass("DB write", "a test write committed")
+	}
+}
+
+// checkServers renders a row per game server with its log, UDP, and
+// rcon checks, and reports whether any of them failed.
+func checkServers(w io.Writer, servers []config.Q3Server) bool {
+	client := collector.NewQ3Client()
+	ok := func(detail string) string { return green("✓ ") + detail }
+	bad := func(detail string) string { return red("✗ " + detail) }
+	none := func(detail string) string { return dim("– " + detail) }
+
+	nameCol := column{header: "SERVER"}
+	logCol := column{header: "LOG"}
+	udpCol := column{header: "UDP"}
+	rconCol := column{header: "RCON"}
+	failed := false
+
+	for _, srv := range servers {
+		nameCol.cells = append(nameCol.cells, srv.Key)
+
+		switch {
+		case srv.QueryOnly:
+			logCol.cells = append(logCol.cells, none("query only"))
+		case srv.LogPath == "":
+			logCol.cells = append(logCol.cells, none("no log_path"))
+		default:
+			if err := checkReadable(srv.LogPath); err != nil {
+				logCol.cells = append(logCol.cells, bad(err.Error()))
+				failed = true
+			} else {
+				logCol.cells = append(logCol.cells, ok(srv.LogPath))
+			}
+		}
+
+		status, err := client.QueryStatus(srv.Address)
+		if err != nil {
+			udpCol.cells = append(udpCol.cells, bad("no reply from "+srv.Address))
+			failed = true
+		} else {
+			udpCol.cells = append(udpCol.cells, ok(fmt.Sprintf("%s on %s", srv.Address, status.Map)))
+		}
+
+		switch {
+		case srv.RconPassword == "":
+			rconCol.cells = append(rconCol.cells, none("no rcon_password"))
+		case err != nil:
+			rconCol.cells = append(rconCol.cells, none("server unreachable"))
+		default:
+			if problem := checkRcon(client, srv); problem != "" {
+				rconCol.cells = append(rconCol.cells, bad(problem))
+				failed = true
+			} else {
+				rconCol.cells = append(rconCol.cells, ok("password accepted"))
+			}
+		}
+	}
+
+	renderTable(w, []column{nameCol, logCol, udpCol, rconCol})
+	return failed
+}
+
+// checkRcon sends srv a read-only command and returns what's wrong
+// with the reply, if anything.
+func checkRcon(client *collector.Q3Client, srv config.Q3Server) string {
+	reply, err := client.RconCommand(srv.Address, srv.RconPassword, "status")
+	switch {
+	case err != nil:
+		return err.Error()
+	case strings.HasPrefix(reply, "Bad rconpassword"):
+		return "password rejected"
+	case strings.HasPrefix(reply, "No rconpassword set"):
+		return "server has no rconpassword set"
+	case strings.TrimSpace(reply) == "":
+		return "no reply"
+	}
+	return ""
+}
+
+// checkReadable opens path and reads a byte, which is what tailing it
+// will need.
+func checkReadable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Read(make([]byte, 1)); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// checkWritableDir creates and removes a file in dir.
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".trinity-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// secretEntropyBits estimates the entropy of secret from its own
+// character frequencies. It's the Shannon entropy of the string, so
+// it can't see that "password1234..." is guessable, but it does catch
+// short, repetitive, and placeholder secrets.
+func secretEntropyBits(secret string) int {
+	if secret == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range secret {
+		counts[r]++
+		n++
+	}
+	perChar := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		perChar -= p * math.Log2(p)
+	}
+	return int(perChar * float64(n))
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ernie/trinity-tracker/cmd/trinity/setup"
+)
+
+func TestSecretEntropyBits(t *testing.T) {
+	if got := secretEntropyBits(""); got != 0 {
+		t.Errorf("empty secret: got %d bits, want 0", got)
+	}
+	if got := secretEntropyBits("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"); got != 0 {
+		t.Errorf("repeated character: got %d bits, want 0", got)
+	}
+	if got := secretEntropyBits("changeme"); got >= minJWTSecretBits {
+		t.Errorf("placeholder: got %d bits, want under %d", got, minJWTSecretBits)
+	}
+	for i := 0; i < 20; i++ {
+		if got := secretEntropyBits(setup.GenerateJWTSecret()); got < minJWTSecretBits {
+			t.Fatalf("generated secret: got %d bits, want at least %d", got, minJWTSecretBits)
+		}
+	}
+}
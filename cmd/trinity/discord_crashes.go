@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// crashEmbedLines is how many of the crashed server's last log lines
+// the alert quotes; the full tail is kept on the hub.
+const crashEmbedLines = 10
+
+// discordCrashNotifier posts to the digest webhook when the collector
+// sees a game server crash. Satisfies collector.CrashNotifier.
+type discordCrashNotifier struct {
+	ctx        context.Context
+	webhookURL string
+}
+
+func (n *discordCrashNotifier) NotifyCrash(serverKey string, crash domain.ServerCrashData) {
+	go func() {
+		ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+		defer cancel()
+		if err := postWebhook(ctx, n.webhookURL, renderCrashEmbed(serverKey, crash)); err != nil {
+			log.Printf("discord: crash alert for %s: %v", serverKey, err)
+		}
+	}()
+}
+
+// renderCrashEmbed builds the crash alert: why the collector thinks
+// the server crashed, when, and the last few log lines.
+func renderCrashEmbed(serverKey string, crash domain.ServerCrashData) discordEmbed {
+	var desc strings.Builder
+	switch crash.Reason {
+	case domain.CrashUnitFailed:
+		fmt.Fprintf(&desc, "systemd gave up on `quake3-server@%s` (%s).", serverKey, crash.Detail)
+	default:
+		desc.WriteString("The server restarted without logging a clean shutdown.")
+	}
+	fmt.Fprintf(&desc, " Its last log line was at <t:%d:f>.", crash.CrashedAt.Unix())
+	lines := crash.LogLines
+	if len(lines) > crashEmbedLines {
+		lines = lines[len(lines)-crashEmbedLines:]
+	}
+	if len(lines) > 0 {
+		tail := strings.ReplaceAll(strings.Join(lines, "\n"), "```", "'''")
+		if len(tail) > 3500 {
+			tail = tail[len(tail)-3500:]
+		}
+		fmt.Fprintf(&desc, "\n```\n%s\n```", tail)
+	}
+	return discordEmbed{
+		Title:       fmt.Sprintf("💥 %s crashed", serverKey),
+		Description: desc.String(),
+		Color:       0xE05D44,
+	}
+}
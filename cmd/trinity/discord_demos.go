@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/demos"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// demoEmbedTopN is how many players the demo announcement lists.
+const demoEmbedTopN = 3
+
+// discordDemoNotifier posts a match summary with a download link to
+// the digest webhook when the harvester attaches a server-side demo to
+// a finished match. Satisfies demos.Notifier.
+type discordDemoNotifier struct {
+	ctx        context.Context
+	webhookURL string
+	publicBase string
+	store      *storage.Store
+}
+
+func newDiscordDemoNotifier(ctx context.Context, cfg *config.Config, store *storage.Store) *discordDemoNotifier {
+	publicBase := ""
+	if cfg.Tracker != nil && cfg.Tracker.Collector != nil {
+		publicBase = strings.TrimSuffix(cfg.Tracker.Collector.PublicURL, "/")
+	}
+	return &discordDemoNotifier{
+		ctx:        ctx,
+		webhookURL: cfg.Discord.WebhookURL,
+		publicBase: publicBase,
+		store:      store,
+	}
+}
+
+func (n *discordDemoNotifier) NotifyHarvest(matchID int64, demo *domain.MatchDemo) {
+	go func() {
+		match, err := n.store.GetMatchSummaryByID(n.ctx, matchID)
+		if err != nil {
+			log.Printf("discord: announce demo %d: loading match %d: %v", demo.ID, matchID, err)
+			return
+		}
+		embed := renderDemoEmbed(match, demo, n.publicBase)
+		ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+		defer cancel()
+		if err := postWebhook(ctx, n.webhookURL, embed); err != nil {
+			log.Printf("discord: announce demo %d: %v", demo.ID, err)
+		}
+	}()
+}
+
+// renderDemoEmbed builds the "demo is up" card: map, mode and server in
+// the title (linked to the match page when publicBase is known), the
+// final score and length, the top players, and where to get the demo.
+func renderDemoEmbed(m *domain.MatchSummary, demo *domain.MatchDemo, publicBase string) discordEmbed {
+	embed := discordEmbed{
+		Title: fmt.Sprintf("🎬 %s · %s on %s", m.MapName, strings.ToUpper(m.GameType), m.ServerKey),
+		Color: trinityEmbedColor,
+	}
+	if publicBase != "" {
+		embed.URL = fmt.Sprintf("%s/matches/%d", publicBase, m.ID)
+	}
+
+	var desc []string
+	if m.RedScore != nil && m.BlueScore != nil {
+		desc = append(desc, fmt.Sprintf("🔴 **%d** – **%d** 🔵", *m.RedScore, *m.BlueScore))
+	}
+	if m.EndedAt != nil {
+		desc = append(desc, "Played "+m.StartedAt.UTC().Format("2006-01-02 15:04")+" UTC for "+
+			m.EndedAt.Sub(m.StartedAt).Round(time.Minute).String())
+	}
+	embed.Description = strings.Join(desc, "\n")
+
+	players := make([]domain.MatchPlayerSummary, 0, len(m.Players))
+	for _, p := range m.Players {
+		if !p.IsBot {
+			players = append(players, p)
+		}
+	}
+	sort.SliceStable(players, func(i, j int) bool { return players[i].Frags > players[j].Frags })
+	if len(players) > demoEmbedTopN {
+		players = players[:demoEmbedTopN]
+	}
+	if len(players) > 0 {
+		lines := make([]string, len(players))
+		for i, p := range players {
+			lines[i] = fmt.Sprintf("%d. %s — %d frags", i+1, stripVRPrefix(p.CleanName), p.Frags)
+		}
+		embed.Fields = append(embed.Fields, discordField{Name: "Top players", Value: strings.Join(lines, "\n")})
+	}
+
+	size := fmt.Sprintf("%.1f MB", float64(demo.SizeBytes)/(1<<20))
+	if publicBase != "" {
+		embed.Fields = append(embed.Fields, discordField{
+			Name:  "Demo",
+			Value: fmt.Sprintf("[Download %s](%s%s) (%s)", demo.Filename, publicBase, demos.URL(demo.ID), size),
+		})
+	} else {
+		embed.Footer = &discordFooter{Text: fmt.Sprintf("Demo %s (%s) is on the match page", demo.Filename, size)}
+	}
+	return embed
+}
@@ -79,11 +79,13 @@ type digestCategory struct {
 	Format   func(domain.LeaderboardEntry) string
 }
 
-// fmtInt and fmtKD avoid pulling fmt into the closure's capture set
+// fmtInt, fmtKD, fmtPct and fmtHours avoid pulling fmt into the closure's capture set
 // at every callsite; same effect as inlining but the registry stays
 // readable.
-func fmtInt(n int64) string  { return fmt.Sprintf("%d", n) }
-func fmtKD(r float64) string { return fmt.Sprintf("%.2f", r) }
+func fmtInt(n int64) string      { return fmt.Sprintf("%d", n) }
+func fmtKD(r float64) string     { return fmt.Sprintf("%.2f", r) }
+func fmtPct(r float64) string    { return fmt.Sprintf("%.1f%%", r*100) }
+func fmtHours(secs int64) string { return fmt.Sprintf("%.1fh", float64(secs)/3600) }
 
 var digestCategoryRegistry = map[string]digestCategory{
 	"frags":        {Title: "🔥 Frags", CLILabel: "FRAGS", Headline: "most frags", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.TotalFrags) }},
@@ -98,6 +100,11 @@ var digestCategoryRegistry = map[string]digestCategory{
 	"impressives":  {Title: "⚡ Impressives", CLILabel: "IMPRESSIVES", Headline: "most impressives", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.Impressives) }},
 	"excellents":   {Title: "💎 Excellents", CLILabel: "EXCELLENTS", Headline: "most excellents", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.Excellents) }},
 	"humiliations": {Title: "😂 Humiliations", CLILabel: "HUMILIATIONS", Headline: "most humiliations", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.Humiliations) }},
+	"sprees":       {Title: "🔪 Best Spree", CLILabel: "SPREE", Headline: "longest killing spree", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.BestSpree) }},
+	"skulls":       {Title: "💀 Skulls", CLILabel: "SKULLS", Headline: "most skulls scored", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.SkullsScored) }},
+	"obelisks":     {Title: "🗿 Obelisks", CLILabel: "OBELISKS", Headline: "most obelisks destroyed", Format: func(e domain.LeaderboardEntry) string { return fmtInt(e.ObeliskDestroys) }},
+	"playtime":     {Title: "⏱️ Playtime", CLILabel: "PLAYTIME", Headline: "most time played", Format: func(e domain.LeaderboardEntry) string { return fmtHours(e.PlaytimeSeconds) }},
+	"accuracy":     {Title: "🎯 Accuracy", CLILabel: "ACCURACY", Headline: "best accuracy", Format: func(e domain.LeaderboardEntry) string { return fmtPct(e.Accuracy) }},
 }
 
 // defaultDigestCategories is the order / selection used when
@@ -250,6 +257,31 @@ func renderDigestEmbed(results map[string]*domain.LeaderboardResponse, categorie
 	return embed
 }
 
+// returningFieldTitle is the heading of the digest's comeback section.
+const returningFieldTitle = "👋 Welcome Back"
+
+// renderReturningField produces the full-width field listing players
+// who came back after a long absence during the digest period. Names
+// link to the player's profile when publicBase is set. Callers skip
+// the field entirely when nobody returned — unlike the leaderboard
+// fields, an empty "welcome back" row carries no information.
+func renderReturningField(players []domain.ReturningPlayer, topN int, publicBase string) discordField {
+	n := topN
+	if n > len(players) {
+		n = len(players)
+	}
+	base := strings.TrimSuffix(publicBase, "/")
+	lines := make([]string, 0, n)
+	for _, rp := range players[:n] {
+		name := stripVRPrefix(rp.Player.CleanName)
+		if base != "" && rp.Player.ID != 0 {
+			name = fmt.Sprintf("[%s](%s/players/%d)", name, base, rp.Player.ID)
+		}
+		lines = append(lines, fmt.Sprintf("%s · back after %s", name, domain.HumanizeDaysAway(rp.DaysAway)))
+	}
+	return discordField{Name: returningFieldTitle, Value: strings.Join(lines, "\n")}
+}
+
 // blankInlineField returns the empty zero-width-space inline field
 // used to pad rows in the 2-per-row layout. Pulled into a helper so
 // the layout logic reads cleanly and the ZWS character has one
@@ -388,6 +388,96 @@ func TestStripVRPrefix(t *testing.T) {
 	}
 }
 
+func mkReturning(id int64, name string, days int) domain.ReturningPlayer {
+	return domain.ReturningPlayer{
+		Player:     domain.Player{ID: id, Name: name, CleanName: stripCarets(name)},
+		ReturnedAt: time.Date(2026, 5, 4, 20, 0, 0, 0, time.UTC),
+		LastSeenAt: time.Date(2026, 5, 4, 20, 0, 0, 0, time.UTC).Add(-time.Duration(days) * 24 * time.Hour),
+		DaysAway:   days,
+	}
+}
+
+// The comeback section is one full-width field: one line per player,
+// linked to their profile, truncated at topN.
+func TestRenderReturningField(t *testing.T) {
+	players := []domain.ReturningPlayer{
+		mkReturning(7, "^1ernie", 95),
+		mkReturning(8, "[VR] rocketeer", 40),
+		mkReturning(9, "straggler", 31),
+	}
+	f := renderReturningField(players, 2, "https://trinity.example.com/")
+	if f.Name != returningFieldTitle {
+		t.Errorf("name: got %q", f.Name)
+	}
+	if f.Inline {
+		t.Error("returning field should span the full embed width")
+	}
+	lines := strings.Split(f.Value, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines (topN), got %d: %q", len(lines), f.Value)
+	}
+	if want := "[ernie](https://trinity.example.com/players/7) · back after 3 months"; lines[0] != want {
+		t.Errorf("line 0: got %q, want %q", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[1], "[rocketeer]") {
+		t.Errorf("line 1 should strip the VR prefix: %q", lines[1])
+	}
+}
+
+func TestRenderReturnEmbed(t *testing.T) {
+	embed := renderReturnEmbed(mkReturning(7, "^1ernie", 95), "ffa", "https://trinity.example.com")
+	if embed.Title != "👋 ernie is back after 3 months!" {
+		t.Errorf("title: got %q", embed.Title)
+	}
+	if embed.URL != "https://trinity.example.com/players/7" {
+		t.Errorf("url: got %q", embed.URL)
+	}
+	if !strings.Contains(embed.Description, "**ffa**") || !strings.Contains(embed.Description, "2026-01-29") {
+		t.Errorf("description: got %q", embed.Description)
+	}
+}
+
+func TestRenderDemoEmbed(t *testing.T) {
+	started := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	ended := started.Add(14*time.Minute + 40*time.Second)
+	red, blue := 8, 5
+	m := &domain.MatchSummary{
+		ID: 42, ServerKey: "ctf", MapName: "q3wctf1", GameType: "ctf",
+		StartedAt: started, EndedAt: &ended, RedScore: &red, BlueScore: &blue,
+		Players: []domain.MatchPlayerSummary{
+			{CleanName: "slow", Frags: 3},
+			{CleanName: "[VR] ernie", Frags: 30},
+			{CleanName: "Sarge", Frags: 50, IsBot: true},
+			{CleanName: "mid", Frags: 12},
+			{CleanName: "low", Frags: 1},
+		},
+	}
+	demo := &domain.MatchDemo{ID: 9, Filename: "m-42.dm_68", SizeBytes: 3 << 20}
+
+	embed := renderDemoEmbed(m, demo, "https://trinity.example.com")
+	if embed.Title != "🎬 q3wctf1 · CTF on ctf" || embed.URL != "https://trinity.example.com/matches/42" {
+		t.Errorf("title: %q %q", embed.Title, embed.URL)
+	}
+	if !strings.Contains(embed.Description, "**8** – **5**") || !strings.Contains(embed.Description, "15m0s") {
+		t.Errorf("description: %q", embed.Description)
+	}
+	if len(embed.Fields) != 2 {
+		t.Fatalf("fields: %+v", embed.Fields)
+	}
+	if want := "1. ernie — 30 frags\n2. mid — 12 frags\n3. slow — 3 frags"; embed.Fields[0].Value != want {
+		t.Errorf("top players: got %q, want %q", embed.Fields[0].Value, want)
+	}
+	if !strings.Contains(embed.Fields[1].Value, "(https://trinity.example.com/api/demos/9.dm_68) (3.0 MB)") {
+		t.Errorf("demo field: %q", embed.Fields[1].Value)
+	}
+
+	// Without a public URL there's nothing to link to.
+	embed = renderDemoEmbed(m, demo, "")
+	if embed.URL != "" || len(embed.Fields) != 1 || embed.Footer == nil {
+		t.Errorf("no public URL: %+v", embed)
+	}
+}
+
 func TestPostWebhook_Success(t *testing.T) {
 	var got struct {
 		Embeds []discordEmbed `json:"embeds"`
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// discordReturnNotifier posts a one-line embed to the digest webhook
+// whenever the hub greets a player back after a long absence.
+// Satisfies hub.ReturnNotifier. Posting happens on its own goroutine
+// so a slow Discord never stalls the greet RPC.
+type discordReturnNotifier struct {
+	ctx        context.Context
+	webhookURL string
+	publicBase string
+	store      *storage.Store
+}
+
+func newDiscordReturnNotifier(ctx context.Context, cfg *config.Config, store *storage.Store) *discordReturnNotifier {
+	publicBase := ""
+	if cfg.Tracker != nil && cfg.Tracker.Collector != nil {
+		publicBase = strings.TrimSuffix(cfg.Tracker.Collector.PublicURL, "/")
+	}
+	return &discordReturnNotifier{
+		ctx:        ctx,
+		webhookURL: cfg.Discord.WebhookURL,
+		publicBase: publicBase,
+		store:      store,
+	}
+}
+
+func (n *discordReturnNotifier) NotifyReturn(serverID int64, rp domain.ReturningPlayer) {
+	go func() {
+		serverKey := ""
+		if srv, err := n.store.GetServerByID(n.ctx, serverID); err == nil && srv != nil {
+			serverKey = srv.Key
+		}
+		embed := renderReturnEmbed(rp, serverKey, n.publicBase)
+		ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+		defer cancel()
+		if err := postWebhook(ctx, n.webhookURL, embed); err != nil {
+			log.Printf("discord: announce return of player %d: %v", rp.Player.ID, err)
+		}
+	}()
+}
+
+// renderReturnEmbed builds the "X is back after 3 months!" embed.
+// The title links to the player's profile when publicBase is known;
+// the description says where they turned up and when they were last
+// around.
+func renderReturnEmbed(rp domain.ReturningPlayer, serverKey, publicBase string) discordEmbed {
+	embed := discordEmbed{
+		Title: fmt.Sprintf("👋 %s is back after %s!",
+			stripVRPrefix(rp.Player.CleanName), domain.HumanizeDaysAway(rp.DaysAway)),
+		Color: trinityEmbedColor,
+	}
+	if publicBase != "" && rp.Player.ID != 0 {
+		embed.URL = fmt.Sprintf("%s/players/%d", publicBase, rp.Player.ID)
+	}
+	desc := "Last seen " + rp.LastSeenAt.UTC().Format("2006-01-02")
+	if serverKey != "" {
+		desc += " · now playing on **" + serverKey + "**"
+	}
+	embed.Description = desc
+	return embed
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// discordStallNotifier posts to the digest webhook when the collector's
+// watchdog gives up on a stalled log tailer. Satisfies
+// collector.StallNotifier.
+type discordStallNotifier struct {
+	ctx        context.Context
+	webhookURL string
+}
+
+func (n *discordStallNotifier) NotifyStall(serverKey, logPath string, silentFor time.Duration, humans int) {
+	go func() {
+		embed := discordEmbed{
+			Title: fmt.Sprintf("⚠️ %s: stats collection is stuck", serverKey),
+			Description: fmt.Sprintf("No new lines in `%s` for %s while %d players are online, and reopening the log didn't help. Matches on this server aren't being recorded.",
+				logPath, silentFor.Round(time.Minute), humans),
+			Color: 0xE76F51,
+		}
+		ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+		defer cancel()
+		if err := postWebhook(ctx, n.webhookURL, embed); err != nil {
+			log.Printf("discord: stall alert for %s: %v", serverKey, err)
+		}
+	}()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// cmdDryRun runs the collector against the configured servers' logs
+// with an in-memory stand-in for the hub: logs are parsed and servers
+// polled exactly as `serve` would, but nothing is written to the
+// database, published to NATS, or sent to the game servers over RCON.
+// Periodically (and on Ctrl-C) it prints what it would have stored.
+func cmdDryRun(args []string) {
+	fs := flag.NewFlagSet("dry-run", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to configuration file")
+	since := fs.String("since", "", "replay log events after this point (RFC3339 or a duration like 24h); default: the whole log")
+	report := fs.Duration("report", time.Minute, "how often to print the running tally")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Q3Servers) == 0 {
+		log.Fatalf("No q3_servers configured in %s", *configPath)
+	}
+
+	// Any non-zero cutoff before the first log line replays the whole
+	// file as live events, which is what a parser check wants.
+	cutoff := time.Unix(0, 0)
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			cutoff = time.Now().Add(-d)
+		} else if ts, err := time.Parse(time.RFC3339, *since); err == nil {
+			cutoff = ts
+		} else {
+			log.Fatalf("invalid --since %q: want RFC3339 or a duration", *since)
+		}
+	}
+
+	backend := collector.NewDryRun()
+	manager := collector.NewServerManager(cfg, backend, backend, backend)
+	manager.SetReadOnly(true)
+	manager.SetReplayCutoff(cutoff)
+	if cfg.Tracker != nil && cfg.Tracker.Collector != nil && cfg.Tracker.Collector.GeoIPDatabase != "" {
+		geo, err := collector.OpenGeoIP(cfg.Tracker.Collector.GeoIPDatabase)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer geo.Close()
+		manager.SetGeoIP(geo)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Drain live events so the manager's channel never backs up; the
+	// counts come from the fact tally.
+	go func() {
+		for range manager.Events() {
+		}
+	}()
+
+	fmt.Printf("Dry run: %d servers, replaying from %s. Nothing will be stored.\n",
+		len(cfg.Q3Servers), cutoff.UTC().Format(time.RFC3339))
+	if err := manager.Start(ctx); err != nil {
+		log.Fatalf("Failed to start collector: %v", err)
+	}
+
+	printDryRunReport(cfg, backend)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*report)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			printDryRunReport(cfg, backend)
+		case <-sigCh:
+			cancel()
+			manager.Stop()
+			printDryRunReport(cfg, backend)
+			return
+		}
+	}
+}
+
+// printDryRunReport polls each configured server once and prints the
+// poll result followed by the backend's fact tally.
+func printDryRunReport(cfg *config.Config, backend *collector.DryRun) {
+	q3 := collector.NewQ3Client()
+	fmt.Printf("\n=== %s ===\n", time.Now().Format("15:04:05"))
+	for _, srv := range cfg.Q3Servers {
+		st, err := q3.QueryStatus(srv.Address)
+		if err != nil {
+			fmt.Printf("poll %s (%s): %v\n", srv.Key, srv.Address, err)
+			continue
+		}
+		fmt.Printf("poll %s (%s): %s, %d humans, %d bots\n", srv.Key, srv.Address, st.Map, st.HumanCount, st.BotCount)
+	}
+	backend.WriteReport(os.Stdout)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	flag "github.com/spf13/pflag"
+)
+
+// cmdExport downloads a CSV or JSON export from the running server's
+// /api/export endpoints into a file.
+func cmdExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to configuration file")
+	baseURLFlag := fs.String("url", "", "base URL of the trinity server")
+	format := fs.String("format", "csv", "output format: csv|json")
+	from := fs.String("from", "", "only matches ending at or after this date (YYYY-MM-DD or RFC3339)")
+	to := fs.String("to", "", "only matches ending before this date (YYYY-MM-DD or RFC3339)")
+	output := fs.StringP("output", "o", "", "file to write (default trinity-<kind>.<format>; - for stdout)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || (fs.Arg(0) != "matches" && fs.Arg(0) != "players") {
+		fmt.Fprintln(os.Stderr, "Usage: trinity export matches|players [--format csv|json] [--from D] [--to D] [-o file]")
+		os.Exit(1)
+	}
+	kind := fs.Arg(0)
+
+	loadCLIConfigFromFlags(*configPath, *baseURLFlag)
+
+	q := url.Values{"format": {*format}}
+	if *from != "" {
+		q.Set("from", *from)
+	}
+	if *to != "" {
+		q.Set("to", *to)
+	}
+	resp, err := http.Get(baseURL + "/api/export/" + kind + "?" + q.Encode())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: request failed: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Error: server returned %d: %s\n", resp.StatusCode, string(body))
+		os.Exit(1)
+	}
+
+	path := *output
+	if path == "" {
+		path = "trinity-" + kind + "." + *format
+	}
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	n, err := io.Copy(out, resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	if path != "-" {
+		fmt.Printf("Wrote %d bytes to %s\n", n, path)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/hub"
+	"github.com/ernie/trinity-tracker/internal/storage"
+	flag "github.com/spf13/pflag"
+)
+
+// cmdImportLogs backfills the hub database from a server's archived
+// logs. The logs go through the collector exactly as a live tail
+// would, and its facts are written straight to the database, so it
+// runs on the hub, alongside `serve` or not. Importing the same logs
+// again changes nothing.
+func cmdImportLogs(args []string) {
+	fs := flag.NewFlagSet("import-logs", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to configuration file")
+	source := fs.String("source", "", "collector source the server belongs to, if more than one has the key")
+	timezone := fs.String("timezone", "", "IANA zone for log timestamps without an offset (default: the server's q3_servers timezone, or this host's)")
+	verbose := fs.Bool("verbose", false, "print the collector and hub log while importing")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: trinity import-logs [--source S] [--timezone Z] [--verbose] <server-key> <log>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	key, paths := fs.Arg(0), fs.Args()[1:]
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database == nil {
+		log.Fatalf("import-logs writes to the hub database; run it on the hub")
+	}
+	store, err := storage.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer store.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	src, srv, err := importTarget(ctx, cfg, store, key, *source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *timezone != "" {
+		if _, err := time.LoadLocation(*timezone); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --timezone: %v\n", err)
+			os.Exit(1)
+		}
+		srv.Timezone = *timezone
+	}
+
+	writer := hub.NewWriter(store)
+	pub := &importPublisher{ctx: ctx, writer: writer, store: store, players: map[string]bool{}}
+	manager := collector.NewServerManager(cfg, writer, writer, pub)
+	go func() {
+		for range manager.Events() {
+		}
+	}()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	start := time.Now()
+	summary, err := manager.ImportLogs(ctx, src, srv, paths, func(path string) {
+		fmt.Printf("Importing %s\n", path)
+	})
+	log.SetOutput(os.Stderr)
+
+	fmt.Println()
+	fmt.Printf("Read %d logs, %d events", summary.Files, summary.Events)
+	if !summary.First.IsZero() {
+		fmt.Printf(" from %s to %s", summary.First.Local().Format("2006-01-02 15:04"), summary.Last.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Printf(" in %s\n", time.Since(start).Round(time.Second))
+	fmt.Printf("  matches stored:     %d\n", pub.stored)
+	fmt.Printf("  already imported:   %d\n", pub.already)
+	fmt.Printf("  players:            %d\n", len(pub.players))
+	fmt.Printf("  joins:              %d\n", pub.joins)
+	if summary.Unstamped > 0 {
+		fmt.Printf("  skipped %d events without a timestamp\n", summary.Unstamped)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// importTarget finds the server to import into: the hub's server with
+// key (of source, if given), or a q3_servers entry of this host's
+// collector the hub hasn't seen yet.
+func importTarget(ctx context.Context, cfg *config.Config, store *storage.Store, key, source string) (string, config.Q3Server, error) {
+	localSource := ""
+	if cfg.Tracker != nil && cfg.Tracker.Collector != nil {
+		localSource = cfg.Tracker.Collector.SourceID
+	}
+	var local *config.Q3Server
+	for i, s := range cfg.Q3Servers {
+		if s.Key == key && localSource != "" && (source == "" || source == localSource) {
+			local = &cfg.Q3Servers[i]
+		}
+	}
+
+	servers, err := store.GetServers(ctx)
+	if err != nil {
+		return "", config.Q3Server{}, err
+	}
+	var found []domain.Server
+	for _, s := range servers {
+		if s.Key == key && (source == "" || s.Source == source) && (local == nil || s.Source != localSource) {
+			found = append(found, s)
+		}
+	}
+	if local != nil {
+		found = append(found, domain.Server{Source: localSource, Key: key, Address: local.Address})
+	}
+	switch len(found) {
+	case 0:
+		return "", config.Q3Server{}, fmt.Errorf("no server %q on this hub", key)
+	case 1:
+	default:
+		var sources []string
+		for _, s := range found {
+			sources = append(sources, s.Source)
+		}
+		return "", config.Q3Server{}, fmt.Errorf("server %q exists for sources %s; pick one with --source", key, strings.Join(sources, ", "))
+	}
+	if local != nil {
+		return localSource, *local, nil
+	}
+	return found[0].Source, config.Q3Server{Key: key, Address: found[0].Address}, nil
+}
+
+// importPublisher writes the importer's facts to the hub as they're
+// published, tallying them for the summary.
+type importPublisher struct {
+	ctx    context.Context
+	writer *hub.Writer
+	store  *storage.Store
+
+	stored, already, joins int
+	players                map[string]bool
+}
+
+func (p *importPublisher) Publish(e domain.FactEvent) error {
+	switch data := e.Data.(type) {
+	case domain.MatchEndData:
+		if m, err := p.store.GetMatchByUUID(p.ctx, data.MatchUUID); err == nil && m != nil {
+			if m.EndedAt != nil {
+				p.already++
+			} else {
+				p.stored++
+			}
+		}
+	case domain.PlayerJoinData:
+		if !data.IsBot {
+			p.joins++
+			p.players[data.GUID] = true
+		}
+	}
+	p.writer.Dispatch(p.ctx, e)
+	return nil
+}
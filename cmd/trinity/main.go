@@ -33,10 +33,13 @@ import (
 	"github.com/ernie/trinity-tracker/internal/collector"
 	"github.com/ernie/trinity-tracker/internal/config"
 	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/export"
 	"github.com/ernie/trinity-tracker/internal/hub"
+	"github.com/ernie/trinity-tracker/internal/demos"
 	"github.com/ernie/trinity-tracker/internal/directory"
 	"github.com/ernie/trinity-tracker/internal/natsbus"
 	"github.com/ernie/trinity-tracker/internal/storage"
+	"github.com/ernie/trinity-tracker/internal/tracing"
 	"github.com/nats-io/nats.go"
 	"github.com/ftrvxmtrx/tga"
 	flag "github.com/spf13/pflag"
@@ -63,16 +66,24 @@ func main() {
 		cmdHelper(os.Args[2:])
 	case "serve":
 		cmdServe(os.Args[2:])
+	case "dry-run":
+		cmdDryRun(os.Args[2:])
+	case "import-logs":
+		cmdImportLogs(os.Args[2:])
 	case "server":
 		cmdServer(os.Args[2:])
 	case "status":
 		cmdStatus(os.Args[2:])
+	case "check":
+		cmdCheck(os.Args[2:])
 	case "players":
 		cmdPlayers(os.Args[2:])
 	case "matches":
 		cmdMatches(os.Args[2:])
 	case "leaderboard":
 		cmdLeaderboard(os.Args[2:])
+	case "export":
+		cmdExport(os.Args[2:])
 	case "discord-digest":
 		cmdDiscordDigest(os.Args[2:])
 	case "user":
@@ -111,14 +122,19 @@ func printUsage() {
 	fmt.Println("  init [--no-systemd] [--dry-run]     Interactive install wizard (collector-only by default)")
 	fmt.Println("  update [--check] [--dry-run]        Update tracker binary, web bundle, engine, and mod from GitHub releases")
 	fmt.Println("  serve                               Start the stats server")
+	fmt.Println("  dry-run [--since T] [--report D]    Parse logs and poll servers without storing anything")
+	fmt.Println("  import-logs <key> <log>...          Backfill a server's history from archived logs")
 	fmt.Println("  server list                         Show configured game servers")
 	fmt.Println("  server add [<key>] [--gametype X] [--port N] [flags]")
 	fmt.Println("                                      Add a game server instance (interactive on a TTY)")
 	fmt.Println("  server remove <key>                 Remove a game server instance")
 	fmt.Println("  status                              Health checks + (hub mode) live game-server status")
+	fmt.Println("  check                               Preflight: config, DB, logs, UDP, RCON, static dir, JWT secret")
 	fmt.Println("  players [--humans]                  Show current players across all servers")
 	fmt.Println("  matches [--recent N]                Show recent matches (default: 20)")
 	fmt.Println("  leaderboard [--top N]               Show top players (default: 20)")
+	fmt.Println("  export matches|players [--format F] [--from D] [--to D] [-o file]")
+	fmt.Println("                                      Download a CSV or JSON export from the server")
 	fmt.Println("  discord-digest [--period P] [--dry-run]")
 	fmt.Println("                                      Post a leaderboard digest to a Discord webhook")
 	fmt.Println("  user add [--admin] [--player-id N] <username>")
@@ -149,6 +165,7 @@ func printUsage() {
 	fmt.Println("  trinity serve --config /etc/trinity/config.yml")
 	fmt.Println("  trinity players --humans")
 	fmt.Println("  trinity matches --recent 50")
+	fmt.Println("  trinity export matches --from 2026-01-01 -o matches.csv")
 	fmt.Println("  trinity user add --admin myuser")
 }
 
@@ -174,6 +191,21 @@ func cmdServe(args []string) {
 	log.Printf("Trinity %s starting...", version)
 	log.Printf("Monitoring %d servers", len(cfg.Q3Servers))
 
+	if cfg.Tracing != nil {
+		shutdown, err := tracing.Setup(context.Background(), cfg.Tracing, version)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		defer func() {
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer flushCancel()
+			if err := shutdown(flushCtx); err != nil {
+				log.Printf("Tracing shutdown error: %v", err)
+			}
+		}()
+		log.Printf("Exporting traces to %s", cfg.Tracing.Endpoint)
+	}
+
 	// Tracker is always non-nil after config.Load (absent block
 	// defaults to hub+local-collector).
 	hasHub := cfg.Tracker.Hub != nil
@@ -186,6 +218,8 @@ func cmdServe(args []string) {
 			log.Fatalf("Failed to initialize database: %v", err)
 		}
 		defer s.Close()
+		s.SetLeaderboardCacheTTL(*cfg.Server.LeaderboardCacheTTL)
+		s.SetLeaderboardRules(leaderboardRules(cfg.Leaderboard))
 		store = s
 		log.Printf("Database initialized at %s", cfg.Database.Path)
 	}
@@ -355,6 +389,44 @@ func cmdServe(args []string) {
 		}))
 	}
 
+	if hasHub && cfg.Discord != nil && cfg.Discord.AnnounceReturns {
+		writerOpts = append(writerOpts, hub.WithReturnNotifier(newDiscordReturnNotifier(ctx, cfg, store)))
+		log.Printf("Announcing returning players to Discord")
+	}
+
+	var achievementNotifier *rconAchievementNotifier
+	if hasHub {
+		achievementNotifier = newRconAchievementNotifier(ctx, store)
+		writerOpts = append(writerOpts, hub.WithAchievementNotifier(achievementNotifier), hub.WithRecordNotifier(achievementNotifier))
+	}
+
+	if hasHub && cfg.Features.ChatPersistenceEnabled() {
+		writerOpts = append(writerOpts, hub.WithChatLog(cfg.Tracker.Hub.ChatRetention.D()))
+		log.Printf("Keeping match chat for %s", cfg.Tracker.Hub.ChatRetention.D())
+	}
+	if hasHub && cfg.Tracker.Hub.HealthPing != nil {
+		hp := cfg.Tracker.Hub.HealthPing
+		writerOpts = append(writerOpts, hub.WithHealthPing(hp.URL, hp.Interval.D(), hp.StaleAfter.D()))
+		log.Printf("Sending health pings every %s while healthy", hp.Interval.D())
+	}
+	if hasHub && cfg.Tracker.Hub.SessionIPs != nil {
+		ips := cfg.Tracker.Hub.SessionIPs
+		if !ips.StoreEnabled() {
+			writerOpts = append(writerOpts, hub.WithSessionIPs(false, 0, nil))
+			log.Printf("Not storing session IPs")
+		} else if ips.Retention > 0 {
+			scrub := hub.TruncateIP
+			switch ips.Scrub {
+			case config.IPScrubHash:
+				scrub = hub.HashIP([]byte(cfg.Auth.JWTSecret))
+			case config.IPScrubDelete:
+				scrub = func(string) string { return "" }
+			}
+			writerOpts = append(writerOpts, hub.WithSessionIPs(true, ips.Retention.D(), scrub))
+			log.Printf("Scrubbing session IPs (%s) after %s", ips.Scrub, ips.Retention.D())
+		}
+	}
+
 	var writer *hub.Writer
 	if hasHub {
 		writer = hub.NewWriter(store, writerOpts...)
@@ -431,6 +503,66 @@ func cmdServe(args []string) {
 		defer dirSrv.Stop()
 	}
 
+	// Optional scheduled CSV exports to S3-compatible storage. Off by
+	// default; opt in via tracker.hub.export.enabled.
+	if hasHub && cfg.Tracker.Hub.Export != nil && cfg.Tracker.Hub.Export.Enabled {
+		x := cfg.Tracker.Hub.Export
+		exp, err := export.New(export.Config{
+			Endpoint:        x.Endpoint,
+			Region:          x.Region,
+			Bucket:          x.Bucket,
+			Prefix:          x.Prefix,
+			AccessKeyID:     x.AccessKeyID,
+			SecretAccessKey: x.SecretAccessKey,
+			Interval:        x.Interval.D(),
+			StatePath:       export.StatePathFor(cfg.Database.Path),
+			Store:           store,
+		})
+		if err != nil {
+			log.Fatalf("CSV export init: %v", err)
+		}
+		exp.Start(ctx)
+		defer exp.Stop()
+		log.Printf("Exporting CSV snapshots to %s every %v", x.Bucket, x.Interval.D())
+	}
+
+	// Client demo library: admin uploads, plus recordings harvested
+	// from q3_servers[].demo_dir for servers this process collects.
+	var demoLibrary *demos.Library
+	if hasHub && cfg.Tracker.Hub.DemoUploads != nil {
+		du := cfg.Tracker.Hub.DemoUploads
+		demoLibrary = &demos.Library{
+			Dir:          du.Dir,
+			MaxFileBytes: int64(du.MaxFileMB) << 20,
+			QuotaBytes:   int64(du.QuotaMB) << 20,
+			Store:        store,
+		}
+		var dirs []demos.HarvestDir
+		for _, srv := range cfg.Q3Servers {
+			if srv.DemoDir != "" && hasCollector {
+				dirs = append(dirs, demos.HarvestDir{Source: collectorSource, ServerKey: srv.Key, Dir: srv.DemoDir})
+			}
+		}
+		if len(dirs) > 0 {
+			hcfg := demos.HarvesterConfig{
+				Library:  demoLibrary,
+				Dirs:     dirs,
+				Interval: time.Minute,
+			}
+			if cfg.Discord != nil && cfg.Discord.AnnounceDemos {
+				hcfg.Notifier = newDiscordDemoNotifier(ctx, cfg, store)
+				log.Printf("Announcing harvested demos to Discord")
+			}
+			harvester, err := demos.NewHarvester(hcfg)
+			if err != nil {
+				log.Fatalf("Demo harvester init: %v", err)
+			}
+			harvester.Start(ctx)
+			defer harvester.Stop()
+			log.Printf("Harvesting demos from %d server demo directories", len(dirs))
+		}
+	}
+
 	// Route manager I/O: writer directly in hub-only; NATS RPC +
 	// buffered publisher when a collector role is active.
 	var (
@@ -451,6 +583,39 @@ func cmdServe(args []string) {
 	if livePublisher != nil {
 		manager.SetLivePublisher(livePublisher)
 	}
+	if hasCollector && cfg.Tracker.Collector.GeoIPDatabase != "" {
+		geo, err := collector.OpenGeoIP(cfg.Tracker.Collector.GeoIPDatabase)
+		if err != nil {
+			log.Fatalf("Failed to open GeoIP database: %v", err)
+		}
+		defer geo.Close()
+		manager.SetGeoIP(geo)
+		log.Printf("GeoIP lookups enabled (%s)", cfg.Tracker.Collector.GeoIPDatabase)
+	}
+	if hasCollector && cfg.Tracker.Collector.EventArchive != nil {
+		archive, err := collector.OpenEventArchive(*cfg.Tracker.Collector.EventArchive)
+		if err != nil {
+			log.Fatalf("Failed to open event archive: %v", err)
+		}
+		defer archive.Close()
+		manager.SetEventArchive(archive)
+		log.Printf("Archiving log events to %s", cfg.Tracker.Collector.EventArchive.Dir)
+	}
+	if hasCollector {
+		manager.SetClockDir(cfg.Tracker.Collector.DataDir)
+		manager.SetCheckpointDir(cfg.Tracker.Collector.DataDir)
+	}
+	if cfg.Discord != nil && cfg.Discord.AlertStalls {
+		manager.SetStallNotifier(&discordStallNotifier{ctx: ctx, webhookURL: cfg.Discord.WebhookURL})
+		log.Printf("Alerting Discord when a server log stalls")
+	}
+	if cfg.Discord != nil && cfg.Discord.AlertCrashes {
+		manager.SetCrashNotifier(&discordCrashNotifier{ctx: ctx, webhookURL: cfg.Discord.WebhookURL})
+		log.Printf("Alerting Discord when a game server crashes")
+	}
+	if hasCollector && useSystemd(cfg) {
+		manager.SetUnitChecker(systemdServerController{})
+	}
 
 	// Replay cutoff: the collector's NATS publisher watermark says
 	// "I have already published everything up to this timestamp; treat
@@ -513,6 +678,18 @@ func cmdServe(args []string) {
 		}
 	}
 
+	// SIGHUP re-reads config.yml and applies the server list; see
+	// configReloader. `systemctl reload trinity` sends it.
+	reloader := &configReloader{path: cfgPath, manager: manager, registrar: registrar}
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			log.Printf("Received SIGHUP, reloading %s", cfgPath)
+			reloader.Reload(ctx)
+		}
+	}()
+
 	// Collector-only mode: no HTTP UI, just wait for signal.
 	if !hasHub {
 		log.Printf("Running in collector-only mode; no HTTP server")
@@ -529,14 +706,35 @@ func cmdServe(args []string) {
 	}
 
 	authService := auth.NewService(cfg.Auth.JWTSecret, cfg.Auth.TokenDuration)
+	authService.SetRefreshTokenDuration(cfg.Auth.RefreshTokenDuration)
 	if cfg.Auth.JWTSecret == "" {
 		log.Printf("Warning: No JWT secret configured. Auth tokens will use an empty secret.")
 	}
 
 	router := api.NewRouter(store, manager, writer, authService, cfg.Server.StaticDir, cfg.Server.Quake3Dir)
+	router.SetFeatures(api.Features{
+		Demos:           cfg.Features.DemosEnabled(),
+		Registration:    cfg.Features.RegistrationEnabled(),
+		ChatPersistence: hasHub && cfg.Features.ChatPersistenceEnabled(),
+		PublicAPI:       cfg.Features.PublicAPIEnabled(),
+	})
+	router.SetHiddenStats(cfg.Features.Hidden())
+	if o := cfg.Auth.OAuth; o != nil && len(o.Providers) > 0 {
+		router.SetOAuth(o.PublicURL, oauthProviders(o.Providers))
+	}
+	router.SetBranding(apiBranding(cfg.Branding))
+	router.SetBroadcastBuffer(cfg.Server.EventBuffers.Broadcast)
+	router.SetReloader(reloader)
+	if demoLibrary != nil {
+		router.SetDemoLibrary(demoLibrary)
+	}
 	if remotePoller != nil {
 		router.SetPoller(remotePoller)
 		remotePoller.SetSink(router)
+		remotePoller.SetMatchSink(router.MatchSink())
+	}
+	if hasHub {
+		router.SetServerBrowser(hub.NewServerBrowser(cfg.Tracker.Hub.MasterServers, collector.NewQ3Client()))
 	}
 	if ns != nil {
 		router.SetUserProvisioner(ns.Auth())
@@ -548,14 +746,25 @@ func cmdServe(args []string) {
 	// permissions so it can target any source's exec subject.
 	if hasCollector {
 		router.SetLocalSource(collectorSource)
+		if detectSystemd() {
+			router.SetServerController(systemdServerController{})
+		}
+		prov := &configServerProvisioner{path: cfgPath, reloader: reloader}
+		if useSystemd(cfg) {
+			prov.units = systemdServerController{}
+		}
+		router.SetServerProvisioner(prov)
 	}
+	var rconClient *natsbus.RconClient
 	if subNC != nil {
-		rconClient, err := natsbus.NewRconClient(subNC, 0)
+		var err error
+		rconClient, err = natsbus.NewRconClient(subNC, 0)
 		if err != nil {
 			log.Fatalf("Failed to create RCON client: %v", err)
 		}
 		router.SetRconClient(rconClient)
 	}
+	achievementNotifier.attach(collectorSource, manager, rconClient)
 	router.StartWebSocketHub()
 	log.Printf("Serving static files from %s", cfg.Server.StaticDir)
 
@@ -582,16 +791,36 @@ func cmdServe(args []string) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 3)
 	go func() {
 		log.Printf("HTTP server listening on %s", addr)
 		log.Printf("Web UI available at http://%s", addr)
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
 			serverErr <- err
 		}
-		close(serverErr)
 	}()
 
+	var httpsServer, redirectServer *http.Server
+	if tc := cfg.Server.TLS; tc != nil {
+		var err error
+		httpsServer, redirectServer, err = newTLSServers(tc, router)
+		if err != nil {
+			log.Fatalf("TLS: %v", err)
+		}
+		go func() {
+			log.Printf("HTTPS server listening on %s", httpsServer.Addr)
+			if err := httpsServer.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				serverErr <- err
+			}
+		}()
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != http.ErrServerClosed {
+				serverErr <- err
+			}
+		}()
+	}
+
 	select {
 	case sig := <-sigCh:
 		log.Printf("Received signal %v, shutting down...", sig)
@@ -605,6 +834,12 @@ func cmdServe(args []string) {
 	if err := server.Shutdown(httpCtx); err != nil {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
+	if httpsServer != nil {
+		if err := httpsServer.Shutdown(httpCtx); err != nil {
+			log.Printf("HTTPS server shutdown error: %v", err)
+		}
+		redirectServer.Close()
+	}
 
 	log.Println("Stopping server manager...")
 	manager.Stop()
@@ -1008,7 +1243,7 @@ func cmdLeaderboard(args []string) {
 	url := fs.String("url", "", "base URL of the trinity server")
 	limit := fs.Int("top", 20, "number of top players to show")
 	category := fs.String("category", "frags",
-		"category: frags, deaths, kd_ratio, matches, victories, captures, flag_returns, assists, defends, impressives, excellents, humiliations")
+		"category: frags, deaths, kd_ratio, matches, victories, captures, flag_returns, assists, defends, impressives, excellents, humiliations, sprees, accuracy, skulls, obelisks")
 	period := fs.String("period", "all", "time window: day|week|month|year|all")
 	colorMode := addColorFlag(fs)
 	fs.Parse(args)
@@ -1150,6 +1385,18 @@ func cmdDiscordDigest(args []string) {
 	}
 	embed := renderDigestEmbed(results, categories, *topN, footerURL, publicURL, staticDir, time.Now())
 
+	// Comebacks get their own full-width row under the leaderboard
+	// grid, and only when someone actually came back. A failed fetch
+	// (e.g. a hub that predates the endpoint) shouldn't sink the
+	// whole digest.
+	var returning domain.ReturningPlayersResponse
+	returningPath := fmt.Sprintf("/api/stats/returning?period=%s&limit=%d&as_of=%s", *period, *topN, asOfStr)
+	if err := getJSON(returningPath, &returning); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: fetching returning players: %v\n", err)
+	} else if len(returning.Players) > 0 {
+		embed.Fields = append(embed.Fields, renderReturningField(returning.Players, *topN, publicURL))
+	}
+
 	if *dryRun {
 		// Pretty-print so operators can eyeball the payload.
 		out, _ := json.MarshalIndent(discordWebhookPayload{Embeds: []discordEmbed{embed}}, "", "  ")
@@ -2185,6 +2432,19 @@ func serviceUser(cfg *config.Config) string {
 	return "quake"
 }
 
+// leaderboardRules converts the leaderboard config block, which may
+// be absent, to the store's rules.
+func leaderboardRules(l *config.LeaderboardConfig) storage.LeaderboardRules {
+	r := storage.LeaderboardRules{MinMatches: l.MinCompletedMatches()}
+	if l != nil {
+		r.MinPlaytime = time.Duration(l.MinPlaytime)
+		r.IncludeBots = l.IncludeBots
+		r.IncludeVRPlaceholders = l.IncludeVRPlaceholders
+		r.IncludeAbandoned = l.IncludeAbandoned
+	}
+	return r
+}
+
 // useSystemd returns whether systemd integration is enabled
 func useSystemd(cfg *config.Config) bool {
 	if cfg != nil && cfg.Server.UseSystemd != nil {
@@ -2565,6 +2825,16 @@ func cmdServerList(args []string) {
 
 // nextAvailablePort finds the lowest unused port >= 27960 based on existing config entries and env files
 func nextAvailablePort(cfg *config.Config, configDir string) int {
+	used := usedPorts(cfg, configDir)
+	for port := 27960; ; port++ {
+		if !used[port] {
+			return port
+		}
+	}
+}
+
+// usedPorts collects the ports taken by config entries and env files
+func usedPorts(cfg *config.Config, configDir string) map[int]bool {
 	used := make(map[int]bool)
 
 	// Scan config entries for ports in addresses
@@ -2586,11 +2856,7 @@ func nextAvailablePort(cfg *config.Config, configDir string) int {
 		}
 	}
 
-	for port := 27960; ; port++ {
-		if !used[port] {
-			return port
-		}
-	}
+	return used
 }
 
 // cmdServerAdd adds a new game server instance. With no name and on
@@ -2624,7 +2890,6 @@ func cmdServerAdd(args []string) {
 		os.Exit(1)
 	}
 	configDir := filepath.Dir(*configPath)
-	sysUser := serviceUser(cfg)
 	useSd := useSystemd(cfg)
 
 	answers := answersFromConfig(cfg)
@@ -2655,8 +2920,6 @@ func cmdServerAdd(args []string) {
 		}
 	}
 
-	uid, gid := uidGid(sysUser)
-
 	// Root-only steps first (systemd, file mode/ownership we couldn't
 	// set after privilege drop).
 	if useSd && os.Getuid() == 0 {
@@ -2668,69 +2931,10 @@ func cmdServerAdd(args []string) {
 		}
 	}
 
-	stem := setup.Stem(s.Gametype, s.UseMissionpack)
-
-	// Write env file (in /etc/trinity, root-owned but group-readable
-	// by the service user; matches what `trinity init` writes).
-	envPath := filepath.Join(configDir, s.Key+".env")
-	opts := fmt.Sprintf("+set net_port %d", s.Port)
-	if s.RunsMissionpack() {
-		opts += " +set fs_game missionpack"
-	}
-	opts += " +exec " + stem + ".cfg"
-	if err := os.WriteFile(envPath, []byte(fmt.Sprintf("SERVER_OPTS=%s\n", opts)), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing env file: %v\n", err)
-		os.Exit(1)
-	}
-	fmt.Println("Wrote:", envPath)
-
-	// Shared <stem>.cfg + rotation.<stem>: only write if absent (other
-	// servers of this gametype already installed them).
-	if cfg.Server.Quake3Dir != "" {
-		cfgPath := filepath.Join(cfg.Server.Quake3Dir, s.ModFolder(), stem+".cfg")
-		if _, err := os.Stat(cfgPath); err == nil {
-			fmt.Printf("  NOTE: %s already exists — left alone (existing rconpassword preserved).\n", cfgPath)
-		} else if body, rerr := setup.RenderServerCfg(s.Gametype, s.UseMissionpack, s.RconPassword); rerr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: cfg template render: %v\n", rerr)
-		} else if err := os.MkdirAll(filepath.Dir(cfgPath), 0755); err == nil {
-			// 0640 root:<service-user> — rconpassword is in this file.
-			if err := os.WriteFile(cfgPath, []byte(body), 0640); err != nil {
-				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", cfgPath, err)
-			} else {
-				_ = os.Chown(cfgPath, 0, gid)
-				fmt.Println("Wrote:", cfgPath)
-			}
-		}
-
-		rotPath := filepath.Join(cfg.Server.Quake3Dir, s.ModFolder(), "rotation."+stem)
-		if _, err := os.Stat(rotPath); err == nil {
-			// rotation already in place; nothing to do.
-		} else if body, rerr := setup.RenderRotation(s.Gametype, s.UseMissionpack); rerr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: rotation render: %v\n", rerr)
-		} else if err := os.WriteFile(rotPath, body, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", rotPath, err)
-		} else {
-			_ = os.Chown(rotPath, uid, gid)
-			fmt.Println("Wrote:", rotPath)
-		}
-	}
-
-	// Append to config.yml (after side files, so a config save failure
-	// doesn't leave us inconsistent — env+cfg without a config entry
-	// is benign; reverse is harder to debug).
-	server := config.Q3Server{
-		Key:               s.Key,
-		Address:           s.Address,
-		LogPath:           s.LogPath,
-		RconPassword:      s.RconPassword,
-		AllowHubAdminRcon: s.AllowHubAdminRcon,
-	}
-	config.AddServer(cfg, server)
-	if err := config.Save(*configPath, cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+	if err := installServer(*configPath, cfg, s, printLine, warnLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Updated:", *configPath)
 
 	fmt.Println()
 	fmt.Println("Next steps:")
@@ -2761,6 +2965,9 @@ func answersFromConfig(cfg *config.Config) *setup.Answers {
 // would suggest.
 func serverAnswersFromFlags(name, gametypeName string, ta bool, port int, rcon, logPath string, allowHubAdminRcon bool, cfg *config.Config, configDir string) (setup.ServerAnswers, error) {
 	s := setup.ServerAnswers{Key: strings.ToLower(name), AllowHubAdminRcon: allowHubAdminRcon}
+	if !config.ValidServerKey(s.Key) {
+		return s, fmt.Errorf("server key %q may only use letters, digits, '_' and '-' (at most 64)", name)
+	}
 	gt, err := parseGametype(gametypeName)
 	if err != nil {
 		return s, err
@@ -2850,7 +3057,6 @@ func cmdServerRemove(args []string) {
 
 	sysUser := serviceUser(cfg)
 	useSd := useSystemd(cfg)
-	configDir := filepath.Dir(*configPath)
 
 	// Do root-only operations first
 	if useSd && os.Getuid() == 0 {
@@ -2866,42 +3072,12 @@ func cmdServerRemove(args []string) {
 		fmt.Fprintf(os.Stderr, "Warning: failed to drop privileges: %v\n", err)
 	}
 
-	// Archive env file rather than deleting it. Operators sometimes
-	// remove a server intending to add it back with a tweak; the
-	// archived .env is a quick reference and a safety net for typos.
-	envPath := filepath.Join(configDir, name+".env")
-	if _, err := os.Stat(envPath); err == nil {
-		stamp := time.Now().UTC().Format("20060102-150405")
-		archived := envPath + ".removed-" + stamp
-		if err := os.Rename(envPath, archived); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to archive %s: %v\n", envPath, err)
-		} else {
-			fmt.Println("Archived:", archived)
-		}
-	}
-
-	// Remove from config (try both the raw name and uppercase as display name)
-	found := config.RemoveServerByKey(cfg, name)
-	if !found {
-		found = config.RemoveServerByKey(cfg, strings.ToUpper(name))
+	found, err := uninstallServer(*configPath, cfg, name, printLine, warnLine)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 	if !found {
-		// Try case-insensitive match
-		for _, srv := range cfg.Q3Servers {
-			if strings.EqualFold(srv.Key, name) {
-				found = config.RemoveServerByKey(cfg, srv.Key)
-				break
-			}
-		}
-	}
-
-	if found {
-		if err := config.Save(*configPath, cfg); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Config: %s updated\n", *configPath)
-	} else {
 		fmt.Fprintf(os.Stderr, "Warning: no matching server entry found in config\n")
 	}
 
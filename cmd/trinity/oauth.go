@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/ernie/trinity-tracker/internal/auth"
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// oauthProviders converts the auth.oauth provider list to auth
+// providers, starting from each one's preset and applying overrides.
+func oauthProviders(providers []config.OAuthProviderConfig) []auth.OAuthProvider {
+	out := make([]auth.OAuthProvider, 0, len(providers))
+	for _, pc := range providers {
+		p := auth.NewOAuthProvider(pc.Name, pc.ClientID, pc.ClientSecret)
+		if pc.Label != "" {
+			p.Label = pc.Label
+		}
+		if pc.AuthURL != "" {
+			p.AuthURL = pc.AuthURL
+		}
+		if pc.TokenURL != "" {
+			p.TokenURL = pc.TokenURL
+		}
+		if pc.UserInfoURL != "" {
+			p.UserInfoURL = pc.UserInfoURL
+		}
+		if len(pc.Scopes) > 0 {
+			p.Scopes = pc.Scopes
+		}
+		if pc.SubjectField != "" {
+			p.SubjectField = pc.SubjectField
+		}
+		if pc.NameField != "" {
+			p.NameField = pc.NameField
+		}
+		out = append(out, p)
+	}
+	return out
+}
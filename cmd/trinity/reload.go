@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/natsbus"
+)
+
+// configReloader re-reads config.yml and hands the server list to the
+// manager. SIGHUP and POST /api/admin/reload both go through it.
+type configReloader struct {
+	path      string
+	manager   *collector.ServerManager
+	registrar *natsbus.Registrar // nil in hub-only mode
+}
+
+func (c *configReloader) Reload(ctx context.Context) (collector.ServerDiff, error) {
+	cfg, err := config.Load(c.path)
+	if err != nil {
+		log.Printf("Config reload failed: %v", err)
+		return collector.ServerDiff{}, fmt.Errorf("loading %s: %w", c.path, err)
+	}
+	diff, err := c.manager.Reload(ctx, cfg)
+	if diff.Changed() && c.registrar != nil {
+		// Tell the hub about the new roster now rather than on the
+		// next heartbeat.
+		c.registrar.Publish()
+	}
+	log.Printf("Config reloaded from %s: added=[%s] removed=[%s] restarted=[%s] updated=[%s]",
+		c.path, strings.Join(diff.Added, ","), strings.Join(diff.Removed, ","),
+		strings.Join(diff.Restarted, ","), strings.Join(diff.Updated, ","))
+	if err != nil {
+		log.Printf("Config reload: %v", err)
+	}
+	return diff, err
+}
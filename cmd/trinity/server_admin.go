@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/cmd/trinity/setup"
+	"github.com/ernie/trinity-tracker/internal/api"
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// printLine and warnLine report installServer/uninstallServer progress
+// for the CLI.
+func printLine(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+func warnLine(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// installServer writes a new server's .env file (port + +exec the
+// shared <stem>.cfg), the shared <stem>.cfg and rotation.<stem> if
+// they don't already exist, and adds it to the config at configPath.
+// Progress goes to say and problems that don't stop the install to
+// warn.
+func installServer(configPath string, cfg *config.Config, s setup.ServerAnswers, say, warn func(format string, args ...any)) error {
+	if !config.ValidServerKey(s.Key) {
+		return fmt.Errorf("invalid server key %q", s.Key)
+	}
+	configDir := filepath.Dir(configPath)
+	uid, gid := uidGid(serviceUser(cfg))
+	stem := setup.Stem(s.Gametype, s.UseMissionpack)
+
+	// Write env file (in /etc/trinity, root-owned but group-readable
+	// by the service user; matches what `trinity init` writes).
+	envPath := filepath.Join(configDir, s.Key+".env")
+	opts := fmt.Sprintf("+set net_port %d", s.Port)
+	if s.RunsMissionpack() {
+		opts += " +set fs_game missionpack"
+	}
+	opts += " +exec " + stem + ".cfg"
+	if err := os.WriteFile(envPath, []byte(fmt.Sprintf("SERVER_OPTS=%s\n", opts)), 0644); err != nil {
+		return fmt.Errorf("writing env file: %w", err)
+	}
+	say("Wrote: %s", envPath)
+
+	// Shared <stem>.cfg + rotation.<stem>: only write if absent (other
+	// servers of this gametype already installed them).
+	if cfg.Server.Quake3Dir != "" {
+		cfgPath := filepath.Join(cfg.Server.Quake3Dir, s.ModFolder(), stem+".cfg")
+		if _, err := os.Stat(cfgPath); err == nil {
+			say("  NOTE: %s already exists — left alone (existing rconpassword preserved).", cfgPath)
+		} else if body, rerr := setup.RenderServerCfg(s.Gametype, s.UseMissionpack, s.RconPassword); rerr != nil {
+			warn("cfg template render: %v", rerr)
+		} else if err := os.MkdirAll(filepath.Dir(cfgPath), 0755); err == nil {
+			// 0640 root:<service-user> — rconpassword is in this file.
+			if err := os.WriteFile(cfgPath, []byte(body), 0640); err != nil {
+				warn("writing %s: %v", cfgPath, err)
+			} else {
+				_ = os.Chown(cfgPath, 0, gid)
+				say("Wrote: %s", cfgPath)
+			}
+		}
+
+		rotPath := filepath.Join(cfg.Server.Quake3Dir, s.ModFolder(), "rotation."+stem)
+		if _, err := os.Stat(rotPath); err == nil {
+			// rotation already in place; nothing to do.
+		} else if body, rerr := setup.RenderRotation(s.Gametype, s.UseMissionpack); rerr != nil {
+			warn("rotation render: %v", rerr)
+		} else if err := os.WriteFile(rotPath, body, 0644); err != nil {
+			warn("writing %s: %v", rotPath, err)
+		} else {
+			_ = os.Chown(rotPath, uid, gid)
+			say("Wrote: %s", rotPath)
+		}
+	}
+
+	// Append to config.yml (after side files, so a config save failure
+	// doesn't leave us inconsistent — env+cfg without a config entry
+	// is benign; reverse is harder to debug).
+	config.AddServer(cfg, config.Q3Server{
+		Key:               s.Key,
+		Address:           s.Address,
+		LogPath:           s.LogPath,
+		RconPassword:      s.RconPassword,
+		AllowHubAdminRcon: s.AllowHubAdminRcon,
+	})
+	if err := config.Save(configPath, cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	say("Updated: %s", configPath)
+	return nil
+}
+
+// uninstallServer archives the server's .env file and removes it from
+// the config at configPath. Reports whether the config had it.
+func uninstallServer(configPath string, cfg *config.Config, key string, say, warn func(format string, args ...any)) (bool, error) {
+	// Archive env file rather than deleting it. Operators sometimes
+	// remove a server intending to add it back with a tweak; the
+	// archived .env is a quick reference and a safety net for typos.
+	envPath := filepath.Join(filepath.Dir(configPath), strings.ToLower(key)+".env")
+	if _, err := os.Stat(envPath); err == nil {
+		stamp := time.Now().UTC().Format("20060102-150405")
+		archived := envPath + ".removed-" + stamp
+		if err := os.Rename(envPath, archived); err != nil {
+			warn("failed to archive %s: %v", envPath, err)
+		} else {
+			say("Archived: %s", archived)
+		}
+	}
+
+	if !config.RemoveServerByKey(cfg, key) {
+		return false, nil
+	}
+	if err := config.Save(configPath, cfg); err != nil {
+		return true, fmt.Errorf("saving config: %w", err)
+	}
+	say("Config: %s updated", configPath)
+	return true, nil
+}
+
+// configServerProvisioner satisfies api.ServerProvisioner. It edits
+// config.yml and the side files like `trinity server add` and `trinity
+// server remove`, starts or stops the quake3-server@ unit when systemd
+// runs the servers, and reloads so the collector picks the change up.
+type configServerProvisioner struct {
+	path     string
+	reloader *configReloader
+	units    api.ServerController // nil without systemd
+
+	mu sync.Mutex // serializes config.yml edits
+}
+
+func (p *configServerProvisioner) AddServer(ctx context.Context, req api.ServerAddRequest) (*api.ServerAddResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cfg, err := config.Load(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", p.path, err)
+	}
+	for _, existing := range cfg.Q3Servers {
+		if strings.EqualFold(existing.Key, req.Key) {
+			return nil, fmt.Errorf("%w: %s", api.ErrServerExists, req.Key)
+		}
+	}
+	configDir := filepath.Dir(p.path)
+	if req.Port != 0 && usedPorts(cfg, configDir)[req.Port] {
+		return nil, fmt.Errorf("%w: port %d is in use", api.ErrServerInvalid, req.Port)
+	}
+	s, err := serverAnswersFromFlags(req.Key, req.GameType, req.TeamArena, req.Port, req.RconPassword, "", req.AllowHubAdminRcon, cfg, configDir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", api.ErrServerInvalid, err)
+	}
+	if err := installServer(p.path, cfg, s, log.Printf, log.Printf); err != nil {
+		return nil, err
+	}
+
+	resp := &api.ServerAddResponse{Key: s.Key, Address: s.Address, LogPath: s.LogPath}
+	if p.units != nil {
+		resp.Unit = "quake3-server@" + s.Key
+		if _, err := p.units.Control(ctx, resp.Unit, "enable"); err != nil {
+			log.Printf("server add %s: %v", s.Key, err)
+		}
+		resp.State, err = p.units.Control(ctx, resp.Unit, "start")
+		if err != nil {
+			log.Printf("server add %s: %v", s.Key, err)
+		}
+	}
+	if _, err := p.reloader.Reload(ctx); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (p *configServerProvisioner) RemoveServer(ctx context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cfg, err := config.Load(p.path)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", p.path, err)
+	}
+	found := false
+	for _, srv := range cfg.Q3Servers {
+		found = found || strings.EqualFold(srv.Key, key)
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", api.ErrServerNotFound, key)
+	}
+
+	if p.units != nil {
+		unit := "quake3-server@" + strings.ToLower(key)
+		for _, action := range []string{"stop", "disable"} {
+			if _, err := p.units.Control(ctx, unit, action); err != nil {
+				log.Printf("server remove %s: %v", key, err)
+			}
+		}
+	}
+	if _, err := uninstallServer(p.path, cfg, key, log.Printf, log.Printf); err != nil {
+		return err
+	}
+	_, err = p.reloader.Reload(ctx)
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/cmd/trinity/setup"
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+func TestInstallUninstallServer(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yml")
+	cfg := &config.Config{}
+	quiet := func(string, ...any) {}
+
+	s := setup.ServerAnswers{
+		Key:          "ctf",
+		Gametype:     setup.GametypeCTF,
+		Port:         27961,
+		Address:      "127.0.0.1:27961",
+		LogPath:      "/var/log/quake3/ctf.log",
+		RconPassword: "secret",
+	}
+	if err := installServer(configPath, cfg, s, quiet, quiet); err != nil {
+		t.Fatalf("installServer: %v", err)
+	}
+	env, err := os.ReadFile(filepath.Join(dir, "ctf.env"))
+	if err != nil {
+		t.Fatalf("env file: %v", err)
+	}
+	if !strings.Contains(string(env), "+set net_port 27961") {
+		t.Errorf("env file: got %q", env)
+	}
+	saved, err := os.ReadFile(configPath)
+	if err != nil || !strings.Contains(string(saved), "key: ctf") {
+		t.Fatalf("config: got %q, err %v", saved, err)
+	}
+
+	bad := s
+	bad.Key = "../ctf"
+	if err := installServer(configPath, cfg, bad, quiet, quiet); err == nil {
+		t.Errorf("installServer accepted key %q", bad.Key)
+	}
+
+	found, err := uninstallServer(configPath, cfg, "CTF", quiet, quiet)
+	if err != nil || !found {
+		t.Fatalf("uninstallServer: found=%v err=%v", found, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ctf.env")); !os.IsNotExist(err) {
+		t.Errorf("env file should be archived, stat err=%v", err)
+	}
+	if len(cfg.Q3Servers) != 0 {
+		t.Errorf("servers left: %+v", cfg.Q3Servers)
+	}
+	if found, _ := uninstallServer(configPath, cfg, "ctf", quiet, quiet); found {
+		t.Errorf("second uninstall found the server")
+	}
+}
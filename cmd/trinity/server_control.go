@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// systemdServerController satisfies api.ServerController by shelling
+// out to systemctl. The trinity service runs unprivileged, so the host
+// needs a polkit rule (or equivalent) letting the service user manage
+// quake3-server@*.service; --no-ask-password makes a missing rule fail
+// fast with systemctl's own message instead of hanging on a prompt.
+type systemdServerController struct{}
+
+func (systemdServerController) Control(ctx context.Context, unit, action string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "systemctl", "--no-ask-password", action, unit)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return systemctlIsActive(unit), fmt.Errorf("systemctl %s %s: %s", action, unit, msg)
+	}
+	return systemctlIsActive(unit), nil
+}
+
+// UnitFailed satisfies collector.UnitChecker. Reading a unit's state
+// needs no privileges.
+func (systemdServerController) UnitFailed(ctx context.Context, unit string) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "systemctl", "show", "--property=ActiveState,Result", unit).Output()
+	if err != nil {
+		return false, "", fmt.Errorf("systemctl show %s: %w", unit, err)
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			props[k] = strings.TrimSpace(v)
+		}
+	}
+	return props["ActiveState"] == "failed", props["Result"], nil
+}
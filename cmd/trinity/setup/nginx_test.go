@@ -23,6 +23,8 @@ func TestRenderHubNginxConfig_ContainsExpectedDirectives(t *testing.T) {
 		"root /var/lib/trinity/web;",
 		"return 301 https://$host$request_uri;",
 		"location /api/ {",
+		"location /badge/ {",
+		"location /public/api/ {",
 		"location /ws {",
 		"proxy_set_header Upgrade $http_upgrade;",
 		"location /demos/                { try_files $uri @trinity_fallback; }",
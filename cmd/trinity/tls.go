@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// newTLSServers builds the HTTPS server for handler and the plain-HTTP
+// server that redirects to it, as configured by tc. With ACME the
+// redirect server also answers the CA's http-01 challenges, and the
+// HTTPS server its tls-alpn-01 ones.
+func newTLSServers(tc *config.TLSConfig, handler http.Handler) (httpsServer, redirectServer *http.Server, err error) {
+	redirect := redirectToHTTPS(tc.Port)
+	var tlsConfig *tls.Config
+	if a := tc.ACME; a != nil {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(a.CacheDir),
+			HostPolicy: autocert.HostWhitelist(a.Domains...),
+			Email:      a.Email,
+		}
+		if a.DirectoryURL != "" {
+			m.Client = &acme.Client{DirectoryURL: a.DirectoryURL}
+		}
+		tlsConfig = m.TLSConfig()
+		redirect = m.HTTPHandler(redirect)
+	} else {
+		// Loaded once, so a renewed certificate needs a restart.
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading server.tls certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	httpsServer = &http.Server{
+		Addr:         net.JoinHostPort(tc.ListenAddr, strconv.Itoa(tc.Port)),
+		Handler:      handler,
+		TLSConfig:    tlsConfig,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	redirectServer = &http.Server{
+		Addr:         net.JoinHostPort(tc.ListenAddr, strconv.Itoa(tc.RedirectPort)),
+		Handler:      redirect,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+	return httpsServer, redirectServer, nil
+}
+
+// redirectToHTTPS sends every request to the same host and path over
+// HTTPS on port.
+func redirectToHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + req.URL.RequestURI()
+		http.Redirect(w, req, target, http.StatusMovedPermanently)
+	})
+}
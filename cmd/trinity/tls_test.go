@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		port       int
+		host, path string
+		want       string
+	}{
+		{443, "tracker.example.com", "/matches/1?x=2", "https://tracker.example.com/matches/1?x=2"},
+		{443, "tracker.example.com:80", "/", "https://tracker.example.com/"},
+		{8443, "tracker.example.com:8080", "/api/servers", "https://tracker.example.com:8443/api/servers"},
+		{443, "[::1]:80", "/", "https://[::1]/"},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		redirectToHTTPS(tc.port).ServeHTTP(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tc.want {
+			t.Errorf("%s%s on %d: %d %q, want %q", tc.host, tc.path, tc.port, w.Code, w.Header().Get("Location"), tc.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleListAdminCommands lists the !kick, !mute, and !map commands
+// admins ran in game, newest first. Optional server_id narrows the
+// list.
+//
+// path: GET /api/admin/admin-commands
+func (r *Router) handleListAdminCommands(w http.ResponseWriter, req *http.Request) {
+	var serverID int64
+	if v := req.URL.Query().Get("server_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid server_id")
+			return
+		}
+		serverID = id
+	}
+	cmds, err := r.store.ListAdminCommands(req.Context(), serverID, parseLimit(req, 100, 500))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, cmds)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/auth"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// anonymizeCoolingOff is how long a "forget me" request waits before
+// the hub carries it out. The user can cancel at any point until then;
+// after that the player's name, GUIDs, and IPs are gone for good.
+const anonymizeCoolingOff = 7 * 24 * time.Hour
+
+// AnonymizeRequest confirms a "forget me" request with the account password.
+type AnonymizeRequest struct {
+	Password string `json:"password"`
+}
+
+// AnonymizeStatusResponse reports the linked player's "forget me" request.
+type AnonymizeStatusResponse struct {
+	Status       string     `json:"status"` // "none" or "pending"
+	RequestedAt  *time.Time `json:"requested_at,omitempty"`
+	ExecuteAfter *time.Time `json:"execute_after,omitempty"`
+}
+
+func anonymizeStatus(a *storage.PlayerAnonymization) AnonymizeStatusResponse {
+	if a == nil || a.CompletedAt != nil {
+		return AnonymizeStatusResponse{Status: "none"}
+	}
+	return AnonymizeStatusResponse{
+		Status:       "pending",
+		RequestedAt:  &a.RequestedAt,
+		ExecuteAfter: &a.ExecuteAfter,
+	}
+}
+
+// accountPlayer reads the user's linked player from the database
+// rather than the token, which can predate a link or unlink.
+func (r *Router) accountPlayer(w http.ResponseWriter, req *http.Request) (*storage.User, int64, bool) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return nil, 0, false
+	}
+	user, err := r.store.GetUserByID(req.Context(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return nil, 0, false
+	}
+	if user.PlayerID == nil {
+		writeError(w, http.StatusBadRequest, "no player linked to this account")
+		return nil, 0, false
+	}
+	return user, *user.PlayerID, true
+}
+
+// handleGetAnonymize reports whether the linked player is queued for
+// anonymization.
+//
+// path: GET /api/account/anonymize
+func (r *Router) handleGetAnonymize(w http.ResponseWriter, req *http.Request) {
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	a, err := r.store.GetAnonymization(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get anonymization request")
+		return
+	}
+	writeJSON(w, http.StatusOK, anonymizeStatus(a))
+}
+
+// handleRequestAnonymize queues the linked player for anonymization
+// after the cooling-off period. Requires the account password.
+//
+// path: POST /api/account/anonymize
+func (r *Router) handleRequestAnonymize(w http.ResponseWriter, req *http.Request) {
+	var body AnonymizeRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	user, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	if !auth.CheckPassword(body.Password, user.PasswordHash) {
+		writeError(w, http.StatusUnauthorized, "password is incorrect")
+		return
+	}
+
+	now := time.Now().UTC()
+	a, err := r.store.RequestAnonymization(req.Context(), playerID, user.ID, now, now.Add(anonymizeCoolingOff))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to request anonymization")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, anonymizeStatus(a))
+}
+
+// handleCancelAnonymize withdraws a pending request.
+//
+// path: DELETE /api/account/anonymize
+func (r *Router) handleCancelAnonymize(w http.ResponseWriter, req *http.Request) {
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	canceled, err := r.store.CancelAnonymization(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to cancel anonymization")
+		return
+	}
+	if !canceled {
+		writeError(w, http.StatusNotFound, "no pending anonymization request")
+		return
+	}
+	writeJSON(w, http.StatusOK, anonymizeStatus(nil))
+}
+
+// handleAdminAnonymize anonymizes a player immediately, for privacy
+// requests that reach an admin rather than coming through an account.
+// There's no cooling-off and no undo.
+//
+// path: POST /api/admin/players/{id}/anonymize
+func (r *Router) handleAdminAnonymize(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	player, err := r.store.GetPlayerByID(req.Context(), playerID)
+	if err != nil || player == nil {
+		writeError(w, http.StatusNotFound, "player not found")
+		return
+	}
+	if player.IsBot {
+		writeError(w, http.StatusBadRequest, "bots can't be anonymized")
+		return
+	}
+	a, err := r.store.GetAnonymization(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get anonymization request")
+		return
+	}
+	if a != nil && a.CompletedAt != nil {
+		writeError(w, http.StatusConflict, "player is already anonymized")
+		return
+	}
+
+	claims := r.getAuthClaims(req)
+	if err := r.writer.AnonymizeNow(req.Context(), playerID, claims.UserID, time.Now().UTC()); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to anonymize player")
+		return
+	}
+	player, err = r.store.GetPlayerByID(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, player)
+}
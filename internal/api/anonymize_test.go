@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAnonymizeRequestFlow(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	tok, userID := tr.loginAs(t, "alice", false)
+
+	// Nothing to anonymize until a player is linked.
+	if w := tr.do("GET", "/api/account/anonymize", "", tok); w.Code != http.StatusBadRequest {
+		t.Fatalf("unlinked: code = %d, body = %s", w.Code, w.Body)
+	}
+	pg, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if err := tr.store.UpdateUserPlayerLink(ctx, userID, &pg.PlayerID); err != nil {
+		t.Fatalf("UpdateUserPlayerLink: %v", err)
+	}
+
+	if w := tr.do("POST", "/api/account/anonymize", `{"password":"wrong"}`, tok); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: code = %d, body = %s", w.Code, w.Body)
+	}
+	w := tr.do("POST", "/api/account/anonymize", `{"password":"password123"}`, tok)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("request: code = %d, body = %s", w.Code, w.Body)
+	}
+	var got AnonymizeStatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Status != "pending" || got.ExecuteAfter == nil || got.ExecuteAfter.Sub(*got.RequestedAt) != anonymizeCoolingOff {
+		t.Errorf("request: got %+v", got)
+	}
+
+	if w := tr.do("DELETE", "/api/account/anonymize", "", tok); w.Code != http.StatusOK {
+		t.Fatalf("cancel: code = %d, body = %s", w.Code, w.Body)
+	}
+	if w := tr.do("DELETE", "/api/account/anonymize", "", tok); w.Code != http.StatusNotFound {
+		t.Errorf("second cancel: code = %d, want 404", w.Code)
+	}
+	w = tr.do("GET", "/api/account/anonymize", "", tok)
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Status != "none" {
+		t.Errorf("after cancel: %+v, %v", got, err)
+	}
+}
+
+func TestAdminAnonymize(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, _ := tr.loginAs(t, "bob", false)
+
+	pg, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	path := "/api/admin/players/" + strconv.FormatInt(pg.PlayerID, 10) + "/anonymize"
+
+	if w := tr.do("POST", path, "", userTok); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: code = %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", "/api/admin/players/9999/anonymize", "", adminTok); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown player: code = %d, want 404", w.Code)
+	}
+
+	w := tr.do("POST", path, "", adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("anonymize: code = %d, body = %s", w.Code, w.Body)
+	}
+	var got struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if want := "Anonymous#" + strconv.FormatInt(pg.PlayerID, 10); got.Name != want {
+		t.Errorf("name: got %q, want %q", got.Name, want)
+	}
+	// The old GUID no longer leads back to the player.
+	if found, err := tr.store.GetPlayerGUIDByGUID(ctx, "AAAA"); err == nil && found != nil {
+		t.Errorf("GUID AAAA still resolves: %+v", found)
+	}
+
+	if w := tr.do("POST", path, "", adminTok); w.Code != http.StatusConflict {
+		t.Errorf("second anonymize: code = %d, want 409", w.Code)
+	}
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/auth"
+)
+
+// API key scopes. A read key can only GET; an admin key can do what
+// its user can, and only an admin can make one.
+const (
+	apiKeyScopeRead  = "read"
+	apiKeyScopeAdmin = "admin"
+)
+
+// maxAPIKeysPerUser caps how many unrevoked keys one user can hold.
+const maxAPIKeysPerUser = 10
+
+// apiKeyAllows reports whether a request authenticated with an API key
+// of scope may go through. No key reaches login, password, sign-in
+// identity or key management, anyone's credentials, or changes to
+// users: making one, resetting a password, or impersonating. So a
+// leaked key can't be turned into a way to log in.
+func apiKeyAllows(scope string, req *http.Request) bool {
+	path := req.URL.Path
+	if strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, "/api/account/api-keys") ||
+		strings.HasPrefix(path, "/api/account/identities") || strings.HasPrefix(path, "/api/account/reauth") ||
+		strings.HasSuffix(path, "/creds") {
+		return false
+	}
+	if (path == "/api/users" || strings.HasPrefix(path, "/api/users/")) &&
+		req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if scope == apiKeyScopeAdmin {
+		return true
+	}
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// apiKeyClaims returns claims for the user behind key, as if they'd
+// logged in, or nil if the key is unknown, revoked, or not allowed to
+// make req.
+func (r *Router) apiKeyClaims(req *http.Request, key string) *auth.Claims {
+	ctx := req.Context()
+	k, user, err := r.store.GetAPIKeyUser(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("api key lookup: %v", err)
+		}
+		return nil
+	}
+	noteUsageAPIKey(ctx, usageAPIKey{id: k.ID, prefix: k.Prefix})
+	if !apiKeyAllows(k.Scope, req) {
+		return nil
+	}
+	if err := r.store.TouchAPIKey(ctx, k.ID, time.Now()); err != nil {
+		log.Printf("api key %d: recording use: %v", k.ID, err)
+	}
+	return &auth.Claims{
+		Username: user.Username,
+		UserID:   user.ID,
+		IsAdmin:  user.IsAdmin && k.Scope == apiKeyScopeAdmin,
+		PlayerID: user.PlayerID,
+		APIKeyID: k.ID,
+	}
+}
+
+// CreateAPIKeyRequest is the request body for making a key.
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+type apiKeyJSON struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Username   string     `json:"username"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Key is only filled in on the response that creates it.
+	Key string `json:"key,omitempty"`
+}
+
+// handleCreateAPIKey makes a key for the logged-in user and returns it.
+// This is the only time the key is shown. scope defaults to read.
+//
+// path: POST /api/account/api-keys
+func (r *Router) handleCreateAPIKey(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var body CreateAPIKeyRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 64 {
+		writeError(w, http.StatusBadRequest, "name must be 1-64 characters")
+		return
+	}
+	if body.Scope == "" {
+		body.Scope = apiKeyScopeRead
+	}
+	if body.Scope != apiKeyScopeRead && body.Scope != apiKeyScopeAdmin {
+		writeError(w, http.StatusBadRequest, `scope must be "read" or "admin"`)
+		return
+	}
+	if body.Scope == apiKeyScopeAdmin && !claims.IsAdmin {
+		writeError(w, http.StatusForbidden, "only admins can create admin keys")
+		return
+	}
+
+	ctx := req.Context()
+	n, err := r.store.CountActiveAPIKeys(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create key")
+		return
+	}
+	if n >= maxAPIKeysPerUser {
+		writeError(w, http.StatusConflict, "too many API keys; revoke one first")
+		return
+	}
+	key, hash, err := auth.NewAPIKey()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create key")
+		return
+	}
+	now := time.Now()
+	prefix := key[:len(auth.APIKeyPrefix)+6]
+	id, err := r.store.CreateAPIKey(ctx, claims.UserID, body.Name, hash, prefix, body.Scope, now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create key")
+		return
+	}
+	log.Printf("audit: create_api_key id=%d name=%q scope=%s user=%q remote=%s", id, body.Name, body.Scope, claims.Username, req.RemoteAddr)
+	writeJSON(w, http.StatusCreated, apiKeyJSON{
+		ID:        id,
+		UserID:    claims.UserID,
+		Username:  claims.Username,
+		Name:      body.Name,
+		Prefix:    prefix,
+		Scope:     body.Scope,
+		CreatedAt: now.UTC().Truncate(time.Second),
+		Key:       key,
+	})
+}
+
+// handleListAPIKeys returns the logged-in user's keys, newest first.
+//
+// path: GET /api/account/api-keys
+func (r *Router) handleListAPIKeys(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	r.writeAPIKeys(req.Context(), w, claims.UserID)
+}
+
+// handleRevokeAPIKey revokes one of the logged-in user's keys.
+//
+// path: DELETE /api/account/api-keys/{id}
+func (r *Router) handleRevokeAPIKey(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	r.revokeAPIKey(w, req, claims, claims.UserID)
+}
+
+// handleAdminListAPIKeys returns every user's keys, newest first.
+//
+// path: GET /api/admin/api-keys
+func (r *Router) handleAdminListAPIKeys(w http.ResponseWriter, req *http.Request) {
+	r.writeAPIKeys(req.Context(), w, 0)
+}
+
+// handleAdminRevokeAPIKey revokes any user's key.
+//
+// path: DELETE /api/admin/api-keys/{id}
+func (r *Router) handleAdminRevokeAPIKey(w http.ResponseWriter, req *http.Request) {
+	r.revokeAPIKey(w, req, r.getAuthClaims(req), 0)
+}
+
+// writeAPIKeys lists userID's keys, or everyone's if it's zero.
+func (r *Router) writeAPIKeys(ctx context.Context, w http.ResponseWriter, userID int64) {
+	keys, err := r.store.ListAPIKeys(ctx, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list keys")
+		return
+	}
+	out := make([]apiKeyJSON, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, apiKeyJSON{
+			ID:         k.ID,
+			UserID:     k.UserID,
+			Username:   k.Username,
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scope:      k.Scope,
+			CreatedAt:  k.CreatedAt,
+			LastUsedAt: k.LastUsedAt,
+			RevokedAt:  k.RevokedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// revokeAPIKey revokes the key in the path, which must belong to
+// ownerID unless that's zero.
+func (r *Router) revokeAPIKey(w http.ResponseWriter, req *http.Request, claims *auth.Claims, ownerID int64) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid key ID")
+		return
+	}
+	err = r.store.RevokeAPIKey(req.Context(), id, ownerID, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke key")
+		return
+	}
+	actor := "unknown"
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: revoke_api_key id=%d actor=%s remote=%s", id, actor, req.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "API key revoked"})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func createAPIKey(t *testing.T, tr *testRouter, tok, body string) apiKeyJSON {
+	t.Helper()
+	w := tr.do("POST", "/api/account/api-keys", body, tok)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create key %s: %d %s", body, w.Code, w.Body)
+	}
+	var key apiKeyJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, _ := tr.loginAs(t, "alice", false)
+
+	read := createAPIKey(t, tr, adminTok, `{"name":"stats bot"}`)
+	if read.Scope != "read" || read.Key == "" || read.Prefix != read.Key[:len(read.Prefix)] {
+		t.Fatalf("read key: %+v", read)
+	}
+	admin := createAPIKey(t, tr, adminTok, `{"name":"ops","scope":"admin"}`)
+	if w := tr.do("POST", "/api/account/api-keys", `{"name":"x","scope":"admin"}`, userTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin making an admin key: %d, want 403", w.Code)
+	}
+
+	// A read key is its user for GETs, without admin rights.
+	w := tr.do("GET", "/api/account/profile", "", read.Key)
+	var profile AccountProfileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil || profile.User.Username != "admin" {
+		t.Fatalf("profile with read key: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("GET", "/api/admin/usage", "", read.Key); w.Code != http.StatusForbidden {
+		t.Errorf("admin endpoint with read key: %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", "/api/account/link-code", "", read.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST with read key: %d, want 401", w.Code)
+	}
+
+	if w := tr.do("GET", "/api/admin/usage", "", admin.Key); w.Code != http.StatusOK {
+		t.Errorf("admin endpoint with admin key: %d", w.Code)
+	}
+	for _, path := range []string{"/api/account/api-keys", "/api/auth/game-token"} {
+		if w := tr.do("GET", path, "", admin.Key); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s with admin key: %d, want 401", path, w.Code)
+		}
+	}
+
+	// The owner and admins can revoke; nobody else can.
+	path := fmt.Sprintf("/api/account/api-keys/%d", read.ID)
+	if w := tr.do("DELETE", path, "", userTok); w.Code != http.StatusNotFound {
+		t.Errorf("revoking someone else's key: %d, want 404", w.Code)
+	}
+	if w := tr.do("DELETE", path, "", adminTok); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", read.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: %d, want 401", w.Code)
+	}
+
+	w = tr.do("GET", "/api/admin/api-keys", "", adminTok)
+	var keys []apiKeyJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &keys); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Key != "" || keys[0].LastUsedAt == nil || keys[1].RevokedAt == nil {
+		t.Errorf("admin list: %+v", keys)
+	}
+}
+
+func TestRevokeUserTokensRevokesAPIKeys(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, userID := tr.loginAs(t, "alice", false)
+	key := createAPIKey(t, tr, userTok, `{"name":"bot"}`)
+
+	if w := tr.do("POST", fmt.Sprintf("/api/users/%d/revoke-tokens", userID), "", adminTok); w.Code != http.StatusOK {
+		t.Fatalf("revoke-tokens: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", key.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("key after revoke-tokens: %d, want 401", w.Code)
+	}
+}
+
+func TestAPIKeyCantManageUsers(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	_, aliceID := tr.loginAs(t, "alice", false)
+	admin := createAPIKey(t, tr, adminTok, `{"name":"ops","scope":"admin"}`)
+
+	if w := tr.do("GET", "/api/users", "", admin.Key); w.Code != http.StatusOK {
+		t.Errorf("GET /api/users with admin key: %d", w.Code)
+	}
+	for _, c := range []struct{ method, path, body string }{
+		{"POST", "/api/users", `{"username":"mallory","password":"password123","is_admin":true}`},
+		{"PATCH", fmt.Sprintf("/api/users/%d", aliceID), `{"is_admin":true}`},
+		{"POST", fmt.Sprintf("/api/users/%d/reset-password", aliceID), `{"new_password":"password456"}`},
+		{"POST", fmt.Sprintf("/api/users/%d/impersonate", aliceID), ""},
+		{"DELETE", "/api/users/alice", ""},
+	} {
+		if w := tr.do(c.method, c.path, c.body, admin.Key); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with admin key: %d, want 401", c.method, c.path, w.Code)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -24,6 +28,7 @@ type LoginResponse struct {
 	IsAdmin                bool   `json:"is_admin"`
 	PlayerID               *int64 `json:"player_id,omitempty"`
 	PasswordChangeRequired bool   `json:"password_change_required"`
+	RefreshToken           string `json:"refresh_token,omitempty"`
 }
 
 // handleLogin authenticates a user and returns a JWT token
@@ -50,6 +55,11 @@ func (r *Router) handleLogin(w http.ResponseWriter, req *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
+	refresh, err := r.issueRefreshToken(req.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
 
 	// Successful login — reset rate limiter for this IP
 	r.loginLimiter.Reset(getClientIP(req))
@@ -63,11 +73,92 @@ func (r *Router) handleLogin(w http.ResponseWriter, req *http.Request) {
 		IsAdmin:                user.IsAdmin,
 		PlayerID:               user.PlayerID,
 		PasswordChangeRequired: user.PasswordChangeRequired,
+		RefreshToken:           refresh,
 	})
 }
 
-// handleLogout handles logout (JWT is stateless, client just discards token)
+// issueRefreshToken stores and returns a new refresh token for userID.
+func (r *Router) issueRefreshToken(ctx context.Context, userID int64) (string, error) {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := r.store.CreateRefreshToken(ctx, userID, hash, now, now.Add(r.auth.RefreshTokenDuration())); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RefreshRequest is the request body for refresh and logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// handleRefresh trades a refresh token for a new access token and a
+// new refresh token; the old one can't be used again. The user is
+// read fresh, so admin and player-link changes take effect. Reusing a
+// spent refresh token logs the user out everywhere.
+//
+// path: POST /api/auth/refresh
+func (r *Router) handleRefresh(w http.ResponseWriter, req *http.Request) {
+	var body RefreshRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+	next, nextHash, err := auth.NewRefreshToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	now := time.Now()
+	userID, err := r.store.RotateRefreshToken(req.Context(), auth.HashRefreshToken(body.RefreshToken), nextHash,
+		now, now.Add(r.auth.RefreshTokenDuration()))
+	if errors.Is(err, storage.ErrRefreshTokenReused) {
+		log.Printf("audit: refresh token reuse user_id=%d remote=%s; revoked all refresh tokens", userID, req.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if errors.Is(err, storage.ErrRefreshTokenInvalid) {
+		writeError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to refresh token")
+		return
+	}
+
+	user, err := r.store.GetUserByID(req.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	token, err := r.auth.GenerateToken(user.ID, user.Username, user.IsAdmin, user.PlayerID, user.PasswordChangeRequired)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	writeJSON(w, http.StatusOK, LoginResponse{
+		Token:                  token,
+		Username:               user.Username,
+		IsAdmin:                user.IsAdmin,
+		PlayerID:               user.PlayerID,
+		PasswordChangeRequired: user.PasswordChangeRequired,
+		RefreshToken:           next,
+	})
+}
+
+// handleLogout revokes the refresh token in the body, if any. The
+// access token is left to expire; the client discards it.
 func (r *Router) handleLogout(w http.ResponseWriter, req *http.Request) {
+	var body RefreshRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err == nil && body.RefreshToken != "" {
+		if err := r.store.RevokeRefreshToken(req.Context(), auth.HashRefreshToken(body.RefreshToken), time.Now()); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to revoke refresh token")
+			return
+		}
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -81,13 +172,17 @@ func (r *Router) handleAuthCheck(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"authenticated":            true,
 		"username":                 claims.Username,
 		"is_admin":                 claims.IsAdmin,
 		"player_id":                claims.PlayerID,
 		"password_change_required": claims.PasswordChangeRequired,
-	})
+	}
+	if claims.ImpersonatedBy != 0 {
+		resp["impersonated_by"] = claims.ImpersonatedBy
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // requireAuth is middleware that validates JWT before calling the handler
@@ -118,7 +213,8 @@ func (r *Router) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// getAuthClaims extracts and validates JWT from Authorization header
+// getAuthClaims extracts and validates JWT from Authorization header.
+// An API key in its place stands in for a login by the key's user.
 func (r *Router) getAuthClaims(req *http.Request) *auth.Claims {
 	authHeader := req.Header.Get("Authorization")
 	if !strings.HasPrefix(authHeader, "Bearer ") {
@@ -126,21 +222,58 @@ func (r *Router) getAuthClaims(req *http.Request) *auth.Claims {
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if strings.HasPrefix(token, auth.APIKeyPrefix) {
+		return r.apiKeyClaims(req, token)
+	}
 	claims, err := r.auth.ValidateToken(token)
 	if err != nil {
 		return nil
 	}
+	if claims.ImpersonatedBy != 0 && !impersonationAllows(req) {
+		return nil
+	}
+	if r.tokenRevoked(req.Context(), claims) {
+		return nil
+	}
 
 	return claims
 }
 
+// tokenRevoked reports whether claims were issued no later than the
+// last force-logout of their user, or of the admin behind an
+// impersonation. Both times are kept to the millisecond, so only a
+// token from the same millisecond as the revocation is caught with it.
+func (r *Router) tokenRevoked(ctx context.Context, claims *auth.Claims) bool {
+	if claims.IssuedAt == nil {
+		return true
+	}
+	for _, userID := range []int64{claims.UserID, claims.ImpersonatedBy} {
+		if userID == 0 {
+			continue
+		}
+		revokedAt, err := r.store.TokensRevokedAt(ctx, userID)
+		if err != nil {
+			return true
+		}
+		if !revokedAt.IsZero() && !claims.IssuedAt.Time.After(revokedAt) {
+			return true
+		}
+	}
+	return false
+}
+
 // ChangePasswordRequest is the request body for password change
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password"`
 	NewPassword     string `json:"new_password"`
+	// ReauthToken stands in for CurrentPassword on an account that has
+	// none; see handleOAuthReauth.
+	ReauthToken string `json:"reauth_token"`
 }
 
-// handleChangePassword allows users to change their own password
+// handleChangePassword allows users to change their own password.
+// Every other session is logged out, and the caller gets a new token
+// and refresh token in place of theirs.
 func (r *Router) handleChangePassword(w http.ResponseWriter, req *http.Request) {
 	claims := r.getAuthClaims(req)
 	if claims == nil {
@@ -166,11 +299,21 @@ func (r *Router) handleChangePassword(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	if !auth.CheckPassword(body.CurrentPassword, user.PasswordHash) {
+	// Accounts made by signing in with a provider have no password to
+	// confirm yet, so they sign in with the provider again instead
+	if user.PasswordHash == auth.NoPasswordHash {
+		if !r.reauthGrants.take(body.ReauthToken, user.ID) {
+			writeError(w, http.StatusUnauthorized, "sign in again with your provider to set a password")
+			return
+		}
+	} else if !auth.CheckPassword(body.CurrentPassword, user.PasswordHash) {
 		writeError(w, http.StatusUnauthorized, "current password is incorrect")
 		return
 	}
 
+	// Taken before hashing, so the new token is issued well after it.
+	now := time.Now()
+
 	// Hash and update new password
 	hash, err := auth.HashPassword(body.NewPassword)
 	if err != nil {
@@ -182,6 +325,10 @@ func (r *Router) handleChangePassword(w http.ResponseWriter, req *http.Request)
 		writeError(w, http.StatusInternalServerError, "failed to update password")
 		return
 	}
+	if err := r.store.RevokeUserTokens(req.Context(), user.ID, now); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
 
 	// Generate new token with updated password_change_required = false
 	newToken, err := r.auth.GenerateToken(user.ID, user.Username, user.IsAdmin, user.PlayerID, false)
@@ -189,10 +336,16 @@ func (r *Router) handleChangePassword(w http.ResponseWriter, req *http.Request)
 		writeError(w, http.StatusInternalServerError, "failed to generate new token")
 		return
 	}
+	refresh, err := r.issueRefreshToken(req.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate new token")
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "password changed successfully",
-		"token":   newToken,
+		"message":       "password changed successfully",
+		"token":         newToken,
+		"refresh_token": refresh,
 	})
 }
 
@@ -330,7 +483,8 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
-// handleResetUserPassword resets a user's password (admin only)
+// handleResetUserPassword resets a user's password (admin only) and
+// logs them out everywhere, as handleRevokeUserTokens does.
 func (r *Router) handleResetUserPassword(w http.ResponseWriter, req *http.Request) {
 	userID, err := parseID(req, "id")
 	if err != nil {
@@ -359,10 +513,59 @@ func (r *Router) handleResetUserPassword(w http.ResponseWriter, req *http.Reques
 		writeError(w, http.StatusInternalServerError, "failed to reset password")
 		return
 	}
+	// A reset is usually for a compromised account, so whoever has
+	// it is logged out too.
+	now := time.Now()
+	if err := r.store.RevokeUserTokens(req.Context(), userID, now); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+	if err := r.store.RevokeUserAPIKeys(req.Context(), userID, now); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke API keys")
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"message": "password reset"})
 }
 
+// handleRevokeUserTokens logs a user out everywhere (admin only):
+// their refresh tokens and API keys are revoked and access tokens
+// already issued stop working.
+//
+// path: POST /api/users/{id}/revoke-tokens
+func (r *Router) handleRevokeUserTokens(w http.ResponseWriter, req *http.Request) {
+	userID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user, err := r.store.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+	now := time.Now()
+	if err := r.store.RevokeUserTokens(req.Context(), user.ID, now); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+	if err := r.store.RevokeUserAPIKeys(req.Context(), user.ID, now); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to revoke API keys")
+		return
+	}
+	claims := r.getAuthClaims(req)
+	actor := "unknown"
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: revoke_tokens user=%q user_id=%d actor=%s remote=%s", user.Username, user.ID, actor, req.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "user logged out everywhere"})
+}
+
 // UpdateUserRequest is the request body for updating user properties
 type UpdateUserRequest struct {
 	IsAdmin  *bool  `json:"is_admin,omitempty"`
@@ -425,6 +628,9 @@ type AccountProfileResponse struct {
 	User   UserResponse        `json:"user"`
 	Player *domain.Player      `json:"player,omitempty"`
 	GUIDs  []domain.PlayerGUID `json:"guids,omitempty"`
+	// HasPassword is false for accounts created by signing in with a
+	// provider, until they set one
+	HasPassword bool `json:"has_password"`
 }
 
 // handleGetAccountProfile returns the current user's profile with linked player info
@@ -451,6 +657,7 @@ func (r *Router) handleGetAccountProfile(w http.ResponseWriter, req *http.Reques
 			CreatedAt:              user.CreatedAt,
 			LastLogin:              user.LastLogin,
 		},
+		HasPassword: user.PasswordHash != auth.NoPasswordHash,
 	}
 
 	// If user has linked player, fetch player profile and GUIDs
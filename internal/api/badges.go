@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Badge colours, as shields.io names them.
+const (
+	badgeBrightGreen = "#4c1"
+	badgeGreen       = "#97ca00"
+	badgeYellow      = "#dfb317"
+	badgeOrange      = "#fe7d37"
+	badgeRed         = "#e05d44"
+	badgeBlue        = "#007ec6"
+	badgeGrey        = "#9f9f9f"
+)
+
+// Badges are embedded in forum signatures and profile READMEs, whose
+// image proxies honour Cache-Control; players' stats move slowly, a
+// server's population doesn't.
+const (
+	playerBadgeCache = "public, max-age=300"
+	serverBadgeCache = "public, max-age=30"
+)
+
+// playerBadge is a stat a player badge can show. hiddenBy is the
+// features.hidden_stats entry that takes it away.
+type playerBadge struct {
+	label    string
+	hiddenBy string
+}
+
+var playerBadges = map[string]playerBadge{
+	"kd":       {"K/D", "kd_ratio"},
+	"frags":    {"frags", ""},
+	"matches":  {"matches", ""},
+	"wins":     {"wins", "victories"},
+	"captures": {"captures", "captures"},
+}
+
+// handlePlayerBadge draws one of a player's stats as an SVG badge:
+// kd, frags, matches, wins or captures. ?period= is as for
+// /api/players/{id}/stats and ?label= replaces the left-hand text.
+//
+// path: GET /badge/player/{id}/{stat}.svg
+func (r *Router) handlePlayerBadge(w http.ResponseWriter, req *http.Request) {
+	name, ok := strings.CutSuffix(req.PathValue("file"), ".svg")
+	badge, known := playerBadges[name]
+	if !ok || !known {
+		writeBadge(w, http.StatusNotFound, "", "badge", "unknown", badgeGrey)
+		return
+	}
+	label := badgeLabel(req, badge.label)
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeBadge(w, http.StatusBadRequest, "", label, "invalid player", badgeGrey)
+		return
+	}
+	if badge.hiddenBy != "" && r.hiddenCategories[badge.hiddenBy] {
+		writeBadge(w, http.StatusNotFound, "", label, "hidden", badgeGrey)
+		return
+	}
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeBadge(w, http.StatusBadRequest, "", label, "invalid period", badgeGrey)
+		return
+	}
+
+	resp, err := r.store.GetPlayerStatsByID(req.Context(), id, period)
+	if err != nil {
+		writeBadge(w, http.StatusNotFound, "", label, "not found", badgeGrey)
+		return
+	}
+	stats := resp.Stats
+	value, color := "", badgeBlue
+	switch name {
+	case "kd":
+		value = strconv.FormatFloat(stats.KDRatio, 'f', 2, 64)
+		switch {
+		case stats.KDRatio >= 2:
+			color = badgeBrightGreen
+		case stats.KDRatio >= 1:
+			color = badgeGreen
+		case stats.KDRatio >= 0.5:
+			color = badgeYellow
+		default:
+			color = badgeOrange
+		}
+	case "frags":
+		value = strconv.FormatInt(stats.Frags, 10)
+	case "matches":
+		value = strconv.FormatInt(stats.Matches, 10)
+	case "wins":
+		value = strconv.FormatInt(stats.Victories, 10)
+	case "captures":
+		value = strconv.FormatInt(stats.Captures, 10)
+	}
+	writeBadge(w, http.StatusOK, playerBadgeCache, label, value, color)
+}
+
+// handleServerBadge draws a server's live state as an SVG badge:
+// players (humans over slots) or map. ?label= replaces the left-hand
+// text.
+//
+// path: GET /badge/server/{id}/{stat}.svg
+func (r *Router) handleServerBadge(w http.ResponseWriter, req *http.Request) {
+	name, ok := strings.CutSuffix(req.PathValue("file"), ".svg")
+	if !ok || (name != "players" && name != "map") {
+		writeBadge(w, http.StatusNotFound, "", "badge", "unknown", badgeGrey)
+		return
+	}
+	label := badgeLabel(req, name)
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeBadge(w, http.StatusBadRequest, "", label, "invalid server", badgeGrey)
+		return
+	}
+	if _, err := r.store.GetServerByID(req.Context(), id); err != nil {
+		writeBadge(w, http.StatusNotFound, "", label, "not found", badgeGrey)
+		return
+	}
+
+	status := r.lookupServerStatus(id)
+	if status == nil || !status.Online {
+		writeBadge(w, http.StatusOK, serverBadgeCache, label, "offline", badgeRed)
+		return
+	}
+	if name == "map" {
+		writeBadge(w, http.StatusOK, serverBadgeCache, label, status.Map, badgeBlue)
+		return
+	}
+	color := badgeGrey
+	if status.HumanCount > 0 {
+		color = badgeBrightGreen
+	}
+	value := fmt.Sprintf("%d/%d", status.HumanCount, status.MaxClients)
+	writeBadge(w, http.StatusOK, serverBadgeCache, label, value, color)
+}
+
+// badgeLabel returns ?label= if given, else def.
+func badgeLabel(req *http.Request, def string) string {
+	if label := req.URL.Query().Get("label"); label != "" {
+		return label
+	}
+	return def
+}
+
+// writeBadge writes a flat shields.io-style badge. Errors are drawn as
+// badges too, so an embed shows what went wrong instead of a broken
+// image; they go out uncached (cache == "").
+func writeBadge(w http.ResponseWriter, status int, cache, label, value, color string) {
+	const pad = 10
+	lw := badgeTextWidth(label) + pad
+	vw := badgeTextWidth(value) + pad
+	width := lw + vw
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	if cache == "" {
+		cache = "no-cache"
+	}
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", cache)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+
+		`<title>%s: %s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`+
+		`<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`+
+		`</g></svg>`,
+		width, label, value,
+		label, value,
+		width,
+		lw, lw, vw, color, width,
+		float64(lw)/2, label, float64(lw)/2, label,
+		float64(lw)+float64(vw)/2, value, float64(lw)+float64(vw)/2, value)
+}
+
+// badgeTextWidth estimates s's width in 11px Verdana, close enough to
+// size a badge without shipping font metrics.
+func badgeTextWidth(s string) int {
+	var width float64
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("iljI.,:;!|'", c):
+			width += 3.5
+		case strings.ContainsRune("frt()[]/ ", c):
+			width += 4.5
+		case strings.ContainsRune("mwMW", c):
+			width += 10.5
+		case c >= 'A' && c <= 'Z':
+			width += 7.5
+		default:
+			width += 7
+		}
+	}
+	return int(width + 0.5)
+}
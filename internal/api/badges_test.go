@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestBadges(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	tr.r.SetHiddenStats([]string{"victories"})
+	pg, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	player := "/badge/player/" + strconv.FormatInt(pg.PlayerID, 10)
+	server := "/badge/server/" + strconv.FormatInt(srv.ID, 10)
+
+	for _, tc := range []struct {
+		path  string
+		code  int
+		cache string
+		want  string
+	}{
+		{player + "/kd.svg", http.StatusOK, playerBadgeCache, "K/D: 0.00"},
+		{player + "/frags.svg?label=alice%20%26%20co", http.StatusOK, playerBadgeCache, "alice &amp; co: 0"},
+		{player + "/wins.svg", http.StatusNotFound, "no-cache", "wins: hidden"},
+		{player + "/kd.svg?period=decade", http.StatusBadRequest, "no-cache", "invalid period"},
+		{player + "/kd.png", http.StatusNotFound, "no-cache", "badge: unknown"},
+		{"/badge/player/999/kd.svg", http.StatusNotFound, "no-cache", "K/D: not found"},
+		{server + "/players.svg", http.StatusOK, serverBadgeCache, "players: offline"},
+		{"/badge/server/999/map.svg", http.StatusNotFound, "no-cache", "map: not found"},
+	} {
+		w := tr.do("GET", tc.path, "", "")
+		if w.Code != tc.code {
+			t.Errorf("%s: %d, want %d", tc.path, w.Code, tc.code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+			t.Errorf("%s: content type %q", tc.path, ct)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != tc.cache {
+			t.Errorf("%s: cache %q, want %q", tc.path, cc, tc.cache)
+		}
+		if !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: body missing %q:\n%s", tc.path, tc.want, w.Body)
+		}
+	}
+}
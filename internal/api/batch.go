@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// batchMaxIDs caps how many ids one batch lookup may ask for.
+const batchMaxIDs = 200
+
+// BatchRequest is the request body for the batch lookups
+type BatchRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// parseBatchIDs decodes a BatchRequest and returns its ids, sorted
+// and deduplicated.
+func parseBatchIDs(req *http.Request) ([]int64, error) {
+	var body BatchRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid request body")
+	}
+	ids := slices.Compact(slices.Sorted(slices.Values(body.IDs)))
+	if len(ids) > batchMaxIDs {
+		return nil, fmt.Errorf("at most %d ids per request", batchMaxIDs)
+	}
+	return ids, nil
+}
+
+// handleBatchPlayers returns compact records for up to 200 players,
+// so a feed can resolve every name and portrait in one request.
+// Unknown ids are left out.
+//
+// path: POST /api/players/batch
+func (r *Router) handleBatchPlayers(w http.ResponseWriter, req *http.Request) {
+	ids, err := parseBatchIDs(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	players, err := r.store.GetPlayerBriefs(req.Context(), ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get players")
+		return
+	}
+	writeJSON(w, http.StatusOK, players)
+}
+
+// handleBatchMatches is handleBatchPlayers for matches: map, game type,
+// times and team scores, without the scoreboard.
+//
+// path: POST /api/matches/batch
+func (r *Router) handleBatchMatches(w http.ResponseWriter, req *http.Request) {
+	ids, err := parseBatchIDs(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matches, err := r.store.GetMatchBriefs(req.Context(), ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get matches")
+		return
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
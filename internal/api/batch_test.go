@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestBatchLookups(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+
+	alice, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "^1alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := tr.store.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: time.Now().UTC()}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"ids":[%d,%d,%d,9999]}`, bob.PlayerID, alice.PlayerID, bob.PlayerID)
+	w := tr.do("POST", "/api/players/batch", body, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("players: %d %s", w.Code, w.Body)
+	}
+	var players []domain.PlayerBrief
+	if err := json.Unmarshal(w.Body.Bytes(), &players); err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 2 || players[0].ID != alice.PlayerID || players[0].Name != "^1alice" || players[1].CleanName != "bob" {
+		t.Errorf("players = %+v", players)
+	}
+
+	w = tr.do("POST", "/api/matches/batch", fmt.Sprintf(`{"ids":[%d,9999]}`, m.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("matches: %d %s", w.Code, w.Body)
+	}
+	var matches []domain.MatchBrief
+	if err := json.Unmarshal(w.Body.Bytes(), &matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].MapName != "q3dm17" || matches[0].ServerKey != "ffa" || matches[0].EndedAt != nil {
+		t.Errorf("matches = %+v", matches)
+	}
+
+	ids := make([]string, batchMaxIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+	if w := tr.do("POST", "/api/players/batch", `{"ids":[`+strings.Join(ids, ",")+`]}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("too many ids: %d, want 400", w.Code)
+	}
+	if w := tr.do("POST", "/api/matches/batch", `{"ids":"1"}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("bad body: %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import "net/http"
+
+// Branding is the operator's community identity, served at
+// /api/config/branding so the SPA can swap in its own name, logo,
+// colors, and links at load time. Empty fields mean "keep the stock
+// look".
+type Branding struct {
+	SiteName       string         `json:"site_name,omitempty"`
+	LogoURL        string         `json:"logo_url,omitempty"`
+	Theme          *BrandingTheme `json:"theme,omitempty"`
+	FooterLinks    []BrandingLink `json:"footer_links"`
+	CommunityLinks []BrandingLink `json:"community_links"`
+}
+
+// BrandingTheme overrides the SPA's base palette (CSS hex colors).
+type BrandingTheme struct {
+	Background string `json:"background,omitempty"`
+	Card       string `json:"card,omitempty"`
+	Text       string `json:"text,omitempty"`
+	Accent     string `json:"accent,omitempty"`
+}
+
+// BrandingLink is one labelled link in the footer or header.
+type BrandingLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// SetBranding applies the operator's branding. main.go fills it from
+// config.BrandingConfig, which has already been validated.
+func (r *Router) SetBranding(b Branding) {
+	if b.FooterLinks == nil {
+		b.FooterLinks = []BrandingLink{}
+	}
+	if b.CommunityLinks == nil {
+		b.CommunityLinks = []BrandingLink{}
+	}
+	r.branding = b
+}
+
+// handleGetBranding reports the site branding.
+//
+// path: GET /api/config/branding
+func (r *Router) handleGetBranding(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.branding)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/hub"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// SetServerBrowser plugs in the master-server browser. Unset, the
+// browser endpoints return 503.
+func (r *Router) SetServerBrowser(b *hub.ServerBrowser) {
+	r.browser = b
+}
+
+// handleBrowseServers lists public servers from the master servers.
+// name matches part of the hostname, map and game_type match exactly,
+// min_players counts humans, and not_full=true leaves full servers
+// out. Servers the hub already tracks are marked.
+//
+// path: GET /api/admin/browser
+func (r *Router) handleBrowseServers(w http.ResponseWriter, req *http.Request) {
+	if r.browser == nil {
+		writeError(w, http.StatusServiceUnavailable, "server browser not available")
+		return
+	}
+	q := req.URL.Query()
+	f := hub.BrowserFilter{
+		Name:     q.Get("name"),
+		Map:      q.Get("map"),
+		GameType: q.Get("game_type"),
+	}
+	if v := q.Get("min_players"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid min_players")
+			return
+		}
+		f.MinPlayers = n
+	}
+	if v := q.Get("not_full"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid not_full")
+			return
+		}
+		f.NotFull = b
+	}
+
+	servers, err := r.browser.Browse(req.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "master servers: "+err.Error())
+		return
+	}
+	tracked, err := r.store.ServerAddresses(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range servers {
+		servers[i].Tracked = tracked[servers[i].Address]
+	}
+	writeJSON(w, http.StatusOK, servers)
+}
+
+// handleTrackExternalServer starts polling a server the hub doesn't
+// run, usually one picked from the browser. It's added query-only:
+// live status and population, no matches. key defaults to one made
+// from the address.
+//
+// path: POST /api/admin/browser/track
+func (r *Router) handleTrackExternalServer(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Address string `json:"address"`
+		Key     string `json:"key"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	address := strings.TrimSpace(body.Address)
+	key, err := externalServerKey(strings.TrimSpace(body.Key), address)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	server, err := r.store.AddExternalServer(req.Context(), key, address)
+	switch {
+	case errors.Is(err, storage.ErrServerTracked), errors.Is(err, storage.ErrServerKeyTaken):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	actor := "unknown"
+	if claims := r.getAuthClaims(req); claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: server_track server=%d key=%s address=%s actor=%s remote=%s",
+		server.ID, server.Key, server.Address, actor, req.RemoteAddr)
+	writeJSON(w, http.StatusCreated, server)
+}
+
+// handleGetServerPopulation returns a query-only server's population
+// by hour. Defaults to the last week.
+//
+// path: GET /api/servers/{id}/population
+func (r *Router) handleGetServerPopulation(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	response, err := r.store.GetServerPopulation(req.Context(), id, period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
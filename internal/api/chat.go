@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleGetMatchChat returns a match's chat in the order it was said.
+// Tells are private, so they're only included for admins. Empty when
+// chat persistence is off.
+//
+// path: GET /api/matches/{id}/chat
+func (r *Router) handleGetMatchChat(w http.ResponseWriter, req *http.Request) {
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	if !r.features.ChatPersistence {
+		writeJSON(w, http.StatusOK, []domain.ChatLine{})
+		return
+	}
+	claims := r.getAuthClaims(req)
+	lines, err := r.store.GetMatchChat(req.Context(), matchID, claims != nil && claims.IsAdmin)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, lines)
+}
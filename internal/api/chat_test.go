@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestGetMatchChatHidesTellsFromNonAdmins(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	start := time.Now().Add(-time.Hour).UTC()
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: start}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	lines := []domain.ChatLine{
+		{Kind: domain.ChatSay, Name: "alice", Message: "gl hf", SentAt: start.Add(time.Second)},
+		{Kind: domain.ChatTell, Name: "alice", ToName: "bob", Message: "psst", SentAt: start.Add(2 * time.Second)},
+		{Kind: domain.ChatSayTeam, Name: "bob", Team: 1, Message: "rail on mid", SentAt: start.Add(3 * time.Second)},
+	}
+	for _, l := range lines {
+		if err := tr.store.InsertMatchChat(ctx, m.ID, l); err != nil {
+			t.Fatalf("InsertMatchChat: %v", err)
+		}
+	}
+	path := "/api/matches/" + strconv.FormatInt(m.ID, 10) + "/chat"
+
+	get := func(token string) []domain.ChatLine {
+		t.Helper()
+		w := tr.do("GET", path, "", token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET chat: %d %s", w.Code, w.Body.String())
+		}
+		var got []domain.ChatLine
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return got
+	}
+
+	if got := get(""); len(got) != 0 {
+		t.Errorf("feature off: got %d lines, want 0", len(got))
+	}
+
+	tr.r.SetFeatures(Features{ChatPersistence: true})
+	if got := get(""); len(got) != 2 || got[0].Message != "gl hf" || got[1].Message != "rail on mid" {
+		t.Errorf("anonymous: got %+v, want say and say_team only", got)
+	}
+	userToken, _ := tr.loginAs(t, "user", false)
+	if got := get(userToken); len(got) != 2 {
+		t.Errorf("user: got %d lines, want 2", len(got))
+	}
+	adminToken, _ := tr.loginAs(t, "admin", true)
+	if got := get(adminToken); len(got) != 3 || got[1].Kind != domain.ChatTell || got[1].ToName != "bob" {
+		t.Errorf("admin: got %+v, want all three in order", got)
+	}
+}
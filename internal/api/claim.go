@@ -62,7 +62,7 @@ func (r *Router) handleClaimValidate(w http.ResponseWriter, req *http.Request) {
 		response.Stats = &stats.Stats
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	r.writeStatsJSON(w, http.StatusOK, response)
 }
 
 // ClaimRegisterRequest is the request body for registering via claim code
@@ -75,6 +75,11 @@ type ClaimRegisterRequest struct {
 
 // handleClaimRegister creates a new account and claims the player
 func (r *Router) handleClaimRegister(w http.ResponseWriter, req *http.Request) {
+	if !r.features.Registration {
+		writeError(w, http.StatusForbidden, "registration is closed on this hub")
+		return
+	}
+
 	var body ClaimRegisterRequest
 	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -136,12 +141,18 @@ func (r *Router) handleClaimRegister(w http.ResponseWriter, req *http.Request) {
 		writeError(w, http.StatusInternalServerError, "account created but failed to generate token")
 		return
 	}
+	refresh, err := r.issueRefreshToken(req.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "account created but failed to generate token")
+		return
+	}
 
 	writeJSON(w, http.StatusCreated, LoginResponse{
-		Token:    token,
-		Username: body.Username,
-		IsAdmin:  false,
-		PlayerID: &claimCode.PlayerID,
+		Token:        token,
+		Username:     body.Username,
+		IsAdmin:      false,
+		PlayerID:     &claimCode.PlayerID,
+		RefreshToken: refresh,
 	})
 }
 
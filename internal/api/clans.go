@@ -0,0 +1,396 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// Clan tags shorter than minClanTagLen would match too many names.
+const (
+	maxClanNameLen = 64
+	minClanTagLen  = 2
+	maxClanTagLen  = 16
+)
+
+func validateClan(name, tag string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxClanNameLen {
+		return fmt.Errorf("name must be 1 to %d characters", maxClanNameLen)
+	}
+	if n := utf8.RuneCountInString(tag); n < minClanTagLen || n > maxClanTagLen {
+		return fmt.Errorf("tag must be %d to %d characters", minClanTagLen, maxClanTagLen)
+	}
+	return nil
+}
+
+// writeClanError maps clan storage errors to responses.
+func writeClanError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "clan not found")
+	case errors.Is(err, storage.ErrAlreadyInClan):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrNotInClan):
+		writeError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "UNIQUE constraint"):
+		writeError(w, http.StatusConflict, "a clan with that name or tag already exists")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// clanManager reports whether the caller may change the clan: admins
+// and the clan's leader can. It writes the error response if not.
+func (r *Router) clanManager(w http.ResponseWriter, req *http.Request, clanID int64) bool {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return false
+	}
+	clan, err := r.store.GetClan(req.Context(), clanID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if clan == nil {
+		writeError(w, http.StatusNotFound, "clan not found")
+		return false
+	}
+	if claims.IsAdmin {
+		return true
+	}
+	user, err := r.store.GetUserByID(req.Context(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return false
+	}
+	if user.PlayerID != nil {
+		role, err := r.store.GetClanRole(req.Context(), clanID, *user.PlayerID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return false
+		}
+		if role == domain.ClanRoleLeader {
+			return true
+		}
+	}
+	writeError(w, http.StatusForbidden, "only the clan's leader or an admin can do that")
+	return false
+}
+
+// handleListClans lists clans, most members first.
+//
+// path: GET /api/clans
+func (r *Router) handleListClans(w http.ResponseWriter, req *http.Request) {
+	clans, err := r.store.ListClans(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, clans)
+}
+
+// handleGetClan returns a clan, its members and their summed stats
+// over ?period= (default all).
+//
+// path: GET /api/clans/{id}
+func (r *Router) handleGetClan(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+	resp, err := r.store.GetClanStats(req.Context(), id, period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if resp == nil {
+		writeError(w, http.StatusNotFound, "clan not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleGetClanMatches returns the clan's latest clan-vs-clan matches.
+// Membership is as it stands now, so a player who changed clans moves
+// their old matches with them.
+//
+// path: GET /api/clans/{id}/matches
+func (r *Router) handleGetClanMatches(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	limit := parseLimit(req, 20, 100)
+	matches, err := r.store.GetClanMatches(req.Context(), id, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
+
+// handleGetClanLeaderboard ranks clans by ?category= (frags, kd_ratio,
+// matches, victories, captures or clan_wins) over ?period=.
+//
+// path: GET /api/clans/leaderboard
+func (r *Router) handleGetClanLeaderboard(w http.ResponseWriter, req *http.Request) {
+	category := req.URL.Query().Get("category")
+	if category == "" {
+		category = "frags"
+	}
+	if !storage.ValidClanCategory(category) {
+		writeError(w, http.StatusBadRequest, "invalid category")
+		return
+	}
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+	limit := parseLimit(req, 25, 100)
+	resp, err := r.store.GetClanLeaderboard(req.Context(), category, period, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type clanRequest struct {
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+// handleCreateClan registers a clan led by the caller's linked player.
+// Admins can create one without a linked player, leaving it leaderless.
+//
+// path: POST /api/clans
+func (r *Router) handleCreateClan(w http.ResponseWriter, req *http.Request) {
+	var body clanRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	body.Tag = strings.TrimSpace(body.Tag)
+	if err := validateClan(body.Name, body.Tag); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims := r.getAuthClaims(req)
+	clan := &domain.Clan{Name: body.Name, Tag: body.Tag, CreatedBy: &claims.UserID}
+	var leaderID *int64
+	if claims.IsAdmin {
+		user, err := r.store.GetUserByID(req.Context(), claims.UserID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to get user")
+			return
+		}
+		leaderID = user.PlayerID
+	} else {
+		_, playerID, ok := r.accountPlayer(w, req)
+		if !ok {
+			return
+		}
+		leaderID = &playerID
+	}
+	if err := r.store.CreateClan(req.Context(), clan, leaderID); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, clan)
+}
+
+// handleUpdateClan renames a clan or changes its tag; fields left out
+// keep their values. Leader or admin only.
+//
+// path: PATCH /api/clans/{id}
+func (r *Router) handleUpdateClan(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	if !r.clanManager(w, req, id) {
+		return
+	}
+	clan, err := r.store.GetClan(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	body := clanRequest{Name: clan.Name, Tag: clan.Tag}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	body.Tag = strings.TrimSpace(body.Tag)
+	if err := validateClan(body.Name, body.Tag); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := r.store.UpdateClan(req.Context(), id, body.Name, body.Tag); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	if body.Tag != clan.Tag {
+		if _, err := r.store.DetectClanMembers(req.Context(), time.Now().UTC()); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if clan, err = r.store.GetClan(req.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, clan)
+}
+
+// handleDeleteClan disbands a clan. Leader or admin only.
+//
+// path: DELETE /api/clans/{id}
+func (r *Router) handleDeleteClan(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	if !r.clanManager(w, req, id) {
+		return
+	}
+	if err := r.store.DeleteClan(req.Context(), id); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleJoinClan adds the caller's linked player to a clan.
+//
+// path: POST /api/clans/{id}/join
+func (r *Router) handleJoinClan(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	if clan, err := r.store.GetClan(req.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if clan == nil {
+		writeError(w, http.StatusNotFound, "clan not found")
+		return
+	}
+	if err := r.store.JoinClan(req.Context(), id, playerID, domain.ClanSourceJoined, time.Now().UTC()); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLeaveClan takes the caller's linked player out of a clan.
+//
+// path: DELETE /api/clans/{id}/members/me
+func (r *Router) handleLeaveClan(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	if err := r.store.LeaveClan(req.Context(), id, playerID, time.Now().UTC()); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddClanMember adds {"player_id": n} to a clan. Leader or admin
+// only.
+//
+// path: POST /api/clans/{id}/members
+func (r *Router) handleAddClanMember(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	var body struct {
+		PlayerID int64 `json:"player_id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !r.clanManager(w, req, id) {
+		return
+	}
+	if _, err := r.store.GetPlayerByID(req.Context(), body.PlayerID); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "player not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := r.store.JoinClan(req.Context(), id, body.PlayerID, domain.ClanSourceAdded, time.Now().UTC()); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRemoveClanMember removes a player from a clan. Leader or admin
+// only.
+//
+// path: DELETE /api/clans/{id}/members/{player_id}
+func (r *Router) handleRemoveClanMember(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clan id")
+		return
+	}
+	playerID, err := parseID(req, "player_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	if !r.clanManager(w, req, id) {
+		return
+	}
+	if err := r.store.LeaveClan(req.Context(), id, playerID, time.Now().UTC()); err != nil {
+		writeClanError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
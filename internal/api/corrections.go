@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// handleListMatchCorrections lists the stat corrections made to a
+// match, oldest first.
+//
+// path: GET /api/admin/matches/{id}/corrections
+func (r *Router) handleListMatchCorrections(w http.ResponseWriter, req *http.Request) {
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	corrections, err := r.store.GetMatchStatCorrections(req.Context(), matchID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, corrections)
+}
+
+// handleCorrectMatchStats adjusts one player's stats in a match. The
+// body names the player, the new values, and a mandatory reason;
+// client_id is only needed when the player has more than one row in
+// the match. Responds with the corrections recorded (empty if every
+// value already matched).
+//
+// path: POST /api/admin/matches/{id}/corrections
+func (r *Router) handleCorrectMatchStats(w http.ResponseWriter, req *http.Request) {
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var body struct {
+		PlayerID int64             `json:"player_id"`
+		ClientID *int              `json:"client_id"`
+		Changes  map[string]*int64 `json:"changes"`
+		Reason   string            `json:"reason"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.PlayerID == 0 {
+		writeError(w, http.StatusBadRequest, "player_id required")
+		return
+	}
+	if body.Reason == "" {
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	if len(body.Changes) == 0 {
+		writeError(w, http.StatusBadRequest, "changes required")
+		return
+	}
+
+	changes := make([]storage.StatCorrection, 0, len(body.Changes))
+	for field, value := range body.Changes {
+		if !storage.CorrectableStatFields[field] {
+			writeError(w, http.StatusBadRequest, "cannot correct "+field+"; allowed: "+strings.Join(storage.CorrectableFieldNames(), ", "))
+			return
+		}
+		// Only score may be blank or negative (suicides take it below 0)
+		if field != "score" && (value == nil || *value < 0) {
+			writeError(w, http.StatusBadRequest, field+" must be a non-negative number")
+			return
+		}
+		changes = append(changes, storage.StatCorrection{Field: field, Value: value})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	corrections, err := r.store.CorrectMatchPlayerStats(req.Context(), matchID, body.PlayerID, body.ClientID, changes, body.Reason, claims.UserID, time.Now())
+	switch {
+	case errors.Is(err, storage.ErrCorrectionTargetNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, storage.ErrCorrectionAmbiguous), errors.Is(err, storage.ErrCorrectionMatchLive):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, corrections)
+}
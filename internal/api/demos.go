@@ -0,0 +1,332 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/demos"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// SetDemoLibrary turns on admin demo uploads, stored in lib. main.go
+// builds it from config.DemoUploadsConfig on hubs.
+func (r *Router) SetDemoLibrary(lib *demos.Library) {
+	r.demoLibrary = lib
+}
+
+// handleUploadMatchDemo stores a .dm_68 demo for a match, sent as the
+// "file" part of a multipart form. Older demos are evicted afterwards
+// if the upload pushed the total over quota.
+//
+// path: POST /api/admin/matches/{id}/demo
+func (r *Router) handleUploadMatchDemo(w http.ResponseWriter, req *http.Request) {
+	if r.demoLibrary == nil {
+		writeError(w, http.StatusServiceUnavailable, "demo uploads are not configured")
+		return
+	}
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if !r.matchExists(w, req, matchID) {
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself
+	req.Body = http.MaxBytesReader(w, req.Body, r.demoLibrary.MaxFileBytes+1<<20)
+	mr, err := req.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected a multipart form")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			writeError(w, http.StatusBadRequest, "file is required")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid multipart form")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		r.storeUploadedDemo(w, req, matchID, claims.UserID, part)
+		part.Close()
+		return
+	}
+}
+
+func (r *Router) storeUploadedDemo(w http.ResponseWriter, req *http.Request, matchID, userID int64, part io.Reader) {
+	name := ""
+	if p, ok := part.(interface{ FileName() string }); ok {
+		name = filepath.Base(p.FileName())
+	}
+	if !demos.HasExt(name) || len(name) > 255 {
+		writeError(w, http.StatusBadRequest, "file must be a "+demos.Ext+" demo")
+		return
+	}
+
+	demo, err := r.demoLibrary.Add(req.Context(), matchID, name, part, &userID, time.Now())
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig) || errors.Is(err, demos.ErrTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, demos.ErrTooLarge.Error())
+		return
+	case errors.Is(err, demos.ErrEmpty):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, demo)
+}
+
+// handleListMatchDemos lists a match's uploaded demos, newest first.
+//
+// path: GET /api/matches/{id}/demos
+func (r *Router) handleListMatchDemos(w http.ResponseWriter, req *http.Request) {
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	if !r.features.Demos {
+		writeJSON(w, http.StatusOK, []domain.MatchDemo{})
+		return
+	}
+	list, err := r.store.ListMatchDemos(req.Context(), matchID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range list {
+		list[i].URL = demos.URL(list[i].ID)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleDownloadDemo serves an uploaded demo. The URL ends in .dm_68 so
+// the web player's engine picks the right demo protocol from it.
+//
+// path: GET /api/demos/{file}
+func (r *Router) handleDownloadDemo(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(stripSuffix(req.PathValue("file"), demos.Ext), 10, 64)
+	if err != nil || !r.features.Demos || r.demoLibrary == nil {
+		http.NotFound(w, req)
+		return
+	}
+	demo, err := r.store.GetMatchDemo(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if demo == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if err := r.store.TouchMatchDemo(req.Context(), id, time.Now()); err != nil {
+		log.Printf("demos: touch %d: %v", id, err)
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(demo.Filename))
+	http.ServeFile(w, req, r.demoLibrary.Path(id))
+}
+
+// DemoListResponse is the response for GET /api/demos and
+// GET /api/account/demos. Total counts every demo the filters match,
+// not just this page.
+type DemoListResponse struct {
+	Total int                  `json:"total"`
+	Demos []domain.DemoListing `json:"demos"`
+}
+
+// handleBrowseDemos lists demos across matches, filtered by map,
+// player, game type, and match date, and sorted by date, size, or
+// match length.
+//
+// path: GET /api/demos
+func (r *Router) handleBrowseDemos(w http.ResponseWriter, req *http.Request) {
+	filter, ok := parseDemoFilter(w, req)
+	if !ok {
+		return
+	}
+	if p := req.URL.Query().Get("player_id"); p != "" {
+		id, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		filter.PlayerID = &id
+	}
+	r.writeDemoList(w, req, filter)
+}
+
+// handleListMyDemos is GET /api/demos for the caller's linked player:
+// the demos of matches they played in.
+//
+// path: GET /api/account/demos
+func (r *Router) handleListMyDemos(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	filter, ok := parseDemoFilter(w, req)
+	if !ok {
+		return
+	}
+	user, err := r.store.GetUserByID(req.Context(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+	if user.PlayerID == nil {
+		writeError(w, http.StatusBadRequest, "no player linked to this account")
+		return
+	}
+	filter.PlayerID = user.PlayerID
+	r.writeDemoList(w, req, filter)
+}
+
+// parseDemoFilter reads the query parameters shared by the demo
+// browsing endpoints, writing a 400 and returning false if one is bad.
+func parseDemoFilter(w http.ResponseWriter, req *http.Request) (storage.DemoFilter, bool) {
+	q := req.URL.Query()
+	filter := storage.DemoFilter{
+		MapName: q.Get("map"),
+		Limit:   parseLimit(req, 20, 100),
+		Offset:  parseOffset(req),
+	}
+	if gt := q.Get("game_type"); gt != "" {
+		if !validateGameType(gt) {
+			writeError(w, http.StatusBadRequest, "invalid game_type")
+			return filter, false
+		}
+		filter.GameType = gt
+	}
+	if sd := q.Get("start_date"); sd != "" {
+		t, err := time.Parse(time.RFC3339, sd)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid start_date format, use RFC3339")
+			return filter, false
+		}
+		filter.StartDate = &t
+	}
+	if ed := q.Get("end_date"); ed != "" {
+		t, err := time.Parse(time.RFC3339, ed)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid end_date format, use RFC3339")
+			return filter, false
+		}
+		filter.EndDate = &t
+	}
+	switch s := q.Get("sort"); s {
+	case "", storage.DemoSortDate, storage.DemoSortSize, storage.DemoSortLength:
+		filter.Sort = s
+	default:
+		writeError(w, http.StatusBadRequest, "sort must be date, size, or length")
+		return filter, false
+	}
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		writeError(w, http.StatusBadRequest, "order must be asc or desc")
+		return filter, false
+	}
+	return filter, true
+}
+
+func (r *Router) writeDemoList(w http.ResponseWriter, req *http.Request, filter storage.DemoFilter) {
+	if !r.features.Demos {
+		writeJSON(w, http.StatusOK, DemoListResponse{Demos: []domain.DemoListing{}})
+		return
+	}
+	list, total, err := r.store.ListDemos(req.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range list {
+		list[i].URL = demos.URL(list[i].ID)
+	}
+	writeJSON(w, http.StatusOK, DemoListResponse{Total: total, Demos: list})
+}
+
+// DemoStorageResponse is the response for GET /api/admin/demos
+type DemoStorageResponse struct {
+	TotalBytes int64              `json:"total_bytes"`
+	QuotaBytes int64              `json:"quota_bytes"`
+	Demos      []domain.MatchDemo `json:"demos"`
+}
+
+// handleListDemos lists recent uploads across all matches, with disk
+// usage against the quota.
+//
+// path: GET /api/admin/demos
+func (r *Router) handleListDemos(w http.ResponseWriter, req *http.Request) {
+	list, err := r.store.ListRecentDemos(req.Context(), parseLimit(req, 50, 500))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	total, err := r.store.DemoStorageBytes(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range list {
+		list[i].URL = demos.URL(list[i].ID)
+	}
+	resp := DemoStorageResponse{TotalBytes: total, Demos: list}
+	if r.demoLibrary != nil {
+		resp.QuotaBytes = r.demoLibrary.QuotaBytes
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDeleteDemo removes an uploaded demo and its file.
+//
+// path: DELETE /api/admin/demos/{id}
+func (r *Router) handleDeleteDemo(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid demo id")
+		return
+	}
+	demo, err := r.store.GetMatchDemo(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if demo == nil {
+		writeError(w, http.StatusNotFound, "demo not found")
+		return
+	}
+	if r.demoLibrary == nil {
+		err = r.store.DeleteMatchDemo(req.Context(), id)
+	} else {
+		err = r.demoLibrary.Remove(req.Context(), id)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/demos"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestUploadMatchDemo(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	tr.r.SetFeatures(Features{Demos: true})
+	tr.r.SetDemoLibrary(&demos.Library{Dir: t.TempDir(), MaxFileBytes: 100, QuotaBytes: 150, Store: tr.store})
+	token, _ := tr.loginAs(t, "admin", true)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: time.Now().Add(-time.Hour)}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	matchID := strconv.FormatInt(m.ID, 10)
+
+	upload := func(name string, size int) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		fw.Write(bytes.Repeat([]byte{'x'}, size))
+		mw.Close()
+		req := httptest.NewRequest("POST", "/api/admin/matches/"+matchID+"/demo", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		tr.r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := upload("match.dm_66", 10); w.Code != http.StatusBadRequest {
+		t.Errorf("wrong extension: got %d", w.Code)
+	}
+	if w := upload("match.dm_68", 101); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large: got %d", w.Code)
+	}
+
+	w := upload("first.dm_68", 80)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first upload: got %d, body=%s", w.Code, w.Body.String())
+	}
+	var first domain.MatchDemo
+	json.Unmarshal(w.Body.Bytes(), &first)
+
+	// The second upload pushes the total past quota, evicting the first.
+	w = upload("second.dm_68", 80)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("second upload: got %d, body=%s", w.Code, w.Body.String())
+	}
+	var second domain.MatchDemo
+	json.Unmarshal(w.Body.Bytes(), &second)
+	if _, err := os.Stat(tr.r.demoLibrary.Path(first.ID)); !os.IsNotExist(err) {
+		t.Errorf("first demo file should be evicted, stat err=%v", err)
+	}
+	if d, _ := tr.store.GetMatchDemo(ctx, first.ID); d != nil {
+		t.Errorf("first demo row should be evicted")
+	}
+
+	w = tr.do("GET", "/api/matches/"+matchID, "", "")
+	var summary domain.MatchSummary
+	json.Unmarshal(w.Body.Bytes(), &summary)
+	if summary.DemoURL != second.URL || second.URL != demos.URL(second.ID) {
+		t.Errorf("demo url: got %q, want %q", summary.DemoURL, second.URL)
+	}
+
+	w = tr.do("GET", second.URL, "", "")
+	if w.Code != http.StatusOK || w.Body.Len() != 80 {
+		t.Fatalf("download: got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if d, _ := tr.store.GetMatchDemo(ctx, second.ID); d == nil || d.LastDownloadedAt == nil {
+		t.Errorf("download should be recorded: got %+v", d)
+	}
+
+	if w := tr.do("DELETE", "/api/admin/demos/"+strconv.FormatInt(second.ID, 10), "", token); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d", w.Code)
+	}
+	if w := tr.do("GET", second.URL, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("download after delete: got %d", w.Code)
+	}
+}
+
+func TestBrowseDemos(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	tr.r.SetFeatures(Features{Demos: true})
+	token, userID := tr.loginAs(t, "alice", false)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	start := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+	// A short q3dm17 match with alice in it, and a longer ctf match without.
+	matches := []struct {
+		uuid, mapName, gameType string
+		length                  time.Duration
+		size                    int64
+		withAlice               bool
+	}{
+		{"m1", "q3dm17", "ffa", 5 * time.Minute, 300, true},
+		{"m2", "q3ctf1", "ctf", 20 * time.Minute, 100, false},
+	}
+	demoIDs := make([]int64, len(matches))
+	for i, mm := range matches {
+		m := &domain.Match{UUID: mm.uuid, ServerID: srv.ID, MapName: mm.mapName, GameType: mm.gameType, StartedAt: start.Add(time.Duration(i) * time.Hour)}
+		if err := tr.store.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if mm.withAlice {
+			if err := tr.store.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 20, 4, true, nil, nil, "", 0, true,
+				0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := tr.store.EndMatch(ctx, m.ID, m.StartedAt.Add(mm.length), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+		d, err := tr.store.CreateMatchDemo(ctx, m.ID, mm.uuid+".dm_68", mm.size, nil, m.StartedAt.Add(mm.length))
+		if err != nil {
+			t.Fatalf("CreateMatchDemo: %v", err)
+		}
+		demoIDs[i] = d.ID
+	}
+
+	list := func(path, tok string) DemoListResponse {
+		t.Helper()
+		w := tr.do("GET", path, "", tok)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+		}
+		var resp DemoListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	ids := func(resp DemoListResponse) []int64 {
+		out := []int64{}
+		for _, d := range resp.Demos {
+			out = append(out, d.ID)
+		}
+		return out
+	}
+
+	resp := list("/api/demos", "")
+	if resp.Total != 2 || len(resp.Demos) != 2 || resp.Demos[0].ID != demoIDs[1] {
+		t.Fatalf("default: got total=%d ids=%v, want newest match first", resp.Total, ids(resp))
+	}
+	if d := resp.Demos[0]; d.MapName != "q3ctf1" || d.DurationSeconds != 1200 || d.URL != demos.URL(d.ID) || d.ServerKey != "ffa" {
+		t.Errorf("metadata: got %+v", d)
+	}
+	if d := resp.Demos[1]; d.PlayerCount != 1 {
+		t.Errorf("player_count: got %d, want 1", d.PlayerCount)
+	}
+
+	if resp := list("/api/demos?sort=size", ""); ids(resp)[0] != demoIDs[0] {
+		t.Errorf("sort=size: got %v", ids(resp))
+	}
+	if resp := list("/api/demos?sort=length&order=asc", ""); ids(resp)[0] != demoIDs[0] {
+		t.Errorf("sort=length&order=asc: got %v", ids(resp))
+	}
+	if resp := list("/api/demos?map=Q3CTF1", ""); resp.Total != 1 || ids(resp)[0] != demoIDs[1] {
+		t.Errorf("map filter: got total=%d ids=%v", resp.Total, ids(resp))
+	}
+	if resp := list("/api/demos?game_type=ffa", ""); resp.Total != 1 || ids(resp)[0] != demoIDs[0] {
+		t.Errorf("game_type filter: got total=%d ids=%v", resp.Total, ids(resp))
+	}
+	if resp := list("/api/demos?player_id="+strconv.FormatInt(alice.PlayerID, 10), ""); resp.Total != 1 || ids(resp)[0] != demoIDs[0] {
+		t.Errorf("player filter: got total=%d ids=%v", resp.Total, ids(resp))
+	}
+	if resp := list("/api/demos?limit=1&offset=1", ""); resp.Total != 2 || len(resp.Demos) != 1 || resp.Demos[0].ID != demoIDs[0] {
+		t.Errorf("paging: got total=%d ids=%v", resp.Total, ids(resp))
+	}
+	if resp := list("/api/demos?start_date="+start.Add(30*time.Minute).Format(time.RFC3339), ""); resp.Total != 1 {
+		t.Errorf("start_date filter: got total=%d", resp.Total)
+	}
+	for _, q := range []string{"sort=fame", "order=up", "game_type=nope", "start_date=yesterday", "player_id=x"} {
+		if w := tr.do("GET", "/api/demos?"+q, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: code = %d, want 400", q, w.Code)
+		}
+	}
+
+	// Demos featuring me needs a linked player.
+	if w := tr.do("GET", "/api/account/demos", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous mine: code = %d, want 401", w.Code)
+	}
+	if w := tr.do("GET", "/api/account/demos", "", token); w.Code != http.StatusBadRequest {
+		t.Errorf("unlinked mine: code = %d, want 400", w.Code)
+	}
+	if err := tr.store.UpdateUserPlayerLink(ctx, userID, &alice.PlayerID); err != nil {
+		t.Fatalf("UpdateUserPlayerLink: %v", err)
+	}
+	if resp := list("/api/account/demos", token); resp.Total != 1 || ids(resp)[0] != demoIDs[0] {
+		t.Errorf("mine: got total=%d ids=%v", resp.Total, ids(resp))
+	}
+
+	tr.r.SetFeatures(Features{Demos: false})
+	if resp := list("/api/demos", ""); resp.Total != 0 || len(resp.Demos) != 0 {
+		t.Errorf("demos off: got %+v", resp)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Long-poll fallback for networks that block WebSockets. It serves the
+// same events /ws broadcasts, numbered by the same seq, so a client can
+// switch between the two without missing or repeating any.
+const (
+	// pollBacklog is how many events the hub keeps for pollers. One that
+	// falls further behind than this is told it missed some.
+	pollBacklog = 1000
+	// pollWait is how long a poll waits for something to happen.
+	pollWait = 25 * time.Second
+)
+
+// sequencedEvent is an encoded broadcast event and its number.
+type sequencedEvent struct {
+	seq  uint64
+	data json.RawMessage
+}
+
+// encode marshals msg for its clients. Events for everyone are numbered
+// and kept for pollers first; spectator-only ones aren't. Run is the
+// only caller, so numbers go out in the order clients see them.
+func (h *WebSocketHub) encode(msg wsMessage) ([]byte, error) {
+	if msg.matchOnly {
+		return json.Marshal(msg.event)
+	}
+	h.pollMu.Lock()
+	defer h.pollMu.Unlock()
+	msg.event.Seq = h.seq + 1
+	data, err := json.Marshal(msg.event)
+	if err != nil {
+		return nil, err
+	}
+	h.seq++
+	if len(h.backlog) == pollBacklog {
+		h.backlog = append(h.backlog[:0], h.backlog[1:]...)
+	}
+	h.backlog = append(h.backlog, sequencedEvent{seq: h.seq, data: data})
+	close(h.wake)
+	h.wake = make(chan struct{})
+	return data, nil
+}
+
+// eventsSince returns the events after since and the latest seq, plus
+// a channel that's closed when the next event goes out. missed is set,
+// with no events, when some after since have already been dropped.
+func (h *WebSocketHub) eventsSince(since uint64) (events []json.RawMessage, seq uint64, missed bool, wake <-chan struct{}) {
+	h.pollMu.Lock()
+	defer h.pollMu.Unlock()
+	events = []json.RawMessage{}
+	// A seq from the future means the hub restarted and numbering
+	// started over.
+	if since > h.seq || (len(h.backlog) > 0 && since+1 < h.backlog[0].seq) {
+		return events, h.seq, true, h.wake
+	}
+	for _, e := range h.backlog {
+		if e.seq > since {
+			events = append(events, e.data)
+		}
+	}
+	return events, h.seq, false, h.wake
+}
+
+// latestSeq is the number of the last event broadcast.
+func (h *WebSocketHub) latestSeq() uint64 {
+	h.pollMu.Lock()
+	defer h.pollMu.Unlock()
+	return h.seq
+}
+
+// PollEventsResponse is one long-poll's worth of events.
+type PollEventsResponse struct {
+	// Seq is the latest event's number; pass it as since_seq next time.
+	Seq    uint64            `json:"seq"`
+	Events []json.RawMessage `json:"events"`
+	// Missed means events after since_seq are gone, so whatever the
+	// client has built from them should be refetched before polling on
+	// from Seq.
+	Missed bool `json:"missed,omitempty"`
+}
+
+// handlePollEvents waits up to pollWait for broadcast events after
+// since_seq and returns them, or none if nothing happened. Without
+// since_seq it returns the current seq straight away to start from.
+// path: GET /api/events/poll
+func (r *Router) handlePollEvents(w http.ResponseWriter, req *http.Request) {
+	raw := req.URL.Query().Get("since_seq")
+	if raw == "" {
+		writeJSON(w, http.StatusOK, PollEventsResponse{Seq: r.wsHub.latestSeq(), Events: []json.RawMessage{}})
+		return
+	}
+	since, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "since_seq must be a non-negative integer")
+		return
+	}
+
+	// The server's WriteTimeout is shorter than a poll.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(pollWait + 10*time.Second))
+	ctx, cancel := context.WithTimeout(req.Context(), pollWait)
+	defer cancel()
+	for {
+		events, seq, missed, wake := r.wsHub.eventsSince(since)
+		if len(events) > 0 || missed {
+			writeJSON(w, http.StatusOK, PollEventsResponse{Seq: seq, Events: events, Missed: missed})
+			return
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			writeJSON(w, http.StatusOK, PollEventsResponse{Seq: seq, Events: events})
+			return
+		}
+	}
+}
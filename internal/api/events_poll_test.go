@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func pollEvents(t *testing.T, tr *testRouter, query string) PollEventsResponse {
+	t.Helper()
+	w := tr.do("GET", "/api/events/poll"+query, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("poll%s: %d %s", query, w.Code, w.Body)
+	}
+	var resp PollEventsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestPollEvents(t *testing.T) {
+	tr := newTestRouter(t)
+	go tr.r.wsHub.Run()
+	hub := tr.r.wsHub
+
+	if resp := pollEvents(t, tr, ""); resp.Seq != 0 || len(resp.Events) != 0 {
+		t.Fatalf("initial poll: %+v", resp)
+	}
+
+	hub.Broadcast(domain.Event{Type: domain.EventPlayerJoin, ServerID: 1})
+	hub.BroadcastMatch(domain.Event{Type: domain.EventPlayerJoin, ServerID: 1})
+	hub.Broadcast(domain.Event{Type: domain.EventPlayerLeave, ServerID: 2})
+
+	for deadline := time.Now().Add(5 * time.Second); hub.latestSeq() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("events weren't broadcast")
+		}
+	}
+	resp := pollEvents(t, tr, "?since_seq=0")
+	if resp.Seq != 2 || len(resp.Events) != 2 || resp.Missed {
+		t.Fatalf("since 0: %+v", resp)
+	}
+	var second domain.Event
+	if err := json.Unmarshal(resp.Events[1], &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Seq != 2 || second.Type != domain.EventPlayerLeave {
+		t.Errorf("second event: %+v", second)
+	}
+
+	// A poll that's waiting returns as soon as the next event goes out.
+	done := make(chan PollEventsResponse)
+	go func() { done <- pollEvents(t, tr, "?since_seq=2") }()
+	time.Sleep(50 * time.Millisecond)
+	hub.Broadcast(domain.Event{Type: domain.EventPlayerJoin, ServerID: 3})
+	select {
+	case resp = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting poll didn't wake")
+	}
+	if resp.Seq != 3 || len(resp.Events) != 1 {
+		t.Errorf("since 2: %+v", resp)
+	}
+
+	if resp := pollEvents(t, tr, "?since_seq=99"); !resp.Missed || resp.Seq != 3 || len(resp.Events) != 0 {
+		t.Errorf("seq from before a restart: %+v", resp)
+	}
+	if w := tr.do("GET", "/api/events/poll?since_seq=-1", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("bad since_seq: %d, want 400", w.Code)
+	}
+}
+
+func TestPollEventsMissed(t *testing.T) {
+	hub := NewWebSocketHub(0)
+	for i := 0; i < pollBacklog+5; i++ {
+		if _, err := hub.encode(wsMessage{event: domain.Event{Type: domain.EventPlayerJoin}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, missed, _ := hub.eventsSince(3); !missed {
+		t.Error("since 3: want missed")
+	}
+	events, seq, missed, _ := hub.eventsSince(5)
+	if missed || seq != pollBacklog+5 || len(events) != pollBacklog {
+		t.Errorf("since 5: %d events to %d, missed %v", len(events), seq, missed)
+	}
+}
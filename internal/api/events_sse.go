@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// Server-Sent Events fallback for proxies that block WebSocket
+// upgrades but pass a long-lived response. It's a client of the same
+// hub as /ws, so it gets the same events, numbered by the same seq,
+// and is dropped the same way when it falls behind. Each event's seq
+// is its SSE id, so a browser reconnecting with Last-Event-ID picks up
+// from the poll backlog where it left off.
+const (
+	// sseHeartbeat is how often an idle stream gets a comment, so
+	// proxies don't time it out. It matches /ws's ping.
+	sseHeartbeat = 30 * time.Second
+	// sseWriteWait bounds each write to the stream.
+	sseWriteWait = 10 * time.Second
+)
+
+// handleEventStream streams broadcast events as Server-Sent Events.
+// server_id and types (comma-separated) narrow the stream like a /ws
+// subscription. A stream resumed after since_seq, or the browser's
+// Last-Event-ID, first replays what it missed; if that's no longer in
+// the backlog, it gets a "missed" event instead.
+//
+// path: GET /api/events
+func (r *Router) handleEventStream(w http.ResponseWriter, req *http.Request) {
+	sub, err := parseSubscription(req.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	since, resume := req.Header.Get("Last-Event-ID"), true
+	if since == "" {
+		since = req.URL.Query().Get("since_seq")
+	}
+	var lastSeq uint64
+	if since == "" {
+		resume = false
+	} else if lastSeq, err = strconv.ParseUint(since, 10, 64); err != nil {
+		writeError(w, http.StatusBadRequest, "since_seq must be a non-negative integer")
+		return
+	}
+
+	client := &WebSocketClient{
+		hub:        r.wsHub,
+		send:       make(chan []byte, wsSendQueue),
+		remoteAddr: getClientIP(req),
+	}
+	if sub != nil {
+		client.subs = []wsSubscription{*sub}
+	}
+	r.wsHub.register <- client
+	defer func() { r.wsHub.unregister <- client }()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	write := func(format string, args ...any) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(sseWriteWait))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	sendEvent := func(seq uint64, data []byte) bool {
+		if seq == 0 {
+			return write("data: %s\n\n", data)
+		}
+		lastSeq = seq
+		return write("id: %d\ndata: %s\n\n", seq, data)
+	}
+	if !write("retry: 3000\n\n") {
+		return
+	}
+
+	// Registered first, so nothing falls between the backlog and the
+	// live events; the seq check skips what's in both.
+	if resume {
+		events, _, missed, _ := r.wsHub.eventsSince(lastSeq)
+		if missed {
+			if !write("event: missed\ndata: {}\n\n") {
+				return
+			}
+		}
+		for _, data := range events {
+			if seq, ok := r.wsHub.clientWants(client, data); ok && !sendEvent(seq, data) {
+				return
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case data, ok := <-client.send:
+			if !ok {
+				// Dropped for falling behind; the browser reconnects
+				// and resumes from its last id.
+				return
+			}
+			seq := eventSeq(data)
+			if seq != 0 && seq <= lastSeq {
+				continue
+			}
+			if !sendEvent(seq, data) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// parseSubscription reads a subscription from server_id and types,
+// or returns nil if neither is set.
+func parseSubscription(q url.Values) (*wsSubscription, error) {
+	rawID, rawTypes := q.Get("server_id"), q.Get("types")
+	if rawID == "" && rawTypes == "" {
+		return nil, nil
+	}
+	var sub wsSubscription
+	if rawID != "" {
+		id, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("server_id must be a positive integer")
+		}
+		sub.ServerID = id
+	}
+	for _, t := range strings.Split(rawTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			sub.Types = append(sub.Types, t)
+		}
+	}
+	return &sub, nil
+}
+
+// eventHeader is the part of an encoded event that routing needs.
+type eventHeader struct {
+	Type     string `json:"event"`
+	ServerID int64  `json:"server_id"`
+	Seq      uint64 `json:"seq"`
+}
+
+// eventSeq returns an encoded event's seq, or 0 if it has none.
+func eventSeq(data []byte) uint64 {
+	var h eventHeader
+	_ = json.Unmarshal(data, &h)
+	return h.Seq
+}
+
+// clientWants reports whether c's filters pass an encoded backlog
+// event, and returns its seq.
+func (h *WebSocketHub) clientWants(c *WebSocketClient, data []byte) (uint64, bool) {
+	var hdr eventHeader
+	if err := json.Unmarshal(data, &hdr); err != nil {
+		return 0, false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return hdr.Seq, c.wants(wsMessage{serverID: hdr.ServerID, event: domain.Event{Type: hdr.Type}})
+}
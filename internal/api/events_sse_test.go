@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// readSSE collects the id and data lines of the next n events on r.
+func readSSE(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v (got %v)", err, got)
+		}
+		if strings.HasPrefix(line, "id: ") || strings.HasPrefix(line, "event: ") {
+			got = append(got, strings.TrimSpace(line))
+		}
+	}
+	return got
+}
+
+func TestEventStreamFiltersAndResumes(t *testing.T) {
+	tr := newTestRouter(t)
+	go tr.r.wsHub.Run()
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+
+	// seq 1 and 2 go out before the stream connects.
+	tr.r.wsHub.Broadcast(domain.Event{Type: domain.EventFrag, ServerID: 3})
+	tr.r.wsHub.Broadcast(domain.Event{Type: domain.EventFrag, ServerID: 4})
+	for tr.r.wsHub.latestSeq() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+"/api/events?server_id=3&types=frag,match_end", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body := bufio.NewReader(resp.Body)
+	if got := readSSE(t, body, 1); got[0] != "id: 1" {
+		t.Errorf("replayed %v, want id 1 only", got)
+	}
+
+	tr.r.wsHub.Broadcast(domain.Event{Type: domain.EventPlayerJoin, ServerID: 3})
+	tr.r.wsHub.Broadcast(domain.Event{Type: domain.EventMatchEnd, ServerID: 3})
+	if got := readSSE(t, body, 1); got[0] != "id: 4" {
+		t.Errorf("live %v, want id 4 only", got)
+	}
+}
+
+func TestEventStreamMissed(t *testing.T) {
+	tr := newTestRouter(t)
+	go tr.r.wsHub.Run()
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events?since_seq=99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := readSSE(t, bufio.NewReader(resp.Body), 1); got[0] != "event: missed" {
+		t.Errorf("got %v, want a missed event", got)
+	}
+
+	for _, bad := range []string{"?server_id=x", "?since_seq=-1"} {
+		resp, err := http.Get(srv.URL + "/api/events" + bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", bad, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// maxExclusionReasonLen bounds the free-text reason on an exclusion.
+const maxExclusionReasonLen = 500
+
+// handleListLeaderboardExclusions lists players kept off the
+// leaderboards, with the reason given.
+//
+// path: GET /api/admin/leaderboard-exclusions
+func (r *Router) handleListLeaderboardExclusions(w http.ResponseWriter, req *http.Request) {
+	exclusions, err := r.store.ListLeaderboardExclusions(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, exclusions)
+}
+
+// handleSetLeaderboardExclusion flags or clears a player's exclusion
+// from leaderboards. Their matches stay visible everywhere else.
+//
+// path: PUT /api/admin/players/{id}/leaderboard-exclusion
+func (r *Router) handleSetLeaderboardExclusion(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	var body struct {
+		Excluded bool   `json:"excluded"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Excluded && body.Reason == "" {
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	if len(body.Reason) > maxExclusionReasonLen {
+		writeError(w, http.StatusBadRequest, "reason is too long")
+		return
+	}
+
+	err = r.store.SetLeaderboardExclusion(req.Context(), playerID, body.Excluded, body.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "player not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	player, err := r.store.GetPlayerByID(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, player)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/export"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// exportPageTimeout is how long the client gets to take each page of
+// an export. The server's WriteTimeout is far too short for a full
+// history, so the deadline is pushed out page by page instead.
+const exportPageTimeout = 30 * time.Second
+
+// exportWriter flushes each page of an export to the client and
+// extends the write deadline for the next one.
+type exportWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newExportWriter(w http.ResponseWriter) *exportWriter {
+	ew := &exportWriter{w: w, rc: http.NewResponseController(w)}
+	ew.extend()
+	return ew
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) { return e.w.Write(p) }
+
+func (e *exportWriter) Flush() error {
+	if err := e.rc.Flush(); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+	e.extend()
+	return nil
+}
+
+func (e *exportWriter) extend() {
+	// Not every writer supports deadlines (httptest's doesn't); the
+	// server default applies then.
+	_ = e.rc.SetWriteDeadline(time.Now().Add(exportPageTimeout))
+}
+
+// exportRange reads format, from, and to. from and to take RFC3339 or
+// a bare YYYY-MM-DD date (midnight UTC); the range is [from, to), and
+// defaults to everything up to now.
+func exportRange(req *http.Request) (format string, from, to time.Time, err error) {
+	q := req.URL.Query()
+	format = q.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if !export.ValidFormat(format) {
+		return "", from, to, errors.New("invalid format, use csv or json")
+	}
+	to = time.Now().UTC()
+	if s := q.Get("from"); s != "" {
+		if from, err = parseExportDate(s); err != nil {
+			return "", from, to, errors.New("invalid from, use RFC3339 or YYYY-MM-DD")
+		}
+	}
+	if s := q.Get("to"); s != "" {
+		if to, err = parseExportDate(s); err != nil {
+			return "", from, to, errors.New("invalid to, use RFC3339 or YYYY-MM-DD")
+		}
+	}
+	if !from.Before(to) {
+		return "", from, to, errors.New("from must be before to")
+	}
+	return format, from, to, nil
+}
+
+func parseExportDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// handleExportMatches streams one row per player per finished match.
+//
+// path: GET /api/export/matches?format=csv|json&from=&to=
+func (r *Router) handleExportMatches(w http.ResponseWriter, req *http.Request) {
+	r.serveExport(w, req, "matches", export.WriteMatches)
+}
+
+// handleExportPlayers streams each player's totals over the range.
+//
+// path: GET /api/export/players?format=csv|json&from=&to=
+func (r *Router) handleExportPlayers(w http.ResponseWriter, req *http.Request) {
+	r.serveExport(w, req, "players", export.WritePlayers)
+}
+
+type exportFunc func(ctx context.Context, store *storage.Store, w io.Writer, format string, from, to time.Time) error
+
+func (r *Router) serveExport(w http.ResponseWriter, req *http.Request, name string, write exportFunc) {
+	format, from, to, err := exportRange(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == export.FormatJSON {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "trinity-"+name+"."+format))
+
+	// Headers are gone once the first page is written, so a failure
+	// partway through can only be logged; the client sees a truncated
+	// body (and, for JSON, an unterminated array).
+	if err := write(req.Context(), r.store, newExportWriter(w), format, from, to); err != nil {
+		log.Printf("api: export %s: %v", name, err)
+	}
+}
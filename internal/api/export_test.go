@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestHandleExport(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	ended := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	pg, err := tr.store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", ended, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: ended.Add(-10 * time.Minute)}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	if err := tr.store.FlushMatchPlayerStats(ctx, m.ID, pg.ID, 0, 20, 4, true, nil, nil, "", 0, true,
+		0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+		t.Fatalf("FlushMatchPlayerStats: %v", err)
+	}
+	if err := tr.store.EndMatch(ctx, m.ID, ended, "fraglimit", nil, nil); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	w := tr.do("GET", "/api/export/matches?from=2026-05-06&to=2026-05-07", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("csv: got %d, body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("csv content type: got %q", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "match_id,") || !strings.Contains(lines[1], ",m1,") {
+		t.Errorf("csv body: got %q", w.Body.String())
+	}
+
+	w = tr.do("GET", "/api/export/players?format=json&from=2026-05-06T00:00:00Z", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("json: got %d, body=%s", w.Code, w.Body.String())
+	}
+	var players []struct {
+		Name  string `json:"name"`
+		Frags int    `json:"frags"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &players); err != nil {
+		t.Fatalf("json body %q: %v", w.Body.String(), err)
+	}
+	if len(players) != 1 || players[0].Name != "alice" || players[0].Frags != 20 {
+		t.Errorf("players: got %+v", players)
+	}
+
+	// A range with nothing in it is still a valid, empty document.
+	w = tr.do("GET", "/api/export/matches?format=json&to=2020-01-01", "", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[\n]" {
+		t.Errorf("empty json: got %d %q", w.Code, w.Body.String())
+	}
+
+	for _, q := range []string{"format=xml", "from=yesterday", "from=2026-05-07&to=2026-05-06"} {
+		if w := tr.do("GET", "/api/export/matches?"+q, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", q, w.Code)
+		}
+	}
+}
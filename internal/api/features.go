@@ -0,0 +1,38 @@
+package api
+
+import "net/http"
+
+// Features lists which optional subsystems this hub has switched on.
+// Served at /api/config/features so the SPA hides pages and links for
+// anything that's off.
+type Features struct {
+	Demos           bool `json:"demos"`
+	Registration    bool `json:"registration"`
+	ChatPersistence bool `json:"chat_persistence"`
+	PublicAPI       bool `json:"public_api"`
+	// Not built yet; always false so the SPA can key off it today.
+	Ratings bool `json:"ratings"`
+}
+
+// SetFeatures applies the operator's feature switches. NewRouter
+// starts with demos and registration on; main.go overrides from
+// config.FeaturesConfig.
+func (r *Router) SetFeatures(f Features) {
+	r.features = f
+}
+
+// handleGetFeatures reports the feature switches, which stats the SPA
+// should leave out of leaderboard and profile columns, and the
+// external sign-in providers to offer.
+//
+// path: GET /api/config/features
+func (r *Router) handleGetFeatures(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, FeaturesResponse{r.features, r.hiddenStats, r.oauthInfo})
+}
+
+// FeaturesResponse is the response for GET /api/config/features.
+type FeaturesResponse struct {
+	Features
+	HiddenStats    []string            `json:"hidden_stats"`
+	OAuthProviders []OAuthProviderInfo `json:"oauth_providers"`
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHandleGetFeatures(t *testing.T) {
+	tr := newTestRouter(t)
+	w := tr.do("GET", "/api/config/features", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body)
+	}
+	var got Features
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got != (Features{Demos: true, Registration: true}) {
+		t.Errorf("defaults: got %+v", got)
+	}
+}
+
+func TestClaimRegisterRefusedWhenRegistrationOff(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.r.SetFeatures(Features{Demos: true})
+	w := tr.do("POST", "/api/claim/register", `{"code":"123456","username":"alice","password":"password123"}`, "")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("code = %d, want 403; body = %s", w.Code, w.Body)
+	}
+}
+
+func TestHandleGetBranding(t *testing.T) {
+	tr := newTestRouter(t)
+	w := tr.do("GET", "/api/config/branding", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body)
+	}
+	if got := w.Body.String(); got != "{\"footer_links\":[],\"community_links\":[]}\n" {
+		t.Errorf("default branding: got %s", got)
+	}
+
+	tr.r.SetBranding(Branding{
+		SiteName:    "Frag Haven",
+		FooterLinks: []BrandingLink{{Label: "Rules", URL: "/rules"}},
+	})
+	w = tr.do("GET", "/api/config/branding", "", "")
+	var got Branding
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.SiteName != "Frag Haven" || len(got.FooterLinks) != 1 || got.CommunityLinks == nil {
+		t.Errorf("branding: got %+v", got)
+	}
+}
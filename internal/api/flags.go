@@ -0,0 +1,28 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// handleGetFlagTimeline returns a match's flag pickups, drops,
+// captures and returns in order, and each player's flag carry time.
+//
+// path: GET /api/matches/{id}/flag-timeline
+func (r *Router) handleGetFlagTimeline(w http.ResponseWriter, req *http.Request) {
+	matchID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	timeline, err := r.store.GetFlagTimeline(req.Context(), matchID)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "match not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, timeline)
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+	"github.com/graphql-go/graphql"
+)
+
+// /api/graphql serves players, matches, their stats and leaderboards
+// in one round trip. Its object types are generated from the domain
+// structs the REST endpoints return, so a field has the same name and
+// meaning as the JSON key it comes from. What the public mirror
+// scrubs (GUIDs, IPs) isn't in the schema at all, and neither are the
+// features.hidden_stats keys of stats types.
+//
+// Nested lists multiply quickly, so each query gets a budget of
+// database lookups and fails once it's spent.
+const graphqlLookupBudget = 100
+
+// gqlNames renames domain types whose Go names read oddly in a schema.
+var gqlNames = map[reflect.Type]string{
+	reflect.TypeOf(domain.MatchSummary{}):        "Match",
+	reflect.TypeOf(domain.MatchPlayerSummary{}):  "MatchPlayer",
+	reflect.TypeOf(domain.PlayerStatsResponse{}): "PlayerStats",
+	reflect.TypeOf(domain.LeaderboardResponse{}): "Leaderboard",
+}
+
+// gqlStatsTypes are the types features.hidden_stats applies to, as
+// writeStatsJSON does for the REST endpoints returning them.
+var gqlStatsTypes = map[reflect.Type]bool{
+	reflect.TypeOf(domain.AggregatedStats{}):  true,
+	reflect.TypeOf(domain.LeaderboardEntry{}): true,
+}
+
+// gqlTypes builds object types from Go structs, one per type.
+type gqlTypes struct {
+	objects map[reflect.Type]*graphql.Object
+	hidden  map[string]bool
+	// extra are hand-written fields added to a generated type, for
+	// what the struct links to rather than holds.
+	extra map[reflect.Type]graphql.Fields
+}
+
+// output returns the GraphQL type for t, or nil if it has none.
+func (g *gqlTypes) output(t reflect.Type) graphql.Output {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return graphql.DateTime
+	case t.Kind() == reflect.Pointer:
+		return g.output(t.Elem())
+	case t.Kind() == reflect.Slice:
+		if elem := g.output(t.Elem()); elem != nil {
+			return graphql.NewList(elem)
+		}
+	case t.Kind() == reflect.Struct:
+		return g.object(t)
+	case t.Kind() == reflect.String:
+		return graphql.String
+	case t.Kind() == reflect.Bool:
+		return graphql.Boolean
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return graphql.Int
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return graphql.Float
+	}
+	return nil
+}
+
+func (g *gqlTypes) object(t reflect.Type) *graphql.Object {
+	if obj, ok := g.objects[t]; ok {
+		return obj
+	}
+	name := gqlNames[t]
+	if name == "" {
+		name = t.Name()
+	}
+	// Fields are a thunk so types can refer to each other.
+	obj := graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			fields := graphql.Fields{}
+			g.addFields(fields, t, nil, gqlStatsTypes[t])
+			for k, f := range g.extra[t] {
+				fields[k] = f
+			}
+			return fields
+		}),
+	})
+	g.objects[t] = obj
+	return obj
+}
+
+// addFields adds a field for each JSON key of struct t, flattening
+// embedded structs as encoding/json does.
+func (g *gqlTypes) addFields(fields graphql.Fields, t reflect.Type, index []int, stats bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			g.addFields(fields, sf.Type, idx, stats)
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" || publicPrivateKeys[name] || (stats && g.hidden[name]) {
+			continue
+		}
+		typ := g.output(sf.Type)
+		if typ == nil {
+			continue
+		}
+		fields[name] = &graphql.Field{Type: typ, Resolve: structField(idx)}
+	}
+}
+
+// structField resolves a field of the source struct by index.
+func structField(index []int) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		v := reflect.ValueOf(p.Source)
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		f := v.FieldByIndex(index)
+		if f.Kind() == reflect.Pointer && f.IsNil() {
+			return nil, nil
+		}
+		return f.Interface(), nil
+	}
+}
+
+type lookupBudgetKey struct{}
+
+// spend takes a lookup from the query's budget.
+func spend(ctx context.Context) error {
+	if n, ok := ctx.Value(lookupBudgetKey{}).(*atomic.Int32); ok && n.Add(1) > graphqlLookupBudget {
+		return fmt.Errorf("query needs more than %d lookups; split it up", graphqlLookupBudget)
+	}
+	return nil
+}
+
+// buildGraphQLSchema builds the schema for the current hidden stats.
+// It's generated from fixed types, so failing is a bug.
+func (r *Router) buildGraphQLSchema() {
+	schema, err := r.newGraphQLSchema(r.hiddenStatKeys)
+	if err != nil {
+		panic(fmt.Sprintf("graphql schema: %v", err))
+	}
+	r.graphqlSchema = schema
+}
+
+// newGraphQLSchema builds the schema, leaving out hidden's keys.
+func (r *Router) newGraphQLSchema(hidden map[string]bool) (graphql.Schema, error) {
+	g := &gqlTypes{objects: map[reflect.Type]*graphql.Object{}, hidden: hidden, extra: map[reflect.Type]graphql.Fields{}}
+	playerType := reflect.TypeOf(domain.Player{})
+	matchType := reflect.TypeOf(domain.MatchSummary{})
+
+	limitArg := func(def int) *graphql.ArgumentConfig {
+		return &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: def}
+	}
+	periodArg := &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "all"}
+
+	g.extra[playerType] = graphql.Fields{
+		"stats": &graphql.Field{
+			Type: g.object(reflect.TypeOf(domain.PlayerStatsResponse{})),
+			Args: graphql.FieldConfigArgument{"period": periodArg},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				period := p.Args["period"].(string)
+				if !validatePeriod(period) {
+					return nil, errors.New("invalid period: must be all, day, week, month, or year")
+				}
+				if err := spend(p.Context); err != nil {
+					return nil, err
+				}
+				return r.store.GetPlayerStatsByID(p.Context, gqlPlayerID(p.Source), period)
+			},
+		},
+		"matches": &graphql.Field{
+			Type: graphql.NewList(g.object(matchType)),
+			Args: graphql.FieldConfigArgument{"limit": limitArg(10), "before": {Type: graphql.Int}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				limit, err := gqlLimit(p.Args, 50)
+				if err != nil {
+					return nil, err
+				}
+				if err := spend(p.Context); err != nil {
+					return nil, err
+				}
+				matches, err := r.store.GetPlayerRecentMatches(p.Context, gqlPlayerID(p.Source), limit, gqlBefore(p.Args))
+				r.populateDemoURLs(p.Context, matches)
+				return matches, err
+			},
+		},
+		"achievements": &graphql.Field{
+			Type: graphql.NewList(g.object(reflect.TypeOf(domain.PlayerAchievement{}))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if err := spend(p.Context); err != nil {
+					return nil, err
+				}
+				return r.store.GetPlayerAchievements(p.Context, gqlPlayerID(p.Source))
+			},
+		},
+	}
+	g.extra[reflect.TypeOf(domain.MatchPlayerSummary{})] = graphql.Fields{
+		"player": &graphql.Field{
+			Type: g.object(playerType),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if err := spend(p.Context); err != nil {
+					return nil, err
+				}
+				var id int64
+				switch mp := p.Source.(type) {
+				case domain.MatchPlayerSummary:
+					id = mp.PlayerID
+				case *domain.MatchPlayerSummary:
+					id = mp.PlayerID
+				}
+				return r.store.GetPlayerByID(p.Context, id)
+			},
+		},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"player": &graphql.Field{
+				Type: g.object(playerType),
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := spend(p.Context); err != nil {
+						return nil, err
+					}
+					player, err := r.store.GetPlayerByID(p.Context, int64(p.Args["id"].(int)))
+					if err != nil {
+						return nil, errors.New("player not found")
+					}
+					return player, nil
+				},
+			},
+			"players": &graphql.Field{
+				Type: graphql.NewList(g.object(playerType)),
+				Args: graphql.FieldConfigArgument{
+					"search": {Type: graphql.String},
+					"limit":  limitArg(20),
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					limit, err := gqlLimit(p.Args, 100)
+					if err != nil {
+						return nil, err
+					}
+					if err := spend(p.Context); err != nil {
+						return nil, err
+					}
+					if search, _ := p.Args["search"].(string); search != "" {
+						return r.store.SearchPlayers(p.Context, search, limit, false)
+					}
+					players, _, err := r.store.GetPlayers(p.Context, limit, max(p.Args["offset"].(int), 0))
+					return players, err
+				},
+			},
+			"match": &graphql.Field{
+				Type: g.object(matchType),
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := spend(p.Context); err != nil {
+						return nil, err
+					}
+					match, err := r.store.GetMatchSummaryByID(p.Context, int64(p.Args["id"].(int)))
+					if err != nil {
+						return nil, errors.New("match not found")
+					}
+					matches := []domain.MatchSummary{*match}
+					r.populateDemoURLs(p.Context, matches)
+					return matches[0], nil
+				},
+			},
+			"matches": &graphql.Field{
+				Type: graphql.NewList(g.object(matchType)),
+				Args: graphql.FieldConfigArgument{
+					"limit":     limitArg(20),
+					"before":    {Type: graphql.Int},
+					"game_type": {Type: graphql.String},
+					"source":    {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					limit, err := gqlLimit(p.Args, 100)
+					if err != nil {
+						return nil, err
+					}
+					filter := storage.MatchFilter{Limit: limit, BeforeID: gqlBefore(p.Args)}
+					filter.GameType, _ = p.Args["game_type"].(string)
+					filter.Source, _ = p.Args["source"].(string)
+					if filter.GameType != "" && !validateGameType(filter.GameType) {
+						return nil, errors.New("invalid game_type")
+					}
+					if err := spend(p.Context); err != nil {
+						return nil, err
+					}
+					matches, err := r.store.GetFilteredMatchSummaries(p.Context, filter)
+					r.populateDemoURLs(p.Context, matches)
+					return matches, err
+				},
+			},
+			"leaderboard": &graphql.Field{
+				Type: g.object(reflect.TypeOf(domain.LeaderboardResponse{})),
+				Args: graphql.FieldConfigArgument{
+					"category":  {Type: graphql.String, DefaultValue: "frags"},
+					"period":    periodArg,
+					"game_type": {Type: graphql.String},
+					"weapon":    {Type: graphql.String},
+					"as_of":     {Type: graphql.DateTime},
+					"limit":     limitArg(50),
+				},
+				Resolve: r.resolveLeaderboard,
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// resolveLeaderboard applies the same checks as GET
+// /api/stats/leaderboard.
+func (r *Router) resolveLeaderboard(p graphql.ResolveParams) (any, error) {
+	limit, err := gqlLimit(p.Args, 100)
+	if err != nil {
+		return nil, err
+	}
+	category := p.Args["category"].(string)
+	if !validateCategory(category) || r.hiddenCategories[category] {
+		return nil, errors.New("invalid category")
+	}
+	period := p.Args["period"].(string)
+	if !validatePeriod(period) {
+		return nil, errors.New("invalid period")
+	}
+	gameType, _ := p.Args["game_type"].(string)
+	if gameType != "" && !validateGameType(gameType) {
+		return nil, errors.New("invalid game_type")
+	}
+	var asOf time.Time
+	switch t := p.Args["as_of"].(type) {
+	case time.Time:
+		asOf = t
+	case *time.Time:
+		asOf = *t
+	}
+	if err := spend(p.Context); err != nil {
+		return nil, err
+	}
+	if weapon, _ := p.Args["weapon"].(string); weapon != "" {
+		if !validateWeapon(weapon) {
+			return nil, errors.New("invalid weapon")
+		}
+		switch category {
+		case "frags":
+			return r.store.GetWeaponLeaderboard(p.Context, weapon, period, limit, gameType, asOf)
+		case "accuracy":
+			return r.store.GetAccuracyLeaderboard(p.Context, weapon, period, limit, gameType, asOf)
+		}
+		return nil, errors.New("weapon leaderboards rank frags or accuracy only")
+	}
+	return r.store.GetLeaderboard(p.Context, category, period, limit, gameType, asOf)
+}
+
+// gqlPlayerID is the ID of a resolved player.
+func gqlPlayerID(source any) int64 {
+	switch p := source.(type) {
+	case *domain.Player:
+		return p.ID
+	case domain.Player:
+		return p.ID
+	}
+	return 0
+}
+
+// gqlLimit reads the limit argument, which must be 1..maxLimit.
+func gqlLimit(args map[string]any, maxLimit int) (int, error) {
+	limit := args["limit"].(int)
+	if limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return limit, nil
+}
+
+// gqlBefore reads the before argument as parseBeforeID does.
+func gqlBefore(args map[string]any) *int64 {
+	if b, ok := args["before"].(int); ok && b > 0 {
+		id := int64(b)
+		return &id
+	}
+	return nil
+}
+
+// graphqlRequest is a GraphQL query, POSTed as JSON or sent as GET
+// parameters.
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// handleGraphQL runs a read-only GraphQL query.
+//
+// path: GET|POST /api/graphql
+func (r *Router) handleGraphQL(w http.ResponseWriter, req *http.Request) {
+	var body graphqlRequest
+	if req.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 64<<10)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	} else {
+		q := req.URL.Query()
+		body.Query, body.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &body.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables")
+				return
+			}
+		}
+	}
+	if body.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	ctx := context.WithValue(req.Context(), lookupBudgetKey{}, new(atomic.Int32))
+	result := graphql.Do(graphql.Params{
+		Schema:         r.graphqlSchema,
+		RequestString:  body.Query,
+		VariableValues: body.Variables,
+		OperationName:  body.OperationName,
+		Context:        ctx,
+	})
+	writeJSON(w, http.StatusOK, result)
+}
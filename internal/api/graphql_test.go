@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// graphqlResult posts query and decodes the response.
+func (tr *testRouter) graphql(t *testing.T, query string) (map[string]any, []string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	w := tr.do("POST", "/api/graphql", string(body), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("graphql: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data   map[string]any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, e := range resp.Errors {
+		errs = append(errs, e.Message)
+	}
+	return resp.Data, errs
+}
+
+func TestGraphQLPlayerProfile(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.r.SetHiddenStats([]string{"deaths"})
+	pg, err := tr.store.UpsertPlayerGUID(context.Background(), "AAAA", "^1alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	data, errs := tr.graphql(t, fmt.Sprintf(`{
+		player(id: %d) {
+			clean_name
+			first_seen
+			stats(period: "all") { period stats { frags captures } }
+			matches(limit: 5) { id map_name }
+			achievements { earned_at }
+		}
+		leaderboard(category: "captures") { category entries { rank player { clean_name } captures } }
+		matches(game_type: "ffa") { id players { frags player { id } } }
+	}`, pg.PlayerID))
+	if len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	player := data["player"].(map[string]any)
+	if player["clean_name"] != "alice" {
+		t.Errorf("clean_name = %v", player["clean_name"])
+	}
+	stats := player["stats"].(map[string]any)
+	if stats["period"] != "all" || stats["stats"].(map[string]any)["frags"] != float64(0) {
+		t.Errorf("stats = %v", stats)
+	}
+	if len(player["matches"].([]any)) != 0 {
+		t.Errorf("matches = %v", player["matches"])
+	}
+
+	// Hidden stats and GUIDs aren't in the schema.
+	for _, q := range []string{
+		fmt.Sprintf(`{ player(id: %d) { stats { stats { deaths } } } }`, pg.PlayerID),
+		fmt.Sprintf(`{ player(id: %d) { guids { guid } } }`, pg.PlayerID),
+		`{ leaderboard(category: "deaths") { category } }`,
+		`{ leaderboard(limit: 500) { category } }`,
+	} {
+		if _, errs := tr.graphql(t, q); len(errs) == 0 {
+			t.Errorf("%s: no error", q)
+		}
+	}
+}
+
+func TestGraphQLLookupBudget(t *testing.T) {
+	tr := newTestRouter(t)
+	for i := 0; i < 3; i++ {
+		if _, err := tr.store.UpsertPlayerGUID(context.Background(), fmt.Sprintf("GUID%d", i), "p", "p", time.Now(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1 + 3 players × 2 lookups fits; 40 aliased copies don't.
+	if _, errs := tr.graphql(t, `{ players { stats { period } achievements { name } } }`); len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	var q strings.Builder
+	q.WriteString("{")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&q, " p%d: players { stats { period } }", i)
+	}
+	q.WriteString(" }")
+	_, errs := tr.graphql(t, q.String())
+	if len(errs) == 0 || !strings.Contains(errs[0], "lookups") {
+		t.Errorf("errors = %v, want the budget spent", errs)
+	}
+}
@@ -3,11 +3,14 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/metrics"
 	"github.com/ernie/trinity-tracker/internal/storage"
 )
 
@@ -56,7 +59,8 @@ type liveServer struct {
 }
 
 // handleGetServers returns servers the hub has observed enforcing
-// g_trinityHandshake AND that are currently checking in. Servers go
+// g_trinityHandshake AND that are currently checking in, plus
+// query-only servers that answer. Servers go
 // missing from this list when the collector source has stopped
 // heartbeating OR the q3 server has been UDP-unreachable for the
 // hide threshold — operators see them disappear instead of stuck on
@@ -81,12 +85,15 @@ func (r *Router) handleGetServers(w http.ResponseWriter, req *http.Request) {
 	now := time.Now().UTC()
 	out := make([]liveServer, 0, len(servers))
 	for _, s := range servers {
-		if !s.HandshakeRequired {
+		if !s.HandshakeRequired && !s.QueryOnly {
 			continue
 		}
-		// Heartbeat staleness.
+		// Heartbeat staleness. Query-only servers have no collector
+		// to heartbeat, so only UDP counts for them.
 		heartbeatAge := livenessHideThreshold + time.Second
-		if s.LastHeartbeatAt != nil {
+		if s.QueryOnly {
+			heartbeatAge = 0
+		} else if s.LastHeartbeatAt != nil {
 			heartbeatAge = now.Sub(*s.LastHeartbeatAt)
 		}
 		// UDP staleness — inferred from the poller's in-memory status.
@@ -151,7 +158,9 @@ func (r *Router) handleGetServer(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, http.StatusOK, server)
 }
 
-// handleGetServerStatus returns current status for a server
+// handleGetServerStatus returns current status for a server. Admins
+// get every cvar and players' GUIDs; everyone else gets the public
+// view (see domain.ServerStatus.Public).
 func (r *Router) handleGetServerStatus(w http.ResponseWriter, req *http.Request) {
 	id, err := parseID(req, "id")
 	if err != nil {
@@ -164,9 +173,22 @@ func (r *Router) handleGetServerStatus(w http.ResponseWriter, req *http.Request)
 		writeError(w, http.StatusNotFound, "server status not available")
 		return
 	}
+	if claims := r.getAuthClaims(req); claims == nil || !claims.IsAdmin {
+		public := status.Public()
+		status = &public
+	}
 	writeJSON(w, http.StatusOK, status)
 }
 
+// ServerPlayersResponse is the response for GET
+// /api/servers/{id}/players. Total counts bots and humans.
+type ServerPlayersResponse struct {
+	Players    []domain.PlayerStatus `json:"players"`
+	HumanCount int                   `json:"human_count"`
+	BotCount   int                   `json:"bot_count"`
+	Total      int                   `json:"total"`
+}
+
 // handleGetServerPlayers returns current players on a server
 func (r *Router) handleGetServerPlayers(w http.ResponseWriter, req *http.Request) {
 	id, err := parseID(req, "id")
@@ -181,14 +203,20 @@ func (r *Router) handleGetServerPlayers(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	// Response with player list and counts
-	response := map[string]interface{}{
-		"players":     status.Players,
-		"human_count": status.HumanCount,
-		"bot_count":   status.BotCount,
-		"total":       len(status.Players),
-	}
-	writeJSON(w, http.StatusOK, response)
+	writeJSON(w, http.StatusOK, ServerPlayersResponse{
+		Players:    status.Public().Players,
+		HumanCount: status.HumanCount,
+		BotCount:   status.BotCount,
+		Total:      len(status.Players),
+	})
+}
+
+// PlayerListResponse is a page of GET /api/players without a search.
+type PlayerListResponse struct {
+	Players []domain.Player `json:"players"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
 }
 
 // handleGetPlayers returns players with optional search and pagination
@@ -224,11 +252,11 @@ func (r *Router) handleGetPlayers(w http.ResponseWriter, req *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"players": players,
-		"total":   total,
-		"limit":   limit,
-		"offset":  offset,
+	writeJSON(w, http.StatusOK, PlayerListResponse{
+		Players: players,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
 	})
 }
 
@@ -272,7 +300,13 @@ func (r *Router) handleGetPlayerStatsByID(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	writeJSON(w, http.StatusOK, stats)
+	r.writeStatsJSON(w, http.StatusOK, stats)
+}
+
+// SourceName is a source in GET /api/sources.
+type SourceName struct {
+	Source string `json:"source"`
+	Active bool   `json:"active"`
 }
 
 // handleGetSourceNames returns the list of source names + active flags
@@ -287,13 +321,9 @@ func (r *Router) handleGetSourceNames(w http.ResponseWriter, req *http.Request)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	type entry struct {
-		Source string `json:"source"`
-		Active bool   `json:"active"`
-	}
-	out := make([]entry, 0, len(rows))
+	out := make([]SourceName, 0, len(rows))
 	for _, s := range rows {
-		out = append(out, entry{Source: s.Source, Active: s.Active})
+		out = append(out, SourceName{Source: s.Source, Active: s.Active})
 	}
 	writeJSON(w, http.StatusOK, out)
 }
@@ -355,12 +385,21 @@ func (r *Router) handleGetMatches(w http.ResponseWriter, req *http.Request) {
 
 	filter.IncludeBotOnly = req.URL.Query().Get("include_bot_only") == "true"
 
+	if ot := req.URL.Query().Get("overtime"); ot != "" {
+		b, err := strconv.ParseBool(ot)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid overtime")
+			return
+		}
+		filter.Overtime = &b
+	}
+
 	matches, err := r.store.GetFilteredMatchSummaries(req.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	r.populateDemoURLs(matches)
+	r.populateDemoURLs(req.Context(), matches)
 	writeJSON(w, http.StatusOK, matches)
 }
 
@@ -382,7 +421,7 @@ func (r *Router) handleGetMatch(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	matches := []domain.MatchSummary{*match}
-	r.populateDemoURLs(matches)
+	r.populateDemoURLs(req.Context(), matches)
 	writeJSON(w, http.StatusOK, matches[0])
 }
 
@@ -394,7 +433,7 @@ func (r *Router) handleGetLeaderboard(w http.ResponseWriter, req *http.Request)
 	if category == "" {
 		category = "frags"
 	}
-	if !validateCategory(category) {
+	if !validateCategory(category) || r.hiddenCategories[category] {
 		writeError(w, http.StatusBadRequest, "invalid category")
 		return
 	}
@@ -415,16 +454,77 @@ func (r *Router) handleGetLeaderboard(w http.ResponseWriter, req *http.Request)
 	}
 
 	// as_of pins the period's upper bound for reproducible snapshot
-	// links (e.g. the Discord digest's footer). Parse-only validation —
-	// nonsense values just return empty/weird leaderboards.
-	var asOf time.Time
-	if s := req.URL.Query().Get("as_of"); s != "" {
-		parsed, err := time.Parse(time.RFC3339, s)
+	// links (e.g. the Discord digest's footer).
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// season scopes the board to a season instead of the period:
+	// "current" or a season id.
+	if v := req.URL.Query().Get("season"); v != "" {
+		if req.URL.Query().Get("weapon") != "" {
+			writeError(w, http.StatusBadRequest, "weapon leaderboards can't be scoped to a season")
+			return
+		}
+		se, ok := r.leaderboardSeason(w, req, v)
+		if !ok {
+			return
+		}
+		response, err := r.store.GetSeasonLeaderboard(req.Context(), se, category, limit, gameType, time.Now().UTC())
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid as_of: "+err.Error())
+			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		asOf = parsed
+		r.writeStatsJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// weapon narrows the board to frags or accuracy with one weapon.
+	if weapon := req.URL.Query().Get("weapon"); weapon != "" {
+		if !validateWeapon(weapon) {
+			writeError(w, http.StatusBadRequest, "invalid weapon")
+			return
+		}
+		var response *domain.LeaderboardResponse
+		var err error
+		switch category {
+		case "frags":
+			response, err = r.store.GetWeaponLeaderboard(req.Context(), weapon, period, limit, gameType, asOf)
+		case "accuracy":
+			response, err = r.store.GetAccuracyLeaderboard(req.Context(), weapon, period, limit, gameType, asOf)
+		default:
+			writeError(w, http.StatusBadRequest, "weapon leaderboards rank frags or accuracy only")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		r.writeStatsJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// Admins can try other eligibility rules; those boards skip the
+	// cache.
+	if q := req.URL.Query(); q.Has("min_matches") || q.Has("min_playtime") || q.Has("include_bots") || q.Has("include_vr") || q.Has("include_abandoned") {
+		if claims := r.getAuthClaims(req); claims == nil || !claims.IsAdmin {
+			writeError(w, http.StatusForbidden, "eligibility overrides are for admins")
+			return
+		}
+		rules, err := parseLeaderboardRules(q, r.store.LeaderboardRules())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		response, err := r.store.GetLeaderboardWithRules(req.Context(), rules, category, period, limit, gameType, asOf)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+		return
 	}
 
 	response, err := r.store.GetLeaderboard(req.Context(), category, period, limit, gameType, asOf)
@@ -432,9 +532,255 @@ func (r *Router) handleGetLeaderboard(w http.ResponseWriter, req *http.Request)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
+// parseLeaderboardRules applies min_matches, min_playtime,
+// include_bots, include_vr and include_abandoned from q over rules.
+func parseLeaderboardRules(q url.Values, rules storage.LeaderboardRules) (storage.LeaderboardRules, error) {
+	if v := q.Get("min_matches"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return rules, errors.New("invalid min_matches")
+		}
+		rules.MinMatches = n
+	}
+	if v := q.Get("min_playtime"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return rules, errors.New("invalid min_playtime")
+		}
+		rules.MinPlaytime = d
+	}
+	if v := q.Get("include_bots"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return rules, errors.New("invalid include_bots")
+		}
+		rules.IncludeBots = b
+	}
+	if v := q.Get("include_vr"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return rules, errors.New("invalid include_vr")
+		}
+		rules.IncludeVRPlaceholders = b
+	}
+	if v := q.Get("include_abandoned"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return rules, errors.New("invalid include_abandoned")
+		}
+		rules.IncludeAbandoned = b
+	}
+	return rules, nil
+}
+
+// handleGetReturningPlayers lists players who came back after a long
+// absence during the period. Feeds the digest's returning-players
+// section; as_of behaves as it does on the leaderboard.
+func (r *Router) handleGetReturningPlayers(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 10, 50)
+
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetReturningPlayers(req.Context(), period, limit, asOf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, response)
 }
 
+// handleGetCountryBreakdown returns how many players and sessions
+// came from each country during the period. Only sessions geolocated
+// by a collector with a GeoIP database count.
+func (r *Router) handleGetCountryBreakdown(w http.ResponseWriter, req *http.Request) {
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetCountryBreakdown(req.Context(), period, asOf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetActivity returns the hour-of-week activity heatmap, per
+// server and overall. tz is an IANA zone for the hours (default UTC);
+// humans_only=true leaves bots out. Defaults to the last month.
+func (r *Router) handleGetActivity(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	loc := time.UTC
+	if tz := q.Get("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			writeError(w, http.StatusBadRequest, "invalid tz")
+			return
+		}
+		loc = l
+	}
+
+	humansOnly := false
+	if v := q.Get("humans_only"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid humans_only")
+			return
+		}
+		humansOnly = b
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetActivityHeatmap(req.Context(), period, loc, humansOnly, asOf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
+// handleGetSessionStats returns session counts and lengths, unique
+// players per day, and 7-day retention for humans. Defaults to the
+// last month.
+func (r *Router) handleGetSessionStats(w http.ResponseWriter, req *http.Request) {
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "month"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetSessionStats(req.Context(), period, asOf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
+// handleGetBotStats returns each bot character's record and how
+// humans fared against each bot skill level. Defaults to all time.
+func (r *Router) handleGetBotStats(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+	gameType := q.Get("game_type")
+	if gameType != "" && !validateGameType(gameType) {
+		writeError(w, http.StatusBadRequest, "invalid game_type")
+		return
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetBotStats(req.Context(), period, gameType, asOf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
+// handleGetServerPing returns each server's ping over the period,
+// overall and by the country players connected from. Defaults to the
+// last week.
+func (r *Router) handleGetServerPing(w http.ResponseWriter, req *http.Request) {
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	response, err := r.store.GetServerPingStats(req.Context(), period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
+// handleGetPlayerLocation returns where the player was last seen
+// from. Admin only — it's derived from the player's IP address.
+func (r *Router) handleGetPlayerLocation(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+
+	loc, err := r.store.GetPlayerLastLocation(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if loc == nil {
+		writeError(w, http.StatusNotFound, "no location recorded for player")
+		return
+	}
+	writeJSON(w, http.StatusOK, loc)
+}
+
 // handleHealth returns a simple health check response
 func (r *Router) handleHealth(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
@@ -442,6 +788,14 @@ func (r *Router) handleHealth(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte("ok"))
 }
 
+// handleMetrics serves process counters in the Prometheus text format.
+//
+// path: GET /metrics
+func (r *Router) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.WritePrometheus(w)
+}
+
 // handleMergePlayers merges another player into the target player
 func (r *Router) handleMergePlayers(w http.ResponseWriter, req *http.Request) {
 	targetID, err := parseID(req, "id")
@@ -468,7 +822,10 @@ func (r *Router) handleMergePlayers(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := r.store.MergePlayers(req.Context(), targetID, body.MergePlayerID); err != nil {
+	if err := r.store.MergePlayers(req.Context(), targetID, body.MergePlayerID); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "player not found")
+		return
+	} else if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -482,6 +839,53 @@ func (r *Router) handleMergePlayers(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, http.StatusOK, player)
 }
 
+// handleListPlayerMerges lists recorded merges, newest first, so an
+// admin can find one to undo.
+//
+// path: GET /api/admin/players/merges
+func (r *Router) handleListPlayerMerges(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 50, 200)
+	merges, err := r.store.ListPlayerMerges(req.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, merges)
+}
+
+// handleUndoPlayerMerge reverses a merge, bringing the merged player
+// back with its GUIDs. Returns the restored player.
+//
+// path: POST /api/admin/players/merges/{id}/undo
+func (r *Router) handleUndoPlayerMerge(w http.ResponseWriter, req *http.Request) {
+	mergeID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid merge id")
+		return
+	}
+	sourceID, err := r.store.UndoPlayerMerge(req.Context(), mergeID, time.Now())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "merge not found")
+		return
+	case errors.Is(err, storage.ErrMergeAlreadyUndone),
+		errors.Is(err, storage.ErrMergeTargetGone),
+		errors.Is(err, storage.ErrMergeSourceTaken):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	player, err := r.store.GetPlayerByID(req.Context(), sourceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, player)
+}
+
 // handleSplitGUID splits a GUID into a new player
 func (r *Router) handleSplitGUID(w http.ResponseWriter, req *http.Request) {
 	guidID, err := parseID(req, "id")
@@ -533,10 +937,170 @@ func (r *Router) handleGetPlayerMatches(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	r.populateDemoURLs(matches)
+	r.populateDemoURLs(req.Context(), matches)
 	writeJSON(w, http.StatusOK, matches)
 }
 
+// handleGetPlayerAchievements returns the badges a player has earned
+func (r *Router) handleGetPlayerAchievements(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+
+	achievements, err := r.store.GetPlayerAchievements(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, achievements)
+}
+
+// handleGetRecords returns the current map records, optionally
+// narrowed by map, game_type, category and player_id.
+func (r *Router) handleGetRecords(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	filter := storage.RecordFilter{
+		MapName:  q.Get("map"),
+		GameType: q.Get("game_type"),
+		Category: q.Get("category"),
+	}
+	if filter.GameType != "" && !validateGameType(filter.GameType) {
+		writeError(w, http.StatusBadRequest, "invalid game_type")
+		return
+	}
+	if _, ok := domain.RecordCategoryByID(filter.Category); filter.Category != "" && !ok {
+		writeError(w, http.StatusBadRequest, "invalid category")
+		return
+	}
+	if v := q.Get("player_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		filter.PlayerID = id
+	}
+
+	records, err := r.store.GetRecords(req.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// handleGetPlayerTrends returns a player's daily or weekly totals from
+// the stats snapshots. limit is the number of days or weeks back.
+func (r *Router) handleGetPlayerTrends(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+
+	interval := req.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var since time.Time
+	switch interval {
+	case "day":
+		since = today.AddDate(0, 0, 1-parseLimit(req, 90, 730))
+	case "week":
+		// Start on a Monday so the oldest week isn't a partial bucket
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		since = monday.AddDate(0, 0, 7*(1-parseLimit(req, 26, 260)))
+	default:
+		writeError(w, http.StatusBadRequest, "interval must be day or week")
+		return
+	}
+
+	trends, err := r.store.GetPlayerTrends(req.Context(), playerID, interval, since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	r.writeStatsJSON(w, http.StatusOK, trends)
+}
+
+// handleGetPlayerMaps returns the player's record on each map they've
+// finished a match on, most played first. sort picks kd_ratio,
+// win_rate or last_played instead; order=asc turns it around to find
+// their worst maps.
+func (r *Router) handleGetPlayerMaps(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+
+	q := req.URL.Query()
+	period := q.Get("period")
+	if period == "" {
+		period = "all"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+	gameType := q.Get("game_type")
+	if gameType != "" && !validateGameType(gameType) {
+		writeError(w, http.StatusBadRequest, "invalid game_type")
+		return
+	}
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = "matches"
+	}
+	if !storage.ValidPlayerMapSort(sort) {
+		writeError(w, http.StatusBadRequest, "sort must be matches, kd_ratio, win_rate, or last_played")
+		return
+	}
+	order := q.Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		writeError(w, http.StatusBadRequest, "order must be asc or desc")
+		return
+	}
+
+	maps, err := r.store.GetPlayerMapStats(req.Context(), playerID, period, gameType, sort, order != "asc")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, maps)
+}
+
+// handleGetPlayerPing returns the player's ping over the period,
+// overall and per server, with their recent lag spikes. Defaults to
+// the last month.
+func (r *Router) handleGetPlayerPing(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = "month"
+	}
+	if !validatePeriod(period) {
+		writeError(w, http.StatusBadRequest, "invalid period")
+		return
+	}
+
+	response, err := r.store.GetPlayerPingStats(req.Context(), playerID, period)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, response)
+}
+
 // handleGetPlayerSessions returns recent sessions for a specific player (admin only)
 func (r *Router) handleGetPlayerSessions(w http.ResponseWriter, req *http.Request) {
 	playerID, err := parseID(req, "id")
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// impersonationTTL is how long a "view as" token lasts.
+const impersonationTTL = 15 * time.Minute
+
+// impersonationAllows reports whether a "view as" token may be used
+// for req. It's read-only, and never reaches the user's credentials
+// (game token, source .creds) since the admin could walk off with
+// them.
+func impersonationAllows(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	path := req.URL.Path
+	return path != "/api/auth/game-token" && !strings.HasSuffix(path, "/creds")
+}
+
+// ImpersonateRequest is the request body for starting a "view as".
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonateResponse is a login response for the impersonated user,
+// plus who is looking and until when.
+type ImpersonateResponse struct {
+	LoginResponse
+	ImpersonatedBy int64     `json:"impersonated_by"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// handleImpersonateUser issues a short-lived, read-only token that
+// lets an admin see the site as the given user, to reproduce what they
+// see without their password. The reason is required and the token is
+// logged before it's handed out. Admin accounts can't be impersonated.
+//
+// path: POST /api/users/{id}/impersonate
+func (r *Router) handleImpersonateUser(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	userID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	var body ImpersonateRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" || len(body.Reason) > 500 {
+		writeError(w, http.StatusBadRequest, "reason is required (at most 500 characters)")
+		return
+	}
+
+	user, err := r.store.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+	if user.ID == claims.UserID {
+		writeError(w, http.StatusBadRequest, "cannot impersonate yourself")
+		return
+	}
+	if user.IsAdmin {
+		writeError(w, http.StatusForbidden, "cannot impersonate an admin")
+		return
+	}
+
+	token, expiresAt, err := r.auth.GenerateImpersonationToken(user.ID, user.Username, user.PlayerID, claims.UserID, impersonationTTL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	if _, err := r.store.RecordImpersonation(req.Context(), storage.Impersonation{
+		AdminUserID:   sql.NullInt64{Int64: claims.UserID, Valid: true},
+		AdminUsername: claims.Username,
+		UserID:        sql.NullInt64{Int64: user.ID, Valid: true},
+		Username:      user.Username,
+		Reason:        body.Reason,
+		RemoteAddr:    req.RemoteAddr,
+		StartedAt:     time.Now(),
+		ExpiresAt:     expiresAt,
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record impersonation")
+		return
+	}
+	log.Printf("audit: impersonate user=%q user_id=%d actor=%s actor_id=%d remote=%s reason=%q",
+		user.Username, user.ID, claims.Username, claims.UserID, req.RemoteAddr, body.Reason)
+
+	writeJSON(w, http.StatusOK, ImpersonateResponse{
+		LoginResponse: LoginResponse{
+			Token:    token,
+			Username: user.Username,
+			PlayerID: user.PlayerID,
+		},
+		ImpersonatedBy: claims.UserID,
+		ExpiresAt:      expiresAt.UTC(),
+	})
+}
+
+// impersonationJSON is the wire shape of one row in
+// /api/admin/impersonations. The user IDs are omitted once that
+// account has been deleted.
+type impersonationJSON struct {
+	ID            int64  `json:"id"`
+	AdminUserID   *int64 `json:"admin_user_id,omitempty"`
+	AdminUsername string `json:"admin_username"`
+	UserID        *int64 `json:"user_id,omitempty"`
+	Username      string `json:"username"`
+	Reason        string `json:"reason"`
+	RemoteAddr    string `json:"remote_addr"`
+	StartedAt     string `json:"started_at"`
+	ExpiresAt     string `json:"expires_at"`
+}
+
+// handleListImpersonations returns the impersonation log, newest
+// first. ?limit defaults to 100, capped at 500.
+//
+// path: GET /api/admin/impersonations
+func (r *Router) handleListImpersonations(w http.ResponseWriter, req *http.Request) {
+	rows, err := r.store.ListImpersonations(req.Context(), parseLimit(req, 100, 500))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	out := make([]impersonationJSON, 0, len(rows))
+	for _, e := range rows {
+		entry := impersonationJSON{
+			ID:            e.ID,
+			AdminUsername: e.AdminUsername,
+			Username:      e.Username,
+			Reason:        e.Reason,
+			RemoteAddr:    e.RemoteAddr,
+			StartedAt:     e.StartedAt.UTC().Format(time.RFC3339),
+			ExpiresAt:     e.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+		if e.AdminUserID.Valid {
+			id := e.AdminUserID.Int64
+			entry.AdminUserID = &id
+		}
+		if e.UserID.Valid {
+			id := e.UserID.Int64
+			entry.UserID = &id
+		}
+		out = append(out, entry)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
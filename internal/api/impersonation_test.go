@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestImpersonateUser(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, aliceID := tr.loginAs(t, "alice", false)
+	_, otherAdminID := tr.loginAs(t, "root", true)
+	path := "/api/users/" + strconv.FormatInt(aliceID, 10) + "/impersonate"
+
+	if w := tr.do("POST", path, `{"reason":"x"}`, aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", path, `{"reason":" "}`, adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("no reason: %d, want 400", w.Code)
+	}
+	if w := tr.do("POST", "/api/users/"+strconv.FormatInt(otherAdminID, 10)+"/impersonate", `{"reason":"x"}`, adminTok); w.Code != http.StatusForbidden {
+		t.Errorf("admin target: %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", "/api/users/9999/impersonate", `{"reason":"x"}`, adminTok); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user: %d, want 404", w.Code)
+	}
+
+	w := tr.do("POST", path, `{"reason":"ticket 42: profile looks wrong"}`, adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("impersonate: %d %s", w.Code, w.Body)
+	}
+	var resp ImpersonateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Username != "alice" || resp.IsAdmin || resp.ImpersonatedBy == 0 || resp.Token == "" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// Reads work as alice.
+	w = tr.do("GET", "/api/account/profile", "", resp.Token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("profile: %d %s", w.Code, w.Body)
+	}
+	var profile AccountProfileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.User.Username != "alice" {
+		t.Errorf("profile user = %q, want alice", profile.User.Username)
+	}
+
+	// Writes, credentials, and admin pages don't.
+	for _, c := range []struct {
+		method, path string
+		want         int
+	}{
+		{"POST", "/api/account/link-code", http.StatusUnauthorized},
+		{"GET", "/api/auth/game-token", http.StatusUnauthorized},
+		{"GET", "/api/admin/impersonations", http.StatusForbidden},
+	} {
+		if w := tr.do(c.method, c.path, "", resp.Token); w.Code != c.want {
+			t.Errorf("%s %s: %d, want %d", c.method, c.path, w.Code, c.want)
+		}
+	}
+
+	w = tr.do("GET", "/api/admin/impersonations", "", adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: %d %s", w.Code, w.Body)
+	}
+	var log []impersonationJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].AdminUsername != "admin" || log[0].Username != "alice" || log[0].Reason != "ticket 42: profile looks wrong" {
+		t.Errorf("unexpected log: %+v", log)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleGetDuelLadder returns the 1v1 challenge ladder and its recent
+// challenges. ?player_id= narrows the challenges to one player's;
+// as_of behaves as it does on the leaderboard.
+//
+// path: GET /api/ladders/duel
+func (r *Router) handleGetDuelLadder(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 20, 100)
+
+	var playerID int64
+	if v := req.URL.Query().Get("player_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		playerID = id
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetDuelLadder(req.Context(), asOf, limit, playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetDuels lists the duels the ladder counts, newest first.
+// ?player_id= keeps one player's; as_of behaves as it does on the
+// leaderboard.
+//
+// path: GET /api/duels
+func (r *Router) handleGetDuels(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 20, 100)
+
+	var playerID int64
+	if v := req.URL.Query().Get("player_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		playerID = id
+	}
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetRecentDuels(req.Context(), asOf, limit, playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetDuelRankings ranks duellers by their win/loss record.
+//
+// path: GET /api/duels/rankings
+func (r *Router) handleGetDuelRankings(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 50, 100)
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetDuelRankings(req.Context(), asOf, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetPlayerDuels returns a player's duel record, head-to-head
+// table and recent duels.
+//
+// path: GET /api/players/{id}/duels
+func (r *Router) handleGetPlayerDuels(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	limit := parseLimit(req, 10, 100)
+
+	asOf, err := parseAsOf(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := r.store.GetPlayerDuels(req.Context(), playerID, asOf, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
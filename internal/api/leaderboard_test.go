@@ -47,3 +47,29 @@ func TestHandleGetLeaderboard_AsOfAccepted(t *testing.T) {
 		})
 	}
 }
+
+// Unknown weapons, and weapons with a category other than frags, are
+// rejected before the store is touched.
+func TestHandleGetLeaderboard_WeaponValidation(t *testing.T) {
+	r := &Router{}
+	for _, q := range []string{"weapon=spoon", "weapon=railgun&category=deaths"} {
+		req := httptest.NewRequest("GET", "/api/stats/leaderboard?"+q, nil)
+		w := httptest.NewRecorder()
+		r.handleGetLeaderboard(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400; body=%s", q, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Eligibility overrides are refused to anyone but an admin, before the
+// store is touched.
+func TestHandleGetLeaderboard_RulesOverrideNeedsAdmin(t *testing.T) {
+	r := &Router{}
+	req := httptest.NewRequest("GET", "/api/stats/leaderboard?min_matches=0", nil)
+	w := httptest.NewRecorder()
+	r.handleGetLeaderboard(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403; body=%s", w.Code, w.Body.String())
+	}
+}
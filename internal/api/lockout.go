@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// Brute-force protection for logins and claim codes. Failures are
+// counted per client IP and per username in the auth_lockouts table, so
+// a restart doesn't hand an attacker a fresh set of guesses. After
+// lockoutThreshold failures each one locks the key out for twice as
+// long as the last.
+const (
+	lockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = 24 * time.Hour
+	// lockoutForget is how long a key has to go without failing for its
+	// count to start over.
+	lockoutForget = 24 * time.Hour
+)
+
+// lockoutDuration is how long the failures'th failure locks a key out.
+func lockoutDuration(failures int) time.Duration {
+	if failures < lockoutThreshold {
+		return 0
+	}
+	shift := failures - lockoutThreshold
+	if shift > 20 {
+		return lockoutMax
+	}
+	return min(lockoutBase<<shift, lockoutMax)
+}
+
+type lockoutKey struct {
+	kind, key string
+}
+
+// guardAuth wraps an endpoint that checks a secret. Requests from a
+// locked-out IP, or naming a locked-out username when byUsername is
+// set, get 429 without reaching next. A response with failStatus counts
+// as a failed guess; a 2xx clears the slate.
+func (r *Router) guardAuth(failStatus int, byUsername bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		keys := []lockoutKey{{storage.LockoutIP, getClientIP(req)}}
+		if byUsername {
+			body, err := io.ReadAll(io.LimitReader(req.Body, 1<<16))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			var peek struct {
+				Username string `json:"username"`
+			}
+			if json.Unmarshal(body, &peek) == nil {
+				if name := strings.ToLower(strings.TrimSpace(peek.Username)); name != "" {
+					keys = append(keys, lockoutKey{storage.LockoutUsername, name})
+				}
+			}
+		}
+
+		now := time.Now()
+		for _, k := range keys {
+			l, err := r.store.GetAuthLockout(ctx, k.kind, k.key)
+			if err != nil || l.LockedUntil == nil || !now.Before(*l.LockedUntil) {
+				continue
+			}
+			wait := int(l.LockedUntil.Sub(now).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(wait))
+			writeError(w, http.StatusTooManyRequests, "too many failed attempts, try again later")
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, req)
+
+		switch {
+		case rec.status == failStatus:
+			for _, k := range keys {
+				l, err := r.store.RecordAuthFailure(ctx, k.kind, k.key, now, lockoutForget, lockoutDuration)
+				if err != nil {
+					log.Printf("auth lockout: recording failure for %s %q: %v", k.kind, k.key, err)
+					continue
+				}
+				if l.LockedUntil != nil {
+					log.Printf("audit: auth_lockout kind=%s key=%q path=%s failures=%d until=%s",
+						k.kind, k.key, req.URL.Path, l.Failures, l.LockedUntil.UTC().Format(time.RFC3339))
+				}
+			}
+		case rec.status >= 200 && rec.status < 300:
+			for _, k := range keys {
+				if err := r.store.ClearAuthLockout(ctx, k.kind, k.key); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					log.Printf("auth lockout: clearing %s %q: %v", k.kind, k.key, err)
+				}
+			}
+		}
+	}
+}
+
+type authLockoutJSON struct {
+	Kind          string  `json:"kind"`
+	Key           string  `json:"key"`
+	Failures      int     `json:"failures"`
+	LastFailureAt string  `json:"last_failure_at"`
+	LockedUntil   *string `json:"locked_until,omitempty"`
+	Locked        bool    `json:"locked"`
+}
+
+// handleListAuthLockouts returns the IPs and usernames with recent
+// failed attempts, most recent first, and whether each is locked out.
+//
+// path: GET /api/admin/auth-lockouts
+func (r *Router) handleListAuthLockouts(w http.ResponseWriter, req *http.Request) {
+	rows, err := r.store.ListAuthLockouts(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	now := time.Now()
+	out := make([]authLockoutJSON, 0, len(rows))
+	for _, l := range rows {
+		entry := authLockoutJSON{
+			Kind:          l.Kind,
+			Key:           l.Key,
+			Failures:      l.Failures,
+			LastFailureAt: l.LastFailureAt.UTC().Format(time.RFC3339),
+		}
+		if l.LockedUntil != nil {
+			until := l.LockedUntil.UTC().Format(time.RFC3339)
+			entry.LockedUntil = &until
+			entry.Locked = now.Before(*l.LockedUntil)
+		}
+		out = append(out, entry)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleClearAuthLockout forgets the failed attempts for an IP or
+// username, lifting any lockout. kind is "ip" or "username".
+//
+// path: DELETE /api/admin/auth-lockouts/{kind}/{key}
+func (r *Router) handleClearAuthLockout(w http.ResponseWriter, req *http.Request) {
+	kind, key := req.PathValue("kind"), req.PathValue("key")
+	if kind != storage.LockoutIP && kind != storage.LockoutUsername {
+		writeError(w, http.StatusBadRequest, `kind must be "ip" or "username"`)
+		return
+	}
+	if kind == storage.LockoutUsername {
+		key = strings.ToLower(key)
+	}
+	err := r.store.ClearAuthLockout(req.Context(), kind, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "no failed attempts recorded")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if kind == storage.LockoutIP {
+		r.loginLimiter.Reset(key)
+	}
+	claims := r.getAuthClaims(req)
+	actor := "unknown"
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: clear_auth_lockout kind=%s key=%q actor=%s remote=%s", kind, key, actor, req.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "lockout cleared"})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:   0,
+		4:   0,
+		5:   time.Minute,
+		6:   2 * time.Minute,
+		8:   8 * time.Minute,
+		100: 24 * time.Hour,
+	} {
+		if got := lockoutDuration(failures); got != want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestClaimCodeLockout(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+
+	for i := 0; i < lockoutThreshold; i++ {
+		if w := tr.do("POST", "/api/claim/validate", `{"code":"000000"}`, ""); w.Code != http.StatusNotFound {
+			t.Fatalf("guess %d: %d, want 404", i+1, w.Code)
+		}
+	}
+	w := tr.do("POST", "/api/claim/validate", `{"code":"000000"}`, "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("guess past the threshold: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = tr.do("GET", "/api/admin/auth-lockouts", "", adminTok)
+	var lockouts []authLockoutJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &lockouts); err != nil {
+		t.Fatal(err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Kind != "ip" || lockouts[0].Failures != lockoutThreshold || !lockouts[0].Locked {
+		t.Fatalf("lockouts: %+v", lockouts)
+	}
+
+	if w := tr.do("DELETE", "/api/admin/auth-lockouts/ip/"+lockouts[0].Key, "", adminTok); w.Code != http.StatusOK {
+		t.Fatalf("clear: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("POST", "/api/claim/validate", `{"code":"000000"}`, ""); w.Code != http.StatusNotFound {
+		t.Errorf("guess after clearing: %d, want 404", w.Code)
+	}
+	if w := tr.do("DELETE", "/api/admin/auth-lockouts/host/x", "", adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("bad kind: %d, want 400", w.Code)
+	}
+}
+
+// A username is locked out whichever IP the guesses come from, and a
+// good password doesn't get past it.
+func TestLoginLockoutByUsername(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.loginAs(t, "alice", false)
+
+	login := func(ip, password string) int {
+		req := httptest.NewRequest("POST", "/api/auth/login",
+			strings.NewReader(`{"username":"Alice","password":"`+password+`"}`))
+		req.Header.Set("X-Real-IP", ip)
+		w := httptest.NewRecorder()
+		tr.r.ServeHTTP(w, req)
+		return w.Code
+	}
+	for i := 0; i < lockoutThreshold; i++ {
+		if got := login("203.0.113."+string(rune('1'+i)), "wrong"); got != http.StatusUnauthorized {
+			t.Fatalf("guess %d: %d, want 401", i+1, got)
+		}
+	}
+	if got := login("203.0.113.99", "password123"); got != http.StatusTooManyRequests {
+		t.Errorf("login while locked out: %d, want 429", got)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/hub"
+)
+
+// matchFeedSink receives the poller's fast (watched-server) updates
+// and fans them out to that server's spectators only.
+type matchFeedSink struct {
+	r *Router
+}
+
+func (s matchFeedSink) Broadcast(event domain.Event) {
+	if !s.r.shouldBroadcast(event) {
+		return
+	}
+	s.r.wsHub.BroadcastMatch(event)
+}
+
+// MatchSink returns the sink main.go hands to the poller's
+// SetMatchSink.
+func (r *Router) MatchSink() hub.LiveEventSink {
+	return matchFeedSink{r: r}
+}
+
+// handleMatchWebSocket streams one server's events to a spectator
+// page. The first message is a match_snapshot (current status and open
+// match) so a page joining mid-match can render immediately; after
+// that the client gets every live event for the server plus
+// server_update about once a second (the poller's fast-poll rate)
+// while it's connected.
+//
+// path: GET /ws/match/{id}   (id is the server id)
+func (r *Router) handleMatchWebSocket(w http.ResponseWriter, req *http.Request) {
+	serverID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	server, err := r.store.GetServerByID(req.Context(), serverID)
+	if err != nil || server == nil {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+
+	var snapshot domain.MatchSnapshotEvent
+	if status := r.lookupServerStatus(serverID); status != nil {
+		public := status.Public()
+		snapshot.Status = &public
+	}
+	if m, err := r.store.GetCurrentMatch(req.Context(), serverID); err != nil {
+		log.Printf("match feed: current match for server %d: %v", serverID, err)
+	} else {
+		snapshot.Match = m
+	}
+	first, err := json.Marshal(domain.Event{
+		Type:      domain.EventMatchSnapshot,
+		ServerID:  serverID,
+		Timestamp: time.Now().UTC(),
+		Data:      snapshot,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build snapshot")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+
+	client := &WebSocketClient{
+		hub:         r.wsHub,
+		conn:        conn,
+		send:        make(chan []byte, wsSendQueue),
+		remoteAddr:  getClientIP(req),
+		matchServer: serverID,
+	}
+	client.send <- first
+	if r.poller != nil {
+		client.onClose = r.poller.Watch(serverID)
+	}
+
+	r.wsHub.register <- client
+
+	go client.writePump()
+	go client.readPump()
+}
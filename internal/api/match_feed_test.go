@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/gorilla/websocket"
+)
+
+func TestMatchWebSocketSnapshotAndFilter(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	go tr.r.wsHub.Run()
+
+	watched := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	other := &domain.Server{Key: "ffa", Address: "127.0.0.1:27961"}
+	for _, srv := range []*domain.Server{watched, other} {
+		if err := tr.store.UpsertServer(ctx, "local", srv); err != nil {
+			t.Fatalf("UpsertServer: %v", err)
+		}
+		if err := tr.store.SetServerHandshakeRequired(ctx, srv.ID, true); err != nil {
+			t.Fatalf("SetServerHandshakeRequired: %v", err)
+		}
+	}
+	m := &domain.Match{UUID: "m-1", ServerID: watched.ID, MapName: "q3ctf1", GameType: "ctf", StartedAt: time.Now().Add(-5 * time.Minute)}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + fmt.Sprintf("/ws/match/%d", watched.ID)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var first struct {
+		Event    string                    `json:"event"`
+		ServerID int64                     `json:"server_id"`
+		Data     domain.MatchSnapshotEvent `json:"data"`
+	}
+	if err := conn.ReadJSON(&first); err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if first.Event != domain.EventMatchSnapshot || first.ServerID != watched.ID {
+		t.Fatalf("first message: got %s for server %d", first.Event, first.ServerID)
+	}
+	if first.Data.Match == nil || first.Data.Match.MapName != "q3ctf1" {
+		t.Errorf("snapshot match: got %+v", first.Data.Match)
+	}
+
+	// Give the hub a moment to register the client before broadcasting.
+	time.Sleep(50 * time.Millisecond)
+	tr.r.Broadcast(domain.Event{Type: domain.EventFrag, ServerID: other.ID})
+	tr.r.MatchSink().Broadcast(domain.Event{Type: domain.EventServerUpdate, ServerID: watched.ID})
+
+	var next domain.Event
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := json.Unmarshal(data, &next); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if next.Type != domain.EventServerUpdate || next.ServerID != watched.ID {
+		t.Errorf("got %s for server %d; other server's events should be filtered", next.Type, next.ServerID)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// handleListMergeSuggestions lists pairs of players the hub's analysis
+// thinks are one person, likeliest first, for an admin to confirm with
+// a merge or dismiss.
+//
+// path: GET /api/admin/players/merge-suggestions
+func (r *Router) handleListMergeSuggestions(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 50, 200)
+	suggestions, err := r.store.ListMergeSuggestions(req.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+// handleDismissMergeSuggestion marks a suggested pair as two different
+// people so it isn't suggested again.
+//
+// path: POST /api/admin/players/merge-suggestions/{id}/dismiss
+func (r *Router) handleDismissMergeSuggestion(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid suggestion id")
+		return
+	}
+	if err := r.store.DismissMergeSuggestion(req.Context(), id, time.Now()); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "suggestion not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
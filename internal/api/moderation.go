@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// handleListModerationIncidents lists chat filter hits, newest first.
+// Optional server_id and player_id narrow the list.
+//
+// path: GET /api/admin/moderation
+func (r *Router) handleListModerationIncidents(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	filter := storage.ModerationFilter{Limit: parseLimit(req, 100, 500)}
+	if v := q.Get("server_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid server_id")
+			return
+		}
+		filter.ServerID = id
+	}
+	if v := q.Get("player_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		filter.PlayerID = id
+	}
+
+	incidents, err := r.store.ListModerationIncidents(req.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, incidents)
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/auth"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// oauthStateTTL is how long a user has to finish signing in with the
+// provider.
+const oauthStateTTL = 10 * time.Minute
+
+// oauthStateCookie holds a pending sign-in's state in the browser that
+// started it. The callback must present it, so a link to the callback
+// with someone else's state can't sign a victim into the sender's
+// account or link the sender's identity to the victim's.
+const oauthStateCookie = "trinity_oauth_state"
+
+// reauthTTL is how long a fresh provider sign-in lets an account with
+// no password set one.
+const reauthTTL = 5 * time.Minute
+
+// oauthPending is a sign-in started at the provider that hasn't come
+// back yet. linkUserID is set when an existing account is linking the
+// identity; reauthUserID when one is proving it's still its owner;
+// claimCode when a new account is registering with it.
+type oauthPending struct {
+	provider     string
+	verifier     string
+	linkUserID   int64
+	reauthUserID int64
+	claimCode    string
+	expires      time.Time
+}
+
+// oauthStates holds pending sign-ins by their state parameter. Each
+// state is good for one callback.
+type oauthStates struct {
+	mu      sync.Mutex
+	pending map[string]oauthPending
+}
+
+func (s *oauthStates) put(state string, p oauthPending) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, v := range s.pending {
+		if now.After(v.expires) {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[state] = p
+}
+
+func (s *oauthStates) take(state string) (oauthPending, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[state]
+	delete(s.pending, state)
+	if !ok || time.Now().After(p.expires) {
+		return oauthPending{}, false
+	}
+	return p, true
+}
+
+// reauthGrant lets userID set a first password until expires.
+type reauthGrant struct {
+	userID  int64
+	expires time.Time
+}
+
+// reauthGrants holds the grants handed out by fresh provider sign-ins,
+// by their token. Each is good for one password change.
+type reauthGrants struct {
+	mu     sync.Mutex
+	grants map[string]reauthGrant
+}
+
+// grant returns a new token for userID.
+func (g *reauthGrants) grant(userID int64) (string, error) {
+	token, _, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for k, v := range g.grants {
+		if now.After(v.expires) {
+			delete(g.grants, k)
+		}
+	}
+	g.grants[token] = reauthGrant{userID: userID, expires: now.Add(reauthTTL)}
+	return token, nil
+}
+
+// take spends token, reporting whether it was a live grant for userID.
+func (g *reauthGrants) take(token string, userID int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v, ok := g.grants[token]
+	delete(g.grants, token)
+	return ok && v.userID == userID && !time.Now().After(v.expires)
+}
+
+// OAuthProviderInfo is a sign-in option as listed for the SPA.
+type OAuthProviderInfo struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// SetOAuth enables sign-in with the given providers. publicURL is the
+// hub's external base URL, used to build each provider's redirect URI.
+func (r *Router) SetOAuth(publicURL string, providers []auth.OAuthProvider) {
+	r.oauthBaseURL = strings.TrimRight(publicURL, "/")
+	r.oauthProviders = make(map[string]*auth.OAuthProvider, len(providers))
+	r.oauthInfo = make([]OAuthProviderInfo, 0, len(providers))
+	for i := range providers {
+		p := providers[i]
+		r.oauthProviders[p.Name] = &p
+		r.oauthInfo = append(r.oauthInfo, OAuthProviderInfo{Name: p.Name, Label: p.Label})
+	}
+}
+
+func (r *Router) oauthRedirectURI(provider string) string {
+	return r.oauthBaseURL + "/api/auth/oauth/" + provider + "/callback"
+}
+
+// startOAuth records a pending sign-in and returns the provider URL to
+// send the browser to.
+func (r *Router) startOAuth(w http.ResponseWriter, req *http.Request, pending oauthPending) {
+	p := r.oauthProviders[req.PathValue("provider")]
+	if p == nil {
+		writeError(w, http.StatusNotFound, "unknown sign-in provider")
+		return
+	}
+	state, verifier, challenge, err := auth.NewOAuthState()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to start sign-in")
+		return
+	}
+	pending.provider = p.Name
+	pending.verifier = verifier
+	pending.expires = time.Now().Add(oauthStateTTL)
+	r.oauthStates.put(state, pending)
+	r.setOAuthStateCookie(w, state, int(oauthStateTTL/time.Second))
+	writeJSON(w, http.StatusOK, map[string]string{
+		"url": p.AuthCodeURL(r.oauthRedirectURI(p.Name), state, challenge),
+	})
+}
+
+// setOAuthStateCookie sets the oauthStateCookie for maxAge seconds, or
+// clears it when maxAge is negative. Lax lets the provider's redirect
+// back carry it.
+func (r *Router) setOAuthStateCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oauth/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(r.oauthBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OAuthStartRequest is the optional request body for starting a sign-in
+type OAuthStartRequest struct {
+	ClaimCode string `json:"claim_code"`
+}
+
+// handleOAuthStart begins signing in with a provider. With a claim
+// code, an identity that isn't linked yet creates a new account for
+// the claimed player, as /api/claim/register does with a password.
+// Returns {"url"} for the browser to visit.
+//
+// path: POST /api/auth/oauth/{provider}/start
+func (r *Router) handleOAuthStart(w http.ResponseWriter, req *http.Request) {
+	var body OAuthStartRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if body.ClaimCode != "" && !r.features.Registration {
+		writeError(w, http.StatusForbidden, "registration is closed on this hub")
+		return
+	}
+	r.startOAuth(w, req, oauthPending{claimCode: body.ClaimCode})
+}
+
+// handleLinkIdentity begins linking a provider identity to the
+// signed-in account, so it can be used to sign in from then on.
+//
+// path: POST /api/account/identities/{provider}
+func (r *Router) handleLinkIdentity(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	r.startOAuth(w, req, oauthPending{linkUserID: claims.UserID})
+}
+
+// handleOAuthReauth begins signing in again with a provider already
+// linked to the signed-in account. An account with no password needs
+// this before setting one, so a stolen access token alone can't add a
+// password login.
+//
+// path: POST /api/account/reauth/{provider}
+func (r *Router) handleOAuthReauth(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	r.startOAuth(w, req, oauthPending{reauthUserID: claims.UserID})
+}
+
+// handleOAuthCallback is where the provider sends the browser back.
+// It always redirects into the SPA: to /account after linking or
+// signing in again, with oauth_reauth set to a reauthGrants token for
+// the latter, or to / with the new session in the URL fragment, where
+// the SPA picks it up. Failures are reported as oauth_error in the
+// fragment. The state must match the browser's oauthStateCookie.
+//
+// path: GET /api/auth/oauth/{provider}/callback
+func (r *Router) handleOAuthCallback(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	state := q.Get("state")
+	cookie, err := req.Cookie(oauthStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		oauthRedirect(w, req, "/", url.Values{"oauth_error": {"Sign-in expired. Please try again."}})
+		return
+	}
+	r.setOAuthStateCookie(w, "", -1)
+	pending, ok := r.oauthStates.take(state)
+	p := r.oauthProviders[req.PathValue("provider")]
+	if !ok || p == nil || pending.provider != p.Name {
+		oauthRedirect(w, req, "/", url.Values{"oauth_error": {"Sign-in expired. Please try again."}})
+		return
+	}
+	back := "/"
+	if pending.linkUserID != 0 || pending.reauthUserID != 0 {
+		back = "/account"
+	}
+	if q.Get("error") != "" || q.Get("code") == "" {
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"Sign-in with " + p.Label + " was cancelled."}})
+		return
+	}
+	identity, err := p.Identify(req.Context(), r.oauthClient, q.Get("code"), r.oauthRedirectURI(p.Name), pending.verifier)
+	if err != nil {
+		log.Printf("oauth: %v", err)
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't sign in with " + p.Label + "."}})
+		return
+	}
+
+	now := time.Now()
+	if pending.linkUserID != 0 {
+		err := r.store.LinkUserIdentity(req.Context(), pending.linkUserID, p.Name, identity.Subject, identity.Name, now)
+		switch {
+		case errors.Is(err, storage.ErrIdentityLinked):
+			oauthRedirect(w, req, back, url.Values{"oauth_error": {"That " + p.Label + " account is linked to another user."}})
+		case errors.Is(err, storage.ErrProviderLinked):
+			oauthRedirect(w, req, back, url.Values{"oauth_error": {"Unlink your current " + p.Label + " account first."}})
+		case err != nil:
+			log.Printf("oauth: linking %s identity to user %d: %v", p.Name, pending.linkUserID, err)
+			oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't link your " + p.Label + " account."}})
+		default:
+			oauthRedirect(w, req, back, url.Values{"oauth_linked": {p.Name}})
+		}
+		return
+	}
+
+	user, err := r.store.GetUserByIdentity(req.Context(), p.Name, identity.Subject)
+	if pending.reauthUserID != 0 {
+		if err != nil || user.ID != pending.reauthUserID {
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("oauth: looking up %s identity: %v", p.Name, err)
+			}
+			oauthRedirect(w, req, back, url.Values{"oauth_error": {"That " + p.Label + " account isn't linked to yours."}})
+			return
+		}
+		token, err := r.reauthGrants.grant(user.ID)
+		if err != nil {
+			oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't sign in with " + p.Label + "."}})
+			return
+		}
+		r.store.TouchUserIdentity(req.Context(), p.Name, identity.Subject, identity.Name, now)
+		oauthRedirect(w, req, back, url.Values{"oauth_reauth": {token}})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) && pending.claimCode != "" {
+		user, err = r.registerOAuthUser(req.Context(), p, identity, pending.claimCode, now)
+		if err != nil {
+			oauthRedirect(w, req, "/claim", url.Values{"oauth_error": {err.Error()}})
+			return
+		}
+	} else if errors.Is(err, sql.ErrNoRows) {
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"No account is linked to that " + p.Label +
+			" account. Claim your player in game with !claim, or link " + p.Label + " from your account page."}})
+		return
+	}
+	if err != nil {
+		log.Printf("oauth: looking up %s identity: %v", p.Name, err)
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't sign in with " + p.Label + "."}})
+		return
+	}
+
+	token, err := r.auth.GenerateToken(user.ID, user.Username, user.IsAdmin, user.PlayerID, user.PasswordChangeRequired)
+	if err != nil {
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't sign in with " + p.Label + "."}})
+		return
+	}
+	refresh, err := r.issueRefreshToken(req.Context(), user.ID)
+	if err != nil {
+		oauthRedirect(w, req, back, url.Values{"oauth_error": {"Couldn't sign in with " + p.Label + "."}})
+		return
+	}
+	r.store.UpdateUserLastLogin(req.Context(), user.ID)
+	r.store.TouchUserIdentity(req.Context(), p.Name, identity.Subject, identity.Name, now)
+	oauthRedirect(w, req, back, url.Values{"oauth_token": {token}, "oauth_refresh_token": {refresh}})
+}
+
+// registerOAuthUser creates an account for the player behind
+// claimCode, signed in with identity instead of a password. The
+// username comes from the provider's display name. Errors are fit to
+// show the user.
+func (r *Router) registerOAuthUser(ctx context.Context, p *auth.OAuthProvider, identity auth.OAuthIdentity, claimCode string, now time.Time) (*storage.User, error) {
+	code, err := r.store.GetValidClaimCode(ctx, claimCode)
+	if err != nil {
+		return nil, errors.New("invalid or expired claim code")
+	}
+	username, err := r.freeUsername(ctx, identity.Name)
+	if err != nil {
+		return nil, errors.New("couldn't pick a username; register with a password instead")
+	}
+	userID, err := r.store.ClaimRegister(ctx, code.ID, code.PlayerID, username, auth.NoPasswordHash)
+	if err != nil {
+		if strings.Contains(err.Error(), "already linked") {
+			return nil, errors.New("player is already linked to another account")
+		}
+		log.Printf("oauth: registering %s user: %v", p.Name, err)
+		return nil, errors.New("failed to create account")
+	}
+	if err := r.store.LinkUserIdentity(ctx, userID, p.Name, identity.Subject, identity.Name, now); err != nil {
+		log.Printf("oauth: linking %s identity to new user %d: %v", p.Name, userID, err)
+		return nil, errors.New("failed to create account")
+	}
+	return r.store.GetUserByID(ctx, userID)
+}
+
+// freeUsername turns a provider display name into an unused username
+// that passes the registration rules, adding a number if needed.
+func (r *Router) freeUsername(ctx context.Context, name string) (string, error) {
+	var b strings.Builder
+	for _, c := range name {
+		switch {
+		case c == ' ' || c == '-' || c == '.':
+			b.WriteByte('_')
+		case c < 128 && validUsernameRegex.MatchString(string(c)):
+			b.WriteRune(c)
+		}
+	}
+	base := b.String()
+	if len(base) > 12 {
+		base = base[:12]
+	}
+	if len(base) < 2 {
+		base = "player"
+	}
+	for i := 1; i < 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		_, err := r.store.GetUserByUsername(ctx, candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no free username")
+}
+
+// oauthRedirect sends the browser to path in the SPA with values in the
+// fragment, which never reaches a server log.
+func oauthRedirect(w http.ResponseWriter, req *http.Request, path string, values url.Values) {
+	http.Redirect(w, req, path+"#"+values.Encode(), http.StatusFound)
+}
+
+// identityJSON is a linked identity as returned to its user.
+type identityJSON struct {
+	Provider    string     `json:"provider"`
+	DisplayName string     `json:"display_name"`
+	LinkedAt    time.Time  `json:"linked_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// handleListIdentities returns the identities linked to the signed-in
+// account.
+//
+// path: GET /api/account/identities
+func (r *Router) handleListIdentities(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	ids, err := r.store.ListUserIdentities(req.Context(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list identities")
+		return
+	}
+	out := make([]identityJSON, len(ids))
+	for i, id := range ids {
+		out[i] = identityJSON{Provider: id.Provider, DisplayName: id.DisplayName, LinkedAt: id.LinkedAt, LastLoginAt: id.LastLoginAt}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleUnlinkIdentity removes a linked identity. An account created
+// with an identity has no password, so it can't drop its last one
+// until it sets a password.
+//
+// path: DELETE /api/account/identities/{provider}
+func (r *Router) handleUnlinkIdentity(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	user, err := r.store.GetUserByID(req.Context(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get user")
+		return
+	}
+	if user.PasswordHash == auth.NoPasswordHash {
+		ids, err := r.store.ListUserIdentities(req.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list identities")
+			return
+		}
+		if len(ids) <= 1 {
+			writeError(w, http.StatusConflict, "set a password before unlinking your only sign-in")
+			return
+		}
+	}
+	err = r.store.UnlinkUserIdentity(req.Context(), user.ID, req.PathValue("provider"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "no identity linked for that provider")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to unlink identity")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/auth"
+)
+
+// fakeOAuthProvider answers the token and userinfo endpoints. Each
+// code is the subject it signs in as; names holds display names.
+func fakeOAuthProvider(t *testing.T, names map[string]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code_verifier") == "" || r.FormValue("client_secret") != "shh" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at-" + r.FormValue("code")})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		sub := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer at-")
+		json.NewEncoder(w).Encode(map[string]string{"sub": sub, "name": names[sub]})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// oauthSignIn starts a sign-in at path and follows it back through the
+// callback as subject, returning the callback's redirect.
+func oauthSignIn(t *testing.T, tr *testRouter, path, body, token, subject string) *url.URL {
+	t.Helper()
+	w := tr.do("POST", path, body, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("start %s: %d %s", path, w.Code, w.Body)
+	}
+	var start struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &start); err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(start.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authURL.Query().Get("redirect_uri"); got != "https://hub.example/api/auth/oauth/test/callback" {
+		t.Errorf("redirect_uri = %q", got)
+	}
+	w = oauthCallback(tr, authURL.Query().Get("state"), subject, w.Result().Cookies())
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: %d %s", w.Code, w.Body)
+	}
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// oauthCallback comes back from the provider with state and code, as a
+// browser holding cookies would.
+func oauthCallback(tr *testRouter, state, code string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	q := url.Values{"state": {state}, "code": {code}}
+	req := httptest.NewRequest("GET", "/api/auth/oauth/test/callback?"+q.Encode(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	tr.r.ServeHTTP(w, req)
+	return w
+}
+
+func fragment(t *testing.T, loc *url.URL) url.Values {
+	t.Helper()
+	v, err := url.ParseQuery(loc.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestOAuthSignIn(t *testing.T) {
+	tr := newTestRouter(t)
+	provider := fakeOAuthProvider(t, map[string]string{"111": "alice", "222": "Carol Smith"})
+	tr.r.SetOAuth("https://hub.example/", []auth.OAuthProvider{{
+		Name: "test", Label: "Test", ClientID: "id", ClientSecret: "shh",
+		AuthURL: provider.URL + "/auth", TokenURL: provider.URL + "/token", UserInfoURL: provider.URL + "/userinfo",
+		SubjectField: "sub", NameField: "name",
+	}})
+	aliceTok, _ := tr.loginAs(t, "alice", false)
+	bobTok, _ := tr.loginAs(t, "bob", false)
+
+	// Nobody has linked 111 yet.
+	loc := oauthSignIn(t, tr, "/api/auth/oauth/test/start", "", "", "111")
+	if v := fragment(t, loc); v.Get("oauth_error") == "" || v.Get("oauth_token") != "" {
+		t.Fatalf("unlinked sign-in: %s", loc)
+	}
+
+	loc = oauthSignIn(t, tr, "/api/account/identities/test", "", aliceTok, "111")
+	if loc.Path != "/account" || fragment(t, loc).Get("oauth_linked") != "test" {
+		t.Fatalf("link: %s", loc)
+	}
+	loc = oauthSignIn(t, tr, "/api/account/identities/test", "", bobTok, "111")
+	if fragment(t, loc).Get("oauth_error") == "" {
+		t.Errorf("linking alice's identity to bob: %s", loc)
+	}
+
+	loc = oauthSignIn(t, tr, "/api/auth/oauth/test/start", "", "", "111")
+	v := fragment(t, loc)
+	if v.Get("oauth_token") == "" || v.Get("oauth_refresh_token") == "" {
+		t.Fatalf("sign-in: %s", loc)
+	}
+	w := tr.do("GET", "/api/account/profile", "", v.Get("oauth_token"))
+	var profile AccountProfileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.User.Username != "alice" || !profile.HasPassword {
+		t.Errorf("signed in as %+v", profile)
+	}
+
+	// A state is good for one callback.
+	w = tr.do("POST", "/api/auth/oauth/test/start", "", "")
+	var start struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &start)
+	authURL, _ := url.Parse(start.URL)
+	state, cookies := authURL.Query().Get("state"), w.Result().Cookies()
+	oauthCallback(tr, state, "111", cookies)
+	w = oauthCallback(tr, state, "111", cookies)
+	if loc, _ := url.Parse(w.Header().Get("Location")); fragment(t, loc).Get("oauth_token") != "" {
+		t.Error("replayed state signed in")
+	}
+
+	// A state only works in the browser that started the sign-in, so
+	// a link to the callback can't sign someone else in.
+	w = tr.do("POST", "/api/auth/oauth/test/start", "", "")
+	json.Unmarshal(w.Body.Bytes(), &start)
+	authURL, _ = url.Parse(start.URL)
+	state, cookies = authURL.Query().Get("state"), w.Result().Cookies()
+	other := tr.do("POST", "/api/auth/oauth/test/start", "", "").Result().Cookies()
+	for name, cookies := range map[string][]*http.Cookie{"no cookie": nil, "other cookie": other} {
+		w = oauthCallback(tr, state, "111", cookies)
+		if loc, _ := url.Parse(w.Header().Get("Location")); fragment(t, loc).Get("oauth_token") != "" {
+			t.Errorf("%s: signed in", name)
+		}
+	}
+	w = oauthCallback(tr, state, "111", cookies)
+	if loc, _ := url.Parse(w.Header().Get("Location")); fragment(t, loc).Get("oauth_token") == "" {
+		t.Errorf("own browser: %s", loc)
+	}
+	cleared := false
+	for _, c := range w.Result().Cookies() {
+		cleared = cleared || c.Name == oauthStateCookie && c.MaxAge < 0
+	}
+	if !cleared {
+		t.Error("callback didn't clear the state cookie")
+	}
+
+	if w := tr.do("POST", "/api/auth/oauth/nope/start", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: %d, want 404", w.Code)
+	}
+}
+
+func TestOAuthRegisterWithClaimCode(t *testing.T) {
+	tr := newTestRouter(t)
+	provider := fakeOAuthProvider(t, map[string]string{"222": "Carol Smith"})
+	tr.r.SetOAuth("https://hub.example", []auth.OAuthProvider{{
+		Name: "test", Label: "Test", ClientID: "id", ClientSecret: "shh",
+		AuthURL: provider.URL + "/auth", TokenURL: provider.URL + "/token", UserInfoURL: provider.URL + "/userinfo",
+		SubjectField: "sub", NameField: "name",
+	}})
+	ctx := context.Background()
+	pg, err := tr.store.UpsertPlayerGUID(ctx, "CCCC", "carol", "carol", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	code, err := tr.store.CreateClaimCode(ctx, pg.PlayerID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateClaimCode: %v", err)
+	}
+
+	loc := oauthSignIn(t, tr, "/api/auth/oauth/test/start", `{"claim_code":"`+code.Code+`"}`, "", "222")
+	tok := fragment(t, loc).Get("oauth_token")
+	if tok == "" {
+		t.Fatalf("register: %s", loc)
+	}
+	w := tr.do("GET", "/api/account/profile", "", tok)
+	var profile AccountProfileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.User.Username != "Carol_Smith" || profile.User.PlayerID == nil || *profile.User.PlayerID != pg.PlayerID || profile.HasPassword {
+		t.Errorf("registered %+v", profile)
+	}
+
+	// The only way in can't be unlinked until there's a password.
+	if w := tr.do("DELETE", "/api/account/identities/test", "", tok); w.Code != http.StatusConflict {
+		t.Errorf("unlink last sign-in: %d, want 409", w.Code)
+	}
+	// Setting the first password takes a fresh sign-in with a provider
+	// linked to this account, not just the access token.
+	if w := tr.do("POST", "/api/auth/change-password", `{"new_password":"password123"}`, tok); w.Code != http.StatusUnauthorized {
+		t.Errorf("set password without signing in again: %d, want 401", w.Code)
+	}
+	if loc := oauthSignIn(t, tr, "/api/account/reauth/test", "", tok, "333"); fragment(t, loc).Get("oauth_error") == "" {
+		t.Errorf("reauth with someone else's identity: %s", loc)
+	}
+	loc = oauthSignIn(t, tr, "/api/account/reauth/test", "", tok, "222")
+	reauth := fragment(t, loc).Get("oauth_reauth")
+	if loc.Path != "/account" || reauth == "" {
+		t.Fatalf("reauth: %s", loc)
+	}
+	w = tr.do("POST", "/api/auth/change-password", `{"new_password":"password123","reauth_token":"`+reauth+`"}`, tok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("set password: %d %s", w.Code, w.Body)
+	}
+	// Setting a password revokes the token it was set with; the reply
+	// carries a new one.
+	var changed struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &changed); err != nil {
+		t.Fatal(err)
+	}
+	if w := tr.do("DELETE", "/api/account/identities/test", "", changed.Token); w.Code != http.StatusOK {
+		t.Errorf("unlink: %d %s", w.Code, w.Body)
+	}
+
+	tr.r.SetFeatures(Features{Demos: true})
+	if w := tr.do("POST", "/api/auth/oauth/test/start", `{"claim_code":"123456"}`, ""); w.Code != http.StatusForbidden {
+		t.Errorf("register with registration off: %d, want 403", w.Code)
+	}
+}
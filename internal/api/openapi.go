@@ -0,0 +1,628 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// /api/openapi.json describes the routes that need no account (what
+// the SPA's public pages, the Discord bot and other tools read) as an
+// OpenAPI 3.1 document, so clients can be generated from it instead of
+// written by hand. Schemas come from the structs the handlers write,
+// by their json tags, so they change with the responses. apiOperations
+// holds what a struct can't say: each route's query parameters, body,
+// and response type. The tests fail when a route that needs no
+// account is added without an entry.
+
+// apiParam is a query parameter.
+type apiParam struct {
+	Name    string
+	Type    string // "string", "integer" or "boolean"
+	Doc     string
+	Default any
+	Enum    []string
+}
+
+// apiOperation documents a route.
+type apiOperation struct {
+	Summary string
+	Params  []apiParam
+	// Body is the zero value of the JSON request body, if any.
+	Body any
+	// Response is the zero value of what the route writes on success,
+	// or nil if that isn't JSON, when ContentType says what it is.
+	Response    any
+	ContentType string
+	// Status is the success status, if not 200.
+	Status int
+}
+
+// apiOneOf is a response that takes one of several shapes.
+type apiOneOf []any
+
+func limitParam(def, maxLimit int) apiParam {
+	return apiParam{Name: "limit", Type: "integer", Default: def, Doc: fmt.Sprintf("1 to %d", maxLimit)}
+}
+
+func periodParam(def string) apiParam {
+	return apiParam{Name: "period", Type: "string", Default: def, Enum: slices.Sorted(maps.Keys(validPeriods))}
+}
+
+var (
+	gameTypeParam = apiParam{Name: "game_type", Type: "string", Enum: slices.Sorted(maps.Keys(validGameTypes))}
+	asOfParam     = apiParam{Name: "as_of", Type: "string", Doc: "RFC 3339 time to end the period at, for reproducible links"}
+	beforeParam   = apiParam{Name: "before", Type: "integer", Doc: "only return matches with a lower id, to page back"}
+	playerIDParam = apiParam{Name: "player_id", Type: "integer"}
+	startParam    = apiParam{Name: "start_date", Type: "string", Doc: "RFC 3339"}
+	endParam      = apiParam{Name: "end_date", Type: "string", Doc: "RFC 3339"}
+)
+
+func recordCategoryIDs() []string {
+	ids := make([]string, len(domain.RecordCategories))
+	for i, c := range domain.RecordCategories {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// apiOperations documents the routes that need no account, by mux
+// pattern.
+var apiOperations = map[string]apiOperation{
+	"GET /api/openapi.json": {Summary: "This document", Response: map[string]any{}},
+
+	"GET /api/servers": {
+		Summary:  "Servers that are enforcing the Trinity handshake and checking in",
+		Response: []liveServer{},
+	},
+	"GET /api/servers/{id}": {Summary: "A server", Response: domain.Server{}},
+	"GET /api/servers/{id}/status": {
+		Summary:  "A server's live status; cvars and GUIDs beyond the public ones need an admin token",
+		Response: domain.ServerStatus{},
+	},
+	"GET /api/servers/{id}/players": {Summary: "Who is on a server now", Response: ServerPlayersResponse{}},
+	"GET /api/servers/{id}/population": {
+		Summary:  "A query-only server's players by hour",
+		Params:   []apiParam{periodParam("week")},
+		Response: domain.PopulationResponse{},
+	},
+	"GET /api/servers/{id}/rcon-status": {Summary: "Whether the caller may use RCON on a server", Response: struct {
+		Available bool `json:"available"`
+	}{}},
+
+	"GET /api/players": {
+		Summary: "A page of players, or with search, the players whose names match",
+		Params: []apiParam{
+			{Name: "search", Type: "string"},
+			{Name: "limit", Type: "integer", Doc: "1 to 100; defaults to 20 with search, 50 without"},
+			{Name: "offset", Type: "integer", Default: 0},
+		},
+		Response: apiOneOf{PlayerListResponse{}, []domain.Player{}},
+	},
+	"POST /api/players/batch": {Summary: "Up to 200 players by id", Body: BatchRequest{}, Response: []domain.PlayerBrief{}},
+	"GET /api/players/{id}":   {Summary: "A player", Response: domain.Player{}},
+	"GET /api/players/{id}/stats": {
+		Summary:  "A player's totals over a period",
+		Params:   []apiParam{periodParam("all")},
+		Response: domain.PlayerStatsResponse{},
+	},
+	"GET /api/players/{id}/matches": {
+		Summary:  "A player's recent matches",
+		Params:   []apiParam{limitParam(10, 50), beforeParam},
+		Response: []domain.MatchSummary{},
+	},
+	"GET /api/players/{id}/achievements": {Summary: "The badges a player has earned", Response: []domain.PlayerAchievement{}},
+	"GET /api/players/{id}/trends": {
+		Summary: "A player's daily or weekly totals",
+		Params: []apiParam{
+			{Name: "interval", Type: "string", Default: "day", Enum: []string{"day", "week"}},
+			{Name: "limit", Type: "integer", Doc: "days (default 90, up to 730) or weeks (default 26, up to 260) back"},
+		},
+		Response: domain.PlayerTrendsResponse{},
+	},
+	"GET /api/players/{id}/duels": {
+		Summary:  "A player's duel record, head-to-head table and recent duels",
+		Params:   []apiParam{limitParam(10, 100), asOfParam},
+		Response: domain.PlayerDuelsResponse{},
+	},
+	"GET /api/players/{id}/maps": {
+		Summary: "A player's matches, K/D and win rate on each map",
+		Params: []apiParam{
+			periodParam("all"), gameTypeParam,
+			{Name: "sort", Type: "string", Default: "matches", Enum: []string{"matches", "kd_ratio", "win_rate", "last_played"}},
+			{Name: "order", Type: "string", Default: "desc", Enum: []string{"asc", "desc"}},
+		},
+		Response: domain.PlayerMapsResponse{},
+	},
+	"GET /api/players/{id}/ping": {
+		Summary:  "A player's average and median ping, per server, and recent lag spikes",
+		Params:   []apiParam{periodParam("month")},
+		Response: domain.PlayerPingResponse{},
+	},
+	"GET /api/players/{id}/guids": {Summary: "A player's GUIDs", Response: []domain.PlayerGUID{}},
+
+	"GET /api/matches": {
+		Summary: "Finished matches, newest first",
+		Params: []apiParam{
+			limitParam(20, 100), beforeParam, gameTypeParam, startParam, endParam,
+			{Name: "source", Type: "string"},
+			{Name: "movement", Type: "string", Enum: slices.Sorted(maps.Keys(validMovementModes))},
+			{Name: "gameplay", Type: "string", Enum: slices.Sorted(maps.Keys(validGameplayModes))},
+			{Name: "include_bot_only", Type: "boolean", Default: false},
+			{Name: "overtime", Type: "boolean", Doc: "only matches that did, or didn't, go to overtime"},
+		},
+		Response: []domain.MatchSummary{},
+	},
+	"POST /api/matches/batch":     {Summary: "Up to 200 matches by id, without scoreboards", Body: BatchRequest{}, Response: []domain.MatchBrief{}},
+	"GET /api/matches/{id}":       {Summary: "A match and its scoreboard", Response: domain.MatchSummary{}},
+	"GET /api/matches/{id}/veto":  {Summary: "The map veto recorded for a match", Response: domain.MapVeto{}},
+	"GET /api/matches/{id}/demos": {Summary: "A match's uploaded demos, newest first", Response: []domain.MatchDemo{}},
+	"GET /api/matches/{id}/chat": {
+		Summary:  "A match's chat; tells are included for admins",
+		Response: []domain.ChatLine{},
+	},
+	"GET /api/matches/{id}/flag-timeline": {
+		Summary:  "A match's flag events in order and each player's flag carry time",
+		Response: domain.FlagTimelineResponse{},
+	},
+
+	"GET /api/demos": {
+		Summary: "Uploaded demos across matches",
+		Params: []apiParam{
+			limitParam(20, 100), {Name: "offset", Type: "integer", Default: 0},
+			{Name: "map", Type: "string"}, playerIDParam, gameTypeParam, startParam, endParam,
+			{Name: "sort", Type: "string", Default: "date", Enum: []string{"date", "size", "length"}},
+			{Name: "order", Type: "string", Default: "desc", Enum: []string{"asc", "desc"}},
+		},
+		Response: DemoListResponse{},
+	},
+	"GET /api/demos/{file}": {Summary: "Download a demo", ContentType: "application/octet-stream"},
+
+	"GET /api/stats/leaderboard": {
+		Summary: "The top players in a category; with weapon, frags or accuracy with that weapon",
+		Params: []apiParam{
+			{Name: "category", Type: "string", Default: "frags", Enum: slices.Sorted(maps.Keys(validCategories))},
+			periodParam("all"), gameTypeParam, asOfParam, limitParam(50, 100),
+			{Name: "weapon", Type: "string", Enum: domain.Weapons},
+			{Name: "season", Type: "string", Doc: "current or a season id; replaces period"},
+			{Name: "min_matches", Type: "integer", Doc: "admins only: minimum completed matches to place"},
+			{Name: "min_playtime", Type: "string", Doc: "admins only: minimum playtime to place, e.g. 2h"},
+			{Name: "include_bots", Type: "boolean", Doc: "admins only: rank bots too"},
+			{Name: "include_vr", Type: "boolean", Doc: "admins only: rank [VR] Player# placeholders too"},
+			{Name: "include_abandoned", Type: "boolean", Doc: "admins only: count abandoned matches too"},
+		},
+		Response: domain.LeaderboardResponse{},
+	},
+	"GET /api/records": {
+		Summary: "Current map records",
+		Params: []apiParam{
+			{Name: "map", Type: "string"}, gameTypeParam, playerIDParam,
+			{Name: "category", Type: "string", Enum: recordCategoryIDs()},
+		},
+		Response: []domain.Record{},
+	},
+	"GET /api/stats/returning": {
+		Summary:  "Players who came back after a long absence",
+		Params:   []apiParam{periodParam("week"), asOfParam, limitParam(10, 50)},
+		Response: domain.ReturningPlayersResponse{},
+	},
+	"GET /api/stats/countries": {
+		Summary:  "Players and sessions by country",
+		Params:   []apiParam{periodParam("all"), asOfParam},
+		Response: domain.CountryBreakdownResponse{},
+	},
+	"GET /api/stats/activity": {
+		Summary: "Average players online by hour of the week, per server and overall",
+		Params: []apiParam{
+			periodParam("month"), asOfParam,
+			{Name: "tz", Type: "string", Default: "UTC", Doc: "IANA time zone the hours are in, e.g. Europe/Berlin"},
+			{Name: "humans_only", Type: "boolean", Default: false},
+		},
+		Response: domain.ActivityResponse{},
+	},
+	"GET /api/stats/sessions": {
+		Summary:  "Session lengths, daily unique players and 7-day retention",
+		Params:   []apiParam{periodParam("month"), asOfParam},
+		Response: domain.SessionStatsResponse{},
+	},
+	"GET /api/stats/bots": {
+		Summary:  "Each bot's record and how humans fare against each skill level",
+		Params:   []apiParam{periodParam("all"), gameTypeParam, asOfParam},
+		Response: domain.BotStatsResponse{},
+	},
+	"GET /api/stats/ping": {
+		Summary:  "Each server's average and median ping, by players' country",
+		Params:   []apiParam{periodParam("week")},
+		Response: domain.ServerPingResponse{},
+	},
+	"GET /api/ladders/duel": {
+		Summary:  "The 1v1 challenge ladder and recent challenges",
+		Params:   []apiParam{limitParam(20, 100), playerIDParam, asOfParam},
+		Response: domain.DuelLadderResponse{},
+	},
+	"GET /api/duels": {
+		Summary:  "Recent 1v1 duels the ladder counts",
+		Params:   []apiParam{limitParam(20, 100), playerIDParam, asOfParam},
+		Response: domain.DuelListResponse{},
+	},
+	"GET /api/duels/rankings": {
+		Summary:  "Duellers ranked by win/loss record",
+		Params:   []apiParam{limitParam(50, 100), asOfParam},
+		Response: domain.DuelRankingsResponse{},
+	},
+	"GET /api/clans": {
+		Summary:  "Registered clans, most members first",
+		Response: []domain.Clan{},
+	},
+	"GET /api/clans/{id}": {
+		Summary:  "A clan, its members and their summed stats",
+		Params:   []apiParam{periodParam("all")},
+		Response: domain.ClanResponse{},
+	},
+	"GET /api/clans/{id}/matches": {
+		Summary:  "A clan's latest clan-vs-clan matches",
+		Params:   []apiParam{limitParam(20, 100)},
+		Response: []domain.ClanMatch{},
+	},
+	"GET /api/clans/leaderboard": {
+		Summary: "Clans ranked by a stat",
+		Params: []apiParam{
+			{Name: "category", Type: "string", Default: "frags",
+				Enum: []string{"frags", "kd_ratio", "matches", "victories", "captures", "clan_wins"}},
+			periodParam("all"), limitParam(25, 100),
+		},
+		Response: domain.ClanLeaderboardResponse{},
+	},
+	"GET /api/tournaments": {
+		Summary: "Tournaments, latest start first",
+		Params: []apiParam{
+			{Name: "status", Type: "string", Enum: []string{"signup", "in_progress", "completed"}},
+		},
+		Response: []domain.Tournament{},
+	},
+	"GET /api/seasons": {
+		Summary:  "Seasons, latest first",
+		Response: []domain.Season{},
+	},
+	"GET /api/seasons/{id}": {
+		Summary:  "A season with its standings, frozen once it has ended",
+		Response: domain.SeasonResponse{},
+	},
+	"GET /api/tournaments/{id}": {
+		Summary:  "A tournament with its entrants, pairings and standings",
+		Response: domain.TournamentResponse{},
+	},
+	"GET /api/portraits": {
+		Summary:  "Models with an extracted portrait, for a profile's preferred_model",
+		Response: []string{},
+	},
+	"GET /api/graphql": {
+		Summary: "Run a GraphQL query",
+		Params: []apiParam{
+			{Name: "query", Type: "string"},
+			{Name: "variables", Type: "string", Doc: "JSON object"},
+			{Name: "operationName", Type: "string"},
+		},
+		Response: map[string]any{},
+	},
+	"POST /api/graphql": {Summary: "Run a GraphQL query", Body: graphqlRequest{}, Response: map[string]any{}},
+
+	"GET /api/export/matches": {
+		Summary:     "One row per player per finished match",
+		Params:      exportParams,
+		ContentType: "text/csv",
+	},
+	"GET /api/export/players": {
+		Summary:     "Each player's totals over a range",
+		Params:      exportParams,
+		ContentType: "text/csv",
+	},
+
+	"GET /api/sources":          {Summary: "Approved sources, for filtering by", Response: []SourceName{}},
+	"GET /api/config/features":  {Summary: "The hub's optional features and hidden stats", Response: FeaturesResponse{}},
+	"GET /api/config/branding":  {Summary: "The site's name, logo, colors and links", Response: Branding{}},
+	"GET /api/events":           {Summary: "Live events as Server-Sent Events", ContentType: "text/event-stream"},
+	"GET /api/events/poll":      {Summary: "Live events by long poll", Params: []apiParam{{Name: "since_seq", Type: "integer"}}, Response: PollEventsResponse{}},
+	"POST /api/auth/login":      {Summary: "Sign in with a password", Body: LoginRequest{}, Response: LoginResponse{}},
+	"POST /api/auth/logout":     {Summary: "Revoke a refresh token", Body: RefreshRequest{}, Response: map[string]string{}},
+	"POST /api/auth/refresh":    {Summary: "Trade a refresh token for new tokens", Body: RefreshRequest{}, Response: LoginResponse{}},
+	"POST /api/auth/game-login": {Summary: "Sign in from the game", Body: GameLoginRequest{}, Response: GameLoginResponse{}},
+	"GET /api/auth/check": {Summary: "Who the token belongs to", Response: struct {
+		Authenticated          bool   `json:"authenticated"`
+		Username               string `json:"username,omitempty"`
+		IsAdmin                bool   `json:"is_admin,omitempty"`
+		PlayerID               *int64 `json:"player_id,omitempty"`
+		PasswordChangeRequired bool   `json:"password_change_required,omitempty"`
+		ImpersonatedBy         int64  `json:"impersonated_by,omitempty"`
+	}{}},
+	"POST /api/auth/oauth/{provider}/start": {Summary: "Start signing in with a provider", Body: OAuthStartRequest{}, Response: struct {
+		URL string `json:"url"`
+	}{}},
+	"GET /api/auth/oauth/{provider}/callback": {Summary: "Where the provider sends the browser back; redirects into the SPA", Status: http.StatusFound},
+	"POST /api/claim/validate":                {Summary: "Check a claim code", Body: ClaimValidateRequest{}, Response: ClaimValidateResponse{}},
+	"POST /api/claim/register": {
+		Summary:  "Create an account for a claimed player",
+		Body:     ClaimRegisterRequest{},
+		Response: LoginResponse{},
+		Status:   http.StatusCreated,
+	},
+}
+
+var exportParams = []apiParam{
+	{Name: "format", Type: "string", Default: "csv", Enum: []string{"csv", "json"}},
+	{Name: "from", Type: "string", Doc: "RFC 3339 time or YYYY-MM-DD"},
+	{Name: "to", Type: "string", Doc: "RFC 3339 time or YYYY-MM-DD"},
+}
+
+// openAPIDocument is the encoded document. It only depends on types,
+// so it's built once.
+var openAPIDocument = sync.OnceValue(func() []byte {
+	data, err := json.Marshal(buildOpenAPI())
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	return data
+})
+
+// handleOpenAPI serves the OpenAPI document.
+//
+// path: GET /api/openapi.json
+func (r *Router) handleOpenAPI(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument())
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)(\.\.\.)?\}`)
+
+func buildOpenAPI() map[string]any {
+	s := &openAPISchemas{components: map[string]any{}, names: map[reflect.Type]string{}}
+	s.components["Error"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+		"required":   []string{"error"},
+	}
+	paths := map[string]map[string]any{}
+	for pattern, op := range apiOperations {
+		method, path, _ := strings.Cut(pattern, " ")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = s.operation(method, path, op)
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "Trinity",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		// A token is optional everywhere here; admins see more.
+		"security": []map[string][]string{{}, {"bearer": {}}},
+	}
+}
+
+func (s *openAPISchemas) operation(method, path string, op apiOperation) map[string]any {
+	var params []map[string]any
+	for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+		typ := "string"
+		if m[1] == "id" {
+			typ = "integer"
+		}
+		params = append(params, map[string]any{
+			"name": m[1], "in": "path", "required": true,
+			"schema": map[string]any{"type": typ},
+		})
+	}
+	for _, p := range op.Params {
+		schema := map[string]any{"type": p.Type}
+		if p.Default != nil {
+			schema["default"] = p.Default
+		}
+		if p.Enum != nil {
+			schema["enum"] = p.Enum
+		}
+		param := map[string]any{"name": p.Name, "in": "query", "schema": schema}
+		if p.Doc != "" {
+			param["description"] = p.Doc
+		}
+		params = append(params, param)
+	}
+
+	status := http.StatusOK
+	if op.Status != 0 {
+		status = op.Status
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": s.value(op.Response)}}
+	case op.ContentType != "":
+		success["content"] = map[string]any{op.ContentType: map[string]any{}}
+	}
+	out := map[string]any{
+		"operationId": operationID(method, path),
+		"summary":     op.Summary,
+		"responses": map[string]any{
+			fmt.Sprint(status): success,
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+				}},
+			},
+		},
+	}
+	if params != nil {
+		out["parameters"] = params
+	}
+	if op.Body != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.value(op.Body)}},
+		}
+	}
+	return out
+}
+
+// operationID names a route for generated clients:
+// "getPlayersByIdStats" for GET /api/players/{id}/stats.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/api/"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '_'
+	}) {
+		if m := pathParamRe.FindStringSubmatch(part); m != nil {
+			b.WriteString("By")
+			part = m[1]
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// openAPISchemas generates schemas from Go types. Named structs become
+// components; the rest are inlined.
+type openAPISchemas struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func (s *openAPISchemas) value(v any) map[string]any {
+	if alts, ok := v.(apiOneOf); ok {
+		var oneOf []map[string]any
+		for _, alt := range alts {
+			oneOf = append(oneOf, s.value(alt))
+		}
+		return map[string]any{"oneOf": oneOf}
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (s *openAPISchemas) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + s.component(t)}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	// interface{} and the like: any JSON value
+	return map[string]any{}
+}
+
+// component adds named struct t to the components, named after it, or
+// with its package in front if another package has a type of that name.
+func (s *openAPISchemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := s.components[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	s.names[t] = name
+	s.components[name] = nil // reserve it; t may refer to itself
+	s.components[name] = s.object(t)
+	return name
+}
+
+// object is the schema of struct t, laid out as encoding/json would:
+// embedded structs are flattened, and fields without omitempty are
+// required. A pointer without omitempty may be null.
+func (s *openAPISchemas) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	s.addProperties(t, props, &required)
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		slices.Sort(required)
+		out["required"] = required
+	}
+	return out
+}
+
+func (s *openAPISchemas) addProperties(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		ft := sf.Type
+		if sf.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addProperties(ft, props, required)
+				continue
+			}
+		}
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		schema := s.schema(ft)
+		omitempty := strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+		if ft.Kind() == reflect.Pointer && !omitempty {
+			schema = nullable(schema)
+		}
+		props[name] = schema
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable lets schema be null too.
+func nullable(schema map[string]any) map[string]any {
+	if typ, ok := schema["type"].(string); ok {
+		out := maps.Clone(schema)
+		out["type"] = []string{typ, "null"}
+		return out
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
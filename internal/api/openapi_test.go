@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// publicRoutes returns the /api patterns router.go registers without
+// requireAuth or requireAdmin around the handler.
+func publicRoutes(t *testing.T) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "router.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "HandleFunc" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok {
+			return true
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		if _, path, _ := strings.Cut(pattern, " "); !strings.HasPrefix(path, "/api/") {
+			return true
+		}
+		if wrap, ok := call.Args[1].(*ast.CallExpr); ok {
+			if sel, ok := wrap.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "requireAuth" || sel.Sel.Name == "requireAdmin") {
+				return true
+			}
+		}
+		out = append(out, pattern)
+		return true
+	})
+	slices.Sort(out)
+	return out
+}
+
+func TestOpenAPICoversPublicRoutes(t *testing.T) {
+	public := publicRoutes(t)
+	if len(public) < 20 {
+		t.Fatalf("found only %d public routes in router.go: %v", len(public), public)
+	}
+	for _, pattern := range public {
+		if _, ok := apiOperations[pattern]; !ok {
+			t.Errorf("%s needs no account but isn't in apiOperations", pattern)
+		}
+	}
+	for pattern := range apiOperations {
+		if !slices.Contains(public, pattern) {
+			t.Errorf("apiOperations has %s, which router.go doesn't register without an account", pattern)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	tr := newTestRouter(t)
+	w := tr.do("GET", "/api/openapi.json", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	stats := doc.Paths["/api/players/{id}/stats"]["get"]
+	if stats["operationId"] != "getPlayersByIdStats" {
+		t.Errorf("operationId = %v", stats["operationId"])
+	}
+	ok := stats["responses"].(map[string]any)["200"].(map[string]any)
+	schema := ok["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	if schema["$ref"] != "#/components/schemas/PlayerStatsResponse" {
+		t.Errorf("stats response schema = %v", schema)
+	}
+	params := stats["parameters"].([]any)
+	if len(params) != 2 || params[0].(map[string]any)["in"] != "path" || params[1].(map[string]any)["name"] != "period" {
+		t.Errorf("stats parameters = %v", params)
+	}
+
+	// Embedded structs are flattened and omitempty fields optional.
+	player := doc.Components.Schemas["Player"]
+	props := player["properties"].(map[string]any)
+	for _, key := range []string{"id", "clean_name", "first_seen"} {
+		if props[key] == nil {
+			t.Errorf("Player has no %s: %v", key, props)
+		}
+	}
+	features := doc.Components.Schemas["FeaturesResponse"]["properties"].(map[string]any)
+	if features["demos"] == nil || features["hidden_stats"] == nil {
+		t.Errorf("FeaturesResponse properties = %v", features)
+	}
+	if req := doc.Components.Schemas["LoginResponse"]["required"].([]any); slices.Contains(req, any("refresh_token")) {
+		t.Errorf("refresh_token is omitempty but required: %v", req)
+	}
+
+	// Every reference resolves.
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if doc.Components.Schemas[name] == nil {
+					t.Errorf("dangling $ref %s", ref)
+				}
+			}
+			for _, c := range v {
+				walk(c)
+			}
+		case []any:
+			for _, c := range v {
+				walk(c)
+			}
+		}
+	}
+	var raw any
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	walk(raw)
+}
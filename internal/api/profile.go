@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleUpdateAccountProfile changes the profile shown on the caller's
+// linked player. Fields left out of the body keep their value; an empty
+// string clears one. preferred_model must be one of GET /api/portraits
+// when the hub has extracted them.
+//
+// path: PATCH /api/account/profile
+func (r *Router) handleUpdateAccountProfile(w http.ResponseWriter, req *http.Request) {
+	user, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	var body struct {
+		AvatarURL      *string `json:"avatar_url"`
+		PreferredModel *string `json:"preferred_model"`
+		Bio            *string `json:"bio"`
+		CountryCode    *string `json:"country_code"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	player, err := r.store.GetPlayerByID(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var profile domain.Profile
+	if player.Profile != nil {
+		profile = *player.Profile
+	}
+	for _, f := range []struct {
+		value *string
+		field *string
+	}{
+		{body.AvatarURL, &profile.AvatarURL},
+		{body.PreferredModel, &profile.PreferredModel},
+		{body.Bio, &profile.Bio},
+		{body.CountryCode, &profile.CountryCode},
+	} {
+		if f.value != nil {
+			*f.field = strings.TrimSpace(*f.value)
+		}
+	}
+	if err := validateProfile(&profile); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.PreferredModel != nil && profile.PreferredModel != "" && !r.hasPortrait(profile.PreferredModel) {
+		writeError(w, http.StatusBadRequest, "no portrait for preferred_model")
+		return
+	}
+
+	if err := r.store.SetUserProfile(req.Context(), user.ID, profile); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	player.Profile = nil
+	if !profile.IsZero() {
+		player.Profile = &profile
+	}
+	writeJSON(w, http.StatusOK, player)
+}
+
+// handleListPortraits lists the models with an extracted portrait, as
+// accepted for a profile's preferred_model. A model's default skin is
+// listed as the bare model name.
+//
+// path: GET /api/portraits
+func (r *Router) handleListPortraits(w http.ResponseWriter, req *http.Request) {
+	models := []string{}
+	if r.staticDir == "" {
+		writeJSON(w, http.StatusOK, models)
+		return
+	}
+	icons, _ := filepath.Glob(filepath.Join(r.staticDir, "assets", "portraits", "*", "icon_*.png"))
+	for _, icon := range icons {
+		model := filepath.Base(filepath.Dir(icon))
+		skin := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(icon), "icon_"), ".png")
+		if skin != "default" {
+			model += "/" + skin
+		}
+		models = append(models, model)
+	}
+	slices.Sort(models)
+	writeJSON(w, http.StatusOK, models)
+}
+
+// hasPortrait reports whether model has an extracted portrait, the
+// same file the web UI shows for it. Without a static dir there's
+// nothing to check against, so every model passes.
+func (r *Router) hasPortrait(model string) bool {
+	if r.staticDir == "" {
+		return true
+	}
+	name, skin, ok := strings.Cut(strings.TrimPrefix(model, "*"), "/")
+	if !ok {
+		skin = "default"
+	}
+	info, err := os.Stat(filepath.Join(r.staticDir, "assets", "portraits", name, "icon_"+skin+".png"))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The public mirror serves a read-only subset of /api under
+// /public/api for exposing to the whole internet, so the primary API
+// can stay behind auth and origin restrictions at the proxy. Each
+// request is answered by the /api handler as an anonymous caller, then
+// anything that identifies a connection rather than a player (GUIDs,
+// IPs, cities, session client details) is scrubbed, and the result is
+// cached in memory and by proxies.
+
+// Cache lifetimes: live server state goes stale fast; everything else
+// moves at match pace.
+const (
+	publicLiveTTL  = 30 * time.Second
+	publicStatsTTL = 5 * time.Minute
+
+	// publicCacheCap bounds the cached responses, since query strings
+	// make the key space unbounded.
+	publicCacheCap = 2000
+)
+
+// publicMirrorRoutes are the /api routes the mirror serves, by mux
+// pattern, with how long their responses are cached.
+var publicMirrorRoutes = map[string]time.Duration{
+	"GET /api/servers":                    publicLiveTTL,
+	"GET /api/servers/{id}":               publicStatsTTL,
+	"GET /api/servers/{id}/status":        publicLiveTTL,
+	"GET /api/servers/{id}/players":       publicLiveTTL,
+	"GET /api/servers/{id}/population":    publicStatsTTL,
+	"GET /api/players":                    publicStatsTTL,
+	"GET /api/players/{id}":               publicStatsTTL,
+	"GET /api/players/{id}/stats":         publicStatsTTL,
+	"GET /api/players/{id}/matches":       publicStatsTTL,
+	"GET /api/players/{id}/achievements":  publicStatsTTL,
+	"GET /api/players/{id}/trends":        publicStatsTTL,
+	"GET /api/players/{id}/duels":         publicStatsTTL,
+	"GET /api/players/{id}/maps":          publicStatsTTL,
+	"GET /api/players/{id}/ping":          publicStatsTTL,
+	"GET /api/matches":                    publicStatsTTL,
+	"GET /api/matches/{id}":               publicStatsTTL,
+	"GET /api/matches/{id}/flag-timeline": publicStatsTTL,
+	"GET /api/stats/leaderboard":          publicStatsTTL,
+	"GET /api/records":                    publicStatsTTL,
+	"GET /api/stats/countries":            publicStatsTTL,
+	"GET /api/stats/activity":             publicStatsTTL,
+	"GET /api/stats/sessions":             publicStatsTTL,
+	"GET /api/stats/bots":                 publicStatsTTL,
+	"GET /api/stats/ping":                 publicStatsTTL,
+	"GET /api/ladders/duel":               publicStatsTTL,
+	"GET /api/duels":                      publicStatsTTL,
+	"GET /api/duels/rankings":             publicStatsTTL,
+	"GET /api/clans":                      publicStatsTTL,
+	"GET /api/clans/leaderboard":          publicStatsTTL,
+	"GET /api/clans/{id}":                 publicStatsTTL,
+	"GET /api/clans/{id}/matches":         publicStatsTTL,
+	"GET /api/tournaments":                publicStatsTTL,
+	"GET /api/tournaments/{id}":           publicStatsTTL,
+	"GET /api/seasons":                    publicStatsTTL,
+	"GET /api/seasons/{id}":               publicStatsTTL,
+}
+
+// publicPrivateKeys are dropped from every object in a mirrored
+// response.
+var publicPrivateKeys = map[string]bool{
+	"guid":             true,
+	"guids":            true,
+	"fragger_guid":     true,
+	"victim_guid":      true,
+	"player_guid_id":   true,
+	"ip":               true,
+	"ip_address":       true,
+	"city":             true,
+	"client_engine":    true,
+	"client_version":   true,
+	"owner_user_id":    true,
+	"owner_username":   true,
+	"manageable_by_me": true,
+}
+
+type publicEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// publicCache holds scrubbed mirror responses by path and query.
+type publicCache struct {
+	mu      sync.Mutex
+	entries map[string]publicEntry
+}
+
+func (c *publicCache) get(key string, now time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+	return e.body, true
+}
+
+// put stores body under key, first dropping expired entries if the
+// cache is full. If it's still full, body isn't kept.
+func (c *publicCache) put(key string, body []byte, expires, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]publicEntry)
+	}
+	if len(c.entries) >= publicCacheCap {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= publicCacheCap {
+			return
+		}
+	}
+	c.entries[key] = publicEntry{body: body, expires: expires}
+}
+
+// bufferedResponse captures a handler's response for scrubbing.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// handlePublicMirror serves /api/{path} for anonymous internet
+// clients when features.public_api is on. Routes outside
+// publicMirrorRoutes are 404s.
+//
+// path: GET /public/api/{path...}
+func (r *Router) handlePublicMirror(w http.ResponseWriter, req *http.Request) {
+	if !r.features.PublicAPI {
+		writeError(w, http.StatusNotFound, "public API not enabled")
+		return
+	}
+	inner := req.Clone(req.Context())
+	inner.URL.Path = "/api/" + req.PathValue("path")
+	inner.URL.RawPath = ""
+	inner.URL.RawQuery = inner.URL.Query().Encode()
+	inner.RequestURI = inner.URL.RequestURI()
+	inner.Header.Del("Authorization")
+	inner.Header.Del("Cookie")
+	inner.Pattern = ""
+
+	_, pattern := r.mux.Handler(inner)
+	ttl, ok := publicMirrorRoutes[pattern]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not available on the public API")
+		return
+	}
+
+	now := time.Now()
+	key := inner.URL.RequestURI()
+	if body, ok := r.publicCache.get(key, now); ok {
+		writePublic(w, ttl, body)
+		return
+	}
+
+	rec := &bufferedResponse{header: http.Header{}}
+	r.mux.ServeHTTP(rec, inner)
+	if rec.status != http.StatusOK {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+		return
+	}
+	body, err := scrubPublic(rec.body.Bytes())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	r.publicCache.put(key, body, now.Add(ttl), now)
+	writePublic(w, ttl, body)
+}
+
+func writePublic(w http.ResponseWriter, ttl time.Duration, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(ttl/time.Second)))
+	w.Write(body)
+}
+
+// scrubPublic re-encodes a JSON response without publicPrivateKeys.
+func scrubPublic(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(dropKeys(tree, publicPrivateKeys))
+}
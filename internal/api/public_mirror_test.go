@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublicMirror(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	token, _ := tr.loginAs(t, "admin", true)
+	if _, err := tr.store.UpsertPlayerGUID(ctx, "AAAA1111", "alice", "alice", time.Now(), false); err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	if w := tr.do("GET", "/public/api/players", "", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("disabled: code = %d, want 404", w.Code)
+	}
+	tr.r.SetFeatures(Features{PublicAPI: true})
+
+	// Admins can search by GUID on /api; the mirror ignores the token.
+	if w := tr.do("GET", "/api/players?search=AAAA1111", "", token); !strings.Contains(w.Body.String(), "alice") {
+		t.Fatalf("/api search by GUID: %s", w.Body)
+	}
+	w := tr.do("GET", "/public/api/players?search=AAAA1111", "", token)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "alice") {
+		t.Errorf("mirror search by GUID: %d %s", w.Code, w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+
+	// Cached: a new player doesn't show until the entry expires.
+	w = tr.do("GET", "/public/api/players", "", "")
+	var first struct{ Total int }
+	json.Unmarshal(w.Body.Bytes(), &first)
+	if _, err := tr.store.UpsertPlayerGUID(ctx, "BBBB2222", "bob", "bob", time.Now(), false); err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	w = tr.do("GET", "/public/api/players", "", "")
+	var second struct{ Total int }
+	json.Unmarshal(w.Body.Bytes(), &second)
+	if first.Total != 1 || second.Total != 1 {
+		t.Errorf("totals = %d, %d; want cached 1, 1", first.Total, second.Total)
+	}
+
+	for _, path := range []string{
+		"/public/api/players/1/sessions",
+		"/public/api/admin/usage",
+		"/public/api/matches/1/chat",
+	} {
+		if w := tr.do("GET", path, "", token); w.Code != http.StatusNotFound {
+			t.Errorf("%s: code = %d, want 404", path, w.Code)
+		}
+	}
+	if w := tr.do("GET", "/public/api/players/999", "", ""); w.Code != http.StatusNotFound || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("unknown player: %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestScrubPublic(t *testing.T) {
+	got, err := scrubPublic([]byte(`{"players":[{"id":1,"name":"alice","guid":"X","guids":[{"guid":"X"}],"ip_address":"10.0.0.1","country":"NZ","city":"Wellington"}],"total":1}`))
+	if err != nil {
+		t.Fatalf("scrubPublic: %v", err)
+	}
+	want := `{"players":[{"country":"NZ","id":1,"name":"alice"}],"total":1}`
+	if string(got) != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.loginAs(t, "alice", false)
+
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", w.Code, w.Body)
+	}
+	var login LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	if login.RefreshToken == "" {
+		t.Fatal("login returned no refresh token")
+	}
+
+	w = tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: %d %s", w.Code, w.Body)
+	}
+	var next LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &next); err != nil {
+		t.Fatal(err)
+	}
+	if next.Token == "" || next.Username != "alice" || next.RefreshToken == "" || next.RefreshToken == login.RefreshToken {
+		t.Fatalf("unexpected refresh response: %+v", next)
+	}
+
+	// Replaying the spent token fails and takes the new one down too.
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused token: %d, want 401", w.Code)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+next.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("token after reuse: %d, want 401", w.Code)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"nope"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: %d, want 401", w.Code)
+	}
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.loginAs(t, "alice", false)
+
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	var login LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"refresh_token":"` + login.RefreshToken + `"}`
+	if w := tr.do("POST", "/api/auth/logout", body, login.Token); w.Code != http.StatusOK {
+		t.Fatalf("logout: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", body, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: %d, want 401", w.Code)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, aliceID := tr.loginAs(t, "alice", false)
+	path := "/api/users/" + strconv.FormatInt(aliceID, 10) + "/revoke-tokens"
+
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	var login LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := tr.do("GET", "/api/account/profile", "", aliceTok); w.Code != http.StatusOK {
+		t.Fatalf("profile before revoke: %d %s", w.Code, w.Body)
+	}
+	if w := tr.do("POST", path, "", aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", "/api/users/9999/revoke-tokens", "", adminTok); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user: %d, want 404", w.Code)
+	}
+	if w := tr.do("POST", path, "", adminTok); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+
+	if w := tr.do("GET", "/api/account/profile", "", aliceTok); w.Code != http.StatusUnauthorized {
+		t.Errorf("access token after revoke: %d, want 401", w.Code)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh token after revoke: %d, want 401", w.Code)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", adminTok); w.Code != http.StatusOK {
+		t.Errorf("admin's own token: %d, want 200", w.Code)
+	}
+}
+
+func TestLoginRightAfterRevokeWorks(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	_, aliceID := tr.loginAs(t, "alice", false)
+
+	if w := tr.do("POST", "/api/users/"+strconv.FormatInt(aliceID, 10)+"/revoke-tokens", "", adminTok); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	var login LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", login.Token); w.Code != http.StatusOK {
+		t.Errorf("token from a login just after revoke: %d, want 200", w.Code)
+	}
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.loginAs(t, "alice", false)
+
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	var other LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &other); err != nil {
+		t.Fatal(err)
+	}
+
+	w = tr.do("POST", "/api/auth/change-password", `{"current_password":"password123","new_password":"password456"}`, other.Token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("change password: %d %s", w.Code, w.Body)
+	}
+	var changed struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &changed); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+other.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("old refresh token: %d, want 401", w.Code)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", other.Token); w.Code != http.StatusUnauthorized {
+		t.Errorf("old access token: %d, want 401", w.Code)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", changed.Token); w.Code != http.StatusOK {
+		t.Errorf("new access token: %d, want 200", w.Code)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+changed.RefreshToken+`"}`, ""); w.Code != http.StatusOK {
+		t.Errorf("new refresh token: %d, want 200", w.Code)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, aliceID := tr.loginAs(t, "alice", false)
+	key := createAPIKey(t, tr, aliceTok, `{"name":"bot"}`)
+
+	w := tr.do("POST", "/api/auth/login", `{"username":"alice","password":"password123"}`, "")
+	var login LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/api/users/" + strconv.FormatInt(aliceID, 10) + "/reset-password"
+	if w := tr.do("POST", path, `{"new_password":"password456"}`, adminTok); w.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", w.Code, w.Body)
+	}
+
+	if w := tr.do("GET", "/api/account/profile", "", aliceTok); w.Code != http.StatusUnauthorized {
+		t.Errorf("access token after reset: %d, want 401", w.Code)
+	}
+	if w := tr.do("POST", "/api/auth/refresh", `{"refresh_token":"`+login.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh token after reset: %d, want 401", w.Code)
+	}
+	if w := tr.do("GET", "/api/account/profile", "", key.Key); w.Code != http.StatusUnauthorized {
+		t.Errorf("API key after reset: %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+)
+
+// Reloader re-reads config.yml and applies the server list without a
+// restart. main.go wires one for the same code path SIGHUP takes; when
+// unset the reload endpoint returns 501.
+type Reloader interface {
+	Reload(ctx context.Context) (collector.ServerDiff, error)
+}
+
+// SetReloader plugs in the config reloader.
+func (r *Router) SetReloader(rl Reloader) {
+	r.reloader = rl
+}
+
+// handleReload re-reads config.yml and starts or stops tracking
+// servers to match it, reporting what changed.
+//
+// path: POST /api/admin/reload
+func (r *Router) handleReload(w http.ResponseWriter, req *http.Request) {
+	if r.reloader == nil {
+		writeError(w, http.StatusNotImplemented, "config reload not enabled on this hub")
+		return
+	}
+	claims := r.getAuthClaims(req)
+	actor := ""
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: config_reload actor=%s remote=%s", actor, req.RemoteAddr)
+
+	diff, err := r.reloader.Reload(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
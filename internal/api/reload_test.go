@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/collector"
+)
+
+type fakeReloader struct {
+	calls int
+	diff  collector.ServerDiff
+	err   error
+}
+
+func (f *fakeReloader) Reload(ctx context.Context) (collector.ServerDiff, error) {
+	f.calls++
+	return f.diff, f.err
+}
+
+func TestHandleReload(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, _ := tr.loginAs(t, "alice", false)
+
+	// Not wired → 501.
+	if w := tr.do("POST", "/api/admin/reload", "", adminTok); w.Code != http.StatusNotImplemented {
+		t.Errorf("unwired: code = %d, want 501", w.Code)
+	}
+
+	rl := &fakeReloader{diff: collector.ServerDiff{Added: []string{"ctf"}, Removed: []string{"ffa"}}}
+	tr.r.SetReloader(rl)
+
+	if w := tr.do("POST", "/api/admin/reload", "", aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: code = %d, want 403", w.Code)
+	}
+	if rl.calls != 0 {
+		t.Errorf("non-admin reloaded: calls = %d", rl.calls)
+	}
+
+	w := tr.do("POST", "/api/admin/reload", "", adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reload: %d %s", w.Code, w.Body)
+	}
+	var diff collector.ServerDiff
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "ctf" || len(diff.Removed) != 1 || diff.Removed[0] != "ffa" {
+		t.Errorf("response: got %+v", diff)
+	}
+
+	rl.err = errors.New("loading config.yml: bad yaml")
+	if w := tr.do("POST", "/api/admin/reload", "", adminTok); w.Code != http.StatusInternalServerError {
+		t.Errorf("failed reload: code = %d, want 500", w.Code)
+	}
+}
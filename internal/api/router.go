@@ -12,10 +12,12 @@ import (
 
 	"github.com/ernie/trinity-tracker/internal/auth"
 	"github.com/ernie/trinity-tracker/internal/collector"
+	"github.com/ernie/trinity-tracker/internal/demos"
 	"github.com/ernie/trinity-tracker/internal/domain"
 	"github.com/ernie/trinity-tracker/internal/hub"
 	"github.com/ernie/trinity-tracker/internal/natsbus"
 	"github.com/ernie/trinity-tracker/internal/storage"
+	"github.com/graphql-go/graphql"
 )
 
 // Router holds the HTTP routes and dependencies
@@ -28,6 +30,7 @@ type Router struct {
 	wsHub         *WebSocketHub
 	auth          *auth.Service
 	loginLimiter  *rateLimiter
+	exportLimiter *rateLimiter
 	rotateLimiter *rotationLimiter
 	staticDir     string
 	quake3Dir     string
@@ -38,6 +41,27 @@ type Router struct {
 	// in-process ServerManager. See SetRconClient / SetLocalSource.
 	rconClient    *natsbus.RconClient
 	localSource   string
+	serverCtl     ServerController
+	reloader      Reloader
+	serverProv    ServerProvisioner
+	usage         *usageTracker
+	features      Features
+	// Set by SetOAuth
+	oauthBaseURL   string
+	oauthProviders map[string]*auth.OAuthProvider
+	oauthInfo      []OAuthProviderInfo
+	oauthStates    *oauthStates
+	reauthGrants   *reauthGrants
+	oauthClient    *http.Client
+	// Set by SetHiddenStats
+	hiddenStats      []string
+	hiddenCategories map[string]bool
+	hiddenStatKeys   map[string]bool
+	graphqlSchema    graphql.Schema
+	branding      Branding
+	demoLibrary   *demos.Library
+	publicCache   publicCache
+	browser       *hub.ServerBrowser
 }
 
 // SetPoller plugs in the hub's UDP poller. Always set in hub mode —
@@ -72,42 +96,104 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 		store:         store,
 		manager:       manager,
 		writer:        writer,
-		wsHub:         NewWebSocketHub(),
+		wsHub:         NewWebSocketHub(256),
 		auth:          authService,
 		loginLimiter:  newRateLimiter(15*time.Minute, 5),
+		exportLimiter: newRateLimiter(time.Minute, 10),
 		rotateLimiter: newRotationLimiter(5, 24*time.Hour),
 		staticDir:     staticDir,
 		quake3Dir:     quake3Dir,
+		usage:         newUsageTracker(),
+		features:      Features{Demos: true, Registration: true},
+		hiddenStats:   []string{},
+		oauthInfo:     []OAuthProviderInfo{},
+		oauthStates:   &oauthStates{pending: make(map[string]oauthPending)},
+		reauthGrants:  &reauthGrants{grants: make(map[string]reauthGrant)},
+		oauthClient:   &http.Client{Timeout: 10 * time.Second},
+		branding:      Branding{FooterLinks: []BrandingLink{}, CommunityLinks: []BrandingLink{}},
 	}
 
+	r.buildGraphQLSchema()
+
 	// API routes
 	r.mux.HandleFunc("GET /api/servers", r.handleGetServers)
 	r.mux.HandleFunc("GET /api/servers/{id}", r.handleGetServer)
 	r.mux.HandleFunc("GET /api/servers/{id}/status", r.handleGetServerStatus)
 	r.mux.HandleFunc("GET /api/servers/{id}/players", r.handleGetServerPlayers)
+	r.mux.HandleFunc("GET /api/servers/{id}/population", r.handleGetServerPopulation)
+	r.mux.HandleFunc("GET /api/servers/{id}/incidents", r.requireAuth(r.handleListServerIncidents))
 
 	r.mux.HandleFunc("GET /api/players", r.handleGetPlayers)
+	r.mux.HandleFunc("POST /api/players/batch", r.handleBatchPlayers)
 	r.mux.HandleFunc("GET /api/players/{id}", r.handleGetPlayer)
 	r.mux.HandleFunc("GET /api/players/{id}/stats", r.handleGetPlayerStatsByID)
 	r.mux.HandleFunc("GET /api/players/{id}/matches", r.handleGetPlayerMatches)
+	r.mux.HandleFunc("GET /api/players/{id}/achievements", r.handleGetPlayerAchievements)
+	r.mux.HandleFunc("GET /api/players/{id}/trends", r.handleGetPlayerTrends)
+	r.mux.HandleFunc("GET /api/players/{id}/duels", r.handleGetPlayerDuels)
+	r.mux.HandleFunc("GET /api/players/{id}/maps", r.handleGetPlayerMaps)
+	r.mux.HandleFunc("GET /api/players/{id}/ping", r.handleGetPlayerPing)
 
 	r.mux.HandleFunc("GET /api/matches", r.handleGetMatches)
+	r.mux.HandleFunc("POST /api/matches/batch", r.handleBatchMatches)
 	r.mux.HandleFunc("GET /api/matches/{id}", r.handleGetMatch)
+	r.mux.HandleFunc("GET /api/matches/{id}/veto", r.handleGetMatchVeto)
+	r.mux.HandleFunc("GET /api/matches/{id}/demos", r.handleListMatchDemos)
+	r.mux.HandleFunc("GET /api/matches/{id}/chat", r.handleGetMatchChat)
+	r.mux.HandleFunc("GET /api/matches/{id}/flag-timeline", r.handleGetFlagTimeline)
+	r.mux.HandleFunc("GET /api/demos", r.handleBrowseDemos)
+	r.mux.HandleFunc("GET /api/demos/{file}", r.handleDownloadDemo)
 
 	r.mux.HandleFunc("GET /api/stats/leaderboard", r.handleGetLeaderboard)
+	r.mux.HandleFunc("GET /api/records", r.handleGetRecords)
+	r.mux.HandleFunc("GET /api/stats/returning", r.handleGetReturningPlayers)
+	r.mux.HandleFunc("GET /api/stats/countries", r.handleGetCountryBreakdown)
+	r.mux.HandleFunc("GET /api/stats/activity", r.handleGetActivity)
+	r.mux.HandleFunc("GET /api/stats/sessions", r.handleGetSessionStats)
+	r.mux.HandleFunc("GET /api/stats/bots", r.handleGetBotStats)
+	r.mux.HandleFunc("GET /api/stats/ping", r.handleGetServerPing)
+	r.mux.HandleFunc("GET /api/ladders/duel", r.handleGetDuelLadder)
+	r.mux.HandleFunc("GET /api/duels", r.handleGetDuels)
+	r.mux.HandleFunc("GET /api/duels/rankings", r.handleGetDuelRankings)
+	r.mux.HandleFunc("GET /api/clans", r.handleListClans)
+	r.mux.HandleFunc("GET /api/clans/leaderboard", r.handleGetClanLeaderboard)
+	r.mux.HandleFunc("GET /api/clans/{id}", r.handleGetClan)
+	r.mux.HandleFunc("GET /api/clans/{id}/matches", r.handleGetClanMatches)
+	r.mux.HandleFunc("GET /api/tournaments", r.handleListTournaments)
+	r.mux.HandleFunc("GET /api/tournaments/{id}", r.handleGetTournament)
+	r.mux.HandleFunc("GET /api/seasons", r.handleListSeasons)
+	r.mux.HandleFunc("GET /api/seasons/{id}", r.handleGetSeason)
+	r.mux.HandleFunc("GET /api/portraits", r.handleListPortraits)
+	r.mux.HandleFunc("GET /api/graphql", r.handleGraphQL)
+	r.mux.HandleFunc("POST /api/graphql", r.handleGraphQL)
+
+	// Bulk CSV/JSON downloads, streamed
+	r.mux.HandleFunc("GET /api/export/matches", r.rateLimit(r.exportLimiter, r.handleExportMatches))
+	r.mux.HandleFunc("GET /api/export/players", r.rateLimit(r.exportLimiter, r.handleExportPlayers))
 
 	// Public list of source names; powers the source-filter dropdown
 	// in the activity log and matches list.
 	r.mux.HandleFunc("GET /api/sources", r.handleGetSourceNames)
 
+	// Optional-feature switches for the SPA
+	r.mux.HandleFunc("GET /api/config/features", r.handleGetFeatures)
+	r.mux.HandleFunc("GET /api/config/branding", r.handleGetBranding)
+
+	// Machine-readable description of the routes above that need no
+	// account, for generating clients
+	r.mux.HandleFunc("GET /api/openapi.json", r.handleOpenAPI)
+
 	// Auth routes
-	r.mux.HandleFunc("POST /api/auth/login", r.rateLimit(r.loginLimiter, r.handleLogin))
+	r.mux.HandleFunc("POST /api/auth/login", r.rateLimit(r.loginLimiter, r.guardAuth(http.StatusUnauthorized, true, r.handleLogin)))
 	r.mux.HandleFunc("POST /api/auth/logout", r.handleLogout)
+	r.mux.HandleFunc("POST /api/auth/refresh", r.handleRefresh)
+	r.mux.HandleFunc("POST /api/auth/oauth/{provider}/start", r.handleOAuthStart)
+	r.mux.HandleFunc("GET /api/auth/oauth/{provider}/callback", r.handleOAuthCallback)
 	r.mux.HandleFunc("GET /api/auth/check", r.handleAuthCheck)
 	r.mux.HandleFunc("POST /api/auth/change-password", r.requireAuth(r.handleChangePassword))
 
 	// Game auth (public - no JWT required)
-	r.mux.HandleFunc("POST /api/auth/game-login", r.rateLimit(r.loginLimiter, r.handleGameLogin))
+	r.mux.HandleFunc("POST /api/auth/game-login", r.rateLimit(r.loginLimiter, r.guardAuth(http.StatusUnauthorized, true, r.handleGameLogin)))
 
 	// Game token management (requires JWT auth)
 	r.mux.HandleFunc("GET /api/auth/game-token", r.requireAuth(r.handleGetGameToken))
@@ -115,12 +201,38 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 
 	// Account routes (authenticated users only)
 	r.mux.HandleFunc("GET /api/account/profile", r.requireAuth(r.handleGetAccountProfile))
+	r.mux.HandleFunc("PATCH /api/account/profile", r.requireAuth(r.handleUpdateAccountProfile))
 	r.mux.HandleFunc("POST /api/account/link-code", r.requireAuth(r.handleCreateLinkCode))
+	r.mux.HandleFunc("GET /api/account/identities", r.requireAuth(r.handleListIdentities))
+	r.mux.HandleFunc("POST /api/account/identities/{provider}", r.requireAuth(r.handleLinkIdentity))
+	r.mux.HandleFunc("DELETE /api/account/identities/{provider}", r.requireAuth(r.handleUnlinkIdentity))
+	r.mux.HandleFunc("POST /api/account/reauth/{provider}", r.requireAuth(r.handleOAuthReauth))
+	r.mux.HandleFunc("GET /api/account/demos", r.requireAuth(r.handleListMyDemos))
+	r.mux.HandleFunc("GET /api/account/api-keys", r.requireAuth(r.handleListAPIKeys))
+	r.mux.HandleFunc("POST /api/account/api-keys", r.requireAuth(r.handleCreateAPIKey))
+	r.mux.HandleFunc("DELETE /api/account/api-keys/{id}", r.requireAuth(r.handleRevokeAPIKey))
+	r.mux.HandleFunc("GET /api/account/anonymize", r.requireAuth(r.handleGetAnonymize))
+	r.mux.HandleFunc("POST /api/account/anonymize", r.requireAuth(r.handleRequestAnonymize))
+	r.mux.HandleFunc("DELETE /api/account/anonymize", r.requireAuth(r.handleCancelAnonymize))
+
+	// Clans: anyone with an account can create or join one; the leader
+	// or an admin manages it
+	r.mux.HandleFunc("POST /api/clans", r.requireAuth(r.handleCreateClan))
+	r.mux.HandleFunc("PATCH /api/clans/{id}", r.requireAuth(r.handleUpdateClan))
+	r.mux.HandleFunc("DELETE /api/clans/{id}", r.requireAuth(r.handleDeleteClan))
+	r.mux.HandleFunc("POST /api/clans/{id}/join", r.requireAuth(r.handleJoinClan))
+	r.mux.HandleFunc("POST /api/clans/{id}/members", r.requireAuth(r.handleAddClanMember))
+	r.mux.HandleFunc("DELETE /api/clans/{id}/members/me", r.requireAuth(r.handleLeaveClan))
+	r.mux.HandleFunc("DELETE /api/clans/{id}/members/{player_id}", r.requireAuth(r.handleRemoveClanMember))
+
+	// Tournament sign-ups, for the player linked to the account
+	r.mux.HandleFunc("POST /api/tournaments/{id}/signup", r.requireAuth(r.handleTournamentSignup))
+	r.mux.HandleFunc("DELETE /api/tournaments/{id}/signup", r.requireAuth(r.handleTournamentWithdraw))
 
 	// Claim routes (player-initiated account creation)
-	r.mux.HandleFunc("POST /api/claim/validate", r.handleClaimValidate)
-	r.mux.HandleFunc("POST /api/claim/register", r.handleClaimRegister)
-	r.mux.HandleFunc("POST /api/claim/link", r.requireAuth(r.handleClaimLink))
+	r.mux.HandleFunc("POST /api/claim/validate", r.guardAuth(http.StatusNotFound, false, r.handleClaimValidate))
+	r.mux.HandleFunc("POST /api/claim/register", r.guardAuth(http.StatusNotFound, false, r.handleClaimRegister))
+	r.mux.HandleFunc("POST /api/claim/link", r.requireAuth(r.guardAuth(http.StatusNotFound, false, r.handleClaimLink)))
 
 	// User management routes (admin only)
 	r.mux.HandleFunc("GET /api/users", r.requireAdmin(r.handleListUsers))
@@ -128,14 +240,19 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 	r.mux.HandleFunc("DELETE /api/users/{username}", r.requireAdmin(r.handleDeleteUser))
 	r.mux.HandleFunc("PATCH /api/users/{id}", r.requireAdmin(r.handleUpdateUser))
 	r.mux.HandleFunc("POST /api/users/{id}/reset-password", r.requireAdmin(r.handleResetUserPassword))
+	r.mux.HandleFunc("POST /api/users/{id}/impersonate", r.requireAdmin(r.handleImpersonateUser))
+	r.mux.HandleFunc("POST /api/users/{id}/revoke-tokens", r.requireAdmin(r.handleRevokeUserTokens))
 
 	// RCON routes. Auth-only (not admin-only); the handler
 	// authorizes by source ownership + per-server admin delegation.
 	r.mux.HandleFunc("POST /api/servers/{id}/rcon", r.requireAuth(r.handleRconCommand))
 	r.mux.HandleFunc("GET /api/servers/{id}/rcon-status", r.handleRconStatus)
 
-	// WebSocket endpoints
+	// Live events: WebSocket, with SSE and long-poll fallbacks
+	r.mux.HandleFunc("GET /api/events", r.handleEventStream)
+	r.mux.HandleFunc("GET /api/events/poll", r.handlePollEvents)
 	r.mux.HandleFunc("GET /ws", r.handleWebSocket)
+	r.mux.HandleFunc("GET /ws/match/{id}", r.handleMatchWebSocket)
 
 	// Asset fallbacks. nginx serves these paths as static when the file
 	// is on disk; on a miss it forwards the request here via try_files
@@ -145,11 +262,71 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 	r.mux.HandleFunc("GET /assets/levelshots/{filename}", r.handleLevelshot)
 	r.mux.HandleFunc("GET /demopk3s/maps/{filename}",    r.handleMapPk3)
 
+	// Embeddable SVG stat badges
+	r.mux.HandleFunc("GET /badge/player/{id}/{file}", r.handlePlayerBadge)
+	r.mux.HandleFunc("GET /badge/server/{id}/{file}", r.handleServerBadge)
+
+	// Anonymized, cached mirror of the public read routes
+	// (features.public_api)
+	r.mux.HandleFunc("GET /public/api/{path...}", r.handlePublicMirror)
+
 	// Player management routes (admin only)
 	r.mux.HandleFunc("GET /api/players/{id}/guids", r.handleGetPlayerGUIDs)
 	r.mux.HandleFunc("GET /api/players/{id}/sessions", r.requireAdmin(r.handleGetPlayerSessions))
+	r.mux.HandleFunc("GET /api/players/{id}/location", r.requireAdmin(r.handleGetPlayerLocation))
 	r.mux.HandleFunc("POST /api/admin/players/{id}/merge", r.requireAdmin(r.handleMergePlayers))
+	r.mux.HandleFunc("GET /api/admin/players/merges", r.requireAdmin(r.handleListPlayerMerges))
+	r.mux.HandleFunc("POST /api/admin/players/merges/{id}/undo", r.requireAdmin(r.handleUndoPlayerMerge))
+	r.mux.HandleFunc("POST /api/admin/players/{id}/anonymize", r.requireAdmin(r.handleAdminAnonymize))
 	r.mux.HandleFunc("POST /api/admin/guids/{id}/split", r.requireAdmin(r.handleSplitGUID))
+	r.mux.HandleFunc("GET /api/admin/players/merge-suggestions", r.requireAdmin(r.handleListMergeSuggestions))
+	r.mux.HandleFunc("POST /api/admin/players/merge-suggestions/{id}/dismiss", r.requireAdmin(r.handleDismissMergeSuggestion))
+	r.mux.HandleFunc("GET /api/admin/stat-flags", r.requireAdmin(r.handleListStatFlags))
+	r.mux.HandleFunc("POST /api/admin/stat-flags/{id}/review", r.requireAdmin(r.handleReviewStatFlag))
+	r.mux.HandleFunc("PUT /api/admin/players/{id}/leaderboard-exclusion", r.requireAdmin(r.handleSetLeaderboardExclusion))
+	r.mux.HandleFunc("GET /api/admin/leaderboard-exclusions", r.requireAdmin(r.handleListLeaderboardExclusions))
+	r.mux.HandleFunc("PUT /api/admin/players/{id}/vip", r.requireAdmin(r.handleSetPlayerVIP))
+	r.mux.HandleFunc("GET /api/admin/vips", r.requireAdmin(r.handleListVIPs))
+	r.mux.HandleFunc("GET /api/admin/vip-kicks", r.requireAdmin(r.handleListVIPKicks))
+	r.mux.HandleFunc("GET /api/admin/admin-commands", r.requireAdmin(r.handleListAdminCommands))
+
+	// Map veto recording for competitive matches (admin only)
+	r.mux.HandleFunc("GET /api/admin/vetoes", r.requireAdmin(r.handleListMapVetoes))
+	r.mux.HandleFunc("POST /api/admin/vetoes", r.requireAdmin(r.handleCreateMapVeto))
+	r.mux.HandleFunc("PUT /api/admin/vetoes/{id}/match", r.requireAdmin(r.handleSetMapVetoMatch))
+	r.mux.HandleFunc("DELETE /api/admin/vetoes/{id}", r.requireAdmin(r.handleDeleteMapVeto))
+	r.mux.HandleFunc("POST /api/admin/tournaments", r.requireAdmin(r.handleCreateTournament))
+	r.mux.HandleFunc("DELETE /api/admin/tournaments/{id}", r.requireAdmin(r.handleDeleteTournament))
+	r.mux.HandleFunc("POST /api/admin/tournaments/{id}/start", r.requireAdmin(r.handleStartTournament))
+	r.mux.HandleFunc("PUT /api/admin/tournaments/{id}/matches/{pairing_id}", r.requireAdmin(r.handleSetTournamentResult))
+	r.mux.HandleFunc("POST /api/admin/seasons", r.requireAdmin(r.handleCreateSeason))
+	r.mux.HandleFunc("PUT /api/admin/seasons/{id}", r.requireAdmin(r.handleUpdateSeason))
+	r.mux.HandleFunc("DELETE /api/admin/seasons/{id}", r.requireAdmin(r.handleDeleteSeason))
+
+	// Master-server browser (admin only)
+	r.mux.HandleFunc("GET /api/admin/browser", r.requireAdmin(r.handleBrowseServers))
+	r.mux.HandleFunc("POST /api/admin/browser/track", r.requireAdmin(r.handleTrackExternalServer))
+
+	// Stat corrections for miscounted matches (admin only)
+	r.mux.HandleFunc("GET /api/admin/matches/{id}/corrections", r.requireAdmin(r.handleListMatchCorrections))
+	r.mux.HandleFunc("POST /api/admin/matches/{id}/corrections", r.requireAdmin(r.handleCorrectMatchStats))
+
+	// Uploaded client demos (admin only)
+	r.mux.HandleFunc("POST /api/admin/matches/{id}/demo", r.requireAdmin(r.handleUploadMatchDemo))
+	r.mux.HandleFunc("GET /api/admin/demos", r.requireAdmin(r.handleListDemos))
+	r.mux.HandleFunc("DELETE /api/admin/demos/{id}", r.requireAdmin(r.handleDeleteDemo))
+
+	// Chat filter hits (admin only)
+	r.mux.HandleFunc("GET /api/admin/moderation", r.requireAdmin(r.handleListModerationIncidents))
+
+	// Game server unit control (admin only, local source only)
+	r.mux.HandleFunc("POST /api/admin/servers", r.requireAdmin(r.handleAddServer))
+	r.mux.HandleFunc("DELETE /api/admin/servers/{id}", r.requireAdmin(r.handleRemoveServer))
+	r.mux.HandleFunc("POST /api/admin/servers/{id}/control", r.requireAdmin(r.handleServerControl))
+	r.mux.HandleFunc("POST /api/admin/reload", r.requireAdmin(r.handleReload))
+
+	// In-game chat command switch (admin only)
+	r.mux.HandleFunc("PUT /api/admin/servers/{id}/commands", r.requireAdmin(r.handleSetServerCommands))
 
 	// Distributed-tracking source management. Sources are pre-provisioned:
 	// POST /api/admin/sources creates a new source + mints initial creds
@@ -168,6 +345,12 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 	r.mux.HandleFunc("POST /api/admin/sources/{source}/owner", r.requireAdmin(r.handleTransferSourceOwner))
 	r.mux.HandleFunc("GET /api/admin/sessions", r.requireAdmin(r.handleListAdminSessions))
 	r.mux.HandleFunc("GET /api/admin/audit", r.requireAdmin(r.handleListAudit))
+	r.mux.HandleFunc("GET /api/admin/impersonations", r.requireAdmin(r.handleListImpersonations))
+	r.mux.HandleFunc("GET /api/admin/api-keys", r.requireAdmin(r.handleAdminListAPIKeys))
+	r.mux.HandleFunc("DELETE /api/admin/api-keys/{id}", r.requireAdmin(r.handleAdminRevokeAPIKey))
+	r.mux.HandleFunc("GET /api/admin/auth-lockouts", r.requireAdmin(r.handleListAuthLockouts))
+	r.mux.HandleFunc("DELETE /api/admin/auth-lockouts/{kind}/{key}", r.requireAdmin(r.handleClearAuthLockout))
+	r.mux.HandleFunc("GET /api/admin/usage", r.requireAdmin(r.handleGetUsage))
 
 	// Owner-scoped self-service. GET /api/sources/mine returns the
 	// caller's full source list (one card per source in My Servers);
@@ -186,6 +369,7 @@ func NewRouter(store *storage.Store, manager *collector.ServerManager, writer *h
 
 	// Health check
 	r.mux.HandleFunc("GET /health", r.handleHealth)
+	r.mux.HandleFunc("GET /metrics", r.handleMetrics)
 
 	// Static files - only serve if staticDir is configured
 	if staticDir != "" {
@@ -207,9 +391,24 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Public API traffic feeds /api/admin/usage. WebSocket upgrades
+	// and static files stay on the plain path (the recorder doesn't
+	// implement http.Hijacker).
+	if strings.HasPrefix(req.URL.Path, "/api/") {
+		r.serveTracked(w, req)
+		return
+	}
 	r.mux.ServeHTTP(w, req)
 }
 
+// SetBroadcastBuffer resizes the WebSocket hub's broadcast channel
+// (default 256). Must be called before StartWebSocketHub.
+func (r *Router) SetBroadcastBuffer(n int) {
+	if n > 0 {
+		r.wsHub.broadcast = make(chan wsMessage, n)
+	}
+}
+
 // StartWebSocketHub starts broadcasting events to WebSocket clients.
 // Events flow: collector emits with GUIDs → writer enriches to fill
 // player IDs → WebSocket hub broadcasts to browser clients.
@@ -244,17 +443,21 @@ func (r *Router) StartWebSocketHub() {
 // observation per serverID. Production wiring always sets r.writer;
 // the nil bypass exists for tests and early bring-up.
 func (r *Router) Broadcast(event domain.Event) {
-	if r.writer == nil {
-		r.wsHub.Broadcast(event)
+	if !r.shouldBroadcast(event) {
 		return
 	}
-	if event.ServerID == 0 {
-		return
+	r.wsHub.Broadcast(event)
+}
+
+// shouldBroadcast applies the handshake gate described on Broadcast.
+func (r *Router) shouldBroadcast(event domain.Event) bool {
+	if r.writer == nil {
+		return true
 	}
-	if !r.writer.IsHandshakeEnforced(context.Background(), event.ServerID) {
-		return
+	if event.ServerID == 0 {
+		return false
 	}
-	r.wsHub.Broadcast(event)
+	return r.writer.IsHandshakeEnforced(context.Background(), event.ServerID)
 }
 
 // handleStatic serves static files from the configured directory
@@ -365,11 +568,32 @@ func getContentType(path string) string {
 // every match flagged demo_available. nginx + handleDemo handle the
 // local-vs-remote dispatch on click. Empty DemoURL = no play button,
 // so users don't see dead links for matches whose recording was
-// discarded or never finalized.
-func (r *Router) populateDemoURLs(matches []domain.MatchSummary) {
+// discarded or never finalized. Matches without a recording fall back
+// to their newest admin-uploaded .dm_68, if any.
+func (r *Router) populateDemoURLs(ctx context.Context, matches []domain.MatchSummary) {
+	if !r.features.Demos {
+		return
+	}
+	var uploadedFor []int64
 	for i := range matches {
 		if matches[i].UUID != "" && matches[i].DemoAvailable {
 			matches[i].DemoURL = "/demos/" + matches[i].UUID + ".tvd"
+		} else {
+			uploadedFor = append(uploadedFor, matches[i].ID)
+		}
+	}
+	// No server-side recording: fall back to an uploaded client demo
+	if len(uploadedFor) == 0 {
+		return
+	}
+	latest, err := r.store.LatestMatchDemoIDs(ctx, uploadedFor)
+	if err != nil {
+		log.Printf("populateDemoURLs: %v", err)
+		return
+	}
+	for i := range matches {
+		if id, ok := latest[matches[i].ID]; ok && matches[i].DemoURL == "" {
+			matches[i].DemoURL = demos.URL(id)
 		}
 	}
 }
@@ -379,7 +603,7 @@ func (r *Router) populateDemoURLs(matches []domain.MatchSummary) {
 // nginx try_files fallback on misses.
 func (r *Router) handleDemo(w http.ResponseWriter, req *http.Request) {
 	uuid := stripSuffix(req.PathValue("filename"), ".tvd")
-	if uuid == "" {
+	if uuid == "" || !r.features.Demos {
 		http.NotFound(w, req)
 		return
 	}
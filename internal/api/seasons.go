@@ -0,0 +1,197 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// writeSeasonError maps season storage errors to responses.
+func writeSeasonError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "season not found")
+	case errors.Is(err, storage.ErrSeasonOverlap), errors.Is(err, storage.ErrSeasonFinalized):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// handleListSeasons lists seasons, latest first.
+//
+// path: GET /api/seasons
+func (r *Router) handleListSeasons(w http.ResponseWriter, req *http.Request) {
+	seasons, err := r.store.ListSeasons(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, seasons)
+}
+
+// handleGetSeason returns a season with its standings: final once it
+// has ended, live until then.
+//
+// path: GET /api/seasons/{id}
+func (r *Router) handleGetSeason(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid season id")
+		return
+	}
+	resp, err := r.store.GetSeasonDetails(req.Context(), id, time.Now().UTC())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if resp == nil {
+		writeError(w, http.StatusNotFound, "season not found")
+		return
+	}
+	r.writeStatsJSON(w, http.StatusOK, resp)
+}
+
+// seasonBody is the request body for creating or changing a season.
+// category defaults to frags.
+type seasonBody struct {
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Category string    `json:"category"`
+}
+
+// decodeSeason reads and validates a seasonBody into a season.
+func decodeSeason(w http.ResponseWriter, req *http.Request) (*domain.Season, bool) {
+	var body seasonBody
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return nil, false
+	}
+	se := &domain.Season{
+		Name:     strings.TrimSpace(body.Name),
+		StartsAt: body.StartsAt.UTC(),
+		EndsAt:   body.EndsAt.UTC(),
+		Category: body.Category,
+	}
+	if se.Category == "" {
+		se.Category = "frags"
+	}
+	if err := validateSeason(se); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return se, true
+}
+
+// handleCreateSeason defines a season. Seasons can't overlap.
+//
+// path: POST /api/admin/seasons
+func (r *Router) handleCreateSeason(w http.ResponseWriter, req *http.Request) {
+	se, ok := decodeSeason(w, req)
+	if !ok {
+		return
+	}
+	actor := "unknown"
+	if claims := r.getAuthClaims(req); claims != nil {
+		se.CreatedBy = &claims.UserID
+		actor = claims.Username
+	}
+	if err := r.store.CreateSeason(req.Context(), se); err != nil {
+		writeSeasonError(w, err)
+		return
+	}
+	log.Printf("audit: season_create season=%d name=%q starts=%s ends=%s actor=%s remote=%s",
+		se.ID, se.Name, se.StartsAt.Format(time.RFC3339), se.EndsAt.Format(time.RFC3339), actor, req.RemoteAddr)
+	writeJSON(w, http.StatusCreated, se)
+}
+
+// handleUpdateSeason changes a season's name, dates or category until
+// its standings are final.
+//
+// path: PUT /api/admin/seasons/{id}
+func (r *Router) handleUpdateSeason(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid season id")
+		return
+	}
+	se, ok := decodeSeason(w, req)
+	if !ok {
+		return
+	}
+	se.ID = id
+	if err := r.store.UpdateSeason(req.Context(), se); err != nil {
+		writeSeasonError(w, err)
+		return
+	}
+	actor := "unknown"
+	if claims := r.getAuthClaims(req); claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: season_update season=%d name=%q starts=%s ends=%s actor=%s remote=%s",
+		se.ID, se.Name, se.StartsAt.Format(time.RFC3339), se.EndsAt.Format(time.RFC3339), actor, req.RemoteAddr)
+	updated, err := r.store.GetSeason(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// handleDeleteSeason removes a season and its standings, badges
+// included.
+//
+// path: DELETE /api/admin/seasons/{id}
+func (r *Router) handleDeleteSeason(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid season id")
+		return
+	}
+	if err := r.store.DeleteSeason(req.Context(), id); err != nil {
+		writeSeasonError(w, err)
+		return
+	}
+	actor := "unknown"
+	if claims := r.getAuthClaims(req); claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: season_delete season=%d actor=%s remote=%s", id, actor, req.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// leaderboardSeason resolves the leaderboard's season parameter:
+// "current" or a season id. Writes the error response and returns
+// false if it names no season.
+func (r *Router) leaderboardSeason(w http.ResponseWriter, req *http.Request, param string) (*domain.Season, bool) {
+	var se *domain.Season
+	var err error
+	if param == "current" {
+		se, err = r.store.CurrentSeason(req.Context(), time.Now().UTC())
+	} else {
+		id, perr := strconv.ParseInt(param, 10, 64)
+		if perr != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "season must be current or a season id")
+			return nil, false
+		}
+		se, err = r.store.GetSeason(req.Context(), id)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if se == nil {
+		writeError(w, http.StatusNotFound, "season not found")
+		return nil, false
+	}
+	return se, true
+}
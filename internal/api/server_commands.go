@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/natsbus"
+)
+
+// ServerCommandsRequest is the request body for switching a server's
+// chat commands.
+type ServerCommandsRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ServerCommandsResponse reports the new setting. Applied is false
+// when the server's collector couldn't be told; it picks the setting
+// up the next time it starts.
+type ServerCommandsResponse struct {
+	Enabled bool `json:"enabled"`
+	Applied bool `json:"applied"`
+}
+
+// handleSetServerCommands turns a server's in-game chat commands
+// (!link, !stats, ...) on or off. While they're off the collector
+// ignores every command sent on that server.
+//
+// path: PUT /api/admin/servers/{id}/commands
+func (r *Router) handleSetServerCommands(w http.ResponseWriter, req *http.Request) {
+	serverID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	var body ServerCommandsRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	enabled := *body.Enabled
+
+	ctx := req.Context()
+	server, err := r.store.GetServerByID(ctx, serverID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load server")
+		return
+	}
+	if err := r.store.SetServerCommandsDisabled(ctx, serverID, !enabled); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save setting")
+		return
+	}
+
+	claims := r.getAuthClaims(req)
+	actor := "unknown"
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: server_commands server=%d enabled=%v actor=%s remote=%s", serverID, enabled, actor, req.RemoteAddr)
+
+	applied := true
+	if err := r.pushServerCommands(ctx, server, enabled, actor); err != nil {
+		log.Printf("server %d: telling collector about chat commands: %v", serverID, err)
+		applied = false
+	}
+	writeJSON(w, http.StatusOK, ServerCommandsResponse{Enabled: enabled, Applied: applied})
+}
+
+// pushServerCommands tells server's collector to turn its chat
+// commands on or off: in-process for the local source, over the RCON
+// proxy subject for everything else.
+func (r *Router) pushServerCommands(ctx context.Context, server *domain.Server, enabled bool, actor string) error {
+	if r.localSource != "" && server.Source == r.localSource && r.manager != nil {
+		if !r.manager.SetCommandsDisabled(server.Key, !enabled) {
+			return fmt.Errorf("server %q not found on local collector", server.Key)
+		}
+		return nil
+	}
+	if r.rconClient == nil {
+		return fmt.Errorf("no transport configured for source %q", server.Source)
+	}
+	command := "disable"
+	if enabled {
+		command = "enable"
+	}
+	_, err := r.rconClient.Exec(ctx, server.Source, natsbus.RconExecRequest{
+		ServerKey: server.Key,
+		Command:   command,
+		Username:  actor,
+		Role:      natsbus.RconRoleCommands,
+	})
+	return err
+}
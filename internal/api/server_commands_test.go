@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestSetServerCommands(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, _ := tr.loginAs(t, "user", false)
+	path := fmt.Sprintf("/api/admin/servers/%d/commands", srv.ID)
+
+	if w := tr.do("PUT", path, `{"enabled":false}`, userTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: %d, want 403", w.Code)
+	}
+	if w := tr.do("PUT", path, `{}`, adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("missing enabled: %d, want 400", w.Code)
+	}
+	if w := tr.do("PUT", "/api/admin/servers/999/commands", `{"enabled":false}`, adminTok); w.Code != http.StatusNotFound {
+		t.Errorf("unknown server: %d, want 404", w.Code)
+	}
+
+	// No collector is wired up, so the setting is saved but not applied.
+	w := tr.do("PUT", path, `{"enabled":false}`, adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("disable: %d %s", w.Code, w.Body)
+	}
+	var resp ServerCommandsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Enabled || resp.Applied {
+		t.Errorf("response = %+v", resp)
+	}
+	got, err := tr.store.GetServerByID(ctx, srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CommandsDisabled {
+		t.Error("commands_disabled not saved")
+	}
+
+	if w := tr.do("PUT", path, `{"enabled":true}`, adminTok); w.Code != http.StatusOK {
+		t.Fatalf("enable: %d %s", w.Code, w.Body)
+	}
+	if got, _ := tr.store.GetServerByID(ctx, srv.ID); got.CommandsDisabled {
+		t.Error("commands still disabled")
+	}
+}
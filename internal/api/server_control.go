@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ServerController starts, stops, and restarts the quake3-server@
+// systemd unit behind a game server. main.go wires a systemctl-backed
+// implementation; when unset the control endpoint returns 501.
+type ServerController interface {
+	// Control runs action ("start", "stop", or "restart") against
+	// unit and returns the unit's active state afterwards
+	// (systemctl is-active: "active", "inactive", "failed", ...).
+	Control(ctx context.Context, unit, action string) (state string, err error)
+}
+
+// SetServerController plugs in the unit controller. Only servers on
+// the local source (see SetLocalSource) can be controlled — remote
+// sources' units live on other hosts.
+func (r *Router) SetServerController(c ServerController) {
+	r.serverCtl = c
+}
+
+// ServerControlRequest is the request body for server control
+type ServerControlRequest struct {
+	Action string `json:"action"`
+}
+
+// ServerControlResponse reports the unit's state after the action
+type ServerControlResponse struct {
+	Unit  string `json:"unit"`
+	State string `json:"state"`
+}
+
+// handleServerControl starts, stops, or restarts a local game server's
+// systemd unit.
+//
+// path: POST /api/admin/servers/{id}/control
+func (r *Router) handleServerControl(w http.ResponseWriter, req *http.Request) {
+	if r.serverCtl == nil {
+		writeError(w, http.StatusNotImplemented, "server control not enabled on this hub")
+		return
+	}
+	serverID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	var body ServerControlRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	switch body.Action {
+	case "start", "stop", "restart":
+	default:
+		writeError(w, http.StatusBadRequest, "action must be start, stop, or restart")
+		return
+	}
+
+	server, err := r.store.GetServerByID(req.Context(), serverID)
+	if err != nil || server == nil {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	if r.localSource == "" || server.Source != r.localSource {
+		writeError(w, http.StatusConflict, "server is not managed by this host")
+		return
+	}
+
+	unit := "quake3-server@" + server.Key
+	claims := r.getAuthClaims(req)
+	actor := ""
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: server_control %s unit=%s actor=%s remote=%s", body.Action, unit, actor, req.RemoteAddr)
+
+	state, err := r.serverCtl.Control(req.Context(), unit, body.Action)
+	if err != nil {
+		log.Printf("server control %s %s: %v", body.Action, unit, err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ServerControlResponse{Unit: unit, State: state})
+}
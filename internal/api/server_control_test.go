@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+type fakeServerCtl struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeServerCtl) Control(ctx context.Context, unit, action string) (string, error) {
+	f.calls = append(f.calls, action+" "+unit)
+	if f.err != nil {
+		return "failed", f.err
+	}
+	if action == "stop" {
+		return "inactive", nil
+	}
+	return "active", nil
+}
+
+func TestHandleServerControl(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, _ := tr.loginAs(t, "alice", false)
+	ctx := context.Background()
+
+	local := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "local", local); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	remote := &domain.Server{Key: "ctf", Address: "10.0.0.2:27960"}
+	if err := tr.store.UpsertServer(ctx, "elsewhere", remote); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	path := func(id int64) string { return fmt.Sprintf("/api/admin/servers/%d/control", id) }
+
+	// Not wired → 501.
+	if w := tr.do("POST", path(local.ID), `{"action":"restart"}`, adminTok); w.Code != http.StatusNotImplemented {
+		t.Errorf("unwired: code = %d, want 501", w.Code)
+	}
+
+	ctl := &fakeServerCtl{}
+	tr.r.SetServerController(ctl)
+	tr.r.SetLocalSource("local")
+
+	if w := tr.do("POST", path(local.ID), `{"action":"restart"}`, aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: code = %d, want 403", w.Code)
+	}
+	if w := tr.do("POST", path(local.ID), `{"action":"reboot"}`, adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("bad action: code = %d, want 400", w.Code)
+	}
+	if w := tr.do("POST", path(remote.ID), `{"action":"stop"}`, adminTok); w.Code != http.StatusConflict {
+		t.Errorf("remote server: code = %d, want 409", w.Code)
+	}
+
+	w := tr.do("POST", path(local.ID), `{"action":"stop"}`, adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop: %d %s", w.Code, w.Body)
+	}
+	var resp ServerControlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Unit != "quake3-server@ffa" || resp.State != "inactive" {
+		t.Errorf("response: got %+v", resp)
+	}
+	if len(ctl.calls) != 1 || ctl.calls[0] != "stop quake3-server@ffa" {
+		t.Errorf("calls: got %v", ctl.calls)
+	}
+
+	ctl.err = errors.New("Access denied")
+	if w := tr.do("POST", path(local.ID), `{"action":"start"}`, adminTok); w.Code != http.StatusBadGateway {
+		t.Errorf("failing unit: code = %d, want 502", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+)
+
+// handleListServerIncidents lists a server's crashes, newest first,
+// with the log lines from before each one. Admins and the owner of
+// the server's source can read them; the lines can carry player names
+// and chat. limit caps the list (default 20, max 100).
+//
+// path: GET /api/servers/{id}/incidents
+func (r *Router) handleListServerIncidents(w http.ResponseWriter, req *http.Request) {
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	server, err := r.store.GetServerByID(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	if !claims.IsAdmin {
+		owners, err := r.store.SourceOwners(req.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if ownerID, ok := owners[server.Source]; !ok || ownerID != claims.UserID {
+			writeError(w, http.StatusForbidden, "you do not manage this server")
+			return
+		}
+	}
+
+	incidents, err := r.store.ListServerIncidents(req.Context(), id, parseLimit(req, 20, 100))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, incidents)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestListServerIncidents(t *testing.T) {
+	tr := newTestRouter(t)
+	ctx := context.Background()
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	bobTok, bobID := tr.loginAs(t, "bob", false)
+	eveTok, _ := tr.loginAs(t, "eve", false)
+
+	if err := tr.store.CreateSource(ctx, "bob-q3", true, &bobID); err != nil {
+		t.Fatalf("CreateSource: %v", err)
+	}
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "bob-q3", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := tr.store.RecordServerIncident(ctx, domain.ServerIncident{
+		ServerID: srv.ID, Reason: domain.CrashUnitFailed, Detail: "core-dump", OccurredAt: at,
+		LogLines: []string{"line one", "line two"},
+	}); err != nil {
+		t.Fatalf("RecordServerIncident: %v", err)
+	}
+	path := "/api/servers/" + strconv.FormatInt(srv.ID, 10) + "/incidents"
+
+	for _, tc := range []struct {
+		name, token string
+		code        int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"other user", eveTok, http.StatusForbidden},
+		{"owner", bobTok, http.StatusOK},
+		{"admin", adminTok, http.StatusOK},
+	} {
+		w := tr.do("GET", path, "", tc.token)
+		if w.Code != tc.code {
+			t.Errorf("%s: %d, want %d; body=%s", tc.name, w.Code, tc.code, w.Body)
+			continue
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+		var got []domain.ServerIncident
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Detail != "core-dump" || len(got[0].LogLines) != 2 || !got[0].OccurredAt.Equal(at) {
+			t.Errorf("%s: incidents = %+v", tc.name, got)
+		}
+	}
+
+	if w := tr.do("GET", "/api/servers/999/incidents", "", adminTok); w.Code != http.StatusNotFound {
+		t.Errorf("unknown server: %d, want 404", w.Code)
+	}
+}
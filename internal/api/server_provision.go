@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Errors a ServerProvisioner wraps so the handlers can pick a status.
+var (
+	ErrServerInvalid  = errors.New("invalid server")
+	ErrServerExists   = errors.New("server already exists")
+	ErrServerNotFound = errors.New("server is not in config.yml")
+)
+
+// ServerProvisioner adds and removes this host's game servers the way
+// `trinity server add` and `trinity server remove` do, then applies the
+// change without a restart. main.go wires one when a collector runs in
+// this process; when unset the endpoints return 501.
+type ServerProvisioner interface {
+	AddServer(ctx context.Context, req ServerAddRequest) (*ServerAddResponse, error)
+	RemoveServer(ctx context.Context, key string) error
+}
+
+// SetServerProvisioner plugs in the server provisioner. Like server
+// control, it only reaches servers on the local source.
+func (r *Router) SetServerProvisioner(p ServerProvisioner) {
+	r.serverProv = p
+}
+
+// ServerAddRequest is the request body for adding a server. Only Key
+// is required; the rest default as they do for `trinity server add`.
+type ServerAddRequest struct {
+	Key               string `json:"key"`
+	GameType          string `json:"game_type,omitempty"`
+	TeamArena         bool   `json:"team_arena,omitempty"`
+	Port              int    `json:"port,omitempty"`
+	RconPassword      string `json:"rcon_password,omitempty"`
+	AllowHubAdminRcon bool   `json:"allow_hub_admin_rcon,omitempty"`
+}
+
+// ServerAddResponse describes the server that was added. State is its
+// systemd unit's state, empty when systemd isn't managing servers.
+type ServerAddResponse struct {
+	Key     string `json:"key"`
+	Address string `json:"address"`
+	LogPath string `json:"log_path"`
+	Unit    string `json:"unit,omitempty"`
+	State   string `json:"state,omitempty"`
+}
+
+// handleAddServer adds a game server on this host and starts tracking
+// it.
+//
+// path: POST /api/admin/servers
+func (r *Router) handleAddServer(w http.ResponseWriter, req *http.Request) {
+	if r.serverProv == nil {
+		writeError(w, http.StatusNotImplemented, "server management not enabled on this hub")
+		return
+	}
+	var body ServerAddRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if body.Key == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	if body.Port < 0 || body.Port > 65535 {
+		writeError(w, http.StatusBadRequest, "port must be between 1 and 65535")
+		return
+	}
+
+	claims := r.getAuthClaims(req)
+	actor := ""
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: server_add key=%s actor=%s remote=%s", body.Key, actor, req.RemoteAddr)
+
+	resp, err := r.serverProv.AddServer(req.Context(), body)
+	if err != nil {
+		writeProvisionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// handleRemoveServer stops a game server on this host, removes it from
+// config.yml, and stops tracking it. Its matches and stats are kept.
+//
+// path: DELETE /api/admin/servers/{id}
+func (r *Router) handleRemoveServer(w http.ResponseWriter, req *http.Request) {
+	if r.serverProv == nil {
+		writeError(w, http.StatusNotImplemented, "server management not enabled on this hub")
+		return
+	}
+	serverID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid server id")
+		return
+	}
+	server, err := r.store.GetServerByID(req.Context(), serverID)
+	if err != nil || server == nil {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	if r.localSource == "" || server.Source != r.localSource {
+		writeError(w, http.StatusConflict, "server is not managed by this host")
+		return
+	}
+
+	claims := r.getAuthClaims(req)
+	actor := ""
+	if claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: server_remove key=%s actor=%s remote=%s", server.Key, actor, req.RemoteAddr)
+
+	if err := r.serverProv.RemoveServer(req.Context(), server.Key); err != nil {
+		writeProvisionError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeProvisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrServerInvalid):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrServerExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrServerNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("server provisioning: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+type fakeServerProv struct {
+	keys    map[string]bool
+	removed []string
+}
+
+func (f *fakeServerProv) AddServer(ctx context.Context, req ServerAddRequest) (*ServerAddResponse, error) {
+	if f.keys[req.Key] {
+		return nil, fmt.Errorf("%w: %s", ErrServerExists, req.Key)
+	}
+	if strings.ToLower(req.Key) != req.Key {
+		return nil, fmt.Errorf("%w: bad key", ErrServerInvalid)
+	}
+	f.keys[req.Key] = true
+	return &ServerAddResponse{Key: req.Key, Address: "127.0.0.1:27961", LogPath: "/var/log/quake3/" + req.Key + ".log"}, nil
+}
+
+func (f *fakeServerProv) RemoveServer(ctx context.Context, key string) error {
+	if !f.keys[key] {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, key)
+	}
+	delete(f.keys, key)
+	f.removed = append(f.removed, key)
+	return nil
+}
+
+func TestHandleAddRemoveServer(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	aliceTok, _ := tr.loginAs(t, "alice", false)
+	ctx := context.Background()
+
+	// Not wired → 501.
+	if w := tr.do("POST", "/api/admin/servers", `{"key":"ctf"}`, adminTok); w.Code != http.StatusNotImplemented {
+		t.Errorf("unwired: code = %d, want 501", w.Code)
+	}
+
+	prov := &fakeServerProv{keys: map[string]bool{"ffa": true}}
+	tr.r.SetServerProvisioner(prov)
+	tr.r.SetLocalSource("local")
+
+	if w := tr.do("POST", "/api/admin/servers", `{"key":"ctf"}`, aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: code = %d, want 403", w.Code)
+	}
+	for body, want := range map[string]int{
+		`{}`:                         http.StatusBadRequest,
+		`{"key":"ctf","port":70000}`: http.StatusBadRequest,
+		`{"key":"CTF"}`:              http.StatusBadRequest,
+		`{"key":"ffa"}`:              http.StatusConflict,
+	} {
+		if w := tr.do("POST", "/api/admin/servers", body, adminTok); w.Code != want {
+			t.Errorf("add %s: code = %d, want %d", body, w.Code, want)
+		}
+	}
+
+	w := tr.do("POST", "/api/admin/servers", `{"key":"ctf","game_type":"ctf"}`, adminTok)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("add: %d %s", w.Code, w.Body)
+	}
+	var resp ServerAddResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Key != "ctf" || resp.LogPath != "/var/log/quake3/ctf.log" {
+		t.Errorf("response: got %+v", resp)
+	}
+
+	local := &domain.Server{Key: "ctf", Address: "127.0.0.1:27961"}
+	if err := tr.store.UpsertServer(ctx, "local", local); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	remote := &domain.Server{Key: "ffa", Address: "10.0.0.2:27960"}
+	if err := tr.store.UpsertServer(ctx, "elsewhere", remote); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	path := func(id int64) string { return fmt.Sprintf("/api/admin/servers/%d", id) }
+
+	if w := tr.do("DELETE", path(remote.ID), "", adminTok); w.Code != http.StatusConflict {
+		t.Errorf("remote server: code = %d, want 409", w.Code)
+	}
+	if w := tr.do("DELETE", path(local.ID), "", aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin remove: code = %d, want 403", w.Code)
+	}
+	if w := tr.do("DELETE", path(local.ID), "", adminTok); w.Code != http.StatusNoContent {
+		t.Fatalf("remove: %d %s", w.Code, w.Body)
+	}
+	if len(prov.removed) != 1 || prov.removed[0] != "ctf" {
+		t.Errorf("removed: got %v", prov.removed)
+	}
+	// Already gone from config.yml.
+	if w := tr.do("DELETE", path(local.ID), "", adminTok); w.Code != http.StatusNotFound {
+		t.Errorf("remove again: code = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// statFlagKinds are the kinds ?kind= accepts.
+var statFlagKinds = map[string]bool{
+	domain.StatFlagAccuracySpike:   true,
+	domain.StatFlagAccuracyExtreme: true,
+	domain.StatFlagKDSpike:         true,
+	domain.StatFlagFragRate:        true,
+}
+
+// handleListStatFlags lists match performances the hub's anomaly
+// analysis found improbable, newest first. Open flags by default.
+//
+// path: GET /api/admin/stat-flags
+func (r *Router) handleListStatFlags(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	filter := storage.StatFlagFilter{Status: q.Get("status"), Kind: q.Get("kind"), Limit: parseLimit(req, 100, 500)}
+	switch filter.Status {
+	case "", "open", "reviewed", "all":
+	default:
+		writeError(w, http.StatusBadRequest, "status must be open, reviewed, or all")
+		return
+	}
+	if filter.Kind != "" && !statFlagKinds[filter.Kind] {
+		writeError(w, http.StatusBadRequest, "invalid kind")
+		return
+	}
+	if v := q.Get("player_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid player_id")
+			return
+		}
+		filter.PlayerID = id
+	}
+
+	flags, err := r.store.ListStatFlags(req.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, flags)
+}
+
+// handleReviewStatFlag records an admin's verdict on a flag: cleared
+// or confirmed, with an optional note. Reviewing again replaces it.
+//
+// path: POST /api/admin/stat-flags/{id}/review
+func (r *Router) handleReviewStatFlag(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid flag id")
+		return
+	}
+	claims := r.getAuthClaims(req)
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var body struct {
+		Verdict string `json:"verdict"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if body.Verdict != domain.StatFlagCleared && body.Verdict != domain.StatFlagConfirmed {
+		writeError(w, http.StatusBadRequest, "verdict must be cleared or confirmed")
+		return
+	}
+
+	flag, err := r.store.ReviewStatFlag(req.Context(), id, body.Verdict, strings.TrimSpace(body.Note), claims.UserID, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "flag not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, flag)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// statKeys maps each stat features.hidden_stats can name to the JSON
+// keys it appears under in leaderboard entries, player stats and
+// trends. Deaths take the K/D ratio with them, since frags and deaths
+// together give it away.
+var statKeys = map[string][]string{
+	"deaths":       {"total_deaths", "deaths", "kd_ratio", "cumulative_kd_ratio"},
+	"kd_ratio":     {"kd_ratio", "cumulative_kd_ratio"},
+	"captures":     {"captures"},
+	"flag_returns": {"flag_returns"},
+	"assists":      {"assists"},
+	"impressives":  {"impressives"},
+	"excellents":   {"excellents"},
+	"humiliations": {"humiliations"},
+	"defends":      {"defends"},
+	"victories":    {"victories", "longest_win_streak"},
+	"sprees":       {"best_spree"},
+	"accuracy":     {"accuracy", "overall_accuracy", "shots", "hits"},
+	"skulls":       {"skulls_scored"},
+	"obelisks":     {"obelisk_destroys"},
+	"playtime":     {"playtime_seconds"},
+}
+
+// SetHiddenStats applies features.hidden_stats: those leaderboard
+// categories are refused and their keys are dropped from leaderboard
+// and player stats responses. main.go passes the validated config.
+func (r *Router) SetHiddenStats(stats []string) {
+	if stats == nil {
+		stats = []string{}
+	}
+	r.hiddenStats = stats
+	r.hiddenCategories = make(map[string]bool)
+	r.hiddenStatKeys = make(map[string]bool)
+	for _, stat := range stats {
+		r.hiddenCategories[stat] = true
+		if stat == "deaths" {
+			r.hiddenCategories["kd_ratio"] = true
+		}
+		for _, key := range statKeys[stat] {
+			r.hiddenStatKeys[key] = true
+		}
+	}
+	r.buildGraphQLSchema()
+}
+
+// writeStatsJSON is writeJSON for leaderboards and player stats: the
+// hidden stats' keys are dropped from the response entirely, so they
+// can't be read off the API even though the SPA doesn't show them.
+func (r *Router) writeStatsJSON(w http.ResponseWriter, status int, v any) {
+	if len(r.hiddenStatKeys) == 0 {
+		writeJSON(w, status, v)
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	writeJSON(w, status, dropKeys(tree, r.hiddenStatKeys))
+}
+
+// dropKeys deletes keys from every object in v.
+func dropKeys(v any, keys map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if keys[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = dropKeys(child, keys)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = dropKeys(child, keys)
+		}
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHiddenStats(t *testing.T) {
+	tr := newTestRouter(t)
+	tr.r.SetHiddenStats([]string{"deaths", "sprees"})
+	pg, err := tr.store.UpsertPlayerGUID(context.Background(), "AAAA", "alice", "alice", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	for _, category := range []string{"deaths", "kd_ratio", "sprees"} {
+		if w := tr.do("GET", "/api/stats/leaderboard?category="+category, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("category %s: %d, want 400", category, w.Code)
+		}
+	}
+	if w := tr.do("GET", "/api/stats/leaderboard?category=captures", "", ""); w.Code != http.StatusOK {
+		t.Errorf("category captures: %d, want 200; body=%s", w.Code, w.Body)
+	}
+
+	w := tr.do("GET", "/api/players/"+strconv.FormatInt(pg.PlayerID, 10)+"/stats", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Stats map[string]any `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"deaths", "kd_ratio", "best_spree"} {
+		if _, ok := resp.Stats[key]; ok {
+			t.Errorf("stats still has %q", key)
+		}
+	}
+	for _, key := range []string{"frags", "captures"} {
+		if _, ok := resp.Stats[key]; !ok {
+			t.Errorf("stats lost %q", key)
+		}
+	}
+
+	w = tr.do("GET", "/api/config/features", "", "")
+	var features struct {
+		HiddenStats []string `json:"hidden_stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &features); err != nil {
+		t.Fatal(err)
+	}
+	if len(features.HiddenStats) != 2 {
+		t.Errorf("features hidden_stats = %v", features.HiddenStats)
+	}
+}
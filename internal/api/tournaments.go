@@ -0,0 +1,229 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// writeTournamentError maps tournament storage errors to responses.
+func writeTournamentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "tournament not found")
+	case errors.Is(err, storage.ErrNotSignedUp):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, storage.ErrTournamentClosed), errors.Is(err, storage.ErrTournamentNotStarted),
+		errors.Is(err, storage.ErrAlreadySignedUp), errors.Is(err, storage.ErrTournamentResultLocked):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrTooFewEntrants), errors.Is(err, storage.ErrInvalidTournamentResult):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// handleListTournaments lists tournaments, latest first, optionally
+// only those with ?status=.
+//
+// path: GET /api/tournaments
+func (r *Router) handleListTournaments(w http.ResponseWriter, req *http.Request) {
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "", domain.TournamentSignup, domain.TournamentInProgress, domain.TournamentCompleted:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	tournaments, err := r.store.ListTournaments(req.Context(), status)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, tournaments)
+}
+
+// handleGetTournament returns a tournament with its entrants, bracket
+// or schedule, and a round robin's standings.
+//
+// path: GET /api/tournaments/{id}
+func (r *Router) handleGetTournament(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	resp, err := r.store.GetTournamentDetails(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if resp == nil {
+		writeError(w, http.StatusNotFound, "tournament not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleTournamentSignup enters the caller's linked player.
+//
+// path: POST /api/tournaments/{id}/signup
+func (r *Router) handleTournamentSignup(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	if err := r.store.SignUpForTournament(req.Context(), id, playerID, time.Now().UTC()); err != nil {
+		writeTournamentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTournamentWithdraw takes the caller's linked player out before
+// the tournament starts.
+//
+// path: DELETE /api/tournaments/{id}/signup
+func (r *Router) handleTournamentWithdraw(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	_, playerID, ok := r.accountPlayer(w, req)
+	if !ok {
+		return
+	}
+	if err := r.store.WithdrawFromTournament(req.Context(), id, playerID); err != nil {
+		writeTournamentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateTournament schedules a tournament, open for sign-ups.
+// game_type defaults to 1v1 for the bracket formats.
+//
+// path: POST /api/admin/tournaments
+func (r *Router) handleCreateTournament(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Name     string    `json:"name"`
+		Format   string    `json:"format"`
+		GameType string    `json:"game_type"`
+		MapPool  []string  `json:"map_pool"`
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	t := &domain.Tournament{
+		Name:     strings.TrimSpace(body.Name),
+		Format:   body.Format,
+		GameType: body.GameType,
+		MapPool:  make([]string, len(body.MapPool)),
+		StartsAt: body.StartsAt.UTC(),
+		EndsAt:   body.EndsAt.UTC(),
+	}
+	for i, m := range body.MapPool {
+		t.MapPool[i] = strings.ToLower(strings.TrimSpace(m))
+	}
+	if t.GameType == "" && t.Format != domain.TournamentPickup {
+		t.GameType = domain.GameType1v1
+	}
+	if err := validateTournament(t); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if claims := r.getAuthClaims(req); claims != nil {
+		t.CreatedBy = &claims.UserID
+	}
+	if err := r.store.CreateTournament(req.Context(), t); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// handleDeleteTournament removes a tournament and its results.
+//
+// path: DELETE /api/admin/tournaments/{id}
+func (r *Router) handleDeleteTournament(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	if err := r.store.DeleteTournament(req.Context(), id); err != nil {
+		writeTournamentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStartTournament closes sign-ups and draws the bracket,
+// schedule, or pickup teams.
+//
+// path: POST /api/admin/tournaments/{id}/start
+func (r *Router) handleStartTournament(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	if err := r.store.StartTournament(req.Context(), id); err != nil {
+		writeTournamentError(w, err)
+		return
+	}
+	r.handleGetTournament(w, req)
+}
+
+// handleSetTournamentResult records or corrects a pairing's result by
+// hand: {"winner_id"} for a bracket pairing or {"winning_team"} for a
+// pickup, with an optional "match_id" for the game that settled it.
+//
+// path: PUT /api/admin/tournaments/{id}/matches/{pairing_id}
+func (r *Router) handleSetTournamentResult(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tournament id")
+		return
+	}
+	pairingID, err := parseID(req, "pairing_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid pairing id")
+		return
+	}
+	var body struct {
+		WinnerID    *int64 `json:"winner_id"`
+		WinningTeam *int   `json:"winning_team"`
+		MatchID     *int64 `json:"match_id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if body.MatchID != nil && !r.matchExists(w, req, *body.MatchID) {
+		return
+	}
+	if err := r.store.SetTournamentResult(req.Context(), id, pairingID, body.WinnerID, body.WinningTeam, body.MatchID); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tournament or pairing not found")
+		return
+	} else if err != nil {
+		writeTournamentError(w, err)
+		return
+	}
+	r.handleGetTournament(w, req)
+}
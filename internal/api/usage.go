@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/auth"
+	"github.com/ernie/trinity-tracker/internal/tracing"
+)
+
+// API usage is kept in memory as 24 hourly buckets, so /api/admin/usage
+// always covers roughly the last day and resets on restart. That's
+// enough to see which tools lean on which endpoints before changing
+// them without adding write load to the database on every request.
+const (
+	usageBucketWidth = time.Hour
+	usageBucketCount = 24
+
+	// usageConsumerCap bounds the distinct IPs and user agents kept per
+	// bucket; past it, new ones are folded into usageOtherConsumer.
+	usageConsumerCap   = 10000
+	usageOtherConsumer = "(other)"
+)
+
+type endpointUsage struct {
+	requests     int
+	clientErrors int
+	serverErrors int
+}
+
+// usageAPIKey identifies the API key a request was made with by its id
+// and the prefix shown on the account page, never the key itself.
+type usageAPIKey struct {
+	id     int64
+	prefix string
+}
+
+// usageAPIKeyCtxKey holds a *usageAPIKey in an /api request's context
+// for apiKeyClaims to fill in, since the claims are looked up inside
+// the handler and don't come back out to serveTracked.
+type usageAPIKeyCtxKey struct{}
+
+// noteUsageAPIKey records that req was made with key, for the usage
+// report.
+func noteUsageAPIKey(ctx context.Context, key usageAPIKey) {
+	if k, ok := ctx.Value(usageAPIKeyCtxKey{}).(*usageAPIKey); ok {
+		*k = key
+	}
+}
+
+// requestAPIKey looks up the API key req was made with, for public
+// routes that served it without checking. Unknown and revoked keys
+// count as no key.
+func (r *Router) requestAPIKey(req *http.Request) usageAPIKey {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, auth.APIKeyPrefix) {
+		return usageAPIKey{}
+	}
+	k, _, err := r.store.GetAPIKeyUser(req.Context(), auth.HashAPIKey(token))
+	if err != nil {
+		return usageAPIKey{}
+	}
+	return usageAPIKey{id: k.ID, prefix: k.Prefix}
+}
+
+type usageBucket struct {
+	start     time.Time // zero when unused
+	endpoints map[string]*endpointUsage
+	ips       map[string]int
+	agents    map[string]int
+	apiKeys   map[usageAPIKey]int
+}
+
+// usageTracker counts /api requests per route pattern, client IP,
+// User-Agent, and API key over a rolling window.
+type usageTracker struct {
+	mu      sync.Mutex
+	buckets [usageBucketCount]usageBucket
+	now     func() time.Time
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{now: time.Now}
+}
+
+// record counts one finished request. endpoint is the mux pattern
+// ("GET /api/players/{id}") so IDs don't fan out into separate rows.
+// key is zero for requests made without an API key.
+func (t *usageTracker) record(endpoint, ip, agent string, key usageAPIKey, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := t.now().Truncate(usageBucketWidth)
+	b := &t.buckets[start.Unix()/int64(usageBucketWidth/time.Second)%usageBucketCount]
+	if !b.start.Equal(start) {
+		*b = usageBucket{
+			start:     start,
+			endpoints: make(map[string]*endpointUsage),
+			ips:       make(map[string]int),
+			agents:    make(map[string]int),
+			apiKeys:   make(map[usageAPIKey]int),
+		}
+	}
+
+	e, ok := b.endpoints[endpoint]
+	if !ok {
+		e = &endpointUsage{}
+		b.endpoints[endpoint] = e
+	}
+	e.requests++
+	switch {
+	case status >= 500:
+		e.serverErrors++
+	case status >= 400:
+		e.clientErrors++
+	}
+	countConsumer(b.ips, ip)
+	countConsumer(b.agents, agent)
+	if key.id != 0 {
+		b.apiKeys[key]++
+	}
+}
+
+func countConsumer(m map[string]int, key string) {
+	if _, ok := m[key]; !ok && len(m) >= usageConsumerCap {
+		key = usageOtherConsumer
+	}
+	m[key]++
+}
+
+// UsageEndpoint is one route's traffic in the usage window
+type UsageEndpoint struct {
+	Endpoint     string  `json:"endpoint"`
+	Requests     int     `json:"requests"`
+	ClientErrors int     `json:"client_errors"`
+	ServerErrors int     `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"` // (client + server errors) / requests
+}
+
+// UsageConsumer is one client IP or User-Agent and its request count
+type UsageConsumer struct {
+	Consumer string `json:"consumer"`
+	Requests int    `json:"requests"`
+}
+
+// UsageAPIKey is one API key and its request count
+type UsageAPIKey struct {
+	ID       int64  `json:"id"`
+	Prefix   string `json:"prefix"`
+	Requests int    `json:"requests"`
+}
+
+// UsageReport is the response for GET /api/admin/usage
+type UsageReport struct {
+	WindowStart   time.Time       `json:"window_start"`
+	WindowEnd     time.Time       `json:"window_end"`
+	TotalRequests int             `json:"total_requests"`
+	Endpoints     []UsageEndpoint `json:"endpoints"`
+	TopIPs        []UsageConsumer `json:"top_ips"`
+	TopAgents     []UsageConsumer `json:"top_user_agents"`
+	TopAPIKeys    []UsageAPIKey   `json:"top_api_keys"`
+}
+
+// report merges the live buckets. Endpoints are busiest first; the
+// consumer lists are cut to limit.
+func (t *usageTracker) report(limit int) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	oldest := now.Truncate(usageBucketWidth).Add(-(usageBucketCount - 1) * usageBucketWidth)
+	rep := UsageReport{WindowStart: oldest, WindowEnd: now}
+
+	endpoints := make(map[string]*endpointUsage)
+	ips := make(map[string]int)
+	agents := make(map[string]int)
+	apiKeys := make(map[usageAPIKey]int)
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		if b.start.IsZero() || b.start.Before(oldest) {
+			continue
+		}
+		for name, e := range b.endpoints {
+			sum, ok := endpoints[name]
+			if !ok {
+				sum = &endpointUsage{}
+				endpoints[name] = sum
+			}
+			sum.requests += e.requests
+			sum.clientErrors += e.clientErrors
+			sum.serverErrors += e.serverErrors
+			rep.TotalRequests += e.requests
+		}
+		for k, n := range b.ips {
+			ips[k] += n
+		}
+		for k, n := range b.agents {
+			agents[k] += n
+		}
+		for k, n := range b.apiKeys {
+			apiKeys[k] += n
+		}
+	}
+
+	rep.Endpoints = make([]UsageEndpoint, 0, len(endpoints))
+	for name, e := range endpoints {
+		rep.Endpoints = append(rep.Endpoints, UsageEndpoint{
+			Endpoint:     name,
+			Requests:     e.requests,
+			ClientErrors: e.clientErrors,
+			ServerErrors: e.serverErrors,
+			ErrorRate:    float64(e.clientErrors+e.serverErrors) / float64(e.requests),
+		})
+	}
+	sort.Slice(rep.Endpoints, func(i, j int) bool {
+		a, b := rep.Endpoints[i], rep.Endpoints[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	rep.TopIPs = topConsumers(ips, limit)
+	rep.TopAgents = topConsumers(agents, limit)
+	rep.TopAPIKeys = topAPIKeys(apiKeys, limit)
+	return rep
+}
+
+func topConsumers(m map[string]int, limit int) []UsageConsumer {
+	out := make([]UsageConsumer, 0, len(m))
+	for k, n := range m {
+		out = append(out, UsageConsumer{Consumer: k, Requests: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Consumer < out[j].Consumer
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func topAPIKeys(m map[usageAPIKey]int, limit int) []UsageAPIKey {
+	out := make([]UsageAPIKey, 0, len(m))
+	for k, n := range m {
+		out = append(out, UsageAPIKey{ID: k.id, Prefix: k.prefix, Requests: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// statusRecorder captures the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// serveTracked runs the mux for an /api request and records it once
+// the handler returns. The mux fills in req.Pattern while routing;
+// unrouted requests are counted under the method alone. It's also
+// where an /api request's trace span starts and ends.
+func (r *Router) serveTracked(w http.ResponseWriter, req *http.Request) {
+	req, span := tracing.StartRequest(req)
+	var key usageAPIKey
+	req = req.WithContext(context.WithValue(req.Context(), usageAPIKeyCtxKey{}, &key))
+	rec := &statusRecorder{ResponseWriter: w}
+	r.mux.ServeHTTP(rec, req)
+
+	endpoint := req.Pattern
+	if endpoint == "" || endpoint == "GET /" {
+		endpoint = req.Method + " (unmatched)"
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	tracing.EndRequest(span, endpoint, status)
+	if key.id == 0 {
+		key = r.requestAPIKey(req)
+	}
+	r.usage.record(endpoint, getClientIP(req), req.UserAgent(), key, status)
+}
+
+// handleGetUsage reports API traffic over the rolling window.
+//
+// path: GET /api/admin/usage
+func (r *Router) handleGetUsage(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, r.usage.report(parseLimit(req, 10, 100)))
+}
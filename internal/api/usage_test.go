@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageTrackerRollsOffOldBuckets(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	tr := newUsageTracker()
+	tr.now = func() time.Time { return now }
+
+	tr.record("GET /api/players", "10.0.0.1", "stats-bot/1.0", usageAPIKey{}, http.StatusOK)
+	tr.record("GET /api/players", "10.0.0.1", "stats-bot/1.0", usageAPIKey{}, http.StatusInternalServerError)
+	tr.record("GET /api/matches", "10.0.0.2", "curl/8", usageAPIKey{id: 7, prefix: "trk_1a2b3c"}, http.StatusNotFound)
+
+	rep := tr.report(10)
+	if rep.TotalRequests != 3 || len(rep.Endpoints) != 2 {
+		t.Fatalf("report: got %+v", rep)
+	}
+	top := rep.Endpoints[0]
+	if top.Endpoint != "GET /api/players" || top.Requests != 2 || top.ServerErrors != 1 || top.ErrorRate != 0.5 {
+		t.Errorf("top endpoint: got %+v", top)
+	}
+	if len(rep.TopIPs) != 2 || rep.TopIPs[0].Consumer != "10.0.0.1" || rep.TopIPs[0].Requests != 2 {
+		t.Errorf("top ips: got %+v", rep.TopIPs)
+	}
+	if len(rep.TopAPIKeys) != 1 || rep.TopAPIKeys[0] != (UsageAPIKey{ID: 7, Prefix: "trk_1a2b3c", Requests: 1}) {
+		t.Errorf("top api keys: got %+v", rep.TopAPIKeys)
+	}
+
+	// A day later the old hour has rolled out of the window.
+	now = now.Add(24 * time.Hour)
+	tr.record("GET /api/matches", "10.0.0.3", "curl/8", usageAPIKey{}, http.StatusOK)
+	rep = tr.report(10)
+	if rep.TotalRequests != 1 || len(rep.TopIPs) != 1 || rep.TopIPs[0].Consumer != "10.0.0.3" {
+		t.Errorf("after a day: got %+v", rep)
+	}
+}
+
+func TestHandleGetUsageCountsByPattern(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, _ := tr.loginAs(t, "alice", false)
+
+	tr.do("GET", "/api/players/1/matches", "", "")
+	tr.do("GET", "/api/players/2/matches", "", "")
+	tr.do("GET", "/api/players/nope/matches", "", "")
+
+	if w := tr.do("GET", "/api/admin/usage", "", userTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: code = %d, want 403", w.Code)
+	}
+	w := tr.do("GET", "/api/admin/usage", "", adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body)
+	}
+	var rep UsageReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var got *UsageEndpoint
+	for i := range rep.Endpoints {
+		if rep.Endpoints[i].Endpoint == "GET /api/players/{id}/matches" {
+			got = &rep.Endpoints[i]
+		}
+	}
+	if got == nil || got.Requests != 3 || got.ClientErrors != 1 {
+		t.Fatalf("player matches endpoint: got %+v in %+v", got, rep.Endpoints)
+	}
+}
+
+func TestHandleGetUsageCountsByAPIKey(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "admin", true)
+	userTok, _ := tr.loginAs(t, "alice", false)
+	key := createAPIKey(t, tr, userTok, `{"name":"stats bot"}`)
+
+	tr.do("GET", "/api/players/1/matches", "", key.Key)
+	tr.do("GET", "/api/players/2/matches", "", key.Key)
+	if w := tr.do("GET", "/api/account/profile", "", key.Key); w.Code != http.StatusOK {
+		t.Fatalf("profile with key: %d %s", w.Code, w.Body)
+	}
+	tr.do("GET", "/api/players/1/matches", "", userTok)
+
+	w := tr.do("GET", "/api/admin/usage", "", adminTok)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), key.Key) {
+		t.Error("report contains the key itself")
+	}
+	var rep UsageReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := UsageAPIKey{ID: key.ID, Prefix: key.Prefix, Requests: 3}
+	if len(rep.TopAPIKeys) != 1 || rep.TopAPIKeys[0] != want {
+		t.Errorf("top api keys: got %+v, want [%+v]", rep.TopAPIKeys, want)
+	}
+}
@@ -1,8 +1,18 @@
 package api
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
 )
 
 var validPeriods = map[string]bool{
@@ -23,7 +33,8 @@ var validCategories = map[string]bool{
 	"frags": true, "deaths": true, "kd_ratio": true, "matches": true,
 	"captures": true, "flag_returns": true, "assists": true,
 	"impressives": true, "excellents": true, "humiliations": true,
-	"defends": true, "victories": true,
+	"defends": true, "victories": true, "sprees": true, "accuracy": true,
+	"skulls": true, "obelisks": true, "playtime": true,
 }
 
 // parseLimit parses and validates a limit parameter with default and max values
@@ -36,6 +47,22 @@ func parseLimit(r *http.Request, defaultLimit, maxLimit int) int {
 	return defaultLimit
 }
 
+// parseAsOf parses the optional as_of query parameter (RFC 3339),
+// which pins a period's upper bound for reproducible snapshot links.
+// Returns the zero time when absent. Parse-only validation — nonsense
+// values just return empty/weird results.
+func parseAsOf(r *http.Request) (time.Time, error) {
+	s := r.URL.Query().Get("as_of")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of: %w", err)
+	}
+	return t, nil
+}
+
 // parseOffset parses and validates an offset parameter
 func parseOffset(r *http.Request) int {
 	if o := r.URL.Query().Get("offset"); o != "" {
@@ -71,5 +98,145 @@ func validateCategory(category string) bool {
 	return validCategories[category]
 }
 
+// validateWeapon accepts any domain.Weapons slug.
+func validateWeapon(weapon string) bool {
+	return slices.Contains(domain.Weapons, weapon)
+}
+
 func validateMovementMode(m string) bool { return validMovementModes[m] }
 func validateGameplayMode(g string) bool { return validGameplayModes[g] }
+
+// validateVetoSteps checks a pick/ban sequence: at least one step,
+// known actions, a team side on every ban/pick and none on a decider,
+// and no map named twice.
+func validateVetoSteps(steps []domain.MapVetoStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+	seen := make(map[string]bool, len(steps))
+	for i, st := range steps {
+		n := i + 1
+		switch st.Action {
+		case domain.VetoBan, domain.VetoPick:
+			if st.Side != "a" && st.Side != "b" {
+				return fmt.Errorf("step %d: side must be \"a\" or \"b\"", n)
+			}
+		case domain.VetoDecider:
+			if st.Side != "" {
+				return fmt.Errorf("step %d: a decider has no side", n)
+			}
+		default:
+			return fmt.Errorf("step %d: action must be ban, pick, or decider", n)
+		}
+		m := strings.ToLower(strings.TrimSpace(st.MapName))
+		if m == "" {
+			return fmt.Errorf("step %d: map_name is required", n)
+		}
+		if seen[m] {
+			return fmt.Errorf("step %d: %s already appears in the veto", n, m)
+		}
+		seen[m] = true
+	}
+	return nil
+}
+
+var validTournamentFormats = map[string]bool{
+	domain.TournamentSingleElimination: true,
+	domain.TournamentRoundRobin:        true,
+	domain.TournamentPickup:            true,
+}
+
+// validateTournament checks a new tournament. Pickups draft two teams,
+// so they need a team gametype.
+func validateTournament(t *domain.Tournament) error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !validTournamentFormats[t.Format] {
+		return fmt.Errorf("format must be single_elimination, round_robin, or pickup")
+	}
+	if !validateGameType(t.GameType) {
+		return fmt.Errorf("invalid game_type")
+	}
+	if t.Format == domain.TournamentPickup && (t.GameType == domain.GameTypeFFA || t.GameType == domain.GameType1v1) {
+		return fmt.Errorf("a pickup needs a team game_type")
+	}
+	if t.StartsAt.IsZero() || !t.EndsAt.After(t.StartsAt) {
+		return fmt.Errorf("starts_at is required and ends_at must be after it")
+	}
+	for i, m := range t.MapPool {
+		if m == "" {
+			return fmt.Errorf("map_pool entry %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// validateSeason checks an admin-defined season. category must be a
+// leaderboard category.
+func validateSeason(se *domain.Season) error {
+	if se.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !validateCategory(se.Category) {
+		return fmt.Errorf("invalid category")
+	}
+	if se.StartsAt.IsZero() || !se.EndsAt.After(se.StartsAt) {
+		return fmt.Errorf("starts_at is required and ends_at must be after it")
+	}
+	return nil
+}
+
+// Profile limits.
+const (
+	maxAvatarURLLen = 500
+	maxBioLen       = 280
+)
+
+// modelPattern is a Q3 model string as the portraits are named:
+// "sarge", "sarge/krusade", or "*james" for a Team Arena head.
+var modelPattern = regexp.MustCompile(`^\*?[a-z0-9_-]+(/[a-z0-9_-]+)?$`)
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// validateProfile checks a profile after its fields have been trimmed,
+// and normalizes the model and country code.
+func validateProfile(p *domain.Profile) error {
+	if p.AvatarURL != "" {
+		u, err := url.Parse(p.AvatarURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" || len(p.AvatarURL) > maxAvatarURLLen {
+			return fmt.Errorf("avatar_url must be an https URL of at most %d characters", maxAvatarURLLen)
+		}
+	}
+	p.PreferredModel = strings.ToLower(p.PreferredModel)
+	if p.PreferredModel != "" && !modelPattern.MatchString(p.PreferredModel) {
+		return fmt.Errorf("invalid preferred_model")
+	}
+	if utf8.RuneCountInString(p.Bio) > maxBioLen {
+		return fmt.Errorf("bio is longer than %d characters", maxBioLen)
+	}
+	p.CountryCode = strings.ToUpper(p.CountryCode)
+	if p.CountryCode != "" && !countryCodePattern.MatchString(p.CountryCode) {
+		return fmt.Errorf("country_code must be a two-letter ISO 3166 code")
+	}
+	return nil
+}
+
+// serverKeyPattern matches q3_servers[].key in config.
+var serverKeyPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// externalServerKey validates the key for a server added from the
+// browser, or makes one from its address when key is empty.
+func externalServerKey(key, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" || port == "" {
+		return "", fmt.Errorf("address must be host:port")
+	}
+	if key == "" {
+		key = strings.NewReplacer(".", "-", ":", "-").Replace(strings.ToLower(host)) + "-" + port
+	}
+	if !serverKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("key must be 1-64 lowercase letters, digits, _ or -")
+	}
+	return key, nil
+}
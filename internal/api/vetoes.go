@@ -0,0 +1,165 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleListMapVetoes lists recorded vetoes, newest first.
+//
+// path: GET /api/admin/vetoes
+func (r *Router) handleListMapVetoes(w http.ResponseWriter, req *http.Request) {
+	limit := parseLimit(req, 50, 200)
+	vetoes, err := r.store.ListMapVetoes(req.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, vetoes)
+}
+
+// handleCreateMapVeto records a pick/ban sequence. match_id is
+// optional; vetoes are usually entered before the match exists and
+// linked afterwards.
+//
+// path: POST /api/admin/vetoes
+func (r *Router) handleCreateMapVeto(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Title   string               `json:"title"`
+		TeamA   string               `json:"team_a"`
+		TeamB   string               `json:"team_b"`
+		MatchID *int64               `json:"match_id"`
+		Steps   []domain.MapVetoStep `json:"steps"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	body.Title = strings.TrimSpace(body.Title)
+	body.TeamA = strings.TrimSpace(body.TeamA)
+	body.TeamB = strings.TrimSpace(body.TeamB)
+	if body.Title == "" || body.TeamA == "" || body.TeamB == "" {
+		writeError(w, http.StatusBadRequest, "title, team_a, and team_b are required")
+		return
+	}
+	for i := range body.Steps {
+		body.Steps[i].MapName = strings.TrimSpace(body.Steps[i].MapName)
+	}
+	if err := validateVetoSteps(body.Steps); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.MatchID != nil && !r.matchExists(w, req, *body.MatchID) {
+		return
+	}
+
+	v := &domain.MapVeto{
+		Title:   body.Title,
+		TeamA:   body.TeamA,
+		TeamB:   body.TeamB,
+		MatchID: body.MatchID,
+		Steps:   body.Steps,
+	}
+	if claims := r.getAuthClaims(req); claims != nil {
+		v.CreatedBy = &claims.UserID
+	}
+	if err := r.store.CreateMapVeto(req.Context(), v); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+// handleSetMapVetoMatch links a veto to its match, or unlinks it with
+// {"match_id": null}.
+//
+// path: PUT /api/admin/vetoes/{id}/match
+func (r *Router) handleSetMapVetoMatch(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid veto id")
+		return
+	}
+	var body struct {
+		MatchID *int64 `json:"match_id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if body.MatchID != nil && !r.matchExists(w, req, *body.MatchID) {
+		return
+	}
+	if err := r.store.SetMapVetoMatch(req.Context(), id, body.MatchID); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "veto not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	v, err := r.store.GetMapVeto(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// handleDeleteMapVeto removes a veto.
+//
+// path: DELETE /api/admin/vetoes/{id}
+func (r *Router) handleDeleteMapVeto(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid veto id")
+		return
+	}
+	if err := r.store.DeleteMapVeto(req.Context(), id); errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "veto not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetMatchVeto returns the veto linked to a match. Public: the
+// match page shows it to everyone.
+//
+// path: GET /api/matches/{id}/veto
+func (r *Router) handleGetMatchVeto(w http.ResponseWriter, req *http.Request) {
+	id, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid match id")
+		return
+	}
+	v, err := r.store.GetMapVetoForMatch(req.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if v == nil {
+		writeError(w, http.StatusNotFound, "no veto recorded for this match")
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// matchExists writes a 400 and returns false when matchID doesn't
+// refer to a match.
+func (r *Router) matchExists(w http.ResponseWriter, req *http.Request, matchID int64) bool {
+	if _, err := r.store.GetMatchSummaryByID(req.Context(), matchID); err == sql.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "match not found")
+		return false
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestMapVetoRecordAndLink(t *testing.T) {
+	tr := newTestRouter(t)
+	adminTok, _ := tr.loginAs(t, "ref", true)
+	aliceTok, _ := tr.loginAs(t, "alice", false)
+	ctx := context.Background()
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := tr.store.UpsertServer(ctx, "local", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "m-1", ServerID: srv.ID, MapName: "q3ctf4", GameType: "ctf", StartedAt: time.Now()}
+	if err := tr.store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	body := `{"title":"Final","team_a":"Alpha","team_b":"Bravo","steps":[
+		{"side":"a","action":"ban","map_name":"q3ctf1"},
+		{"side":"b","action":"ban","map_name":"q3ctf2"},
+		{"side":"","action":"decider","map_name":"q3ctf4"}]}`
+
+	if w := tr.do("POST", "/api/admin/vetoes", body, aliceTok); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin create: code = %d, want 403", w.Code)
+	}
+	dup := `{"title":"Final","team_a":"A","team_b":"B","steps":[
+		{"side":"a","action":"ban","map_name":"q3ctf1"},
+		{"side":"b","action":"pick","map_name":"Q3CTF1"}]}`
+	if w := tr.do("POST", "/api/admin/vetoes", dup, adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("duplicate map: code = %d, want 400", w.Code)
+	}
+
+	w := tr.do("POST", "/api/admin/vetoes", body, adminTok)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var created domain.MapVeto
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	matchVeto := fmt.Sprintf("/api/matches/%d/veto", m.ID)
+	if w := tr.do("GET", matchVeto, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("before link: code = %d, want 404", w.Code)
+	}
+	link := fmt.Sprintf("/api/admin/vetoes/%d/match", created.ID)
+	if w := tr.do("PUT", link, fmt.Sprintf(`{"match_id":%d}`, m.ID+100), adminTok); w.Code != http.StatusBadRequest {
+		t.Errorf("link to missing match: code = %d, want 400", w.Code)
+	}
+	if w := tr.do("PUT", link, fmt.Sprintf(`{"match_id":%d}`, m.ID), adminTok); w.Code != http.StatusOK {
+		t.Fatalf("link: %d %s", w.Code, w.Body)
+	}
+
+	w = tr.do("GET", matchVeto, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("match veto: %d %s", w.Code, w.Body)
+	}
+	var got domain.MapVeto
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TeamA != "Alpha" || len(got.Steps) != 3 || got.Steps[2].MapName != "q3ctf4" {
+		t.Errorf("match veto: got %+v", got)
+	}
+}
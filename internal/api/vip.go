@@ -0,0 +1,90 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// handleListVIPs lists the players flagged as VIPs.
+//
+// path: GET /api/admin/vips
+func (r *Router) handleListVIPs(w http.ResponseWriter, req *http.Request) {
+	vips, err := r.store.ListVIPs(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, vips)
+}
+
+// handleSetPlayerVIP flags or clears a linked player as a VIP. Servers
+// with reserved slots make room for VIPs when they join.
+//
+// path: PUT /api/admin/players/{id}/vip
+func (r *Router) handleSetPlayerVIP(w http.ResponseWriter, req *http.Request) {
+	playerID, err := parseID(req, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid player id")
+		return
+	}
+	var body struct {
+		VIP bool `json:"vip"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err = r.store.SetPlayerVIP(req.Context(), playerID, body.VIP)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "player not found")
+		return
+	case errors.Is(err, storage.ErrPlayerNotLinked):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	actor := "unknown"
+	if claims := r.getAuthClaims(req); claims != nil {
+		actor = claims.Username
+	}
+	log.Printf("audit: set_vip player_id=%d vip=%t actor=%s remote=%s", playerID, body.VIP, actor, req.RemoteAddr)
+
+	player, err := r.store.GetPlayerByID(req.Context(), playerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, player)
+}
+
+// handleListVIPKicks lists players kicked to free reserved slots,
+// newest first. Optional server_id narrows the list.
+//
+// path: GET /api/admin/vip-kicks
+func (r *Router) handleListVIPKicks(w http.ResponseWriter, req *http.Request) {
+	var serverID int64
+	if v := req.URL.Query().Get("server_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid server_id")
+			return
+		}
+		serverID = id
+	}
+	kicks, err := r.store.ListVIPKicks(req.Context(), serverID, parseLimit(req, 100, 500))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, kicks)
+}
@@ -5,11 +5,13 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/metrics"
 	"github.com/gorilla/websocket"
 )
 
@@ -42,24 +44,134 @@ type WebSocketClient struct {
 	conn       *websocket.Conn
 	send       chan []byte
 	remoteAddr string
+	// matchServer is non-zero for /ws/match/{id} spectators: they get
+	// only that server's events, including the fast-poll updates
+	// regular clients never see.
+	matchServer int64
+	// subs are the client's subscriptions, guarded by hub.mu. Until it
+	// sends one, subs is nil and the client gets every event.
+	subs []wsSubscription
+	// slow is set when the hub drops the client for falling behind.
+	slow bool
+	// onClose runs once when the connection goes away.
+	onClose func()
+}
+
+// Clients narrow what they're sent by subscribing:
+//
+//	{"subscribe": {"server_id": 3, "types": ["frag", "match_end"]}}
+//
+// A client that has subscribed gets the events matching any of its
+// subscriptions, and nothing else. Leaving out server_id matches every
+// server, and leaving out types every event type. Sending the same
+// subscription as "unsubscribe" removes it. Malformed messages are
+// ignored.
+const (
+	// wsMaxSubscriptions caps the subscriptions one client can hold.
+	wsMaxSubscriptions = 32
+	// wsReadLimit is the largest message a client may send.
+	wsReadLimit = 4096
+	// wsSendQueue is how many messages a client can fall behind by
+	// before the hub drops it.
+	wsSendQueue = 256
+)
+
+// wsSubscription selects events by server and type.
+type wsSubscription struct {
+	ServerID int64    `json:"server_id,omitempty"`
+	Types    []string `json:"types,omitempty"`
+}
+
+// wsClientMessage is a message from a client.
+type wsClientMessage struct {
+	Subscribe   *wsSubscription `json:"subscribe,omitempty"`
+	Unsubscribe *wsSubscription `json:"unsubscribe,omitempty"`
+}
+
+func (s wsSubscription) matches(msg wsMessage) bool {
+	if s.ServerID != 0 && s.ServerID != msg.serverID {
+		return false
+	}
+	return len(s.Types) == 0 || slices.Contains(s.Types, msg.event.Type)
+}
+
+func (s wsSubscription) equal(o wsSubscription) bool {
+	return s.ServerID == o.ServerID && slices.Equal(s.Types, o.Types)
+}
+
+// wsMessage is one event queued for fan-out. matchOnly messages go to
+// spectators of serverID and nobody else.
+type wsMessage struct {
+	serverID  int64
+	event     domain.Event
+	matchOnly bool
+}
+
+// wants reports whether c should receive msg. The caller holds hub.mu.
+func (c *WebSocketClient) wants(msg wsMessage) bool {
+	if c.matchServer != 0 {
+		if msg.serverID != c.matchServer {
+			return false
+		}
+	} else if msg.matchOnly {
+		return false
+	}
+	if c.subs == nil {
+		return true
+	}
+	for _, sub := range c.subs {
+		if sub.matches(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleMessage applies a message from the client.
+func (c *WebSocketClient) handleMessage(data []byte) {
+	var msg wsClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	if sub := msg.Subscribe; sub != nil && len(c.subs) < wsMaxSubscriptions {
+		if c.subs == nil {
+			c.subs = []wsSubscription{}
+		}
+		if !slices.ContainsFunc(c.subs, sub.equal) {
+			c.subs = append(c.subs, *sub)
+		}
+	}
+	if sub := msg.Unsubscribe; sub != nil {
+		c.subs = slices.DeleteFunc(c.subs, sub.equal)
+	}
 }
 
 // WebSocketHub manages WebSocket connections
 type WebSocketHub struct {
 	clients    map[*WebSocketClient]bool
-	broadcast  chan []byte
+	broadcast  chan wsMessage
 	register   chan *WebSocketClient
 	unregister chan *WebSocketClient
 	mu         sync.RWMutex
+
+	// Broadcast events are numbered as they go out so long-poll
+	// clients can pick up where they left off; see events_poll.go.
+	pollMu  sync.Mutex
+	seq     uint64
+	backlog []sequencedEvent
+	wake    chan struct{}
 }
 
 // NewWebSocketHub creates a new WebSocket hub
-func NewWebSocketHub() *WebSocketHub {
+func NewWebSocketHub(buffer int) *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*WebSocketClient]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan wsMessage, buffer),
 		register:   make(chan *WebSocketClient),
 		unregister: make(chan *WebSocketClient),
+		wake:       make(chan struct{}),
 	}
 }
 
@@ -83,33 +195,48 @@ func (h *WebSocketHub) Run() {
 			log.Printf("WebSocket client disconnected from %s (%d total)", client.remoteAddr, len(h.clients))
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			data, err := h.encode(message)
+			if err != nil {
+				log.Printf("Error marshaling event: %v", err)
+				continue
+			}
+			h.mu.Lock()
 			for client := range h.clients {
+				if !client.wants(message) {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- data:
 				default:
-					// Client's buffer is full, close connection
+					// The client isn't reading fast enough. Dropping it
+					// keeps everyone else's events flowing; it can
+					// reconnect and refetch.
+					client.slow = true
 					close(client.send)
 					delete(h.clients, client)
+					metrics.SlowWebSocketClients.Add(1)
+					log.Printf("WebSocket client %s fell behind, disconnecting", client.remoteAddr)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
 // Broadcast sends an event to all connected clients
 func (h *WebSocketHub) Broadcast(event domain.Event) {
-	data, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("Error marshaling event: %v", err)
-		return
-	}
+	h.enqueue(event, false)
+}
+
+// BroadcastMatch sends an event only to spectators of its server.
+func (h *WebSocketHub) BroadcastMatch(event domain.Event) {
+	h.enqueue(event, true)
+}
 
-	select {
-	case h.broadcast <- data:
-	default:
-		log.Printf("Broadcast channel full, dropping event")
+func (h *WebSocketHub) enqueue(event domain.Event, matchOnly bool) {
+	msg := wsMessage{serverID: event.ServerID, event: event, matchOnly: matchOnly}
+	if !metrics.Send(h.broadcast, msg, metrics.ChannelBroadcast, event.Type, domain.IsHighValueEvent(event.Type)) {
+		log.Printf("Broadcast channel full, dropping %s event", event.Type)
 	}
 }
 
@@ -131,7 +258,7 @@ func (r *Router) handleWebSocket(w http.ResponseWriter, req *http.Request) {
 	client := &WebSocketClient{
 		hub:        r.wsHub,
 		conn:       conn,
-		send:       make(chan []byte, 256),
+		send:       make(chan []byte, wsSendQueue),
 		remoteAddr: getClientIP(req),
 	}
 
@@ -147,9 +274,12 @@ func (c *WebSocketClient) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(wsReadLimit)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -157,14 +287,14 @@ func (c *WebSocketClient) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// We don't process incoming messages for now
+		c.handleMessage(data)
 	}
 }
 
@@ -181,7 +311,11 @@ func (c *WebSocketClient) writePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				var reason []byte
+				if c.slow {
+					reason = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, reason)
 				return
 			}
 
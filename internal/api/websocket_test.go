@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/metrics"
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	tr := newTestRouter(t)
+	go tr.r.wsHub.Run()
+
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	for _, msg := range []string{
+		`{"subscribe": {"server_id": 3, "types": ["frag", "match_end"]}}`,
+		`{"subscribe": {"server_id": 4}}`,
+		`{"unsubscribe": {"server_id": 4}}`,
+		`not json`,
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// Give the client's reader a moment to apply them.
+	time.Sleep(50 * time.Millisecond)
+
+	for _, e := range []domain.Event{
+		{Type: domain.EventFrag, ServerID: 4},
+		{Type: domain.EventPlayerJoin, ServerID: 3},
+		{Type: domain.EventFrag, ServerID: 3},
+	} {
+		tr.r.wsHub.Broadcast(e)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d events, want 1: %s", len(lines), data)
+	}
+	var got domain.Event
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Type != domain.EventFrag || got.ServerID != 3 {
+		t.Errorf("got %s for server %d, want frag for server 3", got.Type, got.ServerID)
+	}
+}
+
+func TestWebSocketHubDropsSlowClient(t *testing.T) {
+	h := NewWebSocketHub(16)
+	go h.Run()
+
+	slow := &WebSocketClient{hub: h, send: make(chan []byte, 1), remoteAddr: "slow"}
+	fast := &WebSocketClient{hub: h, send: make(chan []byte, 8), remoteAddr: "fast"}
+	h.register <- slow
+	h.register <- fast
+	before := metrics.SlowWebSocketClients.Load()
+
+	for i := 0; i < 3; i++ {
+		h.Broadcast(domain.Event{Type: domain.EventFrag, ServerID: 1})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-fast.send:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("fast client got %d of 3 events", i)
+		}
+	}
+
+	if n := h.ClientCount(); n != 1 {
+		t.Errorf("%d clients connected, want 1", n)
+	}
+	<-slow.send
+	if _, ok := <-slow.send; ok {
+		t.Error("slow client's queue is still open")
+	}
+	if got := metrics.SlowWebSocketClients.Load() - before; got != 1 {
+		t.Errorf("slow client count went up by %d, want 1", got)
+	}
+}
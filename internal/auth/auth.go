@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -8,6 +11,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Tokens carry their times to the millisecond, not jwt's default
+// second, so a token issued just after a force-logout can be told
+// from one issued just before it.
+func init() {
+	jwt.TimePrecision = time.Millisecond
+}
+
 var (
 	ErrInvalidCredentials = errors.New("invalid username or password")
 	ErrInvalidToken       = errors.New("invalid or expired token")
@@ -20,13 +30,24 @@ type Claims struct {
 	IsAdmin                bool   `json:"is_admin"`
 	PlayerID               *int64 `json:"player_id,omitempty"`
 	PasswordChangeRequired bool   `json:"password_change_required"`
+	// ImpersonatedBy is the admin's user ID on a "view as" token
+	// (see GenerateImpersonationToken), zero on a normal login.
+	ImpersonatedBy int64 `json:"impersonated_by,omitempty"`
+	// APIKeyID is set when the request was made with an API key rather
+	// than a token; it's never part of a JWT.
+	APIKeyID int64 `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// DefaultRefreshTokenDuration is how long a refresh token lasts
+// unless SetRefreshTokenDuration says otherwise.
+const DefaultRefreshTokenDuration = 30 * 24 * time.Hour
+
 // Service handles authentication operations
 type Service struct {
-	jwtSecret     []byte
-	tokenDuration time.Duration
+	jwtSecret       []byte
+	tokenDuration   time.Duration
+	refreshDuration time.Duration
 }
 
 // NewService creates a new auth service
@@ -35,11 +56,67 @@ func NewService(jwtSecret string, tokenDuration time.Duration) *Service {
 		tokenDuration = 24 * time.Hour
 	}
 	return &Service{
-		jwtSecret:     []byte(jwtSecret),
-		tokenDuration: tokenDuration,
+		jwtSecret:       []byte(jwtSecret),
+		tokenDuration:   tokenDuration,
+		refreshDuration: DefaultRefreshTokenDuration,
+	}
+}
+
+// SetRefreshTokenDuration sets how long refresh tokens last. Zero
+// keeps the default.
+func (s *Service) SetRefreshTokenDuration(d time.Duration) {
+	if d > 0 {
+		s.refreshDuration = d
+	}
+}
+
+// RefreshTokenDuration is how long a new refresh token lasts.
+func (s *Service) RefreshTokenDuration() time.Duration {
+	return s.refreshDuration
+}
+
+// NewRefreshToken returns a random refresh token and the hash to
+// store for it. Only the hash is kept server-side.
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the stored form of a refresh token.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix starts every API key, so they can't be mistaken for a
+// JWT and are easy to spot if one leaks into a log or a repo.
+const APIKeyPrefix = "trk_"
+
+// NewAPIKey returns a random API key and the hash to store for it.
+func NewAPIKey() (key, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
 	}
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, HashAPIKey(key), nil
 }
 
+// HashAPIKey returns the stored form of an API key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NoPasswordHash is stored for accounts created by signing in with an
+// external provider. It's never a valid bcrypt hash, so no password
+// matches it.
+const NoPasswordHash = "!"
+
 // HashPassword creates a bcrypt hash of a password
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -69,6 +146,27 @@ func (s *Service) GenerateToken(userID int64, username string, isAdmin bool, pla
 	return token.SignedString(s.jwtSecret)
 }
 
+// GenerateImpersonationToken creates a JWT that lets adminUserID see
+// the site as the given user until the returned expiry. It never
+// carries admin rights or a pending password change; the API treats it
+// as read-only.
+func (s *Service) GenerateImpersonationToken(userID int64, username string, playerID *int64, adminUserID int64, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		Username:       username,
+		UserID:         userID,
+		PlayerID:       playerID,
+		ImpersonatedBy: adminUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	return token, expiresAt, err
+}
+
 // ValidateToken validates a JWT and returns the claims
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
@@ -0,0 +1,38 @@
+package collector
+
+import "time"
+
+// trackAbandon notes whether the live match has the humans its
+// abandon policy wants. Called after every begin and disconnect, and
+// at warmup's end. Warmup doesn't count.
+func trackAbandon(state *serverState, ts time.Time) {
+	p := state.abandon
+	if p == nil || (state.matchState != "active" && state.matchState != "overtime") {
+		return
+	}
+	if humansOn(state) >= p.MinHumans {
+		state.humansReached = true
+		state.humansLowSince = time.Time{}
+		return
+	}
+	if state.humansReached && state.humansLowSince.IsZero() {
+		state.humansLowSince = ts
+	}
+}
+
+// matchAbandoned reports whether the match ending at end was
+// abandoned: it had its humans, then went without them for the
+// policy's After up to the end.
+func matchAbandoned(state *serverState, end time.Time) bool {
+	p := state.abandon
+	if p == nil || state.humansLowSince.IsZero() {
+		return false
+	}
+	return end.Sub(state.humansLowSince) >= time.Duration(p.After)
+}
+
+// resetAbandon forgets the last match's humans.
+func resetAbandon(state *serverState) {
+	state.humansReached = false
+	state.humansLowSince = time.Time{}
+}
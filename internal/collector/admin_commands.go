@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleAdminCommand runs !kick, !mute, or !map for an admin: a player
+// whose GUID the hub's greet said is linked to an admin account. Runs
+// under m.mu, so the RCON goes out on its own goroutine.
+func (m *ServerManager) handleAdminCommand(serverID int64, state *serverState, clientID int, cmd, args string) {
+	admin, ok := state.clients[clientID]
+	if !ok || !state.isAdmin(admin) {
+		log.Printf("collector: admin command !%s refused for client %d on %s: not an admin", cmd, clientID, state.server.Key)
+		m.sendPrint(serverID, clientID, "^1Only admins can use ^7!"+cmd+"^1.")
+		return
+	}
+	data := domain.AdminCommandData{
+		AdminGUID:    admin.guid,
+		AdminName:    admin.name,
+		Command:      cmd,
+		TargetClient: -1,
+	}
+
+	if cmd == "map" {
+		if args == "" || strings.ContainsAny(args, " ;\"\\") {
+			m.sendPrint(serverID, clientID, "^3Usage: ^7!map <map>")
+			return
+		}
+		data.Target = args
+		go m.runAdminCommand(serverID, clientID, "map "+args, false, data)
+		return
+	}
+
+	if args == "" {
+		m.sendPrint(serverID, clientID, "^3Usage: ^7!"+cmd+" <name or slot>")
+		return
+	}
+	target, problem := findAdminTarget(state, args)
+	switch {
+	case problem != "":
+		m.sendPrint(serverID, clientID, problem)
+		return
+	case target.clientID == clientID:
+		m.sendPrint(serverID, clientID, "^1You can't ^7!"+cmd+" ^1yourself.")
+		return
+	case state.isAdmin(target):
+		m.sendPrint(serverID, clientID, "^1Admins can't be kicked or muted from chat.")
+		return
+	}
+	data.Target = target.name
+	data.TargetGUID = target.guid
+	data.TargetClient = target.clientID
+
+	var rcon string
+	if cmd == "kick" {
+		rcon = fmt.Sprintf("clientkick %d", target.clientID)
+	} else {
+		var mute string
+		for _, srv := range m.config().Q3Servers {
+			if srv.Address == state.server.Address {
+				mute = m.config().MuteCommandFor(srv)
+				break
+			}
+		}
+		if mute == "" {
+			m.sendPrint(serverID, clientID, "^1No mute command is set for this server.")
+			return
+		}
+		rcon = strings.ReplaceAll(mute, "{client}", strconv.Itoa(target.clientID))
+	}
+	go m.runAdminCommand(serverID, clientID, rcon, cmd == "kick" && !target.isBot, data)
+}
+
+// isAdmin reports whether c was greeted as an admin in its slot. A
+// client sharing an admin's GUID in another slot isn't one.
+func (state *serverState) isAdmin(c *clientState) bool {
+	return c.guid != "" && state.admins[c.clientID] == c.guid
+}
+
+// runAdminCommand sends an admin's command to the server, tells the
+// admin how it went, and reports it to the hub for the audit log.
+// notify warns the target first.
+func (m *ServerManager) runAdminCommand(serverID int64, clientID int, rcon string, notify bool, data domain.AdminCommandData) {
+	target := domain.CleanQ3Name(data.Target)
+	if notify {
+		m.sendPrintSync(serverID, data.TargetClient, "^3You are being kicked by an admin.")
+	}
+	output, err := m.ExecuteRcon(serverID, rcon)
+	if err != nil {
+		log.Printf("collector: admin !%s %s on server %d: %v", data.Command, target, serverID, err)
+		m.sendPrint(serverID, clientID, "^1The server didn't answer. Try again.")
+		return
+	}
+	if data.Command == "map" && strings.Contains(output, "Can't find map") {
+		m.sendPrint(serverID, clientID, "^1No such map: ^7"+data.Target)
+		return
+	}
+	log.Printf("collector: admin %s ran !%s %s on server %d", domain.CleanQ3Name(data.AdminName), data.Command, target, serverID)
+	switch data.Command {
+	case "kick":
+		m.sendPrint(serverID, clientID, "^2Kicked ^7"+data.Target)
+	case "mute":
+		m.sendPrint(serverID, clientID, "^2Muted ^7"+data.Target)
+	}
+	data.At = time.Now().UTC()
+	m.pub.Publish(domain.FactEvent{
+		Type:      domain.FactAdminCommand,
+		ServerID:  serverID,
+		Timestamp: data.At,
+		Data:      data,
+	})
+}
+
+// findAdminTarget picks the player args names: a slot number, a whole
+// name, or part of exactly one name, ignoring case and colors. On no
+// match or several, it returns a message for the admin instead.
+func findAdminTarget(state *serverState, args string) (*clientState, string) {
+	if n, err := strconv.Atoi(args); err == nil {
+		if c, ok := state.clients[n]; ok {
+			return c, ""
+		}
+		return nil, fmt.Sprintf("^1No player in slot ^7%d", n)
+	}
+	want := strings.ToLower(domain.CleanQ3Name(args))
+	var matches []*clientState
+	for _, c := range state.clients {
+		name := strings.ToLower(domain.CleanQ3Name(c.name))
+		if name == want {
+			return c, ""
+		}
+		if strings.Contains(name, want) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "^1No player matching ^7" + args
+	case 1:
+		return matches[0], ""
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].clientID < matches[j].clientID })
+	names := make([]string, len(matches))
+	for i, c := range matches {
+		names[i] = fmt.Sprintf("%s ^3(%d)", c.name, c.clientID)
+	}
+	return nil, "^3Several players match: ^7" + strings.Join(names, "^7, ") + "^7. Use a slot number."
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/hub"
+)
+
+// greetRPC answers every greet with reply.
+type greetRPC struct {
+	hub.RPCClient
+	reply hub.GreetReply
+}
+
+func (r greetRPC) Greet(context.Context, hub.GreetRequest) (hub.GreetReply, error) {
+	return r.reply, nil
+}
+
+func TestAdminNeedsVerifiedAuth(t *testing.T) {
+	for _, tc := range []struct {
+		auth hub.AuthResult
+		want bool
+	}{
+		{hub.AuthVerified, true},
+		{hub.AuthUnauthenticated, false},
+	} {
+		m, _, _ := newWarmupTestManager(t)
+		m.servers[1].greetings = &config.GreetingsConfig{}
+		m.rpc = greetRPC{reply: hub.GreetReply{IsAdmin: true, AuthResult: tc.auth}}
+
+		m.performGreet(context.Background(), 1, 0, "AAAA", "alice", "alice", true, true, nil)
+		state := m.servers[1]
+		if got := state.isAdmin(state.clients[0]); got != tc.want {
+			t.Errorf("%s greet for an admin GUID: admin = %t, want %t", tc.auth, got, tc.want)
+		}
+	}
+}
+
+func TestAdminIsPerSlot(t *testing.T) {
+	m, _, feed := newWarmupTestManager(t)
+	m.servers[1].greetings = &config.GreetingsConfig{}
+	m.rpc = greetRPC{reply: hub.GreetReply{IsAdmin: true, AuthResult: hub.AuthVerified}}
+	m.performGreet(context.Background(), 1, 0, "AAAA", "alice", "alice", true, true, nil)
+
+	// Someone else joins with alice's GUID and no sign-in.
+	state := m.servers[1]
+	copycat := &clientState{clientID: 2, name: "alice", guid: "AAAA", began: true}
+	state.clients[2] = copycat
+	if state.isAdmin(copycat) {
+		t.Error("a second client with an admin's GUID is an admin")
+	}
+
+	feed(EventTypeClientDisconnect, ClientDisconnectData{ClientID: 0})
+	state.clients[0] = &clientState{clientID: 0, name: "alice", guid: "AAAA", began: true}
+	if state.isAdmin(state.clients[0]) {
+		t.Error("admin rights outlived the slot's disconnect")
+	}
+}
@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Game server clocks drift. While tailing live, each stamped line is
+// read within moments of being written, so its timestamp minus the time
+// we read it is the server's clock offset less that delay. The largest
+// of the recent samples is the best guess at the offset; once it's at
+// least skewThreshold, every timestamp from the server, replayed or
+// live, is pulled back by it.
+const (
+	skewSamples    = 60
+	skewMinSamples = 10
+	skewThreshold  = 2 * time.Second
+)
+
+// logClock converts one server's log timestamps to collector time.
+// loc is the zone for timestamps written without one.
+type logClock struct {
+	loc *time.Location
+
+	mu      sync.Mutex
+	offset  time.Duration   // server clock minus ours
+	samples []time.Duration // recent stamp-minus-receipt, oldest first
+	// onChange is called, without mu held, when offset moves.
+	onChange func(time.Duration)
+}
+
+func newLogClock(loc *time.Location, offset time.Duration) *logClock {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &logClock{loc: loc, offset: offset}
+}
+
+// Offset returns how far ahead of ours the server's clock is.
+func (c *logClock) Offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offset
+}
+
+// correct moves a timestamp from the server's clock to ours.
+func (c *logClock) correct(t time.Time) time.Time {
+	return t.Add(-c.Offset())
+}
+
+// observe records a line stamped at stamp, by the server's clock, and
+// read at received, by ours.
+func (c *logClock) observe(stamp, received time.Time) {
+	c.mu.Lock()
+	if len(c.samples) == skewSamples {
+		c.samples = append(c.samples[:0], c.samples[1:]...)
+	}
+	c.samples = append(c.samples, stamp.Sub(received))
+	if len(c.samples) < skewMinSamples {
+		c.mu.Unlock()
+		return
+	}
+	est := c.samples[0]
+	for _, s := range c.samples[1:] {
+		est = max(est, s)
+	}
+	// Log timestamps are whole seconds, so the estimate is too.
+	est = est.Round(time.Second)
+	if est.Abs() < skewThreshold {
+		est = 0
+	}
+	if (est - c.offset).Abs() < skewThreshold {
+		c.mu.Unlock()
+		return
+	}
+	c.offset = est
+	onChange := c.onChange
+	c.mu.Unlock()
+	if onChange != nil {
+		onChange(est)
+	}
+}
+
+// clockOffsetsFile keeps each server's offset, by key, so a restarted
+// collector replays its log with the offset it went live with.
+const clockOffsetsFile = "clock_offsets.json"
+
+// loadClockOffsets reads the offsets saved in dir. A missing file is
+// no offsets.
+func loadClockOffsets(dir string) (map[string]time.Duration, error) {
+	data, err := os.ReadFile(filepath.Join(dir, clockOffsetsFile))
+	if os.IsNotExist(err) {
+		return map[string]time.Duration{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ms map[string]int64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", clockOffsetsFile, err)
+	}
+	out := make(map[string]time.Duration, len(ms))
+	for key, v := range ms {
+		out[key] = time.Duration(v) * time.Millisecond
+	}
+	return out, nil
+}
+
+// saveClockOffsets replaces the offsets saved in dir.
+func saveClockOffsets(dir string, offsets map[string]time.Duration) error {
+	ms := make(map[string]int64, len(offsets))
+	for key, d := range offsets {
+		if d != 0 {
+			ms[key] = d.Milliseconds()
+		}
+	}
+	data, err := json.MarshalIndent(ms, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, clockOffsetsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,81 @@
+package collector
+
+import "time"
+
+// Chat command flood control. A player may send commandBurst commands
+// in any commandWindow; one more and the collector ignores their
+// commands for commandMute. Buckets are keyed by GUID where known, so
+// reconnecting doesn't wipe the slate.
+const (
+	commandBurst  = 5
+	commandWindow = 10 * time.Second
+	commandMute   = time.Minute
+)
+
+// chatCommands are the commands handleCommand answers. Anything else
+// is counted as "unknown" so junk doesn't blow up the metric labels.
+var chatCommands = map[string]bool{
+	"link": true, "claim": true, "stats": true, "top": true,
+	"nextmap": true, "maps": true, "help": true,
+	"kick": true, "mute": true, "map": true,
+}
+
+// commandLabel is cmd's label in trinity_chat_commands_total.
+func commandLabel(cmd string) string {
+	if chatCommands[cmd] {
+		return cmd
+	}
+	return "unknown"
+}
+
+type commandBucket struct {
+	recent     []time.Time // commands inside the window, oldest first
+	mutedUntil time.Time
+}
+
+// commandLimiter is one server's per-player command rate limit.
+type commandLimiter struct {
+	buckets map[string]*commandBucket
+}
+
+func newCommandLimiter() *commandLimiter {
+	return &commandLimiter{buckets: make(map[string]*commandBucket)}
+}
+
+// allow records a command from key at now and reports whether to
+// answer it. muted is set on the command that trips the limit, so the
+// player is told once rather than on every command they send after.
+func (l *commandLimiter) allow(key string, now time.Time) (ok, muted bool) {
+	l.prune(now)
+	b := l.buckets[key]
+	if b == nil {
+		b = &commandBucket{}
+		l.buckets[key] = b
+	}
+	if now.Before(b.mutedUntil) {
+		return false, false
+	}
+	if len(b.recent) >= commandBurst {
+		b.recent = b.recent[:0]
+		b.mutedUntil = now.Add(commandMute)
+		return false, true
+	}
+	b.recent = append(b.recent, now)
+	return true, false
+}
+
+// prune drops commands that have left the window, and buckets with
+// nothing left in them.
+func (l *commandLimiter) prune(now time.Time) {
+	cutoff := now.Add(-commandWindow)
+	for key, b := range l.buckets {
+		i := 0
+		for i < len(b.recent) && !b.recent[i].After(cutoff) {
+			i++
+		}
+		b.recent = b.recent[i:]
+		if len(b.recent) == 0 && !now.Before(b.mutedUntil) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// A game server that goes down without logging ServerShutdown has
+// crashed (or been killed). The collector notices in two ways: the
+// log shows the next ServerStartup while the last run was still up,
+// or, for a server that systemd gave up restarting, the watchdog finds
+// its quake3-server@ unit failed. Either way a server_crash fact goes
+// to the hub with the server's last log lines.
+
+// CrashNotifier is told when a game server crashes. Called from the
+// collector's goroutines, so implementations must not block.
+type CrashNotifier interface {
+	NotifyCrash(serverKey string, crash domain.ServerCrashData)
+}
+
+// SetCrashNotifier sets who hears about crashes. Must be called before
+// Start. Unset, they are only logged and sent to the hub.
+func (m *ServerManager) SetCrashNotifier(n CrashNotifier) {
+	m.crashNotifier = n
+}
+
+// UnitChecker reports whether a systemd unit has failed and, if so,
+// systemd's Result for it (such as "signal" or "core-dump").
+type UnitChecker interface {
+	UnitFailed(ctx context.Context, unit string) (failed bool, result string, err error)
+}
+
+// SetUnitChecker lets the watchdog spot servers whose quake3-server@
+// unit has failed. Must be called before Start.
+func (m *ServerManager) SetUnitChecker(c UnitChecker) {
+	m.unitChecker = c
+}
+
+// reportCrash logs crash, sends it to the hub, and notifies.
+func (m *ServerManager) reportCrash(serverID int64, key string, ts time.Time, crash domain.ServerCrashData) {
+	reason := crash.Reason
+	if crash.Detail != "" {
+		reason += ": " + crash.Detail
+	}
+	log.Printf("collector: %s crashed (%s); last log line at %s", key, reason, crash.CrashedAt.Format(time.RFC3339))
+	m.pub.Publish(domain.FactEvent{
+		Type:      domain.FactServerCrash,
+		ServerID:  serverID,
+		Timestamp: ts,
+		Data:      crash,
+	})
+	if m.crashNotifier != nil {
+		m.crashNotifier.NotifyCrash(key, crash)
+	}
+}
+
+// checkUnits reports servers whose unit has failed since the last
+// check. failed holds each server's state at that check; a server's
+// first check only records it, so a unit that was already failed when
+// the collector started isn't reported again.
+func (m *ServerManager) checkUnits(ctx context.Context, failed map[int64]bool, now time.Time) {
+	if m.unitChecker == nil {
+		return
+	}
+	type target struct {
+		key    string
+		tailer *LogTailer
+	}
+	m.mu.RLock()
+	targets := make(map[int64]target, len(m.tailers))
+	for id, t := range m.tailers {
+		if state, ok := m.servers[id]; ok {
+			targets[id] = target{key: state.server.Key, tailer: t}
+		}
+	}
+	m.mu.RUnlock()
+
+	for id := range failed {
+		if _, ok := targets[id]; !ok {
+			delete(failed, id)
+		}
+	}
+	for id, tg := range targets {
+		isFailed, result, err := m.unitChecker.UnitFailed(ctx, "quake3-server@"+tg.key)
+		if err != nil {
+			continue
+		}
+		was, seen := failed[id]
+		failed[id] = isFailed
+		if !seen || was || !isFailed {
+			continue
+		}
+		lines, at := tg.tailer.Recent()
+		if at.IsZero() {
+			at = now
+		}
+		m.reportCrash(id, tg.key, now, domain.ServerCrashData{
+			CrashedAt: at,
+			Reason:    domain.CrashUnitFailed,
+			Detail:    result,
+			LogLines:  lines,
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// The hub saves a log cursor with each match's stats: where in the log
+// the line that ended the match was. On restart the collector replays
+// the lines up to the cursor only to rebuild state and publishes the
+// rest, so a match whose stats were flushed isn't flushed again and
+// one that wasn't is. If the log no longer has the cursor's line (it
+// was rotated, or copytruncated and refilled), replay falls back to
+// the timestamp cutoff.
+
+// replayStart is where replaying a log switches from rebuilding state
+// to publishing: after the cursor's line if the log still has it, else
+// after cutoff.
+type replayStart struct {
+	cutoff time.Time
+	cursor *domain.LogCursor
+	// resumeCutoff still applies when resuming from cursor: the NATS
+	// watermark, or when Reload stopped tailing the server.
+	resumeCutoff time.Time
+}
+
+// fileInode returns info's inode, or 0 if it has none.
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// logCursor returns the position just past event's line, or nil if it
+// isn't known.
+func logCursor(event LogEvent) *domain.LogCursor {
+	if event.inode == 0 {
+		return nil
+	}
+	return &domain.LogCursor{Inode: event.inode, Offset: event.offset, At: event.Timestamp}
+}
+
+// resumes reports whether the open log still has c's line: it's the
+// same file, and the line ending c.Offset bytes in is stamped c.At.
+// Must be called before the tail starts.
+func (t *LogTailer) resumes(c domain.LogCursor) bool {
+	info, err := t.file.Stat()
+	if err != nil || c.Offset <= 0 || fileInode(info) != c.Inode || info.Size() < c.Offset {
+		return false
+	}
+	start := max(c.Offset-4096, 0)
+	buf := make([]byte, c.Offset-start)
+	if _, err := t.file.ReadAt(buf, start); err != nil || buf[len(buf)-1] != '\n' {
+		return false
+	}
+	line := buf[:len(buf)-1]
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	loc := time.Local
+	if t.clock != nil {
+		loc = t.clock.loc
+	}
+	event, err := parseLine(string(bytes.TrimSpace(line)), loc)
+	if err != nil || event == nil || !event.stamped {
+		return false
+	}
+	ts := event.Timestamp
+	if t.clock != nil {
+		ts = t.clock.correct(ts)
+	}
+	// The hub keeps whole seconds.
+	return ts.Truncate(time.Second).Equal(c.At.Truncate(time.Second))
+}
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/hub"
+)
+
+// DryRun stands in for the hub during `trinity dry-run`: it satisfies
+// hub.ServerClient, hub.RPCClient, and hub.FactPublisher, hands out
+// synthetic IDs, and tallies what a real hub would have been asked to
+// store. Nothing is written anywhere. Pair it with
+// ServerManager.SetReadOnly so greets and !commands don't RCON the
+// production server either.
+type DryRun struct {
+	mu         sync.Mutex
+	nextID     int64
+	servers    map[int64]string // id → key
+	serverIDs  map[string]int64 // key → id
+	identities map[string]hub.PlayerIdentity
+	bots       map[string]hub.PlayerIdentity
+	facts      map[int64]map[string]int // server id → fact type → count
+	matchRows  map[int64]int            // server id → match_player_stats rows
+	lastFact   time.Time
+}
+
+// NewDryRun returns an empty DryRun backend.
+func NewDryRun() *DryRun {
+	return &DryRun{
+		servers:    make(map[int64]string),
+		serverIDs:  make(map[string]int64),
+		identities: make(map[string]hub.PlayerIdentity),
+		bots:       make(map[string]hub.PlayerIdentity),
+		facts:      make(map[int64]map[string]int),
+		matchRows:  make(map[int64]int),
+	}
+}
+
+func (d *DryRun) id() int64 {
+	d.nextID++
+	return d.nextID
+}
+
+func (d *DryRun) RegisterServer(ctx context.Context, source, key, address string) (*domain.Server, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id, ok := d.serverIDs[key]
+	if !ok {
+		id = d.id()
+		d.serverIDs[key] = id
+		d.servers[id] = key
+	}
+	return &domain.Server{ID: id, Key: key, Address: address, Source: source}, nil
+}
+
+func (d *DryRun) UpsertPlayerIdentity(ctx context.Context, guid, name, cleanName string, ts time.Time, isVR bool) (hub.PlayerIdentity, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pi, ok := d.identities[guid]
+	if !ok {
+		pi = hub.PlayerIdentity{PlayerID: d.id(), PlayerGUIDID: d.id(), Found: true}
+		d.identities[guid] = pi
+	}
+	return pi, nil
+}
+
+func (d *DryRun) UpsertBotPlayerIdentity(ctx context.Context, name, cleanName string, ts time.Time) (hub.PlayerIdentity, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pi, ok := d.bots[cleanName]
+	if !ok {
+		pi = hub.PlayerIdentity{PlayerID: d.id(), PlayerGUIDID: d.id(), Found: true}
+		d.bots[cleanName] = pi
+	}
+	return pi, nil
+}
+
+func (d *DryRun) LookupPlayerIdentity(ctx context.Context, guid string) (hub.PlayerIdentity, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.identities[guid], nil
+}
+
+func (d *DryRun) GetSourceProgress(ctx context.Context, source string) (hub.SourceProgressReply, error) {
+	return hub.SourceProgressReply{}, nil
+}
+
+// Greet answers as if every player were new and unclaimed.
+func (d *DryRun) Greet(ctx context.Context, req hub.GreetRequest) (hub.GreetReply, error) {
+	return hub.GreetReply{AuthResult: hub.AuthUnauthenticated, CanonicalName: req.ClientName}, nil
+}
+
+func (d *DryRun) Claim(ctx context.Context, req hub.ClaimRequest) (hub.ClaimReply, error) {
+	return hub.ClaimReply{Status: hub.ClaimError, Message: "dry run"}, nil
+}
+
+func (d *DryRun) Link(ctx context.Context, req hub.LinkRequest) (hub.LinkReply, error) {
+	return hub.LinkReply{Status: hub.LinkInvalidCode, Message: "dry run"}, nil
+}
+
+func (d *DryRun) Stats(ctx context.Context, req hub.StatsRequest) (hub.StatsReply, error) {
+	return hub.StatsReply{Status: hub.StatsNotFound}, nil
+}
+
+func (d *DryRun) Top(ctx context.Context, req hub.TopRequest) (hub.TopReply, error) {
+	return hub.TopReply{Status: hub.TopOK}, nil
+}
+
+func (d *DryRun) Publish(e domain.FactEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	byType, ok := d.facts[e.ServerID]
+	if !ok {
+		byType = make(map[string]int)
+		d.facts[e.ServerID] = byType
+	}
+	byType[e.Type]++
+	if data, ok := e.Data.(domain.MatchEndData); ok {
+		d.matchRows[e.ServerID] += len(data.Players)
+	}
+	if e.Timestamp.After(d.lastFact) {
+		d.lastFact = e.Timestamp
+	}
+	return nil
+}
+
+// WriteReport prints a per-server tally of the facts seen so far and
+// the rows they would have produced on the hub.
+func (d *DryRun) WriteReport(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := make([]int64, 0, len(d.servers))
+	for id := range d.servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return d.servers[ids[i]] < d.servers[ids[j]] })
+
+	for _, id := range ids {
+		byType := d.facts[id]
+		fmt.Fprintf(w, "%s:\n", d.servers[id])
+		if len(byType) == 0 {
+			fmt.Fprintln(w, "  (no events yet)")
+			continue
+		}
+		types := make([]string, 0, len(byType))
+		for t := range byType {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			fmt.Fprintf(w, "  %-22s %d\n", t, byType[t])
+		}
+		fmt.Fprintf(w, "  would store: %d matches, %d player match rows, %d sessions\n",
+			byType[domain.FactMatchEnd], d.matchRows[id], byType[domain.FactPlayerJoin])
+	}
+	fmt.Fprintf(w, "Distinct players: %d humans, %d bots\n", len(d.identities), len(d.bots))
+	if !d.lastFact.IsZero() {
+		fmt.Fprintf(w, "Latest event: %s\n", d.lastFact.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,222 @@
+package collector
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// EventArchive appends every live log event to a JSON-lines file per
+// server, rotating and gzipping files as they fill. Optional: a nil
+// *EventArchive archives nothing, so call sites don't have to check
+// whether the operator configured one.
+type EventArchive struct {
+	dir      string
+	maxBytes int64
+	keep     int
+
+	mu    sync.Mutex
+	files map[string]*archiveFile // server key -> current file
+	wg    sync.WaitGroup          // in-flight compressions
+}
+
+type archiveFile struct {
+	f    *os.File
+	size int64
+}
+
+// archivedEvent is one line of an archive file. Line is the raw log
+// line, so the archive can go back through a newer parser; Data is
+// what this build's parser made of it.
+type archivedEvent struct {
+	Timestamp time.Time   `json:"ts"`
+	Type      string      `json:"type"`
+	Line      string      `json:"line,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// OpenEventArchive creates cfg.Dir if needed. Files are opened on
+// each server's first event.
+func OpenEventArchive(cfg config.EventArchiveConfig) (*EventArchive, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating event archive dir %s: %w", cfg.Dir, err)
+	}
+	return &EventArchive{
+		dir:      cfg.Dir,
+		maxBytes: int64(cfg.MaxFileMB) << 20,
+		keep:     cfg.Keep,
+		files:    make(map[string]*archiveFile),
+	}, nil
+}
+
+// Append writes event to serverKey's archive, rotating first if the
+// file is full. Failures are logged rather than returned: losing the
+// archive mustn't hold up the tracker. Safe on a nil receiver.
+func (a *EventArchive) Append(serverKey string, event LogEvent) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(archivedEvent{
+		Timestamp: event.Timestamp.UTC(),
+		Type:      event.Type,
+		Line:      event.Line,
+		Data:      event.Data,
+	})
+	if err != nil {
+		log.Printf("collector: event archive: encoding %s event for %s: %v", event.Type, serverKey, err)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	af, err := a.current(serverKey)
+	if err != nil {
+		log.Printf("collector: event archive: %v", err)
+		return
+	}
+	if af.size > 0 && af.size+int64(len(line)) > a.maxBytes {
+		if err := a.rotate(serverKey, af); err != nil {
+			log.Printf("collector: event archive: rotating %s: %v", serverKey, err)
+		}
+		if af, err = a.current(serverKey); err != nil {
+			log.Printf("collector: event archive: %v", err)
+			return
+		}
+	}
+	n, err := af.f.Write(line)
+	af.size += int64(n)
+	if err != nil {
+		log.Printf("collector: event archive: writing %s: %v", serverKey, err)
+	}
+}
+
+// current returns serverKey's open file, opening it for append if
+// needed. Called under a.mu.
+func (a *EventArchive) current(serverKey string) (*archiveFile, error) {
+	if af, ok := a.files[serverKey]; ok {
+		return af, nil
+	}
+	path := filepath.Join(a.dir, serverKey+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	af := &archiveFile{f: f, size: stat.Size()}
+	a.files[serverKey] = af
+	return af, nil
+}
+
+// rotate closes serverKey's current file and moves it aside, then
+// compresses it in the background so the event loop isn't held up.
+// Called under a.mu.
+func (a *EventArchive) rotate(serverKey string, af *archiveFile) error {
+	delete(a.files, serverKey)
+	if err := af.f.Close(); err != nil {
+		return err
+	}
+	rotated := filepath.Join(a.dir, serverKey+"-"+time.Now().UTC().Format("20060102T150405Z")+".jsonl")
+	if err := os.Rename(af.f.Name(), rotated); err != nil {
+		return err
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := compressFile(rotated); err != nil {
+			log.Printf("collector: event archive: compressing %s: %v", rotated, err)
+			return
+		}
+		a.prune(serverKey)
+	}()
+	return nil
+}
+
+// compressFile gzips path to path.gz and removes path.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune deletes serverKey's oldest compressed files beyond a.keep.
+func (a *EventArchive) prune(serverKey string) {
+	if a.keep <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(filepath.Join(a.dir, serverKey+"-*.jsonl.gz"))
+	if err != nil {
+		return
+	}
+	// Another server's key may extend this one ("ffa" and "ffa-2"),
+	// so only take names that are the key plus a timestamp.
+	var files []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), serverKey+"-"), ".jsonl.gz")
+		if _, err := time.Parse("20060102T150405Z", stamp); err == nil {
+			files = append(files, m)
+		}
+	}
+	sort.Strings(files)
+	for len(files) > a.keep {
+		if err := os.Remove(files[0]); err != nil {
+			log.Printf("collector: event archive: pruning %s: %v", files[0], err)
+		}
+		files = files[1:]
+	}
+}
+
+// Close closes every open file and waits for pending
+// compressions. Safe on a nil receiver.
+func (a *EventArchive) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	var firstErr error
+	for key, af := range a.files {
+		if err := af.f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(a.files, key)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+	return firstErr
+}
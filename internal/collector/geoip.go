@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoIP resolves client IPs to a coarse location using a MaxMind
+// GeoLite2-City (or GeoIP2-City) database file. Optional: a nil *GeoIP
+// resolves nothing, so call sites don't have to check whether the
+// operator configured one.
+type GeoIP struct {
+	reader *geoip2.Reader
+}
+
+// GeoLocation is what a lookup yields. Any field may be empty —
+// GeoLite2 often knows the country but not the city.
+type GeoLocation struct {
+	CountryCode string // ISO 3166-1 alpha-2
+	Country     string
+	City        string
+}
+
+// OpenGeoIP memory-maps the database at path. The file is read once;
+// operators refreshing it (geoipupdate) need to restart the collector.
+func OpenGeoIP(path string) (*GeoIP, error) {
+	r, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening GeoIP database %s: %w", path, err)
+	}
+	return &GeoIP{reader: r}, nil
+}
+
+// Close releases the database. Safe on a nil receiver.
+func (g *GeoIP) Close() error {
+	if g == nil {
+		return nil
+	}
+	return g.reader.Close()
+}
+
+// Lookup resolves addr, which may be a bare IP or the "IP:port" form
+// Q3 logs on ClientConnect. Private, loopback, and unparseable
+// addresses (including bots' "bot") resolve to the zero GeoLocation.
+func (g *GeoIP) Lookup(addr string) GeoLocation {
+	if g == nil || addr == "" {
+		return GeoLocation{}
+	}
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return GeoLocation{}
+	}
+	rec, err := g.reader.City(ip)
+	if err != nil {
+		return GeoLocation{}
+	}
+	return GeoLocation{
+		CountryCode: rec.Country.IsoCode,
+		Country:     rec.Country.Names["en"],
+		City:        rec.City.Names["en"],
+	}
+}
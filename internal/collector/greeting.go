@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/hub"
+)
+
+// greetingsFor returns the greeting templates for a server, or the
+// top-level ones if it isn't tracked.
+func (m *ServerManager) greetingsFor(serverID int64) *config.GreetingsConfig {
+	m.mu.RLock()
+	state, ok := m.servers[serverID]
+	m.mu.RUnlock()
+	if ok && state.greetings != nil {
+		return state.greetings
+	}
+	return m.config().GreetingsFor(config.Q3Server{})
+}
+
+// greetingsUse reports whether any of g's greetings has placeholder.
+func greetingsUse(g *config.GreetingsConfig, placeholder string) bool {
+	for _, t := range []*config.GreetingTemplate{g.New, g.Claimed, g.Returning, g.NoTrinity, g.OutdatedVR, g.TryVR} {
+		if t != nil && (strings.Contains(t.Message, placeholder) || strings.Contains(t.Center, placeholder)) {
+			return true
+		}
+	}
+	return false
+}
+
+// greetingReplacer fills in config.GreetingPlaceholders for a greeted
+// player.
+func greetingReplacer(playerName string, reply hub.GreetReply, siteURL string) *strings.Replacer {
+	kd := fmt.Sprintf("%.2f", reply.KDRatio)
+	matches := strconv.FormatInt(reply.CompletedMatches, 10)
+	stats := ""
+	if reply.CompletedMatches > 0 {
+		stats = "K/D: ^3" + kd + " ^7| Matches: ^3" + matches + "^7"
+	}
+	rank, away := "", ""
+	if reply.Rank > 0 {
+		rank = strconv.Itoa(reply.Rank)
+	}
+	if reply.DaysAway > 0 {
+		away = domain.HumanizeDaysAway(reply.DaysAway)
+	}
+	return strings.NewReplacer(
+		"{name}", playerName,
+		"{kd}", kd,
+		"{matches}", matches,
+		"{stats}", stats,
+		"{rank}", rank,
+		"{away}", away,
+		"{site_url}", siteURL,
+	)
+}
+
+// renderGreeting fills in text and tidies what empty placeholders
+// leave behind: doubled spaces and blank lines.
+func renderGreeting(text string, fill *strings.Replacer) string {
+	lines := strings.Split(fill.Replace(text), "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// sendGreeting prints t to the client's console and centerprints it.
+// Either half may be empty.
+func (m *ServerManager) sendGreeting(serverID int64, clientID int, t *config.GreetingTemplate, fill *strings.Replacer) {
+	if msg := renderGreeting(t.Message, fill); msg != "" {
+		m.sendPrintSync(serverID, clientID, msg)
+	}
+	if cp := renderGreeting(t.Center, fill); cp != "" {
+		m.sendCenterPrint(serverID, clientID, cp)
+	}
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/google/uuid"
+)
+
+// `trinity import-logs` runs archived logs through the same state
+// machine as a live tail, so old matches, sessions, and stats come out
+// as the collector would have recorded them at the time. Logs from
+// before the Trinity engine carry no match UUID and no handshake: an
+// imported match without a UUID gets one derived from the server key
+// and its InitGame time, and the operator's logs stand in for the
+// handshake. Importing a log twice therefore finds the matches the
+// first run created, and the hub skips a match_end for a match that
+// already ended, so nothing is counted twice.
+
+// ImportSummary is what ImportLogs read.
+type ImportSummary struct {
+	Files  int
+	Events int
+	// Unstamped counts event lines skipped for having no timestamp.
+	Unstamped int
+	// First and Last are the earliest and latest events imported.
+	First time.Time
+	Last  time.Time
+}
+
+// importedLog is a log to import and when its first event was logged.
+type importedLog struct {
+	path  string
+	first time.Time
+}
+
+// ImportLogs feeds the logs at paths (plain or gzipped) to the hub as
+// the history of srv, a server of source, oldest log first. onFile, if
+// set, is called as each log is started. A match or session still open
+// at the end of the last log is closed there, so pass every log for a
+// stretch of time in one run, and not the log the collector is
+// tailing. The manager must not be started.
+func (m *ServerManager) ImportLogs(ctx context.Context, source string, srv config.Q3Server, paths []string, onFile func(path string)) (ImportSummary, error) {
+	var summary ImportSummary
+	loc := srv.Location()
+
+	logs := make([]importedLog, 0, len(paths))
+	for _, path := range paths {
+		first, err := firstStamp(path, loc)
+		if err != nil {
+			return summary, err
+		}
+		logs = append(logs, importedLog{path: path, first: first})
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].first.Before(logs[j].first) })
+
+	fullSrv, err := m.server.RegisterServer(ctx, source, srv.Key, srv.Address)
+	if err != nil {
+		return summary, err
+	}
+	state := &serverState{
+		server:         *fullSrv,
+		clients:        make(map[int]*clientState),
+		trinityNonces:  make(map[int]string),
+		openSessions:   make(map[string]bool),
+		vips:           make(map[string]bool),
+		admins:         make(map[int]string),
+		abandon:        srv.AbandonPolicy(),
+		commandLimiter: newCommandLimiter(),
+		clock:          newLogClock(loc, 0),
+	}
+	m.mu.Lock()
+	m.importing = true
+	m.readOnly = true
+	m.servers[fullSrv.ID] = state
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.servers, fullSrv.ID)
+		m.mu.Unlock()
+	}()
+
+	for _, l := range logs {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		if onFile != nil {
+			onFile(l.path)
+		}
+		if err := m.importLog(ctx, fullSrv.ID, l.path, loc, &summary); err != nil {
+			return summary, err
+		}
+		summary.Files++
+	}
+	m.closeImport(state, summary.Last)
+	return summary, nil
+}
+
+// importLog feeds one log's events to the hub.
+func (m *ServerManager) importLog(ctx context.Context, serverID int64, path string, loc *time.Location, summary *ImportSummary) error {
+	r, err := openLog(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			event, perr := parseLine(line, loc)
+			switch {
+			case perr != nil || event == nil:
+			case !event.stamped:
+				// It would be dated now.
+				summary.Unstamped++
+			default:
+				m.handleLogEvent(ctx, serverID, *event, false)
+				summary.Events++
+				if summary.First.IsZero() || event.Timestamp.Before(summary.First) {
+					summary.First = event.Timestamp
+				}
+				if event.Timestamp.After(summary.Last) {
+					summary.Last = event.Timestamp
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// firstStamp returns when the log at path's first stamped line was
+// logged, or the zero time if it has none.
+func firstStamp(path string, loc *time.Location) (time.Time, error) {
+	r, err := openLog(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if event, err := parseLine(strings.TrimSpace(scanner.Text()), loc); err == nil && event != nil && event.stamped {
+			return event.Timestamp, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return time.Time{}, nil
+}
+
+// importedMatchUUID names a match imported from a log without one. The
+// same match always gets the same UUID.
+func importedMatchUUID(key string, initGame time.Time) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("trinity:import/"+key+"/"+initGame.UTC().Format(time.RFC3339))).String()
+}
+
+// closeImport ends what the imported logs left open at at: the match
+// as crashed, and each human's session.
+func (m *ServerManager) closeImport(state *serverState, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state.match != nil && state.match.UUID != "" && state.matchStarted && !state.matchFlushed {
+		m.pub.Publish(domain.FactEvent{
+			Type: domain.FactMatchCrashed, ServerID: state.server.ID, Timestamp: at,
+			Data: domain.MatchCrashedData{MatchUUID: state.match.UUID, EndedAt: at},
+		})
+	}
+	for clientNum, client := range state.clients {
+		if client.isBot || client.guid == "" || !state.openSessions[client.guid] {
+			continue
+		}
+		m.pub.Publish(domain.FactEvent{
+			Type:      domain.FactPlayerLeave,
+			ServerID:  state.server.ID,
+			Timestamp: at,
+			Data: domain.PlayerLeaveData{
+				GUID:            client.guid,
+				ClientNum:       clientNum,
+				LeftAt:          at,
+				DurationSeconds: max(int(at.Sub(client.joinedAt).Seconds()), 0),
+			},
+		})
+	}
+}
@@ -4,11 +4,19 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ernie/trinity-tracker/internal/metrics"
+	"github.com/fsnotify/fsnotify"
 )
 
 // LogEvent represents a parsed event from the log
@@ -16,6 +24,16 @@ type LogEvent struct {
 	Timestamp time.Time
 	Type      string
 	Data      interface{}
+	// Line is the log line the event was parsed from.
+	Line string
+	// stamped is set when Timestamp came from the line rather than
+	// the time it was parsed.
+	stamped bool
+	// inode and offset place the end of the line in the log: offset
+	// bytes into the file with inode inode. inode is 0 when unknown,
+	// as for lines from a rotated log.
+	inode  uint64
+	offset int64
 }
 
 // Event types
@@ -44,6 +62,7 @@ const (
 	EventTypeTeamChange       = "team_change"
 	EventTypeAssist           = "assist"
 	EventTypeAward            = "award"
+	EventTypeWeaponStats      = "weapon_stats"
 	EventTypeSay              = "say"
 	EventTypeSayTeam          = "say_team"
 	EventTypeTell             = "tell"
@@ -58,6 +77,28 @@ const (
 	EventTypeDemoDiscarded    = "demo_discarded"
 )
 
+// highValueLogEvents change match or roster state; dropping one
+// desyncs the collector until the next map. The tailer waits for room
+// rather than dropping them.
+var highValueLogEvents = map[string]bool{
+	EventTypeInitGame:         true,
+	EventTypeWarmupEnd:        true,
+	EventTypeMatchState:       true,
+	EventTypeClientConnect:    true,
+	EventTypeClientUserinfo:   true,
+	EventTypeClientBegin:      true,
+	EventTypeClientDisconnect: true,
+	EventTypeExit:             true,
+	EventTypeScore:            true,
+	EventTypeWeaponStats:      true,
+	EventTypeShutdown:         true,
+	EventTypeTeamChange:       true,
+	EventTypeServerStartup:    true,
+	EventTypeServerShutdown:   true,
+	EventTypeTrinityHandshake: true,
+	EventTypeDemoSaved:        true,
+}
+
 // DemoSavedData carries the structured payload from a trinity-engine
 // "DemoSaved:" log line. MatchUUID is the engine's g_matchUUID cvar at
 // finalization time; the optional fields surface what the engine knows
@@ -127,6 +168,14 @@ type ShutdownGameData struct {
 	UUID string // unique match identifier from game
 }
 
+// ServerStartupData is set on a ServerStartup event that follows a
+// run which never logged ServerShutdown. LastLineAt is when that run
+// logged its last line, and Tail its last lines, oldest first.
+type ServerStartupData struct {
+	LastLineAt time.Time
+	Tail       []string
+}
+
 type ScoreEventData struct {
 	Score    int
 	Ping     int
@@ -217,6 +266,19 @@ type AwardData struct {
 	Name      string
 }
 
+// WeaponStatsData is a client's shots and hits for the match, one
+// entry per weapon they fired, from a "Weapon_Stats:" line.
+type WeaponStatsData struct {
+	ClientID int
+	Weapons  []WeaponShotsData
+}
+
+type WeaponShotsData struct {
+	Weapon string // weapon name as logged, e.g. "RAILGUN"
+	Shots  int
+	Hits   int
+}
+
 type SayData struct {
 	ClientID int
 	Name     string
@@ -297,6 +359,8 @@ var (
 	teamChangeRegex       = regexp.MustCompile(`^TeamChange: (\d+) (\d+) (\d+): (.+)$`)
 	assistRegex           = regexp.MustCompile(`^Assist: (\d+) (\d+) (return|frag): (.+)$`)
 	awardRegex            = regexp.MustCompile(`^Award: (\d+) (impressive|excellent|gauntlet|defend|assist): (.+)$`)
+	// Weapon_Stats: <clientID> <weapon>:<shots>:<hits> ... (our mod, at Exit)
+	weaponStatsRegex      = regexp.MustCompile(`^Weapon_Stats: (\d+)((?: \w+:\d+:\d+)*)$`)
 	// Chat patterns: Say: <clientID> "<name>": <message>
 	sayRegex              = regexp.MustCompile(`^Say: (\d+) "(.+)": (.+)$`)
 	sayTeamRegex          = regexp.MustCompile(`^SayTeam: (\d+) "(.+)": (.+)$`)
@@ -319,19 +383,46 @@ var (
 // LogTailer watches a log file and parses events
 type LogTailer struct {
 	path       string
+	mu         sync.Mutex // guards file and position against Reopen
 	file       *os.File
 	position   int64
 	Events     chan LogEvent
 	Errors     chan error
 	done       chan struct{}
 	startAfter *time.Time // if set, replay events after this timestamp on start
+	// clock, if set, reads timestamps in the server's zone and corrects
+	// them for its clock's offset.
+	clock *logClock
+	// name, the server's key, labels replay progress in the log.
+	name string
+
+	// lastRead is when the tailer last read new lines (or was
+	// started), as unix nanoseconds. The watchdog reads it.
+	lastRead atomic.Int64
+	// watching is set while inotify, not polling, drives the tail.
+	watching atomic.Bool
+
+	// recent is the last crashTailLines lines parsed, oldest first,
+	// for crash reports. up is set once the server is seen running and
+	// cleared by ServerShutdown; lastLineAt is the newest line's time.
+	recentMu   sync.Mutex
+	recent     []string
+	up         bool
+	lastLineAt time.Time
 }
 
-// NewLogTailer creates a new log tailer
-func NewLogTailer(path string, startAfter *time.Time) *LogTailer {
+// crashTailLines is how many log lines a crash report carries.
+const crashTailLines = 50
+
+// NewLogTailer creates a new log tailer whose Events channel holds
+// buffer events (100 if buffer <= 0).
+func NewLogTailer(path string, startAfter *time.Time, buffer int) *LogTailer {
+	if buffer <= 0 {
+		buffer = 100
+	}
 	return &LogTailer{
 		path:       path,
-		Events:     make(chan LogEvent, 100),
+		Events:     make(chan LogEvent, buffer),
 		Errors:     make(chan error, 10),
 		done:       make(chan struct{}),
 		startAfter: startAfter,
@@ -370,94 +461,270 @@ func (t *LogTailer) Start() error {
 		t.position = pos
 	}
 
+	t.lastRead.Store(time.Now().UnixNano())
 	go t.tailLoop()
 	return nil
 }
 
+// LastRead returns when the tailer last saw new lines.
+func (t *LogTailer) LastRead() time.Time {
+	return time.Unix(0, t.lastRead.Load())
+}
+
+// Reopen opens the log path afresh. If the path now names a different
+// file (rotated by rename rather than copytruncate), tailing starts at
+// the top of the new file; otherwise it resumes where it left off.
+func (t *LogTailer) Reopen() (rotated bool, err error) {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return false, fmt.Errorf("opening log file: %w", err)
+	}
+	newStat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return false, fmt.Errorf("stat file: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rotated = true
+	if t.file != nil {
+		if oldStat, err := t.file.Stat(); err == nil && os.SameFile(oldStat, newStat) {
+			rotated = false
+		}
+		t.file.Close()
+	}
+	if rotated {
+		t.position = 0
+	}
+	if _, err := file.Seek(t.position, io.SeekStart); err != nil {
+		file.Close()
+		t.file = nil
+		return rotated, fmt.Errorf("seeking: %w", err)
+	}
+	t.file = file
+	return rotated, nil
+}
+
 // ReplayFromTimestamp reads the file from the beginning and calls handler for each event.
 // Events with timestamp <= after are passed with replayMode=true (state rebuild only, no DB/events).
 // Events with timestamp > after are passed with replayMode=false (full processing).
 // This processes events synchronously to avoid database lock contention during startup.
 func (t *LogTailer) ReplayFromTimestamp(after time.Time, handler func(LogEvent, bool)) error {
-	reader := bufio.NewReader(t.file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("reading line: %w", err)
-		}
-
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	return t.ReplayFromOffset(0, after, handler)
+}
 
-		event, err := ParseLine(line)
-		if err == nil && event != nil {
-			// replayMode=true for events we've already processed (state rebuild only)
-			// replayMode=false for new events (full processing with DB/events)
-			replayMode := !event.Timestamp.After(after)
-			handler(*event, replayMode)
-		}
+// ReplayFromOffset is ReplayFromTimestamp, except that events from
+// lines within the file's first done bytes are also replay-mode.
+func (t *LogTailer) ReplayFromOffset(done int64, after time.Time, handler func(LogEvent, bool)) error {
+	var inode uint64
+	var size int64
+	if info, err := t.file.Stat(); err == nil {
+		inode, size = fileInode(info), info.Size()
 	}
-
-	// Update position to current location (end of file after replay)
-	pos, _ := t.file.Seek(0, io.SeekCurrent)
-	t.position = pos
-	return nil
+	n, err := t.replay(t.file, inode, size, after, done, handler)
+	// position now ends at the last complete line; a partial one is
+	// read by the live tail once it's finished.
+	t.position += n
+	return err
 }
 
 // Stop stops the tailer
 func (t *LogTailer) Stop() {
 	close(t.done)
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.file != nil {
 		t.file.Close()
 	}
 }
 
+// The tailer reads new lines when inotify says the log was written.
+// watchedPoll is a safety net for events that never come; if it keeps
+// finding lines no event announced (logs on NFS and some container
+// mounts don't raise them), the tailer gives up on inotify and polls
+// every pollInterval, as it does when inotify can't be set up at all.
+const (
+	pollInterval    = 100 * time.Millisecond
+	watchedPoll     = 5 * time.Second
+	maxMissedEvents = 3
+)
+
 // tailLoop continuously reads new content from the log
 func (t *LogTailer) tailLoop() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+	watcher, err := t.watch()
+	if err != nil {
+		log.Printf("Log tailer for %s: inotify unavailable (%v); polling instead", t.path, err)
+	}
+	defer func() {
+		if watcher != nil {
+			watcher.Close()
+		}
+	}()
+
+	interval := pollInterval
+	if watcher != nil {
+		interval = watchedPoll
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	var events <-chan fsnotify.Event
+	var watchErrs <-chan error
+	if watcher != nil {
+		events, watchErrs = watcher.Events, watcher.Errors
+	}
+	path := filepath.Clean(t.path)
+	// written is set by a write event and cleared by each safety poll;
+	// missed counts polls in a row that found lines with no event.
+	written, missed := false, 0
 	for {
 		select {
 		case <-t.done:
 			return
+		case ev := <-events:
+			if filepath.Clean(ev.Name) != path {
+				continue
+			}
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Rename) || ev.Has(fsnotify.Remove) {
+				t.report(t.followRotation())
+			}
+			if ev.Has(fsnotify.Write) {
+				written = true
+				_, err := t.readNewContent()
+				t.report(err)
+			}
+		case err := <-watchErrs:
+			t.report(fmt.Errorf("inotify: %w", err))
 		case <-ticker.C:
-			if err := t.readNewContent(); err != nil {
-				select {
-				case t.Errors <- err:
-				default:
-				}
+			t.report(t.followRotation())
+			n, err := t.readNewContent()
+			t.report(err)
+			if watcher == nil {
+				continue
+			}
+			if n == 0 || written {
+				written, missed = false, 0
+				continue
+			}
+			if missed++; missed >= maxMissedEvents {
+				log.Printf("Log tailer for %s: inotify isn't reporting writes; polling instead", t.path)
+				watcher.Close()
+				watcher, events, watchErrs = nil, nil, nil
+				t.watching.Store(false)
+				ticker.Reset(pollInterval)
 			}
 		}
 	}
 }
 
-// readNewContent reads any new content since last read
-func (t *LogTailer) readNewContent() error {
+// watch sets up inotify on the log's directory, so a log rotated by
+// rename is noticed when its replacement is created.
+func (t *LogTailer) watch() (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(t.path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	t.watching.Store(true)
+	return watcher, nil
+}
+
+// report passes a non-nil err on to Errors without blocking.
+func (t *LogTailer) report(err error) {
+	if err == nil {
+		return
+	}
+	select {
+	case t.Errors <- err:
+	default:
+	}
+}
+
+// followRotation switches to the file now at the log path if it isn't
+// the one being read, after reading what's left of the old one. A
+// missing path means the log was renamed away and its replacement
+// isn't there yet, so the old file is kept until it is.
+func (t *LogTailer) followRotation() error {
+	t.mu.Lock()
+	file := t.file
+	t.mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	pathStat, err := os.Stat(t.path)
+	if err != nil {
+		return nil
+	}
+	fileStat, err := file.Stat()
+	if err != nil || os.SameFile(pathStat, fileStat) {
+		return nil
+	}
+	if _, err := t.readNewContent(); err != nil {
+		return err
+	}
+	if _, err := t.Reopen(); err != nil {
+		return err
+	}
+	log.Printf("Log tailer for %s: log was rotated; now tailing the new file", t.path)
+	_, err = t.readNewContent()
+	return err
+}
+
+// Watching reports whether the tailer is woken by inotify rather than
+// polling.
+func (t *LogTailer) Watching() bool {
+	return t.watching.Load()
+}
+
+// Lag returns how many bytes the log has that the tailer hasn't read,
+// such as a line still being written.
+func (t *LogTailer) Lag() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return 0
+	}
+	stat, err := t.file.Stat()
+	if err != nil || stat.Size() < t.position {
+		return 0
+	}
+	return stat.Size() - t.position
+}
+
+// readNewContent reads any complete lines written since the last read
+// and returns how many bytes it consumed. A partial line is left for
+// the next read.
+func (t *LogTailer) readNewContent() (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return 0, nil
+	}
 	stat, err := t.file.Stat()
 	if err != nil {
-		return fmt.Errorf("stat file: %w", err)
+		return 0, fmt.Errorf("stat file: %w", err)
 	}
 
 	// Handle copytruncate: file size smaller than position
 	if stat.Size() < t.position {
 		t.position = 0
-		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
-			return fmt.Errorf("seeking to start after truncate: %w", err)
-		}
 	}
 
 	// No new content
 	if stat.Size() == t.position {
-		return nil
+		return 0, nil
+	}
+	if _, err := t.file.Seek(t.position, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seeking: %w", err)
 	}
 
 	// Read new content
+	start := t.position
+	inode := fileInode(stat)
 	reader := bufio.NewReader(t.file)
 	for {
 		line, err := reader.ReadString('\n')
@@ -466,47 +733,112 @@ func (t *LogTailer) readNewContent() error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("reading line: %w", err)
+			return t.position - start, fmt.Errorf("reading line: %w", err)
 		}
+		t.position += int64(len(line))
 
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 
-		event, err := ParseLine(line)
+		event, err := t.parse(line, true)
 		if err == nil && event != nil {
-			select {
-			case t.Events <- *event:
-			default:
-				// Channel full, drop event
-			}
+			event.inode, event.offset = inode, t.position
+			metrics.Send(t.Events, *event, metrics.ChannelLog, event.Type, highValueLogEvents[event.Type])
 		}
 	}
 
-	// Update position
-	pos, _ := t.file.Seek(0, io.SeekCurrent)
-	t.position = pos
+	if t.position > start {
+		t.lastRead.Store(time.Now().UnixNano())
+	}
+	return t.position - start, nil
+}
 
-	return nil
+// parse parses line with the tailer's clock, if it has one. A live
+// line was just written, so it also tells the clock how far off the
+// server is.
+func (t *LogTailer) parse(line string, live bool) (*LogEvent, error) {
+	if t.clock == nil {
+		event, err := ParseLine(line)
+		t.remember(line, event)
+		return event, err
+	}
+	event, err := parseLine(line, t.clock.loc)
+	if err != nil || event == nil || !event.stamped {
+		t.remember(line, event)
+		return event, err
+	}
+	if live {
+		t.clock.observe(event.Timestamp, time.Now())
+	}
+	event.Timestamp = t.clock.correct(event.Timestamp)
+	t.remember(line, event)
+	return event, nil
+}
+
+// remember adds line to the recent lines. A ServerStartup event while
+// the server is up means the last run ended without ServerShutdown;
+// its lines go on the event as ServerStartupData and are then dropped,
+// so each report covers one run.
+func (t *LogTailer) remember(line string, event *LogEvent) {
+	t.recentMu.Lock()
+	defer t.recentMu.Unlock()
+	if event != nil {
+		switch event.Type {
+		case EventTypeServerStartup:
+			if t.up {
+				event.Data = ServerStartupData{LastLineAt: t.lastLineAt, Tail: slices.Clone(t.recent)}
+			}
+			t.up = true
+			t.recent = t.recent[:0]
+		case EventTypeInitGame:
+			t.up = true
+		case EventTypeServerShutdown:
+			t.up = false
+		}
+		if event.stamped {
+			t.lastLineAt = event.Timestamp
+		}
+	}
+	if len(t.recent) == crashTailLines {
+		copy(t.recent, t.recent[1:])
+		t.recent = t.recent[:crashTailLines-1]
+	}
+	t.recent = append(t.recent, line)
+}
+
+// Recent returns the last lines read, oldest first, and when the
+// newest stamped one was logged.
+func (t *LogTailer) Recent() ([]string, time.Time) {
+	t.recentMu.Lock()
+	defer t.recentMu.Unlock()
+	return slices.Clone(t.recent), t.lastLineAt
 }
 
 // ParseLine parses a single log line into an event
 func ParseLine(line string) (*LogEvent, error) {
+	return parseLine(line, time.Local)
+}
+
+// parseLine parses line, reading a timestamp without a zone in loc.
+func parseLine(line string, loc *time.Location) (*LogEvent, error) {
 	var timestamp time.Time
 	content := line
+	stamped := false
 
 	// Try to extract ISO 8601 timestamp
 	if match := timestampRegex.FindStringSubmatch(line); match != nil {
 		// Try parsing with timezone, then without
 		ts, err := time.Parse(time.RFC3339Nano, match[1])
 		if err != nil {
-			// Try without timezone (local time format: 2006-01-02T15:04:05)
-			ts, err = time.ParseInLocation("2006-01-02T15:04:05", match[1], time.Local)
+			// Try without timezone (server local time: 2006-01-02T15:04:05)
+			ts, err = time.ParseInLocation("2006-01-02T15:04:05", match[1], loc)
 		}
 		if err == nil {
 			timestamp = ts
 			content = line[len(match[0]):]
+			stamped = true
 		}
 	}
 
@@ -516,7 +848,7 @@ func ParseLine(line string) (*LogEvent, error) {
 	}
 
 	// Try to match event patterns
-	event := &LogEvent{Timestamp: timestamp}
+	event := &LogEvent{Timestamp: timestamp, Line: line, stamped: stamped}
 
 	if match := initGameRegex.FindStringSubmatch(content); match != nil {
 		settings := parseInfoString(match[1])
@@ -856,6 +1188,23 @@ func ParseLine(line string) (*LogEvent, error) {
 		return event, nil
 	}
 
+	if match := weaponStatsRegex.FindStringSubmatch(content); match != nil {
+		clientID, _ := strconv.Atoi(match[1])
+		var weapons []WeaponShotsData
+		for _, field := range strings.Fields(match[2]) {
+			parts := strings.Split(field, ":")
+			shots, _ := strconv.Atoi(parts[1])
+			hits, _ := strconv.Atoi(parts[2])
+			weapons = append(weapons, WeaponShotsData{Weapon: parts[0], Shots: shots, Hits: hits})
+		}
+		event.Type = EventTypeWeaponStats
+		event.Data = WeaponStatsData{
+			ClientID: clientID,
+			Weapons:  weapons,
+		}
+		return event, nil
+	}
+
 	if match := sayRegex.FindStringSubmatch(content); match != nil {
 		clientID, _ := strconv.Atoi(match[1])
 		event.Type = EventTypeSay
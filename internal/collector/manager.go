@@ -5,24 +5,34 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ernie/trinity-tracker/internal/config"
 	"github.com/ernie/trinity-tracker/internal/domain"
 	"github.com/ernie/trinity-tracker/internal/hub"
+	"github.com/ernie/trinity-tracker/internal/metrics"
+	"github.com/ernie/trinity-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ServerManager orchestrates log parsing for all configured Q3 servers.
 type ServerManager struct {
-	cfg      *config.Config
+	// cfg is swapped by Reload; read it through config.
+	cfg      atomic.Pointer[config.Config]
 	server   hub.ServerClient
 	rpc      hub.RPCClient
 	pub      hub.FactPublisher
 	livePub  hub.LiveEventPublisher
+	geo      *GeoIP
+	archive  *EventArchive
 	q3client *Q3Client
+	rcon     *rconPool
 	events   chan domain.Event
 
 	// replayCutoff overrides the per-server LastMatchEndedAt boundary
@@ -31,9 +41,40 @@ type ServerManager struct {
 	// hub's watermark.
 	replayCutoff time.Time
 
-	mu              sync.RWMutex
-	servers         map[int64]*serverState
-	tailers         map[int64]*LogTailer
+	// readOnly suppresses RCON to the game servers (greets, !link
+	// replies, handshake prompts) for `trinity dry-run`, which must
+	// observe production servers without touching them.
+	readOnly bool
+
+	// importing is set by ImportLogs: matches without a UUID get one,
+	// and every match is treated as handshake-enforced.
+	importing bool
+
+	stallNotifier StallNotifier
+	crashNotifier CrashNotifier
+	unitChecker   UnitChecker
+
+	// clockDir is where each server's clock offset is saved; empty
+	// keeps them in memory. clockMu guards clockOffsets, the offsets
+	// by server key.
+	clockDir     string
+	clockMu      sync.Mutex
+	clockOffsets map[string]time.Duration
+
+	// checkpointDir is where how far each log was read is saved; empty
+	// saves nothing. checkpointMu guards checkpoints, by server key.
+	checkpointDir string
+	checkpointMu  sync.Mutex
+	checkpoints   map[string]logCheckpoint
+
+	mu      sync.RWMutex
+	servers map[int64]*serverState
+	tailers map[int64]*LogTailer
+	// reloadMu keeps Reloads from overlapping.
+	reloadMu sync.Mutex
+	// detached is when Reload stopped tailing each server, by key, so
+	// one that comes back isn't replayed from before then as new.
+	detached        map[string]time.Time
 	done            chan struct{}
 	wg              sync.WaitGroup
 	startupComplete bool
@@ -48,17 +89,28 @@ type serverState struct {
 	// the welcome), and match_start is refused for any server not
 	// setting the cvar.
 	handshakeRequired bool
-	clients          map[int]*clientState    // client ID -> client state
-	previousClients  map[string]*clientState // GUID -> accumulated stats from previous stints
-	lastInitGame     time.Time               // dedupe InitGame and skip fake ShutdownGame at same timestamp
-	matchState       string                  // "waiting", "warmup", "active", "overtime", "intermission"
-	matchStarted     bool                    // true once MatchStart has been published (at WarmupEnd)
-	matchFlushed     bool                    // true once match stats have been flushed
-	warmupDuration   int                     // warmup duration in seconds (set when warmup starts)
-	pendingExit      *string                 // exit reason from Exit event (deferred until scores captured)
-	pendingExitAt    time.Time               // timestamp of Exit event
-	pendingRedScore  *int                    // team scores captured at Exit time (before server resets)
-	pendingBlueScore *int
+	clients           map[int]*clientState    // client ID -> client state
+	previousClients   map[string]*clientState // GUID -> accumulated stats from previous stints
+	lastInitGame      time.Time               // dedupe InitGame and skip fake ShutdownGame at same timestamp
+	matchState        string                  // "waiting", "warmup", "active", "overtime", "intermission"
+	matchStarted      bool                    // true once MatchStart has been published (at WarmupEnd)
+	matchFlushed      bool                    // true once match stats have been flushed
+	warmupOver        bool                    // true once startMatch has run for this match
+	overtimeAt        time.Time               // when the match went to overtime; zero if it hasn't
+	warmupDuration    int                     // warmup duration in seconds (set when warmup starts)
+	pendingExit       *string                 // exit reason from Exit event (deferred until scores captured)
+	pendingExitAt     time.Time               // timestamp of Exit event
+	pendingRedScore   *int                    // team scores captured at Exit time (before server resets)
+	pendingBlueScore  *int
+	// pendingScoreboard is the scoreboard logged after Exit, in order.
+	pendingScoreboard []domain.MatchEndScore
+	// flagEvents is every flag pickup, drop, capture and return since
+	// warmup ended, in order.
+	flagEvents []domain.MatchEndFlagEvent
+	// warmupStats is each player's frags and deaths in the current
+	// warmup, by GUID. It survives the warmup's map_restart and goes
+	// out with match_start.
+	warmupStats map[string]*domain.WarmupPlayer
 
 	// GUIDs the collector knows have an open session on this server.
 	// Mirrors the hub's sessions table for live, on-server players;
@@ -69,6 +121,61 @@ type serverState struct {
 	// it's a genuine join: emit FactPlayerJoin and greet.
 	openSessions map[string]bool
 
+	// chatFilter is the server's moderation policy; nil when chat
+	// isn't filtered.
+	chatFilter *chatFilter
+
+	// mapVote is the !nextmap ballot; nil when voting is off.
+	mapVote *mapVote
+
+	// reservedSlots is the server's reserved slot policy; nil when
+	// it keeps none.
+	reservedSlots *config.ReservedSlotsConfig
+
+	// abandon is the server's abandoned match policy; nil when it's
+	// off. humansReached is set once the live match has had
+	// abandon.MinHumans humans, and humansLowSince is when it last
+	// fell short of them, zero while it has enough.
+	abandon        *config.AbandonConfig
+	humansReached  bool
+	humansLowSince time.Time
+
+	// teamBalance is the server's team balance policy; nil when it's
+	// off. teamScore is each team's live score this match, by team
+	// number. unevenSince is when the teams last became uneven, zero
+	// while they're even, and balancedAt is when the collector last
+	// acted on it.
+	teamBalance *config.TeamBalanceConfig
+	teamScore   [3]int
+	unevenSince time.Time
+	balancedAt  time.Time
+
+	// greetings are the server's greeting templates, from
+	// config.GreetingsFor.
+	greetings *config.GreetingsConfig
+
+	// GUIDs on the server the hub's greet said are VIPs. Persists
+	// across InitGame like openSessions.
+	vips map[string]bool
+
+	// admins are the slots whose verified session the hub's greet said
+	// is an admin's, with the GUID they were greeted under; see
+	// isAdmin. Cleared when the slot disconnects; otherwise persists
+	// like vips.
+	admins map[int]string
+
+	// ratings are the lifetime K/D of players on the server, by GUID,
+	// as the hub's greet gave them, for team balance. Persists like
+	// vips.
+	ratings map[string]float64
+
+	// commandLimiter rate-limits each player's chat commands.
+	commandLimiter *commandLimiter
+
+	// clock corrects the server's log timestamps for its zone and
+	// clock offset.
+	clock *logClock
+
 	// Trinity handshake state
 	trinityNonces    map[int]string           // map[clientNum]nonce
 	pendingGreetings map[int]*pendingGreeting // map[clientNum]greeting awaiting handshake
@@ -94,33 +201,60 @@ type clientState struct {
 	skill              float64 // bot skill level (1-5), 0 if human
 	team               int
 	joinedAt           time.Time
-	ipAddress          string          // client IP address from ClientConnect
-	began              bool            // true after ClientBegin (actually entered the game)
-	frags              int             // frags accumulated this session (flushed on leave/match end)
-	deaths             int             // deaths accumulated this session (flushed on leave/match end)
-	impressives        int             // impressive awards this match
-	excellents         int             // excellent awards this match
-	humiliations       int             // gauntlet/humiliation awards this match
-	defends            int             // defend awards this match
-	captures           int             // flag captures this match
-	flagReturns        int             // flag returns this match
-	assists            int             // assist awards this match
-	score              *int            // final score from score event at match end (nil if left early)
-	lastGauntletVictim *gauntletVictim // last gauntlet kill victim (for humiliation award)
+	ipAddress          string                        // client IP address from ClientConnect
+	began              bool                          // true after ClientBegin (actually entered the game)
+	frags              int                           // frags accumulated this session (flushed on leave/match end)
+	deaths             int                           // deaths accumulated this session (flushed on leave/match end)
+	impressives        int                           // impressive awards this match
+	excellents         int                           // excellent awards this match
+	humiliations       int                           // gauntlet/humiliation awards this match
+	defends            int                           // defend awards this match
+	captures           int                           // flag captures this match
+	flagReturns        int                           // flag returns this match
+	assists            int                           // assist awards this match
+	spree              int                           // kills since last death this match
+	bestSpree          int                           // longest spree this match
+	flagTakenAt        time.Time                     // when they picked up the flag they're carrying (zero if none)
+	fastestCap         time.Duration                 // quickest pickup-to-capture this match
+	flagCarry          time.Duration                 // total time spent carrying the enemy flag this match
+	score              *int                          // final score from score event at match end (nil if left early)
+	lastGauntletVictim *gauntletVictim               // last gauntlet kill victim (for humiliation award)
+	lastKiller         *clientState                  // who last fragged them, until they get revenge
+	weaponFrags        map[string]int                // frags on others this match, by weapon slug
+	accuracy           map[string]domain.WeaponShots // shots and hits from Weapon_Stats, by weapon slug
+	skullsScored       int                           // skulls brought to the enemy obelisk this match (Harvester)
+	obeliskDestroys    int                           // enemy obelisks destroyed this match (Overload)
+	lastActive         time.Time                     // last frag, death, chat, or team change; see lastSeenActive
+}
+
+// eventBuffer falls back to 100 when no size is configured.
+func eventBuffer(n int) int {
+	if n <= 0 {
+		return 100
+	}
+	return n
 }
 
 func NewServerManager(cfg *config.Config, server hub.ServerClient, rpc hub.RPCClient, pub hub.FactPublisher) *ServerManager {
-	return &ServerManager{
-		cfg:      cfg,
+	m := &ServerManager{
 		server:   server,
 		rpc:      rpc,
 		pub:      pub,
 		q3client: NewQ3Client(),
-		events:   make(chan domain.Event, 100),
+		events:   make(chan domain.Event, eventBuffer(cfg.Server.EventBuffers.Live)),
 		servers:  make(map[int64]*serverState),
 		tailers:  make(map[int64]*LogTailer),
+		detached: make(map[string]time.Time),
 		done:     make(chan struct{}),
 	}
+	m.rcon = newRconPool(m.done, m.rconTarget)
+	m.cfg.Store(cfg)
+	return m
+}
+
+// config returns the current configuration.
+func (m *ServerManager) config() *config.Config {
+	return m.cfg.Load()
 }
 
 // SetLivePublisher enables teeing live events onto NATS. Used in
@@ -129,6 +263,31 @@ func (m *ServerManager) SetLivePublisher(p hub.LiveEventPublisher) {
 	m.livePub = p
 }
 
+// SetGeoIP enables country/city lookups for joining players. Must be
+// called before Start. Unset (nil) publishes joins without location.
+func (m *ServerManager) SetGeoIP(g *GeoIP) {
+	m.geo = g
+}
+
+// SetEventArchive enables the raw event archive. Must be called
+// before Start. Unset (nil) archives nothing.
+func (m *ServerManager) SetEventArchive(a *EventArchive) {
+	m.archive = a
+}
+
+// SetReadOnly stops the manager from sending RCON commands; each one
+// is logged instead. Must be called before Start.
+func (m *ServerManager) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// SetClockDir saves each server's detected clock offset in dir, so a
+// restart replays logs with the same correction. Must be called before
+// Start. Unset keeps offsets in memory only.
+func (m *ServerManager) SetClockDir(dir string) {
+	m.clockDir = dir
+}
+
 // SetReplayCutoff pins the replay/live boundary for every tailed
 // server. Must be called before Start. Zero uses each server's
 // LastMatchEndedAt.
@@ -143,38 +302,51 @@ func (m *ServerManager) Events() <-chan domain.Event {
 
 // Start initializes all servers and begins polling
 func (m *ServerManager) Start(ctx context.Context) error {
-	source := ""
-	if m.cfg.Tracker != nil && m.cfg.Tracker.Collector != nil {
-		source = m.cfg.Tracker.Collector.SourceID
+	source := m.sourceID()
+	m.clockOffsets = map[string]time.Duration{}
+	if m.clockDir != "" {
+		offsets, err := loadClockOffsets(m.clockDir)
+		if err != nil {
+			log.Printf("Warning: clock offsets: %v", err)
+		} else {
+			m.clockOffsets = offsets
+		}
 	}
-	for _, srv := range m.cfg.Q3Servers {
-		fullSrv, err := m.server.RegisterServer(ctx, source, srv.Key, srv.Address)
+	m.checkpoints = map[string]logCheckpoint{}
+	if m.checkpointDir != "" {
+		checkpoints, err := loadLogCheckpoints(m.checkpointDir)
 		if err != nil {
-			return err
+			log.Printf("Warning: log checkpoints: %v", err)
+		} else {
+			m.checkpoints = checkpoints
 		}
-
-		m.servers[fullSrv.ID] = &serverState{
-			server:        *fullSrv,
-			clients:       make(map[int]*clientState),
-			trinityNonces: make(map[int]string),
-			openSessions:  make(map[string]bool),
-		}
-
-		// Serial replay: concurrent tailers fight for the SQLite write lock.
-		if srv.LogPath != "" {
-			startAfter := m.cutoffFor(&srv, fullSrv)
-			serverID := fullSrv.ID
-			if !m.attachTailer(ctx, srv.Key, srv.LogPath, serverID, startAfter) {
-				// Log file isn't there yet — common race when
-				// trinity.service starts before quake3-server@.service
-				// has had a chance to create the file. Poll for it in
-				// the background; the tailer attaches as soon as it
-				// shows up.
-				log.Printf("Log file for %s not yet available (%s); retrying in background", srv.Key, srv.LogPath)
-				m.wg.Add(1)
-				go m.tailWhenReady(ctx, srv.Key, srv.LogPath, serverID, startAfter)
-			}
+	}
+	// Register in config order, so new servers are numbered as they're
+	// listed, then replay the logs side by side.
+	servers := m.config().Q3Servers
+	registered := make([]*domain.Server, len(servers))
+	for i, srv := range servers {
+		fullSrv, err := m.registerServer(ctx, source, srv)
+		if err != nil {
+			return err
 		}
+		registered[i] = fullSrv
+	}
+	started := time.Now()
+	workers := make(chan struct{}, replayWorkers())
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			m.tailServer(ctx, srv, registered[i], time.Time{})
+		}()
+	}
+	wg.Wait()
+	if len(servers) > 1 {
+		log.Printf("Replayed %d server logs in %v", len(servers), time.Since(started).Round(time.Millisecond))
 	}
 
 	m.mu.Lock()
@@ -182,6 +354,9 @@ func (m *ServerManager) Start(ctx context.Context) error {
 	m.mu.Unlock()
 	log.Printf("Startup complete, !link commands now enabled")
 
+	m.wg.Add(1)
+	go m.watchdogLoop(ctx)
+
 	return nil
 }
 
@@ -193,11 +368,11 @@ func (m *ServerManager) Start(ctx context.Context) error {
 const freshLogThreshold = 10 << 20 // 10 MB
 
 // cutoffFor picks the replay cutoff for one server. Precedence:
-//   1. Global m.replayCutoff (NATS publisher watermark) when set —
-//      authoritative because it says "the hub already has up to this".
-//   2. fullSrv.LastMatchEndedAt — hub-side per-server watermark.
-//   3. File-size heuristic: small log = fresh, replay everything;
-//      large log = retrofit, skip history (cutoff = now()).
+//  1. Global m.replayCutoff (NATS publisher watermark) when set —
+//     authoritative because it says "the hub already has up to this".
+//  2. fullSrv.LastMatchEndedAt — hub-side per-server watermark.
+//  3. File-size heuristic: small log = fresh, replay everything;
+//     large log = retrofit, skip history (cutoff = now()).
 func (m *ServerManager) cutoffFor(srvCfg *config.Q3Server, fullSrv *domain.Server) time.Time {
 	if !m.replayCutoff.IsZero() {
 		return m.replayCutoff
@@ -211,6 +386,100 @@ func (m *ServerManager) cutoffFor(srvCfg *config.Q3Server, fullSrv *domain.Serve
 	return time.Time{} // epoch — replay everything as live
 }
 
+// sourceID is the collector's source, or "" for a local collector.
+func (m *ServerManager) sourceID() string {
+	if cfg := m.config(); cfg.Tracker != nil && cfg.Tracker.Collector != nil {
+		return cfg.Tracker.Collector.SourceID
+	}
+	return ""
+}
+
+// replayWorkers is how many servers' logs Start replays at once.
+// Reading and parsing, the bulk of a replay, run in parallel; the
+// events themselves are still handled one at a time under m.mu. Two at
+// least, so one log's disk reads overlap another's parsing even on a
+// single core.
+func replayWorkers() int {
+	return max(runtime.GOMAXPROCS(0), 2)
+}
+
+// startServer registers srv with the hub and starts tailing its log.
+// The log is replayed as new from cutoffFor's cutoff, or from
+// notBefore if that's later.
+func (m *ServerManager) startServer(ctx context.Context, source string, srv config.Q3Server, notBefore time.Time) error {
+	fullSrv, err := m.registerServer(ctx, source, srv)
+	if err != nil {
+		return err
+	}
+	m.tailServer(ctx, srv, fullSrv, notBefore)
+	return nil
+}
+
+// registerServer registers srv with the hub and sets up its state.
+func (m *ServerManager) registerServer(ctx context.Context, source string, srv config.Q3Server) (*domain.Server, error) {
+	fullSrv, err := m.server.RegisterServer(ctx, source, srv.Key, srv.Address)
+	if err != nil {
+		return nil, err
+	}
+	m.clockMu.Lock()
+	clock := newLogClock(srv.Location(), m.clockOffsets[srv.Key])
+	m.clockMu.Unlock()
+	key := srv.Key
+	clock.onChange = func(offset time.Duration) { m.clockChanged(key, offset) }
+
+	m.mu.Lock()
+	m.servers[fullSrv.ID] = &serverState{
+		server:         *fullSrv,
+		clients:        make(map[int]*clientState),
+		trinityNonces:  make(map[int]string),
+		openSessions:   make(map[string]bool),
+		chatFilter:     newChatFilter(m.config().ModerationFor(srv)),
+		mapVote:        newMapVote(srv.MapVotePolicy()),
+		reservedSlots:  srv.ReservedSlotsPolicy(),
+		abandon:        srv.AbandonPolicy(),
+		teamBalance:    srv.TeamBalancePolicy(),
+		greetings:      m.config().GreetingsFor(srv),
+		vips:           make(map[string]bool),
+		admins:         make(map[int]string),
+		ratings:        make(map[string]float64),
+		commandLimiter: newCommandLimiter(),
+		clock:          clock,
+	}
+	m.mu.Unlock()
+	return fullSrv, nil
+}
+
+// tailServer replays fullSrv's log as startServer describes and starts
+// tailing it. Query-only servers have no log; the hub polls them.
+func (m *ServerManager) tailServer(ctx context.Context, srv config.Q3Server, fullSrv *domain.Server, notBefore time.Time) {
+	if srv.LogPath != "" && !srv.QueryOnly {
+		from := replayStart{cutoff: m.cutoffFor(&srv, fullSrv), resumeCutoff: m.replayCutoff}
+		if notBefore.After(from.cutoff) {
+			from.cutoff = notBefore
+		}
+		if notBefore.After(from.resumeCutoff) {
+			from.resumeCutoff = notBefore
+		}
+		// A match closed without a match_end (match_crashed) moves
+		// last_match_ended_at but not the cursor; then the cursor is
+		// stale.
+		if c := fullSrv.LogCursor; c != nil && (fullSrv.LastMatchEndedAt == nil || !fullSrv.LastMatchEndedAt.After(c.At)) {
+			from.cursor = c
+		}
+		serverID := fullSrv.ID
+		if !m.attachTailer(ctx, srv.Key, srv.LogPath, serverID, from) {
+			// Log file isn't there yet — common race when
+			// trinity.service starts before quake3-server@.service
+			// has had a chance to create the file. Poll for it in
+			// the background; the tailer attaches as soon as it
+			// shows up.
+			log.Printf("Log file for %s not yet available (%s); retrying in background", srv.Key, srv.LogPath)
+			m.wg.Add(1)
+			go m.tailWhenReady(ctx, srv.Key, srv.LogPath, serverID, from)
+		}
+	}
+}
+
 // bootstrapServerPresence publishes a PresenceSnapshot for every
 // currently-tracked, began client on one server. Called from
 // attachTailer after replay completes — by then state.clients is
@@ -304,6 +573,7 @@ func (m *ServerManager) Stop() {
 		tailers = append(tailers, t)
 	}
 	m.mu.RUnlock()
+	m.saveCheckpoints()
 	for _, tailer := range tailers {
 		tailer.Stop()
 	}
@@ -311,7 +581,8 @@ func (m *ServerManager) Stop() {
 	log.Println("ServerManager: shutdown complete")
 }
 
-// attachTailer opens the log file, replays from startAfter, publishes
+// attachTailer opens the log file, replays any rotated logs holding
+// lines it missed (see replayRotated), replays the log as from says, publishes
 // a presence bootstrap snapshot for everything that ended up in
 // state.clients, and starts the live tail. Returns false if the log
 // file can't be opened (caller can decide to schedule a retry); returns
@@ -323,17 +594,36 @@ func (m *ServerManager) Stop() {
 // processLogEvents goroutine. That places the snapshot ahead of any
 // new live events on the wire, so the hub sees a consistent slot
 // state before live updates start arriving.
-func (m *ServerManager) attachTailer(ctx context.Context, key, path string, serverID int64, startAfter time.Time) bool {
-	tailer := NewLogTailer(path, nil)
+func (m *ServerManager) attachTailer(ctx context.Context, key, path string, serverID int64, from replayStart) bool {
+	tailer := NewLogTailer(path, nil, m.config().Server.EventBuffers.Log)
+	m.mu.RLock()
+	state, ok := m.servers[serverID]
+	m.mu.RUnlock()
+	if !ok {
+		return true // dropped by Reload while waiting for the file
+	}
+	tailer.clock = state.clock
+	tailer.name = key
 	if _, err := tailer.OpenFile(); err != nil {
 		return false
 	}
-	log.Printf("Replaying log for %s from %v", key, startAfter)
-	if err := tailer.ReplayFromTimestamp(startAfter, func(event LogEvent, replayMode bool) {
+	handler := func(event LogEvent, replayMode bool) {
 		m.handleLogEvent(ctx, serverID, event, replayMode)
-	}); err != nil {
+	}
+	started := time.Now()
+	var err error
+	if from.cursor != nil && tailer.resumes(*from.cursor) {
+		log.Printf("Replaying log for %s after the match that ended at %v", key, from.cursor.At)
+		err = tailer.ReplayFromOffset(from.cursor.Offset, from.resumeCutoff, handler)
+	} else {
+		m.replayRotated(key, tailer, from.cutoff, handler)
+		log.Printf("Replaying log for %s from %v", key, from.cutoff)
+		err = tailer.ReplayFromTimestamp(from.cutoff, handler)
+	}
+	if err != nil {
 		log.Printf("Warning: failed to replay log for %s: %v", key, err)
 	}
+	log.Printf("Replayed log for %s in %v", key, time.Since(started).Round(time.Millisecond))
 	m.bootstrapServerPresence(serverID)
 	if err := tailer.Start(); err != nil {
 		log.Printf("Warning: failed to start log tailer for %s: %v", key, err)
@@ -351,18 +641,41 @@ func (m *ServerManager) attachTailer(ctx context.Context, key, path string, serv
 		return true
 	default:
 	}
+	// Likewise if Reload dropped the server during replay.
+	if _, ok := m.servers[serverID]; !ok {
+		m.mu.Unlock()
+		tailer.Stop()
+		return true
+	}
 	m.tailers[serverID] = tailer
 	m.mu.Unlock()
+	metrics.LogTails.Set(key, func() metrics.TailStat {
+		return metrics.TailStat{LagBytes: tailer.Lag(), LastRead: tailer.LastRead(), Watching: tailer.Watching()}
+	})
 	m.wg.Add(1)
 	go m.processLogEvents(ctx, serverID, tailer)
 	return true
 }
 
+// clockChanged records a server's new clock offset.
+func (m *ServerManager) clockChanged(key string, offset time.Duration) {
+	log.Printf("Clock on %s is %v off; correcting its log timestamps", key, offset)
+	m.clockMu.Lock()
+	defer m.clockMu.Unlock()
+	m.clockOffsets[key] = offset
+	if m.clockDir == "" {
+		return
+	}
+	if err := saveClockOffsets(m.clockDir, m.clockOffsets); err != nil {
+		log.Printf("Warning: saving clock offsets: %v", err)
+	}
+}
+
 // tailWhenReady polls for the log file and attaches the tailer as soon
 // as the q3 server creates it. Most operators see this fire only on
 // fresh installs where trinity.service starts before quake3-server@
 // has written its first log line.
-func (m *ServerManager) tailWhenReady(ctx context.Context, key, path string, serverID int64, startAfter time.Time) {
+func (m *ServerManager) tailWhenReady(ctx context.Context, key, path string, serverID int64, from replayStart) {
 	defer m.wg.Done()
 	const interval = 3 * time.Second
 	ticker := time.NewTicker(interval)
@@ -375,37 +688,60 @@ func (m *ServerManager) tailWhenReady(ctx context.Context, key, path string, ser
 			return
 		case <-ticker.C:
 		}
-		if m.attachTailer(ctx, key, path, serverID, startAfter) {
+		if m.attachTailer(ctx, key, path, serverID, from) {
 			return
 		}
 	}
 }
 
 // GetServerStatus returns the current status for a server
-// ExecuteRcon sends an RCON command to a server and returns the response
+// ExecuteRcon sends an RCON command to a server and returns the response.
+// It waits behind any commands already queued for the server.
 func (m *ServerManager) ExecuteRcon(serverID int64, command string) (string, error) {
+	return m.rcon.exec(serverID, command)
+}
+
+// queueRcon queues an RCON command for a server without waiting for
+// the reply. Commands queued one after another are sent in order.
+// Failures are logged. Safe to call while holding m.mu.
+func (m *ServerManager) queueRcon(serverID int64, command string) {
+	err := m.rcon.queue(serverID, command, func(_ string, err error) {
+		if err != nil {
+			log.Printf("rcon to server %d failed: %q: %v", serverID, command, err)
+		}
+	})
+	if err != nil {
+		log.Printf("rcon to server %d failed: %q: %v", serverID, command, err)
+	}
+}
+
+// rconTarget looks up the address and RCON password for a server. In
+// dry-run it logs command and returns an empty address.
+func (m *ServerManager) rconTarget(serverID int64, command string) (address, password string, err error) {
 	m.mu.RLock()
 	state, ok := m.servers[serverID]
 	m.mu.RUnlock()
 
 	if !ok {
-		return "", fmt.Errorf("server not found")
+		return "", "", fmt.Errorf("server not found")
+	}
+	if m.readOnly {
+		log.Printf("dry-run: would rcon %s: %q", state.server.Key, command)
+		return "", "", nil
 	}
 
 	// Find RCON password from config
-	var rconPassword string
-	for _, srv := range m.cfg.Q3Servers {
+	for _, srv := range m.config().Q3Servers {
 		if srv.Address == state.server.Address {
-			rconPassword = srv.RconPassword
+			password = srv.RconPassword
 			break
 		}
 	}
 
-	if rconPassword == "" {
-		return "", fmt.Errorf("RCON not configured for this server")
+	if password == "" {
+		return "", "", fmt.Errorf("RCON not configured for this server")
 	}
-
-	return m.q3client.RconCommand(state.server.Address, rconPassword, command)
+	return state.server.Address, password, nil
 }
 
 // HasRconAccess checks if a server has RCON configured
@@ -418,7 +754,7 @@ func (m *ServerManager) HasRconAccess(serverID int64) bool {
 		return false
 	}
 
-	for _, srv := range m.cfg.Q3Servers {
+	for _, srv := range m.config().Q3Servers {
 		if srv.Address == state.server.Address && srv.RconPassword != "" {
 			return true
 		}
@@ -432,10 +768,11 @@ func (m *ServerManager) HasRconAccess(serverID int64) bool {
 // the collector resolves locally.
 func (m *ServerManager) ExecuteRconByKey(key, command string) (string, error) {
 	m.mu.RLock()
+	var serverID int64
 	var address string
-	for _, state := range m.servers {
+	for id, state := range m.servers {
 		if strings.EqualFold(state.server.Key, key) {
-			address = state.server.Address
+			serverID, address = id, state.server.Address
 			break
 		}
 	}
@@ -444,7 +781,7 @@ func (m *ServerManager) ExecuteRconByKey(key, command string) (string, error) {
 		return "", fmt.Errorf("server %q not found", key)
 	}
 	var rconPassword string
-	for _, srv := range m.cfg.Q3Servers {
+	for _, srv := range m.config().Q3Servers {
 		if srv.Address == address {
 			rconPassword = srv.RconPassword
 			break
@@ -453,7 +790,7 @@ func (m *ServerManager) ExecuteRconByKey(key, command string) (string, error) {
 	if rconPassword == "" {
 		return "", fmt.Errorf("rcon not configured for server %q", key)
 	}
-	return m.q3client.RconCommand(address, rconPassword, command)
+	return m.rcon.exec(serverID, command)
 }
 
 // AdminDelegationFor returns whether the operator opted this server
@@ -473,7 +810,7 @@ func (m *ServerManager) AdminDelegationFor(key string) bool {
 	if address == "" {
 		return false
 	}
-	for _, srv := range m.cfg.Q3Servers {
+	for _, srv := range m.config().Q3Servers {
 		if srv.Address == address {
 			return srv.AllowHubAdminRcon
 		}
@@ -481,19 +818,38 @@ func (m *ServerManager) AdminDelegationFor(key string) bool {
 	return false
 }
 
+// SetCommandsDisabled switches chat command handling for the server
+// with key, as told by the hub. Reports false if there's no such
+// server.
+func (m *ServerManager) SetCommandsDisabled(key string, disabled bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, state := range m.servers {
+		if strings.EqualFold(state.server.Key, key) {
+			state.server.CommandsDisabled = disabled
+			log.Printf("Chat commands on %s: disabled=%v", key, disabled)
+			return true
+		}
+	}
+	return false
+}
+
 // Roster returns the current list of registered servers as RegdServer
 // entries. Used by the distributed-tracking Registrar to broadcast the
 // collector's roster on heartbeat. AdminDelegationEnabled mirrors the
 // per-server config flag so the hub UI can decide whether a hub admin
-// gets the click-to-RCON affordance.
+// gets the click-to-RCON affordance; QueryOnly tells the hub to poll
+// the server without waiting on the handshake.
 func (m *ServerManager) Roster() []domain.RegdServer {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	// Resolve cfg flag by address (cfg.Q3Servers and m.servers both key
 	// off Address; see ExecuteRcon below for the same lookup pattern).
-	delegationByAddress := make(map[string]bool, len(m.cfg.Q3Servers))
-	for _, srv := range m.cfg.Q3Servers {
+	delegationByAddress := make(map[string]bool, len(m.config().Q3Servers))
+	queryOnlyByAddress := make(map[string]bool, len(m.config().Q3Servers))
+	for _, srv := range m.config().Q3Servers {
 		delegationByAddress[srv.Address] = srv.AllowHubAdminRcon
+		queryOnlyByAddress[srv.Address] = srv.QueryOnly
 	}
 	out := make([]domain.RegdServer, 0, len(m.servers))
 	for _, state := range m.servers {
@@ -502,6 +858,8 @@ func (m *ServerManager) Roster() []domain.RegdServer {
 			Key:                    state.server.Key,
 			Address:                state.server.Address,
 			AdminDelegationEnabled: delegationByAddress[state.server.Address],
+			QueryOnly:              queryOnlyByAddress[state.server.Address],
+			ClockOffsetMs:          state.clock.Offset().Milliseconds(),
 		})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].LocalID < out[j].LocalID })
@@ -516,6 +874,8 @@ func (m *ServerManager) processLogEvents(ctx context.Context, serverID int64, ta
 		select {
 		case <-m.done:
 			return
+		case <-tailer.done:
+			return
 		case err := <-tailer.Errors:
 			log.Printf("Log tailer error for server %d: %v", serverID, err)
 		case event := <-tailer.Events:
@@ -535,54 +895,25 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 	if !ok {
 		return
 	}
+	// Replayed events went into the archive the first time round, and
+	// aren't traced: a replay is a burst of them nobody is waiting on.
+	if !replayMode {
+		m.archive.Append(state.server.Key, event)
+		var span trace.Span
+		ctx, span = tracing.Tracer().Start(ctx, "log "+event.Type,
+			trace.WithAttributes(attribute.String("trinity.server", state.server.Key)))
+		defer span.End()
+	}
 
 	switch event.Type {
 	case EventTypeInitGame:
 		data := event.Data.(InitGameData)
-		m.handleMatchChange(ctx, state, data.MapName, data.GameType, data.UUID, data.Settings["g_movement"], data.Settings["g_gameplay"], data.Settings["g_trinityhandshake"] == "1", event.Timestamp, replayMode)
+		m.handleMatchChange(ctx, state, data.MapName, data.GameType, data.UUID, data.Settings["g_movement"], data.Settings["g_gameplay"], data.Settings["g_trinityhandshake"] == "1", event.Timestamp, logCursor(event), replayMode)
 
 	case EventTypeWarmupEnd:
-		if state.match != nil {
-			state.match.StartedAt = event.Timestamp
-			if state.match.UUID != "" {
-				switch {
-				case replayMode && state.handshakeRequired:
-					// Let a post-restart Shutdown fire match_end against
-					// the hub's existing (pre-watermark) match row.
-					state.matchStarted = true
-				case !replayMode:
-					// Publish match_start regardless of g_trinityHandshake so
-					// the hub's handshake_required column tracks the cvar in
-					// both directions. The hub still gates persistence on
-					// HandshakeRequired, so non-handshake matches don't
-					// produce stats — but the server's UI visibility flips
-					// off without manual intervention. matchStarted gates
-					// match_end below; we only set it when the hub created
-					// a match row, so we don't fire match_end against a
-					// UUID the hub never persisted.
-					m.pub.Publish(domain.FactEvent{
-						Type:      domain.FactMatchStart,
-						ServerID:  state.server.ID,
-						Timestamp: event.Timestamp,
-						Data: domain.MatchStartData{
-							MatchUUID:         state.match.UUID,
-							MapName:           state.match.MapName,
-							GameType:          state.match.GameType,
-							Movement:          state.match.Movement,
-							Gameplay:          state.match.Gameplay,
-							StartedAt:         event.Timestamp,
-							HandshakeRequired: state.handshakeRequired,
-						},
-					})
-					if state.handshakeRequired {
-						state.matchStarted = true
-					} else {
-						log.Printf("collector: match_start for %s on server %s/%s with g_trinityHandshake not enabled — hub will reject stats and downgrade the server", state.match.UUID, state.server.Source, state.server.Key)
-					}
-				}
-			}
+		if !state.warmupOver {
+			m.startMatch(state, event.Timestamp, replayMode)
 		}
-		state.matchState = "active"
 
 	case EventTypeWarmup:
 		data := event.Data.(WarmupData)
@@ -591,7 +922,16 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 	case EventTypeMatchState:
 		data := event.Data.(MatchStateData)
+		// With g_doWarmup off there's no warmup and no WarmupEnd: the
+		// match goes straight to active, so that starts it. A server
+		// that did warm up waits for WarmupEnd.
+		if data.State == "active" && !state.warmupOver && state.warmupDuration == 0 && state.matchState != "warmup" {
+			m.startMatch(state, event.Timestamp, replayMode)
+		}
 		state.matchState = data.State
+		if data.State == "overtime" {
+			state.beginOvertime(event.Timestamp)
+		}
 		if data.State == "warmup" && data.Duration > 0 {
 			state.warmupDuration = data.Duration
 		}
@@ -601,27 +941,42 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		// never published (e.g. non-handshake server), the hub has no
 		// match row to attach match_end to. Skip in replay mode — any
 		// match_end for a pre-watermark match was already sent on its
-		// original run; republishing would flood the hub.
-		if data.State == "intermission" && !replayMode && state.pendingExit != nil &&
+		// original run; republishing would flood the hub. It still
+		// counts as flushed, so a live ShutdownGame after the replay
+		// boundary doesn't send it again.
+		if data.State == "intermission" && state.pendingExit != nil &&
 			state.match != nil && state.match.UUID != "" &&
 			state.matchStarted && !state.matchFlushed && state.match.EndedAt == nil {
-			players := m.buildMatchEndPlayers(state, true)
-			m.pub.Publish(domain.FactEvent{
-				Type:      domain.FactMatchEnd,
-				ServerID:  state.server.ID,
-				Timestamp: state.pendingExitAt,
-				Data: domain.MatchEndData{
-					MatchUUID:  state.match.UUID,
-					EndedAt:    state.pendingExitAt,
-					ExitReason: *state.pendingExit,
-					RedScore:   state.pendingRedScore,
-					BlueScore:  state.pendingBlueScore,
-					Players:    players,
-				},
-			})
+			if !replayMode {
+				players := m.buildMatchEndPlayers(state, true)
+				m.pub.Publish(domain.FactEvent{
+					Type:      domain.FactMatchEnd,
+					ServerID:  state.server.ID,
+					Timestamp: state.pendingExitAt,
+					Data: domain.MatchEndData{
+						MatchUUID:  state.match.UUID,
+						EndedAt:    state.pendingExitAt,
+						ExitReason: *state.pendingExit,
+						Abandoned:  matchAbandoned(state, state.pendingExitAt),
+						Overtime:   state.overtimeSeconds(state.pendingExitAt),
+						RedScore:   state.pendingRedScore,
+						BlueScore:  state.pendingBlueScore,
+						Players:    players,
+						Scoreboard: state.pendingScoreboard,
+						FlagEvents: state.flagEvents,
+						Cursor:     logCursor(event),
+					},
+				})
+			}
 			state.matchFlushed = true
 		}
 
+	case EventTypeBroadcast:
+		data := event.Data.(BroadcastData)
+		if (state.matchState == "active" || state.matchState == "overtime") && isOvertimeBroadcast(data.Message) {
+			state.beginOvertime(event.Timestamp)
+		}
+
 	case EventTypeClientConnect:
 		data := event.Data.(ClientConnectData)
 		initialScore := 0
@@ -709,6 +1064,7 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		data := event.Data.(ClientConnectData)
 		if client, ok := state.clients[data.ClientID]; ok {
 			client.began = true
+			trackAbandon(state, event.Timestamp)
 
 			// A Begin is either a genuine fresh join or a map-change
 			// continuation. The collector mirrors the hub's session
@@ -734,21 +1090,28 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 					})
 				} else {
 					state.openSessions[client.guid] = true
+					var loc GeoLocation
+					if !client.isBot {
+						loc = m.geo.Lookup(client.ipAddress)
+					}
 					m.pub.Publish(domain.FactEvent{
 						Type:      domain.FactPlayerJoin,
 						ServerID:  serverID,
 						Timestamp: event.Timestamp,
 						Data: domain.PlayerJoinData{
-							GUID:      client.guid,
-							Name:      client.name,
-							CleanName: client.cleanName,
-							Model:     client.model,
-							IP:        client.ipAddress,
-							IsBot:     client.isBot,
-							IsVR:      client.isVR,
-							Skill:     client.skill,
-							JoinedAt:  event.Timestamp,
-							ClientNum: data.ClientID,
+							GUID:        client.guid,
+							Name:        client.name,
+							CleanName:   client.cleanName,
+							Model:       client.model,
+							IP:          client.ipAddress,
+							CountryCode: loc.CountryCode,
+							Country:     loc.Country,
+							City:        loc.City,
+							IsBot:       client.isBot,
+							IsVR:        client.isVR,
+							Skill:       client.skill,
+							JoinedAt:    event.Timestamp,
+							ClientNum:   data.ClientID,
 						},
 					})
 					// Greet only humans; bots' synthetic GUID passes
@@ -829,8 +1192,11 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 			if client.guid != "" {
 				delete(state.openSessions, client.guid)
+				delete(state.vips, client.guid)
+				delete(state.ratings, client.guid)
 			}
 			delete(state.trinityNonces, data.ClientID)
+			delete(state.admins, data.ClientID)
 			if state.pendingGreetings != nil {
 				if pg, ok := state.pendingGreetings[data.ClientID]; ok {
 					pg.timer.Stop()
@@ -839,22 +1205,71 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 			}
 
 			delete(state.clients, data.ClientID)
+			if state.mapVote != nil {
+				delete(state.mapVote.votes, data.ClientID)
+			}
+			trackAbandon(state, event.Timestamp)
 		}
 
 	case EventTypeFrag:
 		data := event.Data.(FragEventData)
 
+		// Kill feed extras, read before the counters below move.
+		fragger, hasFragger := state.clients[data.FraggerID]
+		victim, hasVictim := state.clients[data.VictimID]
+		var revenge bool
+		var endedSpree, fraggerTeam, victimTeam int
+		if hasFragger && hasVictim && data.FraggerID != data.VictimID {
+			revenge = fragger.lastKiller == victim
+			if victim.spree >= domain.KillFeedSpree {
+				endedSpree = victim.spree
+			}
+		}
+		if hasFragger {
+			fraggerTeam = fragger.team // 0 (free) outside team games
+			fragger.lastActive = event.Timestamp
+		}
+		if hasVictim {
+			victimTeam = victim.team
+			victim.lastActive = event.Timestamp
+		}
+
+		if state.matchState == "warmup" {
+			state.countWarmupFrag(fragger, victim)
+		}
+
 		// Only track frags/deaths during active gameplay (not warmup/waiting/intermission)
 		// Note: We track stats even during replay so we can flush them if match wasn't completed
 		if state.matchState == "active" || state.matchState == "overtime" {
+			state.scoreFrag(fragger, victim)
+			if hasFragger && hasVictim && data.FraggerID != data.VictimID {
+				victim.lastKiller = fragger
+				if revenge {
+					fragger.lastKiller = nil
+				}
+				if fragger.weaponFrags == nil {
+					fragger.weaponFrags = make(map[string]int)
+				}
+				fragger.weaponFrags[domain.WeaponSlug(data.Weapon)]++
+			}
+
 			// Increment in-memory frag count for fragger (human or bot)
 			if fragger, ok := state.clients[data.FraggerID]; ok {
 				fragger.frags++
+				// Suicides count against frags in the log but don't
+				// extend a spree; the death below ends it anyway.
+				if data.FraggerID != data.VictimID {
+					fragger.spree++
+					if fragger.spree > fragger.bestSpree {
+						fragger.bestSpree = fragger.spree
+					}
+				}
 			}
 
 			// Increment in-memory death count for victim (human or bot)
 			if victim, ok := state.clients[data.VictimID]; ok {
 				victim.deaths++
+				victim.spree = 0
 			}
 
 			// Track gauntlet frag victim for humiliation award (MOD_GAUNTLET = 2)
@@ -888,6 +1303,11 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 					Fragger:     data.FraggerName,
 					Victim:      data.VictimName,
 					Weapon:      data.Weapon,
+					WeaponSlug:  domain.WeaponSlug(data.Weapon),
+					FraggerTeam: fraggerTeam,
+					VictimTeam:  victimTeam,
+					Revenge:     revenge,
+					EndedSpree:  endedSpree,
 					FraggerGUID: fraggerGUID,
 					VictimGUID:  victimGUID,
 				},
@@ -897,10 +1317,30 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 	case EventTypeScore:
 		// Capture score and team at match end (score events fire during Exit sequence)
 		data := event.Data.(ScoreEventData)
+		line := domain.MatchEndScore{ClientID: data.ClientID, Name: data.Name, Score: data.Score, Ping: data.Ping, Team: data.Team}
 		if client, ok := state.clients[data.ClientID]; ok {
 			score := data.Score
 			client.score = &score
 			client.team = data.Team
+			line.GUID = client.guid
+		}
+		if state.pendingExit != nil {
+			state.pendingScoreboard = append(state.pendingScoreboard, line)
+		}
+
+	case EventTypeWeaponStats:
+		// Logged once per client during the Exit sequence, with totals
+		// for the whole match, so it replaces rather than adds.
+		data := event.Data.(WeaponStatsData)
+		if client, ok := state.clients[data.ClientID]; ok {
+			client.accuracy = make(map[string]domain.WeaponShots)
+			for _, w := range data.Weapons {
+				slug := domain.WeaponSlug(w.Weapon)
+				shots := client.accuracy[slug]
+				shots.Shots += w.Shots
+				shots.Hits += w.Hits
+				client.accuracy[slug] = shots
+			}
 		}
 
 	case EventTypeExit:
@@ -914,6 +1354,11 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 			state.pendingRedScore = data.RedScore
 			state.pendingBlueScore = data.BlueScore
+			state.pendingScoreboard = nil
+
+			if !replayMode && m.startupComplete {
+				m.finishMapVote(serverID, state)
+			}
 
 			if !replayMode {
 				m.emitEvent(domain.Event{
@@ -947,9 +1392,14 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 							MatchUUID:  state.match.UUID,
 							EndedAt:    state.pendingExitAt,
 							ExitReason: *state.pendingExit,
+							Abandoned:  matchAbandoned(state, state.pendingExitAt),
+							Overtime:   state.overtimeSeconds(state.pendingExitAt),
 							RedScore:   state.pendingRedScore,
 							BlueScore:  state.pendingBlueScore,
 							Players:    m.buildMatchEndPlayers(state, true),
+							Scoreboard: state.pendingScoreboard,
+							FlagEvents: state.flagEvents,
+							Cursor:     logCursor(event),
 						},
 					})
 				} else {
@@ -962,7 +1412,11 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 							MatchUUID:  state.match.UUID,
 							EndedAt:    event.Timestamp,
 							ExitReason: "shutdown",
+							Abandoned:  matchAbandoned(state, event.Timestamp),
+							Overtime:   state.overtimeSeconds(event.Timestamp),
 							Players:    m.buildMatchEndPlayers(state, false),
+							FlagEvents: state.flagEvents,
+							Cursor:     logCursor(event),
 						},
 					})
 				}
@@ -973,19 +1427,31 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		}
 		state.match = nil
 		state.matchStarted = false
+		state.warmupOver = false
 		state.matchFlushed = false
 		state.pendingExit = nil
 		state.pendingRedScore = nil
 		state.pendingBlueScore = nil
+		state.pendingScoreboard = nil
 		state.clients = make(map[int]*clientState)
 		state.previousClients = make(map[string]*clientState)
+		state.flagEvents = nil
+		resetAbandon(state)
 
 	case EventTypeFlagCapture:
 		data := event.Data.(FlagCaptureData)
 		// Track capture in memory for real-time display
 		if client, ok := state.clients[data.ClientID]; ok {
 			client.captures++
+			state.scoreTeam(client.team, 1)
+			if !client.flagTakenAt.IsZero() {
+				if d := event.Timestamp.Sub(client.flagTakenAt); d > 0 && (client.fastestCap == 0 || d < client.fastestCap) {
+					client.fastestCap = d
+				}
+			}
+			client.endFlagCarry(event.Timestamp)
 		}
+		state.recordFlagEvent(event.Timestamp, domain.FlagEventCaptured, data.Team, data.ClientID)
 		if !replayMode {
 			var guid string
 			if client, ok := state.clients[data.ClientID]; ok {
@@ -1006,6 +1472,10 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 	case EventTypeFlagTaken:
 		data := event.Data.(FlagTakenData)
+		if client, ok := state.clients[data.ClientID]; ok {
+			client.flagTakenAt = event.Timestamp
+		}
+		state.recordFlagEvent(event.Timestamp, domain.FlagEventTaken, data.Team, data.ClientID)
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
@@ -1033,6 +1503,7 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 				client.flagReturns++
 			}
 		}
+		state.recordFlagEvent(event.Timestamp, domain.FlagEventReturned, data.Team, data.ClientID)
 		if !replayMode {
 			var guid string
 			// Auto-returns have ClientID == -1 and no player associated
@@ -1056,6 +1527,10 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 	case EventTypeFlagDrop:
 		data := event.Data.(FlagDropData)
+		if client, ok := state.clients[data.ClientID]; ok {
+			client.endFlagCarry(event.Timestamp)
+		}
+		state.recordFlagEvent(event.Timestamp, domain.FlagEventDropped, data.Team, data.ClientID)
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
@@ -1077,6 +1552,10 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 	case EventTypeObeliskDestroy:
 		data := event.Data.(ObeliskDestroyData)
+		if client, ok := state.clients[data.AttackerID]; ok {
+			client.obeliskDestroys++
+			state.scoreTeam(client.team, 1)
+		}
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
@@ -1097,6 +1576,10 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 
 	case EventTypeSkullScore:
 		data := event.Data.(SkullScoreData)
+		if client, ok := state.clients[data.ClientID]; ok {
+			client.skullsScored += data.Skulls
+			state.scoreTeam(client.team, data.Skulls)
+		}
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
@@ -1147,9 +1630,11 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 				} else {
 					// No active match — just update team
 					client.team = data.NewTeam
+					client.lastActive = event.Timestamp
 				}
 			} else {
 				client.team = data.NewTeam
+				client.lastActive = event.Timestamp
 				// Update joinedAt when entering a playing team from spectator
 				if data.NewTeam != 3 && oldTeam == 3 {
 					client.joinedAt = event.Timestamp
@@ -1244,9 +1729,20 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
+			var team int
 			if client, ok := state.clients[data.ClientID]; ok {
-				guid = client.guid
+				guid, team = client.guid, client.team
+				client.lastActive = event.Timestamp
 			}
+			m.moderateChat(serverID, state, data.ClientID, data.Name, data.Message, event.Timestamp)
+			m.publishChat(serverID, state, domain.ChatMessageData{
+				Kind:    domain.ChatSay,
+				GUID:    guid,
+				Name:    data.Name,
+				Team:    team,
+				Message: data.Message,
+				SentAt:  event.Timestamp,
+			})
 			m.emitEvent(domain.Event{
 				Type:      domain.EventSay,
 				ServerID:  serverID,
@@ -1265,9 +1761,20 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		// Skip events in replay mode
 		if !replayMode {
 			var guid string
+			var team int
 			if client, ok := state.clients[data.ClientID]; ok {
-				guid = client.guid
+				guid, team = client.guid, client.team
+				client.lastActive = event.Timestamp
 			}
+			m.moderateChat(serverID, state, data.ClientID, data.Name, data.Message, event.Timestamp)
+			m.publishChat(serverID, state, domain.ChatMessageData{
+				Kind:    domain.ChatSayTeam,
+				GUID:    guid,
+				Name:    data.Name,
+				Team:    team,
+				Message: data.Message,
+				SentAt:  event.Timestamp,
+			})
 			m.emitEvent(domain.Event{
 				Type:      domain.EventSayTeam,
 				ServerID:  serverID,
@@ -1286,12 +1793,25 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		// Skip events in replay mode
 		if !replayMode {
 			var fromGUID, toGUID string
+			var team int
 			if client, ok := state.clients[data.FromClientID]; ok {
-				fromGUID = client.guid
+				fromGUID, team = client.guid, client.team
+				client.lastActive = event.Timestamp
 			}
 			if client, ok := state.clients[data.ToClientID]; ok {
 				toGUID = client.guid
 			}
+			m.moderateChat(serverID, state, data.FromClientID, data.FromName, data.Message, event.Timestamp)
+			m.publishChat(serverID, state, domain.ChatMessageData{
+				Kind:    domain.ChatTell,
+				GUID:    fromGUID,
+				Name:    data.FromName,
+				Team:    team,
+				ToGUID:  toGUID,
+				ToName:  data.ToName,
+				Message: data.Message,
+				SentAt:  event.Timestamp,
+			})
 			m.emitEvent(domain.Event{
 				Type:      domain.EventTell,
 				ServerID:  serverID,
@@ -1323,6 +1843,17 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 		}
 
 	case EventTypeServerStartup:
+		if data, ok := event.Data.(ServerStartupData); ok && !replayMode {
+			crashedAt := data.LastLineAt
+			if crashedAt.IsZero() {
+				crashedAt = event.Timestamp
+			}
+			m.reportCrash(serverID, state.server.Key, event.Timestamp, domain.ServerCrashData{
+				CrashedAt: crashedAt,
+				Reason:    domain.CrashNoShutdown,
+				LogLines:  data.Tail,
+			})
+		}
 		if !replayMode {
 			m.pub.Publish(domain.FactEvent{
 				Type:      domain.FactServerStartup,
@@ -1397,16 +1928,87 @@ func (m *ServerManager) handleLogEvent(ctx context.Context, serverID int64, even
 			m.handleTrinityHandshake(ctx, serverID, state, data)
 		}
 	}
+
+	if !replayMode {
+		m.checkTeamBalance(serverID, state, event.Timestamp)
+	}
+}
+
+// startMatch begins the match proper once warmup is over: stats count
+// from here, and the hub is told so it can create the match row.
+func (m *ServerManager) startMatch(state *serverState, ts time.Time, replayMode bool) {
+	state.warmupOver = true
+	state.overtimeAt = time.Time{}
+	state.teamScore = [3]int{}
+	state.unevenSince = time.Time{}
+	state.balancedAt = time.Time{}
+	if state.match != nil {
+		state.match.StartedAt = ts
+		state.flagEvents = nil
+		if state.match.UUID != "" {
+			switch {
+			case replayMode && state.handshakeRequired:
+				// Let a post-restart Shutdown fire match_end against
+				// the hub's existing (pre-watermark) match row.
+				state.matchStarted = true
+			case !replayMode:
+				// Publish match_start regardless of g_trinityHandshake so
+				// the hub's handshake_required column tracks the cvar in
+				// both directions. The hub still gates persistence on
+				// HandshakeRequired, so non-handshake matches don't
+				// produce stats — but the server's UI visibility flips
+				// off without manual intervention. matchStarted gates
+				// match_end below; we only set it when the hub created
+				// a match row, so we don't fire match_end against a
+				// UUID the hub never persisted.
+				m.pub.Publish(domain.FactEvent{
+					Type:      domain.FactMatchStart,
+					ServerID:  state.server.ID,
+					Timestamp: ts,
+					Data: domain.MatchStartData{
+						MatchUUID:         state.match.UUID,
+						MapName:           state.match.MapName,
+						GameType:          state.match.GameType,
+						Movement:          state.match.Movement,
+						Gameplay:          state.match.Gameplay,
+						StartedAt:         ts,
+						HandshakeRequired: state.handshakeRequired,
+						Warmup:            state.warmupPlayers(),
+					},
+				})
+				if state.handshakeRequired {
+					state.matchStarted = true
+				} else {
+					log.Printf("collector: match_start for %s on server %s/%s with g_trinityHandshake not enabled — hub will reject stats and downgrade the server", state.match.UUID, state.server.Source, state.server.Key)
+				}
+			}
+		}
+	}
+	state.matchState = "active"
+	resetAbandon(state)
+	trackAbandon(state, ts)
+	state.warmupStats = nil
 }
 
 // handleMapChange handles a new map starting
-func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverState, mapName string, gameType int, uuid string, movement string, gameplay string, handshakeEnabled bool, ts time.Time, replayMode bool) {
-	state.handshakeRequired = handshakeEnabled
+func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverState, mapName string, gameType int, uuid string, movement string, gameplay string, handshakeEnabled bool, ts time.Time, cursor *domain.LogCursor, replayMode bool) {
+	state.handshakeRequired = handshakeEnabled || m.importing
+	if uuid == "" && m.importing {
+		uuid = importedMatchUUID(state.server.Key, ts)
+	}
 	// Skip duplicate InitGame at same timestamp (Q3 sometimes logs it twice on server restart)
 	if ts.Equal(state.lastInitGame) {
 		return
 	}
 	state.lastInitGame = ts
+	// The warmup's map_restart keeps the ballot and the warmup's
+	// stats; a new map clears them.
+	if state.match == nil || state.match.MapName != mapName {
+		if state.mapVote != nil {
+			state.mapVote.reset()
+		}
+		state.warmupStats = nil
+	}
 
 	gameTypeStr := domain.GameTypeFromInt(gameType)
 
@@ -1436,11 +2038,14 @@ func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverStat
 			Gameplay:  gameplay,
 		}
 		state.matchStarted = false
+		state.warmupOver = false
 		state.clients = make(map[int]*clientState)
 		state.previousClients = make(map[string]*clientState)
+		state.flagEvents = nil
 		state.matchFlushed = false
 		state.matchState = ""
 		state.warmupDuration = 0
+		resetAbandon(state)
 		return
 	}
 
@@ -1455,7 +2060,11 @@ func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverStat
 				MatchUUID:  state.match.UUID,
 				EndedAt:    ts,
 				ExitReason: "crashed",
+				Abandoned:  matchAbandoned(state, ts),
+				Overtime:   state.overtimeSeconds(ts),
 				Players:    m.buildMatchEndPlayers(state, false),
+				FlagEvents: state.flagEvents,
+				Cursor:     cursor,
 			},
 		})
 	}
@@ -1473,12 +2082,15 @@ func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverStat
 	}
 	state.match = match
 	state.matchStarted = false
+	state.warmupOver = false
 
 	state.clients = make(map[int]*clientState)
 	state.previousClients = make(map[string]*clientState)
+	state.flagEvents = nil
 	state.matchFlushed = false
 	state.matchState = "" // will be set by MatchState event if warmup enabled
 	state.warmupDuration = 0
+	resetAbandon(state)
 
 	// Emit match start event
 	m.emitEvent(domain.Event{
@@ -1492,6 +2104,30 @@ func (m *ServerManager) handleMatchChange(ctx context.Context, state *serverStat
 	})
 }
 
+// endFlagCarry adds the time since the client picked up the flag to
+// their carry time, if they were carrying it.
+func (c *clientState) endFlagCarry(at time.Time) {
+	if c.flagTakenAt.IsZero() {
+		return
+	}
+	if d := at.Sub(c.flagTakenAt); d > 0 {
+		c.flagCarry += d
+	}
+	c.flagTakenAt = time.Time{}
+}
+
+// recordFlagEvent adds a flag event to the match's timeline. Events
+// during warmup are left out.
+func (state *serverState) recordFlagEvent(at time.Time, event string, team, clientID int) {
+	if state.match == nil || state.matchState == "warmup" {
+		return
+	}
+	e := domain.MatchEndFlagEvent{At: at, Event: event, Team: team, ClientID: clientID}
+	if client, ok := state.clients[clientID]; ok {
+		e.GUID = client.guid
+	}
+	state.flagEvents = append(state.flagEvents, e)
+}
 
 // savePreviousClient accumulates per-GUID counters from a completed stint.
 func (state *serverState) savePreviousClient(client *clientState) {
@@ -1511,6 +2147,28 @@ func (state *serverState) savePreviousClient(client *clientState) {
 		prev.excellents += client.excellents
 		prev.humiliations += client.humiliations
 		prev.defends += client.defends
+		prev.skullsScored += client.skullsScored
+		prev.flagCarry += client.flagCarry
+		prev.obeliskDestroys += client.obeliskDestroys
+		prev.bestSpree = max(prev.bestSpree, client.bestSpree)
+		for weapon, n := range client.weaponFrags {
+			if prev.weaponFrags == nil {
+				prev.weaponFrags = make(map[string]int)
+			}
+			prev.weaponFrags[weapon] += n
+		}
+		for weapon, n := range client.accuracy {
+			if prev.accuracy == nil {
+				prev.accuracy = make(map[string]domain.WeaponShots)
+			}
+			shots := prev.accuracy[weapon]
+			shots.Shots += n.Shots
+			shots.Hits += n.Hits
+			prev.accuracy[weapon] = shots
+		}
+		if client.fastestCap > 0 && (prev.fastestCap == 0 || client.fastestCap < prev.fastestCap) {
+			prev.fastestCap = client.fastestCap
+		}
 		prev.clientID = client.clientID
 		prev.team = client.team
 		prev.model = client.model
@@ -1544,29 +2202,36 @@ func (m *ServerManager) buildMatchEndPlayers(state *serverState, computeVictory
 		}
 		joinedLate := state.match != nil && client.joinedAt.After(state.match.StartedAt)
 		players = append(players, domain.MatchEndPlayer{
-			GUID:         client.guid,
-			ClientID:     client.clientID,
-			Name:         client.name,
-			CleanName:    client.cleanName,
-			Frags:        client.frags,
-			Deaths:       client.deaths,
-			Completed:    false,
-			Score:        client.score,
-			Team:         team,
-			Model:        client.model,
-			Skill:        client.skill,
-			Victory:      false,
-			Captures:     client.captures,
-			FlagReturns:  client.flagReturns,
-			Assists:      client.assists,
-			Impressives:  client.impressives,
-			Excellents:   client.excellents,
-			Humiliations: client.humiliations,
-			Defends:      client.defends,
-			IsBot:        client.isBot,
-			JoinedLate:   joinedLate,
-			JoinedAt:     client.joinedAt,
-			IsVR:         client.isVR,
+			GUID:            client.guid,
+			ClientID:        client.clientID,
+			Name:            client.name,
+			CleanName:       client.cleanName,
+			Frags:           client.frags,
+			Deaths:          client.deaths,
+			Completed:       false,
+			Score:           client.score,
+			Team:            team,
+			Model:           client.model,
+			Skill:           client.skill,
+			Victory:         false,
+			Captures:        client.captures,
+			FlagReturns:     client.flagReturns,
+			Assists:         client.assists,
+			Impressives:     client.impressives,
+			Excellents:      client.excellents,
+			Humiliations:    client.humiliations,
+			Defends:         client.defends,
+			BestSpree:       client.bestSpree,
+			FastestCapMs:    client.fastestCap.Milliseconds(),
+			FlagCarryMs:     client.flagCarry.Milliseconds(),
+			SkullsScored:    client.skullsScored,
+			ObeliskDestroys: client.obeliskDestroys,
+			IsBot:           client.isBot,
+			JoinedLate:      joinedLate,
+			JoinedAt:        client.joinedAt,
+			IsVR:            client.isVR,
+			WeaponFrags:     client.weaponFrags,
+			Accuracy:        client.accuracy,
 		})
 	}
 
@@ -1585,29 +2250,36 @@ func (m *ServerManager) buildMatchEndPlayers(state *serverState, computeVictory
 		}
 		joinedLate := state.match != nil && client.joinedAt.After(state.match.StartedAt)
 		players = append(players, domain.MatchEndPlayer{
-			GUID:         client.guid,
-			ClientID:     clientID,
-			Name:         client.name,
-			CleanName:    client.cleanName,
-			Frags:        client.frags,
-			Deaths:       client.deaths,
-			Completed:    true,
-			Score:        client.score,
-			Team:         team,
-			Model:        client.model,
-			Skill:        client.skill,
-			Victory:      victory,
-			Captures:     client.captures,
-			FlagReturns:  client.flagReturns,
-			Assists:      client.assists,
-			Impressives:  client.impressives,
-			Excellents:   client.excellents,
-			Humiliations: client.humiliations,
-			Defends:      client.defends,
-			IsBot:        client.isBot,
-			JoinedLate:   joinedLate,
-			JoinedAt:     client.joinedAt,
-			IsVR:         client.isVR,
+			GUID:            client.guid,
+			ClientID:        clientID,
+			Name:            client.name,
+			CleanName:       client.cleanName,
+			Frags:           client.frags,
+			Deaths:          client.deaths,
+			Completed:       true,
+			Score:           client.score,
+			Team:            team,
+			Model:           client.model,
+			Skill:           client.skill,
+			Victory:         victory,
+			Captures:        client.captures,
+			FlagReturns:     client.flagReturns,
+			Assists:         client.assists,
+			Impressives:     client.impressives,
+			Excellents:      client.excellents,
+			Humiliations:    client.humiliations,
+			Defends:         client.defends,
+			BestSpree:       client.bestSpree,
+			FastestCapMs:    client.fastestCap.Milliseconds(),
+			FlagCarryMs:     client.flagCarry.Milliseconds(),
+			SkullsScored:    client.skullsScored,
+			ObeliskDestroys: client.obeliskDestroys,
+			IsBot:           client.isBot,
+			JoinedLate:      joinedLate,
+			JoinedAt:        client.joinedAt,
+			IsVR:            client.isVR,
+			WeaponFrags:     client.weaponFrags,
+			Accuracy:        client.accuracy,
 		})
 	}
 
@@ -1677,11 +2349,7 @@ func isMatchWinner(client *clientState, state *serverState, maxFFAScore int, has
 // emitEvent delivers to the local channel and, if livePub is set,
 // tees onto the NATS live-event subject (collector-only deployments).
 func (m *ServerManager) emitEvent(event domain.Event) {
-	select {
-	case m.events <- event:
-	default:
-		// Channel full, drop event
-	}
+	metrics.Send(m.events, event, metrics.ChannelLive, event.Type, domain.IsHighValueEvent(event.Type))
 	if m.livePub != nil {
 		if err := m.livePub.PublishLive(event); err != nil {
 			log.Printf("collector: live publish %s failed: %v", event.Type, err)
@@ -1689,6 +2357,21 @@ func (m *ServerManager) emitEvent(event domain.Event) {
 	}
 }
 
+// publishChat emits a chat_message fact for the current match. Chat
+// outside an announced match (warmup, between maps) isn't kept.
+func (m *ServerManager) publishChat(serverID int64, state *serverState, data domain.ChatMessageData) {
+	if state.match == nil || state.match.UUID == "" || !state.matchStarted {
+		return
+	}
+	data.MatchUUID = state.match.UUID
+	m.pub.Publish(domain.FactEvent{
+		Type:      domain.FactChatMessage,
+		ServerID:  serverID,
+		Timestamp: data.SentAt,
+		Data:      data,
+	})
+}
+
 // handleCommand dispatches a command to the appropriate handler
 func (m *ServerManager) handleCommand(ctx context.Context, serverID int64, state *serverState, clientID int, command string) {
 	// Parse command name and args: "link 12345678" -> cmd="link", args="12345678"
@@ -1699,6 +2382,25 @@ func (m *ServerManager) handleCommand(ctx context.Context, serverID int64, state
 		args = trimSpace(command[idx+1:])
 	}
 
+	label := commandLabel(cmd)
+	if state.server.CommandsDisabled {
+		metrics.ChatCommands.Inc(label, metrics.CommandDisabled)
+		return
+	}
+	key := fmt.Sprintf("#%d", clientID)
+	if client, ok := state.clients[clientID]; ok && client.guid != "" {
+		key = client.guid
+	}
+	if ok, muted := state.commandLimiter.allow(key, time.Now()); !ok {
+		metrics.ChatCommands.Inc(label, metrics.CommandLimited)
+		if muted {
+			log.Printf("Muting commands from client %d (%s) on server %d for %s", clientID, key, serverID, commandMute)
+			m.sendPrint(serverID, clientID, "^1Too many commands. ^7Try again in a minute.")
+		}
+		return
+	}
+	metrics.ChatCommands.Inc(label, metrics.CommandHandled)
+
 	log.Printf("Command from client %d: cmd=%q args=%q", clientID, cmd, args)
 
 	switch cmd {
@@ -1706,19 +2408,40 @@ func (m *ServerManager) handleCommand(ctx context.Context, serverID int64, state
 		m.handleLinkCommand(ctx, serverID, state, clientID, args)
 	case "claim":
 		m.handleClaimCommand(ctx, serverID, state, clientID)
+	case "stats":
+		m.handleStatsCommand(ctx, serverID, state, clientID, args)
+	case "top":
+		m.handleTopCommand(ctx, serverID, clientID, args)
+	case "nextmap":
+		m.handleNextmapCommand(serverID, state, clientID, args)
+	case "maps":
+		m.handleMapsCommand(serverID, state, clientID)
+	case "kick", "mute", "map":
+		m.handleAdminCommand(serverID, state, clientID, cmd, args)
 	case "help":
-		m.handleHelpCommand(serverID, clientID)
+		client, ok := state.clients[clientID]
+		m.handleHelpCommand(serverID, clientID, ok && state.isAdmin(client))
 	default:
 		m.sendPrint(serverID, clientID, "^1Unknown command: ^7"+cmd+". Type ^3!help ^7for available commands.")
 	}
 }
 
-// handleHelpCommand shows available commands to a player
-func (m *ServerManager) handleHelpCommand(serverID int64, clientID int) {
+// handleHelpCommand shows available commands to a player, and the
+// admin commands to an admin
+func (m *ServerManager) handleHelpCommand(serverID int64, clientID int, admin bool) {
 	go func() {
 		m.sendPrintSync(serverID, clientID, "^3Available commands:")
 		m.sendPrintSync(serverID, clientID, "^3!claim ^7- Link your identity to an account")
 		m.sendPrintSync(serverID, clientID, "^3!link <code> ^7- Link current identity to your account")
+		m.sendPrintSync(serverID, clientID, "^3!stats [name] ^7- Show your stats, or another player's")
+		m.sendPrintSync(serverID, clientID, "^3!top [frags|kd|captures] ^7- Show the all-time top 5")
+		m.sendPrintSync(serverID, clientID, "^3!nextmap [map] ^7- Vote for the next map, or see the leader")
+		m.sendPrintSync(serverID, clientID, "^3!maps ^7- List the maps you can vote for")
+		if admin {
+			m.sendPrintSync(serverID, clientID, "^3!kick <name|slot> ^7- Kick a player")
+			m.sendPrintSync(serverID, clientID, "^3!mute <name|slot> ^7- Mute a player")
+			m.sendPrintSync(serverID, clientID, "^3!map <map> ^7- Change the map now")
+		}
 	}()
 }
 
@@ -1782,8 +2505,8 @@ func (m *ServerManager) handleClaimCommand(ctx context.Context, serverID int64,
 	}
 
 	hubHost := "trinity.run"
-	if m.cfg.Tracker != nil && m.cfg.Tracker.Collector != nil && m.cfg.Tracker.Collector.HubHost != "" {
-		hubHost = m.cfg.Tracker.Collector.HubHost
+	if m.config().Tracker != nil && m.config().Tracker.Collector != nil && m.config().Tracker.Collector.HubHost != "" {
+		hubHost = m.config().Tracker.Collector.HubHost
 	}
 	switch reply.Status {
 	case hub.ClaimOK:
@@ -1799,13 +2522,116 @@ func (m *ServerManager) handleClaimCommand(ctx context.Context, serverID int64,
 	}
 }
 
+// handleStatsCommand processes a stats command from a player. With no
+// args it reports the player's own stats; otherwise args names the
+// player to look up.
+func (m *ServerManager) handleStatsCommand(ctx context.Context, serverID int64, state *serverState, clientID int, args string) {
+	req := hub.StatsRequest{Name: args}
+	if args == "" {
+		client, ok := state.clients[clientID]
+		if !ok {
+			log.Printf("stats: client %d not found in state", clientID)
+			return
+		}
+		if client.guid == "" {
+			m.sendPrint(serverID, clientID, "^1Error: Current identity unknown. Try reconnecting.")
+			return
+		}
+		req.GUID = client.guid
+	}
+
+	reply, err := m.rpc.Stats(ctx, req)
+	if err != nil {
+		log.Printf("stats RPC error: %v", err)
+		m.sendPrint(serverID, clientID, "^1Error looking up stats. Please try again.")
+		return
+	}
+
+	switch reply.Status {
+	case hub.StatsOK:
+		rank := "unranked"
+		if reply.Rank > 0 {
+			rank = fmt.Sprintf("rank ^3#%d", reply.Rank)
+		}
+		m.sendPrint(serverID, clientID, fmt.Sprintf("%s^7: ^3%d ^7frags, ^3%d ^7deaths, K/D ^3%.2f^7, ^3%d ^7matches, %s",
+			reply.Name, reply.Frags, reply.Deaths, reply.KDRatio, reply.CompletedMatches, rank))
+	case hub.StatsNotFound:
+		if args == "" {
+			m.sendPrint(serverID, clientID, "^3No stats recorded for you yet.")
+		} else {
+			m.sendPrint(serverID, clientID, "^1No player found matching ^7"+args)
+		}
+	case hub.StatsAmbiguous:
+		m.sendPrint(serverID, clientID, "^3Several players match: ^7"+strings.Join(reply.Matches, "^7, ")+"^7. Be more specific.")
+	default:
+		log.Printf("stats RPC error: %s", reply.Message)
+		m.sendPrint(serverID, clientID, "^1Error looking up stats. Please try again.")
+	}
+}
+
+// handleTopCommand processes a top command from a player
+func (m *ServerManager) handleTopCommand(ctx context.Context, serverID int64, clientID int, args string) {
+	reply, err := m.rpc.Top(ctx, hub.TopRequest{Category: args})
+	if err != nil {
+		log.Printf("top RPC error: %v", err)
+		m.sendPrint(serverID, clientID, "^1Error looking up leaderboard. Please try again.")
+		return
+	}
+
+	switch reply.Status {
+	case hub.TopOK:
+		lines := formatTopLines(args, reply.Entries)
+		go func() {
+			for _, line := range lines {
+				m.sendPrintSync(serverID, clientID, line)
+			}
+		}()
+	case hub.TopUnknownCategory:
+		m.sendPrint(serverID, clientID, "^3Usage: ^7!top [frags|kd|captures]")
+	default:
+		log.Printf("top RPC error: %s", reply.Message)
+		m.sendPrint(serverID, clientID, "^1Error looking up leaderboard. Please try again.")
+	}
+}
+
+// topNameWidth caps names in !top output so each entry fits on one
+// line of the Q3 console.
+const topNameWidth = 16
+
+// formatTopLines renders a !top reply as a heading plus one short line
+// per player.
+func formatTopLines(category string, entries []hub.TopEntry) []string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category == "" {
+		category = "frags"
+	}
+	if len(entries) == 0 {
+		return []string{"^3No ranked players yet."}
+	}
+	lines := []string{"^3Top " + category + ":"}
+	for _, e := range entries {
+		name := e.Name
+		if r := []rune(name); len(r) > topNameWidth {
+			name = string(r[:topNameWidth-1]) + "~"
+		}
+		value := fmt.Sprintf("%.0f", e.Value)
+		if category == "kd" {
+			value = fmt.Sprintf("%.2f", e.Value)
+		}
+		lines = append(lines, fmt.Sprintf("^3%d. ^7%s ^3%s", e.Rank, name, value))
+	}
+	return lines
+}
+
+// sendPrint queues a print without waiting for it to be sent.
 func (m *ServerManager) sendPrint(serverID int64, clientID int, message string) {
-	go m.sendPrintSync(serverID, clientID, message)
+	log.Printf("Sending print to client %d: %q", clientID, message)
+	m.queueRcon(serverID, PrintCommand(clientID, message))
 }
 
 // sendPrintSync is the synchronous form; use when ordering matters.
 func (m *ServerManager) sendPrintSync(serverID int64, clientID int, message string) {
-	cmd := fmt.Sprintf("sv_cmd print %d ^7%s\\n", clientID, message)
+	cmd := PrintCommand(clientID, message)
 	log.Printf("Sending print to client %d: %q", clientID, message)
 	if _, err := m.ExecuteRcon(serverID, cmd); err != nil {
 		log.Printf("Error sending print to client %d on server %d: %v", clientID, serverID, err)
@@ -1827,12 +2653,14 @@ func (m *ServerManager) sendCenterPrint(serverID int64, clientID int, message st
 // already binds to a known player and can't be rebound — but follow
 // up with a prominent re-login notice.
 func (m *ServerManager) performGreet(ctx context.Context, serverID int64, clientID int, guid, playerName, cleanName string, isVR, isTrinityEngine bool, auth *hub.AuthProof) {
+	greetings := m.greetingsFor(serverID)
 	req := hub.GreetRequest{
 		ServerID:   serverID,
 		GUID:       guid,
 		ClientName: playerName,
 		CleanName:  cleanName,
 		Auth:       auth,
+		WantRank:   greetingsUse(greetings, "{rank}"),
 	}
 	reply, err := m.rpc.Greet(ctx, req)
 	if err != nil {
@@ -1845,30 +2673,67 @@ func (m *ServerManager) performGreet(ctx context.Context, serverID int64, client
 		m.sendTrinityAuthFail(serverID, clientID)
 	}
 
-	var message, cpMessage string
-	hasStats := reply.CompletedMatches > 0
+	// Only a session signed in to Trinity gets admin commands. A GUID
+	// alone proves nothing: clients set their own, and a player's are
+	// listed publicly.
+	if reply.IsAdmin && reply.AuthResult == hub.AuthVerified {
+		m.mu.Lock()
+		if state, ok := m.servers[serverID]; ok && state.admins != nil {
+			// The slot may have changed hands while the hub answered.
+			if c, ok := state.clients[clientID]; ok && c.guid == guid {
+				state.admins[clientID] = guid
+			}
+		}
+		m.mu.Unlock()
+	}
+
+	if reply.CompletedMatches > 0 {
+		m.mu.Lock()
+		if state, ok := m.servers[serverID]; ok && state.ratings != nil {
+			state.ratings[guid] = reply.KDRatio
+		}
+		m.mu.Unlock()
+	}
+
+	// A reserved slot kicks others to make room, so like admin commands
+	// it needs a signed-in session, not just the GUID.
+	if reply.IsVIP && reply.AuthResult == hub.AuthVerified {
+		m.mu.Lock()
+		if state, ok := m.servers[serverID]; ok && state.vips != nil {
+			state.vips[guid] = true
+		}
+		m.mu.Unlock()
+		m.makeRoomForVIP(serverID, clientID, guid, playerName)
+	}
+
+	fill := greetingReplacer(playerName, reply, greetings.SiteURL)
+
+	welcome := greetings.New
 	switch {
-	case reply.Claimed && hasStats:
-		message = fmt.Sprintf("Welcome back, %s^7! K/D: ^3%.2f ^7| Matches: ^3%d ^7(^3!help ^7for help)",
-			playerName, reply.KDRatio, reply.CompletedMatches)
-		cpMessage = fmt.Sprintf("Welcome back, %s^7!\nK/D: ^3%.2f ^7| Matches: ^3%d\n^3!help ^7for help",
-			playerName, reply.KDRatio, reply.CompletedMatches)
+	case reply.DaysAway > 0:
+		welcome = greetings.Returning
 	case reply.Claimed:
-		message = fmt.Sprintf("Welcome back, %s^7! (^3!help ^7for help)", playerName)
-		cpMessage = fmt.Sprintf("Welcome back, %s^7!\n^3!help ^7for help", playerName)
-	case hasStats:
-		message = fmt.Sprintf("Welcome, %s^7! K/D: ^3%.2f ^7| Matches: ^3%d ^7- ^3!claim ^7to link your identity! (^3!help ^7for help)",
-			playerName, reply.KDRatio, reply.CompletedMatches)
-		cpMessage = fmt.Sprintf("Welcome, %s^7!\nK/D: ^3%.2f ^7| Matches: ^3%d\n^3!claim ^7to link your identity!",
-			playerName, reply.KDRatio, reply.CompletedMatches)
-	default:
-		message = fmt.Sprintf("Welcome, %s^7! ^3!claim ^7to link your identity! (^3!help ^7for help)", playerName)
-		cpMessage = fmt.Sprintf("Welcome, %s^7!\n^3!claim ^7to link your identity!", playerName)
+		welcome = greetings.Claimed
 	}
 
-	time.Sleep(3 * time.Second)
-	m.sendPrintSync(serverID, clientID, message)
-	m.sendCenterPrint(serverID, clientID, cpMessage)
+	if reply.DaysAway > 0 {
+		m.emitEvent(domain.Event{
+			Type:      domain.EventPlayerReturn,
+			ServerID:  serverID,
+			Timestamp: time.Now().UTC(),
+			Data: domain.PlayerReturnEvent{
+				ClientNum:  clientID,
+				PlayerName: playerName,
+				DaysAway:   reply.DaysAway,
+				GUID:       guid,
+			},
+		})
+	}
+
+	if welcome != nil {
+		time.Sleep(3 * time.Second)
+		m.sendGreeting(serverID, clientID, welcome, fill)
+	}
 
 	if reply.GUIDLinked {
 		time.Sleep(2 * time.Second)
@@ -1876,20 +2741,16 @@ func (m *ServerManager) performGreet(ctx context.Context, serverID int64, client
 	}
 
 	if !isVR {
-		var upgradeMsg, upgradeCpMsg string
+		prompt := greetings.TryVR
 		if strings.Contains(cleanName, "[VR]") {
-			upgradeMsg = "Your VR client is outdated. Upgrade to enjoy all Trinity features! More info at ^5trinity.run/docs"
-			upgradeCpMsg = "Your VR client is outdated.\nUpgrade to enjoy all Trinity features!\n^5trinity.run/docs"
+			prompt = greetings.OutdatedVR
 		} else if !isTrinityEngine {
-			upgradeMsg = "It looks like you're missing out on Trinity-specific features on this server. Go to ^5trinity.run/docs ^7to upgrade."
-			upgradeCpMsg = "Get Trinity for the best experience!\n^5trinity.run/docs"
-		} else {
-			upgradeMsg = "Haven't tried Quake 3 in VR yet? It's a whole new dimension (literally). Visit ^5trinity.run/docs ^7to learn more."
-			upgradeCpMsg = "Do you play VR?\nGet the VR client!\n^5trinity.run/docs"
+			prompt = greetings.NoTrinity
+		}
+		if prompt != nil {
+			time.Sleep(3 * time.Second)
+			m.sendGreeting(serverID, clientID, prompt, fill)
 		}
-		time.Sleep(3 * time.Second)
-		m.sendPrintSync(serverID, clientID, upgradeMsg)
-		m.sendCenterPrint(serverID, clientID, upgradeCpMsg)
 	}
 
 	if reply.AuthResult == hub.AuthFailed {
@@ -1928,4 +2789,3 @@ func indexSpace(s string) int {
 	}
 	return -1
 }
-
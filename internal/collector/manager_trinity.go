@@ -64,8 +64,9 @@ func (m *ServerManager) scheduleGreetingAfterHandshake(ctx context.Context, stat
 		m.mu.Unlock()
 
 		log.Printf("No Trinity handshake from client %d on server %d — sending warning", clientID, serverID)
-		m.sendCenterPrint(serverID, clientID, "^1Trinity client required\n^7You will be disconnected.\nVisit ^5trinity.run/docs")
-		m.sendPrint(serverID, clientID, "^1This server requires a Trinity client. Visit ^5trinity.run/docs ^1to download.")
+		site := m.greetingsFor(serverID).SiteURL
+		m.sendCenterPrint(serverID, clientID, "^1Trinity client required\n^7You will be disconnected.\nVisit ^5"+site)
+		m.sendPrint(serverID, clientID, "^1This server requires a Trinity client. Visit ^5"+site+" ^1to download.")
 	})
 
 	state.pendingGreetings[clientID] = pg
@@ -125,11 +126,6 @@ func (m *ServerManager) handleTrinityHandshake(ctx context.Context, serverID int
 }
 
 func (m *ServerManager) sendTrinityAuthFail(serverID int64, clientNum int) {
-	go func() {
-		cmd := fmt.Sprintf("trinity_auth_fail %d", clientNum)
-		if _, err := m.ExecuteRcon(serverID, cmd); err != nil {
-			log.Printf("Failed to send trinity_auth_fail to server %d client %d: %v", serverID, clientNum, err)
-		}
-	}()
+	m.queueRcon(serverID, fmt.Sprintf("trinity_auth_fail %d", clientNum))
 }
 
@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+)
+
+// mapVoteDelay is how long into the intermission a winning vote waits
+// before changing map, so players get a look at the scoreboard.
+const mapVoteDelay = 8 * time.Second
+
+// mapsPerLine keeps the !maps list inside a console line.
+const mapsPerLine = 6
+
+// mapVote is a server's !nextmap ballot for the current match.
+type mapVote struct {
+	policy config.MapVoteConfig
+	votes  map[int]string // client ID -> map
+}
+
+// newMapVote returns nil when p is nil (voting off).
+func newMapVote(p *config.MapVoteConfig) *mapVote {
+	if p == nil {
+		return nil
+	}
+	return &mapVote{policy: *p, votes: make(map[int]string)}
+}
+
+// lookup finds name among the allowed maps, ignoring case.
+func (v *mapVote) lookup(name string) (string, bool) {
+	for _, m := range v.policy.Maps {
+		if strings.EqualFold(m, name) {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// leader returns the map with the most votes and its count. Ties go
+// to the map listed first in config.
+func (v *mapVote) leader() (string, int) {
+	counts := v.counts()
+	var best string
+	var n int
+	for _, m := range v.policy.Maps {
+		if counts[m] > n {
+			best, n = m, counts[m]
+		}
+	}
+	return best, n
+}
+
+func (v *mapVote) counts() map[string]int {
+	counts := make(map[string]int)
+	for _, m := range v.votes {
+		counts[m]++
+	}
+	return counts
+}
+
+// needed is how many votes carry the ballot with humans on the server.
+func (v *mapVote) needed(humans int) int {
+	return max(1, int(math.Ceil(v.policy.Quorum*float64(humans))))
+}
+
+func (v *mapVote) reset() {
+	clear(v.votes)
+}
+
+// humansOn counts the humans in the game on state's server.
+func humansOn(state *serverState) int {
+	n := 0
+	for _, c := range state.clients {
+		if !c.isBot && c.began {
+			n++
+		}
+	}
+	return n
+}
+
+// handleMapsCommand lists the maps on the ballot with their votes.
+func (m *ServerManager) handleMapsCommand(serverID int64, state *serverState, clientID int) {
+	v := state.mapVote
+	if v == nil {
+		m.sendPrint(serverID, clientID, "^3Map voting is off on this server.")
+		return
+	}
+	counts := v.counts()
+	var lines []string
+	var row []string
+	for _, name := range v.policy.Maps {
+		entry := "^7" + name
+		if n := counts[name]; n > 0 {
+			entry += fmt.Sprintf(" ^3(%d)", n)
+		}
+		row = append(row, entry)
+		if len(row) == mapsPerLine {
+			lines = append(lines, strings.Join(row, "^7, "))
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		lines = append(lines, strings.Join(row, "^7, "))
+	}
+	go func() {
+		m.sendPrintSync(serverID, clientID, "^3Maps you can vote for with ^7!nextmap <map>^3:")
+		for _, line := range lines {
+			m.sendPrintSync(serverID, clientID, line)
+		}
+	}()
+}
+
+// handleNextmapCommand shows the vote with no args, or casts the
+// player's vote for the map named in args.
+func (m *ServerManager) handleNextmapCommand(serverID int64, state *serverState, clientID int, args string) {
+	v := state.mapVote
+	if v == nil {
+		m.sendPrint(serverID, clientID, "^3Map voting is off on this server.")
+		return
+	}
+	need := v.needed(humansOn(state))
+	if args == "" {
+		leader, n := v.leader()
+		if n == 0 {
+			m.sendPrint(serverID, clientID, "^3No map votes yet. ^7Type ^3!nextmap <map> ^7to vote, or ^3!maps ^7for the list.")
+			return
+		}
+		m.sendPrint(serverID, clientID, fmt.Sprintf("^3Leading the vote: ^7%s ^3(%d of %d needed)", leader, n, need))
+		return
+	}
+	client, ok := state.clients[clientID]
+	if !ok || client.isBot {
+		return
+	}
+	name, ok := v.lookup(args)
+	if !ok {
+		m.sendPrint(serverID, clientID, "^1Not on the ballot: ^7"+args+"^7. Type ^3!maps ^7for the list.")
+		return
+	}
+	v.votes[clientID] = name
+	n := v.counts()[name]
+	m.sendPrint(serverID, clientID, fmt.Sprintf("^2Vote counted for ^7%s ^3(%d of %d needed)", name, n, need))
+}
+
+// finishMapVote settles the ballot as the match ends. If the leading
+// map has a quorum, the map changes to it once the intermission has
+// had mapVoteDelay. Either way the ballot is cleared for the next
+// match. Runs under m.mu.
+func (m *ServerManager) finishMapVote(serverID int64, state *serverState) {
+	v := state.mapVote
+	if v == nil {
+		return
+	}
+	defer v.reset()
+	leader, n := v.leader()
+	if n == 0 {
+		return
+	}
+	need := v.needed(humansOn(state))
+	if n < need {
+		log.Printf("collector: map vote on %s: %s had %d of %d votes needed; keeping rotation", state.server.Key, leader, n, need)
+		return
+	}
+	log.Printf("collector: map vote on %s: changing to %s with %d votes", state.server.Key, leader, n)
+	go func() {
+		if _, err := m.ExecuteRcon(serverID, "say ^3Map vote: next map is ^7"+leader); err != nil {
+			log.Printf("collector: map vote announce on server %d: %v", serverID, err)
+		}
+		time.Sleep(mapVoteDelay)
+		if _, err := m.ExecuteRcon(serverID, "map "+leader); err != nil {
+			log.Printf("collector: map vote change to %s on server %d: %v", leader, serverID, err)
+		}
+	}()
+}
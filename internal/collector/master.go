@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+const (
+	getServersResponse = q3Header + "getserversResponse"
+	masterTimeout      = 3 * time.Second
+	// masterProtocol is the Quake 3 1.32 network protocol, which
+	// ioquake3 servers also report.
+	masterProtocol = 68
+)
+
+// QueryMaster asks a Quake 3 master server for the servers it lists,
+// empty and full ones included, and returns their ip:port addresses.
+// The list comes in several packets; reading stops at the end marker
+// or when the master goes quiet.
+func (c *Q3Client) QueryMaster(address string) ([]string, error) {
+	conn, err := net.DialTimeout("udp", address, masterTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	request := fmt.Sprintf("%sgetservers %d empty full", q3Header, masterProtocol)
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return nil, fmt.Errorf("sending getservers: %w", err)
+	}
+
+	var servers []string
+	seen := make(map[string]bool)
+	buf := make([]byte, maxResponse)
+	deadline := time.Now().Add(masterTimeout)
+	for {
+		conn.SetReadDeadline(deadline)
+		n, err := conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && len(servers) > 0 {
+				return servers, nil
+			}
+			return nil, fmt.Errorf("reading getservers response: %w", err)
+		}
+		addrs, done, err := parseGetServersResponse(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if !seen[a] {
+				seen[a] = true
+				servers = append(servers, a)
+			}
+		}
+		if done {
+			return servers, nil
+		}
+		// More packets follow close behind; don't wait out the full
+		// timeout for each.
+		deadline = time.Now().Add(time.Second)
+	}
+}
+
+// parseGetServersResponse reads one getserversResponse packet: a
+// backslash before each 4-byte IPv4 address and 2-byte port, all big
+// endian, with \EOT closing the last packet. done reports the marker.
+func parseGetServersResponse(data []byte) (addrs []string, done bool, err error) {
+	if !bytes.HasPrefix(data, []byte(getServersResponse)) {
+		return nil, false, fmt.Errorf("invalid getservers response prefix")
+	}
+	data = data[len(getServersResponse):]
+	for len(data) > 0 {
+		if data[0] != '\\' {
+			break
+		}
+		data = data[1:]
+		if bytes.HasPrefix(data, []byte("EOT")) {
+			return addrs, true, nil
+		}
+		if len(data) < 6 {
+			break
+		}
+		ip := netip.AddrFrom4([4]byte(data[:4]))
+		port := int(data[4])<<8 | int(data[5])
+		data = data[6:]
+		if port == 0 || ip.IsUnspecified() {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	}
+	return addrs, false, nil
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// chatFilter is a server's moderation policy with its words compiled.
+type chatFilter struct {
+	policy config.ModerationConfig
+	re     *regexp.Regexp
+}
+
+// newChatFilter compiles p, or returns nil when p is nil. Words match
+// whole, so "ass" doesn't fire on "pass"; letters and digits on either
+// side count as part of the word.
+func newChatFilter(p *config.ModerationConfig) *chatFilter {
+	if p == nil {
+		return nil
+	}
+	quoted := make([]string, len(p.Words))
+	for i, w := range p.Words {
+		quoted[i] = regexp.QuoteMeta(strings.TrimSpace(w))
+	}
+	re := regexp.MustCompile(`(?i)(?:^|[^\pL\pN])(` + strings.Join(quoted, "|") + `)(?:$|[^\pL\pN])`)
+	return &chatFilter{policy: *p, re: re}
+}
+
+// match returns the filtered word message contains, or "".
+func (f *chatFilter) match(message string) string {
+	m := f.re.FindStringSubmatch(domain.CleanQ3Name(message))
+	if m == nil {
+		return ""
+	}
+	return strings.ToLower(m[1])
+}
+
+// moderateChat checks a chat line against the server's word filter.
+// On a hit it acts against the sender and reports the incident to the
+// hub. Runs under m.mu, so the RCON goes out on its own goroutine.
+func (m *ServerManager) moderateChat(serverID int64, state *serverState, clientID int, name, message string, ts time.Time) {
+	f := state.chatFilter
+	if f == nil {
+		return
+	}
+	client, ok := state.clients[clientID]
+	if ok && client.isBot {
+		return
+	}
+	word := f.match(message)
+	if word == "" {
+		return
+	}
+	var guid, matchUUID string
+	if ok {
+		guid = client.guid
+	}
+	if state.match != nil && state.matchStarted {
+		matchUUID = state.match.UUID
+	}
+	log.Printf("collector: moderation: %s on %s matched %q; action %s", domain.CleanQ3Name(name), state.server.Key, word, f.policy.Action)
+
+	go m.enforceModeration(serverID, clientID, f.policy)
+	m.pub.Publish(domain.FactEvent{
+		Type:      domain.FactModerationIncident,
+		ServerID:  serverID,
+		Timestamp: ts,
+		Data: domain.ModerationIncidentData{
+			MatchUUID: matchUUID,
+			GUID:      guid,
+			Name:      name,
+			ClientNum: clientID,
+			Message:   message,
+			Matched:   word,
+			Action:    f.policy.Action,
+			At:        ts,
+		},
+	})
+}
+
+// enforceModeration carries out p's action against clientID.
+func (m *ServerManager) enforceModeration(serverID int64, clientID int, p config.ModerationConfig) {
+	var cmd string
+	switch p.Action {
+	case config.ModerationWarn:
+		m.sendPrintSync(serverID, clientID, p.Warning)
+	case config.ModerationMute:
+		m.sendPrintSync(serverID, clientID, p.Warning)
+		cmd = strings.ReplaceAll(p.MuteCommand, "{client}", strconv.Itoa(clientID))
+	case config.ModerationKick:
+		cmd = fmt.Sprintf("clientkick %d", clientID)
+	}
+	if cmd == "" {
+		return
+	}
+	if _, err := m.ExecuteRcon(serverID, cmd); err != nil {
+		log.Printf("collector: moderation %s for client %d on server %d: %v", p.Action, clientID, serverID, err)
+	}
+}
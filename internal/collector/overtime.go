@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// overtimeBroadcastRegex matches an overtime announcement on its own:
+// baseq3's "Sudden Death!" when the timelimit hits with the scores
+// tied, or a mod's "Overtime!" or "Overtime: 2:00". A player's name
+// with the word in it, as in "Overtime entered the game", doesn't.
+var overtimeBroadcastRegex = regexp.MustCompile(`(?i)^(sudden death|overtime)\b[^a-z]*$`)
+
+// isOvertimeBroadcast reports whether a broadcast print announces
+// overtime.
+func isOvertimeBroadcast(msg string) bool {
+	msg = strings.TrimSpace(strings.TrimSuffix(domain.CleanQ3Name(msg), `\n`))
+	return overtimeBroadcastRegex.MatchString(msg)
+}
+
+// beginOvertime notes that the match went past regulation at at. Later
+// announcements of the same overtime are ignored.
+func (state *serverState) beginOvertime(at time.Time) {
+	if state.overtimeAt.IsZero() {
+		state.overtimeAt = at
+	}
+	state.matchState = "overtime"
+}
+
+// overtimeSeconds is how long the match ending at end spent in
+// overtime, in whole seconds, or nil if it didn't go to overtime.
+func (state *serverState) overtimeSeconds(end time.Time) *int {
+	if state.overtimeAt.IsZero() {
+		return nil
+	}
+	secs := max(int(end.Sub(state.overtimeAt)/time.Second), 0)
+	return &secs
+}
@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestIsOvertimeBroadcast(t *testing.T) {
+	for msg, want := range map[string]bool{
+		`Sudden Death!\n`:                    true,
+		`^3Overtime!\n`:                      true,
+		`Overtime: 2:00\n`:                   true,
+		`Overtime^7 entered the game\n`:      false,
+		`OvertimeKing^7 entered the game`:    false,
+		`Sarge^7 was killed by Sudden Death`: false,
+	} {
+		if got := isOvertimeBroadcast(msg); got != want {
+			t.Errorf("isOvertimeBroadcast(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
+
+func TestOvertimeMatchEnd(t *testing.T) {
+	_, pub, feed := newWarmupTestManager(t)
+
+	feed(EventTypeMatchState, MatchStateData{State: "active"})
+	feed(EventTypeBroadcast, BroadcastData{Message: `Sudden Death!\n`})
+	feed(EventTypeMatchState, MatchStateData{State: "overtime"}) // the same overtime
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 1})
+	feed(EventTypeShutdown, nil)
+
+	var end *domain.MatchEndData
+	for _, e := range pub.events {
+		if e.Type == domain.FactMatchEnd {
+			data := e.Data.(domain.MatchEndData)
+			end = &data
+		}
+	}
+	if end == nil {
+		t.Fatal("no match_end published")
+	}
+	// Each fed event is a second apart: the broadcast, then three more.
+	if end.Overtime == nil || *end.Overtime != 3 {
+		t.Errorf("overtime = %v, want 3 seconds", end.Overtime)
+	}
+}
@@ -0,0 +1,290 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// rconQueueSize is how many commands may wait for one server.
+	rconQueueSize = 64
+	// rconInterval spaces commands to one server. ioquake3 answers
+	// about ten rcon packets a second and silently drops the rest.
+	rconInterval = 100 * time.Millisecond
+	// rconReplyTimeout is how long to wait for the first packet of a
+	// reply. The server replies to every rcon, if only with an empty
+	// print.
+	rconReplyTimeout = time.Second
+	// rconReplyGap ends a reply: long output comes in several packets
+	// sent back to back.
+	rconReplyGap = 150 * time.Millisecond
+	// rconAttempts is how many times a command is tried before giving
+	// up; see send for which are tried again.
+	rconAttempts = 3
+	// rconIdleTimeout closes a worker, and its socket, once it has
+	// had nothing to send for this long.
+	rconIdleTimeout = 5 * time.Minute
+)
+
+// rconReadOnly are the commands that only read the server's state, so
+// running one twice does no harm.
+var rconReadOnly = map[string]bool{
+	"status":     true,
+	"serverinfo": true,
+	"systeminfo": true,
+	"dumpuser":   true,
+}
+
+var (
+	errRconQueueFull = errors.New("rcon queue full")
+	errRconNoReply   = errors.New("no reply to rcon command")
+	errRconStopped   = errors.New("collector stopping")
+)
+
+// rconPool sends RCON commands through one worker per server. Each
+// worker keeps one UDP socket open and sends its queue one command at
+// a time, spaced by rconInterval, so a burst (greetings at a map
+// change) waits its turn instead of being dropped by the server's rate
+// limit. Sending one at a time is also what ties a reply to its
+// command: the socket is drained before each send, so a reply that
+// arrives too late is never read as the next command's.
+type rconPool struct {
+	done <-chan struct{}
+	// target looks up where a command goes and with what password. It
+	// runs on the worker, not the caller, so commands can be queued
+	// while holding the manager's lock. An empty address with no
+	// error drops the command (dry-run).
+	target func(serverID int64, command string) (address, password string, err error)
+
+	mu      sync.Mutex
+	workers map[int64]*rconWorker
+}
+
+// rconJob is one queued command. done runs on the worker once the
+// command has a reply or has failed.
+type rconJob struct {
+	command string
+	done    func(output string, err error)
+}
+
+type rconWorker struct {
+	serverID int64
+	queue    chan rconJob
+	conn     *net.UDPConn
+	address  string // conn's address
+	last     time.Time
+}
+
+func newRconPool(done <-chan struct{}, target func(int64, string) (string, string, error)) *rconPool {
+	return &rconPool{done: done, target: target, workers: make(map[int64]*rconWorker)}
+}
+
+// exec queues command for the server and waits for its reply.
+func (p *rconPool) exec(serverID int64, command string) (string, error) {
+	// Only the command's name goes on the span: its arguments can be
+	// chat text or a player's name.
+	name, _, _ := strings.Cut(command, " ")
+	_, span := tracing.Tracer().Start(context.Background(), "rcon "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int64("trinity.server_id", serverID)),
+	)
+	type result struct {
+		output string
+		err    error
+	}
+	replies := make(chan result, 1)
+	err := p.queue(serverID, command, func(output string, err error) {
+		replies <- result{output, err}
+	})
+	if err != nil {
+		tracing.End(span, err)
+		return "", err
+	}
+	select {
+	case r := <-replies:
+		tracing.End(span, r.err)
+		return r.output, r.err
+	case <-p.done:
+		tracing.End(span, errRconStopped)
+		return "", errRconStopped
+	}
+}
+
+// queue adds command to the server's queue without waiting. done may
+// be nil. Commands queued from one goroutine are sent in order.
+func (p *rconPool) queue(serverID int64, command string, done func(string, error)) error {
+	job := rconJob{command: command, done: done}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	w := p.workers[serverID]
+	if w == nil {
+		w = &rconWorker{serverID: serverID, queue: make(chan rconJob, rconQueueSize)}
+		p.workers[serverID] = w
+		go p.run(w)
+	}
+	select {
+	case w.queue <- job:
+		return nil
+	default:
+		return errRconQueueFull
+	}
+}
+
+// run sends w's queue until the pool stops or w goes idle.
+func (p *rconPool) run(w *rconWorker) {
+	defer func() {
+		if w.conn != nil {
+			w.conn.Close()
+		}
+	}()
+	idle := time.NewTimer(rconIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-idle.C:
+			// Checked under p.mu, which queue holds while adding, so
+			// nothing is added to a worker that's gone.
+			p.mu.Lock()
+			if len(w.queue) == 0 {
+				delete(p.workers, w.serverID)
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+		case job := <-w.queue:
+			output, err := p.send(w, job)
+			if job.done != nil {
+				job.done(output, err)
+			}
+		}
+		idle.Reset(rconIdleTimeout)
+	}
+}
+
+// send runs one command on w. A command that couldn't be sent is tried
+// again. One that was sent but got no reply is only resent if it's in
+// rconReadOnly: the reply may be what was lost, and a kick, map change
+// or say must not run twice. Others return the error instead.
+func (p *rconPool) send(w *rconWorker, job rconJob) (string, error) {
+	name, _, _ := strings.Cut(job.command, " ")
+	retryable := rconReadOnly[strings.ToLower(name)]
+	address, password, err := p.target(w.serverID, job.command)
+	if err != nil || address == "" {
+		return "", err
+	}
+	if w.conn != nil && w.address != address {
+		// A reload moved the server.
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.address = address
+	request := []byte(fmt.Sprintf("%s%s %s", rconPrefix, password, job.command))
+	err = errRconNoReply
+	for attempt := 0; attempt < rconAttempts; attempt++ {
+		if wait := rconInterval - time.Since(w.last); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-p.done:
+				return "", errRconStopped
+			}
+		}
+		if w.conn == nil {
+			if w.conn, err = dialUDP(w.address); err != nil {
+				w.last = time.Now()
+				continue
+			}
+		}
+		w.drain()
+		w.last = time.Now()
+		if _, err = w.conn.Write(request); err != nil {
+			err = fmt.Errorf("sending rcon command: %w", err)
+			w.conn.Close()
+			w.conn = nil
+			continue
+		}
+		output, ok, readErr := w.readReply()
+		w.last = time.Now()
+		if ok {
+			return output, nil
+		}
+		err = errRconNoReply
+		if readErr != nil {
+			err = readErr
+			w.conn.Close()
+			w.conn = nil
+		}
+		if !retryable {
+			return "", err
+		}
+		if attempt+1 < rconAttempts {
+			log.Printf("collector: rcon to %s: %v; retrying", w.address, err)
+		}
+	}
+	return "", err
+}
+
+// drain discards whatever is waiting on the socket, such as a reply
+// that came in after its command gave up on it.
+func (w *rconWorker) drain() {
+	buf := make([]byte, maxResponse)
+	for {
+		w.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+		if _, err := w.conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+// readReply reads one command's reply, which may span packets. ok is
+// false when nothing came; err is set when the socket itself failed.
+func (w *rconWorker) readReply() (output string, ok bool, err error) {
+	var response strings.Builder
+	buf := make([]byte, maxResponse)
+	deadline := time.Now().Add(rconReplyTimeout)
+	for {
+		w.conn.SetReadDeadline(deadline)
+		n, err := w.conn.Read(buf)
+		if err != nil {
+			if netErr, isNet := err.(net.Error); isNet && netErr.Timeout() {
+				return response.String(), ok, nil
+			}
+			if ok {
+				return response.String(), true, nil
+			}
+			return "", false, fmt.Errorf("reading response: %w", err)
+		}
+		data := string(buf[:n])
+		if !strings.HasPrefix(data, printPrefix) {
+			continue
+		}
+		response.WriteString(strings.TrimPrefix(data, printPrefix))
+		ok = true
+		deadline = time.Now().Add(rconReplyGap)
+	}
+}
+
+// dialUDP opens a socket to address for rcon.
+func dialUDP(address string) (*net.UDPConn, error) {
+	conn, err := net.DialTimeout("udp", address, rconTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", address, err)
+	}
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to %s: not a UDP connection", address)
+	}
+	return udp, nil
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestRconNoReplyNotResent checks that a command that may have run is
+// not sent again when its reply doesn't come.
+func TestRconNoReplyNotResent(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	address := server.LocalAddr().String()
+
+	done := make(chan struct{})
+	defer close(done)
+	pool := newRconPool(done, func(int64, string) (string, string, error) {
+		return address, "secret", nil
+	})
+	if _, err := pool.exec(1, "clientkick 3"); !errors.Is(err, errRconNoReply) {
+		t.Fatalf("exec err = %v, want %v", err, errRconNoReply)
+	}
+
+	buf := make([]byte, maxResponse)
+	packets := 0
+	for {
+		server.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, err := server.Read(buf); err != nil {
+			break
+		}
+		packets++
+	}
+	if packets != 1 {
+		t.Errorf("server got %d packets, want 1", packets)
+	}
+}
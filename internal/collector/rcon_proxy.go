@@ -2,7 +2,9 @@ package collector
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"regexp"
 
 	"github.com/ernie/trinity-tracker/internal/natsbus"
 )
@@ -23,6 +25,13 @@ import (
 // Hub admin role: validated against AllowHubAdminRcon for the named
 // server. A flip to false in the operator's cfg propagates within one
 // heartbeat to the hub UI; this layer is the safety net for the gap.
+//
+// Announce role: the hub itself telling one player something (e.g. an
+// achievement). Allowed on every server, but only for a single-client
+// print as built by PrintCommand — nothing that changes server state.
+//
+// Commands role: a hub admin switching the server's chat commands on
+// or off. Handled here without touching RCON.
 type RconProxyHandler struct {
 	manager *ServerManager
 }
@@ -44,6 +53,20 @@ func (h *RconProxyHandler) HandleRcon(_ context.Context, req natsbus.RconExecReq
 	switch req.Role {
 	case natsbus.RconRoleOwner:
 		// Trust the hub: ownership was verified there.
+	case natsbus.RconRoleAnnounce:
+		if !IsPrintCommand(req.Command) {
+			log.Printf("collector.rcon: refusing announce %q for %q (not a client print)", req.Command, req.ServerKey)
+			return natsbus.RconExecReply{Error: "announce role may only print to a client"}
+		}
+	case natsbus.RconRoleCommands:
+		if req.Command != "enable" && req.Command != "disable" {
+			return natsbus.RconExecReply{Error: `commands role takes "enable" or "disable"`}
+		}
+		if !h.manager.SetCommandsDisabled(req.ServerKey, req.Command == "disable") {
+			return natsbus.RconExecReply{Error: fmt.Sprintf("server %q not found", req.ServerKey)}
+		}
+		log.Printf("collector.rcon: %s %sd chat commands on %s", req.Username, req.Command, req.ServerKey)
+		return natsbus.RconExecReply{}
 	case natsbus.RconRoleHubAdmin:
 		if !h.manager.AdminDelegationFor(req.ServerKey) {
 			log.Printf("collector.rcon: refusing hub-admin RCON for %q (delegation disabled in cfg)", req.ServerKey)
@@ -59,3 +82,20 @@ func (h *RconProxyHandler) HandleRcon(_ context.Context, req natsbus.RconExecReq
 	log.Printf("collector.rcon: %s %s ran %q on %s", req.Role, req.Username, req.Command, req.ServerKey)
 	return natsbus.RconExecReply{Output: output}
 }
+
+// printCommandRe matches exactly what PrintCommand produces. Quotes,
+// semicolons, and newlines are refused so the message can't smuggle a
+// second command past the server's command parser.
+var printCommandRe = regexp.MustCompile(`^sv_cmd print [0-9]{1,2} [^;"\r\n]*$`)
+
+// PrintCommand builds the rcon command that prints message in one
+// client's console.
+func PrintCommand(clientID int, message string) string {
+	return fmt.Sprintf("sv_cmd print %d ^7%s\\n", clientID, message)
+}
+
+// IsPrintCommand reports whether command is a single-client print
+// safe to run on behalf of the announce role.
+func IsPrintCommand(command string) bool {
+	return printCommandRe.MatchString(command)
+}
@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/metrics"
+)
+
+// ServerDiff is what a Reload did to the server list, by key.
+type ServerDiff struct {
+	// Added servers are now tailed; Removed ones no longer are.
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Restarted servers had their address, log_path or timezone
+	// changed, so their log is tailed afresh.
+	Restarted []string `json:"restarted"`
+	// Updated servers had other settings change, which took effect in
+	// place.
+	Updated []string `json:"updated"`
+}
+
+// Changed reports whether the reload touched any server.
+func (d ServerDiff) Changed() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Restarted)+len(d.Updated) > 0
+}
+
+// needsRestart reports whether going from a to b means tailing the
+// server's log afresh.
+func needsRestart(a, b config.Q3Server) bool {
+	return a.Address != b.Address || a.LogPath != b.LogPath || a.Timezone != b.Timezone
+}
+
+// Reload applies cfg's q3_servers and moderation without a restart.
+// New servers are registered and tailed, dropped ones stop being
+// tailed, and ones whose log moved are restarted. Other per-server
+// settings (rcon password, moderation, map vote, ...) take effect in
+// place; a map vote in progress is only reset if its settings changed.
+// Nothing else in cfg is applied.
+//
+// Players on a dropped server are left as they were, so drop servers
+// between matches.
+func (m *ServerManager) Reload(ctx context.Context, cfg *config.Config) (ServerDiff, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	var diff ServerDiff
+	old := m.config()
+	oldByKey := make(map[string]config.Q3Server, len(old.Q3Servers))
+	for _, srv := range old.Q3Servers {
+		oldByKey[strings.ToLower(srv.Key)] = srv
+	}
+	newByKey := make(map[string]config.Q3Server, len(cfg.Q3Servers))
+	for _, srv := range cfg.Q3Servers {
+		newByKey[strings.ToLower(srv.Key)] = srv
+	}
+
+	for k, srv := range oldByKey {
+		next, kept := newByKey[k]
+		switch {
+		case !kept:
+			m.detachServer(srv.Key)
+			diff.Removed = append(diff.Removed, srv.Key)
+		case needsRestart(srv, next):
+			m.detachServer(srv.Key)
+		}
+	}
+
+	next := *old
+	next.Q3Servers = cfg.Q3Servers
+	next.Moderation = cfg.Moderation
+	m.cfg.Store(&next)
+
+	source := m.sourceID()
+	var errs []string
+	for _, srv := range cfg.Q3Servers {
+		prev, existed := oldByKey[strings.ToLower(srv.Key)]
+		switch {
+		case existed && !needsRestart(prev, srv):
+			if m.updateServer(old, prev, srv) {
+				diff.Updated = append(diff.Updated, srv.Key)
+			}
+			continue
+		case existed:
+			diff.Restarted = append(diff.Restarted, srv.Key)
+		default:
+			diff.Added = append(diff.Added, srv.Key)
+		}
+		m.mu.RLock()
+		notBefore := m.detached[strings.ToLower(srv.Key)]
+		m.mu.RUnlock()
+		if err := m.startServer(ctx, source, srv, notBefore); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", srv.Key, err))
+		}
+	}
+	sort.Strings(diff.Removed)
+	if len(errs) > 0 {
+		return diff, fmt.Errorf("starting servers: %s", strings.Join(errs, "; "))
+	}
+	return diff, nil
+}
+
+// detachServer stops tracking the server with key and tailing its log.
+func (m *ServerManager) detachServer(key string) {
+	m.mu.Lock()
+	var tailer *LogTailer
+	for id, state := range m.servers {
+		if strings.EqualFold(state.server.Key, key) {
+			tailer = m.tailers[id]
+			delete(m.servers, id)
+			delete(m.tailers, id)
+			break
+		}
+	}
+	m.detached[strings.ToLower(key)] = time.Now().UTC()
+	m.mu.Unlock()
+	if tailer != nil {
+		tailer.Stop()
+	}
+	metrics.LogTails.Remove(key)
+	log.Printf("Stopped tracking %s", key)
+}
+
+// updateServer applies next's in-place settings to the running server
+// that was configured as prev under oldCfg. Reports whether anything
+// changed.
+func (m *ServerManager) updateServer(oldCfg *config.Config, prev, next config.Q3Server) bool {
+	moderation := m.config().ModerationFor(next)
+	greetings := m.config().GreetingsFor(next)
+	if reflect.DeepEqual(prev, next) && reflect.DeepEqual(oldCfg.ModerationFor(prev), moderation) &&
+		reflect.DeepEqual(oldCfg.GreetingsFor(prev), greetings) {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, state := range m.servers {
+		if !strings.EqualFold(state.server.Key, next.Key) {
+			continue
+		}
+		state.chatFilter = newChatFilter(moderation)
+		if !reflect.DeepEqual(prev.MapVotePolicy(), next.MapVotePolicy()) {
+			state.mapVote = newMapVote(next.MapVotePolicy())
+		}
+		state.reservedSlots = next.ReservedSlotsPolicy()
+		state.abandon = next.AbandonPolicy()
+		state.teamBalance = next.TeamBalancePolicy()
+		state.greetings = greetings
+	}
+	return true
+}
@@ -0,0 +1,326 @@
+package collector
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// When the collector is down across a log rotation, the lines it
+// missed are in the rotated copy (q3.log.1, or q3.log.1.gz with
+// logrotate's compress), not the live log. So before replaying the
+// live log, attachTailer replays the rotated copies that hold lines
+// past the last checkpoint, oldest first.
+//
+// A checkpoint records how far into a log the collector had read. The
+// file is recognised by a hash of its first bytes, which survive a
+// rename, compression, or copytruncate's copy. Checkpoints are saved
+// once a minute and on shutdown, in log_checkpoints.json in the data
+// dir. The live log itself is still replayed from the usual cutoff.
+//
+// They're a file rather than a table because the collector has no
+// database: a remote collector only talks to the hub over NATS, and
+// needs its checkpoints before it has a connection, to decide what to
+// replay. The natsbus watermark lives in the same dir for the same
+// reason.
+
+// logCheckpointsFile keeps each server's checkpoint, by key.
+const logCheckpointsFile = "log_checkpoints.json"
+
+// checkpointHeadBytes is how much of a log's start identifies it.
+const checkpointHeadBytes = 1024
+
+// logCheckpoint is how far the collector had read the log whose first
+// HeadLen bytes hash to Head.
+type logCheckpoint struct {
+	Head    string `json:"head"`
+	HeadLen int    `json:"head_len"`
+	Offset  int64  `json:"offset"`
+}
+
+// rotatedSuffix matches logrotate's names for rotated copies: numbered
+// (.1, .2.gz) or dated with dateext (-20261016, -2026101612.gz).
+var rotatedSuffix = regexp.MustCompile(`^(\.\d+|-\d{8,10})(\.gz)?$`)
+
+// SetCheckpointDir saves each server's log checkpoint in dir, so a
+// restart can replay rotated logs it missed. Must be called before
+// Start. Unset, only rotated logs modified after the replay cutoff are
+// replayed.
+func (m *ServerManager) SetCheckpointDir(dir string) {
+	m.checkpointDir = dir
+}
+
+// loadLogCheckpoints reads the checkpoints saved in dir. A missing
+// file is no checkpoints.
+func loadLogCheckpoints(dir string) (map[string]logCheckpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, logCheckpointsFile))
+	if os.IsNotExist(err) {
+		return map[string]logCheckpoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]logCheckpoint
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", logCheckpointsFile, err)
+	}
+	return out, nil
+}
+
+// saveLogCheckpoints replaces the checkpoints saved in dir. The new
+// file is written and synced beside the old one, then renamed over it,
+// so a crash leaves one or the other whole.
+func saveLogCheckpoints(dir string, checkpoints map[string]logCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, logCheckpointsFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveCheckpoints records how far each tailer has read.
+func (m *ServerManager) saveCheckpoints() {
+	if m.checkpointDir == "" {
+		return
+	}
+	current := map[string]logCheckpoint{}
+	m.mu.RLock()
+	for id, t := range m.tailers {
+		if state, ok := m.servers[id]; ok {
+			if cp, ok := t.checkpoint(); ok {
+				current[state.server.Key] = cp
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	m.checkpointMu.Lock()
+	defer m.checkpointMu.Unlock()
+	for key, cp := range current {
+		m.checkpoints[key] = cp
+	}
+	if err := saveLogCheckpoints(m.checkpointDir, m.checkpoints); err != nil {
+		log.Printf("Warning: saving log checkpoints: %v", err)
+	}
+}
+
+// checkpoint returns how far the tailer has read its open file.
+func (t *LogTailer) checkpoint() (logCheckpoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return logCheckpoint{}, false
+	}
+	head := make([]byte, checkpointHeadBytes)
+	n, err := t.file.ReadAt(head, 0)
+	if n == 0 || (err != nil && err != io.EOF) {
+		return logCheckpoint{}, false
+	}
+	return logCheckpoint{Head: hashHead(head[:n]), HeadLen: n, Offset: t.position}, true
+}
+
+func hashHead(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// matches reports whether the log at path starts as cp's did.
+func (cp logCheckpoint) matches(path string) bool {
+	if cp.HeadLen <= 0 {
+		return false
+	}
+	r, err := openLog(path)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	head := make([]byte, cp.HeadLen)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return false
+	}
+	return hashHead(head) == cp.Head
+}
+
+// openLog opens a log for reading, decompressing a .gz one.
+func openLog(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
+
+// rotatedLog is a rotated copy of a log.
+type rotatedLog struct {
+	path    string
+	modTime time.Time
+}
+
+// rotatedLogs returns the rotated copies of the log at path, oldest
+// first.
+func rotatedLogs(path string) []rotatedLog {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	base := filepath.Base(path)
+	var out []rotatedLog
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), base)
+		if !ok || e.IsDir() || !rotatedSuffix.MatchString(suffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, rotatedLog{path: filepath.Join(filepath.Dir(path), e.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].modTime.Before(out[j].modTime) })
+	return out
+}
+
+// replayRotated replays the rotated copies of key's log that may hold
+// lines the collector never read, oldest first. With a checkpoint in a
+// rotated copy, that's every copy from it on, and lines up to the
+// checkpoint are replayed only to rebuild state. Without one, it's the
+// copies modified after startAfter, unless startAfter is zero (a fresh
+// log, whose rotated history isn't backfilled).
+func (m *ServerManager) replayRotated(key string, tailer *LogTailer, startAfter time.Time, handler func(LogEvent, bool)) {
+	m.checkpointMu.Lock()
+	cp, haveCP := m.checkpoints[key]
+	m.checkpointMu.Unlock()
+	if haveCP && cp.matches(tailer.path) {
+		return // nothing was rotated away unread
+	}
+
+	archives := rotatedLogs(tailer.path)
+	start, done := -1, int64(0)
+	if haveCP {
+		for i := len(archives) - 1; i >= 0; i-- {
+			if cp.matches(archives[i].path) {
+				start, done = i, cp.Offset
+				break
+			}
+		}
+	}
+	if start < 0 && !startAfter.IsZero() {
+		for i, a := range archives {
+			if a.modTime.After(startAfter) {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		return
+	}
+	for i, a := range archives[start:] {
+		if i > 0 {
+			done = 0
+		}
+		log.Printf("Replaying rotated log %s for %s", a.path, key)
+		r, err := openLog(a.path)
+		if err != nil {
+			log.Printf("Warning: failed to open rotated log for %s: %v", key, err)
+			continue
+		}
+		_, err = tailer.replay(r, 0, 0, startAfter, done, handler)
+		r.Close()
+		if err != nil {
+			log.Printf("Warning: failed to replay rotated log %s for %s: %v", a.path, key, err)
+		}
+	}
+}
+
+// replayProgressEvery is how often a long replay logs how far it's got.
+const replayProgressEvery = 15 * time.Second
+
+// replay calls handler for each event in r, the log file with inode
+// inode (0 for a rotated log) and size bytes (0 if not known). An
+// event is in replay mode (state rebuild only) if its line ends within
+// the first done bytes or it's no later than after. Returns the bytes
+// of complete lines read.
+func (t *LogTailer) replay(r io.Reader, inode uint64, size int64, after time.Time, done int64, handler func(LogEvent, bool)) (int64, error) {
+	reader := bufio.NewReader(r)
+	var pos int64
+	nextProgress := time.Now().Add(replayProgressEvery)
+	for {
+		if now := time.Now(); now.After(nextProgress) {
+			t.logReplayProgress(pos, size)
+			nextProgress = now.Add(replayProgressEvery)
+		}
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return pos, fmt.Errorf("reading line: %w", err)
+		}
+		pos += int64(len(line))
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		event, err := t.parse(line, false)
+		if err == nil && event != nil {
+			event.inode, event.offset = inode, pos
+			handler(*event, pos <= done || !event.Timestamp.After(after))
+		}
+	}
+	return pos, nil
+}
+
+// logReplayProgress logs that a replay has read pos bytes of size.
+func (t *LogTailer) logReplayProgress(pos, size int64) {
+	name := t.name
+	if name == "" {
+		name = t.path
+	}
+	if size > 0 {
+		log.Printf("Replaying log for %s: %d%% (%d of %d MB)", name, pos*100/size, pos>>20, size>>20)
+	} else {
+		log.Printf("Replaying log for %s: %d MB read", name, pos>>20)
+	}
+}
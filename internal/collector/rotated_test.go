@@ -0,0 +1,29 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLogCheckpointsReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	// Left behind by a save that crashed partway.
+	if err := os.WriteFile(filepath.Join(dir, logCheckpointsFile+".tmp"), []byte("{trunc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]logCheckpoint{"ffa": {Head: "abc", HeadLen: 1024, Offset: 4096}}
+	if err := saveLogCheckpoints(dir, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := loadLogCheckpoints(dir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 1 || got["ffa"] != want["ffa"] {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, logCheckpointsFile+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+}
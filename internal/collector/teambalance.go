@@ -0,0 +1,250 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// teamNames are the teams as announced in chat, and teamArgs as
+// forceteam takes them, by team number.
+var (
+	teamNames = map[int]string{domain.TeamRed: "^1Red", domain.TeamBlue: "^4Blue"}
+	teamArgs  = map[int]string{domain.TeamRed: "red", domain.TeamBlue: "blue"}
+)
+
+// sayName keeps a player's name from ending the say it's in.
+var sayName = strings.NewReplacer(";", "", `"`, "")
+
+// scoreTeam adds n to team's live score while the match is on.
+func (state *serverState) scoreTeam(team, n int) {
+	if team != domain.TeamRed && team != domain.TeamBlue {
+		return
+	}
+	if state.matchState != "active" && state.matchState != "overtime" {
+		return
+	}
+	state.teamScore[team] += n
+}
+
+// scoreFrag keeps a team deathmatch score the way the game does: a
+// point for an enemy frag, one off for a suicide or a team kill.
+// fragger is nil for the world.
+func (state *serverState) scoreFrag(fragger, victim *clientState) {
+	if state.match == nil || state.match.GameType != domain.GameTypeTDM || victim == nil {
+		return
+	}
+	switch {
+	case fragger == nil || fragger == victim:
+		state.scoreTeam(victim.team, -1)
+	case fragger.team == victim.team:
+		state.scoreTeam(fragger.team, -1)
+	default:
+		state.scoreTeam(fragger.team, 1)
+	}
+}
+
+// balancePlayer is a player on a team, with their rating.
+type balancePlayer struct {
+	client *clientState
+	rating float64
+}
+
+// rating is how strong c counts for team balance: their lifetime K/D
+// from the hub, or 1 if it has none. Bots rate their skill over
+// three, so a skill 3 bot counts as an even K/D.
+func (state *serverState) rating(c *clientState) float64 {
+	if c.isBot {
+		return c.skill / 3
+	}
+	if r, ok := state.ratings[c.guid]; ok {
+		return r
+	}
+	return 1
+}
+
+// teamPlayers returns who is playing on each team, by slot.
+func (state *serverState) teamPlayers() (red, blue []balancePlayer) {
+	for _, c := range state.clients {
+		if !c.began {
+			continue
+		}
+		p := balancePlayer{client: c, rating: state.rating(c)}
+		switch c.team {
+		case domain.TeamRed:
+			red = append(red, p)
+		case domain.TeamBlue:
+			blue = append(blue, p)
+		}
+	}
+	bySlot := func(ps []balancePlayer) {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].client.clientID < ps[j].client.clientID })
+	}
+	bySlot(red)
+	bySlot(blue)
+	return red, blue
+}
+
+// strength totals a team's ratings.
+func strength(ps []balancePlayer) float64 {
+	var s float64
+	for _, p := range ps {
+		s += p.rating
+	}
+	return s
+}
+
+// movable reports whether c may be moved. Flag carriers stay put.
+func movable(c *clientState) bool {
+	return c.flagTakenAt.IsZero()
+}
+
+// planBalance decides whether the teams are uneven under p and, if
+// so, which moves best even their ratings. Uneven sizes are fixed by
+// moving players from the bigger team until the sizes are within one;
+// an uneven score by swapping one player each way, only when that
+// brings the teams' ratings closer. It returns the reason, "size" or
+// "score", and no moves when the teams are even or nothing helps.
+func planBalance(p config.TeamBalanceConfig, red, blue []balancePlayer, redScore, blueScore int) (string, []domain.TeamBalanceMove) {
+	big, small, to := red, blue, domain.TeamBlue
+	if len(blue) > len(red) {
+		big, small, to = blue, red, domain.TeamRed
+	}
+	if diff := len(big) - len(small); diff > p.MaxSizeDiff {
+		big = append([]balancePlayer(nil), big...)
+		bigStrength, smallStrength := strength(big), strength(small)
+		var moves []domain.TeamBalanceMove
+		for n := diff / 2; n > 0; n-- {
+			best := -1
+			var bestGap float64
+			for i, c := range big {
+				if !movable(c.client) {
+					continue
+				}
+				gap := math.Abs((bigStrength - c.rating) - (smallStrength + c.rating))
+				// On a tie the latest to join moves.
+				if best < 0 || gap < bestGap || (gap == bestGap && c.client.joinedAt.After(big[best].client.joinedAt)) {
+					best, bestGap = i, gap
+				}
+			}
+			if best < 0 {
+				break
+			}
+			c := big[best]
+			big = append(big[:best], big[best+1:]...)
+			bigStrength -= c.rating
+			smallStrength += c.rating
+			moves = append(moves, balanceMove(c.client, to))
+		}
+		if len(moves) == 0 {
+			return "", nil
+		}
+		return "size", moves
+	}
+
+	if p.MaxScoreDiff == 0 || abs(redScore-blueScore) <= p.MaxScoreDiff {
+		return "", nil
+	}
+	leader, trailer, toLeader, toTrailer := red, blue, domain.TeamRed, domain.TeamBlue
+	if blueScore > redScore {
+		leader, trailer, toLeader, toTrailer = blue, red, domain.TeamBlue, domain.TeamRed
+	}
+	gap := strength(leader) - strength(trailer)
+	var from, with *balancePlayer
+	bestGap := math.Abs(gap)
+	for i := range leader {
+		for j := range trailer {
+			a, b := &leader[i], &trailer[j]
+			if a.rating <= b.rating || !movable(a.client) || !movable(b.client) {
+				continue
+			}
+			if g := math.Abs(gap - 2*(a.rating-b.rating)); g < bestGap {
+				from, with, bestGap = a, b, g
+			}
+		}
+	}
+	if from == nil {
+		return "", nil
+	}
+	return "score", []domain.TeamBalanceMove{
+		balanceMove(from.client, toTrailer),
+		balanceMove(with.client, toLeader),
+	}
+}
+
+func balanceMove(c *clientState, to int) domain.TeamBalanceMove {
+	return domain.TeamBalanceMove{ClientNum: c.clientID, PlayerName: c.name, ToTeam: to, GUID: c.guid}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// checkTeamBalance runs after each live event. Once a team game's
+// teams have stayed uneven for the policy's grace, it announces the
+// moves that even them, makes them with forceteam if the policy
+// enforces, and tells the web UI. Runs under m.mu.
+func (m *ServerManager) checkTeamBalance(serverID int64, state *serverState, ts time.Time) {
+	p := state.teamBalance
+	if p == nil || state.match == nil || !domain.IsTeamGame(state.match.GameType) ||
+		(state.matchState != "active" && state.matchState != "overtime") {
+		state.unevenSince = time.Time{}
+		return
+	}
+	red, blue := state.teamPlayers()
+	reason, moves := planBalance(*p, red, blue, state.teamScore[domain.TeamRed], state.teamScore[domain.TeamBlue])
+	if len(moves) == 0 {
+		state.unevenSince = time.Time{}
+		return
+	}
+	if state.unevenSince.IsZero() {
+		state.unevenSince = ts
+	}
+	if ts.Sub(state.unevenSince) < time.Duration(p.Grace) ||
+		(!state.balancedAt.IsZero() && ts.Sub(state.balancedAt) < time.Duration(p.Cooldown)) {
+		return
+	}
+	state.unevenSince = time.Time{}
+	state.balancedAt = ts
+
+	parts := make([]string, len(moves))
+	for i, mv := range moves {
+		parts[i] = fmt.Sprintf("^7%s ^3to %s", sayName.Replace(mv.PlayerName), teamNames[mv.ToTeam])
+	}
+	heading := "^3Teams are uneven. Suggested: "
+	if p.Enforce {
+		heading = "^3Balancing teams: "
+	}
+	m.queueRcon(serverID, "say "+heading+strings.Join(parts, "^3, ")+"^3.")
+	if p.Enforce {
+		for _, mv := range moves {
+			m.queueRcon(serverID, fmt.Sprintf("forceteam %d %s", mv.ClientNum, teamArgs[mv.ToTeam]))
+		}
+	}
+	log.Printf("collector: team balance on %s (%s, %dv%d, %d-%d): %d move(s), enforced=%t",
+		state.server.Key, reason, len(red), len(blue), state.teamScore[domain.TeamRed], state.teamScore[domain.TeamBlue], len(moves), p.Enforce)
+
+	m.emitEvent(domain.Event{
+		Type:      domain.EventTeamBalance,
+		ServerID:  serverID,
+		Timestamp: ts,
+		Data: domain.TeamBalanceEvent{
+			Reason:    reason,
+			RedCount:  len(red),
+			BlueCount: len(blue),
+			RedScore:  state.teamScore[domain.TeamRed],
+			BlueScore: state.teamScore[domain.TeamBlue],
+			Moves:     moves,
+			Enforced:  p.Enforce,
+		},
+	})
+}
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestPlanBalance(t *testing.T) {
+	at := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+	player := func(id int, rating float64) balancePlayer {
+		return balancePlayer{client: &clientState{clientID: id, name: string(rune('a' + id)), joinedAt: at}, rating: rating}
+	}
+	carrier := player(1, 1)
+	carrier.client.flagTakenAt = at
+	late := player(2, 0.5)
+	late.client.joinedAt = at.Add(time.Minute)
+
+	policy := config.TeamBalanceConfig{MaxSizeDiff: 1, MaxScoreDiff: 20}
+	for _, tc := range []struct {
+		name                string
+		red, blue           []balancePlayer
+		redScore, blueScore int
+		reason              string
+		want                []domain.TeamBalanceMove
+	}{
+		{
+			name: "even",
+			red:  []balancePlayer{player(0, 2), player(1, 1)},
+			blue: []balancePlayer{player(2, 1), player(3, 1)},
+		},
+		{
+			name:   "three on one",
+			red:    []balancePlayer{player(0, 2), player(1, 1), player(2, 0.5)},
+			blue:   []balancePlayer{player(3, 1)},
+			reason: "size",
+			want:   []domain.TeamBalanceMove{{ClientNum: 1, PlayerName: "b", ToTeam: domain.TeamBlue}},
+		},
+		{
+			name:   "five on one",
+			red:    []balancePlayer{player(4, 1)},
+			blue:   []balancePlayer{player(0, 1), player(1, 1), player(2, 1), player(3, 1), player(5, 1)},
+			reason: "size",
+			want: []domain.TeamBalanceMove{
+				{ClientNum: 0, PlayerName: "a", ToTeam: domain.TeamRed},
+				{ClientNum: 1, PlayerName: "b", ToTeam: domain.TeamRed},
+			},
+		},
+		{
+			name:   "flag carrier stays, latest joiner moves",
+			red:    []balancePlayer{player(0, 2), carrier, late},
+			blue:   []balancePlayer{player(3, 1)},
+			reason: "size",
+			want:   []domain.TeamBalanceMove{{ClientNum: 2, PlayerName: "c", ToTeam: domain.TeamBlue}},
+		},
+		{
+			name:      "blowout",
+			red:       []balancePlayer{player(0, 2), player(1, 1)},
+			blue:      []balancePlayer{player(2, 1), player(3, 0.5)},
+			redScore:  30,
+			blueScore: 5,
+			reason:    "score",
+			want: []domain.TeamBalanceMove{
+				{ClientNum: 0, PlayerName: "a", ToTeam: domain.TeamBlue},
+				{ClientNum: 2, PlayerName: "c", ToTeam: domain.TeamRed},
+			},
+		},
+		{
+			name:      "close game",
+			red:       []balancePlayer{player(0, 2), player(1, 1)},
+			blue:      []balancePlayer{player(2, 1), player(3, 0.5)},
+			redScore:  20,
+			blueScore: 5,
+		},
+		{
+			name:      "blowout by the weaker team",
+			red:       []balancePlayer{player(0, 0.5), player(1, 0.5)},
+			blue:      []balancePlayer{player(2, 1), player(3, 1)},
+			redScore:  30,
+			blueScore: 5,
+		},
+	} {
+		reason, moves := planBalance(policy, tc.red, tc.blue, tc.redScore, tc.blueScore)
+		if reason != tc.reason || len(moves) != len(tc.want) {
+			t.Errorf("%s: got %q %+v, want %q %+v", tc.name, reason, moves, tc.reason, tc.want)
+			continue
+		}
+		for i := range moves {
+			if moves[i] != tc.want[i] {
+				t.Errorf("%s: move %d = %+v, want %+v", tc.name, i, moves[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestTeamBalanceEvent(t *testing.T) {
+	m, _, feed := newWarmupTestManager(t)
+	state := m.servers[1]
+	state.match.GameType = domain.GameTypeTDM
+	state.teamBalance = &config.TeamBalanceConfig{MaxSizeDiff: 1, Grace: config.Duration(5 * time.Second), Cooldown: config.Duration(time.Minute)}
+	state.ratings = map[string]float64{"AAAA": 2, "BBBB": 0.5}
+	state.clients[0].team = domain.TeamRed
+	state.clients[1].team = domain.TeamRed
+	state.clients[1].skill = 3
+	state.clients[2] = &clientState{clientID: 2, name: "bob", guid: "BBBB", began: true, team: domain.TeamRed}
+	state.clients[3] = &clientState{clientID: 3, name: "Doom", guid: "BOT:Doom", isBot: true, skill: 3, began: true, team: domain.TeamBlue}
+
+	feed(EventTypeMatchState, MatchStateData{State: "active"})
+	for i := 0; i < 20; i++ {
+		feed(EventTypeFrag, FragEventData{FraggerID: 3, VictimID: 2})
+	}
+
+	var got []domain.TeamBalanceEvent
+	for len(m.events) > 0 {
+		if e := <-m.events; e.Type == domain.EventTeamBalance {
+			got = append(got, e.Data.(domain.TeamBalanceEvent))
+		}
+	}
+	// Once after the grace, then not again inside the cooldown.
+	if len(got) != 1 {
+		t.Fatalf("got %d team_balance events, want 1", len(got))
+	}
+	e := got[0]
+	if e.Reason != "size" || e.RedCount != 3 || e.BlueCount != 1 || e.BlueScore != 5 || e.Enforced {
+		t.Errorf("event = %+v", e)
+	}
+	if len(e.Moves) != 1 || e.Moves[0].ClientNum != 1 || e.Moves[0].ToTeam != domain.TeamBlue {
+		t.Errorf("moves = %+v, want Sarge to blue", e.Moves)
+	}
+}
@@ -1,6 +1,9 @@
 package collector
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strconv"
@@ -8,11 +11,14 @@ import (
 	"time"
 
 	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	q3Header    = "\xff\xff\xff\xff"
-	getStatus   = q3Header + "getstatus\n"
+	getStatus   = q3Header + "getstatus"
 	rconPrefix  = q3Header + "rcon "
 	printPrefix = q3Header + "print\n"
 	timeout     = 2 * time.Second
@@ -28,33 +34,88 @@ func NewQ3Client() *Q3Client {
 	return &Q3Client{}
 }
 
-// QueryStatus queries a Q3 server and returns its status
+// QueryStatus queries a Q3 server and returns its status. The request
+// carries a random challenge, which the server echoes back in its
+// serverinfo. Replies that don't come from the server's address or
+// don't echo the challenge are dropped as spoofed, so a forged packet
+// can't stand in for the server's status or player list.
 func (c *Q3Client) QueryStatus(address string) (*domain.ServerStatus, error) {
 	conn, err := net.DialTimeout("udp", address, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to %s: %w", address, err)
 	}
 	defer conn.Close()
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return nil, fmt.Errorf("connecting to %s: not a UDP connection", address)
+	}
+	peer := udp.RemoteAddr().(*net.UDPAddr)
 
-	conn.SetDeadline(time.Now().Add(timeout))
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, err
+	}
+	udp.SetDeadline(time.Now().Add(timeout))
 
 	// Send getstatus request
-	if _, err := conn.Write([]byte(getStatus)); err != nil {
+	if _, err := udp.Write([]byte(getStatus + " " + challenge + "\n")); err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
 	}
 
-	// Read response
+	// Read until a reply passes, or the deadline. rejected keeps why
+	// the last reply was dropped, to report if nothing better comes.
 	buf := make([]byte, maxResponse)
-	n, err := conn.Read(buf)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+	var rejected error
+	for {
+		n, from, err := udp.ReadFromUDP(buf)
+		if err != nil {
+			if rejected != nil {
+				return nil, rejected
+			}
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+		if !from.IP.Equal(peer.IP) || from.Port != peer.Port {
+			rejected = fmt.Errorf("response from %s, not %s", from, peer)
+			continue
+		}
+		status, err := parseStatusResponse(address, buf[:n])
+		if err != nil {
+			rejected = err
+			continue
+		}
+		if status.ServerVars["challenge"] != challenge {
+			rejected = fmt.Errorf("response does not match the challenge")
+			continue
+		}
+		delete(status.ServerVars, "challenge")
+		return status, nil
 	}
+}
 
-	return parseStatusResponse(address, buf[:n])
+// newChallenge returns a random token for a getstatus request.
+func newChallenge() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating challenge: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // RconCommand sends an RCON command to a Q3 server and returns the response
 func (c *Q3Client) RconCommand(address, password, command string) (string, error) {
+	// Only the command's name goes on the span: its arguments can be
+	// chat text or a player's name.
+	name, _, _ := strings.Cut(command, " ")
+	_, span := tracing.Tracer().Start(context.Background(), "rcon "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", address)),
+	)
+	reply, err := c.rconCommand(address, password, command)
+	tracing.End(span, err)
+	return reply, err
+}
+
+func (c *Q3Client) rconCommand(address, password, command string) (string, error) {
 	conn, err := net.DialTimeout("udp", address, rconTimeout)
 	if err != nil {
 		return "", fmt.Errorf("connecting to %s: %w", address, err)
@@ -165,6 +226,7 @@ func parseStatusResponse(address string, data []byte) (*domain.ServerStatus, err
 	if levelTime, ok := parseIntVar(vars, "g_leveltime"); ok {
 		if levelStartTime, ok := parseIntVar(vars, "g_levelstarttime"); ok {
 			status.GameTimeMs = levelTime - levelStartTime
+			status.ClockSource = domain.ClockServer
 		}
 		// Calculate warmup remaining from absolute warmup end time
 		if warmupEndTime, ok := parseIntVar(vars, "g_warmupendtime"); ok && warmupEndTime > 0 {
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// vipKickMessage is printed to a human before they're kicked for a VIP.
+const vipKickMessage = "^3You are being moved off this server to free a reserved slot. Sorry!"
+
+// lastSeenActive is when c last did something: fragged, died, spoke,
+// or changed team. Joining counts.
+func (c *clientState) lastSeenActive() time.Time {
+	if c.lastActive.After(c.joinedAt) {
+		return c.lastActive
+	}
+	return c.joinedAt
+}
+
+// pickVIPKick returns whom to kick to make room for a VIP in
+// clientID's slot: the longest idle bot, or with p.KickSpectators the
+// longest idle spectator who isn't a VIP themselves. Players on a team
+// are never picked. Returns nil if there is no one to kick.
+func pickVIPKick(p config.ReservedSlotsConfig, clients map[int]*clientState, vips map[string]bool, clientID int) *clientState {
+	var bot, spec *clientState
+	for id, c := range clients {
+		if id == clientID {
+			continue
+		}
+		switch {
+		case c.isBot:
+			if bot == nil || idleLonger(c, bot) {
+				bot = c
+			}
+		case p.KickSpectators && c.team == 3 && !vips[c.guid]:
+			if spec == nil || idleLonger(c, spec) {
+				spec = c
+			}
+		}
+	}
+	if bot != nil {
+		return bot
+	}
+	return spec
+}
+
+// idleLonger orders candidates by last activity, then slot, so the
+// pick doesn't depend on map order.
+func idleLonger(a, b *clientState) bool {
+	at, bt := a.lastSeenActive(), b.lastSeenActive()
+	if !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	return a.clientID < b.clientID
+}
+
+// makeRoomForVIP runs after a VIP's greet. If the server has reserved
+// slots and fewer than that many are free, it kicks someone to free
+// one and reports the kick to the hub for the audit log.
+func (m *ServerManager) makeRoomForVIP(serverID int64, clientID int, guid, name string) {
+	m.mu.RLock()
+	state, ok := m.servers[serverID]
+	if !ok {
+		m.mu.RUnlock()
+		return
+	}
+	policy, address, key := state.reservedSlots, state.server.Address, state.server.Key
+	m.mu.RUnlock()
+	if policy == nil {
+		return
+	}
+
+	status, err := m.q3client.QueryStatus(address)
+	if err != nil {
+		log.Printf("collector: reserved slots: status for %s: %v", key, err)
+		return
+	}
+	if free := status.MaxClients - len(status.Players); free >= policy.Slots {
+		return
+	}
+
+	m.mu.RLock()
+	victim := pickVIPKick(*policy, state.clients, state.vips, clientID)
+	var kicked domain.VIPKickData
+	if victim != nil {
+		kicked = domain.VIPKickData{
+			VIPGUID:      guid,
+			VIPName:      name,
+			KickedGUID:   victim.guid,
+			KickedName:   victim.name,
+			KickedClient: victim.clientID,
+			KickedIsBot:  victim.isBot,
+		}
+	}
+	m.mu.RUnlock()
+	if victim == nil {
+		log.Printf("collector: reserved slots: %s is full but there is no bot or spectator to kick for %s", key, domain.CleanQ3Name(name))
+		return
+	}
+
+	if !kicked.KickedIsBot {
+		m.sendPrintSync(serverID, kicked.KickedClient, vipKickMessage)
+	}
+	if _, err := m.ExecuteRcon(serverID, fmt.Sprintf("clientkick %d", kicked.KickedClient)); err != nil {
+		log.Printf("collector: reserved slots: kick client %d on %s: %v", kicked.KickedClient, key, err)
+		return
+	}
+	log.Printf("collector: reserved slots: kicked %s from %s for VIP %s",
+		domain.CleanQ3Name(kicked.KickedName), key, domain.CleanQ3Name(name))
+	kicked.At = time.Now().UTC()
+	m.pub.Publish(domain.FactEvent{
+		Type:      domain.FactVIPKick,
+		ServerID:  serverID,
+		Timestamp: kicked.At,
+		Data:      kicked,
+	})
+}
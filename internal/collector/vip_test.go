@@ -0,0 +1,28 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/hub"
+)
+
+func TestVIPNeedsVerifiedAuth(t *testing.T) {
+	for _, tc := range []struct {
+		auth hub.AuthResult
+		want bool
+	}{
+		{hub.AuthVerified, true},
+		{hub.AuthUnauthenticated, false},
+	} {
+		m, _, _ := newWarmupTestManager(t)
+		m.servers[1].greetings = &config.GreetingsConfig{}
+		m.rpc = greetRPC{reply: hub.GreetReply{IsVIP: true, AuthResult: tc.auth}}
+
+		m.performGreet(context.Background(), 1, 0, "AAAA", "alice", "alice", true, true, nil)
+		if got := m.servers[1].vips["AAAA"]; got != tc.want {
+			t.Errorf("%s greet for a VIP GUID: vip = %t, want %t", tc.auth, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"sort"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// countWarmupFrag adds a warmup frag to warmupStats: a kill for the
+// fragger unless it was a suicide, and a death for the victim. Either
+// may be nil for a client the collector doesn't know.
+func (state *serverState) countWarmupFrag(fragger, victim *clientState) {
+	if fragger != nil && fragger != victim {
+		if p := state.warmupPlayer(fragger); p != nil {
+			p.Frags++
+		}
+	}
+	if victim != nil {
+		if p := state.warmupPlayer(victim); p != nil {
+			p.Deaths++
+		}
+	}
+}
+
+// warmupPlayer returns c's warmup stats, or nil if c has no GUID yet.
+func (state *serverState) warmupPlayer(c *clientState) *domain.WarmupPlayer {
+	if c.guid == "" {
+		return nil
+	}
+	if state.warmupStats == nil {
+		state.warmupStats = make(map[string]*domain.WarmupPlayer)
+	}
+	p, ok := state.warmupStats[c.guid]
+	if !ok {
+		p = &domain.WarmupPlayer{GUID: c.guid}
+		state.warmupStats[c.guid] = p
+	}
+	return p
+}
+
+// warmupPlayers lists warmupStats for match_start, by GUID.
+func (state *serverState) warmupPlayers() []domain.WarmupPlayer {
+	if len(state.warmupStats) == 0 {
+		return nil
+	}
+	out := make([]domain.WarmupPlayer, 0, len(state.warmupStats))
+	for _, p := range state.warmupStats {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].GUID < out[j].GUID })
+	return out
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []domain.FactEvent
+}
+
+func (p *recordingPublisher) Publish(e domain.FactEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
+
+// matchStarts returns the match_start facts published so far.
+func (p *recordingPublisher) matchStarts() []domain.MatchStartData {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []domain.MatchStartData
+	for _, e := range p.events {
+		if e.Type == domain.FactMatchStart {
+			out = append(out, e.Data.(domain.MatchStartData))
+		}
+	}
+	return out
+}
+
+// newWarmupTestManager returns a manager tracking one server, with
+// alice and a bot already on it.
+func newWarmupTestManager(t *testing.T) (*ServerManager, *recordingPublisher, func(typ string, data any)) {
+	t.Helper()
+	pub := &recordingPublisher{}
+	m := NewServerManager(&config.Config{}, nil, nil, pub)
+	t.Cleanup(func() { close(m.done) })
+	m.servers[1] = &serverState{
+		server:       domain.Server{ID: 1, Key: "ffa"},
+		clients:      make(map[int]*clientState),
+		openSessions: make(map[string]bool),
+		vips:         make(map[string]bool),
+		admins:       make(map[int]string),
+	}
+	at := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+	feed := func(typ string, data any) {
+		at = at.Add(time.Second)
+		m.handleLogEvent(context.Background(), 1, LogEvent{Timestamp: at, Type: typ, Data: data}, false)
+	}
+	feed(EventTypeInitGame, InitGameData{MapName: "q3dm17", UUID: "m1", Settings: map[string]string{"g_trinityhandshake": "1"}})
+	m.servers[1].clients[0] = &clientState{clientID: 0, name: "alice", guid: "AAAA", began: true}
+	m.servers[1].clients[1] = &clientState{clientID: 1, name: "Sarge", guid: "BOT:Sarge", isBot: true, began: true}
+	return m, pub, feed
+}
+
+func TestWarmupStats(t *testing.T) {
+	m, pub, feed := newWarmupTestManager(t)
+
+	feed(EventTypeWarmup, WarmupData{Duration: 20})
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 1})
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 1})
+	feed(EventTypeFrag, FragEventData{FraggerID: 1, VictimID: 0})
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 0}) // suicide
+	// A MatchState active before WarmupEnd doesn't start the match
+	// early on a server that warms up.
+	feed(EventTypeMatchState, MatchStateData{State: "active"})
+	if n := len(pub.matchStarts()); n != 0 {
+		t.Fatalf("got %d match_starts before WarmupEnd, want 0", n)
+	}
+	feed(EventTypeWarmupEnd, nil)
+
+	starts := pub.matchStarts()
+	if len(starts) != 1 {
+		t.Fatalf("got %d match_starts, want 1", len(starts))
+	}
+	want := []domain.WarmupPlayer{{GUID: "AAAA", Frags: 2, Deaths: 2}, {GUID: "BOT:Sarge", Frags: 1, Deaths: 2}}
+	if got := starts[0].Warmup; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("warmup = %+v, want %+v", got, want)
+	}
+
+	// Warmup frags stay out of the match's stats.
+	if c := m.servers[1].clients[0]; c.frags != 0 || c.deaths != 0 {
+		t.Errorf("alice's match stats = %d/%d, want 0/0", c.frags, c.deaths)
+	}
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 1})
+	if c := m.servers[1].clients[0]; c.frags != 1 {
+		t.Errorf("alice's match frags = %d, want 1", c.frags)
+	}
+	if m.servers[1].warmupStats != nil {
+		t.Errorf("warmup stats kept after the match started: %+v", m.servers[1].warmupStats)
+	}
+}
+
+func TestNoWarmupStartsMatch(t *testing.T) {
+	m, pub, feed := newWarmupTestManager(t)
+
+	// g_doWarmup 0: no Warmup and no WarmupEnd, straight to active.
+	feed(EventTypeMatchState, MatchStateData{State: "active"})
+	starts := pub.matchStarts()
+	if len(starts) != 1 || starts[0].MatchUUID != "m1" || len(starts[0].Warmup) != 0 {
+		t.Fatalf("match_starts = %+v, want one for m1 without warmup", starts)
+	}
+	if !m.servers[1].matchStarted {
+		t.Error("match not started")
+	}
+	feed(EventTypeFrag, FragEventData{FraggerID: 0, VictimID: 1})
+	if c := m.servers[1].clients[0]; c.frags != 1 {
+		t.Errorf("alice's match frags = %d, want 1", c.frags)
+	}
+
+	// Nor does a later WarmupEnd start it twice.
+	feed(EventTypeWarmupEnd, nil)
+	if n := len(pub.matchStarts()); n != 1 {
+		t.Errorf("got %d match_starts, want 1", n)
+	}
+}
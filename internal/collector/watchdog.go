@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// The watchdog catches a log tailer that has gone quiet while the
+// server is plainly in use: getstatus shows humans on it but no new
+// lines have arrived for stallThreshold. The usual cause is the log
+// being rotated out from under the open file handle. Each check while
+// the stall persists reopens the log; after maxStallReopens failed
+// attempts the incident is handed to the StallNotifier.
+const (
+	watchdogInterval = time.Minute
+	stallThreshold   = 5 * time.Minute
+	maxStallReopens  = 3
+)
+
+// StallNotifier is told when the watchdog can't get a stalled log
+// tailer going again. Called from the watchdog goroutine, so
+// implementations must not block.
+type StallNotifier interface {
+	NotifyStall(serverKey, logPath string, silentFor time.Duration, humans int)
+}
+
+// SetStallNotifier sets who hears about stalls the watchdog couldn't
+// fix. Must be called before Start. Unset, they are only logged.
+func (m *ServerManager) SetStallNotifier(n StallNotifier) {
+	m.stallNotifier = n
+}
+
+// stallIncident tracks one server's stall from detection to recovery.
+type stallIncident struct {
+	since    time.Time // last line read before the stall
+	reopens  int
+	notified bool
+}
+
+func (m *ServerManager) watchdogLoop(ctx context.Context) {
+	defer m.wg.Done()
+	incidents := make(map[int64]*stallIncident)
+	failedUnits := make(map[int64]bool)
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.checkTailers(incidents, now)
+			m.checkUnits(ctx, failedUnits, now)
+			m.saveCheckpoints()
+		}
+	}
+}
+
+// checkTailers runs one watchdog pass over every attached tailer.
+func (m *ServerManager) checkTailers(incidents map[int64]*stallIncident, now time.Time) {
+	type target struct {
+		key, address string
+		tailer       *LogTailer
+	}
+	m.mu.RLock()
+	targets := make(map[int64]target, len(m.tailers))
+	for id, t := range m.tailers {
+		if state, ok := m.servers[id]; ok {
+			targets[id] = target{key: state.server.Key, address: state.server.Address, tailer: t}
+		}
+	}
+	m.mu.RUnlock()
+
+	for id, tg := range targets {
+		silent := now.Sub(tg.tailer.LastRead())
+		inc := incidents[id]
+		if silent < stallThreshold {
+			if inc != nil {
+				log.Printf("collector: watchdog: %s log recovered after %s silent", tg.key, now.Sub(inc.since).Round(time.Second))
+				delete(incidents, id)
+			}
+			continue
+		}
+		// Quiet logs are normal on an empty server.
+		status, err := m.q3client.QueryStatus(tg.address)
+		if err != nil || status.HumanCount == 0 {
+			if inc != nil {
+				log.Printf("collector: watchdog: %s has emptied; closing stall incident", tg.key)
+				delete(incidents, id)
+			}
+			continue
+		}
+
+		if inc == nil {
+			inc = &stallIncident{since: tg.tailer.LastRead()}
+			incidents[id] = inc
+			log.Printf("collector: watchdog: %s log %s silent for %s with %d players online",
+				tg.key, tg.tailer.path, silent.Round(time.Second), status.HumanCount)
+		}
+		if inc.reopens < maxStallReopens {
+			inc.reopens++
+			rotated, err := tg.tailer.Reopen()
+			switch {
+			case err != nil:
+				log.Printf("collector: watchdog: reopen %s (attempt %d): %v", tg.key, inc.reopens, err)
+			case rotated:
+				log.Printf("collector: watchdog: %s log was rotated; now tailing the new file", tg.key)
+			default:
+				log.Printf("collector: watchdog: reopened %s log (attempt %d)", tg.key, inc.reopens)
+			}
+			continue
+		}
+		if !inc.notified {
+			inc.notified = true
+			log.Printf("collector: watchdog: %s log still silent after %d reopens; giving up until it recovers", tg.key, inc.reopens)
+			if m.stallNotifier != nil {
+				m.stallNotifier.NotifyStall(tg.key, tg.tailer.path, now.Sub(inc.since), status.HumanCount)
+			}
+		}
+	}
+}
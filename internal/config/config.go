@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -34,8 +36,19 @@ type Config struct {
 	Database  *DatabaseConfig `yaml:"database,omitempty"`
 	Auth      *AuthConfig     `yaml:"auth,omitempty"`
 	Discord   *DiscordConfig  `yaml:"discord,omitempty"`
+	Features  *FeaturesConfig `yaml:"features,omitempty"`
+	Branding  *BrandingConfig `yaml:"branding,omitempty"`
 	Q3Servers []Q3Server      `yaml:"q3_servers,omitempty"`
 	Tracker   *TrackerConfig  `yaml:"tracker,omitempty"`
+	// Moderation is the default chat policy; q3_servers[].moderation
+	// overrides it per server.
+	Moderation *ModerationConfig `yaml:"moderation,omitempty"`
+	// Greetings sets what players are told when they join;
+	// q3_servers[].greetings overrides it per server.
+	Greetings *GreetingsConfig `yaml:"greetings,omitempty"`
+	Tracing   *TracingConfig   `yaml:"tracing,omitempty"`
+	// Leaderboard decides who places on the leaderboards.
+	Leaderboard *LeaderboardConfig `yaml:"leaderboard,omitempty"`
 }
 
 // Duration extends time.Duration's YAML parsing to accept a "d" (days) suffix
@@ -110,11 +123,91 @@ type NATSConfig struct {
 	KeyFile         string `yaml:"key_file,omitempty"`
 }
 
-// HubConfig configures the aggregator role.
+// HubConfig configures the aggregator role. ChatRetention is how long
+// match chat is kept when features.chat_persistence is on.
 type HubConfig struct {
-	DedupWindow Duration         `yaml:"dedup_window"`
-	Retention   Duration         `yaml:"retention"`
-	Directory   *DirectoryConfig `yaml:"directory,omitempty"`
+	DedupWindow   Duration           `yaml:"dedup_window"`
+	Retention     Duration           `yaml:"retention"`
+	ChatRetention Duration           `yaml:"chat_retention,omitempty"`
+	Directory     *DirectoryConfig   `yaml:"directory,omitempty"`
+	Export        *ExportConfig      `yaml:"export,omitempty"`
+	DemoUploads   *DemoUploadsConfig `yaml:"demo_uploads,omitempty"`
+	SessionIPs    *SessionIPsConfig  `yaml:"session_ips,omitempty"`
+	HealthPing    *HealthPingConfig  `yaml:"health_ping,omitempty"`
+	// MasterServers are the host:port masters the admin server
+	// browser asks for public servers. Empty means the ioquake3 and
+	// id masters.
+	MasterServers []string `yaml:"master_servers,omitempty"`
+}
+
+// HealthPingConfig has the hub GET URL (a healthchecks.io check or any
+// uptime monitor's heartbeat URL) every Interval, but only while the
+// database answers and no active collector has gone StaleAfter without
+// a heartbeat. The monitor then alerts on internal health, not just
+// on the web server being reachable.
+type HealthPingConfig struct {
+	URL        string   `yaml:"url"`
+	Interval   Duration `yaml:"interval,omitempty"`
+	StaleAfter Duration `yaml:"stale_after,omitempty"`
+}
+
+// Ways SessionIPsConfig.Scrub can scrub an IP.
+const (
+	IPScrubTruncate = "truncate"
+	IPScrubHash     = "hash"
+	IPScrubDelete   = "delete"
+)
+
+// SessionIPsConfig limits how long the hub keeps the IP address each
+// session was played from. Without the block, IPs are kept forever.
+// Once a session is older than Retention its IP is scrubbed: truncate
+// (the default) keeps only the IPv4 /24 or IPv6 /48, which merge
+// suggestions still group by; hash replaces it with a hash keyed by
+// auth.jwt_secret, so repeat visits still match but the address is
+// gone; delete blanks it. Store false stops recording IPs at all and
+// blanks the ones already stored.
+type SessionIPsConfig struct {
+	Store     *bool    `yaml:"store,omitempty"`
+	Retention Duration `yaml:"retention,omitempty"`
+	Scrub     string   `yaml:"scrub,omitempty"`
+}
+
+// StoreEnabled reports whether session IPs are recorded. On unless
+// set false. Safe on nil.
+func (c *SessionIPsConfig) StoreEnabled() bool {
+	return c == nil || c.Store == nil || *c.Store
+}
+
+// DemoUploadsConfig sets where admin-uploaded .dm_68 demos are kept
+// and how much disk they may use. Always on for a hub; the block only
+// overrides the defaults. Dir defaults to demo_uploads/ next to the
+// database. When the files add up to more than QuotaMB, the least
+// recently downloaded demos are deleted after each upload.
+type DemoUploadsConfig struct {
+	Dir       string `yaml:"dir,omitempty"`
+	MaxFileMB int    `yaml:"max_file_mb,omitempty"`
+	QuotaMB   int    `yaml:"quota_mb,omitempty"`
+}
+
+// ExportConfig configures periodic CSV snapshots of the leaderboard
+// and recently finished matches, uploaded to an S3-compatible bucket
+// (AWS S3, Cloudflare R2, MinIO, Backblaze B2, ...). Off by default;
+// Enabled must be true before the hub starts uploading.
+//
+// Endpoint is the service's base URL (e.g. https://s3.us-east-1.amazonaws.com
+// or https://<account>.r2.cloudflarestorage.com). Objects are
+// addressed path-style (<endpoint>/<bucket>/<key>), which every
+// S3-compatible service accepts. AccessKeyID/SecretAccessKey are
+// credentials for a key that only needs PutObject on the bucket.
+type ExportConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	Endpoint        string   `yaml:"endpoint"`
+	Region          string   `yaml:"region,omitempty"`
+	Bucket          string   `yaml:"bucket"`
+	Prefix          string   `yaml:"prefix,omitempty"`
+	AccessKeyID     string   `yaml:"access_key_id"`
+	SecretAccessKey string   `yaml:"secret_access_key"`
+	Interval        Duration `yaml:"interval,omitempty"`
 }
 
 // DirectoryConfig configures the optional Quake 3 directory (a.k.a.
@@ -158,17 +251,101 @@ type CollectorConfig struct {
 	HeartbeatInterval Duration `yaml:"heartbeat_interval"`
 	PublicURL         string   `yaml:"public_url"`
 	HubHost           string   `yaml:"hub_host"`
+	// GeoIPDatabase is an optional path to a MaxMind GeoLite2-City
+	// .mmdb file. When set, the collector resolves each joining
+	// player's IP to country/city and the hub stores it on the
+	// session row. Unset disables lookups entirely.
+	GeoIPDatabase string `yaml:"geoip_database,omitempty"`
+	// EventArchive, when set, keeps a copy of every parsed log event.
+	EventArchive *EventArchiveConfig `yaml:"event_archive,omitempty"`
+}
+
+// EventArchiveConfig keeps a write-ahead copy of every log event the
+// collector parses, as JSON lines in <Dir>/<server key>.jsonl, so
+// matches can be reprocessed after parser fixes without keeping the
+// game logs. Once a file passes MaxFileMB it's gzipped to
+// <server key>-<time>.jsonl.gz and a new one started. Keep caps the
+// compressed files kept per server, oldest deleted first; 0 keeps
+// them all. Dir defaults to event_archive/ under data_dir.
+type EventArchiveConfig struct {
+	Dir       string `yaml:"dir,omitempty"`
+	MaxFileMB int    `yaml:"max_file_mb,omitempty"`
+	Keep      int    `yaml:"keep,omitempty"`
 }
 
 // AuthConfig holds authentication settings
 type AuthConfig struct {
 	JWTSecret     string        `yaml:"jwt_secret"`
 	TokenDuration time.Duration `yaml:"token_duration"`
+	// RefreshTokenDuration is how long a login can be renewed through
+	// /api/auth/refresh without the password. Default 30 days.
+	RefreshTokenDuration time.Duration `yaml:"refresh_token_duration,omitempty"`
+	// OAuth lets users sign in with an external identity provider
+	// instead of a password. Off unless providers are listed.
+	OAuth *OAuthConfig `yaml:"oauth,omitempty"`
+}
+
+// OAuthConfig lists the identity providers offered on the login form.
+// PublicURL is the hub's address as users' browsers reach it; each
+// provider's redirect URI is PublicURL/api/auth/oauth/<name>/callback
+// and must be registered with the provider as such.
+type OAuthConfig struct {
+	PublicURL string                `yaml:"public_url"`
+	Providers []OAuthProviderConfig `yaml:"providers"`
+}
+
+// OAuthProviderConfig is one identity provider. "discord" and "google"
+// only need the client credentials; any other name is treated as a
+// generic OIDC provider and needs the three URLs. The URLs, scopes and
+// userinfo fields override the presets when set.
+type OAuthProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Label        string   `yaml:"label,omitempty"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	AuthURL      string   `yaml:"auth_url,omitempty"`
+	TokenURL     string   `yaml:"token_url,omitempty"`
+	UserInfoURL  string   `yaml:"userinfo_url,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	SubjectField string   `yaml:"subject_field,omitempty"`
+	NameField    string   `yaml:"name_field,omitempty"`
+}
+
+// oauthPresetNames mirrors the presets in internal/auth, which fill in
+// the URLs for these providers.
+var oauthPresetNames = map[string]bool{"discord": true, "google": true}
+
+func validateOAuth(a *AuthConfig) error {
+	if a == nil || a.OAuth == nil || len(a.OAuth.Providers) == 0 {
+		return nil
+	}
+	u, err := url.Parse(a.OAuth.PublicURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("auth.oauth.public_url must be an http(s) URL (got %q)", a.OAuth.PublicURL)
+	}
+	seen := make(map[string]bool)
+	for i, p := range a.OAuth.Providers {
+		if !idPattern.MatchString(p.Name) {
+			return fmt.Errorf("auth.oauth.providers[%d].name %q must match %s", i, p.Name, idPattern.String())
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("auth.oauth.providers[%d]: %q is listed twice", i, p.Name)
+		}
+		seen[p.Name] = true
+		if p.ClientID == "" || p.ClientSecret == "" {
+			return fmt.Errorf("auth.oauth.providers[%d] (%s): client_id and client_secret are required", i, p.Name)
+		}
+		if !oauthPresetNames[p.Name] && (p.AuthURL == "" || p.TokenURL == "" || p.UserInfoURL == "") {
+			return fmt.Errorf("auth.oauth.providers[%d] (%s): auth_url, token_url and userinfo_url are required for providers other than discord and google", i, p.Name)
+		}
+	}
+	return nil
 }
 
 // DiscordConfig is read by the `trinity discord-digest` subcommand
-// (invoked from cron / a systemd timer). Never read by `trinity serve`,
-// so an empty/missing block has no effect on hub startup.
+// (invoked from cron / a systemd timer). `trinity serve` only reads it
+// when AnnounceReturns, AlertStalls, AlertCrashes or AnnounceDemos is set, so an empty/missing block
+// has no effect on hub startup.
 //
 // WebhookURL is the full https://discord.com/api/webhooks/{id}/{token}
 // URL — the URL itself is the credential. Stored alongside other
@@ -177,9 +354,27 @@ type AuthConfig struct {
 // DigestCategories optionally overrides the 9 default leaderboard
 // categories shown in the embed. Order is preserved. Each entry must
 // be one of the categories accepted by /api/stats/leaderboard.
+//
+// AnnounceReturns makes the hub post a short "X is back after 3
+// months!" message to the same webhook whenever a long-absent player
+// rejoins.
+//
+// AlertStalls makes the collector post to the webhook when a server's
+// log has stopped moving with players online and reopening it didn't
+// help.
+//
+// AlertCrashes makes the collector post to the webhook when a game
+// server crashes, quoting its last log lines.
+//
+// AnnounceDemos makes the hub post a match summary with a download
+// link whenever it harvests a server-side demo of a finished match.
 type DiscordConfig struct {
 	WebhookURL       string   `yaml:"webhook_url"`
 	DigestCategories []string `yaml:"digest_categories,omitempty"`
+	AnnounceReturns  bool     `yaml:"announce_returns,omitempty"`
+	AlertStalls      bool     `yaml:"alert_stalls,omitempty"`
+	AlertCrashes     bool     `yaml:"alert_crashes,omitempty"`
+	AnnounceDemos    bool     `yaml:"announce_demos,omitempty"`
 }
 
 // discordWebhookURLPattern matches Discord's webhook URL shape. We
@@ -189,12 +384,14 @@ var discordWebhookURLPattern = regexp.MustCompile(`^https://discord\.com/api/web
 
 // validDigestCategories mirrors internal/api.validCategories. Kept here
 // (and not imported) to keep config out of api's dependency graph.
+// Also checks features.hidden_stats.
 // If you add a category there, add it here too.
 var validDigestCategories = map[string]bool{
 	"frags": true, "deaths": true, "kd_ratio": true, "matches": true,
 	"captures": true, "flag_returns": true, "assists": true,
 	"impressives": true, "excellents": true, "humiliations": true,
-	"defends": true, "victories": true,
+	"defends": true, "victories": true, "sprees": true, "accuracy": true,
+	"skulls": true, "obelisks": true, "playtime": true,
 }
 
 func validateDiscord(d *DiscordConfig) error {
@@ -204,6 +401,18 @@ func validateDiscord(d *DiscordConfig) error {
 	if d.WebhookURL != "" && !discordWebhookURLPattern.MatchString(d.WebhookURL) {
 		return fmt.Errorf("discord.webhook_url %q does not match Discord webhook shape (https://discord.com/api/webhooks/{id}/{token})", d.WebhookURL)
 	}
+	if d.AnnounceReturns && d.WebhookURL == "" {
+		return fmt.Errorf("discord.announce_returns requires discord.webhook_url")
+	}
+	if d.AlertStalls && d.WebhookURL == "" {
+		return fmt.Errorf("discord.alert_stalls requires discord.webhook_url")
+	}
+	if d.AlertCrashes && d.WebhookURL == "" {
+		return fmt.Errorf("discord.alert_crashes requires discord.webhook_url")
+	}
+	if d.AnnounceDemos && d.WebhookURL == "" {
+		return fmt.Errorf("discord.announce_demos requires discord.webhook_url")
+	}
 	for i, cat := range d.DigestCategories {
 		if !validDigestCategories[cat] {
 			return fmt.Errorf("discord.digest_categories[%d] %q is not a valid leaderboard category", i, cat)
@@ -212,6 +421,184 @@ func validateDiscord(d *DiscordConfig) error {
 	return nil
 }
 
+// FeaturesConfig turns optional web features off. Everything except
+// chat persistence is on unless set to false; GET /api/config/features reports the result so
+// the SPA can hide pages instead of linking to something that 404s.
+//
+// Demos: demo playback and download links on match cards.
+// Registration: creating a new account from an in-game !claim code
+// (linking a claim to an existing account still works).
+// ChatPersistence: storing match chat for the match page (opt-in).
+// HiddenStats: leaderboard categories (deaths, kd_ratio, ...) left out
+// of the leaderboards and player profiles altogether, for communities
+// that would rather not rank them. Frags and matches can't be hidden.
+type FeaturesConfig struct {
+	Demos           *bool    `yaml:"demos,omitempty"`
+	Registration    *bool    `yaml:"registration,omitempty"`
+	ChatPersistence *bool    `yaml:"chat_persistence,omitempty"`
+	HiddenStats     []string `yaml:"hidden_stats,omitempty"`
+	PublicAPI       *bool    `yaml:"public_api,omitempty"`
+}
+
+// LeaderboardConfig decides who places on the leaderboards. Players
+// need MinMatches completed matches in the board's period (default 5)
+// and MinPlaytime on the servers in all (default none). Bots and
+// "[VR] Player#" placeholder names are left off unless included, and
+// so are abandoned matches' stats. Admins can try other values per request on /api/stats/leaderboard.
+type LeaderboardConfig struct {
+	MinMatches            *int     `yaml:"min_matches,omitempty"`
+	MinPlaytime           Duration `yaml:"min_playtime,omitempty"`
+	IncludeBots           bool     `yaml:"include_bots,omitempty"`
+	IncludeVRPlaceholders bool     `yaml:"include_vr_placeholders,omitempty"`
+	IncludeAbandoned      bool     `yaml:"include_abandoned,omitempty"`
+}
+
+// defaultLeaderboardMinMatches applies when min_matches is unset.
+const defaultLeaderboardMinMatches = 5
+
+// MinCompletedMatches returns MinMatches or its default. Safe on nil.
+func (l *LeaderboardConfig) MinCompletedMatches() int {
+	if l == nil || l.MinMatches == nil {
+		return defaultLeaderboardMinMatches
+	}
+	return *l.MinMatches
+}
+
+func validateLeaderboard(l *LeaderboardConfig) error {
+	if l == nil {
+		return nil
+	}
+	if l.MinMatches != nil && *l.MinMatches < 0 {
+		return fmt.Errorf("leaderboard.min_matches must not be negative")
+	}
+	if l.MinPlaytime < 0 {
+		return fmt.Errorf("leaderboard.min_playtime must not be negative")
+	}
+	return nil
+}
+
+// DemosEnabled reports whether demo links are served. Safe on nil.
+func (f *FeaturesConfig) DemosEnabled() bool {
+	return f == nil || f.Demos == nil || *f.Demos
+}
+
+// RegistrationEnabled reports whether claim codes may create new
+// accounts. Safe on nil.
+func (f *FeaturesConfig) RegistrationEnabled() bool {
+	return f == nil || f.Registration == nil || *f.Registration
+}
+
+// ChatPersistenceEnabled reports whether match chat is stored. Off
+// unless set, since players may not expect their chat to be kept.
+// Safe on nil.
+func (f *FeaturesConfig) ChatPersistenceEnabled() bool {
+	return f != nil && f.ChatPersistence != nil && *f.ChatPersistence
+}
+
+// PublicAPIEnabled reports whether the anonymized /public/api mirror
+// is served. Off unless set. Safe on nil.
+func (f *FeaturesConfig) PublicAPIEnabled() bool {
+	return f != nil && f.PublicAPI != nil && *f.PublicAPI
+}
+
+// Hidden returns the hidden stat categories. Safe on nil.
+func (f *FeaturesConfig) Hidden() []string {
+	if f == nil {
+		return nil
+	}
+	return f.HiddenStats
+}
+
+func validateFeatures(f *FeaturesConfig) error {
+	for i, stat := range f.Hidden() {
+		if stat == "frags" || stat == "matches" || !validDigestCategories[stat] {
+			return fmt.Errorf("features.hidden_stats[%d] %q is not a stat that can be hidden", i, stat)
+		}
+	}
+	return nil
+}
+
+// BrandingConfig lets an operator present the hub under their own
+// community's name without rebuilding the web UI. Every field is
+// optional; unset ones fall back to the stock Trinity look. Served
+// at GET /api/config/branding.
+//
+// LogoURL may be absolute (http/https) or a path on this host
+// (e.g. /assets/custom/logo.png). Theme colors are CSS hex colors.
+// FooterLinks appear at the bottom of every page; CommunityLinks sit
+// beside the page title (Discord, forums, a server list, ...).
+type BrandingConfig struct {
+	SiteName       string         `yaml:"site_name,omitempty"`
+	LogoURL        string         `yaml:"logo_url,omitempty"`
+	Theme          *ThemeColors   `yaml:"theme,omitempty"`
+	FooterLinks    []BrandingLink `yaml:"footer_links,omitempty"`
+	CommunityLinks []BrandingLink `yaml:"community_links,omitempty"`
+}
+
+// ThemeColors override the web UI's base palette.
+type ThemeColors struct {
+	Background string `yaml:"background,omitempty"`
+	Card       string `yaml:"card,omitempty"`
+	Text       string `yaml:"text,omitempty"`
+	Accent     string `yaml:"accent,omitempty"`
+}
+
+// BrandingLink is one labelled outbound link.
+type BrandingLink struct {
+	Label string `yaml:"label"`
+	URL   string `yaml:"url"`
+}
+
+// hexColorPattern matches #rgb and #rrggbb. Anything looser would let
+// arbitrary CSS through to the page's style attribute.
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validateBranding(b *BrandingConfig) error {
+	if b == nil {
+		return nil
+	}
+	if len(b.SiteName) > 64 {
+		return fmt.Errorf("branding.site_name must be at most 64 chars")
+	}
+	if b.LogoURL != "" && !validBrandingURL(b.LogoURL) {
+		return fmt.Errorf("branding.logo_url must be an http(s) URL or a path starting with / (got %q)", b.LogoURL)
+	}
+	if t := b.Theme; t != nil {
+		for _, c := range []struct{ name, value string }{
+			{"background", t.Background}, {"card", t.Card}, {"text", t.Text}, {"accent", t.Accent},
+		} {
+			if c.value != "" && !hexColorPattern.MatchString(c.value) {
+				return fmt.Errorf("branding.theme.%s %q must be a hex color like #1a2b3c", c.name, c.value)
+			}
+		}
+	}
+	for _, list := range []struct {
+		name  string
+		links []BrandingLink
+	}{{"footer_links", b.FooterLinks}, {"community_links", b.CommunityLinks}} {
+		for i, l := range list.links {
+			if l.Label == "" {
+				return fmt.Errorf("branding.%s[%d]: label is required", list.name, i)
+			}
+			if !validBrandingURL(l.URL) {
+				return fmt.Errorf("branding.%s[%d].url must be an http(s) URL or a path starting with / (got %q)", list.name, i, l.URL)
+			}
+		}
+	}
+	return nil
+}
+
+// validBrandingURL accepts absolute http(s) URLs and site-relative
+// paths. Rejects javascript: and friends, since these end up in
+// href/src attributes.
+func validBrandingURL(s string) bool {
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
 	ListenAddr   string        `yaml:"listen_addr"`
@@ -221,6 +608,68 @@ type ServerConfig struct {
 	Quake3Dir    string        `yaml:"quake3_dir"`
 	ServiceUser  string        `yaml:"service_user,omitempty"`
 	UseSystemd   *bool         `yaml:"use_systemd,omitempty"`
+	// LeaderboardCacheTTL is how long a live leaderboard result is
+	// reused before re-querying (default 1m; 0 disables). Cached
+	// results are also dropped whenever a match ends.
+	LeaderboardCacheTTL *time.Duration `yaml:"leaderboard_cache_ttl,omitempty"`
+	// EventBuffers sizes the in-process event channels.
+	EventBuffers EventBuffersConfig `yaml:"event_buffers,omitempty"`
+	// TLS serves HTTPS directly, for installs without a reverse proxy.
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+}
+
+// TLSConfig serves the web UI and API over HTTPS on ListenAddr:Port,
+// with either a static certificate (CertFile and KeyFile) or one
+// fetched and renewed from Let's Encrypt (ACME). A plain-HTTP listener
+// on RedirectPort sends browsers to HTTPS and answers ACME challenges.
+// The server.http_port listener keeps serving plain HTTP for the CLI
+// and health checks, so leave server.listen_addr on loopback.
+type TLSConfig struct {
+	ListenAddr   string      `yaml:"listen_addr,omitempty"`
+	Port         int         `yaml:"port,omitempty"`
+	RedirectPort int         `yaml:"redirect_port,omitempty"`
+	CertFile     string      `yaml:"cert_file,omitempty"`
+	KeyFile      string      `yaml:"key_file,omitempty"`
+	ACME         *ACMEConfig `yaml:"acme,omitempty"`
+}
+
+// ACMEConfig gets certificates for Domains from an ACME CA, Let's
+// Encrypt unless DirectoryURL says otherwise (e.g. its staging
+// server). Email is given to the CA for expiry notices. Certificates
+// and the account key are kept in CacheDir.
+type ACMEConfig struct {
+	Domains      []string `yaml:"domains"`
+	Email        string   `yaml:"email,omitempty"`
+	CacheDir     string   `yaml:"cache_dir,omitempty"`
+	DirectoryURL string   `yaml:"directory_url,omitempty"`
+}
+
+// TracingConfig exports OpenTelemetry traces of API requests, storage
+// queries, rcon commands, and log event handling to an OTLP/HTTP
+// collector (Jaeger, Tempo, Honeycomb, ...). Endpoint is the full
+// traces URL, e.g. http://localhost:4318/v1/traces; Headers are sent
+// with each export, for collectors that want an API key. SampleRatio
+// is the share of traces kept (default 1); a request inherits its
+// caller's decision when it arrives with a traceparent.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	ServiceName string            `yaml:"service_name,omitempty"`
+	SampleRatio *float64          `yaml:"sample_ratio,omitempty"`
+}
+
+// EventBuffersConfig sizes the buffered channels events pass through
+// on their way to the live feed. When one fills (a frag storm outruns
+// its reader), further events are dropped and counted in GET /metrics;
+// match start/end and other state-changing events wait briefly first.
+//
+// Log: each server's log tailer → collector (default 1000).
+// Live: collector → WebSocket fan-out (default 1000).
+// Broadcast: WebSocket hub → browser clients (default 1000).
+type EventBuffersConfig struct {
+	Log       int `yaml:"log,omitempty"`
+	Live      int `yaml:"live,omitempty"`
+	Broadcast int `yaml:"broadcast,omitempty"`
 }
 
 // DatabaseConfig holds SQLite settings
@@ -238,12 +687,503 @@ type DatabaseConfig struct {
 // own this collector's source. Owners of the source can RCON
 // regardless of this flag. Default false — operators must explicitly
 // hand the keys over.
+//
+// DemoDir is where the game server records demos, if it does. The hub
+// copies finished .dm_68 files from it into tracker.hub.demo_uploads
+// and attaches them to their matches. Only for collectors that run in
+// the hub's process.
+//
+// QueryOnly servers have no log to read: the hub only UDP-polls them
+// for live status and population history. They need no log_path and
+// can't take the options that act on log events.
 type Q3Server struct {
 	Key               string `yaml:"key"`
 	Address           string `yaml:"address"`
 	LogPath           string `yaml:"log_path"`
 	RconPassword      string `yaml:"rcon_password"`
 	AllowHubAdminRcon bool   `yaml:"allow_hub_admin_rcon"`
+	DemoDir           string `yaml:"demo_dir,omitempty"`
+	// Moderation overrides fields of the top-level moderation policy
+	// for this server.
+	Moderation *ModerationConfig `yaml:"moderation,omitempty"`
+	// Greetings overrides fields of the top-level greetings for this
+	// server.
+	Greetings *GreetingsConfig `yaml:"greetings,omitempty"`
+	// MapVote turns on !nextmap voting for this server.
+	MapVote *MapVoteConfig `yaml:"map_vote,omitempty"`
+	// ReservedSlots keeps room on a full server for VIP players.
+	ReservedSlots *ReservedSlotsConfig `yaml:"reserved_slots,omitempty"`
+	// Abandon tunes when a match the humans left counts as abandoned.
+	Abandon *AbandonConfig `yaml:"abandon,omitempty"`
+	// TeamBalance watches team games for uneven teams.
+	TeamBalance *TeamBalanceConfig `yaml:"team_balance,omitempty"`
+	// Timezone is the IANA zone the server writes log timestamps in
+	// when they carry no offset. Empty means the collector's own.
+	Timezone  string `yaml:"timezone,omitempty"`
+	QueryOnly bool   `yaml:"query_only,omitempty"`
+}
+
+// Location returns the zone for the server's log timestamps.
+func (s Q3Server) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		// Load rejects bad zones, so this is only reached for a
+		// config built by hand.
+		return time.Local
+	}
+	return loc
+}
+
+// MapVoteConfig lets players pick the next map with !nextmap. Votes
+// are for one of Maps. When the match ends, if the leading map has
+// votes from at least Quorum (a fraction, default one half) of the
+// humans on the server, the collector changes to it during the
+// intermission. Otherwise the server's own rotation carries on.
+type MapVoteConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Maps    []string `yaml:"maps"`
+	Quorum  float64  `yaml:"quorum,omitempty"`
+}
+
+// defaultMapVoteQuorum applies when quorum is unset.
+const defaultMapVoteQuorum = 0.5
+
+// MapVotePolicy returns the server's map vote settings with defaults
+// filled in, or nil when voting is off.
+func (s Q3Server) MapVotePolicy() *MapVoteConfig {
+	if s.MapVote == nil || !s.MapVote.Enabled {
+		return nil
+	}
+	p := *s.MapVote
+	if p.Quorum == 0 {
+		p.Quorum = defaultMapVoteQuorum
+	}
+	return &p
+}
+
+// ReservedSlotsConfig holds slots open for VIPs, the linked players
+// an admin has flagged on the hub. When a VIP joins and fewer than
+// Slots (default 1) slots are free, the collector kicks the longest
+// idle bot or, with KickSpectators, the longest idle non-VIP
+// spectator. Playing humans are never kicked. Every kick is recorded
+// for GET /api/admin/vip-kicks.
+type ReservedSlotsConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	Slots          int  `yaml:"slots,omitempty"`
+	KickSpectators bool `yaml:"kick_spectators,omitempty"`
+}
+
+// ReservedSlotsPolicy returns the server's reserved slot settings with
+// defaults filled in, or nil when they're off.
+func (s Q3Server) ReservedSlotsPolicy() *ReservedSlotsConfig {
+	if s.ReservedSlots == nil || !s.ReservedSlots.Enabled {
+		return nil
+	}
+	p := *s.ReservedSlots
+	if p.Slots == 0 {
+		p.Slots = 1
+	}
+	return &p
+}
+
+// AbandonConfig decides when a match counts as abandoned: it had
+// MinHumans humans (default 1) at some point after warmup, then fewer
+// for at least After (default 2m) up to its end. Abandoned matches are
+// flagged in match summaries and left off the leaderboards. It's on
+// unless Enabled is false.
+type AbandonConfig struct {
+	Enabled   *bool    `yaml:"enabled,omitempty"`
+	MinHumans int      `yaml:"min_humans,omitempty"`
+	After     Duration `yaml:"after,omitempty"`
+}
+
+// defaultAbandonAfter applies when after is unset.
+const defaultAbandonAfter = 2 * time.Minute
+
+// AbandonPolicy returns the server's abandon settings with defaults
+// filled in, or nil when detection is off.
+func (s Q3Server) AbandonPolicy() *AbandonConfig {
+	var p AbandonConfig
+	if s.Abandon != nil {
+		if s.Abandon.Enabled != nil && !*s.Abandon.Enabled {
+			return nil
+		}
+		p = *s.Abandon
+	}
+	if p.MinHumans == 0 {
+		p.MinHumans = 1
+	}
+	if p.After == 0 {
+		p.After = Duration(defaultAbandonAfter)
+	}
+	return &p
+}
+
+// TeamBalanceConfig evens out team games. The teams are uneven when
+// one has more than MaxSizeDiff (default 1) players over the other,
+// or, with MaxScoreDiff set, leads by more than that many points. Once
+// they've stayed uneven for Grace (default 30s), the collector picks
+// the moves that best even the teams' ratings and announces them;
+// with Enforce it makes them itself with forceteam. It then waits
+// Cooldown (default 2m) before acting again. A player's rating is
+// their lifetime K/D from the hub; bots rate their skill over three.
+type TeamBalanceConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	MaxSizeDiff  int      `yaml:"max_size_diff,omitempty"`
+	MaxScoreDiff int      `yaml:"max_score_diff,omitempty"`
+	Grace        Duration `yaml:"grace,omitempty"`
+	Cooldown     Duration `yaml:"cooldown,omitempty"`
+	Enforce      bool     `yaml:"enforce,omitempty"`
+}
+
+// Team balance defaults, for fields left unset.
+const (
+	defaultTeamBalanceGrace    = 30 * time.Second
+	defaultTeamBalanceCooldown = 2 * time.Minute
+)
+
+// TeamBalancePolicy returns the server's team balance settings with
+// defaults filled in, or nil when balancing is off.
+func (s Q3Server) TeamBalancePolicy() *TeamBalanceConfig {
+	if s.TeamBalance == nil || !s.TeamBalance.Enabled {
+		return nil
+	}
+	p := *s.TeamBalance
+	if p.MaxSizeDiff == 0 {
+		p.MaxSizeDiff = 1
+	}
+	if p.Grace == 0 {
+		p.Grace = Duration(defaultTeamBalanceGrace)
+	}
+	if p.Cooldown == 0 {
+		p.Cooldown = Duration(defaultTeamBalanceCooldown)
+	}
+	return &p
+}
+
+// Moderation actions, from mildest to harshest. Every action but off
+// records an incident for GET /api/admin/moderation.
+const (
+	ModerationOff  = "off"
+	ModerationLog  = "log"
+	ModerationWarn = "warn"
+	ModerationMute = "mute"
+	ModerationKick = "kick"
+)
+
+// ModerationConfig is a chat word filter. Words are matched as whole
+// words, ignoring case and color codes, in says, team says, and tells.
+// On a match the collector takes Action (default log) against the
+// sender: warn prints Warning to them, mute warns and then runs
+// MuteCommand with {client} replaced by their slot (stock Quake 3 has
+// no mute, so the command depends on the server's mod), and kick runs
+// clientkick.
+type ModerationConfig struct {
+	Words       []string `yaml:"words,omitempty"`
+	Action      string   `yaml:"action,omitempty"`
+	Warning     string   `yaml:"warning,omitempty"`
+	MuteCommand string   `yaml:"mute_command,omitempty"`
+}
+
+// defaultModerationWarning is printed to offenders when warning is unset.
+const defaultModerationWarning = "^3Keep it civil, please."
+
+// ModerationFor merges the server's overrides onto the default policy.
+// Returns nil when the server has no words to filter or the action is
+// off.
+func (c *Config) ModerationFor(srv Q3Server) *ModerationConfig {
+	var p ModerationConfig
+	for _, m := range []*ModerationConfig{c.Moderation, srv.Moderation} {
+		if m == nil {
+			continue
+		}
+		if m.Words != nil {
+			p.Words = m.Words
+		}
+		if m.Action != "" {
+			p.Action = m.Action
+		}
+		if m.Warning != "" {
+			p.Warning = m.Warning
+		}
+		if m.MuteCommand != "" {
+			p.MuteCommand = m.MuteCommand
+		}
+	}
+	if len(p.Words) == 0 || p.Action == ModerationOff {
+		return nil
+	}
+	if p.Action == "" {
+		p.Action = ModerationLog
+	}
+	if p.Warning == "" {
+		p.Warning = defaultModerationWarning
+	}
+	return &p
+}
+
+// MuteCommandFor returns the server's mute_command, or the top-level
+// one, whether or not the word filter is on. Admins' !mute uses it.
+func (c *Config) MuteCommandFor(srv Q3Server) string {
+	if srv.Moderation != nil && srv.Moderation.MuteCommand != "" {
+		return srv.Moderation.MuteCommand
+	}
+	if c.Moderation != nil {
+		return c.Moderation.MuteCommand
+	}
+	return ""
+}
+
+func validateModeration(cfg *Config) error {
+	check := func(field string, m *ModerationConfig) error {
+		if m == nil {
+			return nil
+		}
+		switch m.Action {
+		case "", ModerationOff, ModerationLog, ModerationWarn, ModerationMute, ModerationKick:
+		default:
+			return fmt.Errorf("%s.action %q must be off, log, warn, mute, or kick", field, m.Action)
+		}
+		for i, w := range m.Words {
+			if strings.TrimSpace(w) == "" {
+				return fmt.Errorf("%s.words[%d] is empty", field, i)
+			}
+		}
+		return nil
+	}
+	if err := check("moderation", cfg.Moderation); err != nil {
+		return err
+	}
+	for i, srv := range cfg.Q3Servers {
+		if err := check(fmt.Sprintf("q3_servers[%d].moderation", i), srv.Moderation); err != nil {
+			return err
+		}
+		if p := cfg.ModerationFor(srv); p != nil && p.Action == ModerationMute && !strings.Contains(p.MuteCommand, "{client}") {
+			return fmt.Errorf("q3_servers[%d]: moderation action mute needs a mute_command containing {client}", i)
+		}
+	}
+	return nil
+}
+
+// GreetingsConfig sets what the collector tells players when they
+// join. New greets players who haven't claimed their identity, Claimed
+// those who have, and Returning anyone back after a long absence. The
+// upgrade prompts that follow point players without the Trinity
+// client (NoTrinity), with an old VR client (OutdatedVR), or without
+// VR (TryVR) at SiteURL. Enabled false turns all of it off;
+// UpgradePrompts false turns off just the prompts. Templates are
+// filled in from GreetingPlaceholders.
+type GreetingsConfig struct {
+	Enabled        *bool             `yaml:"enabled,omitempty"`
+	UpgradePrompts *bool             `yaml:"upgrade_prompts,omitempty"`
+	SiteURL        string            `yaml:"site_url,omitempty"`
+	New            *GreetingTemplate `yaml:"new,omitempty"`
+	Claimed        *GreetingTemplate `yaml:"claimed,omitempty"`
+	Returning      *GreetingTemplate `yaml:"returning,omitempty"`
+	NoTrinity      *GreetingTemplate `yaml:"no_trinity,omitempty"`
+	OutdatedVR     *GreetingTemplate `yaml:"outdated_vr,omitempty"`
+	TryVR          *GreetingTemplate `yaml:"try_vr,omitempty"`
+}
+
+// GreetingTemplate is one greeting: Message goes to the player's
+// console and Center is centerprinted, with a newline between lines.
+type GreetingTemplate struct {
+	Message string `yaml:"message,omitempty"`
+	Center  string `yaml:"center,omitempty"`
+}
+
+// GreetingPlaceholders are what greeting templates may use. {stats}
+// is the player's K/D and match count, or nothing before their first
+// finished match. {rank} is their place on the all-time frags
+// leaderboard, or nothing outside the top 100. {away} is how long a
+// returning player was gone.
+var GreetingPlaceholders = []string{"{name}", "{kd}", "{matches}", "{stats}", "{rank}", "{away}", "{site_url}"}
+
+// defaultGreetings fill in whatever greetings leave unset.
+var defaultGreetings = GreetingsConfig{
+	SiteURL: "trinity.run/docs",
+	New: &GreetingTemplate{
+		Message: "Welcome, {name}^7! {stats} ^3!claim ^7to link your identity! (^3!help ^7for help)",
+		Center:  "Welcome, {name}^7!\n{stats}\n^3!claim ^7to link your identity!",
+	},
+	Claimed: &GreetingTemplate{
+		Message: "Welcome back, {name}^7! {stats} (^3!help ^7for help)",
+		Center:  "Welcome back, {name}^7!\n{stats}\n^3!help ^7for help",
+	},
+	Returning: &GreetingTemplate{
+		Message: "Welcome back, {name}^7! It's been ^3{away}^7. {stats} (^3!help ^7for help)",
+		Center:  "Welcome back, {name}^7!\nIt's been ^3{away}^7\n{stats}",
+	},
+	NoTrinity: &GreetingTemplate{
+		Message: "It looks like you're missing out on Trinity-specific features on this server. Go to ^5{site_url} ^7to upgrade.",
+		Center:  "Get Trinity for the best experience!\n^5{site_url}",
+	},
+	OutdatedVR: &GreetingTemplate{
+		Message: "Your VR client is outdated. Upgrade to enjoy all Trinity features! More info at ^5{site_url}",
+		Center:  "Your VR client is outdated.\nUpgrade to enjoy all Trinity features!\n^5{site_url}",
+	},
+	TryVR: &GreetingTemplate{
+		Message: "Haven't tried Quake 3 in VR yet? It's a whole new dimension (literally). Visit ^5{site_url} ^7to learn more.",
+		Center:  "Do you play VR?\nGet the VR client!\n^5{site_url}",
+	},
+}
+
+// GreetingsFor merges the server's overrides onto the top-level
+// greetings and the defaults. The greetings that are turned off are
+// nil; SiteURL is always set.
+func (c *Config) GreetingsFor(srv Q3Server) *GreetingsConfig {
+	p := defaultGreetings
+	enabled, prompts := true, true
+	for _, g := range []*GreetingsConfig{c.Greetings, srv.Greetings} {
+		if g == nil {
+			continue
+		}
+		if g.Enabled != nil {
+			enabled = *g.Enabled
+		}
+		if g.UpgradePrompts != nil {
+			prompts = *g.UpgradePrompts
+		}
+		if g.SiteURL != "" {
+			p.SiteURL = g.SiteURL
+		}
+		p.New = mergeGreeting(p.New, g.New)
+		p.Claimed = mergeGreeting(p.Claimed, g.Claimed)
+		p.Returning = mergeGreeting(p.Returning, g.Returning)
+		p.NoTrinity = mergeGreeting(p.NoTrinity, g.NoTrinity)
+		p.OutdatedVR = mergeGreeting(p.OutdatedVR, g.OutdatedVR)
+		p.TryVR = mergeGreeting(p.TryVR, g.TryVR)
+	}
+	if !enabled {
+		p.New, p.Claimed, p.Returning = nil, nil, nil
+	}
+	if !enabled || !prompts {
+		p.NoTrinity, p.OutdatedVR, p.TryVR = nil, nil, nil
+	}
+	p.Enabled, p.UpgradePrompts = nil, nil
+	return &p
+}
+
+// mergeGreeting returns base with the fields override sets.
+func mergeGreeting(base, override *GreetingTemplate) *GreetingTemplate {
+	if override == nil {
+		return base
+	}
+	t := *base
+	if override.Message != "" {
+		t.Message = override.Message
+	}
+	if override.Center != "" {
+		t.Center = override.Center
+	}
+	return &t
+}
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func validateGreetings(cfg *Config) error {
+	check := func(field string, g *GreetingsConfig) error {
+		if g == nil {
+			return nil
+		}
+		for _, t := range []struct {
+			name string
+			tmpl *GreetingTemplate
+		}{
+			{"new", g.New}, {"claimed", g.Claimed}, {"returning", g.Returning},
+			{"no_trinity", g.NoTrinity}, {"outdated_vr", g.OutdatedVR}, {"try_vr", g.TryVR},
+		} {
+			if t.tmpl == nil {
+				continue
+			}
+			for _, text := range []string{t.tmpl.Message, t.tmpl.Center} {
+				for _, ph := range placeholderPattern.FindAllString(text, -1) {
+					if !slices.Contains(GreetingPlaceholders, ph) {
+						return fmt.Errorf("%s.%s: unknown placeholder %s", field, t.name, ph)
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if err := check("greetings", cfg.Greetings); err != nil {
+		return err
+	}
+	for i, srv := range cfg.Q3Servers {
+		if err := check(fmt.Sprintf("q3_servers[%d].greetings", i), srv.Greetings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMapVote(cfg *Config) error {
+	for i, srv := range cfg.Q3Servers {
+		v := srv.MapVote
+		if v == nil || !v.Enabled {
+			continue
+		}
+		if len(v.Maps) == 0 {
+			return fmt.Errorf("q3_servers[%d].map_vote: enabled needs at least one map", i)
+		}
+		for j, m := range v.Maps {
+			if strings.TrimSpace(m) == "" || strings.ContainsAny(m, " ;\"\\") {
+				return fmt.Errorf("q3_servers[%d].map_vote.maps[%d] %q is not a map name", i, j, m)
+			}
+		}
+		if v.Quorum < 0 || v.Quorum > 1 {
+			return fmt.Errorf("q3_servers[%d].map_vote.quorum must be between 0 and 1", i)
+		}
+	}
+	return nil
+}
+
+func validateReservedSlots(cfg *Config) error {
+	for i, srv := range cfg.Q3Servers {
+		if r := srv.ReservedSlots; r != nil && r.Slots < 0 {
+			return fmt.Errorf("q3_servers[%d].reserved_slots.slots must not be negative", i)
+		}
+	}
+	return nil
+}
+
+func validateAbandon(cfg *Config) error {
+	for i, srv := range cfg.Q3Servers {
+		a := srv.Abandon
+		if a == nil {
+			continue
+		}
+		if a.MinHumans < 0 {
+			return fmt.Errorf("q3_servers[%d].abandon.min_humans must not be negative", i)
+		}
+		if a.After < 0 {
+			return fmt.Errorf("q3_servers[%d].abandon.after must not be negative", i)
+		}
+	}
+	return nil
+}
+
+func validateTeamBalance(cfg *Config) error {
+	for i, srv := range cfg.Q3Servers {
+		b := srv.TeamBalance
+		if b == nil {
+			continue
+		}
+		switch {
+		case b.MaxSizeDiff < 0:
+			return fmt.Errorf("q3_servers[%d].team_balance.max_size_diff must not be negative", i)
+		case b.MaxScoreDiff < 0:
+			return fmt.Errorf("q3_servers[%d].team_balance.max_score_diff must not be negative", i)
+		case b.Grace < 0:
+			return fmt.Errorf("q3_servers[%d].team_balance.grace must not be negative", i)
+		case b.Cooldown < 0:
+			return fmt.Errorf("q3_servers[%d].team_balance.cooldown must not be negative", i)
+		}
+	}
+	return nil
 }
 
 // Load reads configuration from a YAML file
@@ -268,6 +1208,20 @@ func Load(path string) (*Config, error) {
 	if cfg.Server.PollInterval == 0 {
 		cfg.Server.PollInterval = 5 * time.Second
 	}
+	if cfg.Server.LeaderboardCacheTTL == nil {
+		ttl := time.Minute
+		cfg.Server.LeaderboardCacheTTL = &ttl
+	} else if *cfg.Server.LeaderboardCacheTTL < 0 {
+		return nil, fmt.Errorf("server.leaderboard_cache_ttl must not be negative")
+	}
+	for _, b := range []*int{&cfg.Server.EventBuffers.Log, &cfg.Server.EventBuffers.Live, &cfg.Server.EventBuffers.Broadcast} {
+		if *b < 0 {
+			return nil, fmt.Errorf("server.event_buffers sizes must not be negative")
+		}
+		if *b == 0 {
+			*b = 1000
+		}
+	}
 	// Note: StaticDir intentionally has no default - empty means don't serve static files
 	if cfg.Server.Quake3Dir == "" {
 		cfg.Server.Quake3Dir = "/usr/lib/quake3"
@@ -290,18 +1244,47 @@ func Load(path string) (*Config, error) {
 		if cfg.Auth.TokenDuration == 0 {
 			cfg.Auth.TokenDuration = 24 * time.Hour
 		}
+		if cfg.Tracker.Hub != nil {
+			if cfg.Tracker.Hub.DemoUploads == nil {
+				cfg.Tracker.Hub.DemoUploads = &DemoUploadsConfig{}
+			}
+			d := cfg.Tracker.Hub.DemoUploads
+			if d.Dir == "" {
+				d.Dir = dirOf(cfg.Database.Path) + "/demo_uploads"
+			}
+			if d.MaxFileMB == 0 {
+				d.MaxFileMB = 100
+			}
+			if d.QuotaMB == 0 {
+				d.QuotaMB = 5120
+			}
+		}
 	}
 	if err := validateTracker(cfg.Tracker); err != nil {
 		return nil, err
 	}
+	if err := validateDemoUploads(cfg.Tracker.Hub); err != nil {
+		return nil, err
+	}
 
 	for i, srv := range cfg.Q3Servers {
 		if srv.Key == "" {
 			return nil, fmt.Errorf("q3_servers[%d]: key is required", i)
 		}
-		if len(srv.Key) > 64 || !idPattern.MatchString(srv.Key) {
+		if !ValidServerKey(srv.Key) {
 			return nil, fmt.Errorf("q3_servers[%d].key %q must match %s and be at most 64 chars", i, srv.Key, idPattern.String())
 		}
+		if srv.Timezone != "" {
+			if _, err := time.LoadLocation(srv.Timezone); err != nil {
+				return nil, fmt.Errorf("q3_servers[%d].timezone: %w", i, err)
+			}
+		}
+		if srv.DemoDir != "" && (cfg.Tracker == nil || cfg.Tracker.Hub == nil) {
+			return nil, fmt.Errorf("q3_servers[%d].demo_dir needs a hub in the same process to harvest into", i)
+		}
+		if err := validateQueryOnly(srv); err != nil {
+			return nil, fmt.Errorf("q3_servers[%d]: %w", i, err)
+		}
 	}
 
 	if err := validateNoPlaceholders(&cfg); err != nil {
@@ -312,9 +1295,148 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := validateFeatures(cfg.Features); err != nil {
+		return nil, err
+	}
+
+	if err := validateOAuth(cfg.Auth); err != nil {
+		return nil, err
+	}
+
+	if err := validateBranding(cfg.Branding); err != nil {
+		return nil, err
+	}
+
+	if err := validateModeration(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateMapVote(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateGreetings(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateReservedSlots(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateAbandon(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateTeamBalance(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateLeaderboard(cfg.Leaderboard); err != nil {
+		return nil, err
+	}
+
+	if err := validateTLS(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validateTracing(cfg.Tracing); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// validateTLS checks server.tls and fills in its defaults: HTTPS on
+// 443, the redirect on 80, and the ACME cache beside the database.
+func validateTLS(cfg *Config) error {
+	t := cfg.Server.TLS
+	if t == nil {
+		return nil
+	}
+	if t.Port == 0 {
+		t.Port = 443
+	}
+	if t.RedirectPort == 0 {
+		t.RedirectPort = 80
+	}
+	if t.Port < 1 || t.Port > 65535 {
+		return fmt.Errorf("server.tls.port %d is out of range", t.Port)
+	}
+	if t.RedirectPort < 1 || t.RedirectPort > 65535 {
+		return fmt.Errorf("server.tls.redirect_port %d is out of range", t.RedirectPort)
+	}
+	if t.Port == t.RedirectPort {
+		return fmt.Errorf("server.tls.port and redirect_port must differ")
+	}
+	if t.ListenAddr == cfg.Server.ListenAddr && (t.Port == cfg.Server.HTTPPort || t.RedirectPort == cfg.Server.HTTPPort) {
+		return fmt.Errorf("server.tls ports must differ from server.http_port")
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("server.tls.cert_file and key_file must be set together")
+	}
+	static := t.CertFile != ""
+	if static == (t.ACME != nil) {
+		return fmt.Errorf("server.tls needs either cert_file and key_file or acme, not both")
+	}
+	if a := t.ACME; a != nil {
+		if len(a.Domains) == 0 {
+			return fmt.Errorf("server.tls.acme.domains is required")
+		}
+		for i, d := range a.Domains {
+			if d == "" || strings.ContainsAny(d, ":/ ") {
+				return fmt.Errorf("server.tls.acme.domains[%d] %q must be a bare host name", i, d)
+			}
+		}
+		if a.DirectoryURL != "" && !strings.HasPrefix(a.DirectoryURL, "https://") {
+			return fmt.Errorf("server.tls.acme.directory_url must be an https:// URL")
+		}
+		if a.CacheDir == "" {
+			if cfg.Database != nil {
+				a.CacheDir = dirOf(cfg.Database.Path) + "/acme"
+			} else {
+				a.CacheDir = "/var/lib/trinity/acme"
+			}
+		}
+	}
+	return nil
+}
+
+// validateTracing checks the tracing block and fills in its defaults.
+func validateTracing(t *TracingConfig) error {
+	if t == nil {
+		return nil
+	}
+	u, err := url.Parse(t.Endpoint)
+	if t.Endpoint == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tracing.endpoint must be an http(s) URL, e.g. http://localhost:4318/v1/traces")
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = "trinity"
+	}
+	if t.SampleRatio == nil {
+		one := 1.0
+		t.SampleRatio = &one
+	}
+	if r := *t.SampleRatio; r < 0 || r > 1 {
+		return fmt.Errorf("tracing.sample_ratio %v must be between 0 and 1", r)
+	}
+	return nil
+}
+
+func validateDemoUploads(h *HubConfig) error {
+	if h == nil || h.DemoUploads == nil {
+		return nil
+	}
+	d := h.DemoUploads
+	if d.MaxFileMB < 0 {
+		return fmt.Errorf("tracker.hub.demo_uploads.max_file_mb must not be negative")
+	}
+	if d.QuotaMB < d.MaxFileMB {
+		return fmt.Errorf("tracker.hub.demo_uploads.quota_mb (%d) must be at least max_file_mb (%d)", d.QuotaMB, d.MaxFileMB)
+	}
+	return nil
+}
+
 // validateNoPlaceholders refuses configs where the operator forgot to
 // edit literal "REPLACE-ME" placeholders. The wizard never writes
 // these — but a hand-rolled config or one carried over from an
@@ -371,6 +1493,9 @@ func applyTrackerDefaults(cfg *Config) {
 		if t.Hub.Retention == 0 {
 			t.Hub.Retention = Duration(10 * 24 * time.Hour)
 		}
+		if t.Hub.ChatRetention == 0 {
+			t.Hub.ChatRetention = Duration(30 * 24 * time.Hour)
+		}
 		if t.Hub.Directory != nil {
 			d := t.Hub.Directory
 			if d.Port == 0 {
@@ -392,6 +1517,25 @@ func applyTrackerDefaults(cfg *Config) {
 				d.PersistedFreshness = Duration(5 * time.Minute)
 			}
 		}
+		if hp := t.Hub.HealthPing; hp != nil {
+			if hp.Interval == 0 {
+				hp.Interval = Duration(time.Minute)
+			}
+			if hp.StaleAfter == 0 {
+				hp.StaleAfter = Duration(3 * time.Minute)
+			}
+		}
+		if ips := t.Hub.SessionIPs; ips != nil && ips.Scrub == "" {
+			ips.Scrub = IPScrubTruncate
+		}
+		if e := t.Hub.Export; e != nil {
+			if e.Region == "" {
+				e.Region = "us-east-1"
+			}
+			if e.Interval == 0 {
+				e.Interval = Duration(24 * time.Hour)
+			}
+		}
 	}
 	if t.Collector != nil {
 		if t.Collector.HeartbeatInterval == 0 {
@@ -414,6 +1558,14 @@ func applyTrackerDefaults(cfg *Config) {
 			// or admin-direct create).
 			t.Collector.SourceID = "local"
 		}
+		if a := t.Collector.EventArchive; a != nil {
+			if a.Dir == "" {
+				a.Dir = strings.TrimSuffix(t.Collector.DataDir, "/") + "/event_archive"
+			}
+			if a.MaxFileMB == 0 {
+				a.MaxFileMB = 64
+			}
+		}
 	}
 	// NATS URL: embedded hub or in-process collector use localhost;
 	// collector-only mode derives from HubHost. Explicit nats.url in
@@ -463,6 +1615,62 @@ func validateTracker(t *TrackerConfig) error {
 		if t.Collector.HubHost == "" {
 			return fmt.Errorf("tracker.collector.hub_host is required (the trinity hub this collector reports to)")
 		}
+		if a := t.Collector.EventArchive; a != nil && (a.MaxFileMB < 0 || a.Keep < 0) {
+			return fmt.Errorf("tracker.collector.event_archive.max_file_mb and keep must not be negative")
+		}
+	}
+	if t.Hub != nil && t.Hub.HealthPing != nil {
+		hp := t.Hub.HealthPing
+		u, err := url.Parse(hp.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracker.hub.health_ping.url must be an http(s) URL (got %q)", hp.URL)
+		}
+		if hp.Interval.D() < 10*time.Second {
+			return fmt.Errorf("tracker.hub.health_ping.interval must be at least 10s (got %s)", hp.Interval.D())
+		}
+		if hp.StaleAfter < 0 {
+			return fmt.Errorf("tracker.hub.health_ping.stale_after must not be negative")
+		}
+	}
+	if t.Hub != nil {
+		for _, m := range t.Hub.MasterServers {
+			if _, port, err := net.SplitHostPort(m); err != nil || port == "" {
+				return fmt.Errorf("tracker.hub.master_servers entries must be host:port (got %q)", m)
+			}
+		}
+	}
+	if t.Hub != nil && t.Hub.SessionIPs != nil {
+		ips := t.Hub.SessionIPs
+		switch ips.Scrub {
+		case IPScrubTruncate, IPScrubHash, IPScrubDelete:
+		default:
+			return fmt.Errorf("tracker.hub.session_ips.scrub must be %s, %s, or %s (got %q)", IPScrubTruncate, IPScrubHash, IPScrubDelete, ips.Scrub)
+		}
+		if ips.Retention < 0 {
+			return fmt.Errorf("tracker.hub.session_ips.retention must not be negative")
+		}
+	}
+	if t.Hub != nil && t.Hub.Export != nil && t.Hub.Export.Enabled {
+		if err := validateExport(t.Hub.Export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateExport(e *ExportConfig) error {
+	u, err := url.Parse(e.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tracker.hub.export.endpoint must be an http(s) URL (got %q)", e.Endpoint)
+	}
+	if e.Bucket == "" {
+		return fmt.Errorf("tracker.hub.export.bucket is required")
+	}
+	if e.AccessKeyID == "" || e.SecretAccessKey == "" {
+		return fmt.Errorf("tracker.hub.export.access_key_id and secret_access_key are required")
+	}
+	if e.Interval.D() < time.Hour {
+		return fmt.Errorf("tracker.hub.export.interval must be at least 1h (got %s)", e.Interval.D())
 	}
 	return nil
 }
@@ -481,13 +1689,41 @@ func ValidateForSave(cfg *Config) error {
 		if srv.Key == "" {
 			return fmt.Errorf("q3_servers[%d]: key is required", i)
 		}
-		if len(srv.Key) > 64 || !idPattern.MatchString(srv.Key) {
+		if !ValidServerKey(srv.Key) {
 			return fmt.Errorf("q3_servers[%d].key %q must match %s and be at most 64 chars", i, srv.Key, idPattern.String())
 		}
 	}
 	return validateNoPlaceholders(cfg)
 }
 
+// validateQueryOnly rejects a query-only server with settings that
+// only work off its log.
+func validateQueryOnly(srv Q3Server) error {
+	if !srv.QueryOnly {
+		return nil
+	}
+	switch {
+	case srv.LogPath != "":
+		return fmt.Errorf("query_only servers have no log_path")
+	case srv.DemoDir != "":
+		return fmt.Errorf("query_only servers have no demo_dir")
+	case srv.MapVote != nil && srv.MapVote.Enabled:
+		return fmt.Errorf("map_vote needs the server's log; it can't be query_only")
+	case srv.ReservedSlots != nil && srv.ReservedSlots.Enabled:
+		return fmt.Errorf("reserved_slots needs the server's log; it can't be query_only")
+	case srv.TeamBalance != nil && srv.TeamBalance.Enabled:
+		return fmt.Errorf("team_balance needs the server's log; it can't be query_only")
+	}
+	return nil
+}
+
+// ValidServerKey reports whether key can name a q3 server: lowercase
+// letters, digits, underscores and hyphens, at most 64 chars. Keys end
+// up in file and unit names, so nothing else is allowed.
+func ValidServerKey(key string) bool {
+	return len(key) <= 64 && idPattern.MatchString(key)
+}
+
 // Save writes the configuration to a YAML file, backing up the original first
 func Save(path string, cfg *Config) error {
 	// Back up existing file
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -258,3 +259,739 @@ func TestDurationParsesDaysSuffix(t *testing.T) {
 		t.Error("expected error for empty duration")
 	}
 }
+
+func TestLoadHubExportDefaultsAndValidation(t *testing.T) {
+	p := writeConfig(t, `
+tracker:
+  hub:
+    export:
+      enabled: true
+      endpoint: "https://s3.example.com"
+      bucket: "trinity"
+      access_key_id: "AKID"
+      secret_access_key: "secret"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	e := cfg.Tracker.Hub.Export
+	if e.Region != "us-east-1" {
+		t.Errorf("default Region = %q, want us-east-1", e.Region)
+	}
+	if e.Interval.D() != 24*time.Hour {
+		t.Errorf("default Interval = %v, want 24h", e.Interval.D())
+	}
+
+	p = writeConfig(t, `
+tracker:
+  hub:
+    export:
+      enabled: true
+      endpoint: "https://s3.example.com"
+      access_key_id: "AKID"
+      secret_access_key: "secret"
+`)
+	if _, err := Load(p); err == nil {
+		t.Error("expected error for enabled export without bucket")
+	}
+}
+
+func TestLoadFeaturesDefaultOn(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Features.DemosEnabled() || !cfg.Features.RegistrationEnabled() {
+		t.Error("features should default on when the block is absent")
+	}
+
+	p = writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+features:
+  registration: false
+`)
+	cfg, err = Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Features.DemosEnabled() {
+		t.Error("demos should stay on when unset")
+	}
+	if cfg.Features.RegistrationEnabled() {
+		t.Error("registration: false should turn registration off")
+	}
+}
+
+func TestLoadFeaturesHiddenStats(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+features:
+  hidden_stats: [deaths, kd_ratio]
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Features.Hidden(); len(got) != 2 || got[0] != "deaths" || got[1] != "kd_ratio" {
+		t.Errorf("Hidden() = %v", got)
+	}
+
+	for _, stat := range []string{"frags", "matches", "kills"} {
+		p = writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+features:
+  hidden_stats: [`+stat+`]
+`)
+		if _, err := Load(p); err == nil {
+			t.Errorf("hidden_stats [%s]: expected error", stat)
+		}
+	}
+}
+
+func TestLoadOAuthValidation(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+auth:
+  jwt_secret: "x"
+  oauth:
+    public_url: "https://stats.example.com"
+    providers:
+      - name: discord
+        client_id: "123"
+        client_secret: "abc"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Auth.OAuth.Providers; len(got) != 1 || got[0].Name != "discord" {
+		t.Errorf("providers = %+v", got)
+	}
+
+	for name, oauth := range map[string]string{
+		"no public_url":  "{providers: [{name: discord, client_id: a, client_secret: b}]}",
+		"no secret":      "{public_url: 'https://x.example', providers: [{name: discord, client_id: a}]}",
+		"generic no url": "{public_url: 'https://x.example', providers: [{name: keycloak, client_id: a, client_secret: b}]}",
+		"duplicate":      "{public_url: 'https://x.example', providers: [{name: google, client_id: a, client_secret: b}, {name: google, client_id: a, client_secret: b}]}",
+	} {
+		p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+auth:
+  jwt_secret: "x"
+  oauth: `+oauth+`
+`)
+		if _, err := Load(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadBrandingValidation(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+branding:
+  site_name: "Frag Haven"
+  logo_url: "/assets/custom/logo.png"
+  theme:
+    accent: "#ff8800"
+  community_links:
+    - label: "Forums"
+      url: "https://forums.example.com"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Branding.SiteName != "Frag Haven" || cfg.Branding.Theme.Accent != "#ff8800" || len(cfg.Branding.CommunityLinks) != 1 {
+		t.Errorf("branding: got %+v", cfg.Branding)
+	}
+
+	for _, bad := range []string{
+		"  theme:\n    background: \"red; background-image: url(x)\"\n",
+		"  footer_links:\n    - label: \"x\"\n      url: \"javascript:alert(1)\"\n",
+		"  footer_links:\n    - url: \"https://example.com\"\n",
+		"  logo_url: \"//evil.example.com/logo.png\"\n",
+	} {
+		p := writeConfig(t, "server:\n  listen_addr: \"127.0.0.1\"\nbranding:\n"+bad)
+		if _, err := Load(p); err == nil {
+			t.Errorf("expected error for branding:\n%s", bad)
+		}
+	}
+}
+
+func TestLoadDemoUploadsDefaults(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  listen_addr: "127.0.0.1"
+database:
+  path: /srv/trinity/trinity.db
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	d := cfg.Tracker.Hub.DemoUploads
+	if d == nil || d.Dir != "/srv/trinity/demo_uploads" || d.MaxFileMB != 100 || d.QuotaMB != 5120 {
+		t.Errorf("defaults: got %+v", d)
+	}
+
+	p = writeConfig(t, `
+tracker:
+  hub:
+    demo_uploads:
+      max_file_mb: 200
+      quota_mb: 100
+`)
+	if _, err := Load(p); err == nil {
+		t.Error("expected error for quota_mb below max_file_mb")
+	}
+}
+
+func TestLoadModerationPerServerOverrides(t *testing.T) {
+	p := writeConfig(t, `
+moderation:
+  words: ["badword"]
+  action: warn
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+  - key: duel
+    address: "127.0.0.1:27961"
+    moderation:
+      action: kick
+  - key: casual
+    address: "127.0.0.1:27962"
+    moderation:
+      action: "off"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ffa := cfg.ModerationFor(cfg.Q3Servers[0])
+	if ffa == nil || ffa.Action != ModerationWarn || ffa.Warning == "" || len(ffa.Words) != 1 {
+		t.Errorf("ffa policy = %+v, want default warn with a warning", ffa)
+	}
+	if duel := cfg.ModerationFor(cfg.Q3Servers[1]); duel == nil || duel.Action != ModerationKick || duel.Words[0] != "badword" {
+		t.Errorf("duel policy = %+v, want kick with the default words", duel)
+	}
+	if casual := cfg.ModerationFor(cfg.Q3Servers[2]); casual != nil {
+		t.Errorf("casual policy = %+v, want nil", casual)
+	}
+	if got := cfg.MuteCommandFor(cfg.Q3Servers[2]); got != "" {
+		t.Errorf("casual mute command = %q, want none", got)
+	}
+
+	// Admins' !mute needs the command even with the filter off.
+	muted := writeConfig(t, `
+moderation:
+  mute_command: "mute {client}"
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+  - key: duel
+    address: "127.0.0.1:27961"
+    moderation:
+      mute_command: "shutup {client}"
+`)
+	cfg, err = Load(muted)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.MuteCommandFor(cfg.Q3Servers[0]); got != "mute {client}" {
+		t.Errorf("ffa mute command = %q", got)
+	}
+	if got := cfg.MuteCommandFor(cfg.Q3Servers[1]); got != "shutup {client}" {
+		t.Errorf("duel mute command = %q", got)
+	}
+
+	bad := writeConfig(t, `
+moderation:
+  words: ["badword"]
+  action: mute
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+`)
+	if _, err := Load(bad); err == nil {
+		t.Error("mute without mute_command loaded; want error")
+	}
+}
+
+func TestLoadGreetings(t *testing.T) {
+	p := writeConfig(t, `
+greetings:
+  site_url: "example.com/q3"
+  new:
+    message: "Hi {name}^7! {stats}"
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+  - key: duel
+    address: "127.0.0.1:27961"
+    greetings:
+      upgrade_prompts: false
+      new:
+        center: "Rank ^3{rank}"
+  - key: casual
+    address: "127.0.0.1:27962"
+    greetings:
+      enabled: false
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ffa := cfg.GreetingsFor(cfg.Q3Servers[0])
+	if ffa.SiteURL != "example.com/q3" || ffa.New.Message != "Hi {name}^7! {stats}" || ffa.New.Center != defaultGreetings.New.Center {
+		t.Errorf("ffa new = %+v at %q, want the top-level message over the default center", ffa.New, ffa.SiteURL)
+	}
+	if ffa.Claimed == nil || ffa.TryVR == nil {
+		t.Errorf("ffa greetings = %+v, want the defaults filled in", ffa)
+	}
+	duel := cfg.GreetingsFor(cfg.Q3Servers[1])
+	if duel.New.Message != "Hi {name}^7! {stats}" || duel.New.Center != "Rank ^3{rank}" {
+		t.Errorf("duel new = %+v, want the server's center over the top-level message", duel.New)
+	}
+	if duel.Claimed == nil || duel.NoTrinity != nil || duel.OutdatedVR != nil || duel.TryVR != nil {
+		t.Errorf("duel greetings = %+v, want welcomes without upgrade prompts", duel)
+	}
+	casual := cfg.GreetingsFor(cfg.Q3Servers[2])
+	if casual.New != nil || casual.Returning != nil || casual.TryVR != nil || casual.SiteURL == "" {
+		t.Errorf("casual greetings = %+v, want none, with a site URL", casual)
+	}
+
+	bad := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    greetings:
+      claimed:
+        message: "Welcome {player}"
+`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "{player}") {
+		t.Errorf("unknown placeholder: got %v, want error naming it", err)
+	}
+}
+
+func TestLoadMapVote(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    map_vote:
+      enabled: true
+      maps: [q3dm6, q3dm17]
+  - key: duel
+    address: "127.0.0.1:27961"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if v := cfg.Q3Servers[0].MapVotePolicy(); v == nil || v.Quorum != defaultMapVoteQuorum || len(v.Maps) != 2 {
+		t.Errorf("ffa map vote = %+v, want two maps at the default quorum", v)
+	}
+	if v := cfg.Q3Servers[1].MapVotePolicy(); v != nil {
+		t.Errorf("duel map vote = %+v, want nil", v)
+	}
+
+	for _, mapVote := range []string{
+		`{enabled: true}`,
+		`{enabled: true, maps: ["q3dm17; quit"]}`,
+		`{enabled: true, maps: [q3dm17], quorum: 2}`,
+	} {
+		bad := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    map_vote: `+mapVote+`
+`)
+		if _, err := Load(bad); err == nil {
+			t.Errorf("map_vote %s loaded; want error", mapVote)
+		}
+	}
+}
+
+func TestLoadReservedSlots(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    reserved_slots:
+      enabled: true
+  - key: duel
+    address: "127.0.0.1:27961"
+    reserved_slots:
+      slots: 2
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if r := cfg.Q3Servers[0].ReservedSlotsPolicy(); r == nil || r.Slots != 1 || r.KickSpectators {
+		t.Errorf("ffa reserved slots = %+v, want one slot kicking only bots", r)
+	}
+	if r := cfg.Q3Servers[1].ReservedSlotsPolicy(); r != nil {
+		t.Errorf("duel reserved slots = %+v, want nil", r)
+	}
+}
+
+func TestLoadAbandon(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+  - key: ctf
+    address: "127.0.0.1:27961"
+    abandon:
+      min_humans: 2
+      after: 5m
+  - key: duel
+    address: "127.0.0.1:27962"
+    abandon:
+      enabled: false
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if a := cfg.Q3Servers[0].AbandonPolicy(); a == nil || a.MinHumans != 1 || time.Duration(a.After) != 2*time.Minute {
+		t.Errorf("ffa abandon = %+v, want the defaults", a)
+	}
+	if a := cfg.Q3Servers[1].AbandonPolicy(); a == nil || a.MinHumans != 2 || time.Duration(a.After) != 5*time.Minute {
+		t.Errorf("ctf abandon = %+v, want 2 humans for 5m", a)
+	}
+	if a := cfg.Q3Servers[2].AbandonPolicy(); a != nil {
+		t.Errorf("duel abandon = %+v, want nil", a)
+	}
+
+	bad := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    abandon:
+      min_humans: -1
+`)
+	if _, err := Load(bad); err == nil {
+		t.Error("negative min_humans loaded; want error")
+	}
+}
+
+func TestLoadTeamBalance(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: ctf
+    address: "127.0.0.1:27960"
+    team_balance:
+      enabled: true
+  - key: tdm
+    address: "127.0.0.1:27961"
+    team_balance:
+      enabled: true
+      max_size_diff: 2
+      max_score_diff: 20
+      enforce: true
+  - key: ffa
+    address: "127.0.0.1:27962"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if b := cfg.Q3Servers[0].TeamBalancePolicy(); b == nil || b.MaxSizeDiff != 1 || b.MaxScoreDiff != 0 ||
+		time.Duration(b.Grace) != 30*time.Second || time.Duration(b.Cooldown) != 2*time.Minute || b.Enforce {
+		t.Errorf("ctf team balance = %+v, want the defaults", b)
+	}
+	if b := cfg.Q3Servers[1].TeamBalancePolicy(); b == nil || b.MaxSizeDiff != 2 || b.MaxScoreDiff != 20 || !b.Enforce {
+		t.Errorf("tdm team balance = %+v", b)
+	}
+	if b := cfg.Q3Servers[2].TeamBalancePolicy(); b != nil {
+		t.Errorf("ffa team balance = %+v, want nil", b)
+	}
+
+	bad := writeConfig(t, `
+q3_servers:
+  - key: ctf
+    address: "127.0.0.1:27960"
+    team_balance:
+      enabled: true
+      max_score_diff: -5
+`)
+	if _, err := Load(bad); err == nil {
+		t.Error("negative max_score_diff loaded; want error")
+	}
+}
+
+func TestLoadCollectorEventArchive(t *testing.T) {
+	p := writeConfig(t, `
+tracker:
+  collector:
+    source_id: "remote-1"
+    data_dir: "/var/lib/trinity"
+    public_url: "https://remote-1.example.com"
+    hub_host: "trinity.run"
+    event_archive:
+      keep: 10
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	a := cfg.Tracker.Collector.EventArchive
+	if a == nil || a.Dir != "/var/lib/trinity/event_archive" || a.MaxFileMB != 64 || a.Keep != 10 {
+		t.Errorf("defaults: got %+v", a)
+	}
+
+	p = writeConfig(t, `
+tracker:
+  collector:
+    source_id: "remote-1"
+    data_dir: "/var/lib/trinity"
+    public_url: "https://remote-1.example.com"
+    hub_host: "trinity.run"
+    event_archive:
+      keep: -1
+`)
+	if _, err := Load(p); err == nil {
+		t.Error("expected error for negative keep")
+	}
+}
+
+func TestLoadHubSessionIPs(t *testing.T) {
+	p := writeConfig(t, `
+tracker:
+  hub:
+    session_ips:
+      retention: 30d
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ips := cfg.Tracker.Hub.SessionIPs
+	if ips.Scrub != IPScrubTruncate || ips.Retention.D() != 30*24*time.Hour || !ips.StoreEnabled() {
+		t.Errorf("defaults: got %+v", ips)
+	}
+
+	p = writeConfig(t, `
+tracker:
+  hub:
+    session_ips:
+      store: false
+`)
+	cfg, err = Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Tracker.Hub.SessionIPs.StoreEnabled() {
+		t.Error("store: false not honored")
+	}
+
+	p = writeConfig(t, `
+tracker:
+  hub:
+    session_ips:
+      scrub: rot13
+`)
+	if _, err := Load(p); err == nil {
+		t.Error("expected error for unknown scrub")
+	}
+}
+
+func TestLoadHubHealthPing(t *testing.T) {
+	p := writeConfig(t, `
+tracker:
+  hub:
+    health_ping:
+      url: "https://hc-ping.com/abc"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	hp := cfg.Tracker.Hub.HealthPing
+	if hp.Interval.D() != time.Minute || hp.StaleAfter.D() != 3*time.Minute {
+		t.Errorf("defaults: got %+v", hp)
+	}
+
+	p = writeConfig(t, `
+tracker:
+  hub:
+    health_ping:
+      url: "hc-ping.com/abc"
+`)
+	if _, err := Load(p); err == nil {
+		t.Error("expected error for URL without scheme")
+	}
+}
+
+func TestLoadServerTLS(t *testing.T) {
+	p := writeConfig(t, `
+server:
+  tls:
+    acme:
+      domains: [tracker.example.com]
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tc := cfg.Server.TLS
+	if tc.Port != 443 || tc.RedirectPort != 80 || tc.ACME.CacheDir != "/var/lib/trinity/acme" {
+		t.Errorf("defaults: got %+v %+v", tc, tc.ACME)
+	}
+
+	for _, block := range []string{
+		`{cert_file: /etc/ssl/a.pem}`,
+		`{cert_file: /etc/ssl/a.pem, key_file: /etc/ssl/a.key, acme: {domains: [a.example.com]}}`,
+		`{}`,
+		`{acme: {domains: []}}`,
+		`{acme: {domains: ["https://a.example.com"]}}`,
+		`{cert_file: /etc/ssl/a.pem, key_file: /etc/ssl/a.key, port: 8443, redirect_port: 8443}`,
+		`{cert_file: /etc/ssl/a.pem, key_file: /etc/ssl/a.key, listen_addr: "127.0.0.1", port: 8080}`,
+	} {
+		bad := writeConfig(t, `
+server:
+  tls: `+block+`
+`)
+		if _, err := Load(bad); err == nil {
+			t.Errorf("tls %s loaded; want error", block)
+		}
+	}
+}
+
+func TestLoadServerTimezone(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    timezone: "Europe/Berlin"
+  - key: duel
+    address: "127.0.0.1:27961"
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Q3Servers[0].Location().String(); got != "Europe/Berlin" {
+		t.Errorf("ffa location = %s, want Europe/Berlin", got)
+	}
+	if got := cfg.Q3Servers[1].Location(); got != time.Local {
+		t.Errorf("duel location = %s, want Local", got)
+	}
+
+	bad := writeConfig(t, `
+q3_servers:
+  - key: ffa
+    address: "127.0.0.1:27960"
+    timezone: "Mars/Olympus_Mons"
+`)
+	if _, err := Load(bad); err == nil {
+		t.Error("unknown timezone loaded; want error")
+	}
+}
+
+func TestLoadQueryOnlyServer(t *testing.T) {
+	p := writeConfig(t, `
+q3_servers:
+  - key: friends
+    address: "203.0.113.7:27960"
+    query_only: true
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Q3Servers[0].QueryOnly {
+		t.Error("query_only not loaded")
+	}
+
+	for name, extra := range map[string]string{
+		"log_path": `
+    log_path: /var/log/quake3/friends.log`,
+		"map_vote": `
+    map_vote:
+      enabled: true
+      maps: [q3dm17]`,
+		"reserved_slots": `
+    reserved_slots:
+      enabled: true`,
+	} {
+		bad := writeConfig(t, `
+q3_servers:
+  - key: friends
+    address: "203.0.113.7:27960"
+    query_only: true`+extra+"\n")
+		if _, err := Load(bad); err == nil {
+			t.Errorf("query_only with %s loaded; want error", name)
+		}
+	}
+}
+
+func TestLoadTracing(t *testing.T) {
+	p := writeConfig(t, `
+tracing:
+  endpoint: http://localhost:4318/v1/traces
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if tc := cfg.Tracing; tc.ServiceName != "trinity" || *tc.SampleRatio != 1 {
+		t.Errorf("defaults: got %+v", tc)
+	}
+
+	for _, block := range []string{
+		`{}`,
+		`{endpoint: "localhost:4318"}`,
+		`{endpoint: http://localhost:4318/v1/traces, sample_ratio: 1.5}`,
+	} {
+		bad := writeConfig(t, `
+tracing: `+block+`
+`)
+		if _, err := Load(bad); err == nil {
+			t.Errorf("tracing %s loaded; want error", block)
+		}
+	}
+}
+
+func TestLoadLeaderboard(t *testing.T) {
+	p := writeConfig(t, `
+leaderboard:
+  min_matches: 0
+  min_playtime: 1d
+  include_bots: true
+`)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if l := cfg.Leaderboard; l.MinCompletedMatches() != 0 || l.MinPlaytime != Duration(24*time.Hour) || !l.IncludeBots {
+		t.Errorf("got %+v", l)
+	}
+	var unset *LeaderboardConfig
+	if unset.MinCompletedMatches() != 5 {
+		t.Errorf("default min_matches: got %d", unset.MinCompletedMatches())
+	}
+
+	for _, block := range []string{`{min_matches: -1}`, `{min_playtime: -1h}`} {
+		bad := writeConfig(t, `
+leaderboard: `+block+`
+`)
+		if _, err := Load(bad); err == nil {
+			t.Errorf("leaderboard %s loaded; want error", block)
+		}
+	}
+}
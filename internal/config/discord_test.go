@@ -89,6 +89,14 @@ discord:
 `,
 			wantField: "discord.digest_categories[1]",
 		},
+		{
+			name: "announce_without_webhook",
+			body: `
+discord:
+  announce_returns: true
+`,
+			wantField: "discord.announce_returns",
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
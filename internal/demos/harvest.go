@@ -0,0 +1,184 @@
+package demos
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+const (
+	// harvestSettle is how long a file must go unmodified before it is
+	// taken, so a demo still being recorded isn't copied half-written.
+	harvestSettle = 30 * time.Second
+	// harvestEndSlack is how long after a match ends its recording may
+	// still be written to.
+	harvestEndSlack = 2 * time.Minute
+	// harvestGiveUp is how long a file that fits no finished match is
+	// retried, in case its match is still running, before it's skipped
+	// for good.
+	harvestGiveUp = time.Hour
+)
+
+// HarvestDir is a game server's demo directory.
+type HarvestDir struct {
+	Source    string
+	ServerKey string
+	Dir       string
+}
+
+// Notifier hears about each demo the harvester attaches to a match,
+// e.g. to announce it. It's called on the scan goroutine, so it should
+// hand slow work off.
+type Notifier interface {
+	NotifyHarvest(matchID int64, demo *domain.MatchDemo)
+}
+
+// HarvesterConfig configures a Harvester. Notifier is optional.
+type HarvesterConfig struct {
+	Library  *Library
+	Dirs     []HarvestDir
+	Interval time.Duration
+	Notifier Notifier
+}
+
+// Harvester scans game servers' demo directories and copies each
+// finished .dm_68 into the library, attached to the match it recorded.
+// A file named after a match UUID goes to that match; otherwise its
+// modification time (when recording stopped) picks the match that was
+// running on that server.
+type Harvester struct {
+	cfg HarvesterConfig
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewHarvester validates cfg and returns a Harvester ready to Start.
+func NewHarvester(cfg HarvesterConfig) (*Harvester, error) {
+	if cfg.Library == nil || cfg.Library.Store == nil {
+		return nil, errors.New("demos: Library with a Store is required")
+	}
+	if cfg.Interval <= 0 {
+		return nil, errors.New("demos: Interval must be positive")
+	}
+	return &Harvester{
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}, nil
+}
+
+// Start runs the scan loop on its own goroutine.
+func (h *Harvester) Start(ctx context.Context) {
+	go h.run(ctx)
+}
+
+// Stop halts the scan loop and waits for any in-flight scan to exit.
+func (h *Harvester) Stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+	<-h.doneCh
+}
+
+func (h *Harvester) run(ctx context.Context) {
+	defer close(h.doneCh)
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.stopCh:
+			return
+		case <-ticker.C:
+			for _, d := range h.cfg.Dirs {
+				if err := h.Scan(ctx, d, time.Now()); err != nil {
+					log.Printf("demos: harvest %s: %v", d.Dir, err)
+				}
+			}
+		}
+	}
+}
+
+// Scan harvests every settled, not yet handled demo in d as of now.
+// A file that can't be copied is logged and retried next scan.
+func (h *Harvester) Scan(ctx context.Context, d HarvestDir, now time.Time) error {
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		return err
+	}
+	store := h.cfg.Library.Store
+	for _, e := range entries {
+		if e.IsDir() || !HasExt(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		modTime := info.ModTime().UTC().Truncate(time.Second)
+		if now.Sub(modTime) < harvestSettle {
+			continue
+		}
+		path := filepath.Join(d.Dir, e.Name())
+		done, err := store.IsDemoHarvested(ctx, path, info.Size(), modTime)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+
+		uuid := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		matchID, err := store.FindDemoMatch(ctx, d.Source, d.ServerKey, uuid, modTime, harvestEndSlack)
+		if err != nil {
+			return err
+		}
+		if matchID == nil {
+			if now.Sub(modTime) < harvestGiveUp {
+				continue
+			}
+			log.Printf("demos: no finished match on %s fits %s, skipping it", d.ServerKey, path)
+			if err := store.RecordHarvestedDemo(ctx, path, info.Size(), modTime, nil, now); err != nil {
+				return err
+			}
+			continue
+		}
+
+		demoID, err := h.harvest(ctx, path, *matchID, now)
+		if errors.Is(err, ErrTooLarge) || errors.Is(err, ErrEmpty) {
+			log.Printf("demos: skipping %s: %v", path, err)
+		} else if err != nil {
+			log.Printf("demos: harvest %s: %v", path, err)
+			continue
+		}
+		if err := store.RecordHarvestedDemo(ctx, path, info.Size(), modTime, demoID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Harvester) harvest(ctx context.Context, path string, matchID int64, now time.Time) (*int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	demo, err := h.cfg.Library.Add(ctx, matchID, filepath.Base(path), f, nil, now)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("demos: harvested %s as %d for match %d", path, demo.ID, matchID)
+	if h.cfg.Notifier != nil {
+		h.cfg.Notifier.NotifyHarvest(matchID, demo)
+	}
+	return &demo.ID, nil
+}
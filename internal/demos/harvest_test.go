@@ -0,0 +1,101 @@
+package demos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+func TestHarvesterScan(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	now := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "local", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	newMatch := func(uuid string, start, end time.Time) int64 {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: start}
+		if err := store.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := store.EndMatch(ctx, m.ID, end, "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+		return m.ID
+	}
+	early := newMatch("early", now.Add(-3*time.Hour), now.Add(-150*time.Minute))
+	late := newMatch("late", now.Add(-time.Hour), now.Add(-40*time.Minute))
+
+	dir := t.TempDir()
+	writeDemo := func(name string, modTime time.Time) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("demo"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDemo("by-time.dm_68", now.Add(-39*time.Minute))   // just after late ended
+	writeDemo("early.dm_68", now.Add(-10*time.Minute))     // named for its match
+	writeDemo("recording.dm_68", now.Add(-10*time.Second)) // not settled yet
+	writeDemo("notes.txt", now.Add(-time.Hour))
+
+	lib := &Library{Dir: t.TempDir(), MaxFileBytes: 1 << 20, QuotaBytes: 1 << 20, Store: store}
+	notified := &recordingNotifier{}
+	h, err := NewHarvester(HarvesterConfig{Library: lib, Interval: time.Minute, Notifier: notified})
+	if err != nil {
+		t.Fatalf("NewHarvester: %v", err)
+	}
+	hd := HarvestDir{Source: "local", ServerKey: "ffa", Dir: dir}
+	for range 2 {
+		if err := h.Scan(ctx, hd, now); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		matchID int64
+		want    string
+	}{{late, "by-time.dm_68"}, {early, "early.dm_68"}} {
+		got, err := store.ListMatchDemos(ctx, tc.matchID)
+		if err != nil {
+			t.Fatalf("ListMatchDemos: %v", err)
+		}
+		if len(got) != 1 || got[0].Filename != tc.want || got[0].UploadedBy != nil {
+			t.Fatalf("match %d demos: got %+v, want one %s", tc.matchID, got, tc.want)
+		}
+		if _, err := os.Stat(lib.Path(got[0].ID)); err != nil {
+			t.Errorf("library file for %s: %v", tc.want, err)
+		}
+	}
+	total, err := store.DemoStorageBytes(ctx)
+	if err != nil || total != 8 {
+		t.Errorf("library size: got %d, %v; want 8", total, err)
+	}
+	// Each demo is announced once, however many scans see it.
+	if len(notified.matches) != 2 {
+		t.Errorf("notified for matches %v, want both once", notified.matches)
+	}
+}
+
+type recordingNotifier struct {
+	matches []int64
+}
+
+func (n *recordingNotifier) NotifyHarvest(matchID int64, demo *domain.MatchDemo) {
+	n.matches = append(n.matches, matchID)
+}
@@ -0,0 +1,120 @@
+// Package demos keeps the hub's library of Quake 3 client demos
+// (.dm_68): uploads from admins and recordings harvested from game
+// servers' demo directories. Files live as <Dir>/<id>.dm_68, one per
+// match_demos row, and the least recently downloaded are evicted once
+// the library outgrows its quota.
+package demos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// Ext is the only demo format the library accepts: the stock Quake 3
+// 1.32 client demo.
+const Ext = ".dm_68"
+
+var (
+	// ErrTooLarge means the demo is over MaxFileBytes.
+	ErrTooLarge = errors.New("demo is too large")
+	// ErrEmpty means the demo has no data.
+	ErrEmpty = errors.New("demo is empty")
+)
+
+// Library stores demo files and their match_demos rows.
+type Library struct {
+	Dir          string
+	MaxFileBytes int64
+	QuotaBytes   int64
+	Store        *storage.Store
+}
+
+// HasExt reports whether name is a .dm_68 file, ignoring case.
+func HasExt(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), Ext)
+}
+
+// URL is where the API serves a demo. It ends in .dm_68 so the web
+// player's engine picks the demo protocol from it.
+func URL(id int64) string {
+	return "/api/demos/" + strconv.FormatInt(id, 10) + Ext
+}
+
+// Path is where a demo's file lives.
+func (l *Library) Path(id int64) string {
+	return filepath.Join(l.Dir, strconv.FormatInt(id, 10)+Ext)
+}
+
+// Add copies r into the library as a demo of matchID, then evicts
+// older demos if that put the library over quota. name is the original
+// filename, kept for downloads. uploadedBy is nil for harvested demos.
+func (l *Library) Add(ctx context.Context, matchID int64, name string, r io.Reader, uploadedBy *int64, at time.Time) (*domain.MatchDemo, error) {
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(l.Dir, "incoming-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed into place
+	n, err := io.Copy(tmp, io.LimitReader(r, l.MaxFileBytes+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case n > l.MaxFileBytes:
+		return nil, ErrTooLarge
+	case n == 0:
+		return nil, ErrEmpty
+	}
+
+	demo, err := l.Store.CreateMatchDemo(ctx, matchID, filepath.Base(name), n, uploadedBy, at)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), l.Path(demo.ID)); err != nil {
+		l.Store.DeleteMatchDemo(ctx, demo.ID)
+		return nil, fmt.Errorf("moving demo into place: %w", err)
+	}
+	l.enforceQuota(ctx, demo.ID)
+	demo.URL = URL(demo.ID)
+	return demo, nil
+}
+
+// Remove deletes a demo's file and row.
+func (l *Library) Remove(ctx context.Context, id int64) error {
+	if err := os.Remove(l.Path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return l.Store.DeleteMatchDemo(ctx, id)
+}
+
+// enforceQuota deletes the least recently used demos until the total
+// fits the quota again. keepID is the demo that triggered it.
+func (l *Library) enforceQuota(ctx context.Context, keepID int64) {
+	evict, err := l.Store.DemosOverQuota(ctx, l.QuotaBytes, keepID)
+	if err != nil {
+		log.Printf("demos: quota check: %v", err)
+		return
+	}
+	for _, d := range evict {
+		if err := l.Remove(ctx, d.ID); err != nil {
+			log.Printf("demos: evict %d: %v", d.ID, err)
+			continue
+		}
+		log.Printf("demos: evicted %d (%s, %d bytes) for match %d to stay under quota", d.ID, d.Filename, d.SizeBytes, d.MatchID)
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import "time"
+
+// Clan roles. A clan's creator leads it; the leader and admins can
+// change it and remove members.
+const (
+	ClanRoleLeader = "leader"
+	ClanRoleMember = "member"
+)
+
+// How a player came to be in a clan.
+const (
+	ClanSourceJoined = "joined" // joined, or created the clan
+	ClanSourceAdded  = "added"  // added by an admin or the clan's leader
+	ClanSourceTag    = "tag"    // their name carries the clan's tag
+)
+
+// Clan is a registered clan. Tag is matched case-insensitively against
+// the start or end of players' clean names, so "[TA]" picks up
+// "[TA]alice" and "bob[ta]".
+type Clan struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Tag         string    `json:"tag"`
+	CreatedBy   *int64    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	MemberCount int       `json:"member_count"`
+}
+
+// ClanMember is a player in a clan. Source is a ClanSource* value.
+type ClanMember struct {
+	Player   Player    `json:"player"`
+	Role     string    `json:"role"`
+	Source   string    `json:"source"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ClanStats sums the match stats of a clan's current members.
+type ClanStats struct {
+	Matches          int64   `json:"matches"`
+	CompletedMatches int64   `json:"completed_matches"`
+	Frags            int64   `json:"frags"`
+	Deaths           int64   `json:"deaths"`
+	KDRatio          float64 `json:"kd_ratio"`
+	Captures         int64   `json:"captures"`
+	Assists          int64   `json:"assists"`
+	Defends          int64   `json:"defends"`
+	Victories        int64   `json:"victories"`
+	ClanWins         int64   `json:"clan_wins"`   // clan matches won
+	ClanLosses       int64   `json:"clan_losses"` // clan matches lost
+}
+
+// ClanResponse is returned by GET /api/clans/{id}.
+type ClanResponse struct {
+	Clan    Clan         `json:"clan"`
+	Members []ClanMember `json:"members"`
+	Period  string       `json:"period"`
+	Stats   ClanStats    `json:"stats"`
+}
+
+// ClanLeaderboardEntry is one clan's place on a clan leaderboard.
+type ClanLeaderboardEntry struct {
+	Rank  int       `json:"rank"`
+	Clan  Clan      `json:"clan"`
+	Stats ClanStats `json:"stats"`
+}
+
+// ClanLeaderboardResponse is returned by GET /api/clans/leaderboard.
+type ClanLeaderboardResponse struct {
+	Category    string                 `json:"category"`
+	Period      string                 `json:"period"`
+	PeriodStart *time.Time             `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time             `json:"period_end,omitempty"`
+	Entries     []ClanLeaderboardEntry `json:"entries"`
+}
+
+// Results of a clan match, from the clan's side.
+const (
+	ClanResultWin  = "win"
+	ClanResultLoss = "loss"
+	ClanResultTie  = "tie"
+)
+
+// ClanMatch is a finished team game in which most of one team belonged
+// to the clan and most of the other to Opponent. Players counts the
+// clan's members on their team.
+type ClanMatch struct {
+	MatchID         int64     `json:"match_id"`
+	MapName         string    `json:"map_name"`
+	GameType        string    `json:"game_type"`
+	EndedAt         time.Time `json:"ended_at"`
+	Team            int       `json:"team"`
+	Opponent        Clan      `json:"opponent"`
+	Score           *int      `json:"score,omitempty"`
+	OpponentScore   *int      `json:"opponent_score,omitempty"`
+	Result          string    `json:"result"`
+	Players         int       `json:"players"`
+	OpponentPlayers int       `json:"opponent_players"`
+}
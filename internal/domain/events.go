@@ -1,36 +1,57 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Event types for WebSocket notifications
 const (
-	EventPlayerJoin    = "player_join"
-	EventPlayerLeave   = "player_leave"
-	EventServerUpdate  = "server_update"
-	EventMatchStart    = "match_start"
-	EventMatchEnd      = "match_end"
-	EventFrag          = "frag"
-	EventFlagCapture   = "flag_capture"
-	EventFlagTaken     = "flag_taken"
-	EventFlagReturn    = "flag_return"
-	EventFlagDrop      = "flag_drop"
+	EventPlayerJoin     = "player_join"
+	EventPlayerLeave    = "player_leave"
+	EventServerUpdate   = "server_update"
+	EventMatchStart     = "match_start"
+	EventMatchEnd       = "match_end"
+	EventFrag           = "frag"
+	EventFlagCapture    = "flag_capture"
+	EventFlagTaken      = "flag_taken"
+	EventFlagReturn     = "flag_return"
+	EventFlagDrop       = "flag_drop"
 	EventObeliskDestroy = "obelisk_destroy"
-	EventSkullScore    = "skull_score"
-	EventTeamChange    = "team_change"
-	EventSay           = "say"
-	EventSayTeam       = "say_team"
-	EventTell          = "tell"
-	EventSayRcon       = "say_rcon"
-	EventAward            = "award"
-	EventClientUserinfo   = "client_userinfo"
+	EventSkullScore     = "skull_score"
+	EventTeamChange     = "team_change"
+	EventSay            = "say"
+	EventSayTeam        = "say_team"
+	EventTell           = "tell"
+	EventSayRcon        = "say_rcon"
+	EventAward          = "award"
+	EventClientUserinfo = "client_userinfo"
+	EventPlayerReturn   = "player_return"
+	EventMatchSnapshot  = "match_snapshot"
+	EventTeamBalance    = "team_balance"
 )
 
+// IsHighValueEvent reports whether losing an event of type t would
+// leave the live feed wrong until the next match rather than just
+// missing a moment. Buffers wait briefly for these instead of
+// dropping them outright.
+func IsHighValueEvent(t string) bool {
+	switch t {
+	case EventMatchStart, EventMatchEnd, EventPlayerJoin, EventPlayerLeave, EventTeamChange:
+		return true
+	}
+	return false
+}
+
 // Event represents a real-time event for WebSocket broadcast
 type Event struct {
 	Type      string      `json:"event"`
 	ServerID  int64       `json:"server_id"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data,omitempty"`
+	// Seq numbers the events the hub sends to every browser client, in
+	// order. It's set on the way out and is zero everywhere else.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 // PlayerJoinEvent is sent when a player connects
@@ -51,6 +72,27 @@ type ClientUserinfoEvent struct {
 	IsVR      bool   `json:"is_vr"`
 }
 
+// PlayerReturnEvent is sent after the greet for a player whose
+// previous session ended ReturningPlayerThreshold or more ago. Emitted
+// alongside (not instead of) the PlayerJoinEvent so the activity feed
+// can call out the comeback.
+type PlayerReturnEvent struct {
+	ClientNum  int    `json:"client_num"`
+	PlayerName string `json:"player_name"`
+	DaysAway   int    `json:"days_away"`
+	GUID       string `json:"guid,omitempty"`
+	PlayerID   *int64 `json:"player_id,omitempty"`
+}
+
+// MatchSnapshotEvent is the first message on a /ws/match/{id}
+// connection: everything a spectator page needs to draw the scoreboard
+// before live events start arriving. Status is nil until the poller
+// has reached the server; Match is nil between matches.
+type MatchSnapshotEvent struct {
+	Status *ServerStatus `json:"status,omitempty"`
+	Match  *Match        `json:"match,omitempty"`
+}
+
 // PlayerLeaveEvent is sent when a player disconnects
 type PlayerLeaveEvent struct {
 	PlayerName string `json:"player_name"`
@@ -69,17 +111,60 @@ type MatchEndEvent struct {
 	ExitReason string `json:"exit_reason"`
 }
 
-// FragEvent is sent when a frag occurs
+// FragEvent is sent when a frag occurs. Weapon is the raw means of
+// death from the log; WeaponSlug is its normalized form for icons.
+// Teams are 1=Red, 2=Blue, omitted outside team games. EndedSpree is
+// the victim's kill streak when it was at least KillFeedSpree.
 type FragEvent struct {
 	Fragger         string `json:"fragger"`
 	Victim          string `json:"victim"`
 	Weapon          string `json:"weapon"`
+	WeaponSlug      string `json:"weapon_slug"`
+	FraggerTeam     int    `json:"fragger_team,omitempty"`
+	VictimTeam      int    `json:"victim_team,omitempty"`
+	Revenge         bool   `json:"revenge,omitempty"`
+	EndedSpree      int    `json:"ended_spree,omitempty"`
 	FraggerGUID     string `json:"fragger_guid,omitempty"`
 	VictimGUID      string `json:"victim_guid,omitempty"`
 	FraggerPlayerID *int64 `json:"fragger_player_id,omitempty"`
 	VictimPlayerID  *int64 `json:"victim_player_id,omitempty"`
 }
 
+// KillFeedSpree is the shortest kill streak whose end the kill feed
+// calls out.
+const KillFeedSpree = 5
+
+// Weapons lists the slugs of every weapon a player can frag with, in
+// the game's weapon order.
+var Weapons = []string{
+	"gauntlet", "machinegun", "shotgun", "grenade", "rocket", "lightning",
+	"railgun", "plasma", "bfg", "nailgun", "chaingun", "proxmine",
+	"grapple", "kamikaze", "juiced", "telefrag",
+}
+
+// weaponSlugs renames means of death whose trimmed name makes a poor
+// icon key. Anything not listed is lowercased as is.
+var weaponSlugs = map[string]string{
+	"nail":           "nailgun",
+	"proximity_mine": "proxmine",
+	"target_laser":   "laser",
+}
+
+// WeaponSlug normalizes a means of death such as "MOD_ROCKET_SPLASH"
+// to the weapon it came from ("rocket"). Splash and direct hits share
+// a slug; environmental deaths keep their own ("lava", "falling").
+func WeaponSlug(mod string) string {
+	s := strings.ToLower(strings.TrimPrefix(mod, "MOD_"))
+	s = strings.TrimSuffix(s, "_splash")
+	if r, ok := weaponSlugs[s]; ok {
+		return r
+	}
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
 // FlagCaptureEvent is sent when a flag is captured (CTF)
 type FlagCaptureEvent struct {
 	ClientNum  int    `json:"client_num"`
@@ -100,7 +185,7 @@ type FlagTakenEvent struct {
 
 // FlagReturnEvent is sent when a flag is returned
 type FlagReturnEvent struct {
-	ClientNum  int    `json:"client_num"` // -1 for auto-return
+	ClientNum  int    `json:"client_num"`  // -1 for auto-return
 	PlayerName string `json:"player_name"` // may be empty for auto-return
 	Team       int    `json:"team"`        // team of the flag that was returned
 	GUID       string `json:"guid,omitempty"`
@@ -142,6 +227,29 @@ type TeamChangeEvent struct {
 	PlayerID   *int64 `json:"player_id,omitempty"`
 }
 
+// TeamBalanceEvent is sent when the collector finds a team game's
+// teams uneven and suggests moves to even them, or makes them itself
+// when Enforced. Reason is "size" or "score"; the counts and scores
+// are the teams' before the moves.
+type TeamBalanceEvent struct {
+	Reason    string            `json:"reason"`
+	RedCount  int               `json:"red_count"`
+	BlueCount int               `json:"blue_count"`
+	RedScore  int               `json:"red_score"`
+	BlueScore int               `json:"blue_score"`
+	Moves     []TeamBalanceMove `json:"moves"`
+	Enforced  bool              `json:"enforced"`
+}
+
+// TeamBalanceMove is one player to move to ToTeam (1=Red, 2=Blue).
+type TeamBalanceMove struct {
+	ClientNum  int    `json:"client_num"`
+	PlayerName string `json:"player_name"`
+	ToTeam     int    `json:"to_team"`
+	GUID       string `json:"guid,omitempty"`
+	PlayerID   *int64 `json:"player_id,omitempty"`
+}
+
 // SayEvent is sent when a player sends a global chat message
 type SayEvent struct {
 	ClientNum  int    `json:"client_num"`
@@ -162,15 +270,15 @@ type SayTeamEvent struct {
 
 // TellEvent is sent when a player sends a private message
 type TellEvent struct {
-	FromClientNum  int    `json:"from_client_num"`
-	ToClientNum    int    `json:"to_client_num"`
-	FromName       string `json:"from_name"`
-	ToName         string `json:"to_name"`
-	Message        string `json:"message"`
-	FromGUID       string `json:"from_guid,omitempty"`
-	ToGUID         string `json:"to_guid,omitempty"`
-	FromPlayerID   *int64 `json:"from_player_id,omitempty"`
-	ToPlayerID     *int64 `json:"to_player_id,omitempty"`
+	FromClientNum int    `json:"from_client_num"`
+	ToClientNum   int    `json:"to_client_num"`
+	FromName      string `json:"from_name"`
+	ToName        string `json:"to_name"`
+	Message       string `json:"message"`
+	FromGUID      string `json:"from_guid,omitempty"`
+	ToGUID        string `json:"to_guid,omitempty"`
+	FromPlayerID  *int64 `json:"from_player_id,omitempty"`
+	ToPlayerID    *int64 `json:"to_player_id,omitempty"`
 }
 
 // SayRconEvent is sent when an RCON message is broadcast
@@ -182,8 +290,8 @@ type SayRconEvent struct {
 type AwardEvent struct {
 	ClientNum      int    `json:"client_num"`
 	PlayerName     string `json:"player_name"`
-	AwardType      string `json:"award_type"` // impressive, excellent, humiliation, defend, assist
-	Team           int    `json:"team,omitempty"`             // player's team (1=Red, 2=Blue)
+	AwardType      string `json:"award_type"`     // impressive, excellent, humiliation, defend, assist
+	Team           int    `json:"team,omitempty"` // player's team (1=Red, 2=Blue)
 	GUID           string `json:"guid,omitempty"`
 	PlayerID       *int64 `json:"player_id,omitempty"`
 	VictimName     string `json:"victim_name,omitempty"`      // for humiliation awards
@@ -0,0 +1,22 @@
+package domain
+
+import "testing"
+
+func TestWeaponSlug(t *testing.T) {
+	cases := map[string]string{
+		"MOD_ROCKET":         "rocket",
+		"MOD_ROCKET_SPLASH":  "rocket",
+		"MOD_RAILGUN":        "railgun",
+		"MOD_BFG_SPLASH":     "bfg",
+		"MOD_NAIL":           "nailgun",
+		"MOD_PROXIMITY_MINE": "proxmine",
+		"MOD_TRIGGER_HURT":   "trigger_hurt",
+		"MOD_LAVA":           "lava",
+		"":                   "unknown",
+	}
+	for mod, want := range cases {
+		if got := WeaponSlug(mod); got != want {
+			t.Errorf("WeaponSlug(%q) = %q, want %q", mod, got, want)
+		}
+	}
+}
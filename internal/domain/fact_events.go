@@ -7,17 +7,22 @@ import "time"
 // GUIDs and match UUIDs, never DB row IDs.
 
 const (
-	FactMatchStart           = "match_start"
-	FactMatchEnd             = "match_end"
-	FactMatchSettingsUpdate  = "match_settings_update"
-	FactMatchCrashed         = "match_crashed"
-	FactPlayerJoin           = "player_join"
-	FactPlayerLeave          = "player_leave"
-	FactPresenceSnapshot     = "presence_snapshot"
-	FactTrinityHandshake     = "trinity_handshake"
-	FactServerStartup        = "server_startup"
-	FactServerShutdown       = "server_shutdown"
-	FactDemoFinalized        = "demo_finalized"
+	FactMatchStart          = "match_start"
+	FactMatchEnd            = "match_end"
+	FactMatchSettingsUpdate = "match_settings_update"
+	FactMatchCrashed        = "match_crashed"
+	FactPlayerJoin          = "player_join"
+	FactPlayerLeave         = "player_leave"
+	FactPresenceSnapshot    = "presence_snapshot"
+	FactTrinityHandshake    = "trinity_handshake"
+	FactServerStartup       = "server_startup"
+	FactServerShutdown      = "server_shutdown"
+	FactServerCrash         = "server_crash"
+	FactDemoFinalized       = "demo_finalized"
+	FactChatMessage         = "chat_message"
+	FactModerationIncident  = "moderation_incident"
+	FactVIPKick             = "vip_kick"
+	FactAdminCommand        = "admin_command"
 )
 
 // FactEvent is the in-process envelope carrying a payload from the
@@ -49,6 +54,16 @@ type MatchStartData struct {
 	Gameplay          string    `json:"gameplay,omitempty"`
 	StartedAt         time.Time `json:"started_at"`
 	HandshakeRequired bool      `json:"handshake_required"`
+	// Warmup is what each player scored in the warmup just ended.
+	// Warmup play is for fun: it's kept apart from the match's stats.
+	Warmup []WarmupPlayer `json:"warmup,omitempty"`
+}
+
+// WarmupPlayer is one player's frags and deaths during warmup.
+type WarmupPlayer struct {
+	GUID   string `json:"guid"`
+	Frags  int    `json:"frags"`
+	Deaths int    `json:"deaths"`
 }
 
 // MatchEndData is emitted on match shutdown (intermission or abrupt).
@@ -60,34 +75,106 @@ type MatchEndData struct {
 	RedScore   *int             `json:"red_score,omitempty"`
 	BlueScore  *int             `json:"blue_score,omitempty"`
 	Players    []MatchEndPlayer `json:"players"`
+	// Scoreboard is the final scoreboard as the server logged it after
+	// Exit, in its order. Empty when the match didn't exit normally.
+	Scoreboard []MatchEndScore `json:"scoreboard,omitempty"`
+	// FlagEvents is every flag pickup, drop, capture and return after
+	// warmup, in order. Empty outside flag modes.
+	FlagEvents []MatchEndFlagEvent `json:"flag_events,omitempty"`
+	// Abandoned is set when the humans left and the match ran on
+	// without them; see config.AbandonConfig.
+	Abandoned bool `json:"abandoned,omitempty"`
+	// Overtime is how many seconds the match ran past regulation, or
+	// nil if it didn't go to overtime (sudden death included).
+	Overtime *int `json:"overtime_seconds,omitempty"`
+	// Cursor is the log position just past the line that ended the
+	// match. The hub saves it with the stats, so a restarted collector
+	// replays exactly the lines after it as new.
+	Cursor *LogCursor `json:"cursor,omitempty"`
+}
+
+// LogCursor is a position in a game server's log: Offset bytes into
+// the file with inode Inode, just past a line stamped At.
+type LogCursor struct {
+	Inode  uint64    `json:"inode"`
+	Offset int64     `json:"offset"`
+	At     time.Time `json:"at"`
 }
 
 // MatchEndPlayer carries one player's final stats for a match. Identity
 // is by GUID; the hub writer resolves it to player_guid_id.
 type MatchEndPlayer struct {
-	GUID         string    `json:"guid"`
-	ClientID     int       `json:"client_id"`
-	Name         string    `json:"name"`
-	CleanName    string    `json:"clean_name"`
-	Frags        int       `json:"frags"`
-	Deaths       int       `json:"deaths"`
-	Completed    bool      `json:"completed"`
-	Score        *int      `json:"score,omitempty"`
-	Team         *int      `json:"team,omitempty"`
-	Model        string    `json:"model,omitempty"`
-	Skill        float64   `json:"skill,omitempty"`
-	Victory      bool      `json:"victory"`
-	Captures     int       `json:"captures"`
-	FlagReturns  int       `json:"flag_returns"`
-	Assists      int       `json:"assists"`
-	Impressives  int       `json:"impressives"`
-	Excellents   int       `json:"excellents"`
-	Humiliations int       `json:"humiliations"`
-	Defends      int       `json:"defends"`
-	IsBot        bool      `json:"is_bot"`
-	JoinedLate   bool      `json:"joined_late"`
-	JoinedAt     time.Time `json:"joined_at"`
-	IsVR         bool      `json:"is_vr"`
+	GUID            string    `json:"guid"`
+	ClientID        int       `json:"client_id"`
+	Name            string    `json:"name"`
+	CleanName       string    `json:"clean_name"`
+	Frags           int       `json:"frags"`
+	Deaths          int       `json:"deaths"`
+	Completed       bool      `json:"completed"`
+	Score           *int      `json:"score,omitempty"`
+	Team            *int      `json:"team,omitempty"`
+	Model           string    `json:"model,omitempty"`
+	Skill           float64   `json:"skill,omitempty"`
+	Victory         bool      `json:"victory"`
+	Captures        int       `json:"captures"`
+	FlagReturns     int       `json:"flag_returns"`
+	Assists         int       `json:"assists"`
+	Impressives     int       `json:"impressives"`
+	Excellents      int       `json:"excellents"`
+	Humiliations    int       `json:"humiliations"`
+	Defends         int       `json:"defends"`
+	BestSpree       int       `json:"best_spree,omitempty"`       // most kills without dying
+	FastestCapMs    int64     `json:"fastest_cap_ms,omitempty"`   // quickest flag pickup to capture
+	FlagCarryMs     int64     `json:"flag_carry_ms,omitempty"`    // total time carrying the flag
+	SkullsScored    int       `json:"skulls_scored,omitempty"`    // skulls brought to the enemy obelisk (Harvester)
+	ObeliskDestroys int       `json:"obelisk_destroys,omitempty"` // enemy obelisks destroyed (Overload)
+	IsBot           bool      `json:"is_bot"`
+	JoinedLate      bool      `json:"joined_late"`
+	JoinedAt        time.Time `json:"joined_at"`
+	IsVR            bool      `json:"is_vr"`
+
+	// WeaponFrags counts frags on other players by WeaponSlug.
+	WeaponFrags map[string]int `json:"weapon_frags,omitempty"`
+	// Accuracy is shots and hits by WeaponSlug, from the Weapon_Stats
+	// lines our mod logs at Exit. Empty on servers that don't log them.
+	Accuracy map[string]WeaponShots `json:"accuracy,omitempty"`
+}
+
+// WeaponShots is how often a player fired one weapon and how often
+// it hit.
+type WeaponShots struct {
+	Shots int `json:"shots"`
+	Hits  int `json:"hits"`
+}
+
+// MatchEndFlagEvent is one flag event in a match. Event is a
+// FlagEvent* value and Team is the team of the flag. ClientID is -1
+// for a flag that returned on its own.
+type MatchEndFlagEvent struct {
+	At       time.Time `json:"at"`
+	Event    string    `json:"event"`
+	Team     int       `json:"team"`
+	ClientID int       `json:"client_id"`
+	GUID     string    `json:"guid,omitempty"`
+}
+
+// Flag events, as MatchEndFlagEvent and FlagTimelineEvent carry them.
+const (
+	FlagEventTaken    = "taken"
+	FlagEventDropped  = "dropped"
+	FlagEventCaptured = "captured"
+	FlagEventReturned = "returned"
+)
+
+// MatchEndScore is one line of the scoreboard a server logs at Exit.
+// The hub stores it with the match, GUID resolved to the player.
+type MatchEndScore struct {
+	ClientID int    `json:"client_id"`
+	GUID     string `json:"guid,omitempty"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	Ping     int    `json:"ping"`
+	Team     int    `json:"team"`
 }
 
 // MatchSettingsUpdateData is emitted when `g_movement` or `g_gameplay`
@@ -112,14 +199,20 @@ type MatchCrashedData struct {
 // client_engine / client_version are NOT set here — they arrive later
 // via TrinityHandshakeData once the handshake completes.
 type PlayerJoinData struct {
-	MatchUUID string    `json:"match_uuid,omitempty"`
-	GUID      string    `json:"guid"`
-	Name      string    `json:"name"`
-	CleanName string    `json:"clean_name"`
-	Model     string    `json:"model,omitempty"`
-	IP        string    `json:"ip,omitempty"`
-	IsBot     bool      `json:"is_bot"`
-	IsVR      bool      `json:"is_vr"`
+	MatchUUID string `json:"match_uuid,omitempty"`
+	GUID      string `json:"guid"`
+	Name      string `json:"name"`
+	CleanName string `json:"clean_name"`
+	Model     string `json:"model,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	// CountryCode / Country / City come from the collector's optional
+	// GeoIP database; all empty when it isn't configured or the IP
+	// doesn't resolve.
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+	IsBot       bool   `json:"is_bot"`
+	IsVR        bool   `json:"is_vr"`
 	// Skill is the bot's q3 skill level (1-5). Zero for humans.
 	// Carried so the hub's presence tracker can render the bot badge
 	// tier on the live dashboard.
@@ -190,6 +283,26 @@ type ServerShutdownData struct {
 	ShutdownAt time.Time `json:"shutdown_at"`
 }
 
+// ServerCrashData is emitted when the collector finds its game server
+// went down without a clean ServerShutdown: the log shows a new
+// ServerStartup first (CrashNoShutdown), or systemd has marked the
+// server's unit failed (CrashUnitFailed, with systemd's result in
+// Detail). LogLines are the server's last log lines before it went
+// down, oldest first. The hub writer records an incident and closes
+// the server's open sessions at CrashedAt.
+type ServerCrashData struct {
+	CrashedAt time.Time `json:"crashed_at"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail,omitempty"`
+	LogLines  []string  `json:"log_lines"`
+}
+
+// Server crash reasons.
+const (
+	CrashNoShutdown = "no_shutdown"
+	CrashUnitFailed = "unit_failed"
+)
+
 // DemoFinalizedData is emitted when trinity-engine logs a "DemoSaved:"
 // line for a match — i.e. the .tvd file has been finalized on disk
 // and is fetchable from the source's public_url. The hub writer flips
@@ -202,3 +315,65 @@ type DemoFinalizedData struct {
 	DurationMS int    `json:"duration_ms,omitempty"`
 	Bytes      uint64 `json:"bytes,omitempty"`
 }
+
+// Chat message kinds carried in ChatMessageData.Kind.
+const (
+	ChatSay     = "say"
+	ChatSayTeam = "say_team"
+	ChatTell    = "tell"
+)
+
+// ChatMessageData is emitted for each say, say_team, or tell during a
+// match. Team is the sender's team when they spoke. ToGUID and ToName
+// are set only for tells.
+type ChatMessageData struct {
+	MatchUUID string    `json:"match_uuid"`
+	Kind      string    `json:"kind"`
+	GUID      string    `json:"guid"`
+	Name      string    `json:"name"`
+	Team      int       `json:"team,omitempty"`
+	ToGUID    string    `json:"to_guid,omitempty"`
+	ToName    string    `json:"to_name,omitempty"`
+	Message   string    `json:"message"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// ModerationIncidentData is emitted when a chat line trips the
+// server's word filter. Action is what the collector did about it
+// (log, warn, mute, or kick). MatchUUID is empty outside a match.
+type ModerationIncidentData struct {
+	MatchUUID string    `json:"match_uuid,omitempty"`
+	GUID      string    `json:"guid"`
+	Name      string    `json:"name"`
+	ClientNum int       `json:"client_num"`
+	Message   string    `json:"message"`
+	Matched   string    `json:"matched"`
+	Action    string    `json:"action"`
+	At        time.Time `json:"at"`
+}
+
+// VIPKickData is emitted when the collector kicks someone to keep a
+// reserved slot free for a VIP who just joined. KickedIsBot is false
+// for a human spectator.
+type VIPKickData struct {
+	VIPGUID      string    `json:"vip_guid"`
+	VIPName      string    `json:"vip_name"`
+	KickedGUID   string    `json:"kicked_guid"`
+	KickedName   string    `json:"kicked_name"`
+	KickedClient int       `json:"kicked_client"`
+	KickedIsBot  bool      `json:"kicked_is_bot"`
+	At           time.Time `json:"at"`
+}
+
+// AdminCommandData is emitted when an admin runs !kick, !mute, or !map
+// from in-game chat and the server accepted it. Target is the map for
+// !map; the Target fields name the player otherwise.
+type AdminCommandData struct {
+	AdminGUID    string    `json:"admin_guid"`
+	AdminName    string    `json:"admin_name"`
+	Command      string    `json:"command"`
+	Target       string    `json:"target"`
+	TargetGUID   string    `json:"target_guid,omitempty"`
+	TargetClient int       `json:"target_client"`
+	At           time.Time `json:"at"`
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "time"
+
+// DuelLadderDecay is how long a duel ladder player can go without a
+// duel before slipping a rung below someone who has played more
+// recently. Each further DuelLadderDecay idle costs another.
+const DuelLadderDecay = 14 * 24 * time.Hour
+
+// DuelLadderEntry is one rung of the duel ladder. Position 1 is the
+// top.
+type DuelLadderEntry struct {
+	Position   int       `json:"position"`
+	Player     Player    `json:"player"`
+	Wins       int       `json:"wins"`
+	Losses     int       `json:"losses"`
+	LastDuelAt time.Time `json:"last_duel_at"`
+}
+
+// DuelChallenge is one 1v1 match as the ladder saw it. WinnerFrom and
+// LoserFrom are the positions going in (0 for a newcomer); WinnerTo is
+// where the winner ended up. An upset, where the lower player won,
+// moves the winner into the loser's place.
+type DuelChallenge struct {
+	MatchID     int64     `json:"match_id"`
+	PlayedAt    time.Time `json:"played_at"`
+	MapName     string    `json:"map_name"`
+	Winner      Player    `json:"winner"`
+	Loser       Player    `json:"loser"`
+	WinnerFrags int       `json:"winner_frags"`
+	LoserFrags  int       `json:"loser_frags"`
+	WinnerFrom  int       `json:"winner_from"`
+	LoserFrom   int       `json:"loser_from"`
+	WinnerTo    int       `json:"winner_to"`
+	Upset       bool      `json:"upset"`
+}
+
+// DuelLadderResponse is the /api/ladders/duel payload: the ladder as
+// of AsOf and its most recent challenges, newest first.
+type DuelLadderResponse struct {
+	AsOf       time.Time         `json:"as_of"`
+	DecayDays  int               `json:"decay_days"`
+	Entries    []DuelLadderEntry `json:"entries"`
+	Challenges []DuelChallenge   `json:"challenges"`
+}
+
+// DuelRecord is a player's 1v1 record over every duel the ladder
+// counts. Position is their ladder rung.
+type DuelRecord struct {
+	Player       Player    `json:"player"`
+	Position     int       `json:"position"`
+	Wins         int       `json:"wins"`
+	Losses       int       `json:"losses"`
+	WinRate      float64   `json:"win_rate"`
+	FragsFor     int       `json:"frags_for"`
+	FragsAgainst int       `json:"frags_against"`
+	LastDuelAt   time.Time `json:"last_duel_at"`
+}
+
+// DuelHeadToHead is one player's record against one opponent. Wins,
+// Losses and frags are from the player's side.
+type DuelHeadToHead struct {
+	Opponent     Player    `json:"opponent"`
+	Wins         int       `json:"wins"`
+	Losses       int       `json:"losses"`
+	FragsFor     int       `json:"frags_for"`
+	FragsAgainst int       `json:"frags_against"`
+	LastDuelAt   time.Time `json:"last_duel_at"`
+}
+
+// DuelListResponse is the /api/duels payload: counted duels, newest
+// first.
+type DuelListResponse struct {
+	AsOf  time.Time       `json:"as_of"`
+	Duels []DuelChallenge `json:"duels"`
+}
+
+// DuelRankingsResponse is the /api/duels/rankings payload: every
+// dueller's record, most wins first.
+type DuelRankingsResponse struct {
+	AsOf    time.Time    `json:"as_of"`
+	Entries []DuelRecord `json:"entries"`
+}
+
+// PlayerDuelsResponse is the /api/players/{id}/duels payload. Record
+// is nil if the player has no counted duels. Opponents is their
+// head-to-head table, most duels first; Recent their latest duels.
+type PlayerDuelsResponse struct {
+	AsOf      time.Time        `json:"as_of"`
+	Record    *DuelRecord      `json:"record"`
+	Opponents []DuelHeadToHead `json:"opponents"`
+	Recent    []DuelChallenge  `json:"recent"`
+}
@@ -54,27 +54,83 @@ func GameTypeFromInt(gt int) string {
 
 // MatchPlayerSummary represents a player's participation in a match
 type MatchPlayerSummary struct {
-	PlayerID     int64    `json:"player_id"`
-	Name         string   `json:"name"`
-	CleanName    string   `json:"clean_name"`
-	Frags        int      `json:"frags"`
-	Deaths       int      `json:"deaths"`
-	Completed    bool     `json:"completed"`
-	IsBot        bool     `json:"is_bot"`
-	IsVR         bool     `json:"is_vr"`
-	IsVerified   bool     `json:"is_verified"`
-	IsAdmin      bool     `json:"is_admin"`
-	Skill        *float64 `json:"skill,omitempty"`
-	Score        *int     `json:"score,omitempty"`
-	Team         *int     `json:"team,omitempty"`
-	Model        string   `json:"model,omitempty"`
-	Impressives  int      `json:"impressives,omitempty"`
-	Excellents   int      `json:"excellents,omitempty"`
-	Humiliations int      `json:"humiliations,omitempty"`
-	Defends      int      `json:"defends,omitempty"`
-	Victories    int      `json:"victories,omitempty"`
-	Captures     int      `json:"captures,omitempty"`
-	Assists      int      `json:"assists,omitempty"`
+	PlayerID        int64    `json:"player_id"`
+	Name            string   `json:"name"`
+	CleanName       string   `json:"clean_name"`
+	Frags           int      `json:"frags"`
+	Deaths          int      `json:"deaths"`
+	Completed       bool     `json:"completed"`
+	IsBot           bool     `json:"is_bot"`
+	IsVR            bool     `json:"is_vr"`
+	IsVerified      bool     `json:"is_verified"`
+	IsAdmin         bool     `json:"is_admin"`
+	Skill           *float64 `json:"skill,omitempty"`
+	Score           *int     `json:"score,omitempty"`
+	Team            *int     `json:"team,omitempty"`
+	Model           string   `json:"model,omitempty"`
+	Impressives     int      `json:"impressives,omitempty"`
+	Excellents      int      `json:"excellents,omitempty"`
+	Humiliations    int      `json:"humiliations,omitempty"`
+	Defends         int      `json:"defends,omitempty"`
+	Victories       int      `json:"victories,omitempty"`
+	Captures        int      `json:"captures,omitempty"`
+	Assists         int      `json:"assists,omitempty"`
+	SkullsScored    int      `json:"skulls_scored,omitempty"`    // Harvester
+	ObeliskDestroys int      `json:"obelisk_destroys,omitempty"` // Overload
+	Corrected       bool     `json:"corrected,omitempty"`        // an admin has adjusted these stats
+}
+
+// MatchStatCorrection is one admin change to one field of a player's
+// match stats. OldValue is what the field held just before; the
+// earliest correction of a field holds the collector's original value.
+type MatchStatCorrection struct {
+	ID          int64     `json:"id"`
+	MatchID     int64     `json:"match_id"`
+	PlayerID    int64     `json:"player_id"`
+	ClientID    int       `json:"client_id"`
+	Field       string    `json:"field"`
+	OldValue    *int64    `json:"old_value"`
+	NewValue    *int64    `json:"new_value"`
+	Reason      string    `json:"reason"`
+	CorrectedBy *int64    `json:"corrected_by,omitempty"`
+	CorrectedAt time.Time `json:"corrected_at"`
+}
+
+// Kinds of StatFlag: the ways a match performance can look too good.
+const (
+	StatFlagAccuracySpike   = "accuracy_spike"   // a weapon's accuracy far above the player's own
+	StatFlagAccuracyExtreme = "accuracy_extreme" // a hitscan weapon's accuracy beyond top human play
+	StatFlagKDSpike         = "kd_spike"         // K/D far above the player's own
+	StatFlagFragRate        = "frag_rate"        // frags per minute beyond top human play
+)
+
+// Verdicts an admin can give a StatFlag.
+const (
+	StatFlagCleared   = "cleared"
+	StatFlagConfirmed = "confirmed"
+)
+
+// StatFlag is a match performance the hub's anomaly analysis found
+// improbable, for an admin to review. Value is what the player did and
+// Baseline what they usually do; Baseline is nil for the kinds judged
+// against a fixed ceiling. Weapon is set for the accuracy kinds.
+// Verdict is empty until an admin has reviewed it.
+type StatFlag struct {
+	ID         int64      `json:"id"`
+	Kind       string     `json:"kind"`
+	PlayerID   int64      `json:"player_id"`
+	PlayerName string     `json:"player_name"`
+	MatchID    int64      `json:"match_id"`
+	MapName    string     `json:"map_name"`
+	StartedAt  time.Time  `json:"started_at"`
+	Weapon     string     `json:"weapon,omitempty"`
+	Value      float64    `json:"value"`
+	Baseline   *float64   `json:"baseline,omitempty"`
+	DetectedAt time.Time  `json:"detected_at"`
+	Verdict    string     `json:"verdict,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	ReviewedBy *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
 }
 
 // MatchSummary represents a match with server and player info.
@@ -93,13 +149,327 @@ type MatchSummary struct {
 	Source        string               `json:"source"`
 	MapName       string               `json:"map_name"`
 	DemoAvailable bool                 `json:"demo_available"`
-	GameType   string               `json:"game_type"`
-	StartedAt  time.Time            `json:"started_at"`
-	EndedAt    *time.Time           `json:"ended_at,omitempty"`
-	ExitReason string               `json:"exit_reason,omitempty"`
-	Players    []MatchPlayerSummary `json:"players"`
-	RedScore   *int                 `json:"red_score,omitempty"`
-	BlueScore  *int                 `json:"blue_score,omitempty"`
-	Movement   string               `json:"movement,omitempty"`
-	Gameplay   string               `json:"gameplay,omitempty"`
+	GameType      string               `json:"game_type"`
+	StartedAt     time.Time            `json:"started_at"`
+	EndedAt       *time.Time           `json:"ended_at,omitempty"`
+	ExitReason    string               `json:"exit_reason,omitempty"`
+	Abandoned     bool                 `json:"abandoned,omitempty"` // the humans left; off the leaderboards
+	Players       []MatchPlayerSummary `json:"players"`
+	RedScore      *int                 `json:"red_score,omitempty"`
+	BlueScore     *int                 `json:"blue_score,omitempty"`
+	Movement      string               `json:"movement,omitempty"`
+	Gameplay      string               `json:"gameplay,omitempty"`
+
+	// Overtime is set for a match that went past regulation, sudden
+	// death included, and OvertimeSeconds is for how long.
+	Overtime        bool `json:"overtime,omitempty"`
+	OvertimeSeconds *int `json:"overtime_seconds,omitempty"`
+
+	// Rollups for a single match's page; see AddRollups.
+	DurationSeconds *int        `json:"duration_seconds,omitempty"`
+	Teams           []MatchTeam `json:"teams,omitempty"`
+	WinningTeam     *int        `json:"winning_team,omitempty"`
+	WinnerIDs       []int64     `json:"winner_ids,omitempty"`
+
+	// Scoreboard is what players saw at intermission, for a single
+	// match that exited normally.
+	Scoreboard []ScoreboardLine `json:"scoreboard,omitempty"`
+
+	// Warmup is who fragged whom before the match, for a single match.
+	// It's kept apart from Players and never ranked.
+	Warmup []WarmupLine `json:"warmup,omitempty"`
+}
+
+// WarmupLine is one player's frags and deaths in a match's warmup.
+type WarmupLine struct {
+	PlayerID int64  `json:"player_id"`
+	Name     string `json:"name"`
+	Frags    int    `json:"frags"`
+	Deaths   int    `json:"deaths"`
+}
+
+// ScoreboardLine is one line of a match's final scoreboard, as the
+// server printed it. Name is the name the client had then, color
+// codes and all. PlayerID is nil if the client's GUID never resolved.
+type ScoreboardLine struct {
+	ClientID int    `json:"client_id"`
+	PlayerID *int64 `json:"player_id,omitempty"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	Ping     int    `json:"ping"`
+	Team     int    `json:"team"`
+}
+
+// FlagTimelineEvent is one flag event on a match's timeline. OffsetMs
+// is the time since the match started. PlayerID and Name are unset for
+// a flag that returned on its own.
+type FlagTimelineEvent struct {
+	At       time.Time `json:"at"`
+	OffsetMs int64     `json:"offset_ms"`
+	Event    string    `json:"event"`
+	Team     int       `json:"team"`
+	ClientID int       `json:"client_id"`
+	PlayerID *int64    `json:"player_id,omitempty"`
+	Name     string    `json:"name,omitempty"`
+}
+
+// FlagCarrier is how long one player carried the flag in a match.
+type FlagCarrier struct {
+	PlayerID int64  `json:"player_id"`
+	Name     string `json:"name"`
+	Team     *int   `json:"team,omitempty"`
+	CarryMs  int64  `json:"carry_ms"`
+	Captures int    `json:"captures"`
+}
+
+// FlagTimelineResponse is returned by GET /api/matches/{id}/flag-timeline.
+type FlagTimelineResponse struct {
+	MatchID  int64               `json:"match_id"`
+	Events   []FlagTimelineEvent `json:"events"`
+	Carriers []FlagCarrier       `json:"carriers"`
+}
+
+// Team numbers, as the game logs them.
+const (
+	TeamFree      = 0
+	TeamRed       = 1
+	TeamBlue      = 2
+	TeamSpectator = 3
+)
+
+// IsTeamGame reports whether gameType is played in red and blue teams.
+func IsTeamGame(gameType string) bool {
+	switch gameType {
+	case GameTypeTDM, GameTypeCTF, GameType1FCTF, GameTypeOverload, GameTypeHarvester:
+		return true
+	}
+	return false
+}
+
+// MatchTeam is one team's roster and totals in a match.
+type MatchTeam struct {
+	Team            int     `json:"team"`
+	Score           *int    `json:"score,omitempty"`
+	PlayerIDs       []int64 `json:"player_ids"`
+	Frags           int     `json:"frags"`
+	Deaths          int     `json:"deaths"`
+	Captures        int     `json:"captures"`
+	Assists         int     `json:"assists"`
+	Defends         int     `json:"defends"`
+	SkullsScored    int     `json:"skulls_scored"`
+	ObeliskDestroys int     `json:"obelisk_destroys"`
+}
+
+// AddRollups works out what the match page would otherwise compute
+// from the scoreboard: how long the match ran, each team's roster and
+// totals, and who won. StartedAt is when warmup ended, so the duration
+// leaves warmup out.
+//
+// A team game is won by the higher team score; there's no winner on a
+// tie or if neither team scored. Otherwise the winners are the players
+// the game credited with the victory, or failing that, the top scorers
+// among those who finished, unless that's a tied duel.
+func (m *MatchSummary) AddRollups() {
+	if m.EndedAt != nil {
+		d := int(m.EndedAt.Sub(m.StartedAt).Seconds())
+		m.DurationSeconds = &d
+	}
+
+	if IsTeamGame(m.GameType) {
+		m.Teams = []MatchTeam{
+			{Team: TeamRed, Score: m.RedScore, PlayerIDs: []int64{}},
+			{Team: TeamBlue, Score: m.BlueScore, PlayerIDs: []int64{}},
+		}
+		for _, p := range m.Players {
+			if p.Team == nil || (*p.Team != TeamRed && *p.Team != TeamBlue) {
+				continue
+			}
+			t := &m.Teams[*p.Team-1]
+			t.PlayerIDs = append(t.PlayerIDs, p.PlayerID)
+			t.Frags += p.Frags
+			t.Deaths += p.Deaths
+			t.Captures += p.Captures
+			t.Assists += p.Assists
+			t.Defends += p.Defends
+			t.SkullsScored += p.SkullsScored
+			t.ObeliskDestroys += p.ObeliskDestroys
+		}
+		if m.EndedAt != nil && m.RedScore != nil && m.BlueScore != nil &&
+			max(*m.RedScore, *m.BlueScore) > 0 && *m.RedScore != *m.BlueScore {
+			winner := TeamRed
+			if *m.BlueScore > *m.RedScore {
+				winner = TeamBlue
+			}
+			m.WinningTeam = &winner
+		}
+		return
+	}
+
+	if m.EndedAt == nil {
+		return
+	}
+	for _, p := range m.Players {
+		if p.Victories > 0 {
+			m.WinnerIDs = append(m.WinnerIDs, p.PlayerID)
+		}
+	}
+	if m.WinnerIDs != nil {
+		return
+	}
+	best := 0
+	var top []int64
+	for _, p := range m.Players {
+		if !p.Completed || p.isSpectator() {
+			continue
+		}
+		score := p.Frags
+		if p.Score != nil {
+			score = *p.Score
+		}
+		switch {
+		case score > best:
+			best, top = score, []int64{p.PlayerID}
+		case score == best && best > 0:
+			top = append(top, p.PlayerID)
+		}
+	}
+	if len(top) > 1 && m.GameType == GameType1v1 {
+		return
+	}
+	m.WinnerIDs = top
+}
+
+// isSpectator reports whether p only watched: on the spectator team,
+// without a frag or death.
+func (p MatchPlayerSummary) isSpectator() bool {
+	return p.Team != nil && *p.Team == TeamSpectator && p.Frags == 0 && p.Deaths == 0
+}
+
+// MatchBrief is a match without its scoreboard, for linking to it
+// from a feed. Returned by POST /api/matches/batch.
+type MatchBrief struct {
+	ID        int64      `json:"id"`
+	ServerID  int64      `json:"server_id"`
+	ServerKey string     `json:"server_key"`
+	MapName   string     `json:"map_name"`
+	GameType  string     `json:"game_type"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	RedScore  *int       `json:"red_score,omitempty"`
+	BlueScore *int       `json:"blue_score,omitempty"`
+}
+
+// MatchDemo is an admin-uploaded client demo (.dm_68) for a match.
+// URL is filled in by the API layer.
+type MatchDemo struct {
+	ID               int64      `json:"id"`
+	MatchID          int64      `json:"match_id"`
+	Filename         string     `json:"filename"`
+	SizeBytes        int64      `json:"size_bytes"`
+	UploadedBy       *int64     `json:"uploaded_by,omitempty"`
+	UploadedAt       time.Time  `json:"uploaded_at"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at,omitempty"`
+	URL              string     `json:"url,omitempty"`
+}
+
+// DemoListing is a demo with the match it records, for browsing demos
+// across matches. DurationSeconds is the match's length.
+type DemoListing struct {
+	MatchDemo
+	ServerID        int64      `json:"server_id"`
+	ServerKey       string     `json:"server_key"`
+	Source          string     `json:"source"`
+	MapName         string     `json:"map_name"`
+	GameType        string     `json:"game_type"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	PlayerCount     int        `json:"player_count"`
+}
+
+// ChatLine is a persisted chat message from a match. Kind is one of
+// ChatSay, ChatSayTeam, or ChatTell; the To* fields are set only for
+// tells.
+type ChatLine struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	PlayerID   *int64    `json:"player_id,omitempty"`
+	Name       string    `json:"name"`
+	Team       int       `json:"team,omitempty"`
+	ToPlayerID *int64    `json:"to_player_id,omitempty"`
+	ToName     string    `json:"to_name,omitempty"`
+	Message    string    `json:"message"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// ModerationIncident is a chat line that tripped a server's word
+// filter, with what was done about it.
+type ModerationIncident struct {
+	ID        int64     `json:"id"`
+	ServerID  int64     `json:"server_id"`
+	ServerKey string    `json:"server_key"`
+	MatchID   *int64    `json:"match_id,omitempty"`
+	PlayerID  *int64    `json:"player_id,omitempty"`
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+	Matched   string    `json:"matched"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VIPKick is someone kicked to free a reserved slot for a VIP.
+type VIPKick struct {
+	ID             int64     `json:"id"`
+	ServerID       int64     `json:"server_id"`
+	ServerKey      string    `json:"server_key"`
+	VIPPlayerID    *int64    `json:"vip_player_id,omitempty"`
+	VIPName        string    `json:"vip_name"`
+	KickedPlayerID *int64    `json:"kicked_player_id,omitempty"`
+	KickedName     string    `json:"kicked_name"`
+	KickedIsBot    bool      `json:"kicked_is_bot"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AdminCommand is a !kick, !mute, or !map an admin ran in game. Target
+// is the player's name, or the map; TargetPlayerID is set for a player
+// the hub knows.
+type AdminCommand struct {
+	ID             int64     `json:"id"`
+	ServerID       int64     `json:"server_id"`
+	ServerKey      string    `json:"server_key"`
+	AdminPlayerID  *int64    `json:"admin_player_id,omitempty"`
+	AdminName      string    `json:"admin_name"`
+	Command        string    `json:"command"`
+	Target         string    `json:"target"`
+	TargetPlayerID *int64    `json:"target_player_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Map veto step actions.
+const (
+	VetoBan     = "ban"
+	VetoPick    = "pick"
+	VetoDecider = "decider"
+)
+
+// MapVeto is a referee-recorded pick/ban sequence for a competitive
+// match. MatchID is nil until the veto is linked to the tracked match.
+type MapVeto struct {
+	ID        int64         `json:"id"`
+	Title     string        `json:"title"`
+	TeamA     string        `json:"team_a"`
+	TeamB     string        `json:"team_b"`
+	MatchID   *int64        `json:"match_id,omitempty"`
+	CreatedBy *int64        `json:"created_by,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Steps     []MapVetoStep `json:"steps"`
+}
+
+// MapVetoStep is one pick or ban. Side is "a" or "b" for the team that
+// made the call, empty for the decider (the map left over).
+type MapVetoStep struct {
+	Seq     int    `json:"seq"`
+	Side    string `json:"side"`
+	Action  string `json:"action"`
+	MapName string `json:"map_name"`
 }
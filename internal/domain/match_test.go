@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAddRollupsTeamGame(t *testing.T) {
+	start := time.Date(2026, 10, 1, 20, 0, 0, 0, time.UTC)
+	end := start.Add(10*time.Minute + 30*time.Second)
+	red, blue, spec := TeamRed, TeamBlue, TeamSpectator
+	redScore, blueScore := 3, 1
+	m := MatchSummary{
+		GameType: GameTypeCTF, StartedAt: start, EndedAt: &end,
+		RedScore: &redScore, BlueScore: &blueScore,
+		Players: []MatchPlayerSummary{
+			{PlayerID: 1, Team: &red, Frags: 20, Deaths: 5, Captures: 2, Assists: 1},
+			{PlayerID: 2, Team: &red, Frags: 10, Deaths: 8, Captures: 1, Defends: 4},
+			{PlayerID: 3, Team: &blue, Frags: 12, Deaths: 25, Captures: 1},
+			{PlayerID: 4, Team: &spec},
+		},
+	}
+	m.AddRollups()
+
+	if m.DurationSeconds == nil || *m.DurationSeconds != 630 {
+		t.Errorf("duration = %v, want 630", m.DurationSeconds)
+	}
+	if len(m.Teams) != 2 {
+		t.Fatalf("teams = %+v", m.Teams)
+	}
+	r := m.Teams[0]
+	if r.Team != TeamRed || *r.Score != 3 || !slices.Equal(r.PlayerIDs, []int64{1, 2}) ||
+		r.Frags != 30 || r.Deaths != 13 || r.Captures != 3 || r.Assists != 1 || r.Defends != 4 {
+		t.Errorf("red = %+v", r)
+	}
+	if b := m.Teams[1]; b.Team != TeamBlue || !slices.Equal(b.PlayerIDs, []int64{3}) || b.Frags != 12 {
+		t.Errorf("blue = %+v", b)
+	}
+	if m.WinningTeam == nil || *m.WinningTeam != TeamRed {
+		t.Errorf("winning team = %v, want red", m.WinningTeam)
+	}
+	if m.WinnerIDs != nil {
+		t.Errorf("winner ids = %v in a team game", m.WinnerIDs)
+	}
+
+	// A tie, or a match still being played, has no winner.
+	blueScore = 3
+	m.WinningTeam = nil
+	m.AddRollups()
+	if m.WinningTeam != nil {
+		t.Errorf("tied match won by %d", *m.WinningTeam)
+	}
+	blueScore = 1
+	m.EndedAt, m.DurationSeconds, m.WinningTeam = nil, nil, nil
+	m.AddRollups()
+	if m.WinningTeam != nil || m.DurationSeconds != nil {
+		t.Errorf("live match: winner %v, duration %v", m.WinningTeam, m.DurationSeconds)
+	}
+}
+
+func TestAddRollupsWinners(t *testing.T) {
+	end := time.Now()
+	score := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		gameType string
+		players  []MatchPlayerSummary
+		want     []int64
+	}{
+		{"victory", GameTypeFFA, []MatchPlayerSummary{
+			{PlayerID: 1, Completed: true, Score: score(30)},
+			{PlayerID: 2, Completed: true, Score: score(20), Victories: 1},
+		}, []int64{2}},
+		{"top score", GameTypeFFA, []MatchPlayerSummary{
+			{PlayerID: 1, Completed: true, Score: score(30)},
+			{PlayerID: 2, Completed: false, Score: score(40)},
+			{PlayerID: 3, Completed: true, Frags: 20},
+		}, []int64{1}},
+		{"shared", GameTypeFFA, []MatchPlayerSummary{
+			{PlayerID: 1, Completed: true, Score: score(30)},
+			{PlayerID: 2, Completed: true, Score: score(30)},
+		}, []int64{1, 2}},
+		{"tied duel", GameType1v1, []MatchPlayerSummary{
+			{PlayerID: 1, Completed: true, Score: score(5)},
+			{PlayerID: 2, Completed: true, Score: score(5)},
+		}, nil},
+		{"no contest", GameTypeFFA, []MatchPlayerSummary{
+			{PlayerID: 1, Completed: true},
+			{PlayerID: 2, Completed: true},
+		}, nil},
+	}
+	for _, tt := range tests {
+		m := MatchSummary{GameType: tt.gameType, EndedAt: &end, Players: tt.players}
+		m.AddRollups()
+		if !slices.Equal(m.WinnerIDs, tt.want) {
+			t.Errorf("%s: winners = %v, want %v", tt.name, m.WinnerIDs, tt.want)
+		}
+		if m.Teams != nil || m.WinningTeam != nil {
+			t.Errorf("%s: team rollups in a %s match", tt.name, tt.gameType)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"regexp"
 	"time"
 )
@@ -15,11 +16,15 @@ type Player struct {
 	TotalPlaytimeSeconds int64        `json:"total_playtime_seconds"`
 	IsBot                bool         `json:"is_bot"`
 	IsVR                 bool         `json:"is_vr"`
-	Model                string       `json:"model,omitempty"`  // most recent model used
-	Skill                float64      `json:"skill,omitempty"`  // bot skill level (1-5), 0 if human
+	Model                string       `json:"model,omitempty"` // most recent model used
+	Skill                float64      `json:"skill,omitempty"` // bot skill level (1-5), 0 if human
 	IsVerified           bool         `json:"is_verified"`
 	IsAdmin              bool         `json:"is_admin"`
-	GUIDs                []PlayerGUID `json:"guids,omitempty"`  // populated when fetching with details
+	LeaderboardExcluded  bool         `json:"leaderboard_excluded,omitempty"` // admin flag; see LeaderboardExclusion
+	IsVIP                bool         `json:"is_vip,omitempty"`               // linked account flagged for reserved slots
+	Profile              *Profile     `json:"profile,omitempty"`              // set by the linked account's owner
+	SeasonWins           []SeasonWin  `json:"season_wins,omitempty"`          // populated when fetching with details
+	GUIDs                []PlayerGUID `json:"guids,omitempty"`                // populated when fetching with details
 }
 
 // PlayerGUID represents a single GUID belonging to a player
@@ -46,6 +51,9 @@ type Session struct {
 	IPAddress       string     `json:"ip_address,omitempty"`
 	ClientEngine    string     `json:"client_engine,omitempty"`
 	ClientVersion   string     `json:"client_version,omitempty"`
+	CountryCode     string     `json:"country_code,omitempty"`
+	Country         string     `json:"country,omitempty"`
+	City            string     `json:"city,omitempty"`
 }
 
 // PlayerSession represents a session for display (includes server name)
@@ -60,6 +68,9 @@ type PlayerSession struct {
 	IPAddress       string     `json:"ip_address,omitempty"`
 	ClientEngine    string     `json:"client_engine,omitempty"`
 	ClientVersion   string     `json:"client_version,omitempty"`
+	CountryCode     string     `json:"country_code,omitempty"`
+	Country         string     `json:"country,omitempty"`
+	City            string     `json:"city,omitempty"`
 }
 
 // AdminSession is a session row for the admin sessions view, with player identity included.
@@ -77,6 +88,9 @@ type AdminSession struct {
 	IPAddress       string     `json:"ip_address,omitempty"`
 	ClientEngine    string     `json:"client_engine,omitempty"`
 	ClientVersion   string     `json:"client_version,omitempty"`
+	CountryCode     string     `json:"country_code,omitempty"`
+	Country         string     `json:"country,omitempty"`
+	City            string     `json:"city,omitempty"`
 }
 
 // PlayerStats holds aggregated stats for a player (for leaderboards)
@@ -92,33 +106,176 @@ type PlayerStats struct {
 
 // LeaderboardEntry represents a player's position on a leaderboard
 type LeaderboardEntry struct {
-	Rank         int     `json:"rank"`
-	Player       Player  `json:"player"`
-	TotalFrags   int64   `json:"total_frags"`
-	TotalDeaths  int64   `json:"total_deaths"`
+	Rank               int     `json:"rank"`
+	Player             Player  `json:"player"`
+	TotalFrags         int64   `json:"total_frags"`
+	TotalDeaths        int64   `json:"total_deaths"`
 	TotalMatches       int64   `json:"total_matches"`
 	CompletedMatches   int64   `json:"completed_matches"`
 	UncompletedMatches int64   `json:"uncompleted_matches"`
 	KDRatio            float64 `json:"kd_ratio"`
-	Captures     int64   `json:"captures"`
-	FlagReturns  int64   `json:"flag_returns"`
-	Assists      int64   `json:"assists"`
-	Impressives  int64   `json:"impressives"`
-	Excellents   int64   `json:"excellents"`
-	Humiliations int64   `json:"humiliations"`
-	Defends      int64   `json:"defends"`
-	Victories    int64   `json:"victories"`
+	Captures           int64   `json:"captures"`
+	FlagReturns        int64   `json:"flag_returns"`
+	Assists            int64   `json:"assists"`
+	Impressives        int64   `json:"impressives"`
+	Excellents         int64   `json:"excellents"`
+	Humiliations       int64   `json:"humiliations"`
+	Defends            int64   `json:"defends"`
+	Victories          int64   `json:"victories"`
+	BestSpree          int64   `json:"best_spree"`
+	SkullsScored       int64   `json:"skulls_scored"`    // Harvester
+	ObeliskDestroys    int64   `json:"obelisk_destroys"` // Overload
+	PlaytimeSeconds    int64   `json:"playtime_seconds"` // time on the servers in the period
+	// Shots, Hits and Accuracy (Hits over Shots, 0-1) are only set on
+	// the accuracy leaderboard.
+	Shots    int64   `json:"shots,omitempty"`
+	Hits     int64   `json:"hits,omitempty"`
+	Accuracy float64 `json:"accuracy,omitempty"`
 }
 
 // LeaderboardResponse is the API response for leaderboard data
 type LeaderboardResponse struct {
 	Category    string             `json:"category"`
+	Weapon      string             `json:"weapon,omitempty"`
 	Period      string             `json:"period"`
 	PeriodStart *time.Time         `json:"period_start,omitempty"`
 	PeriodEnd   *time.Time         `json:"period_end,omitempty"`
+	Season      *Season            `json:"season,omitempty"` // when scoped to a season
 	Entries     []LeaderboardEntry `json:"entries"`
 }
 
+// CountryCount is one row of the /api/stats/countries breakdown.
+type CountryCount struct {
+	CountryCode string `json:"country_code"`
+	Country     string `json:"country"`
+	Players     int64  `json:"players"`
+	Sessions    int64  `json:"sessions"`
+}
+
+// CountryBreakdownResponse is the API response for /api/stats/countries
+type CountryBreakdownResponse struct {
+	Period      string         `json:"period"`
+	PeriodStart *time.Time     `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time     `json:"period_end,omitempty"`
+	Countries   []CountryCount `json:"countries"`
+}
+
+// SessionStatsResponse is the API response for /api/stats/sessions:
+// humans' finished sessions that began in the period. Retention looks
+// at players whose first session in the period began at least a week
+// before its end: RetentionRate is the share of them who came back on
+// a later day within 7 days, nil if there are none yet.
+type SessionStatsResponse struct {
+	Period            string         `json:"period"`
+	PeriodStart       *time.Time     `json:"period_start,omitempty"`
+	PeriodEnd         *time.Time     `json:"period_end,omitempty"`
+	Sessions          int64          `json:"sessions"`
+	UniquePlayers     int64          `json:"unique_players"`
+	TotalSeconds      int64          `json:"total_seconds"`
+	AverageSeconds    float64        `json:"average_seconds"`
+	Daily             []DailyPlayers `json:"daily"`
+	RetentionCohort   int64          `json:"retention_cohort"`
+	RetentionReturned int64          `json:"retention_returned"`
+	RetentionRate     *float64       `json:"retention_rate,omitempty"`
+}
+
+// BotStats is how one bot character did in the finished matches it
+// completed. Skill is the average skill level it played at.
+type BotStats struct {
+	PlayerID  int64   `json:"player_id"`
+	Name      string  `json:"name"`
+	CleanName string  `json:"clean_name"`
+	Matches   int64   `json:"matches"`
+	Victories int64   `json:"victories"`
+	WinRate   float64 `json:"win_rate"`
+	AvgFrags  float64 `json:"avg_frags"`
+	KDRatio   float64 `json:"kd_ratio"`
+	Skill     float64 `json:"skill"`
+}
+
+// BotSkillStats is how humans did in the finished matches they
+// completed against bots of one skill level, rounded to a whole
+// number. A match with bots at several levels counts under each.
+type BotSkillStats struct {
+	Skill          int     `json:"skill"`
+	Matches        int64   `json:"matches"`
+	HumanMatches   int64   `json:"human_matches"`
+	HumanVictories int64   `json:"human_victories"`
+	HumanWinRate   float64 `json:"human_win_rate"`
+	HumanAvgFrags  float64 `json:"human_avg_frags"`
+	HumanKDRatio   float64 `json:"human_kd_ratio"`
+}
+
+// BotStatsResponse is the API response for /api/stats/bots.
+type BotStatsResponse struct {
+	Period      string          `json:"period"`
+	PeriodStart *time.Time      `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time      `json:"period_end,omitempty"`
+	GameType    string          `json:"game_type,omitempty"`
+	Bots        []BotStats      `json:"bots"`
+	Skills      []BotSkillStats `json:"skills"`
+}
+
+// DailyPlayers is one UTC day of SessionStatsResponse.Daily.
+type DailyPlayers struct {
+	Day      string `json:"day"` // YYYY-MM-DD
+	Players  int64  `json:"players"`
+	Sessions int64  `json:"sessions"`
+}
+
+// PlayerLocation is where a player's most recent geolocated session
+// came from. Admin-only: it's derived from the player's IP.
+type PlayerLocation struct {
+	CountryCode string    `json:"country_code"`
+	Country     string    `json:"country"`
+	City        string    `json:"city,omitempty"`
+	SeenAt      time.Time `json:"seen_at"`
+	ServerID    int64     `json:"server_id"`
+}
+
+// ReturningPlayerThreshold is how long a player has to be gone before
+// their next join counts as a comeback (greeted as such, announced to
+// Discord, listed in the digest).
+const ReturningPlayerThreshold = 30 * 24 * time.Hour
+
+// ReturningPlayer is one comeback: the first session a player opened
+// after a gap of at least ReturningPlayerThreshold.
+type ReturningPlayer struct {
+	Player     Player    `json:"player"`
+	ReturnedAt time.Time `json:"returned_at"`
+	LastSeenAt time.Time `json:"last_seen_at"` // end of the session before the gap
+	DaysAway   int       `json:"days_away"`
+}
+
+// ReturningPlayersResponse is the API response for /api/stats/returning
+type ReturningPlayersResponse struct {
+	Period      string            `json:"period"`
+	PeriodStart *time.Time        `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time        `json:"period_end,omitempty"`
+	Players     []ReturningPlayer `json:"players"`
+}
+
+// HumanizeDaysAway renders an absence as the coarsest unit that still
+// reads naturally: "45 days", "3 months", "2 years". Shared by the
+// in-game greeting and the Discord announcement so both say the same
+// thing.
+func HumanizeDaysAway(days int) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case days >= 365:
+		return plural(days/365, "year")
+	case days >= 60:
+		return plural(days/30, "month")
+	default:
+		return plural(days, "day")
+	}
+}
+
 // PlayerName represents a historical name used by a player GUID
 type PlayerName struct {
 	Name      string    `json:"name"`       // original color-coded name
@@ -143,6 +300,87 @@ type AggregatedStats struct {
 	Humiliations       int64   `json:"humiliations"`
 	Defends            int64   `json:"defends"`
 	Victories          int64   `json:"victories"`
+	BestSpree          int64   `json:"best_spree"`         // most kills without dying in one match
+	LongestWinStreak   int64   `json:"longest_win_streak"` // consecutive completed matches won
+	SkullsScored       int64   `json:"skulls_scored"`      // skulls brought to the enemy obelisk (Harvester)
+	ObeliskDestroys    int64   `json:"obelisk_destroys"`   // enemy obelisks destroyed (Overload)
+}
+
+// LeaderboardExclusion is a player an admin has kept off the
+// leaderboards. Their matches still show everywhere else.
+type LeaderboardExclusion struct {
+	PlayerID  int64  `json:"player_id"`
+	Name      string `json:"name"`
+	CleanName string `json:"clean_name"`
+	Reason    string `json:"reason"`
+}
+
+// Profile is what a linked player's owner shows about themselves.
+// PreferredModel is a model string like "sarge/krusade" whose
+// extracted portrait stands in when there's no AvatarURL.
+type Profile struct {
+	AvatarURL      string `json:"avatar_url,omitempty"`
+	PreferredModel string `json:"preferred_model,omitempty"`
+	Bio            string `json:"bio,omitempty"`
+	CountryCode    string `json:"country_code,omitempty"`
+}
+
+// IsZero reports whether nothing has been set.
+func (p Profile) IsZero() bool {
+	return p == Profile{}
+}
+
+// VIP is a linked player an admin has flagged for reserved slots.
+type VIP struct {
+	PlayerID  int64  `json:"player_id"`
+	Name      string `json:"name"`
+	CleanName string `json:"clean_name"`
+	Username  string `json:"username"`
+}
+
+// TrendPoint is one day's or week's totals for a player, from the
+// stats_snapshots table. KDRatio covers just this bucket;
+// CumulativeKDRatio covers everything from the start of the series.
+type TrendPoint struct {
+	Start             string  `json:"start"` // YYYY-MM-DD; Monday for weekly buckets
+	Matches           int64   `json:"matches"`
+	CompletedMatches  int64   `json:"completed_matches"`
+	Frags             int64   `json:"frags"`
+	Deaths            int64   `json:"deaths"`
+	Captures          int64   `json:"captures"`
+	Victories         int64   `json:"victories"`
+	KDRatio           float64 `json:"kd_ratio"`
+	CumulativeKDRatio float64 `json:"cumulative_kd_ratio"`
+}
+
+// PlayerTrendsResponse is the API response for /api/players/{id}/trends
+type PlayerTrendsResponse struct {
+	PlayerID int64        `json:"player_id"`
+	Interval string       `json:"interval"` // "day" or "week"
+	Points   []TrendPoint `json:"points"`
+}
+
+// PlayerMapStats is a player's record on one map. Matches and
+// Victories count completed matches; WinRate is Victories over
+// Matches (0-1). Frags and Deaths include matches they left early.
+type PlayerMapStats struct {
+	MapName    string    `json:"map_name"`
+	Matches    int64     `json:"matches"`
+	Victories  int64     `json:"victories"`
+	WinRate    float64   `json:"win_rate"`
+	Frags      int64     `json:"frags"`
+	Deaths     int64     `json:"deaths"`
+	KDRatio    float64   `json:"kd_ratio"`
+	LastPlayed time.Time `json:"last_played"`
+}
+
+// PlayerMapsResponse is the API response for /api/players/{id}/maps
+type PlayerMapsResponse struct {
+	PlayerID    int64            `json:"player_id"`
+	Period      string           `json:"period"`
+	PeriodStart *time.Time       `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time       `json:"period_end,omitempty"`
+	Maps        []PlayerMapStats `json:"maps"`
 }
 
 // PlayerStatsResponse is the API response for player stats with time filtering
@@ -153,6 +391,47 @@ type PlayerStatsResponse struct {
 	PeriodEnd   *time.Time      `json:"period_end,omitempty"`
 	Stats       AggregatedStats `json:"stats"`
 	Names       []PlayerName    `json:"names"`
+	// Weapons is the player's frags by weapon over the period, most
+	// used first. SignatureWeapon is the top one, once the player has
+	// enough weapon frags for it to mean something.
+	Weapons         []WeaponUsage `json:"weapons"`
+	SignatureWeapon string        `json:"signature_weapon,omitempty"`
+	// Accuracy is the player's shots and hits by weapon over the
+	// period, most fired first, from servers that log Weapon_Stats.
+	// OverallAccuracy is hits over shots for all of them, nil if the
+	// player has no shots logged.
+	Accuracy        []WeaponAccuracy `json:"accuracy"`
+	OverallAccuracy *float64         `json:"overall_accuracy,omitempty"`
+}
+
+// WeaponUsage is one weapon's share of a player's frags. Weapon is a
+// WeaponSlug; Share is Frags over all their weapon frags (0-1).
+type WeaponUsage struct {
+	Weapon string  `json:"weapon"`
+	Frags  int64   `json:"frags"`
+	Share  float64 `json:"share"`
+}
+
+// WeaponAccuracy is a player's shots and hits with one weapon. Weapon
+// is a WeaponSlug; Accuracy is Hits over Shots (0-1).
+type WeaponAccuracy struct {
+	Weapon   string  `json:"weapon"`
+	Shots    int64   `json:"shots"`
+	Hits     int64   `json:"hits"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// PlayerBrief is just enough of a player to render their name and
+// portrait in a feed. Returned by POST /api/players/batch.
+type PlayerBrief struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	CleanName  string `json:"clean_name"`
+	Model      string `json:"model,omitempty"`
+	IsBot      bool   `json:"is_bot"`
+	IsVR       bool   `json:"is_vr"`
+	IsVerified bool   `json:"is_verified"`
+	IsAdmin    bool   `json:"is_admin"`
 }
 
 // PlayerProfile is used for search results and basic player info
@@ -173,3 +452,137 @@ var q3ColorCodeRegex = regexp.MustCompile(`\^[0-9]`)
 func CleanQ3Name(name string) string {
 	return q3ColorCodeRegex.ReplaceAllString(name, "")
 }
+
+// Achievement IDs. These are stored in player_achievements, so never
+// rename one; retire it instead.
+const (
+	AchievementThousandFrags = "frags_1000"
+	AchievementWinStreak     = "win_streak_10"
+	AchievementCenturion     = "captures_100"
+	AchievementMarathon      = "marathon"
+)
+
+// Achievement describes one badge a player can earn.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Achievements lists every badge in display order.
+var Achievements = []Achievement{
+	{ID: AchievementThousandFrags, Name: "Thousand Frags", Description: "Reach 1,000 career frags"},
+	{ID: AchievementWinStreak, Name: "Unstoppable", Description: "Win 10 matches in a row"},
+	{ID: AchievementCenturion, Name: "Flag Runner", Description: "Capture 100 flags"},
+	{ID: AchievementMarathon, Name: "Marathon", Description: "Play 3 hours in a single session"},
+}
+
+// AchievementByID looks up a badge definition.
+func AchievementByID(id string) (Achievement, bool) {
+	for _, a := range Achievements {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// PlayerAchievement is a badge a player has earned.
+type PlayerAchievement struct {
+	Achievement
+	MatchID  *int64    `json:"match_id,omitempty"` // the match that earned it
+	EarnedAt time.Time `json:"earned_at"`
+}
+
+// AchievementProgress is the career tally achievement rules are
+// evaluated against at the end of a match.
+type AchievementProgress struct {
+	TotalFrags    int
+	TotalCaptures int
+	WinStreak     int           // consecutive completed wins, newest first
+	SessionLength time.Duration // current open session on the match's server
+}
+
+// Record categories. Each is the best single-match performance on one
+// map and gametype. These are stored in records, so never rename one.
+const (
+	RecordFrags      = "frags"
+	RecordScore      = "score"
+	RecordCaptures   = "captures"
+	RecordBestSpree  = "best_spree"
+	RecordFastestCap = "fastest_cap" // milliseconds from flag pickup to capture
+)
+
+// RecordCategory describes one kind of record.
+type RecordCategory struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	LowerIsBetter bool   `json:"lower_is_better,omitempty"`
+}
+
+// RecordCategories lists every record category in display order.
+var RecordCategories = []RecordCategory{
+	{ID: RecordFrags, Name: "Most frags"},
+	{ID: RecordScore, Name: "Highest score"},
+	{ID: RecordCaptures, Name: "Most captures"},
+	{ID: RecordBestSpree, Name: "Longest spree"},
+	{ID: RecordFastestCap, Name: "Fastest capture", LowerIsBetter: true},
+}
+
+// RecordCategoryByID looks up a record category.
+func RecordCategoryByID(id string) (RecordCategory, bool) {
+	for _, c := range RecordCategories {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return RecordCategory{}, false
+}
+
+// Record is the current holder of one category on a map and gametype.
+type Record struct {
+	MapName  string    `json:"map_name"`
+	GameType string    `json:"game_type"`
+	Category string    `json:"category"`
+	Value    int64     `json:"value"`
+	Player   Player    `json:"player"`
+	MatchID  int64     `json:"match_id"`
+	SetAt    time.Time `json:"set_at"`
+}
+
+// BrokenRecord is a record a match just set, with the one it replaced.
+type BrokenRecord struct {
+	Record
+	Previous Record
+}
+
+// Reasons a MergeSuggestion gives for thinking two players are one.
+const (
+	MergeReasonSharedIPRange       = "shared_ip_range"      // played from the same /24 (IPv4) or /48 (IPv6)
+	MergeReasonSharedName          = "shared_name"          // used the same name
+	MergeReasonAlternatingSessions = "alternating_sessions" // never on at once, one joining soon after the other left
+)
+
+// MergeSuggestion is a pair of players the hub's analysis thinks may
+// be the same person. Score is 0-100; higher is likelier.
+type MergeSuggestion struct {
+	ID         int64     `json:"id"`
+	Player     Player    `json:"player"`
+	Other      Player    `json:"other"`
+	Score      int       `json:"score"`
+	Reasons    []string  `json:"reasons"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// PlayerMerge is one recorded player merge. The source player was
+// folded into the target; UndoneAt is set once it's been reversed.
+type PlayerMerge struct {
+	ID             int64      `json:"id"`
+	TargetPlayerID int64      `json:"target_player_id"`
+	TargetName     string     `json:"target_name"`
+	SourcePlayerID int64      `json:"source_player_id"`
+	SourceName     string     `json:"source_name"`
+	GUIDCount      int        `json:"guid_count"`
+	MergedAt       time.Time  `json:"merged_at"`
+	UndoneAt       *time.Time `json:"undone_at,omitempty"`
+}
@@ -34,4 +34,11 @@ type RegdServer struct {
 	Key                    string `json:"key"`
 	Address                string `json:"address"`
 	AdminDelegationEnabled bool   `json:"admin_delegation_enabled,omitempty"`
+	// QueryOnly mirrors Q3Server.QueryOnly: the collector reads no
+	// log for the server, so the hub polls it without waiting to see
+	// the handshake.
+	QueryOnly bool `json:"query_only,omitempty"`
+	// ClockOffsetMs is how far ahead of the collector's clock the
+	// server's is, as detected from its log.
+	ClockOffsetMs int64 `json:"clock_offset_ms,omitempty"`
 }
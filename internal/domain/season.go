@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Season is a stretch of play leaderboards can be scoped to. Category
+// is the leaderboard that decides the season: once EndsAt passes its
+// standings are frozen, FinalizedAt is set, and the player ranked
+// first wins the season.
+type Season struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	Category    string     `json:"category"`
+	FinalizedAt *time.Time `json:"finalized_at,omitempty"`
+	WinnerID    *int64     `json:"winner_id,omitempty"`
+	CreatedBy   *int64     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Active reports whether at falls within the season.
+func (s *Season) Active(at time.Time) bool {
+	return !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
+
+// SeasonWin is a badge for a season the player finished first in.
+type SeasonWin struct {
+	SeasonID int64     `json:"season_id"`
+	Name     string    `json:"name"`
+	Category string    `json:"category"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// SeasonResponse is returned by GET /api/seasons/{id}: the season and
+// its standings, frozen once it is finalized and live until then.
+type SeasonResponse struct {
+	Season    Season             `json:"season"`
+	Standings []LeaderboardEntry `json:"standings"`
+}
@@ -9,11 +9,11 @@ import "time"
 // source deactivation cascade); the row sticks around for historical
 // matches and the UI dims it.
 type Server struct {
-	ID                int64      `json:"id"`
-	Source            string     `json:"source"`
-	Key               string     `json:"key"`
-	Address           string     `json:"address"`
-	Active            bool       `json:"active"`
+	ID      int64  `json:"id"`
+	Source  string `json:"source"`
+	Key     string `json:"key"`
+	Address string `json:"address"`
+	Active  bool   `json:"active"`
 	// HandshakeRequired latches to true the first time the hub sees a
 	// match_start with handshake_required=true on this server, and
 	// flips back to false on a match_start with handshake_required=false.
@@ -27,30 +27,58 @@ type Server struct {
 	// "hide" rules in the live-cards endpoint — older than the hide
 	// threshold means the collector isn't checking in and the data
 	// behind the live card is no longer trustworthy.
-	LastHeartbeatAt   *time.Time `json:"last_heartbeat_at,omitempty"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
 	// AdminDelegationEnabled is the operator's per-server opt-in for
 	// hub-admin RCON. Refreshed on every collector heartbeat. The
 	// collector stays authoritative — this column drives UI gating
 	// only.
-	AdminDelegationEnabled bool   `json:"admin_delegation_enabled"`
-	CreatedAt         time.Time  `json:"created_at"`
+	AdminDelegationEnabled bool `json:"admin_delegation_enabled"`
+	// CommandsDisabled is a hub admin's switch for in-game chat
+	// commands. The collector picks it up when it registers the server
+	// and ignores every command while it's set.
+	CommandsDisabled bool `json:"commands_disabled"`
+	// QueryOnly servers have no collector reading their log: the hub
+	// polls them for live status and population, and records no
+	// matches or players.
+	QueryOnly bool `json:"query_only"`
+	// ClockOffsetMs is how far ahead of its collector's clock the game
+	// server's clock is. Timestamps from the server are already
+	// corrected by it; it's here so admins can see the drift.
+	ClockOffsetMs int64     `json:"clock_offset_ms"`
+	CreatedAt     time.Time `json:"created_at"`
+	// LogCursor is how far into the server's log the hub has flushed
+	// match stats. Only RegisterServer fills it in, for the collector's
+	// replay.
+	LogCursor *LogCursor `json:"log_cursor,omitempty"`
+}
+
+// ServerIncident is a game server crash, kept for post-mortems.
+// Reason is a Crash* constant.
+type ServerIncident struct {
+	ID         int64     `json:"id"`
+	ServerID   int64     `json:"server_id"`
+	Reason     string    `json:"reason"`
+	Detail     string    `json:"detail,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+	LogLines   []string  `json:"log_lines"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // ServerStatus represents the current state of a server from UDP query
 type ServerStatus struct {
-	ServerID        int64             `json:"server_id"`
-	Source          string            `json:"source"`
-	Key             string            `json:"key"`
-	Address         string            `json:"address"`
-	Map             string            `json:"map"`
-	GameType        string            `json:"game_type"`
-	GameTimeMs      int               `json:"game_time_ms"`
-	MaxClients      int               `json:"max_clients"`
-	Players         []PlayerStatus    `json:"players"`
-	HumanCount      int               `json:"human_count"`
-	BotCount        int               `json:"bot_count"`
-	Online          bool              `json:"online"`
-	LastUpdated     time.Time         `json:"last_updated"`
+	ServerID    int64          `json:"server_id"`
+	Source      string         `json:"source"`
+	Key         string         `json:"key"`
+	Address     string         `json:"address"`
+	Map         string         `json:"map"`
+	GameType    string         `json:"game_type"`
+	GameTimeMs  int            `json:"game_time_ms"`
+	MaxClients  int            `json:"max_clients"`
+	Players     []PlayerStatus `json:"players"`
+	HumanCount  int            `json:"human_count"`
+	BotCount    int            `json:"bot_count"`
+	Online      bool           `json:"online"`
+	LastUpdated time.Time      `json:"last_updated"`
 	// LastSeenAt is the timestamp of the most recent successful UDP
 	// query. Held even after the server goes offline so the API can
 	// compute "offline duration" from it.
@@ -58,8 +86,64 @@ type ServerStatus struct {
 	ServerVars      map[string]string `json:"server_vars,omitempty"`
 	TeamScores      *TeamScores       `json:"team_scores,omitempty"`
 	FlagStatus      *FlagStatus       `json:"flag_status,omitempty"`
-	MatchState      string            `json:"match_state,omitempty"`       // "waiting", "warmup", "active", "overtime", "intermission"
+	MatchState      string            `json:"match_state,omitempty"`      // "waiting", "warmup", "active", "overtime", "intermission"
 	WarmupRemaining int               `json:"warmup_remaining,omitempty"` // milliseconds remaining in warmup
+
+	// MatchClockMs is how far into the match the server is, and
+	// ClockSource says where it came from (ClockServer or ClockLog).
+	// TimeRemainingMs counts down to the timelimit cvar; nil when
+	// there's no timelimit or no clock. All are left empty outside
+	// active play.
+	MatchClockMs    int    `json:"match_clock_ms,omitempty"`
+	TimeRemainingMs *int   `json:"time_remaining_ms,omitempty"`
+	ClockSource     string `json:"clock_source,omitempty"`
+
+	// QueryOnly is set for servers polled without a collector reading
+	// their log: players aren't identified and no matches are kept.
+	QueryOnly bool `json:"query_only,omitempty"`
+}
+
+// Match clock sources. ClockServer is the engine's own level time from
+// the status cvars, corrected for half the query's round trip.
+// ClockLog is wall time since the match_start log line, used when the
+// cvars aren't there.
+const (
+	ClockServer = "server"
+	ClockLog    = "log"
+)
+
+// PublicServerVars are the cvars anyone may see in a server's status.
+// Everything else the server reports is admin only: mods put
+// passwords, admin lists, and paths in serverinfo, and a new one can
+// turn up any time, so this is an allowlist.
+var PublicServerVars = []string{
+	"sv_hostname", "mapname", "g_gametype", "gamename", "version", "engine", "protocol",
+	"sv_maxclients", "g_needpass", "fraglimit", "timelimit", "capturelimit",
+	"g_movement", "g_gameplay", "g_redteam", "g_blueteam",
+}
+
+// Public returns a copy of s safe to show without logging in:
+// ServerVars is cut to PublicServerVars and players' GUIDs are
+// dropped. s is left alone.
+func (s ServerStatus) Public() ServerStatus {
+	if s.ServerVars != nil {
+		vars := make(map[string]string, len(PublicServerVars))
+		for _, k := range PublicServerVars {
+			if v, ok := s.ServerVars[k]; ok {
+				vars[k] = v
+			}
+		}
+		s.ServerVars = vars
+	}
+	if s.Players != nil {
+		players := make([]PlayerStatus, len(s.Players))
+		for i, p := range s.Players {
+			p.GUID = ""
+			players[i] = p
+		}
+		s.Players = players
+	}
+	return s
 }
 
 // TeamScores represents team scores for team game modes
@@ -93,7 +177,7 @@ type PlayerStatus struct {
 	Ping         int       `json:"ping"`
 	IsBot        bool      `json:"is_bot"`
 	IsVR         bool      `json:"is_vr"`
-	Skill        float64   `json:"skill,omitempty"`        // bot skill level (1-5), 0 if human
+	Skill        float64   `json:"skill,omitempty"` // bot skill level (1-5), 0 if human
 	Team         int       `json:"team,omitempty"`
 	JoinedAt     time.Time `json:"joined_at,omitempty"`
 	Impressives  int       `json:"impressives,omitempty"`  // impressive awards this match
@@ -105,5 +189,126 @@ type PlayerStatus struct {
 	PlayerID     *int64    `json:"player_id,omitempty"`    // database player ID if known
 	IsVerified   bool      `json:"is_verified"`
 	IsAdmin      bool      `json:"is_admin"`
-	Model        string    `json:"model,omitempty"`        // player model (e.g., "sarge/krusade")
+	Model        string    `json:"model,omitempty"` // player model (e.g., "sarge/krusade")
+}
+
+// ServerPing is one server's ping samples from a player, or from
+// everyone. Pings are in milliseconds.
+type ServerPing struct {
+	ServerID   int64   `json:"server_id"`
+	ServerKey  string  `json:"server_key"`
+	Source     string  `json:"source"`
+	Samples    int64   `json:"samples"`
+	AvgPing    float64 `json:"avg_ping"`
+	MedianPing int     `json:"median_ping"`
+}
+
+// PingSpike is a ping sample well above the player's usual.
+type PingSpike struct {
+	SampledAt time.Time `json:"sampled_at"`
+	Ping      int       `json:"ping"`
+	ServerID  int64     `json:"server_id"`
+	ServerKey string    `json:"server_key"`
+}
+
+// PlayerPingResponse is the API response for /api/players/{id}/ping.
+type PlayerPingResponse struct {
+	PlayerID   int64        `json:"player_id"`
+	Period     string       `json:"period"`
+	Samples    int64        `json:"samples"`
+	AvgPing    float64      `json:"avg_ping"`
+	MedianPing int          `json:"median_ping"`
+	Servers    []ServerPing `json:"servers"`
+	Spikes     []PingSpike  `json:"spikes"` // newest first
+}
+
+// RegionPing is a server's ping samples from players in one country.
+// CountryCode is empty for sessions that weren't geolocated.
+type RegionPing struct {
+	CountryCode string  `json:"country_code"`
+	Country     string  `json:"country,omitempty"`
+	Players     int64   `json:"players"`
+	Samples     int64   `json:"samples"`
+	AvgPing     float64 `json:"avg_ping"`
+	MedianPing  int     `json:"median_ping"`
+}
+
+// ServerPingStats is a server's ping samples, overall and by region.
+type ServerPingStats struct {
+	ServerID   int64        `json:"server_id"`
+	ServerKey  string       `json:"server_key"`
+	Source     string       `json:"source"`
+	Samples    int64        `json:"samples"`
+	AvgPing    float64      `json:"avg_ping"`
+	MedianPing int          `json:"median_ping"`
+	Regions    []RegionPing `json:"regions"`
+}
+
+// ServerPingResponse is the API response for /api/stats/ping.
+type ServerPingResponse struct {
+	Period  string            `json:"period"`
+	Servers []ServerPingStats `json:"servers"`
+}
+
+// PopulationHour is a query-only server's population over one UTC
+// hour, from the poller's samples.
+type PopulationHour struct {
+	Hour       time.Time `json:"hour"`
+	AvgHumans  float64   `json:"avg_humans"`
+	PeakHumans int       `json:"peak_humans"`
+	AvgBots    float64   `json:"avg_bots"`
+	MaxClients int       `json:"max_clients"`
+}
+
+// PopulationResponse is the API response for
+// /api/servers/{id}/population.
+type PopulationResponse struct {
+	ServerID int64            `json:"server_id"`
+	Period   string           `json:"period"`
+	Hours    []PopulationHour `json:"hours"`
+}
+
+// BrowserServer is one server from the master-server browser. Tracked
+// is set when the hub already has a row with its address.
+type BrowserServer struct {
+	Address    string `json:"address"`
+	Hostname   string `json:"hostname"`
+	CleanName  string `json:"clean_name"`
+	Map        string `json:"map"`
+	GameType   string `json:"game_type"`
+	Humans     int    `json:"humans"`
+	Bots       int    `json:"bots"`
+	MaxClients int    `json:"max_clients"`
+	Tracked    bool   `json:"tracked"`
+}
+
+// ActivityHeatmap is average population by hour of the week. Hours is
+// indexed [weekday][hour] in the requested timezone, Sunday first, and
+// holds the average number of players online during that hour.
+type ActivityHeatmap struct {
+	ServerID  int64          `json:"server_id,omitempty"` // zero for all servers together
+	ServerKey string         `json:"server_key,omitempty"`
+	Source    string         `json:"source,omitempty"`
+	Hours     [7][24]float64 `json:"hours"`
+	Peak      *ActivityHour  `json:"peak,omitempty"` // nil if no one played
+}
+
+// ActivityHour is one hour of the week.
+type ActivityHour struct {
+	Weekday time.Weekday `json:"weekday"` // 0 is Sunday
+	Hour    int          `json:"hour"`
+	Players float64      `json:"players"`
+}
+
+// ActivityResponse is the API response for /api/stats/activity.
+// Overall adds every server together; Servers has one heatmap per
+// server anyone played on, busiest first.
+type ActivityResponse struct {
+	Period      string            `json:"period"`
+	PeriodStart *time.Time        `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time        `json:"period_end,omitempty"`
+	Timezone    string            `json:"timezone"`
+	HumansOnly  bool              `json:"humans_only"`
+	Overall     ActivityHeatmap   `json:"overall"`
+	Servers     []ActivityHeatmap `json:"servers"`
 }
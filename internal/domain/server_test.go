@@ -0,0 +1,35 @@
+package domain
+
+import "testing"
+
+func TestServerStatusPublic(t *testing.T) {
+	full := ServerStatus{
+		ServerVars: map[string]string{
+			"sv_hostname":  "Trinity FFA",
+			"timelimit":    "15",
+			"g_movement":   "1",
+			"rconpassword": "hunter2",
+			"g_adminpass":  "secret",
+		},
+		Players: []PlayerStatus{{ClientNum: 0, GUID: "ABCDEF", Name: "Sarge"}},
+	}
+
+	pub := full.Public()
+	want := map[string]string{"sv_hostname": "Trinity FFA", "timelimit": "15", "g_movement": "1"}
+	if len(pub.ServerVars) != len(want) {
+		t.Errorf("server vars: got %v, want %v", pub.ServerVars, want)
+	}
+	for k, v := range want {
+		if pub.ServerVars[k] != v {
+			t.Errorf("server_vars[%s]: got %q, want %q", k, pub.ServerVars[k], v)
+		}
+	}
+	if pub.Players[0].GUID != "" || pub.Players[0].Name != "Sarge" {
+		t.Errorf("player: got %+v, want name kept and GUID dropped", pub.Players[0])
+	}
+
+	// The original is untouched; admins still get everything.
+	if full.ServerVars["rconpassword"] != "hunter2" || full.Players[0].GUID != "ABCDEF" {
+		t.Errorf("Public modified the original: %+v", full)
+	}
+}
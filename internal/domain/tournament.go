@@ -0,0 +1,81 @@
+package domain
+
+import "time"
+
+// Tournament formats. The bracket formats pair players off one on one;
+// a pickup drafts everyone who signed up into two teams for one game.
+const (
+	TournamentSingleElimination = "single_elimination"
+	TournamentRoundRobin        = "round_robin"
+	TournamentPickup            = "pickup"
+)
+
+// Tournament statuses, in order. Players sign up until an admin starts
+// the tournament, which draws the bracket; it completes when the last
+// result is in.
+const (
+	TournamentSignup     = "signup"
+	TournamentInProgress = "in_progress"
+	TournamentCompleted  = "completed"
+)
+
+// Tournament is a scheduled event. Games played on GameType between
+// StartsAt and EndsAt by a pairing's players are attached to it
+// automatically. MapPool is played in order, one map per round.
+type Tournament struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Format       string    `json:"format"`
+	GameType     string    `json:"game_type"`
+	MapPool      []string  `json:"map_pool"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Status       string    `json:"status"`
+	WinnerID     *int64    `json:"winner_id,omitempty"`    // bracket formats
+	WinningTeam  *int      `json:"winning_team,omitempty"` // pickups
+	CreatedBy    *int64    `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	EntrantCount int       `json:"entrant_count"`
+}
+
+// TournamentEntrant is a player signed up for a tournament. Seed is
+// their place in sign-up order; Team is the side a pickup drafted them
+// to.
+type TournamentEntrant struct {
+	Player     Player    `json:"player"`
+	Seed       int       `json:"seed"`
+	Team       *int      `json:"team,omitempty"`
+	SignedUpAt time.Time `json:"signed_up_at"`
+}
+
+// TournamentMatch is one pairing in a tournament's bracket or schedule.
+// Rounds and slots count from 1. A bracket pairing whose players aren't
+// known yet has nil players; one with only Player1 is a bye. A pickup
+// has a single pairing, decided by WinningTeam. MatchID links the game
+// that settled it, if any.
+type TournamentMatch struct {
+	ID          int64   `json:"id"`
+	Round       int     `json:"round"`
+	Slot        int     `json:"slot"`
+	MapName     string  `json:"map_name,omitempty"`
+	Player1     *Player `json:"player1,omitempty"`
+	Player2     *Player `json:"player2,omitempty"`
+	WinnerID    *int64  `json:"winner_id,omitempty"`
+	WinningTeam *int    `json:"winning_team,omitempty"`
+	MatchID     *int64  `json:"match_id,omitempty"`
+}
+
+// TournamentStanding is a player's record in a round robin.
+type TournamentStanding struct {
+	Player Player `json:"player"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+}
+
+// TournamentResponse is returned by GET /api/tournaments/{id}.
+type TournamentResponse struct {
+	Tournament Tournament           `json:"tournament"`
+	Entrants   []TournamentEntrant  `json:"entrants"`
+	Matches    []TournamentMatch    `json:"matches"`
+	Standings  []TournamentStanding `json:"standings,omitempty"` // round robins
+}
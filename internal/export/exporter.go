@@ -0,0 +1,260 @@
+// Package export gets hub data out as CSV or JSON, for operators who
+// want the numbers in a spreadsheet or warehouse without querying the
+// SQLite file directly: periodic snapshots uploaded to an S3-compatible
+// bucket, and on-demand streams behind /api/export and `trinity export`.
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// leaderboardRows caps the all-time leaderboard snapshot. Well above
+// any realistic player count; present so a runaway table can't produce
+// an unbounded file.
+const leaderboardRows = 10000
+
+// Config configures an Exporter.
+type Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Interval        time.Duration
+	// StatePath is where the last-exported watermark is persisted so
+	// each matches file only carries matches the hub recorded as
+	// finished since the previous upload, across restarts.
+	StatePath string
+	Store     *storage.Store
+}
+
+// Exporter writes, every Interval:
+//
+//	<prefix>leaderboard/<timestamp>.csv  all-time leaderboard snapshot
+//	<prefix>matches/<timestamp>.csv      one row per player per match
+//	                                     recorded as finished since the
+//	                                     last run
+type Exporter struct {
+	cfg    Config
+	client *s3Client
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// exportState is the on-disk watermark. MatchEndSeq is the last
+// matches.end_seq exported. MatchesThrough is when that run was; state
+// files from before end_seq only have it.
+type exportState struct {
+	MatchesThrough time.Time `json:"matches_through"`
+	MatchEndSeq    int64     `json:"match_end_seq"`
+}
+
+// New validates cfg and returns an Exporter ready to Start.
+func New(cfg Config) (*Exporter, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("export: Store is required")
+	}
+	if cfg.Interval <= 0 {
+		return nil, errors.New("export: Interval must be positive")
+	}
+	client, err := newS3Client(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKeyID, cfg.SecretAccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	return &Exporter{
+		cfg:    cfg,
+		client: client,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}, nil
+}
+
+// Start runs the export loop on its own goroutine. The first export
+// happens one Interval after start, not immediately, so a restart
+// loop doesn't spray the bucket with snapshots.
+func (e *Exporter) Start(ctx context.Context) {
+	go e.run(ctx)
+}
+
+// Stop halts the export loop and waits for any in-flight run to exit.
+func (e *Exporter) Stop() {
+	e.stopOnce.Do(func() { close(e.stopCh) })
+	<-e.doneCh
+}
+
+func (e *Exporter) run(ctx context.Context) {
+	defer close(e.doneCh)
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+			if err := e.RunOnce(ctx, time.Now()); err != nil {
+				log.Printf("export: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce builds and uploads both snapshots as of now. The watermark
+// only advances after the matches file is uploaded, so a failed run is
+// retried in full next time.
+func (e *Exporter) RunOnce(ctx context.Context, now time.Time) error {
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	lb, err := e.leaderboardCSV(ctx, now)
+	if err != nil {
+		return fmt.Errorf("building leaderboard: %w", err)
+	}
+	if err := e.client.PutObject(ctx, e.cfg.Prefix+"leaderboard/"+stamp+".csv", "text/csv", lb); err != nil {
+		return fmt.Errorf("uploading leaderboard: %w", err)
+	}
+
+	state, err := e.loadState()
+	if err != nil {
+		return err
+	}
+	if state.MatchEndSeq == 0 && !state.MatchesThrough.IsZero() {
+		state.MatchEndSeq, err = e.cfg.Store.LastMatchEndSeq(ctx, state.MatchesThrough)
+		if err != nil {
+			return fmt.Errorf("reading old watermark: %w", err)
+		}
+	}
+	through, err := e.cfg.Store.LastMatchEndSeq(ctx, time.Time{})
+	if err != nil {
+		return fmt.Errorf("building matches: %w", err)
+	}
+	matches, n, err := e.matchesCSV(ctx, state.MatchEndSeq, through)
+	if err != nil {
+		return fmt.Errorf("building matches: %w", err)
+	}
+	if n > 0 {
+		if err := e.client.PutObject(ctx, e.cfg.Prefix+"matches/"+stamp+".csv", "text/csv", matches); err != nil {
+			return fmt.Errorf("uploading matches: %w", err)
+		}
+	}
+	state.MatchesThrough = now.UTC()
+	state.MatchEndSeq = through
+	if err := e.saveState(state); err != nil {
+		return err
+	}
+	log.Printf("export: uploaded leaderboard and %d match rows to %s", n, e.cfg.Bucket)
+	return nil
+}
+
+func (e *Exporter) leaderboardCSV(ctx context.Context, now time.Time) ([]byte, error) {
+	resp, err := e.cfg.Store.GetLeaderboard(ctx, "frags", "all", leaderboardRows, "", now)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{
+		"rank", "player_id", "player_name", "frags", "deaths", "kd_ratio",
+		"matches", "completed_matches", "victories", "captures", "flag_returns",
+		"assists", "impressives", "excellents", "humiliations", "defends",
+		"best_spree", "skulls_scored", "obelisk_destroys",
+	})
+	for _, en := range resp.Entries {
+		w.Write([]string{
+			strconv.Itoa(en.Rank),
+			strconv.FormatInt(en.Player.ID, 10),
+			en.Player.CleanName,
+			i64(en.TotalFrags), i64(en.TotalDeaths),
+			strconv.FormatFloat(en.KDRatio, 'f', 2, 64),
+			i64(en.TotalMatches), i64(en.CompletedMatches), i64(en.Victories),
+			i64(en.Captures), i64(en.FlagReturns), i64(en.Assists),
+			i64(en.Impressives), i64(en.Excellents), i64(en.Humiliations), i64(en.Defends),
+			i64(en.BestSpree), i64(en.SkullsScored), i64(en.ObeliskDestroys),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (e *Exporter) matchesCSV(ctx context.Context, afterSeq, throughSeq int64) ([]byte, int, error) {
+	rows, err := e.cfg.Store.GetMatchPlayerRows(ctx, afterSeq, throughSeq)
+	if err != nil {
+		return nil, 0, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(matchColumns)
+	for _, r := range rows {
+		w.Write(matchRecord(r))
+	}
+	w.Flush()
+	return buf.Bytes(), len(rows), w.Error()
+}
+
+func (e *Exporter) loadState() (exportState, error) {
+	var st exportState
+	data, err := os.ReadFile(e.cfg.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("reading export state: %w", err)
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("parsing export state %s: %w", e.cfg.StatePath, err)
+	}
+	return st, nil
+}
+
+func (e *Exporter) saveState(st exportState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := e.cfg.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing export state: %w", err)
+	}
+	if err := os.Rename(tmp, e.cfg.StatePath); err != nil {
+		return fmt.Errorf("writing export state: %w", err)
+	}
+	return nil
+}
+
+// StatePathFor returns the default watermark location: next to the
+// database file.
+func StatePathFor(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "export_state.json")
+}
+
+func i64(v int64) string { return strconv.FormatInt(v, 10) }
+
+func intPtr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func ts(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
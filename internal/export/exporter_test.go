@@ -0,0 +1,162 @@
+package export
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+func TestRunOnceUploadsAndAdvancesWatermark(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		uploads[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store, err := storage.New(filepath.Join(dir, "trinity.db"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	defer store.Close()
+
+	e, err := New(Config{
+		Endpoint:        srv.URL,
+		Region:          "us-east-1",
+		Bucket:          "stats",
+		Prefix:          "trinity/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Interval:        time.Hour,
+		StatePath:       StatePathFor(filepath.Join(dir, "trinity.db")),
+		Store:           store,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	now := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	if err := e.RunOnce(context.Background(), now); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	lb, ok := uploads["/stats/trinity/leaderboard/20261016T030000Z.csv"]
+	if !ok {
+		t.Fatalf("leaderboard not uploaded; got %v", keys(uploads))
+	}
+	if !strings.HasPrefix(lb, "rank,player_id,player_name,") {
+		t.Errorf("leaderboard header: got %q", strings.SplitN(lb, "\n", 2)[0])
+	}
+	// No matches finished yet, so no matches file.
+	if _, ok := uploads["/stats/trinity/matches/20261016T030000Z.csv"]; ok {
+		t.Error("matches file uploaded with no matches")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "export_state.json"))
+	if err != nil {
+		t.Fatalf("state not written: %v", err)
+	}
+	if !strings.Contains(string(data), "2026-10-16T03:00:00Z") {
+		t.Errorf("state: got %s", data)
+	}
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// A match a collector sends after the export that covered its end time
+// still goes out in the next one.
+func TestRunOnceExportsLateMatches(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		uploads[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store, err := storage.New(filepath.Join(dir, "trinity.db"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	defer store.Close()
+	e, err := New(Config{
+		Endpoint: srv.URL, Region: "us-east-1", Bucket: "stats",
+		AccessKeyID: "AKID", SecretAccessKey: "secret", Interval: time.Hour,
+		StatePath: StatePathFor(filepath.Join(dir, "trinity.db")),
+		Store:     store,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx := context.Background()
+	base := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	server := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", server); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	play := func(uuid string, ended time.Time) {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: server.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: ended.Add(-10 * time.Minute)}
+		if err := store.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		score := 10
+		if err := store.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 10, 3, true, &score, nil, "", 0, true,
+			0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := store.EndMatch(ctx, m.ID, ended, "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	play("m-first", base.Add(-time.Hour))
+	if err := e.RunOnce(ctx, base); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := uploads["/stats/matches/20261016T030000Z.csv"]; !strings.Contains(got, "m-first") {
+		t.Fatalf("first matches file: got %q", got)
+	}
+
+	// Ended before the first run, but only reached the hub after it.
+	play("m-late", base.Add(-2*time.Hour))
+	if err := e.RunOnce(ctx, base.Add(time.Hour)); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	got := uploads["/stats/matches/20261016T040000Z.csv"]
+	if !strings.Contains(got, "m-late") || strings.Contains(got, "m-first") {
+		t.Errorf("second matches file: got %q", got)
+	}
+}
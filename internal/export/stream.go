@@ -0,0 +1,165 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+// Formats accepted by WriteMatches and WritePlayers.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ValidFormat reports whether f is a format the stream writers accept.
+func ValidFormat(f string) bool {
+	return f == FormatCSV || f == FormatJSON
+}
+
+var matchColumns = []string{
+	"match_id", "match_uuid", "server_source", "server_key", "map", "game_type",
+	"started_at", "ended_at", "exit_reason", "red_score", "blue_score",
+	"player_id", "player_name", "is_bot", "is_vr", "team", "score",
+	"frags", "deaths", "completed", "victory", "captures", "flag_returns",
+	"assists", "impressives", "excellents", "humiliations", "defends",
+}
+
+func matchRecord(r storage.MatchPlayerRow) []string {
+	return []string{
+		i64(r.MatchID), r.MatchUUID, r.ServerSource, r.ServerKey, r.MapName, r.GameType,
+		ts(r.StartedAt), ts(r.EndedAt), r.ExitReason, intPtr(r.RedScore), intPtr(r.BlueScore),
+		i64(r.PlayerID), r.PlayerName, strconv.FormatBool(r.IsBot), strconv.FormatBool(r.IsVR),
+		intPtr(r.Team), intPtr(r.Score),
+		i64(r.Frags), i64(r.Deaths), strconv.FormatBool(r.Completed), strconv.FormatBool(r.Victory),
+		i64(r.Captures), i64(r.FlagReturns), i64(r.Assists),
+		i64(r.Impressives), i64(r.Excellents), i64(r.Humiliations), i64(r.Defends),
+	}
+}
+
+var playerColumns = []string{
+	"player_id", "name", "clean_name", "first_seen", "last_seen", "is_bot", "is_vr",
+	"matches", "completed_matches", "frags", "deaths", "kd_ratio",
+	"captures", "flag_returns", "assists", "impressives", "excellents",
+	"humiliations", "defends", "victories", "best_spree",
+}
+
+func playerRecord(r storage.PlayerExportRow) []string {
+	return []string{
+		i64(r.PlayerID), r.Name, r.CleanName, tsPtr(r.FirstSeen), tsPtr(r.LastSeen),
+		strconv.FormatBool(r.IsBot), strconv.FormatBool(r.IsVR),
+		i64(r.Matches), i64(r.CompletedMatches), i64(r.Frags), i64(r.Deaths),
+		strconv.FormatFloat(r.KDRatio, 'f', 2, 64),
+		i64(r.Captures), i64(r.FlagReturns), i64(r.Assists), i64(r.Impressives), i64(r.Excellents),
+		i64(r.Humiliations), i64(r.Defends), i64(r.Victories), i64(r.BestSpree),
+	}
+}
+
+// WriteMatches streams one row per player per match that ended in
+// [from, to) to w. Output is written a page of matches at a time; if w
+// has a Flush method it's called after each page so a client sees rows
+// as they're read rather than when the whole range is done.
+func WriteMatches(ctx context.Context, store *storage.Store, w io.Writer, format string, from, to time.Time) error {
+	return stream(w, format, matchColumns, func(emit rowFunc, endPage func() error) error {
+		return store.EachMatchPlayerRow(ctx, from, to, func(page []storage.MatchPlayerRow) error {
+			for _, r := range page {
+				if err := emit(matchRecord(r), r); err != nil {
+					return err
+				}
+			}
+			return endPage()
+		})
+	})
+}
+
+// WritePlayers streams each player's totals over the matches that
+// ended in [from, to) to w, flushing like WriteMatches.
+func WritePlayers(ctx context.Context, store *storage.Store, w io.Writer, format string, from, to time.Time) error {
+	return stream(w, format, playerColumns, func(emit rowFunc, endPage func() error) error {
+		return store.EachPlayerExportRow(ctx, from, to, func(page []storage.PlayerExportRow) error {
+			for _, r := range page {
+				if err := emit(playerRecord(r), r); err != nil {
+					return err
+				}
+			}
+			return endPage()
+		})
+	})
+}
+
+// rowFunc writes one row: rec is its CSV record, row the value to
+// marshal as JSON.
+type rowFunc func(rec []string, row any) error
+
+// stream writes the framing for format (a CSV header, or the brackets
+// of a JSON array) around the rows walk hands to emit. walk calls
+// endPage after each page.
+func stream(w io.Writer, format string, columns []string, walk func(emit rowFunc, endPage func() error) error) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		endPage := func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return flush(w)
+		}
+		err := walk(func(rec []string, _ any) error { return cw.Write(rec) }, endPage)
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		first := true
+		err := walk(func(_ []string, row any) error {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			_, err = w.Write(append([]byte("\n"), data...))
+			return err
+		}, func() error { return flush(w) })
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n]\n")
+		return err
+	default:
+		return fmt.Errorf("export: unknown format %q", format)
+	}
+}
+
+// flush pushes w's buffered output to the client, if it buffers.
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func tsPtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return ts(*t)
+}
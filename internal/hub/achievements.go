@@ -0,0 +1,75 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// AchievementNotifier is told when a player earns a badge in a match
+// that just ended, so it can be announced in-game. Called from the
+// writer's consume goroutine, so implementations must not block.
+type AchievementNotifier interface {
+	NotifyAchievement(serverID int64, clientID int, playerID int64, a domain.Achievement)
+}
+
+// WithAchievementNotifier announces newly earned badges.
+func WithAchievementNotifier(n AchievementNotifier) Option {
+	return func(w *Writer) { w.achievementNotifier = n }
+}
+
+// achievementAnnounceWindow bounds how stale a match_end can be and
+// still get an in-game announcement. Backfilled matches earn their
+// badges silently; the player is long gone.
+const achievementAnnounceWindow = 5 * time.Minute
+
+// achievementRules decide, from a player's career progress, which
+// badges they qualify for.
+var achievementRules = []struct {
+	id     string
+	earned func(domain.AchievementProgress) bool
+}{
+	{domain.AchievementThousandFrags, func(p domain.AchievementProgress) bool { return p.TotalFrags >= 1000 }},
+	{domain.AchievementWinStreak, func(p domain.AchievementProgress) bool { return p.WinStreak >= 10 }},
+	{domain.AchievementCenturion, func(p domain.AchievementProgress) bool { return p.TotalCaptures >= 100 }},
+	{domain.AchievementMarathon, func(p domain.AchievementProgress) bool { return p.SessionLength >= 3*time.Hour }},
+}
+
+// matchEndParticipant is a human player whose stats were flushed for
+// the match being closed.
+type matchEndParticipant struct {
+	playerID int64
+	clientID int
+}
+
+// awardAchievements evaluates every rule for each participant once the
+// match's stats are on disk, recording and announcing new badges.
+func (w *Writer) awardAchievements(ctx context.Context, match *domain.Match, endedAt time.Time, participants []matchEndParticipant) {
+	announce := w.achievementNotifier != nil && time.Since(endedAt) < achievementAnnounceWindow
+	for _, pp := range participants {
+		progress, err := w.store.GetAchievementProgress(ctx, pp.playerID, match.ServerID, endedAt)
+		if err != nil {
+			log.Printf("hub: achievement progress for player %d: %v", pp.playerID, err)
+			continue
+		}
+		for _, rule := range achievementRules {
+			if !rule.earned(progress) {
+				continue
+			}
+			added, err := w.store.AwardAchievement(ctx, pp.playerID, rule.id, match.ID, endedAt)
+			if err != nil {
+				log.Printf("hub: award %s to player %d: %v", rule.id, pp.playerID, err)
+				continue
+			}
+			if !added {
+				continue
+			}
+			log.Printf("hub: player %d earned %s in match %d", pp.playerID, rule.id, match.ID)
+			if def, ok := domain.AchievementByID(rule.id); ok && announce {
+				w.achievementNotifier.NotifyAchievement(match.ServerID, pp.clientID, pp.playerID, def)
+			}
+		}
+	}
+}
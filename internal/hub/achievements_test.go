@@ -0,0 +1,64 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+type recordingAchievementNotifier struct {
+	got []string
+}
+
+func (n *recordingAchievementNotifier) NotifyAchievement(serverID int64, clientID int, playerID int64, a domain.Achievement) {
+	n.got = append(n.got, a.ID)
+}
+
+func TestHandleMatchEndAwardsAndAnnouncesOnce(t *testing.T) {
+	w, store := newTestWriter(t)
+	notifier := &recordingAchievementNotifier{}
+	w.achievementNotifier = notifier
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	pg, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now.Add(-5*time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	// Four hours on the server: a marathon.
+	if err := store.CreateSession(ctx, &domain.Session{PlayerGUIDID: pg.ID, ServerID: srv.ID, JoinedAt: now.Add(-4 * time.Hour)}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	endMatch := func(uuid string, endedAt time.Time) {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: endedAt.Add(-10 * time.Minute)}
+		if err := store.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		w.handleMatchEnd(ctx, domain.MatchEndData{
+			MatchUUID: uuid,
+			EndedAt:   endedAt,
+			Players:   []domain.MatchEndPlayer{{GUID: "AAAA", ClientID: 3, Frags: 10, Completed: true, JoinedAt: m.StartedAt}},
+		})
+	}
+	endMatch("m1", now)
+	endMatch("m2", now.Add(time.Second))
+
+	if len(notifier.got) != 1 || notifier.got[0] != domain.AchievementMarathon {
+		t.Fatalf("announcements: got %v, want [%s]", notifier.got, domain.AchievementMarathon)
+	}
+	earned, err := store.GetPlayerAchievements(ctx, pg.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerAchievements: %v", err)
+	}
+	if len(earned) != 1 || earned[0].ID != domain.AchievementMarathon {
+		t.Errorf("earned: got %+v", earned)
+	}
+}
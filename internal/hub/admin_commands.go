@@ -0,0 +1,31 @@
+package hub
+
+import (
+	"context"
+	"log"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleAdminCommand records a command an admin ran in game for the
+// audit log. The collector has already run it.
+func (w *Writer) handleAdminCommand(ctx context.Context, serverID int64, data domain.AdminCommandData) {
+	cmd := domain.AdminCommand{
+		ServerID:  serverID,
+		AdminName: data.AdminName,
+		Command:   data.Command,
+		Target:    data.Target,
+		CreatedAt: data.At,
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.AdminGUID); ok {
+		cmd.AdminPlayerID = &id
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.TargetGUID); ok {
+		cmd.TargetPlayerID = &id
+	}
+	log.Printf("audit: ingame_%s server=%d admin=%q target=%q client=%d",
+		data.Command, serverID, domain.CleanQ3Name(data.AdminName), domain.CleanQ3Name(data.Target), data.TargetClient)
+	if err := w.store.RecordAdminCommand(ctx, cmd); err != nil {
+		log.Printf("hub: RecordAdminCommand server=%d: %v", serverID, err)
+	}
+}
@@ -0,0 +1,54 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestAdminCommandAudit(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	admin, err := store.UpsertPlayerGUID(ctx, "GUID-ADMIN", "boss", "boss", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if err := store.CreateUser(ctx, "boss", "hash", true, &admin.PlayerID); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	reply, err := w.Greet(ctx, GreetRequest{ServerID: srv.ID, GUID: "GUID-ADMIN", ClientName: "boss", CleanName: "boss"})
+	if err != nil || !reply.IsAdmin {
+		t.Fatalf("Greet: got %+v, %v", reply, err)
+	}
+
+	w.handleAdminCommand(ctx, srv.ID, domain.AdminCommandData{
+		AdminGUID: "GUID-ADMIN", AdminName: "boss",
+		Command: "kick", Target: "^1griefer", TargetGUID: "GUID-UNKNOWN", TargetClient: 4, At: now,
+	})
+	w.handleAdminCommand(ctx, srv.ID, domain.AdminCommandData{
+		AdminGUID: "GUID-ADMIN", AdminName: "boss",
+		Command: "map", Target: "q3dm17", TargetClient: -1, At: now.Add(time.Minute),
+	})
+	got, err := store.ListAdminCommands(ctx, srv.ID, 10)
+	if err != nil {
+		t.Fatalf("ListAdminCommands: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d commands, want 2", len(got))
+	}
+	if c := got[0]; c.Command != "map" || c.Target != "q3dm17" || c.TargetPlayerID != nil {
+		t.Errorf("newest = %+v, want the map change", c)
+	}
+	if c := got[1]; c.ServerKey != "ffa" || c.AdminPlayerID == nil || *c.AdminPlayerID != admin.PlayerID ||
+		c.Command != "kick" || c.Target != "^1griefer" || c.TargetPlayerID != nil {
+		t.Errorf("kick = %+v", c)
+	}
+}
@@ -0,0 +1,66 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// anonymizeInterval is how often the writer carries out "forget me"
+// requests whose cooling-off period has ended. The period is days
+// long, so an extra hour of slack doesn't matter.
+const anonymizeInterval = time.Hour
+
+func (w *Writer) anonymizeLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.anonymizeDue(ctx, time.Now())
+
+	ticker := time.NewTicker(anonymizeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.anonymizeDue(ctx, now)
+		}
+	}
+}
+
+// anonymizeDue anonymizes every player whose request is due as of now.
+func (w *Writer) anonymizeDue(ctx context.Context, now time.Time) {
+	ids, err := w.store.DueAnonymizations(ctx, now)
+	if err != nil {
+		log.Printf("hub: due anonymizations: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	for _, id := range ids {
+		if err := w.store.AnonymizePlayer(ctx, id, now); err != nil {
+			log.Printf("hub: anonymize player %d: %v", id, err)
+			continue
+		}
+		log.Printf("hub: anonymized player %d", id)
+	}
+	// The player's GUIDs were rewritten; drop any cached lookups so a
+	// returning client resolves to a fresh player.
+	w.invalidateAllGUIDs()
+}
+
+// AnonymizeNow anonymizes playerID right away on an admin's say-so,
+// skipping the cooling-off period. The request is recorded against
+// adminUserID like a self-service one, so GetAnonymization shows it
+// completed afterwards.
+func (w *Writer) AnonymizeNow(ctx context.Context, playerID, adminUserID int64, now time.Time) error {
+	if _, err := w.store.RequestAnonymization(ctx, playerID, adminUserID, now, now); err != nil {
+		return err
+	}
+	if err := w.store.AnonymizePlayer(ctx, playerID, now); err != nil {
+		return err
+	}
+	log.Printf("hub: anonymized player %d at the request of user %d", playerID, adminUserID)
+	w.invalidateAllGUIDs()
+	return nil
+}
@@ -0,0 +1,194 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// DefaultMasterServers are the masters the browser asks when
+// tracker.hub.master_servers is unset.
+var DefaultMasterServers = []string{
+	"master.ioquake3.org:27950",
+	"master.quake3arena.com:27950",
+}
+
+const (
+	// browserCacheTTL is how long one sweep of the masters is reused,
+	// so flipping filters doesn't query every server again.
+	browserCacheTTL = 2 * time.Minute
+	// browserMaxServers caps how many listed servers one sweep queries.
+	browserMaxServers = 512
+	// browserConcurrency is how many status queries run at once.
+	browserConcurrency = 32
+)
+
+// MasterQuerier lists the servers a Quake 3 master server knows.
+type MasterQuerier interface {
+	QueryMaster(address string) ([]string, error)
+}
+
+// BrowserQuerier is what ServerBrowser needs from a UDP client.
+type BrowserQuerier interface {
+	MasterQuerier
+	StatusQuerier
+}
+
+// BrowserFilter narrows ServerBrowser.Browse. Zero values match
+// everything.
+type BrowserFilter struct {
+	Name       string // substring of the hostname, ignoring case and colors
+	Map        string
+	GameType   string
+	MinPlayers int // humans
+	NotFull    bool
+}
+
+// ServerBrowser lists the public servers the master servers know,
+// with each one's current status.
+type ServerBrowser struct {
+	masters []string
+	querier BrowserQuerier
+
+	mu       sync.Mutex
+	cached   []domain.BrowserServer
+	cachedAt time.Time
+}
+
+// NewServerBrowser constructs a browser over masters, or
+// DefaultMasterServers if there are none.
+func NewServerBrowser(masters []string, q BrowserQuerier) *ServerBrowser {
+	if len(masters) == 0 {
+		masters = DefaultMasterServers
+	}
+	return &ServerBrowser{masters: masters, querier: q}
+}
+
+// Browse returns the listed servers that answered and match f, most
+// humans first. Tracked is left for the caller to fill in.
+func (b *ServerBrowser) Browse(ctx context.Context, f BrowserFilter) ([]domain.BrowserServer, error) {
+	servers, err := b.sweep(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(f.Name)
+	out := []domain.BrowserServer{}
+	for _, s := range servers {
+		switch {
+		case name != "" && !strings.Contains(strings.ToLower(s.CleanName), name):
+		case f.Map != "" && !strings.EqualFold(s.Map, f.Map):
+		case f.GameType != "" && !strings.EqualFold(s.GameType, f.GameType):
+		case s.Humans < f.MinPlayers:
+		case f.NotFull && s.MaxClients > 0 && s.Humans+s.Bots >= s.MaxClients:
+		default:
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// sweep asks every master for its list and queries each server on it,
+// or returns the last sweep if it's recent enough.
+func (b *ServerBrowser) sweep(ctx context.Context) ([]domain.BrowserServer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cached != nil && time.Since(b.cachedAt) < browserCacheTTL {
+		return b.cached, nil
+	}
+
+	var addrs []string
+	seen := make(map[string]bool)
+	var errs []error
+	for _, m := range b.masters {
+		list, err := b.querier.QueryMaster(m)
+		if err != nil {
+			log.Printf("hub.ServerBrowser: master %s: %v", m, err)
+			errs = append(errs, err)
+			continue
+		}
+		for _, a := range list {
+			if !seen[a] {
+				seen[a] = true
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	if len(errs) == len(b.masters) {
+		return nil, errors.Join(errs...)
+	}
+	if len(addrs) > browserMaxServers {
+		addrs = addrs[:browserMaxServers]
+	}
+
+	results := make([]*domain.BrowserServer, len(addrs))
+	sem := make(chan struct{}, browserConcurrency)
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status, err := b.querier.QueryStatus(addr)
+			if err != nil || status == nil {
+				return
+			}
+			results[i] = browserServer(addr, status)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	servers := []domain.BrowserServer{}
+	for _, s := range results {
+		if s != nil {
+			servers = append(servers, *s)
+		}
+	}
+	slices.SortFunc(servers, func(a, b domain.BrowserServer) int {
+		if a.Humans != b.Humans {
+			return b.Humans - a.Humans
+		}
+		return strings.Compare(a.CleanName, b.CleanName)
+	})
+	b.cached, b.cachedAt = servers, time.Now()
+	return servers, nil
+}
+
+// browserServer sums up a status reply for the browser.
+func browserServer(addr string, status *domain.ServerStatus) *domain.BrowserServer {
+	s := &domain.BrowserServer{
+		Address:    addr,
+		Hostname:   status.ServerVars["sv_hostname"],
+		Map:        status.Map,
+		GameType:   status.GameType,
+		MaxClients: status.MaxClients,
+	}
+	s.CleanName = domain.CleanQ3Name(s.Hostname)
+	s.Humans, s.Bots = countByPing(status.Players)
+	return s
+}
+
+// countByPing splits players into humans and bots for servers without
+// a collector. Bots always report a ping of 0.
+func countByPing(players []domain.PlayerStatus) (humans, bots int) {
+	for _, p := range players {
+		if p.Ping > 0 {
+			humans++
+		} else {
+			bots++
+		}
+	}
+	return humans, bots
+}
@@ -0,0 +1,97 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+type fakeBrowserQuerier struct {
+	fakeQuerier
+	masters map[string][]string
+}
+
+func (f *fakeBrowserQuerier) QueryMaster(addr string) ([]string, error) {
+	list, ok := f.masters[addr]
+	if !ok {
+		return nil, errors.New("timeout")
+	}
+	return list, nil
+}
+
+func browserStatus(hostname, mapName string, maxClients int, pings ...int) *domain.ServerStatus {
+	s := &domain.ServerStatus{
+		Map:        mapName,
+		GameType:   "ffa",
+		MaxClients: maxClients,
+		ServerVars: map[string]string{"sv_hostname": hostname},
+	}
+	for _, p := range pings {
+		s.Players = append(s.Players, domain.PlayerStatus{Ping: p})
+	}
+	return s
+}
+
+func TestServerBrowser(t *testing.T) {
+	q := &fakeBrowserQuerier{
+		fakeQuerier: fakeQuerier{responses: map[string]*domain.ServerStatus{
+			"1.1.1.1:27960": browserStatus("^1Rocket ^7Arena", "q3dm17", 8, 50, 60, 70, 0),
+			"2.2.2.2:27960": browserStatus("Bot Farm", "q3dm6", 4, 0, 0, 0, 0),
+			"3.3.3.3:27960": browserStatus("Full House", "q3dm17", 2, 30, 40),
+			// 4.4.4.4 doesn't answer.
+		}},
+		masters: map[string][]string{
+			"a:27950": {"1.1.1.1:27960", "2.2.2.2:27960", "4.4.4.4:27960"},
+			"b:27950": {"1.1.1.1:27960", "3.3.3.3:27960"},
+		},
+	}
+	b := NewServerBrowser([]string{"a:27950", "b:27950", "down:27950"}, q)
+	ctx := context.Background()
+
+	all, err := b.Browse(ctx, BrowserFilter{})
+	if err != nil {
+		t.Fatalf("Browse: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("got %d servers, want 3: %+v", len(all), all)
+	}
+	if all[0].Address != "1.1.1.1:27960" || all[0].CleanName != "Rocket Arena" || all[0].Humans != 3 || all[0].Bots != 1 {
+		t.Errorf("first = %+v", all[0])
+	}
+	if len(q.calls) != 4 {
+		t.Errorf("queried %d servers, want 4 (duplicates once)", len(q.calls))
+	}
+
+	for _, tc := range []struct {
+		name string
+		f    BrowserFilter
+		want []string
+	}{
+		{"name", BrowserFilter{Name: "rocket"}, []string{"1.1.1.1:27960"}},
+		{"map", BrowserFilter{Map: "Q3DM17"}, []string{"1.1.1.1:27960", "3.3.3.3:27960"}},
+		{"min players", BrowserFilter{MinPlayers: 1}, []string{"1.1.1.1:27960", "3.3.3.3:27960"}},
+		{"not full", BrowserFilter{NotFull: true}, []string{"1.1.1.1:27960"}},
+	} {
+		got, err := b.Browse(ctx, tc.f)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var addrs []string
+		for _, s := range got {
+			addrs = append(addrs, s.Address)
+		}
+		if len(addrs) != len(tc.want) || (len(addrs) > 0 && addrs[0] != tc.want[0]) || (len(addrs) > 1 && addrs[1] != tc.want[1]) {
+			t.Errorf("%s: got %v, want %v", tc.name, addrs, tc.want)
+		}
+	}
+	// Filtering reuses the sweep.
+	if len(q.calls) != 4 {
+		t.Errorf("filters queried servers again: %d calls", len(q.calls))
+	}
+
+	if _, err := NewServerBrowser([]string{"down:27950"}, q).Browse(ctx, BrowserFilter{}); err == nil {
+		t.Error("no master answering: want an error")
+	}
+}
@@ -0,0 +1,76 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// chatPruneInterval is how often chat older than the retention window
+// is deleted. Retention is days long, so hourly is plenty.
+const chatPruneInterval = time.Hour
+
+// WithChatLog stores chat_message facts in match_chat and deletes
+// lines older than retention. Without it chat facts are dropped.
+func WithChatLog(retention time.Duration) Option {
+	return func(w *Writer) { w.chatRetention = retention }
+}
+
+// handleChatMessage stores a chat line against its match. Senders and
+// recipients the hub hasn't seen keep their name with no player id.
+func (w *Writer) handleChatMessage(ctx context.Context, data domain.ChatMessageData) {
+	if w.chatRetention <= 0 || data.MatchUUID == "" || data.Message == "" {
+		return
+	}
+	match, err := w.store.GetMatchByUUID(ctx, data.MatchUUID)
+	if err != nil || match == nil {
+		return
+	}
+	line := domain.ChatLine{
+		Kind:    data.Kind,
+		Name:    data.Name,
+		Team:    data.Team,
+		ToName:  data.ToName,
+		Message: data.Message,
+		SentAt:  data.SentAt,
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.GUID); ok {
+		line.PlayerID = &id
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.ToGUID); ok {
+		line.ToPlayerID = &id
+	}
+	if err := w.store.InsertMatchChat(ctx, match.ID, line); err != nil {
+		log.Printf("hub: InsertMatchChat(%s): %v", data.MatchUUID, err)
+	}
+}
+
+func (w *Writer) chatPruneLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.pruneChat(ctx, time.Now())
+
+	ticker := time.NewTicker(chatPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.pruneChat(ctx, now)
+		}
+	}
+}
+
+// pruneChat deletes chat sent before the retention window.
+func (w *Writer) pruneChat(ctx context.Context, now time.Time) {
+	n, err := w.store.PruneMatchChat(ctx, now.Add(-w.chatRetention))
+	if err != nil {
+		log.Printf("hub: prune chat: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("hub: pruned %d chat lines", n)
+	}
+}
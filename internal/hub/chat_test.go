@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestHandleChatMessageStoresAndPrunes(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "chat-match", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: now.Add(-time.Hour)}
+	if err := store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	pg, err := store.UpsertPlayerGUID(ctx, "GUID-ALICE", "alice", "alice", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	say := func(msg string, at time.Time) domain.ChatMessageData {
+		return domain.ChatMessageData{
+			MatchUUID: m.UUID, Kind: domain.ChatSay, GUID: "GUID-ALICE", Name: "alice",
+			Message: msg, SentAt: at,
+		}
+	}
+
+	// Without WithChatLog, chat is dropped.
+	w.handleChatMessage(ctx, say("dropped", now))
+	if got, _ := store.GetMatchChat(ctx, m.ID, true); len(got) != 0 {
+		t.Fatalf("stored %d lines with chat log off", len(got))
+	}
+
+	WithChatLog(24 * time.Hour)(w)
+	w.handleChatMessage(ctx, say("old", now.Add(-48*time.Hour)))
+	w.handleChatMessage(ctx, say("new", now))
+	w.handleChatMessage(ctx, domain.ChatMessageData{MatchUUID: "no-such-match", Kind: domain.ChatSay, Message: "lost", SentAt: now})
+
+	got, err := store.GetMatchChat(ctx, m.ID, true)
+	if err != nil {
+		t.Fatalf("GetMatchChat: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d lines, want 2", len(got))
+	}
+	if got[1].PlayerID == nil || *got[1].PlayerID != pg.PlayerID {
+		t.Errorf("sender not resolved: %+v", got[1])
+	}
+
+	w.pruneChat(ctx, now)
+	got, _ = store.GetMatchChat(ctx, m.ID, true)
+	if len(got) != 1 || got[0].Message != "new" {
+		t.Errorf("after prune: got %+v, want only the new line", got)
+	}
+}
@@ -0,0 +1,40 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// clanTagInterval is how often the writer adds players whose names
+// carry a clan tag to that clan. New clans and tag changes are picked
+// up right away; this catches players who rename or first show up.
+const clanTagInterval = time.Hour
+
+func (w *Writer) clanTagLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.detectClanMembers(ctx, time.Now())
+
+	ticker := time.NewTicker(clanTagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.detectClanMembers(ctx, now)
+		}
+	}
+}
+
+// detectClanMembers adds tagged players to their clans.
+func (w *Writer) detectClanMembers(ctx context.Context, now time.Time) {
+	n, err := w.store.DetectClanMembers(ctx, now.UTC())
+	if err != nil {
+		log.Printf("hub: clan tags: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("hub: %d players added to clans by tag", n)
+	}
+}
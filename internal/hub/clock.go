@@ -0,0 +1,46 @@
+package hub
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// clockRunning reports whether the match clock means anything in
+// state. Stock servers don't publish g_matchstate, so "" counts.
+func clockRunning(state string) bool {
+	return state == "" || state == "active" || state == "overtime"
+}
+
+// reconcileClock fills status's match clock and time remaining. A
+// status whose ClockSource is already ClockServer (the level-time cvars
+// were there) is trusted, pushed forward by half the query's round
+// trip to account for the reply's time in flight. Otherwise the clock
+// runs from matchStart, the open match's start as logged; nil leaves
+// the status without a clock.
+func reconcileClock(status *domain.ServerStatus, rtt time.Duration, matchStart *time.Time, now time.Time) {
+	status.MatchClockMs = 0
+	status.TimeRemainingMs = nil
+	if !clockRunning(status.MatchState) {
+		status.ClockSource = ""
+		return
+	}
+	switch {
+	case status.ClockSource == domain.ClockServer:
+		status.MatchClockMs = status.GameTimeMs + int((rtt / 2).Milliseconds())
+	case matchStart != nil && !matchStart.After(now):
+		status.MatchClockMs = int(now.Sub(*matchStart).Milliseconds())
+		status.ClockSource = domain.ClockLog
+	default:
+		status.ClockSource = ""
+		return
+	}
+
+	limit, err := strconv.Atoi(status.ServerVars["timelimit"])
+	if err != nil || limit <= 0 {
+		return
+	}
+	remaining := max(limit*60_000-status.MatchClockMs, 0)
+	status.TimeRemainingMs = &remaining
+}
@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestReconcileClockPrefersServerCvars(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-10 * time.Minute)
+	status := &domain.ServerStatus{
+		MatchState:  "active",
+		GameTimeMs:  120_000,
+		ClockSource: domain.ClockServer,
+		ServerVars:  map[string]string{"timelimit": "15"},
+	}
+	reconcileClock(status, 40*time.Millisecond, &started, now)
+	if status.MatchClockMs != 120_020 || status.ClockSource != domain.ClockServer {
+		t.Errorf("clock = %d from %q, want 120020 from server", status.MatchClockMs, status.ClockSource)
+	}
+	if status.TimeRemainingMs == nil || *status.TimeRemainingMs != 15*60_000-120_020 {
+		t.Errorf("remaining = %v, want %d", status.TimeRemainingMs, 15*60_000-120_020)
+	}
+}
+
+func TestReconcileClockFallsBackToLog(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-20 * time.Minute)
+	status := &domain.ServerStatus{ServerVars: map[string]string{"timelimit": "15"}}
+	reconcileClock(status, 0, &started, now)
+	if status.MatchClockMs != 20*60_000 || status.ClockSource != domain.ClockLog {
+		t.Errorf("clock = %d from %q, want 1200000 from log", status.MatchClockMs, status.ClockSource)
+	}
+	// Past the limit counts down to zero, not below.
+	if status.TimeRemainingMs == nil || *status.TimeRemainingMs != 0 {
+		t.Errorf("remaining = %v, want 0", status.TimeRemainingMs)
+	}
+}
+
+func TestReconcileClockIdleOutsidePlay(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Minute)
+	status := &domain.ServerStatus{
+		MatchState:  "warmup",
+		GameTimeMs:  5_000,
+		ClockSource: domain.ClockServer,
+		ServerVars:  map[string]string{"timelimit": "15"},
+	}
+	reconcileClock(status, 0, &started, now)
+	if status.MatchClockMs != 0 || status.ClockSource != "" || status.TimeRemainingMs != nil {
+		t.Errorf("warmup status got clock %d from %q, remaining %v", status.MatchClockMs, status.ClockSource, status.TimeRemainingMs)
+	}
+
+	// No cvars and no open match: no clock at all.
+	status = &domain.ServerStatus{MatchState: "active"}
+	reconcileClock(status, 0, nil, now)
+	if status.ClockSource != "" || status.TimeRemainingMs != nil {
+		t.Errorf("no source: got %q, remaining %v", status.ClockSource, status.TimeRemainingMs)
+	}
+}
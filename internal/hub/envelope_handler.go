@@ -136,12 +136,42 @@ func decodeEventPayload(event string, raw json.RawMessage) (interface{}, error)
 			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
 		}
 		return p, nil
+	case domain.FactServerCrash:
+		var p domain.ServerCrashData
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
+		}
+		return p, nil
 	case domain.FactDemoFinalized:
 		var p domain.DemoFinalizedData
 		if err := json.Unmarshal(raw, &p); err != nil {
 			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
 		}
 		return p, nil
+	case domain.FactChatMessage:
+		var p domain.ChatMessageData
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
+		}
+		return p, nil
+	case domain.FactModerationIncident:
+		var p domain.ModerationIncidentData
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
+		}
+		return p, nil
+	case domain.FactVIPKick:
+		var p domain.VIPKickData
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
+		}
+		return p, nil
+	case domain.FactAdminCommand:
+		var p domain.AdminCommandData
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("hub: decode %s: %w", event, err)
+		}
+		return p, nil
 	default:
 		return nil, fmt.Errorf("hub: unknown event type %q", event)
 	}
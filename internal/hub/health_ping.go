@@ -0,0 +1,90 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthPingTimeout bounds one ping so a slow monitor can't pile them
+// up.
+const healthPingTimeout = 10 * time.Second
+
+// WithHealthPing makes the hub GET url every interval, but only while
+// the database answers and every active collector has heartbeated
+// within staleAfter. Pointed at an uptime monitor (healthchecks.io and
+// the like), a missed ping then means something inside is wrong, not
+// just that the web server is down.
+func WithHealthPing(url string, interval, staleAfter time.Duration) Option {
+	return func(w *Writer) {
+		w.healthPingURL = url
+		w.healthPingInterval = interval
+		w.healthStaleAfter = staleAfter
+	}
+}
+
+func (w *Writer) healthPingLoop(ctx context.Context) {
+	defer w.wg.Done()
+	client := &http.Client{Timeout: healthPingTimeout}
+	w.healthPing(ctx, client, time.Now())
+
+	ticker := time.NewTicker(w.healthPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.healthPing(ctx, client, now)
+		}
+	}
+}
+
+// healthPing pings the monitor if the hub is healthy. Changes in health
+// are logged once rather than every interval.
+func (w *Writer) healthPing(ctx context.Context, client *http.Client, now time.Time) {
+	problem := w.checkHealth(ctx, now)
+	if problem != w.healthProblem {
+		if problem == "" {
+			log.Printf("hub: healthy again; resuming health pings")
+		} else {
+			log.Printf("hub: unhealthy (%s); holding health pings", problem)
+		}
+		w.healthProblem = problem
+	}
+	if problem != "" {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.healthPingURL, nil)
+	if err != nil {
+		log.Printf("hub: health ping: %v", err)
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("hub: health ping: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("hub: health ping: %s", resp.Status)
+	}
+}
+
+// checkHealth returns why the hub isn't healthy, or "" if it is.
+func (w *Writer) checkHealth(ctx context.Context, now time.Time) string {
+	if err := w.store.Ping(ctx); err != nil {
+		return fmt.Sprintf("database: %v", err)
+	}
+	stale, err := w.store.StaleSources(ctx, now.Add(-w.healthStaleAfter))
+	if err != nil {
+		return fmt.Sprintf("database: %v", err)
+	}
+	if len(stale) > 0 {
+		return fmt.Sprintf("no heartbeat for %s from %s", w.healthStaleAfter, strings.Join(stale, ", "))
+	}
+	return ""
+}
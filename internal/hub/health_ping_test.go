@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthPingOnlyWhenHealthy(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		pings.Add(1)
+	}))
+	defer srv.Close()
+	WithHealthPing(srv.URL, time.Minute, 3*time.Minute)(w)
+	client := srv.Client()
+
+	// A source that has never heartbeated isn't set up yet.
+	if err := store.CreateSource(ctx, "remote-1", true, seedOwnerID(t, store)); err != nil {
+		t.Fatalf("CreateSource: %v", err)
+	}
+	w.healthPing(ctx, client, now)
+	if got := pings.Load(); got != 1 {
+		t.Fatalf("pings = %d, want 1 with no heartbeats yet", got)
+	}
+
+	if err := store.TouchSourceHeartbeat(ctx, "remote-1", now.Add(-5*time.Minute), "", ""); err != nil {
+		t.Fatalf("TouchSourceHeartbeat: %v", err)
+	}
+	w.healthPing(ctx, client, now)
+	if got := pings.Load(); got != 1 {
+		t.Errorf("pings = %d, want still 1 with a stale collector", got)
+	}
+	if w.healthProblem == "" {
+		t.Error("stale collector not reported")
+	}
+
+	if err := store.TouchSourceHeartbeat(ctx, "remote-1", now, "", ""); err != nil {
+		t.Fatalf("TouchSourceHeartbeat: %v", err)
+	}
+	w.healthPing(ctx, client, now)
+	if got := pings.Load(); got != 2 {
+		t.Errorf("pings = %d, want 2 once the collector is back", got)
+	}
+}
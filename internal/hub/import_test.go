@@ -0,0 +1,63 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// Imported logs replay history the hub may already have, and may
+// arrive after newer live data.
+func TestImportedHistoryIsIdempotent(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	then := now.AddDate(-2, 0, 0)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	if _, err := w.UpsertPlayerIdentity(ctx, "AAAA", "alice", "alice", now, false); err != nil {
+		t.Fatalf("UpsertPlayerIdentity: %v", err)
+	}
+	// Live: alice is on the server now.
+	w.Dispatch(ctx, domain.FactEvent{Type: domain.FactPlayerJoin, ServerID: srv.ID, Timestamp: now,
+		Data: domain.PlayerJoinData{GUID: "AAAA", JoinedAt: now}})
+
+	// Imported, twice: a visit two years ago under an older name.
+	for range 2 {
+		if _, err := w.UpsertPlayerIdentity(ctx, "AAAA", "al", "al", then, false); err != nil {
+			t.Fatalf("UpsertPlayerIdentity: %v", err)
+		}
+		w.Dispatch(ctx, domain.FactEvent{Type: domain.FactPlayerJoin, ServerID: srv.ID, Timestamp: then,
+			Data: domain.PlayerJoinData{GUID: "AAAA", JoinedAt: then}})
+		w.Dispatch(ctx, domain.FactEvent{Type: domain.FactPlayerLeave, ServerID: srv.ID, Timestamp: then.Add(time.Hour),
+			Data: domain.PlayerLeaveData{GUID: "AAAA", LeftAt: then.Add(time.Hour)}})
+	}
+
+	pg, err := store.GetPlayerGUIDByGUID(ctx, "AAAA")
+	if err != nil {
+		t.Fatalf("GetPlayerGUIDByGUID: %v", err)
+	}
+	if pg.CleanName != "alice" || !pg.FirstSeen.Equal(then) || !pg.LastSeen.Equal(now) {
+		t.Errorf("player guid: got %s, %s–%s; want alice, %s–%s", pg.CleanName, pg.FirstSeen, pg.LastSeen, then, now)
+	}
+	live, err := store.GetOpenSessionForPlayer(ctx, pg.ID, srv.ID)
+	if err != nil || live == nil || !live.JoinedAt.Equal(now) {
+		t.Fatalf("live session: got %+v, %v; want still open", live, err)
+	}
+	old, err := store.GetSessionByPlayerAndJoinTime(ctx, pg.ID, srv.ID, then)
+	if err != nil || old == nil || old.LeftAt == nil {
+		t.Fatalf("imported session: got %+v, %v; want closed", old, err)
+	}
+	sessions, err := store.GetPlayerSessions(ctx, pg.PlayerID, 10, nil)
+	if err != nil {
+		t.Fatalf("GetPlayerSessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("sessions: got %d, want 2", len(sessions))
+	}
+}
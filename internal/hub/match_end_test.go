@@ -0,0 +1,157 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestHandleMatchEndTwiceFlushesOnce(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	if _, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now.Add(-time.Hour), false); err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: now.Add(-10 * time.Minute)}
+	if err := store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	end := domain.MatchEndData{
+		MatchUUID:  "m1",
+		EndedAt:    now,
+		ExitReason: "fraglimit",
+		Players:    []domain.MatchEndPlayer{{GUID: "AAAA", ClientID: 3, Frags: 10, Completed: true, JoinedAt: m.StartedAt}},
+		Cursor:     &domain.LogCursor{Inode: 42, Offset: 1000, At: now},
+	}
+	w.handleMatchEnd(ctx, end)
+	// Resent after a crash, with the collector a little further along.
+	end.Cursor = &domain.LogCursor{Inode: 42, Offset: 1200, At: now.Add(time.Second)}
+	w.handleMatchEnd(ctx, end)
+
+	summary, err := store.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	if len(summary.Players) != 1 || summary.Players[0].Frags != 10 {
+		t.Errorf("players: got %+v, want one with 10 frags", summary.Players)
+	}
+	cursor, err := store.GetLogCursor(ctx, srv.ID)
+	if err != nil {
+		t.Fatalf("GetLogCursor: %v", err)
+	}
+	if cursor == nil || cursor.Inode != 42 || cursor.Offset != 1200 || !cursor.At.Equal(now.Add(time.Second)) {
+		t.Errorf("cursor: got %+v", cursor)
+	}
+
+	// A cursor from an older line doesn't move it back.
+	end.Cursor = &domain.LogCursor{Inode: 42, Offset: 1000, At: now}
+	w.handleMatchEnd(ctx, end)
+	if cursor, _ := store.GetLogCursor(ctx, srv.ID); cursor == nil || cursor.Offset != 1200 {
+		t.Errorf("cursor after older match_end: got %+v", cursor)
+	}
+}
+
+func TestHandleMatchEndSavesScoreboard(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now.Add(-time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: now.Add(-10 * time.Minute)}
+	if err := store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	w.handleMatchEnd(ctx, domain.MatchEndData{
+		MatchUUID:  "m1",
+		EndedAt:    now,
+		ExitReason: "fraglimit",
+		Players:    []domain.MatchEndPlayer{{GUID: "AAAA", ClientID: 3, Frags: 10, Completed: true, JoinedAt: m.StartedAt}},
+		Scoreboard: []domain.MatchEndScore{
+			{ClientID: 3, GUID: "AAAA", Name: "^1alice", Score: 10, Ping: 48},
+			{ClientID: 5, GUID: "ZZZZ", Name: "stranger", Score: 4, Ping: 999},
+		},
+	})
+
+	summary, err := store.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	sb := summary.Scoreboard
+	if len(sb) != 2 {
+		t.Fatalf("scoreboard: got %+v", sb)
+	}
+	if sb[0].Name != "^1alice" || sb[0].Score != 10 || sb[0].Ping != 48 || sb[0].PlayerID == nil || *sb[0].PlayerID != alice.PlayerID {
+		t.Errorf("first line: got %+v", sb[0])
+	}
+	if sb[1].ClientID != 5 || sb[1].PlayerID != nil {
+		t.Errorf("unresolved line: got %+v", sb[1])
+	}
+}
+
+func TestHandleMatchEndSavesFlagTimeline(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now.Add(-time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	start := now.Add(-10 * time.Minute)
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3ctf1", GameType: "ctf", StartedAt: start}
+	if err := store.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	red := domain.TeamRed
+	w.handleMatchEnd(ctx, domain.MatchEndData{
+		MatchUUID:  "m1",
+		EndedAt:    now,
+		ExitReason: "capturelimit",
+		Players: []domain.MatchEndPlayer{{GUID: "AAAA", ClientID: 3, Captures: 1, Team: &red, Completed: true,
+			JoinedAt: start, FlagCarryMs: 45000}},
+		FlagEvents: []domain.MatchEndFlagEvent{
+			{At: start.Add(time.Minute), Event: domain.FlagEventTaken, Team: domain.TeamBlue, ClientID: 3, GUID: "AAAA"},
+			{At: start.Add(90 * time.Second), Event: domain.FlagEventCaptured, Team: domain.TeamBlue, ClientID: 3, GUID: "AAAA"},
+			{At: start.Add(2 * time.Minute), Event: domain.FlagEventReturned, Team: domain.TeamRed, ClientID: -1},
+		},
+	})
+
+	tl, err := store.GetFlagTimeline(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetFlagTimeline: %v", err)
+	}
+	if len(tl.Events) != 3 {
+		t.Fatalf("events: got %+v", tl.Events)
+	}
+	if e := tl.Events[1]; e.Event != domain.FlagEventCaptured || e.OffsetMs != 90000 || e.PlayerID == nil || *e.PlayerID != alice.PlayerID || e.Name != "alice" {
+		t.Errorf("capture: got %+v", e)
+	}
+	if e := tl.Events[2]; e.ClientID != -1 || e.PlayerID != nil || e.Team != domain.TeamRed {
+		t.Errorf("auto return: got %+v", e)
+	}
+	if len(tl.Carriers) != 1 || tl.Carriers[0].PlayerID != alice.PlayerID || tl.Carriers[0].CarryMs != 45000 || tl.Carriers[0].Captures != 1 {
+		t.Errorf("carriers: got %+v", tl.Carriers)
+	}
+}
@@ -0,0 +1,40 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// mergeSuggestionInterval is how often the writer reruns the analysis
+// behind /api/admin/players/merge-suggestions. It looks back months of
+// sessions, so a few hours stale is fine.
+const mergeSuggestionInterval = 6 * time.Hour
+
+func (w *Writer) mergeSuggestionLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.refreshMergeSuggestions(ctx, time.Now())
+
+	ticker := time.NewTicker(mergeSuggestionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.refreshMergeSuggestions(ctx, now)
+		}
+	}
+}
+
+// refreshMergeSuggestions reruns the duplicate-player analysis.
+func (w *Writer) refreshMergeSuggestions(ctx context.Context, now time.Time) {
+	n, err := w.store.RefreshMergeSuggestions(ctx, now)
+	if err != nil {
+		log.Printf("hub: merge suggestions: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("hub: %d merge suggestions pending", n)
+	}
+}
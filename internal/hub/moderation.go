@@ -0,0 +1,32 @@
+package hub
+
+import (
+	"context"
+	"log"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleModerationIncident records a chat filter hit for the admin
+// moderation log. The collector has already acted on it.
+func (w *Writer) handleModerationIncident(ctx context.Context, serverID int64, data domain.ModerationIncidentData) {
+	inc := domain.ModerationIncident{
+		ServerID:  serverID,
+		Name:      data.Name,
+		Message:   data.Message,
+		Matched:   data.Matched,
+		Action:    data.Action,
+		CreatedAt: data.At,
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.GUID); ok {
+		inc.PlayerID = &id
+	}
+	if data.MatchUUID != "" {
+		if match, err := w.store.GetMatchByUUID(ctx, data.MatchUUID); err == nil && match != nil {
+			inc.MatchID = &match.ID
+		}
+	}
+	if err := w.store.RecordModerationIncident(ctx, inc); err != nil {
+		log.Printf("hub: RecordModerationIncident server=%d: %v", serverID, err)
+	}
+}
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+func TestHandleModerationIncidentRecords(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	pg, err := store.UpsertPlayerGUID(ctx, "GUID-RUDE", "rude", "rude", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	w.handleModerationIncident(ctx, srv.ID, domain.ModerationIncidentData{
+		GUID: "GUID-RUDE", Name: "rude", ClientNum: 3,
+		Message: "you badword", Matched: "badword", Action: "warn", At: now,
+	})
+
+	got, err := store.ListModerationIncidents(ctx, storage.ModerationFilter{PlayerID: pg.PlayerID, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListModerationIncidents: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d incidents, want 1", len(got))
+	}
+	if inc := got[0]; inc.ServerKey != "ffa" || inc.Matched != "badword" || inc.Action != "warn" || inc.MatchID != nil {
+		t.Errorf("incident = %+v", inc)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"net/netip"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -63,6 +64,16 @@ type RemotePoller struct {
 	// server.
 	warnedNonTrinity map[int64]string
 
+	// watched counts spectators per server (see Watch); those servers
+	// are also polled every fastPollInterval, with results going to
+	// matchSink only.
+	watched   map[int64]int
+	matchSink LiveEventSink
+
+	// sampledAt is when each server's pings or population were last
+	// stored; see sampleDue.
+	sampledAt map[int64]time.Time
+
 	stopOnce sync.Once
 	stopCh   chan struct{}
 	doneCh   chan struct{}
@@ -86,6 +97,8 @@ func NewRemotePoller(store *storage.Store, q StatusQuerier, interval time.Durati
 		conns:            conns,
 		statuses:         make(map[int64]*domain.ServerStatus),
 		warnedNonTrinity: make(map[int64]string),
+		watched:          make(map[int64]int),
+		sampledAt:        make(map[int64]time.Time),
 		stopCh:           make(chan struct{}),
 		doneCh:           make(chan struct{}),
 	}
@@ -98,8 +111,10 @@ func NewRemotePoller(store *storage.Store, q StatusQuerier, interval time.Durati
 // freshest is picked — that's ips[0] per the SourceConns contract.
 // Returns "" when a remote row has no minted creds or no connected
 // collector — the poll then falls into the standard offline path.
+// Query-only rows aren't hosted by their collector, so their address
+// is used as is.
 func (p *RemotePoller) pollTarget(r storage.RemoteServer) string {
-	if !r.IsRemote || p.conns == nil {
+	if !r.IsRemote || r.QueryOnly || p.conns == nil {
 		return r.Address
 	}
 	if r.UserPubKey == "" {
@@ -159,12 +174,46 @@ func (p *RemotePoller) GetAllStatuses() []domain.ServerStatus {
 	return out
 }
 
+// fastPollInterval is how often watched servers are polled for
+// spectators. One status packet per second per watched server is
+// negligible next to what the game itself sends each client.
+const fastPollInterval = time.Second
+
+// SetMatchSink attaches the sink for fast (watched-server) polls.
+// Safe to call before or after Start.
+func (p *RemotePoller) SetMatchSink(sink LiveEventSink) {
+	p.mu.Lock()
+	p.matchSink = sink
+	p.mu.Unlock()
+}
+
+// Watch adds serverID to the fast-poll set until the returned func is
+// called. Calls nest: the server stays watched while any caller
+// still holds an unwatch func.
+func (p *RemotePoller) Watch(serverID int64) (unwatch func()) {
+	p.mu.Lock()
+	p.watched[serverID]++
+	p.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			if p.watched[serverID]--; p.watched[serverID] <= 0 {
+				delete(p.watched, serverID)
+			}
+			p.mu.Unlock()
+		})
+	}
+}
+
 func (p *RemotePoller) run(ctx context.Context) {
 	defer close(p.doneCh)
 	p.pollAll(ctx)
 
 	t := time.NewTicker(p.interval)
 	defer t.Stop()
+	fast := time.NewTicker(fastPollInterval)
+	defer fast.Stop()
 	for {
 		select {
 		case <-p.stopCh:
@@ -173,90 +222,215 @@ func (p *RemotePoller) run(ctx context.Context) {
 			return
 		case <-t.C:
 			p.pollAll(ctx)
+		case <-fast.C:
+			p.pollWatched(ctx)
 		}
 	}
 }
 
-func (p *RemotePoller) pollAll(ctx context.Context) {
+// pollWatched fast-polls every watched server. No-op (and no DB
+// read) when nobody is spectating.
+func (p *RemotePoller) pollWatched(ctx context.Context) {
+	p.mu.RLock()
+	n := len(p.watched)
+	p.mu.RUnlock()
+	if n == 0 {
+		return
+	}
 	servers, err := p.store.ListPollableServers(ctx)
 	if err != nil {
 		log.Printf("hub.RemotePoller: list servers: %v", err)
 		return
 	}
 	for _, r := range servers {
-		var status *domain.ServerStatus
-		var err error
-		if target := p.pollTarget(r); target != "" {
-			status, err = p.querier.QueryStatus(target)
-		} else {
-			err = fmt.Errorf("no live collector connection for source %q", r.Source)
-		}
-		now := time.Now().UTC()
-		if err != nil || status == nil {
-			p.mu.Lock()
-			existing, ok := p.statuses[r.ID]
-			if !ok {
-				existing = &domain.ServerStatus{ServerID: r.ID, Key: r.Key, Source: r.Source, Address: r.Address}
-				p.statuses[r.ID] = existing
-			}
-			existing.Source = r.Source
-			existing.Online = false
-			existing.LastUpdated = now
-			// Preserve LastSeenAt — it represents the last successful
-			// UDP query, used to compute offline duration.
-			snapshot := *existing
-			sink := p.sink
-			p.mu.Unlock()
-			p.broadcast(sink, snapshot)
-			continue
-		}
-		status.ServerID = r.ID
-		status.Key = r.Key
-		status.Source = r.Source
-		status.Address = r.Address
-		status.LastUpdated = now
-		// Engine fingerprint gate: trinity-engine self-identifies via the
-		// `engine` infostring field (added to SVC_Info / SVC_Status by
-		// the fork). Stock ioquake3 has no such field. Anything that
-		// fails the prefix check is treated as offline so it never lands
-		// in live UI.
-		engine := status.ServerVars["engine"]
-		if !strings.HasPrefix(engine, trinityEnginePrefix) {
-			p.noteNonTrinity(r.ID, r.Source, r.Key, engine)
-			p.mu.Lock()
-			existing, ok := p.statuses[r.ID]
-			if !ok {
-				existing = &domain.ServerStatus{ServerID: r.ID, Key: r.Key, Source: r.Source, Address: r.Address}
-				p.statuses[r.ID] = existing
-			}
-			existing.Source = r.Source
-			existing.Online = false
-			existing.LastUpdated = now
-			snapshot := *existing
-			sink := p.sink
-			p.mu.Unlock()
-			p.broadcast(sink, snapshot)
-			continue
+		p.mu.RLock()
+		_, ok := p.watched[r.ID]
+		p.mu.RUnlock()
+		if ok {
+			p.pollOne(ctx, r, true)
 		}
-		// Once a server is verified, clear any prior warning so a future
-		// regression re-logs.
+	}
+}
+
+func (p *RemotePoller) pollAll(ctx context.Context) {
+	servers, err := p.store.ListPollableServers(ctx)
+	if err != nil {
+		log.Printf("hub.RemotePoller: list servers: %v", err)
+		return
+	}
+	for _, r := range servers {
+		p.pollOne(ctx, r, false)
+	}
+}
+
+// pollOne queries one server, caches the result, and hands a
+// server_update to the sink — or, for a fast (watched) poll, to the
+// match sink only, so regular /ws clients don't see the extra
+// traffic.
+func (p *RemotePoller) pollOne(ctx context.Context, r storage.RemoteServer, fast bool) {
+	var status *domain.ServerStatus
+	var err error
+	sent := time.Now()
+	if target := p.pollTarget(r); target != "" {
+		status, err = p.querier.QueryStatus(target)
+	} else {
+		err = fmt.Errorf("no live collector connection for source %q", r.Source)
+	}
+	rtt := time.Since(sent)
+	now := time.Now().UTC()
+	if err != nil || status == nil {
 		p.mu.Lock()
-		delete(p.warnedNonTrinity, r.ID)
+		existing, ok := p.statuses[r.ID]
+		if !ok {
+			existing = &domain.ServerStatus{ServerID: r.ID, Key: r.Key, Source: r.Source, Address: r.Address, QueryOnly: r.QueryOnly}
+			p.statuses[r.ID] = existing
+		}
+		existing.Source = r.Source
+		existing.Online = false
+		existing.LastUpdated = now
+		// Preserve LastSeenAt — it represents the last successful
+		// UDP query, used to compute offline duration.
+		snapshot := *existing
+		sink := p.sinkFor(fast)
 		p.mu.Unlock()
-
-		status.Online = true
-		seen := now
-		status.LastSeenAt = &seen
-		status.HumanCount = 0
-		status.BotCount = 0
-		p.enrichPlayers(ctx, r.ID, &status.HumanCount, &status.BotCount, status.Players)
+		p.broadcast(sink, snapshot)
+		return
+	}
+	status.ServerID = r.ID
+	status.Key = r.Key
+	status.Source = r.Source
+	status.Address = r.Address
+	status.LastUpdated = now
+	// Engine fingerprint gate: trinity-engine self-identifies via the
+	// `engine` infostring field (added to SVC_Info / SVC_Status by
+	// the fork). Stock ioquake3 has no such field. Anything that
+	// fails the prefix check is treated as offline so it never lands
+	// in live UI.
+	// Query-only servers run whatever engine they like.
+	engine := status.ServerVars["engine"]
+	if !r.QueryOnly && !strings.HasPrefix(engine, trinityEnginePrefix) {
+		p.noteNonTrinity(r.ID, r.Source, r.Key, engine)
 		p.mu.Lock()
-		p.statuses[r.ID] = status
-		snapshot := *status
-		sink := p.sink
+		existing, ok := p.statuses[r.ID]
+		if !ok {
+			existing = &domain.ServerStatus{ServerID: r.ID, Key: r.Key, Source: r.Source, Address: r.Address}
+			p.statuses[r.ID] = existing
+		}
+		existing.Source = r.Source
+		existing.Online = false
+		existing.LastUpdated = now
+		snapshot := *existing
+		sink := p.sinkFor(fast)
 		p.mu.Unlock()
 		p.broadcast(sink, snapshot)
+		return
+	}
+	// Once a server is verified, clear any prior warning so a future
+	// regression re-logs.
+	p.mu.Lock()
+	delete(p.warnedNonTrinity, r.ID)
+	p.mu.Unlock()
+
+	// The players are marked up below; the querier may still hold the
+	// slice it answered with.
+	status.Players = slices.Clone(status.Players)
+	status.Online = true
+	status.QueryOnly = r.QueryOnly
+	seen := now
+	status.LastSeenAt = &seen
+	status.HumanCount = 0
+	status.BotCount = 0
+	if r.QueryOnly {
+		// No presence to go by; nothing is known about the players
+		// beyond the status reply.
+		for i := range status.Players {
+			status.Players[i].IsBot = status.Players[i].Ping == 0
+		}
+		status.HumanCount, status.BotCount = countByPing(status.Players)
+	} else {
+		p.enrichPlayers(ctx, r.ID, &status.HumanCount, &status.BotCount, status.Players)
+	}
+	reconcileClock(status, rtt, p.loggedMatchStart(ctx, r.ID, status), now)
+	if !fast && p.sampleDue(r.ID, now) {
+		if r.QueryOnly {
+			p.recordPopulation(ctx, status, now)
+		} else {
+			p.samplePings(ctx, r.ID, status.Players, now)
+		}
+	}
+	p.mu.Lock()
+	p.statuses[r.ID] = status
+	snapshot := *status
+	sink := p.sinkFor(fast)
+	p.mu.Unlock()
+	p.broadcast(sink, snapshot)
+}
+
+// loggedMatchStart returns when the server's open match started, for
+// servers whose status has no level-time cvars to run a clock from.
+func (p *RemotePoller) loggedMatchStart(ctx context.Context, serverID int64, status *domain.ServerStatus) *time.Time {
+	if p.store == nil || status.ClockSource == domain.ClockServer || !clockRunning(status.MatchState) {
+		return nil
+	}
+	m, err := p.store.GetCurrentMatch(ctx, serverID)
+	if err != nil {
+		log.Printf("hub.RemotePoller: current match for server %d: %v", serverID, err)
+		return nil
+	}
+	if m == nil {
+		return nil
+	}
+	return &m.StartedAt
+}
+
+// sampleInterval is how often each server's pings, or a query-only
+// server's population, are stored.
+const sampleInterval = time.Minute
+
+// sampleDue reports whether serverID hasn't been sampled for
+// sampleInterval, and if so counts it as sampled now.
+func (p *RemotePoller) sampleDue(serverID int64, now time.Time) bool {
+	if p.store == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.Sub(p.sampledAt[serverID]) < sampleInterval {
+		return false
+	}
+	p.sampledAt[serverID] = now
+	return true
+}
+
+// samplePings stores the humans' pings against their open sessions.
+// Pings of 0 or 999 are left out: the engine reports those for clients
+// still connecting.
+func (p *RemotePoller) samplePings(ctx context.Context, serverID int64, players []domain.PlayerStatus, now time.Time) {
+	pings := make(map[string]int)
+	for _, ps := range players {
+		if ps.IsBot || ps.GUID == "" || ps.Ping <= 0 || ps.Ping >= 999 {
+			continue
+		}
+		pings[ps.GUID] = ps.Ping
+	}
+	if err := p.store.RecordPingSamples(ctx, serverID, now, pings); err != nil {
+		log.Printf("hub.RemotePoller: record pings for server %d: %v", serverID, err)
+	}
+}
+
+// recordPopulation stores a query-only server's player counts.
+func (p *RemotePoller) recordPopulation(ctx context.Context, status *domain.ServerStatus, now time.Time) {
+	if err := p.store.RecordPopulation(ctx, status.ServerID, now, status.HumanCount, status.BotCount, status.MaxClients); err != nil {
+		log.Printf("hub.RemotePoller: record population for server %d: %v", status.ServerID, err)
+	}
+}
+
+// sinkFor picks the destination for a poll result. Caller holds p.mu.
+func (p *RemotePoller) sinkFor(fast bool) LiveEventSink {
+	if fast {
+		return p.matchSink
 	}
+	return p.sink
 }
 
 // noteNonTrinity logs the first time we see a non-trinity engine
@@ -278,6 +452,8 @@ func (p *RemotePoller) noteNonTrinity(serverID int64, source, key, engine string
 	}
 }
 
+// broadcast sends status to sink. Live feeds are open to anyone, so
+// they get the public view.
 func (p *RemotePoller) broadcast(sink LiveEventSink, status domain.ServerStatus) {
 	if sink == nil {
 		return
@@ -286,7 +462,7 @@ func (p *RemotePoller) broadcast(sink LiveEventSink, status domain.ServerStatus)
 		Type:      domain.EventServerUpdate,
 		ServerID:  status.ServerID,
 		Timestamp: status.LastUpdated,
-		Data:      status,
+		Data:      status.Public(),
 	})
 }
 
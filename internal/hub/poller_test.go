@@ -410,3 +410,132 @@ func TestRemotePollerHidesNonTrinityEngine(t *testing.T) {
 		})
 	}
 }
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func (s *recordingSink) Broadcast(e domain.Event) {
+	s.mu.Lock()
+	s.events = append(s.events, e)
+	s.mu.Unlock()
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func TestRemotePollerWatchRoutesFastPollsToMatchSink(t *testing.T) {
+	_, store := newTestWriter(t)
+	ctx := context.Background()
+	reg := domain.Registration{
+		Source:  "remote",
+		Servers: []domain.RegdServer{{LocalID: 1, Key: "r1", Address: "r.example:27960"}},
+	}
+	if err := store.CreateSource(ctx, reg.Source, true, seedOwnerID(t, store)); err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	if err := store.UpsertRemoteServers(ctx, reg); err != nil {
+		t.Fatalf("upsert roster: %v", err)
+	}
+	id, _ := store.ResolveServerIDForSource(ctx, reg.Source, 1)
+	if err := store.SetServerHandshakeRequired(ctx, id, true); err != nil {
+		t.Fatalf("SetServerHandshakeRequired: %v", err)
+	}
+
+	q := &fakeQuerier{responses: map[string]*domain.ServerStatus{
+		"r.example:27960": {Map: "q3ctf1", ServerVars: map[string]string{"engine": "trinity-engine/0.4.2"}},
+	}}
+	poller := NewRemotePoller(store, q, time.Hour, nil, nil, nil)
+	regular, match := &recordingSink{}, &recordingSink{}
+	poller.SetSink(regular)
+	poller.SetMatchSink(match)
+
+	poller.pollWatched(ctx)
+	if len(q.calls) != 0 {
+		t.Fatalf("polled %d times with nothing watched", len(q.calls))
+	}
+
+	unwatchA := poller.Watch(id)
+	unwatchB := poller.Watch(id)
+	poller.pollWatched(ctx)
+	if match.count() != 1 || regular.count() != 0 {
+		t.Fatalf("after fast poll: match=%d regular=%d, want 1/0", match.count(), regular.count())
+	}
+
+	// Still watched while any spectator remains.
+	unwatchA()
+	unwatchA()
+	poller.pollWatched(ctx)
+	if match.count() != 2 {
+		t.Fatalf("match sink got %d events, want 2", match.count())
+	}
+
+	unwatchB()
+	poller.pollWatched(ctx)
+	if match.count() != 2 {
+		t.Errorf("fast poll ran after last unwatch")
+	}
+}
+
+// TestRemotePollerQueryOnly checks a query-only roster entry is polled
+// at its own address, with no handshake and on any engine, counted by
+// ping, and sampled into population history.
+func TestRemotePollerQueryOnly(t *testing.T) {
+	_, store := newTestWriter(t)
+	ctx := context.Background()
+	reg := domain.Registration{
+		Source:  "remote",
+		Servers: []domain.RegdServer{{LocalID: 1, Key: "friends", Address: "friends.example:27960", QueryOnly: true}},
+	}
+	if err := store.CreateSource(ctx, reg.Source, true, seedOwnerID(t, store)); err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	if err := store.UpsertRemoteServers(ctx, reg); err != nil {
+		t.Fatalf("upsert roster: %v", err)
+	}
+
+	q := &fakeQuerier{responses: map[string]*domain.ServerStatus{
+		"friends.example:27960": {
+			Map:        "q3dm6",
+			MaxClients: 12,
+			ServerVars: map[string]string{"engine": "ioq3 1.36"},
+			Players:    []domain.PlayerStatus{{Name: "alice", Ping: 48}, {Name: "Sarge", Ping: 0}},
+		},
+	}}
+	// No collector connection: a query-only server is polled anyway.
+	poller := NewRemotePoller(store, q, 50*time.Millisecond, nil, nil, fakeSourceConns{})
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	poller.Start(pctx)
+	defer poller.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := poller.GetAllStatuses(); len(s) > 0 && s[0].Online {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	statuses := poller.GetAllStatuses()
+	if len(statuses) != 1 || !statuses[0].Online {
+		t.Fatalf("statuses = %+v, want one online", statuses)
+	}
+	st := statuses[0]
+	if !st.QueryOnly || st.HumanCount != 1 || st.BotCount != 1 || !st.Players[1].IsBot {
+		t.Errorf("status = %+v", st)
+	}
+
+	// Periods end before the current second; step past it.
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	pop, err := store.GetServerPopulation(ctx, st.ServerID, "day")
+	if err != nil {
+		t.Fatalf("GetServerPopulation: %v", err)
+	}
+	if len(pop.Hours) != 1 || pop.Hours[0].PeakHumans != 1 || pop.Hours[0].MaxClients != 12 {
+		t.Errorf("population = %+v", pop.Hours)
+	}
+}
@@ -0,0 +1,68 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordNotifier is told when a match that just ended broke a map
+// record, so it can be announced to the players who were in it. Called
+// from the writer's consume goroutine, so implementations must not
+// block.
+type RecordNotifier interface {
+	NotifyRecord(serverID int64, clientIDs []int, message string)
+}
+
+// WithRecordNotifier announces broken records in-game.
+func WithRecordNotifier(n RecordNotifier) Option {
+	return func(w *Writer) { w.recordNotifier = n }
+}
+
+// updateRecords folds the match into the map's record boards and
+// announces any records it broke. Stale match_ends update the boards
+// silently, like achievements.
+func (w *Writer) updateRecords(ctx context.Context, match *domain.Match, endedAt time.Time, participants []matchEndParticipant) {
+	broken, err := w.store.UpdateRecordsForMatch(ctx, match.ID)
+	if err != nil {
+		log.Printf("hub: records for match %d: %v", match.ID, err)
+		return
+	}
+	if len(broken) == 0 {
+		return
+	}
+	var clientIDs []int
+	for _, pp := range participants {
+		clientIDs = append(clientIDs, pp.clientID)
+	}
+	announce := w.recordNotifier != nil && len(clientIDs) > 0 && time.Since(endedAt) < achievementAnnounceWindow
+	for _, b := range broken {
+		log.Printf("hub: player %d set the %s %s record on %s: %d (was %d)", b.Player.ID, b.GameType, b.Category, b.MapName, b.Value, b.Previous.Value)
+		if announce {
+			w.recordNotifier.NotifyRecord(match.ServerID, clientIDs, recordMessage(b))
+		}
+	}
+}
+
+// recordMessage is the in-game announcement for a broken record, in
+// Quake 3 color codes.
+func recordMessage(b domain.BrokenRecord) string {
+	label := b.Category
+	if c, ok := domain.RecordCategoryByID(b.Category); ok {
+		label = c.Name
+	}
+	return fmt.Sprintf("^3New %s %s record! ^7%s^3: %s %s (was %s by ^7%s^3)",
+		b.MapName, b.GameType, b.Player.Name, label,
+		formatRecordValue(b.Category, b.Value), formatRecordValue(b.Category, b.Previous.Value), b.Previous.Player.Name)
+}
+
+// formatRecordValue renders a record value for chat.
+func formatRecordValue(category string, value int64) string {
+	if category == domain.RecordFastestCap {
+		return fmt.Sprintf("%.1fs", float64(value)/1000)
+	}
+	return fmt.Sprint(value)
+}
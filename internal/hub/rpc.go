@@ -5,11 +5,14 @@ import (
 	"time"
 )
 
-// RPCClient is the collector → hub contract for greet/claim/link.
+// RPCClient is the collector → hub contract for greet/claim/link and
+// the stats chat commands.
 type RPCClient interface {
 	Greet(ctx context.Context, req GreetRequest) (GreetReply, error)
 	Claim(ctx context.Context, req ClaimRequest) (ClaimReply, error)
 	Link(ctx context.Context, req LinkRequest) (LinkReply, error)
+	Stats(ctx context.Context, req StatsRequest) (StatsReply, error)
+	Top(ctx context.Context, req TopRequest) (TopReply, error)
 }
 
 // AuthResult reports whether the optional auth info inside a Trinity
@@ -34,6 +37,9 @@ type GreetRequest struct {
 	ClientEngine  string     `json:"client_engine,omitempty"`
 	ClientVersion string     `json:"client_version,omitempty"`
 	Auth          *AuthProof `json:"auth,omitempty"`
+	// WantRank asks for GreetReply.Rank, which costs a leaderboard
+	// query; set when the greeting shows it.
+	WantRank bool `json:"want_rank,omitempty"`
 }
 
 // AuthProof is the optional trailing portion of the Trinity handshake
@@ -50,16 +56,22 @@ type AuthProof struct {
 
 // GreetReply: IsVerified means the GUID is linked to a user account
 // (green-checkmark state). GUIDLinked means this greet just did the
-// linking. AuthResult reports session-scoped auth outcome.
+// linking. AuthResult reports session-scoped auth outcome. DaysAway is
+// non-zero only when the player is coming back after at least
+// domain.ReturningPlayerThreshold. Rank is as in StatsReply, filled in
+// only for WantRank.
 type GreetReply struct {
 	AuthResult       AuthResult `json:"auth_result"`
 	CanonicalName    string     `json:"canonical_name"`
 	Claimed          bool       `json:"claimed"`
 	IsVerified       bool       `json:"is_verified"`
 	IsAdmin          bool       `json:"is_admin"`
+	IsVIP            bool       `json:"is_vip,omitempty"`
 	GUIDLinked       bool       `json:"guid_linked"`
 	KDRatio          float64    `json:"kd_ratio"`
 	CompletedMatches int64      `json:"completed_matches"`
+	DaysAway         int        `json:"days_away,omitempty"`
+	Rank             int        `json:"rank,omitempty"`
 }
 
 type ClaimStatus string
@@ -102,3 +114,71 @@ type LinkReply struct {
 	Status  LinkStatus `json:"status"`
 	Message string     `json:"message,omitempty"`
 }
+
+type StatsStatus string
+
+const (
+	StatsOK        StatsStatus = "ok"
+	StatsNotFound  StatsStatus = "not_found"
+	StatsAmbiguous StatsStatus = "ambiguous"
+	StatsError     StatsStatus = "error"
+)
+
+// StatsRequest looks up a player by Name when set, otherwise by GUID
+// (the asking player's own stats).
+type StatsRequest struct {
+	GUID string `json:"guid"`
+	Name string `json:"name,omitempty"`
+}
+
+// StatsReply carries all-time stats. Rank is the player's place on the
+// all-time frags leaderboard, or 0 when they aren't on it. Matches
+// lists a few candidate names when the status is StatsAmbiguous.
+type StatsReply struct {
+	Status           StatsStatus `json:"status"`
+	Name             string      `json:"name,omitempty"`
+	Frags            int64       `json:"frags"`
+	Deaths           int64       `json:"deaths"`
+	KDRatio          float64     `json:"kd_ratio"`
+	CompletedMatches int64       `json:"completed_matches"`
+	Rank             int         `json:"rank,omitempty"`
+	Matches          []string    `json:"matches,omitempty"`
+	Message          string      `json:"message,omitempty"`
+}
+
+type TopStatus string
+
+const (
+	TopOK              TopStatus = "ok"
+	TopUnknownCategory TopStatus = "unknown_category"
+	TopError           TopStatus = "error"
+)
+
+// TopCategories maps the !top argument to its leaderboard category.
+// An empty argument means frags.
+var TopCategories = map[string]string{
+	"":         "frags",
+	"frags":    "frags",
+	"kd":       "kd_ratio",
+	"captures": "captures",
+}
+
+// TopRequest asks for the head of an all-time leaderboard. Category
+// is a TopCategories key.
+type TopRequest struct {
+	Category string `json:"category,omitempty"`
+}
+
+// TopEntry is one leaderboard row; Value is the ranked figure (frags,
+// K/D, or captures).
+type TopEntry struct {
+	Rank  int     `json:"rank"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+type TopReply struct {
+	Status  TopStatus  `json:"status"`
+	Entries []TopEntry `json:"entries,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// seasonInterval is how often the writer looks for seasons that have
+// ended. Standings freeze within this long of a season's end.
+const seasonInterval = 15 * time.Minute
+
+func (w *Writer) seasonLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.finalizeSeasons(ctx, time.Now())
+
+	ticker := time.NewTicker(seasonInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.finalizeSeasons(ctx, now)
+		}
+	}
+}
+
+// finalizeSeasons freezes the standings of seasons that have ended.
+func (w *Writer) finalizeSeasons(ctx context.Context, now time.Time) {
+	finalized, err := w.store.FinalizeEndedSeasons(ctx, now.UTC())
+	for _, se := range finalized {
+		if se.WinnerID != nil {
+			log.Printf("hub: season %q finalized, won by player %d", se.Name, *se.WinnerID)
+		} else {
+			log.Printf("hub: season %q finalized with no one placed", se.Name)
+		}
+	}
+	if err != nil {
+		log.Printf("hub: finalizing seasons: %v", err)
+	}
+}
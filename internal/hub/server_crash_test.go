@@ -0,0 +1,47 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestHandleServerCrashRecordsAndEndsSessions(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	pg, err := store.UpsertPlayerGUID(ctx, "GUID-A", "alice", "alice", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if err := store.CreateSession(ctx, &domain.Session{PlayerGUIDID: pg.ID, ServerID: srv.ID, JoinedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	w.handleServerCrash(ctx, srv.ID, domain.ServerCrashData{
+		CrashedAt: now,
+		Reason:    domain.CrashNoShutdown,
+		LogLines:  []string{"2026-05-01T11:59:59Z Kill: 0 1 10: alice killed bob by MOD_RAILGUN"},
+	})
+
+	got, err := store.ListServerIncidents(ctx, srv.ID, 10)
+	if err != nil {
+		t.Fatalf("ListServerIncidents: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d incidents, want 1", len(got))
+	}
+	if inc := got[0]; inc.Reason != domain.CrashNoShutdown || !inc.OccurredAt.Equal(now) || len(inc.LogLines) != 1 {
+		t.Errorf("incident = %+v", inc)
+	}
+	if sess, err := store.GetOpenSessionForPlayer(ctx, pg.ID, srv.ID); err == nil && sess != nil {
+		t.Errorf("session %d still open after crash", sess.ID)
+	}
+}
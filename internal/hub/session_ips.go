@@ -0,0 +1,98 @@
+package hub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/netip"
+	"time"
+)
+
+// ipScrubInterval is how often session IPs past the retention window
+// are scrubbed. Retention is days long, so hourly is plenty.
+const ipScrubInterval = time.Hour
+
+// hashedIPPrefix marks an IP replaced by HashIP, so it can't be
+// mistaken for an address.
+const hashedIPPrefix = "hmac:"
+
+// WithSessionIPs sets how players' session IPs are kept. With store
+// false, new sessions are recorded without one and stored IPs are
+// blanked. Otherwise IPs older than retention are replaced by
+// scrub(ip). Without it IPs are kept as they are.
+func WithSessionIPs(store bool, retention time.Duration, scrub func(ip string) string) Option {
+	return func(w *Writer) {
+		w.dropIPs = !store
+		w.ipRetention = retention
+		w.ipScrub = scrub
+	}
+}
+
+// TruncateIP zeroes all but the IPv4 /24 or IPv6 /48 of ip, the same
+// ranges merge suggestions group players by. Anything that doesn't
+// parse comes back empty.
+func TruncateIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
+
+// HashIP returns a scrubber that replaces an IP with a hash keyed by
+// key. The same address still hashes the same, but without the key
+// there's no way back: a plain hash of every IPv4 address takes
+// seconds to build.
+func HashIP(key []byte) func(ip string) string {
+	return func(ip string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(ip))
+		return hashedIPPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+}
+
+func (w *Writer) ipScrubLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.scrubSessionIPs(ctx, time.Now())
+
+	ticker := time.NewTicker(ipScrubInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.scrubSessionIPs(ctx, now)
+		}
+	}
+}
+
+// scrubSessionIPs scrubs the IPs of sessions that joined before the
+// retention window, or blanks them all when IPs aren't being stored.
+func (w *Writer) scrubSessionIPs(ctx context.Context, now time.Time) {
+	var n int64
+	var err error
+	if w.dropIPs {
+		n, err = w.store.ClearSessionIPs(ctx, now)
+	} else {
+		n, err = w.store.ScrubSessionIPs(ctx, now.Add(-w.ipRetention), now, w.ipScrub)
+	}
+	if err != nil {
+		log.Printf("hub: scrub session IPs: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("hub: scrubbed %d session IPs", n)
+	}
+}
@@ -0,0 +1,88 @@
+package hub
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestTruncateIP(t *testing.T) {
+	for in, want := range map[string]string{
+		"203.0.113.77":          "203.0.113.0",
+		"::ffff:203.0.113.77":   "203.0.113.0",
+		"2001:db8:1234:5678::1": "2001:db8:1234::",
+		"bot":                   "",
+	} {
+		if got := TruncateIP(in); got != want {
+			t.Errorf("TruncateIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSessionIPPolicy(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	pg, err := store.UpsertPlayerGUID(ctx, "GUID-ALICE", "alice", "alice", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	join := func(at time.Time) {
+		t.Helper()
+		w.handlePlayerJoin(ctx, srv.ID, domain.PlayerJoinData{GUID: "GUID-ALICE", Name: "alice", CleanName: "alice", IP: "203.0.113.77", JoinedAt: at})
+		sess, err := store.GetOpenSessionForPlayer(ctx, pg.ID, srv.ID)
+		if err != nil || sess == nil {
+			t.Fatalf("GetOpenSessionForPlayer: %v", err)
+		}
+		if err := store.EndSession(ctx, sess.ID, at.Add(time.Hour)); err != nil {
+			t.Fatalf("EndSession: %v", err)
+		}
+	}
+	ips := func() []string {
+		t.Helper()
+		sessions, err := store.GetPlayerSessions(ctx, pg.PlayerID, 100, nil)
+		if err != nil {
+			t.Fatalf("GetPlayerSessions: %v", err)
+		}
+		var out []string
+		for _, s := range sessions {
+			out = append(out, s.IPAddress)
+		}
+		return out
+	}
+
+	join(now.Add(-40 * 24 * time.Hour))
+	join(now.Add(-2 * time.Hour))
+
+	WithSessionIPs(true, 30*24*time.Hour, HashIP([]byte("k")))(w)
+	w.scrubSessionIPs(ctx, now)
+	got := ips()
+	if len(got) != 2 || got[0] != "203.0.113.77" || !strings.HasPrefix(got[1], hashedIPPrefix) {
+		t.Fatalf("after hash scrub: %v, want recent kept and old hashed", got)
+	}
+	hashed := got[1]
+
+	// Scrubbed sessions aren't scrubbed again.
+	WithSessionIPs(true, 30*24*time.Hour, TruncateIP)(w)
+	w.scrubSessionIPs(ctx, now)
+	if got := ips(); got[1] != hashed {
+		t.Errorf("rescrubbed: %q, want %q", got[1], hashed)
+	}
+
+	WithSessionIPs(false, 0, nil)(w)
+	w.scrubSessionIPs(ctx, now)
+	join(now.Add(-time.Hour))
+	for _, ip := range ips() {
+		if ip != "" {
+			t.Errorf("IP %q kept with store off", ip)
+		}
+	}
+}
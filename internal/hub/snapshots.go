@@ -0,0 +1,61 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// snapshotInterval is how often the stats_snapshots job runs. Trends
+// are day-grained, so hourly keeps today's bucket reasonably fresh.
+const snapshotInterval = time.Hour
+
+// snapshotLookback is how many days before the newest snapshot each
+// run rewrites, so matches that arrive late (a remote collector
+// catching up after an outage) still land in their day's bucket.
+const snapshotLookback = 2
+
+func (w *Writer) statsSnapshotLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.snapshotStats(ctx, time.Now())
+
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.snapshotStats(ctx, now)
+		}
+	}
+}
+
+// snapshotStats brings stats_snapshots up to date through today. The
+// first run on an empty table backfills from the first match.
+func (w *Writer) snapshotStats(ctx context.Context, now time.Time) {
+	from, err := w.store.LatestSnapshotDay(ctx)
+	if err != nil {
+		log.Printf("hub: stats snapshot: %v", err)
+		return
+	}
+	if from.IsZero() {
+		if from, err = w.store.EarliestMatchDay(ctx); err != nil {
+			log.Printf("hub: stats snapshot: %v", err)
+			return
+		}
+		if from.IsZero() {
+			return
+		}
+	} else {
+		from = from.AddDate(0, 0, -snapshotLookback)
+	}
+	to := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	n, err := w.store.SnapshotStats(ctx, from, to)
+	if err != nil {
+		log.Printf("hub: stats snapshot %s..%s: %v", from.Format("2006-01-02"), to.Format("2006-01-02"), err)
+		return
+	}
+	log.Printf("hub: stats snapshot %s..%s wrote %d rows", from.Format("2006-01-02"), to.Format("2006-01-02"), n)
+}
@@ -0,0 +1,40 @@
+package hub
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// statFlagInterval is how often the writer reruns the anomaly analysis
+// behind /api/admin/stat-flags. Flags wait for an admin anyway, so an
+// hour's delay costs nothing.
+const statFlagInterval = time.Hour
+
+func (w *Writer) statFlagLoop(ctx context.Context) {
+	defer w.wg.Done()
+	w.refreshStatFlags(ctx, time.Now())
+
+	ticker := time.NewTicker(statFlagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.refreshStatFlags(ctx, now)
+		}
+	}
+}
+
+// refreshStatFlags reruns the anomaly analysis.
+func (w *Writer) refreshStatFlags(ctx context.Context, now time.Time) {
+	n, err := w.store.RefreshStatFlags(ctx, now)
+	if err != nil {
+		log.Printf("hub: stat flags: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("hub: %d new stat flags to review", n)
+	}
+}
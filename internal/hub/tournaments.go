@@ -0,0 +1,21 @@
+package hub
+
+import (
+	"context"
+	"log"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// attachTournamentMatch settles the tournament pairing a finished game
+// decides, if there is one.
+func (w *Writer) attachTournamentMatch(ctx context.Context, match *domain.Match) {
+	tournamentID, pairingID, err := w.store.AttachTournamentMatch(ctx, match.ID)
+	if err != nil {
+		log.Printf("hub: tournament attach for match %d: %v", match.ID, err)
+		return
+	}
+	if pairingID != 0 {
+		log.Printf("hub: match %d settled tournament %d pairing %d", match.ID, tournamentID, pairingID)
+	}
+}
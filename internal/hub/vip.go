@@ -0,0 +1,31 @@
+package hub
+
+import (
+	"context"
+	"log"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// handleVIPKick records a reserved-slot kick for the admin audit log.
+// The collector has already kicked them.
+func (w *Writer) handleVIPKick(ctx context.Context, serverID int64, data domain.VIPKickData) {
+	kick := domain.VIPKick{
+		ServerID:    serverID,
+		VIPName:     data.VIPName,
+		KickedName:  data.KickedName,
+		KickedIsBot: data.KickedIsBot,
+		CreatedAt:   data.At,
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.VIPGUID); ok {
+		kick.VIPPlayerID = &id
+	}
+	if id, ok := w.resolveGUIDPlayerID(ctx, data.KickedGUID); ok {
+		kick.KickedPlayerID = &id
+	}
+	log.Printf("audit: vip_kick server=%d vip=%q kicked=%q client=%d bot=%t",
+		serverID, domain.CleanQ3Name(data.VIPName), domain.CleanQ3Name(data.KickedName), data.KickedClient, data.KickedIsBot)
+	if err := w.store.RecordVIPKick(ctx, kick); err != nil {
+		log.Printf("hub: RecordVIPKick server=%d: %v", serverID, err)
+	}
+}
@@ -0,0 +1,56 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+	"github.com/ernie/trinity-tracker/internal/storage"
+)
+
+func TestVIPGreetAndKick(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	vip, err := store.UpsertPlayerGUID(ctx, "GUID-VIP", "boss", "boss", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// Only a linked player can be a VIP.
+	if err := store.SetPlayerVIP(ctx, vip.PlayerID, true); err != storage.ErrPlayerNotLinked {
+		t.Fatalf("SetPlayerVIP unlinked: got %v", err)
+	}
+	if err := store.CreateUser(ctx, "boss", "hash", false, &vip.PlayerID); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := store.SetPlayerVIP(ctx, vip.PlayerID, true); err != nil {
+		t.Fatalf("SetPlayerVIP: %v", err)
+	}
+	reply, err := w.Greet(ctx, GreetRequest{ServerID: srv.ID, GUID: "GUID-VIP", ClientName: "boss", CleanName: "boss"})
+	if err != nil || !reply.IsVIP {
+		t.Fatalf("Greet: got %+v, %v", reply, err)
+	}
+
+	w.handleVIPKick(ctx, srv.ID, domain.VIPKickData{
+		VIPGUID: "GUID-VIP", VIPName: "boss",
+		KickedGUID: "BOT-1", KickedName: "Sarge", KickedClient: 5, KickedIsBot: true, At: now,
+	})
+	got, err := store.ListVIPKicks(ctx, srv.ID, 10)
+	if err != nil {
+		t.Fatalf("ListVIPKicks: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d kicks, want 1", len(got))
+	}
+	if k := got[0]; k.ServerKey != "ffa" || k.VIPPlayerID == nil || *k.VIPPlayerID != vip.PlayerID ||
+		k.KickedPlayerID != nil || !k.KickedIsBot {
+		t.Errorf("kick = %+v", k)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,27 @@ type Writer struct {
 
 	publisher FactPublisher
 
+	returnNotifier ReturnNotifier
+
+	achievementNotifier AchievementNotifier
+
+	recordNotifier RecordNotifier
+
+	chatRetention time.Duration
+
+	// Session IP policy; see WithSessionIPs. Neither set keeps IPs
+	// as they are.
+	dropIPs     bool
+	ipRetention time.Duration
+	ipScrub     func(ip string) string
+
+	// Health ping; see WithHealthPing. healthProblem is the last
+	// reason the hub was unhealthy, only touched by the ping loop.
+	healthPingURL      string
+	healthPingInterval time.Duration
+	healthStaleAfter   time.Duration
+	healthProblem      string
+
 	presence *Presence
 
 	sources *SourceRegistry
@@ -67,6 +89,19 @@ func WithFactPublisher(p FactPublisher) Option {
 	return func(w *Writer) { w.publisher = p }
 }
 
+// ReturnNotifier is told when Greet recognizes a player coming back
+// after at least domain.ReturningPlayerThreshold. Called on the RPC
+// path, so implementations must not block.
+type ReturnNotifier interface {
+	NotifyReturn(serverID int64, rp domain.ReturningPlayer)
+}
+
+// WithReturnNotifier announces comebacks somewhere outside the game
+// (e.g. a Discord channel).
+func WithReturnNotifier(n ReturnNotifier) Option {
+	return func(w *Writer) { w.returnNotifier = n }
+}
+
 const eventBufferSize = 1024
 
 func NewWriter(store *storage.Store, opts ...Option) *Writer {
@@ -180,6 +215,30 @@ func (w *Writer) Start(ctx context.Context) {
 	go w.run(ctx)
 	w.wg.Add(1)
 	go w.linkCodeCleanupLoop(ctx)
+	w.wg.Add(1)
+	go w.statsSnapshotLoop(ctx)
+	w.wg.Add(1)
+	go w.anonymizeLoop(ctx)
+	w.wg.Add(1)
+	go w.mergeSuggestionLoop(ctx)
+	w.wg.Add(1)
+	go w.statFlagLoop(ctx)
+	w.wg.Add(1)
+	go w.clanTagLoop(ctx)
+	w.wg.Add(1)
+	go w.seasonLoop(ctx)
+	if w.chatRetention > 0 {
+		w.wg.Add(1)
+		go w.chatPruneLoop(ctx)
+	}
+	if w.dropIPs || w.ipScrub != nil {
+		w.wg.Add(1)
+		go w.ipScrubLoop(ctx)
+	}
+	if w.healthPingURL != "" {
+		w.wg.Add(1)
+		go w.healthPingLoop(ctx)
+	}
 }
 
 // Stop drains the consume goroutine. Safe to call more than once.
@@ -206,8 +265,47 @@ func (w *Writer) Publish(e domain.FactEvent) error {
 func (w *Writer) run(ctx context.Context) {
 	defer w.wg.Done()
 	for e := range w.events {
+		if len(w.events) == 0 {
+			w.dispatch(ctx, e)
+		} else {
+			w.dispatchBacklog(ctx, e)
+		}
+	}
+}
+
+// maxBatchEvents caps how many events share a storage batch. The batch
+// holds the store's connection, so API reads wait for its commit.
+const maxBatchEvents = 500
+
+// dispatchBacklog applies e and the events queued behind it in one
+// storage batch. A backlog is what a collector replaying its log at
+// startup produces, and one commit for many events is what makes that
+// replay quick; events arriving one at a time are applied as before.
+func (w *Writer) dispatchBacklog(ctx context.Context, e domain.FactEvent) {
+	bctx, batch, err := w.store.BeginBatch(ctx)
+	if err != nil {
+		log.Printf("hub: starting write batch: %v", err)
 		w.dispatch(ctx, e)
+		return
+	}
+	defer batch.Rollback()
+	w.dispatch(bctx, e)
+	for n := 1; n < maxBatchEvents && len(w.events) > 0; n++ {
+		next, ok := <-w.events
+		if !ok {
+			break
+		}
+		w.dispatch(bctx, next)
 	}
+	if err := batch.Commit(); err != nil {
+		log.Printf("hub: committing write batch: %v", err)
+	}
+}
+
+// Dispatch applies e at once, for callers that run without Start
+// (trinity import-logs).
+func (w *Writer) Dispatch(ctx context.Context, e domain.FactEvent) {
+	w.dispatch(ctx, e)
 }
 
 func (w *Writer) dispatch(ctx context.Context, e domain.FactEvent) {
@@ -232,8 +330,18 @@ func (w *Writer) dispatch(ctx context.Context, e domain.FactEvent) {
 		w.handleServerStartup(ctx, e.ServerID, data)
 	case domain.ServerShutdownData:
 		w.handleServerShutdown(ctx, e.ServerID, data)
+	case domain.ServerCrashData:
+		w.handleServerCrash(ctx, e.ServerID, data)
 	case domain.DemoFinalizedData:
 		w.handleDemoFinalized(ctx, data)
+	case domain.ChatMessageData:
+		w.handleChatMessage(ctx, data)
+	case domain.ModerationIncidentData:
+		w.handleModerationIncident(ctx, e.ServerID, data)
+	case domain.VIPKickData:
+		w.handleVIPKick(ctx, e.ServerID, data)
+	case domain.AdminCommandData:
+		w.handleAdminCommand(ctx, e.ServerID, data)
 	default:
 		log.Printf("hub.Writer: received %s event for server %d (dispatch not yet implemented)",
 			e.Type, e.ServerID)
@@ -326,6 +434,9 @@ func (w *Writer) handleMatchStart(ctx context.Context, serverID int64, data doma
 		return
 	}
 	log.Printf("hub: match_start created match %d uuid=%s server=%d map=%s", match.ID, data.MatchUUID, serverID, data.MapName)
+	if err := w.store.RecordWarmupStats(ctx, match.ID, data.Warmup); err != nil {
+		log.Printf("hub: RecordWarmupStats for match %d: %v", match.ID, err)
+	}
 }
 
 // handleMatchEnd flushes per-player stats and closes the match row.
@@ -340,28 +451,83 @@ func (w *Writer) handleMatchEnd(ctx context.Context, data domain.MatchEndData) {
 		return
 	}
 
-	flushed := 0
+	// Resolve GUIDs first: the flush transaction holds the store's only
+	// connection, so it can't look anything up.
+	type resolved struct {
+		p  domain.MatchEndPlayer
+		pg *domain.PlayerGUID
+	}
+	var players []resolved
 	for _, p := range data.Players {
 		pg, err := w.store.GetPlayerGUIDByGUID(ctx, p.GUID)
 		if err != nil || pg == nil {
 			log.Printf("hub: match_end cannot resolve GUID %s: %v", p.GUID, err)
 			continue
 		}
-		if err := w.store.FlushMatchPlayerStats(ctx, match.ID, pg.ID, p.ClientID,
-			p.Frags, p.Deaths, p.Completed, p.Score, p.Team, p.Model, p.Skill, p.Victory,
-			p.Captures, p.FlagReturns, p.Assists, p.Impressives, p.Excellents, p.Humiliations, p.Defends,
-			p.IsBot, p.JoinedLate, p.JoinedAt, p.IsVR); err != nil {
-			log.Printf("hub: FlushMatchPlayerStats for GUID %s: %v", p.GUID, err)
-			continue
-		}
-		flushed++
+		players = append(players, resolved{p, pg})
 	}
 
-	if err := w.store.EndMatch(ctx, match.ID, data.EndedAt, data.ExitReason, data.RedScore, data.BlueScore); err != nil {
-		log.Printf("hub: EndMatch failed for UUID %s: %v", data.MatchUUID, err)
+	flushed := 0
+	var participants []matchEndParticipant
+	ok, err := w.store.FlushMatch(ctx, match, data, func(tx *storage.MatchTx) {
+		for _, r := range players {
+			p, pg := r.p, r.pg
+			if err := tx.FlushMatchPlayerStats(ctx, match.ID, pg.ID, p.ClientID,
+				p.Frags, p.Deaths, p.Completed, p.Score, p.Team, p.Model, p.Skill, p.Victory,
+				p.Captures, p.FlagReturns, p.Assists, p.Impressives, p.Excellents, p.Humiliations, p.Defends,
+				p.IsBot, p.JoinedLate, p.JoinedAt, p.IsVR); err != nil {
+				log.Printf("hub: FlushMatchPlayerStats for GUID %s: %v", p.GUID, err)
+				continue
+			}
+			if p.BestSpree > 0 {
+				if err := tx.RecordBestSpree(ctx, match.ID, pg.ID, p.ClientID, p.BestSpree); err != nil {
+					log.Printf("hub: RecordBestSpree for GUID %s: %v", p.GUID, err)
+				}
+			}
+			if p.FastestCapMs > 0 {
+				if err := tx.RecordFastestCap(ctx, match.ID, pg.ID, p.ClientID, p.FastestCapMs); err != nil {
+					log.Printf("hub: RecordFastestCap for GUID %s: %v", p.GUID, err)
+				}
+			}
+			if p.FlagCarryMs > 0 {
+				if err := tx.RecordFlagCarry(ctx, match.ID, pg.ID, p.ClientID, p.FlagCarryMs); err != nil {
+					log.Printf("hub: RecordFlagCarry for GUID %s: %v", p.GUID, err)
+				}
+			}
+			if p.SkullsScored > 0 || p.ObeliskDestroys > 0 {
+				if err := tx.RecordTeamArenaStats(ctx, match.ID, pg.ID, p.ClientID, p.SkullsScored, p.ObeliskDestroys); err != nil {
+					log.Printf("hub: RecordTeamArenaStats for GUID %s: %v", p.GUID, err)
+				}
+			}
+			if len(p.WeaponFrags) > 0 {
+				if err := tx.RecordWeaponFrags(ctx, match.ID, pg.ID, p.WeaponFrags); err != nil {
+					log.Printf("hub: RecordWeaponFrags for GUID %s: %v", p.GUID, err)
+				}
+			}
+			if len(p.Accuracy) > 0 {
+				if err := tx.RecordWeaponAccuracy(ctx, match.ID, pg.ID, p.Accuracy); err != nil {
+					log.Printf("hub: RecordWeaponAccuracy for GUID %s: %v", p.GUID, err)
+				}
+			}
+			flushed++
+			if !p.IsBot {
+				participants = append(participants, matchEndParticipant{playerID: pg.PlayerID, clientID: p.ClientID})
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("hub: match_end flush failed for UUID %s: %v", data.MatchUUID, err)
 		return
 	}
-	log.Printf("hub: match_end match=%d uuid=%s players=%d reason=%q", match.ID, data.MatchUUID, flushed, data.ExitReason)
+	if !ok {
+		log.Printf("hub: match_end for already ended match %d uuid=%s; skipping", match.ID, data.MatchUUID)
+		return
+	}
+	log.Printf("hub: match_end match=%d uuid=%s players=%d reason=%q abandoned=%t", match.ID, data.MatchUUID, flushed, data.ExitReason, data.Abandoned)
+
+	w.awardAchievements(ctx, match, data.EndedAt, participants)
+	w.updateRecords(ctx, match, data.EndedAt, participants)
+	w.attachTournamentMatch(ctx, match)
 }
 
 func (w *Writer) handleMatchSettingsUpdate(ctx context.Context, data domain.MatchSettingsUpdateData) {
@@ -455,7 +621,14 @@ func (w *Writer) handlePlayerJoin(ctx context.Context, serverID int64, data doma
 	if existing, err := w.store.GetOpenSessionForPlayer(ctx, pg.ID, serverID); err != nil && !notFound(err) {
 		log.Printf("hub: player_join open-session check for GUID %s: %v", data.GUID, err)
 		return
-	} else if existing != nil {
+	} else if existing != nil && !existing.JoinedAt.After(data.JoinedAt) {
+		return
+	}
+	// A join already recorded, seen again by a log import.
+	if done, err := w.store.GetSessionByPlayerAndJoinTime(ctx, pg.ID, serverID, data.JoinedAt); err != nil {
+		log.Printf("hub: player_join session lookup for GUID %s: %v", data.GUID, err)
+		return
+	} else if done != nil {
 		return
 	}
 	session := &domain.Session{
@@ -463,6 +636,12 @@ func (w *Writer) handlePlayerJoin(ctx context.Context, serverID int64, data doma
 		ServerID:     serverID,
 		JoinedAt:     data.JoinedAt,
 		IPAddress:    data.IP,
+		CountryCode:  data.CountryCode,
+		Country:      data.Country,
+		City:         data.City,
+	}
+	if w.dropIPs {
+		session.IPAddress = ""
 	}
 	if err := w.store.CreateSession(ctx, session); err != nil {
 		log.Printf("hub: CreateSession for GUID %s: %v", data.GUID, err)
@@ -474,8 +653,12 @@ func (w *Writer) handlePlayerJoin(ctx context.Context, serverID int64, data doma
 func (w *Writer) handlePlayerLeave(ctx context.Context, serverID int64, data domain.PlayerLeaveData) {
 	w.presence.RecordLeave(serverID, data.ClientNum, data.GUID)
 
-	session, err := w.resolveOpenSession(ctx, serverID, data.GUID)
-	if err != nil {
+	var session *domain.Session
+	pg, err := w.store.GetPlayerGUIDByGUID(ctx, data.GUID)
+	if err == nil && pg != nil {
+		session, err = w.store.GetOpenSessionAt(ctx, pg.ID, serverID, data.LeftAt)
+	}
+	if err != nil && !notFound(err) {
 		log.Printf("hub: player_leave resolve session: %v", err)
 		return
 	}
@@ -524,6 +707,28 @@ func (w *Writer) handleServerShutdown(ctx context.Context, serverID int64, data
 	log.Printf("hub: server_shutdown server=%d swept open sessions before %s", serverID, data.ShutdownAt.Format(time.RFC3339))
 }
 
+// handleServerCrash records a crash incident and closes the sessions
+// the crash cut off. A crash found by the next ServerStartup would be
+// swept by that too, but at the restart rather than the crash.
+func (w *Writer) handleServerCrash(ctx context.Context, serverID int64, data domain.ServerCrashData) {
+	w.presence.Clear(serverID)
+	inc := domain.ServerIncident{
+		ServerID:   serverID,
+		Reason:     data.Reason,
+		Detail:     data.Detail,
+		OccurredAt: data.CrashedAt,
+		LogLines:   data.LogLines,
+	}
+	if err := w.store.RecordServerIncident(ctx, inc); err != nil {
+		log.Printf("hub: RecordServerIncident server=%d: %v", serverID, err)
+	}
+	if err := w.store.EndOpenSessionsBefore(ctx, serverID, data.CrashedAt, data.CrashedAt); err != nil {
+		log.Printf("hub: EndOpenSessionsBefore (crash) for server %d: %v", serverID, err)
+		return
+	}
+	log.Printf("hub: server_crash server=%d reason=%s at %s", serverID, data.Reason, data.CrashedAt.Format(time.RFC3339))
+}
+
 // handleDemoFinalized flips matches.demo_available so the UI knows to
 // render a play button. Idempotent — if the match doesn't exist or is
 // already flagged, this is a no-op (the UPDATE just affects 0 rows).
@@ -673,10 +878,52 @@ func (w *Writer) Greet(ctx context.Context, req GreetRequest) (GreetReply, error
 	verified, admin := w.store.GetPlayerVerifiedStatus(ctx, playerID)
 	reply.IsVerified = verified
 	reply.IsAdmin = admin
+	reply.IsVIP = verified && w.store.IsPlayerVIP(ctx, playerID)
+	if req.WantRank {
+		reply.Rank = w.fragsRank(ctx, playerID)
+	}
+
+	w.checkReturning(ctx, req.ServerID, playerID, &reply)
 
 	return reply, nil
 }
 
+// checkReturning fills reply.DaysAway when the player's last closed
+// session ended at least domain.ReturningPlayerThreshold ago, and
+// hands the comeback to the return notifier. Players with no closed
+// sessions are first-timers, not returners.
+func (w *Writer) checkReturning(ctx context.Context, serverID, playerID int64, reply *GreetReply) {
+	lastSeen, err := w.store.GetPlayerLastSessionEnd(ctx, playerID)
+	if err != nil {
+		log.Printf("hub: greet last session lookup for player %d: %v", playerID, err)
+		return
+	}
+	if lastSeen.IsZero() {
+		return
+	}
+	now := time.Now().UTC()
+	away := now.Sub(lastSeen)
+	if away < domain.ReturningPlayerThreshold {
+		return
+	}
+	reply.DaysAway = int(away.Hours() / 24)
+	log.Printf("hub: greet player %d returning after %d days", playerID, reply.DaysAway)
+
+	if w.returnNotifier == nil {
+		return
+	}
+	player, err := w.store.GetPlayerByID(ctx, playerID)
+	if err != nil || player == nil {
+		return
+	}
+	w.returnNotifier.NotifyReturn(serverID, domain.ReturningPlayer{
+		Player:     *player,
+		ReturnedAt: now,
+		LastSeenAt: lastSeen,
+		DaysAway:   reply.DaysAway,
+	})
+}
+
 // Claim handles a !claim chat-command RPC.
 func (w *Writer) Claim(ctx context.Context, req ClaimRequest) (ClaimReply, error) {
 	if req.GUID == "" {
@@ -738,6 +985,119 @@ func (w *Writer) Link(ctx context.Context, req LinkRequest) (LinkReply, error) {
 	return LinkReply{Status: LinkOK}, nil
 }
 
+// statsRankDepth is how far down the all-time frags leaderboard Stats
+// looks for a player's rank; anyone below it is reported unranked.
+const statsRankDepth = 100
+
+// Stats handles a !stats [name] chat-command RPC.
+func (w *Writer) Stats(ctx context.Context, req StatsRequest) (StatsReply, error) {
+	var playerID int64
+	if name := strings.TrimSpace(domain.CleanQ3Name(req.Name)); name != "" {
+		players, err := w.store.SearchPlayers(ctx, name, 10, false)
+		if err != nil {
+			return StatsReply{Status: StatsError, Message: err.Error()}, nil
+		}
+		var candidates []domain.Player
+		for _, p := range players {
+			if p.IsBot {
+				continue
+			}
+			if strings.EqualFold(p.CleanName, name) {
+				candidates = []domain.Player{p}
+				break
+			}
+			candidates = append(candidates, p)
+		}
+		switch len(candidates) {
+		case 0:
+			return StatsReply{Status: StatsNotFound}, nil
+		case 1:
+			playerID = candidates[0].ID
+		default:
+			reply := StatsReply{Status: StatsAmbiguous}
+			for _, p := range candidates {
+				reply.Matches = append(reply.Matches, p.CleanName)
+			}
+			return reply, nil
+		}
+	} else {
+		if req.GUID == "" {
+			return StatsReply{Status: StatsNotFound}, nil
+		}
+		pg, err := w.store.GetPlayerGUIDByGUID(ctx, req.GUID)
+		if notFound(err) || pg == nil {
+			return StatsReply{Status: StatsNotFound}, nil
+		}
+		if err != nil {
+			return StatsReply{Status: StatsError, Message: err.Error()}, nil
+		}
+		playerID = pg.PlayerID
+	}
+
+	stats, err := w.store.GetPlayerStatsByID(ctx, playerID, "all")
+	if err != nil {
+		return StatsReply{Status: StatsError, Message: err.Error()}, nil
+	}
+	if stats == nil {
+		return StatsReply{Status: StatsNotFound}, nil
+	}
+	reply := StatsReply{
+		Status:           StatsOK,
+		Name:             stats.Player.Name,
+		Frags:            stats.Stats.Frags,
+		Deaths:           stats.Stats.Deaths,
+		KDRatio:          stats.Stats.KDRatio,
+		CompletedMatches: stats.Stats.CompletedMatches,
+	}
+	reply.Rank = w.fragsRank(ctx, playerID)
+	return reply, nil
+}
+
+// fragsRank is the player's place on the all-time frags leaderboard,
+// or 0 below statsRankDepth.
+func (w *Writer) fragsRank(ctx context.Context, playerID int64) int {
+	lb, err := w.store.GetLeaderboard(ctx, "frags", "all", statsRankDepth, "", time.Time{})
+	if err != nil {
+		log.Printf("hub: leaderboard lookup for player %d: %v", playerID, err)
+		return 0
+	}
+	for _, e := range lb.Entries {
+		if e.Player.ID == playerID {
+			return e.Rank
+		}
+	}
+	return 0
+}
+
+// topLimit is how many players !top lists.
+const topLimit = 5
+
+// Top handles a !top [category] chat-command RPC.
+func (w *Writer) Top(ctx context.Context, req TopRequest) (TopReply, error) {
+	category, ok := TopCategories[strings.ToLower(strings.TrimSpace(req.Category))]
+	if !ok {
+		return TopReply{Status: TopUnknownCategory}, nil
+	}
+	lb, err := w.store.GetLeaderboard(ctx, category, "all", topLimit, "", time.Time{})
+	if err != nil {
+		return TopReply{Status: TopError, Message: err.Error()}, nil
+	}
+	reply := TopReply{Status: TopOK}
+	for _, e := range lb.Entries {
+		entry := TopEntry{Rank: e.Rank, Name: e.Player.CleanName}
+		switch category {
+		case "kd_ratio":
+			entry.Value = e.KDRatio
+		case "captures":
+			entry.Value = float64(e.Captures)
+		default:
+			entry.Value = float64(e.TotalFrags)
+		}
+		reply.Entries = append(reply.Entries, entry)
+	}
+	return reply, nil
+}
+
 func isNumeric(s string) bool {
 	for _, c := range s {
 		if c < '0' || c > '9' {
@@ -752,7 +1112,14 @@ func (w *Writer) RegisterServer(ctx context.Context, source, key, address string
 	if err := w.store.UpsertServer(ctx, source, dbSrv); err != nil {
 		return nil, err
 	}
-	return w.store.GetServerByID(ctx, dbSrv.ID)
+	srv, err := w.store.GetServerByID(ctx, dbSrv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if srv.LogCursor, err = w.store.GetLogCursor(ctx, srv.ID); err != nil {
+		return nil, err
+	}
+	return srv, nil
 }
 
 // TagLocalServerSource attaches (source, local_id) so envelope
@@ -857,6 +1224,18 @@ func (w *Writer) EnrichEvent(ctx context.Context, event domain.Event) domain.Eve
 			w.presence.IncrementByGUID(event.ServerID, d.GUID, AwardAssist)
 		}
 		event.Data = d
+	case domain.PlayerReturnEvent:
+		d.PlayerID = resolve(d.GUID)
+		event.Data = d
+	case domain.TeamBalanceEvent:
+		// Copied: the event may be shared with other subscribers.
+		moves := make([]domain.TeamBalanceMove, len(d.Moves))
+		for i, mv := range d.Moves {
+			mv.PlayerID = resolve(mv.GUID)
+			moves[i] = mv
+		}
+		d.Moves = moves
+		event.Data = d
 	case domain.ClientUserinfoEvent:
 		w.presence.UpdateClientUserinfo(event.ServerID, d.ClientNum, d.GUID, d.Model, d.IsVR)
 		event.Data = d
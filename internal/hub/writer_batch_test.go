@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// A backlog of events, as a replaying collector leaves, is applied in
+// one batch with the same result as one event at a time.
+func TestRunAppliesBacklogInBatch(t *testing.T) {
+	w, store := newTestWriter(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := store.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	if _, err := store.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now.Add(-time.Hour), false); err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		uuid := fmt.Sprintf("m%d", i)
+		start := now.Add(time.Duration(i*10-30) * time.Minute)
+		w.events <- domain.FactEvent{Type: domain.FactMatchStart, ServerID: srv.ID, Data: domain.MatchStartData{
+			MatchUUID: uuid, MapName: "q3dm17", GameType: "ffa", StartedAt: start, HandshakeRequired: true,
+		}}
+		w.events <- domain.FactEvent{Type: domain.FactMatchEnd, ServerID: srv.ID, Data: domain.MatchEndData{
+			MatchUUID: uuid, EndedAt: start.Add(5 * time.Minute), ExitReason: "fraglimit",
+			Players: []domain.MatchEndPlayer{{GUID: "AAAA", ClientID: 0, Frags: i + 1, Completed: true, JoinedAt: start}},
+		}}
+	}
+	// One that fails inside the batch doesn't take the others with it.
+	w.events <- domain.FactEvent{Type: domain.FactMatchEnd, ServerID: srv.ID, Data: domain.MatchEndData{MatchUUID: "unknown", EndedAt: now}}
+
+	w.wg.Add(1)
+	go w.run(ctx)
+	close(w.events)
+	w.wg.Wait()
+
+	for i := 0; i < 3; i++ {
+		m, err := store.GetMatchByUUID(ctx, fmt.Sprintf("m%d", i))
+		if err != nil || m == nil {
+			t.Fatalf("match m%d: %v, %v", i, m, err)
+		}
+		if m.EndedAt == nil {
+			t.Errorf("match m%d not ended", i)
+		}
+		summary, err := store.GetMatchSummaryByID(ctx, m.ID)
+		if err != nil {
+			t.Fatalf("GetMatchSummaryByID: %v", err)
+		}
+		if len(summary.Players) != 1 || summary.Players[0].Frags != i+1 {
+			t.Errorf("match m%d players: got %+v, want one with %d frags", i, summary.Players, i+1)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Package metrics holds the process-wide counters served at GET
+// /metrics in the Prometheus text format, plus gauges read from the
+// log tailers at scrape time. Nothing here needs histograms yet.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Buffered event channels, as named in the channel label.
+const (
+	ChannelLog       = "log"       // log tailer → collector
+	ChannelLive      = "live"      // collector → WebSocket fan-out
+	ChannelBroadcast = "broadcast" // WebSocket hub → clients
+)
+
+// HighValueWait is how long Send blocks on a full channel for an
+// event that mustn't be lost before giving up and dropping it.
+const HighValueWait = 2 * time.Second
+
+type dropKey struct {
+	channel   string
+	eventType string
+}
+
+// DropCounter counts events dropped because a buffered channel was
+// full, by channel and event type.
+type DropCounter struct {
+	mu     sync.Mutex
+	counts map[dropKey]uint64
+}
+
+// EventDrops is the process's drop counter.
+var EventDrops = &DropCounter{}
+
+// Inc records one dropped event.
+func (c *DropCounter) Inc(channel, eventType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[dropKey]uint64)
+	}
+	c.counts[dropKey{channel, eventType}]++
+}
+
+// Count returns how many events of eventType were dropped from channel.
+func (c *DropCounter) Count(channel, eventType string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[dropKey{channel, eventType}]
+}
+
+// SlowWebSocketClients counts WebSocket clients disconnected because
+// their send queue filled.
+var SlowWebSocketClients atomic.Uint64
+
+// In-game chat command outcomes, as named in the result label.
+const (
+	CommandHandled  = "handled"
+	CommandLimited  = "limited"  // sender was over the rate limit or muted
+	CommandDisabled = "disabled" // commands are switched off on the server
+)
+
+type commandKey struct {
+	command string
+	result  string
+}
+
+// CommandCounter counts in-game chat commands by command and result.
+type CommandCounter struct {
+	mu     sync.Mutex
+	counts map[commandKey]uint64
+}
+
+// ChatCommands is the process's chat command counter.
+var ChatCommands = &CommandCounter{}
+
+// Inc records one command.
+func (c *CommandCounter) Inc(command, result string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[commandKey]uint64)
+	}
+	c.counts[commandKey{command, result}]++
+}
+
+// Count returns how many of command ended in result.
+func (c *CommandCounter) Count(command, result string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[commandKey{command, result}]
+}
+
+// TailStat is a log tailer's state at scrape time.
+type TailStat struct {
+	LagBytes int64     // written but not yet read
+	LastRead time.Time // when new lines were last read
+	Watching bool      // woken by inotify rather than polling
+}
+
+// TailRegistry reads each running log tailer's state, by server key.
+type TailRegistry struct {
+	mu    sync.Mutex
+	tails map[string]func() TailStat
+}
+
+// LogTails is the process's log tailer registry.
+var LogTails = &TailRegistry{}
+
+// Set registers stat as the way to read server's tailer, replacing
+// any earlier one.
+func (r *TailRegistry) Set(server string, stat func() TailStat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tails == nil {
+		r.tails = make(map[string]func() TailStat)
+	}
+	r.tails[server] = stat
+}
+
+// Remove forgets server's tailer.
+func (r *TailRegistry) Remove(server string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tails, server)
+}
+
+// snapshot reads every tailer, sorted by server key.
+func (r *TailRegistry) snapshot() ([]string, map[string]TailStat) {
+	r.mu.Lock()
+	fns := make(map[string]func() TailStat, len(r.tails))
+	for k, f := range r.tails {
+		fns[k] = f
+	}
+	r.mu.Unlock()
+	servers := make([]string, 0, len(fns))
+	stats := make(map[string]TailStat, len(fns))
+	for k, f := range fns {
+		servers = append(servers, k)
+		stats[k] = f()
+	}
+	sort.Strings(servers)
+	return servers, stats
+}
+
+// Send delivers v on ch without blocking, or, for a high-value event,
+// waiting up to HighValueWait. An event that can't be delivered is
+// counted against channel and eventType. Reports whether v was sent.
+func Send[T any](ch chan<- T, v T, channel, eventType string, highValue bool) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+	}
+	if highValue {
+		timer := time.NewTimer(HighValueWait)
+		defer timer.Stop()
+		select {
+		case ch <- v:
+			return true
+		case <-timer.C:
+		}
+	}
+	EventDrops.Inc(channel, eventType)
+	return false
+}
+
+// WritePrometheus writes every counter in the Prometheus text format.
+func WritePrometheus(w io.Writer) error {
+	EventDrops.mu.Lock()
+	keys := make([]dropKey, 0, len(EventDrops.counts))
+	for k := range EventDrops.counts {
+		keys = append(keys, k)
+	}
+	counts := make(map[dropKey]uint64, len(keys))
+	for _, k := range keys {
+		counts[k] = EventDrops.counts[k]
+	}
+	EventDrops.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].channel != keys[j].channel {
+			return keys[i].channel < keys[j].channel
+		}
+		return keys[i].eventType < keys[j].eventType
+	})
+
+	if _, err := io.WriteString(w, "# HELP trinity_events_dropped_total Events dropped because a buffered channel was full.\n"+
+		"# TYPE trinity_events_dropped_total counter\n"); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "trinity_events_dropped_total{channel=%q,type=%q} %d\n", k.channel, k.eventType, counts[k]); err != nil {
+			return err
+		}
+	}
+
+	ChatCommands.mu.Lock()
+	cmdKeys := make([]commandKey, 0, len(ChatCommands.counts))
+	cmdCounts := make(map[commandKey]uint64, len(ChatCommands.counts))
+	for k, n := range ChatCommands.counts {
+		cmdKeys = append(cmdKeys, k)
+		cmdCounts[k] = n
+	}
+	ChatCommands.mu.Unlock()
+	sort.Slice(cmdKeys, func(i, j int) bool {
+		if cmdKeys[i].command != cmdKeys[j].command {
+			return cmdKeys[i].command < cmdKeys[j].command
+		}
+		return cmdKeys[i].result < cmdKeys[j].result
+	})
+
+	if _, err := io.WriteString(w, "# HELP trinity_chat_commands_total In-game chat commands received, by command and result.\n"+
+		"# TYPE trinity_chat_commands_total counter\n"); err != nil {
+		return err
+	}
+	for _, k := range cmdKeys {
+		if _, err := fmt.Fprintf(w, "trinity_chat_commands_total{command=%q,result=%q} %d\n", k.command, k.result, cmdCounts[k]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "# HELP trinity_websocket_slow_clients_total WebSocket clients disconnected for falling behind.\n"+
+		"# TYPE trinity_websocket_slow_clients_total counter\n"+
+		"trinity_websocket_slow_clients_total %d\n", SlowWebSocketClients.Load()); err != nil {
+		return err
+	}
+
+	servers, tails := LogTails.snapshot()
+	now := time.Now()
+	gauges := []struct {
+		name, help string
+		value      func(TailStat) float64
+	}{
+		{"trinity_log_tail_lag_bytes", "Bytes written to a server's log that the tailer hasn't read yet.",
+			func(t TailStat) float64 { return float64(t.LagBytes) }},
+		{"trinity_log_tail_idle_seconds", "Seconds since the tailer last read new lines from a server's log.",
+			func(t TailStat) float64 { return now.Sub(t.LastRead).Seconds() }},
+		{"trinity_log_tail_inotify", "1 if a server's log tailer is woken by inotify, 0 if it is polling.",
+			func(t TailStat) float64 {
+				if t.Watching {
+					return 1
+				}
+				return 0
+			}},
+	}
+	for _, g := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name); err != nil {
+			return err
+		}
+		for _, srv := range servers {
+			if _, err := fmt.Fprintf(w, "%s{server=%q} %g\n", g.name, srv, g.value(tails[srv])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
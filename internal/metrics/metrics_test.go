@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendCountsDrops(t *testing.T) {
+	ch := make(chan int, 1)
+	if !Send(ch, 1, "test", "frag", false) {
+		t.Fatal("first send should fit")
+	}
+	if Send(ch, 2, "test", "frag", false) {
+		t.Fatal("second send should drop")
+	}
+	if got := EventDrops.Count("test", "frag"); got != 1 {
+		t.Errorf("frag drops = %d, want 1", got)
+	}
+
+	// A high-value event waits for the reader instead of dropping.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-ch
+	}()
+	if !Send(ch, 3, "test", "match_end", true) {
+		t.Fatal("high-value send should wait for room")
+	}
+	if got := EventDrops.Count("test", "match_end"); got != 0 {
+		t.Errorf("match_end drops = %d, want 0", got)
+	}
+
+	var out strings.Builder
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	if want := `trinity_events_dropped_total{channel="test",type="frag"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
+
+func TestChatCommandCounts(t *testing.T) {
+	ChatCommands.Inc("link", CommandHandled)
+	ChatCommands.Inc("link", CommandLimited)
+	ChatCommands.Inc("link", CommandLimited)
+	if got := ChatCommands.Count("link", CommandLimited); got != 2 {
+		t.Errorf("limited = %d, want 2", got)
+	}
+
+	var out strings.Builder
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, want := range []string{
+		`trinity_chat_commands_total{command="link",result="handled"} 1`,
+		`trinity_chat_commands_total{command="link",result="limited"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLogTailGauges(t *testing.T) {
+	LogTails.Set("ffa", func() TailStat {
+		return TailStat{LagBytes: 42, LastRead: time.Now(), Watching: true}
+	})
+	LogTails.Set("ctf", func() TailStat {
+		return TailStat{LastRead: time.Now()}
+	})
+	LogTails.Remove("ctf")
+	defer LogTails.Remove("ffa")
+
+	var out strings.Builder
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, want := range []string{
+		`trinity_log_tail_lag_bytes{server="ffa"} 42`,
+		`trinity_log_tail_inotify{server="ffa"} 1`,
+		`trinity_log_tail_idle_seconds{server="ffa"}`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), `server="ctf"`) {
+		t.Errorf("removed tailer still reported:\n%s", out.String())
+	}
+}
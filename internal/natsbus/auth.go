@@ -319,6 +319,10 @@ func addSourcePermissions(uc *jwt.UserClaims, sourceID string) {
 		"trinity.rpc.claim."+sourceID,
 		"trinity.rpc.link."+sourceID+".>",
 		"trinity.rpc.link."+sourceID,
+		"trinity.rpc.stats."+sourceID+".>",
+		"trinity.rpc.stats."+sourceID,
+		"trinity.rpc.top."+sourceID+".>",
+		"trinity.rpc.top."+sourceID,
 		"trinity.rpc.server.register."+sourceID+".>",
 		"trinity.rpc.server.register."+sourceID,
 		"trinity.rpc.identity.upsert."+sourceID+".>",
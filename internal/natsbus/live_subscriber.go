@@ -147,6 +147,10 @@ func decodeLiveEventPayload(eventType string, raw json.RawMessage) (interface{},
 		target = &domain.AwardEvent{}
 	case domain.EventClientUserinfo:
 		target = &domain.ClientUserinfoEvent{}
+	case domain.EventPlayerReturn:
+		target = &domain.PlayerReturnEvent{}
+	case domain.EventTeamBalance:
+		target = &domain.TeamBalanceEvent{}
 	default:
 		return nil, fmt.Errorf("unknown live event type %q", eventType)
 	}
@@ -193,6 +197,10 @@ func decodeLiveEventPayload(eventType string, raw json.RawMessage) (interface{},
 		return *t, nil
 	case *domain.ClientUserinfoEvent:
 		return *t, nil
+	case *domain.PlayerReturnEvent:
+		return *t, nil
+	case *domain.TeamBalanceEvent:
+		return *t, nil
 	}
 	return nil, fmt.Errorf("unreachable: live event %q unmapped after decode", eventType)
 }
@@ -29,11 +29,17 @@ const (
 // RconRole is what the hub asserts about the calling user. The
 // collector trusts the hub on identity (JWT was already validated)
 // but re-checks RoleHubAdmin against its per-server delegation flag.
+// RoleAnnounce is the hub speaking for itself, not a user, and is
+// limited collector-side to printing to a single client. RoleCommands
+// isn't RCON at all: it relays a hub admin switching the server's chat
+// commands, with Command "enable" or "disable".
 type RconRole string
 
 const (
 	RconRoleOwner    RconRole = "owner"
 	RconRoleHubAdmin RconRole = "hub_admin"
+	RconRoleAnnounce RconRole = "announce"
+	RconRoleCommands RconRole = "commands"
 )
 
 // RconExecRequest is the hub's RCON proxy request. ServerKey is the
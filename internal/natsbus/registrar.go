@@ -87,6 +87,12 @@ func (r *Registrar) run(ctx context.Context) {
 	}
 }
 
+// Publish sends a heartbeat now rather than waiting for the next tick,
+// so a change to the roster reaches the hub straight away.
+func (r *Registrar) Publish() {
+	r.publish()
+}
+
 func (r *Registrar) publish() {
 	reg := domain.Registration{
 		Source:        r.source,
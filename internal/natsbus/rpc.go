@@ -22,6 +22,8 @@ const (
 	subjectGreetPrefix          = "trinity.rpc.greet."
 	subjectClaimPrefix          = "trinity.rpc.claim."
 	subjectLinkPrefix           = "trinity.rpc.link."
+	subjectStatsPrefix          = "trinity.rpc.stats."
+	subjectTopPrefix            = "trinity.rpc.top."
 	subjectServerRegisterPrefix = "trinity.rpc.server.register."
 	subjectIdentityUpsertPrefix = "trinity.rpc.identity.upsert."
 	subjectIdentityUpsertBot    = "trinity.rpc.identity.upsert_bot."
@@ -69,6 +71,18 @@ func (c *RPCClient) Link(ctx context.Context, req hub.LinkRequest) (hub.LinkRepl
 	return reply, err
 }
 
+func (c *RPCClient) Stats(ctx context.Context, req hub.StatsRequest) (hub.StatsReply, error) {
+	var reply hub.StatsReply
+	err := c.request(ctx, subjectStatsPrefix+c.source, req, &reply)
+	return reply, err
+}
+
+func (c *RPCClient) Top(ctx context.Context, req hub.TopRequest) (hub.TopReply, error) {
+	var reply hub.TopReply
+	err := c.request(ctx, subjectTopPrefix+c.source, req, &reply)
+	return reply, err
+}
+
 func (c *RPCClient) RegisterServer(ctx context.Context, source, key, address string) (*domain.Server, error) {
 	if source == "" {
 		source = c.source
@@ -234,6 +248,38 @@ func RegisterRPCHandlers(nc *nats.Conn, h RPCHandlers) (*RPCServer, error) {
 		return nil, err
 	}
 
+	if err := subscribe("trinity.rpc.stats.>", func(m *nats.Msg) {
+		var req hub.StatsRequest
+		if err := json.Unmarshal(m.Data, &req); err != nil {
+			log.Printf("natsbus.RPC stats: bad request: %v", err)
+			return
+		}
+		reply, err := h.Stats(context.Background(), req)
+		if err != nil {
+			log.Printf("natsbus.RPC stats: handler error: %v", err)
+		}
+		respond(m, reply)
+	}); err != nil {
+		s.Stop()
+		return nil, err
+	}
+
+	if err := subscribe("trinity.rpc.top.>", func(m *nats.Msg) {
+		var req hub.TopRequest
+		if err := json.Unmarshal(m.Data, &req); err != nil {
+			log.Printf("natsbus.RPC top: bad request: %v", err)
+			return
+		}
+		reply, err := h.Top(context.Background(), req)
+		if err != nil {
+			log.Printf("natsbus.RPC top: handler error: %v", err)
+		}
+		respond(m, reply)
+	}); err != nil {
+		s.Stop()
+		return nil, err
+	}
+
 	if err := subscribe("trinity.rpc.server.register.>", func(m *nats.Msg) {
 		var req hub.RegisterServerRequest
 		if err := json.Unmarshal(m.Data, &req); err != nil {
@@ -144,6 +144,77 @@ func TestRPCClaimRoundTripGeneratesCode(t *testing.T) {
 	}
 }
 
+func TestRPCStatsUnknownPlayer(t *testing.T) {
+	client, _ := startRPCRig(t)
+	reply, err := client.Stats(context.Background(), hub.StatsRequest{GUID: "anyguid"})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if reply.Status != hub.StatsNotFound {
+		t.Errorf("Status = %q, want %q", reply.Status, hub.StatsNotFound)
+	}
+}
+
+func TestRPCStatsByName(t *testing.T) {
+	client, store := startRPCRig(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for _, p := range []struct{ guid, name string }{
+		{"guid-a", "Ernie"},
+		{"guid-b", "Ernest"},
+	} {
+		if _, err := store.UpsertPlayerGUID(ctx, p.guid, p.name, p.name, now, false); err != nil {
+			t.Fatalf("seed %s: %v", p.name, err)
+		}
+	}
+
+	reply, err := client.Stats(ctx, hub.StatsRequest{Name: "^1ernie"})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if reply.Status != hub.StatsOK || reply.Name != "Ernie" {
+		t.Errorf("exact match = %+v, want ok for Ernie", reply)
+	}
+
+	reply, err = client.Stats(ctx, hub.StatsRequest{Name: "ern"})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if reply.Status != hub.StatsAmbiguous || len(reply.Matches) != 2 {
+		t.Errorf("partial match = %+v, want ambiguous with 2 matches", reply)
+	}
+
+	reply, err = client.Stats(ctx, hub.StatsRequest{Name: "nobody"})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if reply.Status != hub.StatsNotFound {
+		t.Errorf("Status = %q, want %q", reply.Status, hub.StatsNotFound)
+	}
+}
+
+func TestRPCTopCategories(t *testing.T) {
+	client, _ := startRPCRig(t)
+	ctx := context.Background()
+
+	reply, err := client.Top(ctx, hub.TopRequest{Category: "kd"})
+	if err != nil {
+		t.Fatalf("Top: %v", err)
+	}
+	if reply.Status != hub.TopOK {
+		t.Errorf("kd Status = %q, want %q", reply.Status, hub.TopOK)
+	}
+
+	reply, err = client.Top(ctx, hub.TopRequest{Category: "rockets"})
+	if err != nil {
+		t.Fatalf("Top: %v", err)
+	}
+	if reply.Status != hub.TopUnknownCategory {
+		t.Errorf("rockets Status = %q, want %q", reply.Status, hub.TopUnknownCategory)
+	}
+}
+
 func TestRPCRegisterServerUpsertsAndTags(t *testing.T) {
 	client, store := startRPCRig(t)
 	ctx := context.Background()
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// accuracyMinShots is how many shots a player needs over a period
+// before they place on an accuracy leaderboard, so a lucky handful of
+// shots can't top it.
+const accuracyMinShots = 200
+
+// RecordWeaponAccuracy adds a player's per-weapon shots and hits for
+// matchID. Stints of one GUID arrive as separate calls and add up.
+func (s *Store) RecordWeaponAccuracy(ctx context.Context, matchID, playerGUIDID int64, accuracy map[string]domain.WeaponShots) error {
+	return recordWeaponAccuracy(ctx, s.conn(ctx), matchID, playerGUIDID, accuracy)
+}
+
+// recordWeaponAccuracy writes all of a player's weapons in one
+// statement. Weapons they never fired are skipped.
+func recordWeaponAccuracy(ctx context.Context, db execer, matchID, playerGUIDID int64, accuracy map[string]domain.WeaponShots) error {
+	var values []string
+	var args []any
+	for weapon, n := range accuracy {
+		if n.Shots <= 0 {
+			continue
+		}
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, matchID, playerGUIDID, weapon, n.Shots, min(n.Hits, n.Shots))
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO match_weapon_accuracy (match_id, player_guid_id, weapon, shots, hits)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT(match_id, player_guid_id, weapon) DO UPDATE SET
+			shots = shots + excluded.shots,
+			hits = hits + excluded.hits
+	`, args...)
+	return err
+}
+
+// getPlayerAccuracy returns a player's shots and hits by weapon, most
+// fired first, for matches started in [start, end). all ignores the
+// bounds.
+func (s *Store) getPlayerAccuracy(ctx context.Context, playerID int64, all bool, start, end time.Time) ([]domain.WeaponAccuracy, error) {
+	query := `
+		SELECT wa.weapon, SUM(wa.shots) AS total_shots, SUM(wa.hits) AS total_hits
+		FROM match_weapon_accuracy wa
+		JOIN player_guids pg ON wa.player_guid_id = pg.id`
+	args := []any{playerID}
+	if all {
+		query += `
+		WHERE pg.player_id = ?`
+	} else {
+		query += `
+		JOIN matches m ON wa.match_id = m.id
+		WHERE pg.player_id = ? AND m.started_at >= ? AND m.started_at < ?`
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	query += `
+		GROUP BY wa.weapon
+		ORDER BY total_shots DESC, wa.weapon`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.WeaponAccuracy{}
+	for rows.Next() {
+		var a domain.WeaponAccuracy
+		if err := rows.Scan(&a.Weapon, &a.Shots, &a.Hits); err != nil {
+			return nil, err
+		}
+		a.Accuracy = float64(a.Hits) / float64(a.Shots)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// overallAccuracy is hits over shots across every weapon in accuracy,
+// or nil if there were no shots.
+func overallAccuracy(accuracy []domain.WeaponAccuracy) *float64 {
+	var shots, hits int64
+	for _, a := range accuracy {
+		shots += a.Shots
+		hits += a.Hits
+	}
+	if shots == 0 {
+		return nil
+	}
+	ratio := float64(hits) / float64(shots)
+	return &ratio
+}
+
+// GetAccuracyLeaderboard ranks players by hits over shots, with one
+// weapon or, when weapon is "", all of them. Period, game type, and
+// asOf filter as they do for GetLeaderboard, and the same players are
+// left off. Players need accuracyMinShots shots to place. TotalMatches
+// is the matches they have shots logged in.
+func (s *Store) GetAccuracyLeaderboard(ctx context.Context, weapon, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+	return s.accuracyLeaderboardBetween(ctx, weapon, period, start, end, limit, gameType)
+}
+
+// accuracyLeaderboardBetween is GetAccuracyLeaderboard over matches
+// started in [start, end), or all of them when period is "all".
+func (s *Store) accuracyLeaderboardBetween(ctx context.Context, weapon, period string, start, end time.Time, limit int, gameType string) (*domain.LeaderboardResponse, error) {
+	rules := s.LeaderboardRules()
+	where := rules.playerFilter() + rules.matchFilter("wa")
+	var args []any
+	if weapon != "" {
+		where += " AND wa.weapon = ?"
+		args = append(args, weapon)
+	}
+	if period != "all" {
+		where += " AND m.started_at >= ? AND m.started_at < ?"
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	if gameType != "" {
+		where += " AND m.game_type = ?"
+		args = append(args, gameType)
+	}
+	args = append(args, accuracyMinShots, limit)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
+			COALESCE(u.is_admin, 0) as is_admin,
+			SUM(wa.shots) as total_shots,
+			SUM(wa.hits) as total_hits,
+			CAST(SUM(wa.hits) AS REAL) / SUM(wa.shots) as accuracy,
+			COUNT(DISTINCT wa.match_id) as total_matches
+		FROM match_weapon_accuracy wa
+		JOIN player_guids pg ON wa.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		JOIN matches m ON wa.match_id = m.id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE `+where+`
+		GROUP BY p.id
+		HAVING total_shots >= ?
+		ORDER BY accuracy DESC, total_shots DESC, p.id
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.LeaderboardResponse{
+		Category: "accuracy",
+		Weapon:   weapon,
+		Period:   period,
+		Entries:  []domain.LeaderboardEntry{},
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	for rows.Next() {
+		var e domain.LeaderboardEntry
+		if err := rows.Scan(&e.Player.ID, &e.Player.Name, &e.Player.CleanName, &e.Player.FirstSeen, &e.Player.LastSeen,
+			&e.Player.IsBot, &e.Player.IsVR, &e.Player.IsVerified, &e.Player.IsAdmin,
+			&e.Shots, &e.Hits, &e.Accuracy, &e.TotalMatches); err != nil {
+			return nil, err
+		}
+		e.Rank = len(resp.Entries) + 1
+		resp.Entries = append(resp.Entries, e)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestAccuracyStatsAndLeaderboard(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	carol, err := s.UpsertPlayerGUID(ctx, "CCCC", "carol", "carol", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		// Two stints of alice's add up.
+		for _, acc := range []map[string]domain.WeaponShots{
+			{"railgun": {Shots: 50, Hits: 25}, "machinegun": {Shots: 100, Hits: 30}},
+			{"railgun": {Shots: 50, Hits: 15}, "rocket": {Shots: 0, Hits: 0}},
+		} {
+			if err := s.RecordWeaponAccuracy(ctx, m.ID, alice.ID, acc); err != nil {
+				t.Fatalf("RecordWeaponAccuracy: %v", err)
+			}
+		}
+		if err := s.RecordWeaponAccuracy(ctx, m.ID, bob.ID, map[string]domain.WeaponShots{"railgun": {Shots: 150, Hits: 90}}); err != nil {
+			t.Fatalf("RecordWeaponAccuracy: %v", err)
+		}
+		// Carol is deadly but hasn't fired enough to place.
+		if err := s.RecordWeaponAccuracy(ctx, m.ID, carol.ID, map[string]domain.WeaponShots{"railgun": {Shots: 10, Hits: 9}}); err != nil {
+			t.Fatalf("RecordWeaponAccuracy: %v", err)
+		}
+	}
+
+	resp, err := s.GetPlayerStatsByID(ctx, alice.PlayerID, "all")
+	if err != nil {
+		t.Fatalf("GetPlayerStatsByID: %v", err)
+	}
+	if len(resp.Accuracy) != 2 || resp.Accuracy[0].Weapon != "machinegun" || resp.Accuracy[1].Weapon != "railgun" {
+		t.Fatalf("accuracy: got %+v", resp.Accuracy)
+	}
+	if rail := resp.Accuracy[1]; rail.Shots != 200 || rail.Hits != 80 || rail.Accuracy != 0.4 {
+		t.Errorf("railgun accuracy: got %+v", rail)
+	}
+	if resp.OverallAccuracy == nil || *resp.OverallAccuracy != 140.0/400.0 {
+		t.Errorf("overall accuracy = %v, want %v", resp.OverallAccuracy, 140.0/400.0)
+	}
+
+	lb, err := s.GetLeaderboard(ctx, "accuracy", "week", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(lb.Entries) != 2 || lb.Entries[0].Player.ID != bob.PlayerID || lb.Entries[0].Accuracy != 0.6 || lb.Entries[0].Shots != 300 {
+		t.Fatalf("accuracy leaderboard: got %+v", lb.Entries)
+	}
+	if lb.Entries[1].Player.ID != alice.PlayerID || lb.Entries[1].TotalMatches != 2 {
+		t.Errorf("alice entry: got %+v", lb.Entries[1])
+	}
+
+	lb, err = s.GetAccuracyLeaderboard(ctx, "machinegun", "all", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetAccuracyLeaderboard: %v", err)
+	}
+	if lb.Weapon != "machinegun" || len(lb.Entries) != 1 || lb.Entries[0].Player.ID != alice.PlayerID || lb.Entries[0].Hits != 60 {
+		t.Errorf("machinegun leaderboard: got %+v", lb)
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetAchievementProgress tallies the player's career totals across all
+// their GUIDs, their current win streak, and how long their open
+// session on serverID has lasted as of asOf.
+func (s *Store) GetAchievementProgress(ctx context.Context, playerID, serverID int64, asOf time.Time) (domain.AchievementProgress, error) {
+	var p domain.AchievementProgress
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(mps.frags), 0), COALESCE(SUM(mps.captures), 0)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		WHERE pg.player_id = ?
+	`, playerID).Scan(&p.TotalFrags, &p.TotalCaptures)
+	if err != nil {
+		return p, err
+	}
+
+	// Walk completed matches newest first until the first non-win. A
+	// player can hold several rows in one match (reconnects), so
+	// collapse to one result per match. The + keeps SQLite off the
+	// completed index: without ANALYZE statistics it prefers it, and
+	// every match_end then reads every completed row there is.
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT MAX(mps.victories > 0)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		JOIN matches m ON m.id = mps.match_id
+		WHERE pg.player_id = ? AND +mps.completed = TRUE AND m.ended_at IS NOT NULL
+		GROUP BY m.id
+		ORDER BY m.ended_at DESC, m.id DESC
+	`, playerID)
+	if err != nil {
+		return p, err
+	}
+	for rows.Next() {
+		var won bool
+		if err := rows.Scan(&won); err != nil {
+			rows.Close()
+			return p, err
+		}
+		if !won {
+			break
+		}
+		p.WinStreak++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return p, err
+	}
+
+	var joinedAt sql.NullString
+	err = s.conn(ctx).QueryRowContext(ctx, `
+		SELECT MIN(s.joined_at)
+		FROM sessions s
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		WHERE pg.player_id = ? AND s.server_id = ? AND s.left_at IS NULL
+	`, playerID, serverID).Scan(&joinedAt)
+	if err != nil {
+		return p, err
+	}
+	if joinedAt.Valid && joinedAt.String != "" {
+		t, err := time.Parse(time.RFC3339, joinedAt.String)
+		if err != nil {
+			return p, fmt.Errorf("parsing joined_at %q: %w", joinedAt.String, err)
+		}
+		if d := asOf.Sub(t); d > 0 {
+			p.SessionLength = d
+		}
+	}
+	return p, nil
+}
+
+// AwardAchievement records that the player earned achievement in
+// matchID. Returns false if they already had it.
+func (s *Store) AwardAchievement(ctx context.Context, playerID int64, achievement string, matchID int64, earnedAt time.Time) (bool, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT OR IGNORE INTO player_achievements (player_id, achievement, match_id, earned_at)
+		VALUES (?, ?, ?, ?)
+	`, playerID, achievement, matchID, formatTimestamp(earnedAt))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetPlayerAchievements lists the player's badges, oldest first.
+// Rows for achievements no longer defined are skipped.
+func (s *Store) GetPlayerAchievements(ctx context.Context, playerID int64) ([]domain.PlayerAchievement, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT achievement, match_id, earned_at
+		FROM player_achievements
+		WHERE player_id = ?
+		ORDER BY earned_at, achievement
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	earned := []domain.PlayerAchievement{}
+	for rows.Next() {
+		var id string
+		var matchID sql.NullInt64
+		var earnedAt time.Time
+		if err := rows.Scan(&id, &matchID, &earnedAt); err != nil {
+			return nil, err
+		}
+		def, ok := domain.AchievementByID(id)
+		if !ok {
+			continue
+		}
+		earned = append(earned, domain.PlayerAchievement{
+			Achievement: def,
+			MatchID:     scanNullInt64Ptr(matchID),
+			EarnedAt:    earnedAt,
+		})
+	}
+	return earned, rows.Err()
+}
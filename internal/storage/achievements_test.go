@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestAchievementProgressAndAward(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	var lastMatch int64
+	play := func(uuid string, ended time.Time, frags, caps int, won bool) {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3ctf1", GameType: "ctf", StartedAt: ended.Add(-10 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, frags, 3, true, nil, nil, "", 0, won,
+			caps, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.EndMatch(ctx, m.ID, ended, "capturelimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+		lastMatch = m.ID
+	}
+	// A loss, then three wins: the streak counts back to the loss.
+	play("m1", base.Add(-4*time.Hour), 10, 1, false)
+	play("m2", base.Add(-3*time.Hour), 20, 2, true)
+	play("m3", base.Add(-2*time.Hour), 30, 3, true)
+	play("m4", base.Add(-1*time.Hour), 40, 4, true)
+
+	sess := &domain.Session{PlayerGUIDID: alice.ID, ServerID: srv.ID, JoinedAt: base.Add(-5 * time.Hour)}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	p, err := s.GetAchievementProgress(ctx, alice.PlayerID, srv.ID, base)
+	if err != nil {
+		t.Fatalf("GetAchievementProgress: %v", err)
+	}
+	if p.TotalFrags != 100 || p.TotalCaptures != 10 || p.WinStreak != 3 || p.SessionLength != 5*time.Hour {
+		t.Errorf("progress: got %+v", p)
+	}
+
+	first, err := s.AwardAchievement(ctx, alice.PlayerID, domain.AchievementMarathon, lastMatch, base)
+	if err != nil || !first {
+		t.Fatalf("first award: got %v, %v", first, err)
+	}
+	again, err := s.AwardAchievement(ctx, alice.PlayerID, domain.AchievementMarathon, lastMatch, base.Add(time.Hour))
+	if err != nil || again {
+		t.Fatalf("repeat award: got %v, %v", again, err)
+	}
+
+	earned, err := s.GetPlayerAchievements(ctx, alice.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerAchievements: %v", err)
+	}
+	if len(earned) != 1 || earned[0].ID != domain.AchievementMarathon || !earned[0].EarnedAt.Equal(base) {
+		t.Fatalf("earned: got %+v", earned)
+	}
+	if earned[0].MatchID == nil || *earned[0].MatchID != lastMatch {
+		t.Errorf("match id: got %v, want %d", earned[0].MatchID, lastMatch)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetActivityHeatmap averages how many players were online in each
+// hour of the week over the period, per server and overall, with
+// hours taken in loc. Only finished sessions count. humansOnly leaves
+// bots out. For "all" the period starts at the first session.
+func (s *Store) GetActivityHeatmap(ctx context.Context, period string, loc *time.Location, humansOnly bool, asOf time.Time) (*domain.ActivityResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+	if period == "all" {
+		end = asOf
+		if end.IsZero() {
+			end = time.Now()
+		}
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT s.server_id, srv.key, srv.source, s.joined_at, s.left_at
+		FROM sessions s
+		JOIN servers srv ON srv.id = s.server_id
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		JOIN players p ON p.id = pg.player_id
+		WHERE s.left_at IS NOT NULL AND s.joined_at < ? AND s.left_at > ?
+		  AND (? = 0 OR p.is_bot = FALSE)
+	`, formatTimestamp(end), formatTimestamp(start), humansOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Player-seconds by server and hour of the week
+	overall := &domain.ActivityHeatmap{}
+	servers := map[int64]*domain.ActivityHeatmap{}
+	var first time.Time
+	for rows.Next() {
+		var serverID int64
+		var key, source string
+		var joinedAt, leftAt time.Time
+		if err := rows.Scan(&serverID, &key, &source, &joinedAt, &leftAt); err != nil {
+			return nil, err
+		}
+		h := servers[serverID]
+		if h == nil {
+			h = &domain.ActivityHeatmap{ServerID: serverID, ServerKey: key, Source: source}
+			servers[serverID] = h
+		}
+		from, to := maxTime(joinedAt, start), minTime(leftAt, end)
+		if first.IsZero() || from.Before(first) {
+			first = from
+		}
+		for hour := hourStart(from, loc); hour.Before(to); hour = hour.Add(time.Hour) {
+			secs := minTime(hour.Add(time.Hour), to).Sub(maxTime(hour, from)).Seconds()
+			if secs <= 0 {
+				continue
+			}
+			local := hour.In(loc)
+			h.Hours[local.Weekday()][local.Hour()] += secs
+			overall.Hours[local.Weekday()][local.Hour()] += secs
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if period == "all" {
+		start = first
+	}
+
+	// Divide by how many times each hour of the week came round
+	var seen [7][24]float64
+	if !start.IsZero() {
+		for hour := hourStart(start, loc); hour.Before(end); hour = hour.Add(time.Hour) {
+			secs := minTime(hour.Add(time.Hour), end).Sub(maxTime(hour, start)).Seconds()
+			local := hour.In(loc)
+			seen[local.Weekday()][local.Hour()] += secs
+		}
+	}
+	average := func(h *domain.ActivityHeatmap) {
+		for d := range h.Hours {
+			for hr := range h.Hours[d] {
+				if seen[d][hr] > 0 {
+					h.Hours[d][hr] /= seen[d][hr]
+				}
+				if v := h.Hours[d][hr]; v > 0 && (h.Peak == nil || v > h.Peak.Players) {
+					h.Peak = &domain.ActivityHour{Weekday: time.Weekday(d), Hour: hr, Players: v}
+				}
+			}
+		}
+	}
+	average(overall)
+	resp := &domain.ActivityResponse{
+		Period:     period,
+		Timezone:   loc.String(),
+		HumansOnly: humansOnly,
+		Overall:    *overall,
+		Servers:    []domain.ActivityHeatmap{},
+	}
+	for _, h := range servers {
+		average(h)
+		resp.Servers = append(resp.Servers, *h)
+	}
+	slices.SortFunc(resp.Servers, func(a, b domain.ActivityHeatmap) int {
+		return cmp.Or(cmp.Compare(heatmapTotal(b), heatmapTotal(a)), cmp.Compare(a.ServerID, b.ServerID))
+	})
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	return resp, nil
+}
+
+// hourStart is the start of the hour t falls in, on loc's clock.
+func hourStart(t time.Time, loc *time.Location) time.Time {
+	l := t.In(loc)
+	return time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), 0, 0, 0, loc)
+}
+
+func heatmapTotal(h domain.ActivityHeatmap) float64 {
+	var total float64
+	for d := range h.Hours {
+		for _, v := range h.Hours[d] {
+			total += v
+		}
+	}
+	return total
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
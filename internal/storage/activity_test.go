@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestActivityHeatmap(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	// A week ending at midnight Sunday UTC, so each hour comes round once.
+	asOf := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	wed := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	ffa := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	ctf := &domain.Server{Key: "ctf", Address: "127.0.0.1:27961"}
+	for _, srv := range []*domain.Server{ffa, ctf} {
+		if err := s.UpsertServer(ctx, "test", srv); err != nil {
+			t.Fatalf("UpsertServer: %v", err)
+		}
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", wed, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bot, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", wed)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+	// Alice plays 20:00-22:00 on ffa; a bot half of 20:00-21:00 on ctf.
+	for _, sess := range []struct {
+		pg       int64
+		srv      int64
+		from, to time.Time
+	}{
+		{alice.ID, ffa.ID, wed, wed.Add(2 * time.Hour)},
+		{bot.ID, ctf.ID, wed.Add(30 * time.Minute), wed.Add(time.Hour)},
+	} {
+		ds := &domain.Session{PlayerGUIDID: sess.pg, ServerID: sess.srv, JoinedAt: sess.from}
+		if err := s.CreateSession(ctx, ds); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if err := s.EndSession(ctx, ds.ID, sess.to); err != nil {
+			t.Fatalf("EndSession: %v", err)
+		}
+	}
+
+	resp, err := s.GetActivityHeatmap(ctx, "week", time.UTC, false, asOf)
+	if err != nil {
+		t.Fatalf("GetActivityHeatmap: %v", err)
+	}
+	if got := resp.Overall.Hours[time.Wednesday][20]; got != 1.5 {
+		t.Errorf("Wednesday 20:00: got %v, want 1.5", got)
+	}
+	if p := resp.Overall.Peak; p == nil || p.Weekday != time.Wednesday || p.Hour != 20 {
+		t.Errorf("peak: got %+v", p)
+	}
+	if len(resp.Servers) != 2 || resp.Servers[0].ServerID != ffa.ID || resp.Servers[0].Hours[time.Wednesday][21] != 1 {
+		t.Errorf("servers: got %+v", resp.Servers)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no zoneinfo: %v", err)
+	}
+	resp, err = s.GetActivityHeatmap(ctx, "week", ny, true, asOf)
+	if err != nil {
+		t.Fatalf("GetActivityHeatmap: %v", err)
+	}
+	if got := resp.Overall.Hours[time.Wednesday][16]; got != 1 {
+		t.Errorf("humans only, Wednesday 16:00 New York: got %v, want 1", got)
+	}
+	if len(resp.Servers) != 1 {
+		t.Errorf("humans only: got %d servers, want 1", len(resp.Servers))
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordAdminCommand stores a command an admin ran in game.
+func (s *Store) RecordAdminCommand(ctx context.Context, c domain.AdminCommand) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO admin_commands (server_id, admin_player_id, admin_name, command, target, target_player_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ServerID, c.AdminPlayerID, c.AdminName, c.Command, c.Target, c.TargetPlayerID, formatTimestamp(c.CreatedAt))
+	return err
+}
+
+// ListAdminCommands returns in-game admin commands newest first,
+// optionally for one server.
+func (s *Store) ListAdminCommands(ctx context.Context, serverID int64, limit int) ([]domain.AdminCommand, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT ac.id, ac.server_id, sv.key, ac.admin_player_id, ac.admin_name,
+			ac.command, ac.target, ac.target_player_id, ac.created_at
+		FROM admin_commands ac
+		JOIN servers sv ON sv.id = ac.server_id
+		WHERE ? = 0 OR ac.server_id = ?
+		ORDER BY ac.created_at DESC, ac.id DESC
+		LIMIT ?
+	`, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.AdminCommand{}
+	for rows.Next() {
+		var c domain.AdminCommand
+		var adminID, targetID sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.ServerID, &c.ServerKey, &adminID, &c.AdminName,
+			&c.Command, &c.Target, &targetID, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.AdminPlayerID = scanNullInt64Ptr(adminID)
+		c.TargetPlayerID = scanNullInt64Ptr(targetID)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
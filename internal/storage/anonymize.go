@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// anonymizedGUIDPrefix marks a GUID that has been replaced by its
+// hash. Real Q3 GUIDs are 32 hex chars, so the prefix keeps a hashed
+// value from ever colliding with one a client could present.
+const anonymizedGUIDPrefix = "anon:"
+
+// PlayerAnonymization is a player's "forget me" request.
+type PlayerAnonymization struct {
+	PlayerID     int64
+	UserID       *int64
+	RequestedAt  time.Time
+	ExecuteAfter time.Time
+	CompletedAt  *time.Time
+}
+
+// RequestAnonymization queues playerID for anonymization once
+// executeAfter passes. An existing request for the player, pending or
+// completed, is left as it was; the stored row is returned either way.
+func (s *Store) RequestAnonymization(ctx context.Context, playerID, userID int64, requestedAt, executeAfter time.Time) (*PlayerAnonymization, error) {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO player_anonymizations (player_id, user_id, requested_at, execute_after)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(player_id) DO NOTHING
+	`, playerID, userID, formatTimestamp(requestedAt), formatTimestamp(executeAfter))
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAnonymization(ctx, playerID)
+}
+
+// GetAnonymization returns the player's request, or nil if there is none.
+func (s *Store) GetAnonymization(ctx context.Context, playerID int64) (*PlayerAnonymization, error) {
+	var a PlayerAnonymization
+	var userID sql.NullInt64
+	var completedAt sql.NullTime
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT player_id, user_id, requested_at, execute_after, completed_at
+		FROM player_anonymizations WHERE player_id = ?
+	`, playerID).Scan(&a.PlayerID, &userID, &a.RequestedAt, &a.ExecuteAfter, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.UserID = scanNullInt64Ptr(userID)
+	a.CompletedAt = scanNullTime(completedAt)
+	return &a, nil
+}
+
+// CancelAnonymization drops a pending request. Returns false if there
+// was nothing pending (never requested, or already carried out).
+func (s *Store) CancelAnonymization(ctx context.Context, playerID int64) (bool, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		DELETE FROM player_anonymizations WHERE player_id = ? AND completed_at IS NULL
+	`, playerID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// DueAnonymizations lists players whose cooling-off period has ended
+// as of now and who haven't been anonymized yet.
+func (s *Store) DueAnonymizations(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT player_id FROM player_anonymizations
+		WHERE completed_at IS NULL AND execute_after <= ?
+		ORDER BY execute_after
+	`, formatTimestamp(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AnonymizePlayer permanently strips a player's identity while
+// keeping their match rows, so scoreboards and totals still add up:
+//
+//   - the player and each of their GUIDs are renamed "Anonymous#<id>"
+//   - GUIDs are replaced by a hash, so a returning client starts over
+//     as a new player instead of reattaching to this one
+//   - name history, session IPs, and GeoIP locations are deleted
+//   - the user account is unlinked and pending link codes dropped
+//   - their chat lines and moderation incidents are deleted, and
+//     tells to them and their scoreboard lines lose the name
+//   - merge history and suggestions naming them are deleted, so a
+//     merge into them can no longer be undone
+//
+// The request row is stamped completed. Not reversible.
+func (s *Store) AnonymizePlayer(ctx context.Context, playerID int64, now time.Time) error {
+	name := fmt.Sprintf("Anonymous#%d", playerID)
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, guid FROM player_guids WHERE player_id = ?`, playerID)
+	if err != nil {
+		return err
+	}
+	guids := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var guid string
+		if err := rows.Scan(&id, &guid); err != nil {
+			rows.Close()
+			return err
+		}
+		guids[id] = guid
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, guid := range guids {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE player_guids SET guid = ?, name = ?, clean_name = ? WHERE id = ?
+		`, hashGUID(guid), name, name, id); err != nil {
+			return err
+		}
+	}
+
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE players SET name = ?, clean_name = ? WHERE id = ?`, []any{name, name, playerID}},
+		{`DELETE FROM player_names WHERE player_guid_id IN (SELECT id FROM player_guids WHERE player_id = ?)`, []any{playerID}},
+		{`UPDATE sessions SET ip_address = '', country_code = '', country = '', city = ''
+			WHERE player_guid_id IN (SELECT id FROM player_guids WHERE player_id = ?)`, []any{playerID}},
+		{`DELETE FROM match_chat WHERE player_id = ?`, []any{playerID}},
+		{`UPDATE match_chat SET to_name = ? WHERE to_player_id = ?`, []any{name, playerID}},
+		{`UPDATE matches SET scoreboard = (
+			SELECT json_group_array(CASE
+				WHEN l.value ->> '$.player_guid_id' IN (SELECT id FROM player_guids WHERE player_id = ?)
+				THEN json_set(l.value, '$.name', ?)
+				ELSE json(l.value) END ORDER BY l.key)
+			FROM json_each(matches.scoreboard) l)
+			WHERE scoreboard IS NOT NULL AND id IN (
+				SELECT match_id FROM match_player_stats
+				WHERE player_guid_id IN (SELECT id FROM player_guids WHERE player_id = ?))`, []any{playerID, name, playerID}},
+		{`DELETE FROM moderation_incidents WHERE player_id = ?`, []any{playerID}},
+		{`UPDATE vip_kicks SET vip_name = ? WHERE vip_player_id = ?`, []any{name, playerID}},
+		{`UPDATE vip_kicks SET kicked_name = ? WHERE kicked_player_id = ?`, []any{name, playerID}},
+		{`UPDATE admin_commands SET admin_name = ? WHERE admin_player_id = ?`, []any{name, playerID}},
+		{`UPDATE admin_commands SET target = ? WHERE target_player_id = ?`, []any{name, playerID}},
+		{`UPDATE users SET avatar_url = '', preferred_model = '', bio = '', country_code = '' WHERE player_id = ?`, []any{playerID}},
+		{`UPDATE users SET player_id = NULL WHERE player_id = ?`, []any{playerID}},
+		{`DELETE FROM link_codes WHERE player_id = ?`, []any{playerID}},
+		{`DELETE FROM merge_suggestions WHERE player_id = ? OR other_player_id = ?`, []any{playerID, playerID}},
+		{`DELETE FROM player_merges WHERE target_player_id = ? OR source_player_id = ?`, []any{playerID, playerID}},
+		{`UPDATE player_anonymizations SET completed_at = ?, user_id = NULL WHERE player_id = ?`, []any{formatTimestamp(now), playerID}},
+		// The old names leave the search index unless another player
+		// still goes by them.
+		{`DELETE FROM search_names WHERE name NOT IN (
+			SELECT clean_name FROM players UNION SELECT clean_name FROM player_guids
+			UNION SELECT clean_name FROM player_names)`, nil},
+	}
+	for _, st := range steps {
+		if _, err := tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.InvalidateLeaderboardCache()
+	return nil
+}
+
+// hashGUID is idempotent so a retried anonymization doesn't rehash.
+func hashGUID(guid string) string {
+	if strings.HasPrefix(guid, anonymizedGUIDPrefix) {
+		return guid
+	}
+	sum := sha256.Sum256([]byte(guid))
+	return anonymizedGUIDPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestAnonymizePlayer(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "^1alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if err := s.RecordPlayerName(ctx, alice.ID, "^1alice", "alice"); err != nil {
+		t.Fatalf("RecordPlayerName: %v", err)
+	}
+	if err := s.CreateSession(ctx, &domain.Session{PlayerGUIDID: alice.ID, ServerID: srv.ID, JoinedAt: base,
+		IPAddress: "203.0.113.7", CountryCode: "NL", Country: "Netherlands", City: "Amsterdam"}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 12, 3, true, nil, nil, "", 0, true,
+		0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+		t.Fatalf("FlushMatchPlayerStats: %v", err)
+	}
+	if err := recordScoreboard(ctx, s.db, m.ID, []domain.MatchEndScore{
+		{ClientID: 0, GUID: "AAAA", Name: "^1alice", Score: 12},
+		{ClientID: 1, Name: "bob", Score: 3},
+	}); err != nil {
+		t.Fatalf("recordScoreboard: %v", err)
+	}
+	if err := s.CreateUser(ctx, "alice", "hash", false, &alice.PlayerID); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := s.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+
+	// Requested, then cancelled, then requested again.
+	if _, err := s.RequestAnonymization(ctx, alice.PlayerID, user.ID, base, base.Add(7*24*time.Hour)); err != nil {
+		t.Fatalf("RequestAnonymization: %v", err)
+	}
+	if ok, err := s.CancelAnonymization(ctx, alice.PlayerID); err != nil || !ok {
+		t.Fatalf("CancelAnonymization: %v, %v", ok, err)
+	}
+	a, err := s.RequestAnonymization(ctx, alice.PlayerID, user.ID, base, base.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("RequestAnonymization: %v", err)
+	}
+	if a.CompletedAt != nil || !a.ExecuteAfter.Equal(base.Add(7*24*time.Hour)) {
+		t.Fatalf("request: got %+v", a)
+	}
+
+	if due, _ := s.DueAnonymizations(ctx, base.Add(24*time.Hour)); len(due) != 0 {
+		t.Fatalf("due during cooling-off: %v", due)
+	}
+	due, err := s.DueAnonymizations(ctx, base.Add(8*24*time.Hour))
+	if err != nil || len(due) != 1 || due[0] != alice.PlayerID {
+		t.Fatalf("due after cooling-off: %v, %v", due, err)
+	}
+	if err := s.AnonymizePlayer(ctx, alice.PlayerID, base.Add(8*24*time.Hour)); err != nil {
+		t.Fatalf("AnonymizePlayer: %v", err)
+	}
+
+	p, err := s.GetPlayerByID(ctx, alice.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerByID: %v", err)
+	}
+	if want := fmt.Sprintf("Anonymous#%d", alice.PlayerID); p.Name != want || p.CleanName != want {
+		t.Errorf("player name: got %q / %q", p.Name, p.CleanName)
+	}
+	if pg, _ := s.GetPlayerGUIDByGUID(ctx, "AAAA"); pg != nil {
+		t.Errorf("raw GUID still resolves to %+v", pg)
+	}
+	if names, _ := s.GetPlayerNames(ctx, alice.PlayerID); len(names) != 0 {
+		t.Errorf("name history kept: %+v", names)
+	}
+	var ip, city string
+	if err := s.db.QueryRowContext(ctx, `SELECT ip_address, city FROM sessions WHERE player_guid_id = ?`, alice.ID).Scan(&ip, &city); err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	if ip != "" || city != "" {
+		t.Errorf("session location kept: ip=%q city=%q", ip, city)
+	}
+	if u, _ := s.GetUserByID(ctx, user.ID); u.PlayerID != nil {
+		t.Errorf("user still linked to player %d", *u.PlayerID)
+	}
+	resp, err := s.GetPlayerStatsByID(ctx, alice.PlayerID, "all")
+	if err != nil || resp.Stats.Frags != 12 {
+		t.Errorf("match stats: %+v, %v", resp, err)
+	}
+	if sb, err := s.getScoreboard(ctx, m.ID); err != nil || len(sb) != 2 ||
+		sb[0].Name != p.Name || sb[0].Score != 12 || sb[1].Name != "bob" {
+		t.Errorf("scoreboard: %+v, %v", sb, err)
+	}
+	if a, _ := s.GetAnonymization(ctx, alice.PlayerID); a == nil || a.CompletedAt == nil || a.UserID != nil {
+		t.Errorf("request after completion: %+v", a)
+	}
+	if ok, _ := s.CancelAnonymization(ctx, alice.PlayerID); ok {
+		t.Error("completed request should not be cancellable")
+	}
+
+	// The same client coming back is a new player.
+	again, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base.Add(9*24*time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if again.PlayerID == alice.PlayerID {
+		t.Error("returning GUID reattached to the anonymized player")
+	}
+}
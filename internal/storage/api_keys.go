@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// APIKey is a key a user made for a bot or script. The key itself is
+// only ever shown once; Prefix is enough to recognise it by.
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	Username   string
+	Name       string
+	Prefix     string
+	Scope      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// CreateAPIKey stores the hash of a new key for userID and returns its
+// id.
+func (s *Store) CreateAPIKey(ctx context.Context, userID int64, name, keyHash, prefix, scope string, createdAt time.Time) (int64, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, prefix, scope, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, name, keyHash, prefix, scope, formatTimestamp(createdAt))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// CountActiveAPIKeys returns how many unrevoked keys userID has.
+func (s *Store) CountActiveAPIKeys(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := s.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked_at IS NULL`,
+		userID).Scan(&n)
+	return n, err
+}
+
+// GetAPIKeyUser returns the unrevoked key hashed keyHash and the user
+// it belongs to, or sql.ErrNoRows.
+func (s *Store) GetAPIKeyUser(ctx context.Context, keyHash string) (*APIKey, *User, error) {
+	var (
+		k          APIKey
+		lastUsedAt sql.NullTime
+		u          User
+		lastLogin  sql.NullTime
+		playerID   sql.NullInt64
+	)
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT k.id, k.name, k.prefix, k.scope, k.created_at, k.last_used_at,
+		       u.id, u.username, u.password_hash, u.is_admin, u.player_id, u.password_change_required, u.created_at, u.last_login, u.game_token
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL
+	`, keyHash).Scan(&k.ID, &k.Name, &k.Prefix, &k.Scope, &k.CreatedAt, &lastUsedAt,
+		&u.ID, &u.Username, &u.PasswordHash, &u.IsAdmin, &playerID, &u.PasswordChangeRequired, &u.CreatedAt, &lastLogin, &u.GameToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	k.UserID, k.Username = u.ID, u.Username
+	k.LastUsedAt = scanNullTime(lastUsedAt)
+	u.LastLogin = scanNullTime(lastLogin)
+	u.PlayerID = scanNullInt64Ptr(playerID)
+	return &k, &u, nil
+}
+
+// TouchAPIKey records that key id was used at at. It only writes once
+// a minute per key, so a busy bot doesn't turn every read into a write.
+func (s *Store) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = ?
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)
+	`, formatTimestamp(at), id, formatTimestamp(at.Add(-time.Minute)))
+	return err
+}
+
+// ListAPIKeys returns userID's keys, or everyone's if userID is zero,
+// newest first.
+func (s *Store) ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT k.id, k.user_id, u.username, k.name, k.prefix, k.scope, k.created_at, k.last_used_at, k.revoked_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE ? = 0 OR k.user_id = ?
+		ORDER BY k.created_at DESC, k.id DESC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		var lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Username, &k.Name, &k.Prefix, &k.Scope, &k.CreatedAt,
+			&lastUsedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		k.LastUsedAt = scanNullTime(lastUsedAt)
+		k.RevokedAt = scanNullTime(revokedAt)
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey revokes key id. If userID is non-zero the key must be
+// theirs. Returns sql.ErrNoRows if there's no such unrevoked key.
+func (s *Store) RevokeAPIKey(ctx context.Context, id, userID int64, at time.Time) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND (? = 0 OR user_id = ?) AND revoked_at IS NULL
+	`, formatTimestamp(at), id, userID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeUserAPIKeys revokes every key userID has.
+func (s *Store) RevokeUserAPIKeys(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		formatTimestamp(at), userID)
+	return err
+}
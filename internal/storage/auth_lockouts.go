@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Kinds of key an auth lockout is counted against.
+const (
+	LockoutIP       = "ip"
+	LockoutUsername = "username"
+)
+
+// AuthLockout is the failed-attempt count for one IP or username.
+type AuthLockout struct {
+	Kind          string
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// GetAuthLockout returns the count for kind and key, or sql.ErrNoRows
+// if it has no recent failures.
+func (s *Store) GetAuthLockout(ctx context.Context, kind, key string) (*AuthLockout, error) {
+	l := &AuthLockout{Kind: kind, Key: key}
+	var lockedUntil sql.NullTime
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT failures, last_failure_at, locked_until FROM auth_lockouts WHERE kind = ? AND key = ?
+	`, kind, key).Scan(&l.Failures, &l.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	l.LockedUntil = scanNullTime(lockedUntil)
+	return l, nil
+}
+
+// RecordAuthFailure counts a failed attempt against kind and key at at
+// and locks it out for lockFor(failures), if that's positive. A count
+// whose last failure is older than forgetAfter starts over, and stale
+// counts for other keys are cleared out while it's there.
+func (s *Store) RecordAuthFailure(ctx context.Context, kind, key string, at time.Time, forgetAfter time.Duration, lockFor func(failures int) time.Duration) (*AuthLockout, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	forgetBefore := formatTimestamp(at.Add(-forgetAfter))
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM auth_lockouts
+		WHERE last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)
+	`, forgetBefore, formatTimestamp(at)); err != nil {
+		return nil, err
+	}
+
+	l := &AuthLockout{Kind: kind, Key: key, LastFailureAt: at}
+	err = tx.QueryRowContext(ctx, `SELECT failures FROM auth_lockouts WHERE kind = ? AND key = ?`,
+		kind, key).Scan(&l.Failures)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	l.Failures++
+	var lockedUntil any
+	if d := lockFor(l.Failures); d > 0 {
+		until := at.Add(d)
+		l.LockedUntil = &until
+		lockedUntil = formatTimestamp(until)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO auth_lockouts (kind, key, failures, last_failure_at, locked_until)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET
+			failures = excluded.failures,
+			last_failure_at = excluded.last_failure_at,
+			locked_until = excluded.locked_until
+	`, kind, key, l.Failures, formatTimestamp(at), lockedUntil); err != nil {
+		return nil, err
+	}
+	return l, tx.Commit()
+}
+
+// ClearAuthLockout forgets the failures counted against kind and key.
+// Returns sql.ErrNoRows if there weren't any.
+func (s *Store) ClearAuthLockout(ctx context.Context, kind, key string) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM auth_lockouts WHERE kind = ? AND key = ?`, kind, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListAuthLockouts returns every counted key, most recent failure
+// first.
+func (s *Store) ListAuthLockouts(ctx context.Context) ([]AuthLockout, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT kind, key, failures, last_failure_at, locked_until
+		FROM auth_lockouts ORDER BY last_failure_at DESC, kind, key
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []AuthLockout{}
+	for rows.Next() {
+		var l AuthLockout
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&l.Kind, &l.Key, &l.Failures, &l.LastFailureAt, &lockedUntil); err != nil {
+			return nil, err
+		}
+		l.LockedUntil = scanNullTime(lockedUntil)
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ernie/trinity-tracker/internal/tracing"
+)
+
+// A batch runs many writes in one transaction. A log replayed at
+// startup arrives as a burst of fact events, and committing each of
+// their writes on its own costs a WAL sync apiece; inside a batch they
+// share one commit, and the index pages they touch are written out
+// once instead of once per statement.
+//
+// The batch travels in the context: store methods called with the
+// context BeginBatch returns join its transaction, and a method that
+// opens a transaction of its own gets a savepoint inside it instead,
+// so it still commits or rolls back as a unit. The batch holds the
+// store's only connection until it ends, so calls on any other
+// context wait for it, and the batch's own goroutine must not make
+// them.
+
+// querier is a *sql.DB, a *sql.Tx, or a savepoint.
+type querier interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// txn is a transaction: a *sql.Tx, or a savepoint in a batch.
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+type batchKey struct{}
+
+// Batch is a transaction that store calls made with its context join.
+type Batch struct {
+	store *Store
+	tx    *sql.Tx
+	// savepoints numbers the batch's savepoints, so nested ones get
+	// distinct names.
+	savepoints int
+}
+
+// BeginBatch starts a batch, returning the context to make the
+// batch's calls with. End it with Commit or Rollback.
+func (s *Store) BeginBatch(ctx context.Context) (context.Context, *Batch, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+	b := &Batch{store: s, tx: tx}
+	return context.WithValue(ctx, batchKey{}, b), b, nil
+}
+
+// Commit writes the batch's changes.
+func (b *Batch) Commit() error {
+	return b.tx.Commit()
+}
+
+// Rollback discards the batch's changes. It's a no-op after Commit.
+func (b *Batch) Rollback() error {
+	return b.tx.Rollback()
+}
+
+// batch returns the batch of s that ctx carries, if any.
+func (s *Store) batch(ctx context.Context) *Batch {
+	if b, ok := ctx.Value(batchKey{}).(*Batch); ok && b.store == s {
+		return b
+	}
+	return nil
+}
+
+// conn is where a call made with ctx runs: the batch ctx carries, or
+// the database. With tracing on, its statements are traced.
+func (s *Store) conn(ctx context.Context) querier {
+	var q querier = s.db
+	if b := s.batch(ctx); b != nil {
+		q = b.tx
+	}
+	if tracing.Enabled() {
+		return traced(q, 1)
+	}
+	return q
+}
+
+// beginTx opens a transaction, or a savepoint if ctx carries a batch.
+func (s *Store) beginTx(ctx context.Context) (txn, error) {
+	tx, err := s.openTx(ctx)
+	if err != nil || !tracing.Enabled() {
+		return tx, err
+	}
+	return &tracedTxn{tracedQuerier: traced(tx, 1), tx: tx, ctx: ctx}, nil
+}
+
+func (s *Store) openTx(ctx context.Context) (txn, error) {
+	b := s.batch(ctx)
+	if b == nil {
+		return s.db.BeginTx(ctx, nil)
+	}
+	b.savepoints++
+	sp := &savepoint{tx: b.tx, name: fmt.Sprintf("batch_sp%d", b.savepoints)}
+	if _, err := b.tx.ExecContext(ctx, "SAVEPOINT "+sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// savepoint is a transaction nested in a batch's.
+type savepoint struct {
+	tx   *sql.Tx
+	name string
+	done bool
+}
+
+func (p *savepoint) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.tx.ExecContext(ctx, query, args...)
+}
+
+func (p *savepoint) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.tx.QueryContext(ctx, query, args...)
+}
+
+func (p *savepoint) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (p *savepoint) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.tx.PrepareContext(ctx, query)
+}
+
+// Commit keeps the savepoint's changes in the batch.
+func (p *savepoint) Commit() error {
+	if p.done {
+		return sql.ErrTxDone
+	}
+	p.done = true
+	_, err := p.tx.Exec("RELEASE " + p.name)
+	return err
+}
+
+// Rollback undoes the savepoint's changes, leaving the rest of the
+// batch as it was. Like sql.Tx's, it's a no-op after Commit.
+func (p *savepoint) Rollback() error {
+	if p.done {
+		return sql.ErrTxDone
+	}
+	p.done = true
+	if _, err := p.tx.Exec("ROLLBACK TO " + p.name); err != nil {
+		return err
+	}
+	_, err := p.tx.Exec("RELEASE " + p.name)
+	return err
+}
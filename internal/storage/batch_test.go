@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestBatchSavepoints(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(filepath.Join(t.TempDir(), "trinity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	upsert := func(ctx context.Context, key string) {
+		t.Helper()
+		if err := s.UpsertServer(ctx, "test", &domain.Server{Key: key, Address: key + ":27960"}); err != nil {
+			t.Fatalf("UpsertServer(%s): %v", key, err)
+		}
+	}
+	insertIn := func(tx txn, key string) {
+		t.Helper()
+		if _, err := tx.ExecContext(ctx, `INSERT INTO servers (key, address, source) VALUES (?, ?, 'test')`, key, key+":27960"); err != nil {
+			t.Fatalf("insert %s: %v", key, err)
+		}
+	}
+
+	bctx, batch, err := s.BeginBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upsert(bctx, "kept")
+
+	// A transaction inside the batch is a savepoint: rolling it back
+	// leaves the rest of the batch alone.
+	tx, err := s.beginTx(bctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertIn(tx, "undone")
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("savepoint Rollback: %v", err)
+	}
+	tx, err = s.beginTx(bctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertIn(tx, "released")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("savepoint Commit: %v", err)
+	}
+	// As with sql.Tx, the deferred Rollback after Commit does nothing.
+	if err := tx.Rollback(); err == nil {
+		t.Error("Rollback after Commit: want ErrTxDone")
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// A batch that's rolled back leaves nothing.
+	bctx, batch, err = s.BeginBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upsert(bctx, "discarded")
+	if err := batch.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	servers, err := s.GetServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, srv := range servers {
+		got[srv.Key] = true
+	}
+	for key, want := range map[string]bool{"kept": true, "released": true, "undone": false, "discarded": false} {
+		if got[key] != want {
+			t.Errorf("server %s stored = %v, want %v", key, got[key], want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetBotStats summarizes finished matches over a leaderboard period,
+// optionally one game type: each bot character's record, most matches
+// first, and how humans did against each bot skill level.
+func (s *Store) GetBotStats(ctx context.Context, period, gameType string, asOf time.Time) (*domain.BotStatsResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+
+	where := "m.ended_at IS NOT NULL"
+	var args []any
+	if period != "all" {
+		where += " AND m.started_at >= ? AND m.started_at < ?"
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	if gameType != "" {
+		where += " AND m.game_type = ?"
+		args = append(args, gameType)
+	}
+
+	resp := &domain.BotStatsResponse{
+		Period:   period,
+		GameType: gameType,
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	var err error
+	if resp.Bots, err = s.botRecords(ctx, where, args); err != nil {
+		return nil, err
+	}
+	if resp.Skills, err = s.botSkillRecords(ctx, where, args); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *Store) botRecords(ctx context.Context, where string, args []any) ([]domain.BotStats, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name,
+			COUNT(DISTINCT m.id) as matches,
+			COUNT(DISTINCT CASE WHEN mps.victories > 0 THEN m.id END),
+			COALESCE(CAST(SUM(mps.frags) AS REAL) / COUNT(DISTINCT m.id), 0),
+			CASE WHEN SUM(mps.deaths) > 0
+				THEN CAST(SUM(mps.frags) AS REAL) / SUM(mps.deaths)
+				ELSE SUM(mps.frags) END,
+			COALESCE(AVG(mps.skill), 0)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		JOIN players p ON p.id = pg.player_id
+		JOIN matches m ON m.id = mps.match_id
+		WHERE `+where+` AND p.is_bot = TRUE AND mps.completed = 1
+		GROUP BY p.id
+		ORDER BY matches DESC, p.clean_name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bots := []domain.BotStats{}
+	for rows.Next() {
+		var b domain.BotStats
+		if err := rows.Scan(&b.PlayerID, &b.Name, &b.CleanName, &b.Matches, &b.Victories,
+			&b.AvgFrags, &b.KDRatio, &b.Skill); err != nil {
+			return nil, err
+		}
+		b.WinRate = float64(b.Victories) / float64(b.Matches)
+		bots = append(bots, b)
+	}
+	return bots, rows.Err()
+}
+
+func (s *Store) botSkillRecords(ctx context.Context, where string, args []any) ([]domain.BotSkillStats, error) {
+	// The bot skill levels present in each match, then the humans who
+	// completed those matches.
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		WITH bot_skills AS (
+			SELECT DISTINCT mps.match_id, CAST(ROUND(mps.skill) AS INTEGER) as skill
+			FROM match_player_stats mps
+			JOIN player_guids pg ON pg.id = mps.player_guid_id
+			JOIN players p ON p.id = pg.player_id
+			JOIN matches m ON m.id = mps.match_id
+			WHERE `+where+` AND p.is_bot = TRUE AND mps.skill > 0
+		)
+		SELECT bs.skill,
+			COUNT(DISTINCT bs.match_id),
+			COUNT(DISTINCT CASE WHEN h.completed = 1 THEN bs.match_id || ':' || pg.player_id END),
+			COUNT(DISTINCT CASE WHEN h.completed = 1 AND h.victories > 0 THEN bs.match_id || ':' || pg.player_id END),
+			COALESCE(SUM(CASE WHEN h.completed = 1 THEN h.frags END), 0),
+			COALESCE(SUM(CASE WHEN h.completed = 1 THEN h.deaths END), 0)
+		FROM bot_skills bs
+		LEFT JOIN match_player_stats h ON h.match_id = bs.match_id
+			AND h.player_guid_id IN (
+				SELECT pg2.id FROM player_guids pg2
+				JOIN players p2 ON p2.id = pg2.player_id
+				WHERE p2.is_bot = FALSE
+			)
+		LEFT JOIN player_guids pg ON pg.id = h.player_guid_id
+		GROUP BY bs.skill
+		ORDER BY bs.skill`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := []domain.BotSkillStats{}
+	for rows.Next() {
+		var k domain.BotSkillStats
+		var frags, deaths int64
+		if err := rows.Scan(&k.Skill, &k.Matches, &k.HumanMatches, &k.HumanVictories, &frags, &deaths); err != nil {
+			return nil, err
+		}
+		if k.HumanMatches > 0 {
+			k.HumanWinRate = float64(k.HumanVictories) / float64(k.HumanMatches)
+			k.HumanAvgFrags = float64(frags) / float64(k.HumanMatches)
+		}
+		k.HumanKDRatio = float64(frags)
+		if deaths > 0 {
+			k.HumanKDRatio = float64(frags) / float64(deaths)
+		}
+		skills = append(skills, k)
+	}
+	return skills, rows.Err()
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestBotStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	sarge, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", base)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+	// Alice beats Sarge at skill 2, then loses to him at skill 5.
+	for i, skill := range []float64{2, 5} {
+		start := base.Add(time.Duration(i) * time.Hour)
+		m := &domain.Match{UUID: string(rune('a' + i)), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: start}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		aliceWon := i == 0
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 20, 10, true, nil, nil, "", 0, aliceWon,
+			0, 0, 0, 0, 0, 0, 0, false, false, start, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, sarge.ID, 1, 10, 20, true, nil, nil, "", skill, !aliceWon,
+			0, 0, 0, 0, 0, 0, 0, true, false, start, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.EndMatch(ctx, m.ID, start.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	resp, err := s.GetBotStats(ctx, "all", "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetBotStats: %v", err)
+	}
+	if len(resp.Bots) != 1 {
+		t.Fatalf("bots: got %+v", resp.Bots)
+	}
+	b := resp.Bots[0]
+	if b.PlayerID != sarge.PlayerID || b.Matches != 2 || b.Victories != 1 || b.WinRate != 0.5 ||
+		b.AvgFrags != 10 || b.KDRatio != 0.5 || b.Skill != 3.5 {
+		t.Errorf("sarge: got %+v", b)
+	}
+	want := []domain.BotSkillStats{
+		{Skill: 2, Matches: 1, HumanMatches: 1, HumanVictories: 1, HumanWinRate: 1, HumanAvgFrags: 20, HumanKDRatio: 2},
+		{Skill: 5, Matches: 1, HumanMatches: 1, HumanAvgFrags: 20, HumanKDRatio: 2},
+	}
+	if len(resp.Skills) != 2 || resp.Skills[0] != want[0] || resp.Skills[1] != want[1] {
+		t.Errorf("skills: got %+v", resp.Skills)
+	}
+
+	resp, err = s.GetBotStats(ctx, "all", "ctf", time.Time{})
+	if err != nil {
+		t.Fatalf("GetBotStats: %v", err)
+	}
+	if len(resp.Bots) != 0 || len(resp.Skills) != 0 {
+		t.Errorf("ctf: got %+v", resp)
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// idPlaceholders returns "?,?,..." and the args for an IN clause.
+func idPlaceholders(ids []int64) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ","), args
+}
+
+// GetPlayerBriefs returns the players among ids, in id order. Unknown
+// ids are left out.
+func (s *Store) GetPlayerBriefs(ctx context.Context, ids []int64) ([]domain.PlayerBrief, error) {
+	out := []domain.PlayerBrief{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	in, args := idPlaceholders(ids)
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name,
+			(SELECT mps.model
+				FROM match_player_stats mps
+				JOIN player_guids pg ON mps.player_guid_id = pg.id
+				JOIN matches m ON mps.match_id = m.id
+				WHERE pg.player_id = p.id AND mps.model IS NOT NULL AND mps.model != ''
+				ORDER BY m.ended_at DESC
+				LIMIT 1),
+			p.is_bot, p.is_vr,
+			u.id IS NOT NULL, COALESCE(u.is_admin, 0)
+		FROM players p
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE p.id IN (`+in+`)
+		ORDER BY p.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p domain.PlayerBrief
+		var model sql.NullString
+		if err := rows.Scan(&p.ID, &p.Name, &p.CleanName, &model, &p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		p.Model = model.String
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetMatchBriefs returns the matches among ids, in id order. Unknown
+// ids are left out.
+func (s *Store) GetMatchBriefs(ctx context.Context, ids []int64) ([]domain.MatchBrief, error) {
+	out := []domain.MatchBrief{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	in, args := idPlaceholders(ids)
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT m.id, m.server_id, s.key, m.map_name, m.game_type, m.started_at, m.ended_at,
+			m.red_score, m.blue_score
+		FROM matches m
+		JOIN servers s ON m.server_id = s.id
+		WHERE m.id IN (`+in+`)
+		ORDER BY m.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m domain.MatchBrief
+		var endedAt sql.NullTime
+		var red, blue sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.ServerID, &m.ServerKey, &m.MapName, &m.GameType, &m.StartedAt, &endedAt, &red, &blue); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			m.EndedAt = &endedAt.Time
+		}
+		if red.Valid {
+			score := int(red.Int64)
+			m.RedScore = &score
+		}
+		if blue.Valid {
+			score := int(blue.Int64)
+			m.BlueScore = &score
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
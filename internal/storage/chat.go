@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// InsertMatchChat stores a chat line for matchID. PlayerID and
+// ToPlayerID may be nil for unresolved GUIDs.
+func (s *Store) InsertMatchChat(ctx context.Context, matchID int64, c domain.ChatLine) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO match_chat (match_id, kind, player_id, name, team, to_player_id, to_name, message, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, matchID, c.Kind, c.PlayerID, c.Name, c.Team, c.ToPlayerID, c.ToName, c.Message, formatTimestamp(c.SentAt))
+	return err
+}
+
+// GetMatchChat returns a match's chat in the order it was said. Tells
+// are left out unless includeTells is set.
+func (s *Store) GetMatchChat(ctx context.Context, matchID int64, includeTells bool) ([]domain.ChatLine, error) {
+	query := `
+		SELECT id, kind, player_id, name, team, to_player_id, to_name, message, sent_at
+		FROM match_chat WHERE match_id = ?`
+	if !includeTells {
+		query += ` AND kind != '` + domain.ChatTell + `'`
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, query+` ORDER BY sent_at, id`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.ChatLine{}
+	for rows.Next() {
+		var c domain.ChatLine
+		var playerID, toPlayerID sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.Kind, &playerID, &c.Name, &c.Team, &toPlayerID, &c.ToName, &c.Message, &c.SentAt); err != nil {
+			return nil, err
+		}
+		c.PlayerID = scanNullInt64Ptr(playerID)
+		c.ToPlayerID = scanNullInt64Ptr(toPlayerID)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// PruneMatchChat deletes chat sent before cutoff and returns how many
+// lines went.
+func (s *Store) PruneMatchChat(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM match_chat WHERE sent_at < ?`, formatTimestamp(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
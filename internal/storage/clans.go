@@ -0,0 +1,499 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+var (
+	ErrAlreadyInClan = errors.New("player is already in a clan")
+	ErrNotInClan     = errors.New("player is not in this clan")
+)
+
+// clanColumns selects a domain.Clan for scanClan, current members
+// counted.
+const clanColumns = `c.id, c.name, c.tag, c.created_by, c.created_at,
+	(SELECT COUNT(*) FROM clan_members cm WHERE cm.clan_id = c.id AND cm.left_at IS NULL)`
+
+func scanClan(s scanner) (domain.Clan, error) {
+	var c domain.Clan
+	var createdBy sql.NullInt64
+	err := s.Scan(&c.ID, &c.Name, &c.Tag, &createdBy, &c.CreatedAt, &c.MemberCount)
+	c.CreatedBy = scanNullInt64Ptr(createdBy)
+	return c, err
+}
+
+// CreateClan inserts c, filling in c.ID and c.CreatedAt. A non-nil
+// leaderID joins as its leader, and fails with ErrAlreadyInClan if
+// they're already in one. Players whose names carry the tag are then
+// added, so c.MemberCount counts them.
+func (s *Store) CreateClan(ctx context.Context, c *domain.Clan, leaderID *int64) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now().UTC()
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO clans (name, tag, created_by, created_at) VALUES (?, ?, ?, ?)
+	`, c.Name, c.Tag, c.CreatedBy, formatTimestamp(c.CreatedAt))
+	if err != nil {
+		return err
+	}
+	c.ID, _ = res.LastInsertId()
+	if leaderID != nil {
+		if err := joinClan(ctx, tx, c.ID, *leaderID, domain.ClanRoleLeader, domain.ClanSourceJoined, c.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if _, err := detectClanMembers(ctx, tx, c.CreatedAt); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM clan_members WHERE clan_id = ? AND left_at IS NULL
+	`, c.ID).Scan(&c.MemberCount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetClan returns the clan, or nil if it doesn't exist.
+func (s *Store) GetClan(ctx context.Context, id int64) (*domain.Clan, error) {
+	c, err := scanClan(s.conn(ctx).QueryRowContext(ctx, `SELECT `+clanColumns+` FROM clans c WHERE c.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListClans returns every clan, most members first.
+func (s *Store) ListClans(ctx context.Context) ([]domain.Clan, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `SELECT `+clanColumns+` FROM clans c`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	clans := []domain.Clan{}
+	for rows.Next() {
+		c, err := scanClan(rows)
+		if err != nil {
+			return nil, err
+		}
+		clans = append(clans, c)
+	}
+	slices.SortStableFunc(clans, func(a, b domain.Clan) int {
+		return cmp.Or(cmp.Compare(b.MemberCount, a.MemberCount), cmp.Compare(a.Name, b.Name))
+	})
+	return clans, rows.Err()
+}
+
+// UpdateClan renames the clan and changes its tag. Players who already
+// joined by the old tag stay. Returns sql.ErrNoRows if the clan
+// doesn't exist.
+func (s *Store) UpdateClan(ctx context.Context, id int64, name, tag string) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE clans SET name = ?, tag = ? WHERE id = ?`, name, tag, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteClan removes the clan and its memberships. Returns
+// sql.ErrNoRows if it doesn't exist.
+func (s *Store) DeleteClan(ctx context.Context, id int64) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM clans WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetClanMembers returns the clan's current members, its leader first,
+// then by when they joined.
+func (s *Store) GetClanMembers(ctx context.Context, clanID int64) ([]domain.ClanMember, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END, COALESCE(u.is_admin, 0),
+			cm.role, cm.source, cm.joined_at
+		FROM clan_members cm
+		JOIN players p ON p.id = cm.player_id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE cm.clan_id = ? AND cm.left_at IS NULL
+		ORDER BY cm.role = 'leader' DESC, cm.joined_at, p.id
+	`, clanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := []domain.ClanMember{}
+	for rows.Next() {
+		var m domain.ClanMember
+		if err := rows.Scan(&m.Player.ID, &m.Player.Name, &m.Player.CleanName, &m.Player.FirstSeen, &m.Player.LastSeen,
+			&m.Player.IsBot, &m.Player.IsVR, &m.Player.IsVerified, &m.Player.IsAdmin,
+			&m.Role, &m.Source, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetClanRole returns the player's role in the clan, or "" if they're
+// not a current member.
+func (s *Store) GetClanRole(ctx context.Context, clanID, playerID int64) (string, error) {
+	var role string
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT role FROM clan_members WHERE clan_id = ? AND player_id = ? AND left_at IS NULL
+	`, clanID, playerID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// JoinClan makes the player a member of the clan. source is a
+// domain.ClanSource* value. Fails with ErrAlreadyInClan if they're in
+// a clan already, this one included.
+func (s *Store) JoinClan(ctx context.Context, clanID, playerID int64, source string, at time.Time) error {
+	return joinClan(ctx, s.conn(ctx), clanID, playerID, domain.ClanRoleMember, source, at)
+}
+
+func joinClan(ctx context.Context, db execer, clanID, playerID int64, role, source string, at time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO clan_members (clan_id, player_id, role, source, joined_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(clan_id, player_id) DO UPDATE SET
+			role = excluded.role,
+			source = excluded.source,
+			joined_at = excluded.joined_at,
+			left_at = NULL
+	`, clanID, playerID, role, source, formatTimestamp(at))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint") {
+		return ErrAlreadyInClan
+	}
+	return err
+}
+
+// LeaveClan takes the player out of the clan. The row is kept so the
+// tag doesn't add them back. If the leader leaves, the longest-standing
+// member takes over. Fails with ErrNotInClan if they weren't a member.
+func (s *Store) LeaveClan(ctx context.Context, clanID, playerID int64, at time.Time) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE clan_members SET left_at = ?, role = ?
+		WHERE clan_id = ? AND player_id = ? AND left_at IS NULL
+	`, formatTimestamp(at), domain.ClanRoleMember, clanID, playerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotInClan
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE clan_members SET role = ?
+		WHERE clan_id = ? AND left_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM clan_members WHERE clan_id = ? AND left_at IS NULL AND role = ?)
+		  AND player_id = (SELECT player_id FROM clan_members WHERE clan_id = ? AND left_at IS NULL
+			ORDER BY joined_at, player_id LIMIT 1)
+	`, domain.ClanRoleLeader, clanID, clanID, domain.ClanRoleLeader, clanID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DetectClanMembers adds every player whose clean name starts or ends
+// with a clan's tag to that clan, unless they're in a clan already or
+// once left this one. Bots are skipped. Returns how many were added.
+func (s *Store) DetectClanMembers(ctx context.Context, at time.Time) (int64, error) {
+	return detectClanMembers(ctx, s.conn(ctx), at)
+}
+
+func detectClanMembers(ctx context.Context, db execer, at time.Time) (int64, error) {
+	// OR IGNORE drops a player whose name carries two tags from all but
+	// the first clan, as the one-current-clan index would refuse them.
+	res, err := db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO clan_members (clan_id, player_id, role, source, joined_at)
+		SELECT c.id, p.id, ?, ?, ?
+		FROM clans c
+		JOIN players p ON length(p.clean_name) > length(c.tag) AND (
+			lower(substr(p.clean_name, 1, length(c.tag))) = lower(c.tag) OR
+			lower(substr(p.clean_name, -length(c.tag))) = lower(c.tag))
+		WHERE p.is_bot = FALSE
+		  AND NOT EXISTS (SELECT 1 FROM clan_members cm
+			WHERE cm.player_id = p.id AND (cm.left_at IS NULL OR cm.clan_id = c.id))
+		ORDER BY c.id
+	`, domain.ClanRoleMember, domain.ClanSourceTag, formatTimestamp(at))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetClanStats returns the clan, its members and their summed stats
+// over period, or nil if the clan doesn't exist.
+func (s *Store) GetClanStats(ctx context.Context, clanID int64, period string) (*domain.ClanResponse, error) {
+	clan, err := s.GetClan(ctx, clanID)
+	if err != nil || clan == nil {
+		return nil, err
+	}
+	members, err := s.GetClanMembers(ctx, clanID)
+	if err != nil {
+		return nil, err
+	}
+	start, end := getTimePeriodBounds(period, time.Time{})
+	stats, err := s.clanStats(ctx, clanID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	resp := &domain.ClanResponse{Clan: *clan, Members: members, Period: period}
+	if st, ok := stats[clanID]; ok {
+		resp.Stats = *st
+	}
+	return resp, nil
+}
+
+// clanStats sums current members' stats for matches started in
+// [start, end), by clan. clanID 0 means every clan. Members excluded
+// from leaderboards don't count.
+func (s *Store) clanStats(ctx context.Context, clanID int64, start, end time.Time) (map[int64]*domain.ClanStats, error) {
+	query := `
+		SELECT cm.clan_id,
+			COUNT(DISTINCT mps.match_id),
+			COUNT(DISTINCT CASE WHEN mps.completed = 1 THEN mps.match_id END),
+			COALESCE(SUM(mps.frags), 0), COALESCE(SUM(mps.deaths), 0),
+			COALESCE(SUM(mps.captures), 0), COALESCE(SUM(mps.assists), 0),
+			COALESCE(SUM(mps.defends), 0), COALESCE(SUM(mps.victories), 0)
+		FROM clan_members cm
+		JOIN players p ON p.id = cm.player_id
+		JOIN player_guids pg ON pg.player_id = p.id
+		JOIN match_player_stats mps ON mps.player_guid_id = pg.id
+		JOIN matches m ON m.id = mps.match_id
+		WHERE cm.left_at IS NULL AND COALESCE(p.exclude_from_leaderboards, 0) = 0
+		  AND m.started_at >= ? AND m.started_at < ?`
+	args := []any{formatTimestamp(start), formatTimestamp(end)}
+	if clanID != 0 {
+		query += ` AND cm.clan_id = ?`
+		args = append(args, clanID)
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, query+` GROUP BY cm.clan_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]*domain.ClanStats)
+	for rows.Next() {
+		var id int64
+		var st domain.ClanStats
+		if err := rows.Scan(&id, &st.Matches, &st.CompletedMatches, &st.Frags, &st.Deaths,
+			&st.Captures, &st.Assists, &st.Defends, &st.Victories); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		st.KDRatio = kdRatio(st.Frags, st.Deaths)
+		out[id] = &st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Clan matches are read after the cursor above is closed: the
+	// store runs on a single connection.
+	matches, err := s.clanMatches(ctx, clanID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range matches {
+		st, ok := out[cm.clanID]
+		if !ok {
+			st = &domain.ClanStats{}
+			out[cm.clanID] = st
+		}
+		switch cm.Result {
+		case domain.ClanResultWin:
+			st.ClanWins++
+		case domain.ClanResultLoss:
+			st.ClanLosses++
+		}
+	}
+	return out, nil
+}
+
+// clanLeaderboardCategories are the stats clans can be ranked by.
+var clanLeaderboardCategories = map[string]func(domain.ClanStats) float64{
+	"frags":     func(s domain.ClanStats) float64 { return float64(s.Frags) },
+	"kd_ratio":  func(s domain.ClanStats) float64 { return s.KDRatio },
+	"matches":   func(s domain.ClanStats) float64 { return float64(s.CompletedMatches) },
+	"victories": func(s domain.ClanStats) float64 { return float64(s.Victories) },
+	"captures":  func(s domain.ClanStats) float64 { return float64(s.Captures) },
+	"clan_wins": func(s domain.ClanStats) float64 { return float64(s.ClanWins) },
+}
+
+// ValidClanCategory reports whether clans can be ranked by category.
+func ValidClanCategory(category string) bool {
+	_, ok := clanLeaderboardCategories[category]
+	return ok
+}
+
+// GetClanLeaderboard ranks clans by category over period, ties going
+// to the clan with more completed matches. Clans whose members haven't
+// played in the period are left off.
+func (s *Store) GetClanLeaderboard(ctx context.Context, category, period string, limit int) (*domain.ClanLeaderboardResponse, error) {
+	value, ok := clanLeaderboardCategories[category]
+	if !ok {
+		value = clanLeaderboardCategories["frags"]
+	}
+	start, end := getTimePeriodBounds(period, time.Time{})
+	stats, err := s.clanStats(ctx, 0, start, end)
+	if err != nil {
+		return nil, err
+	}
+	clans, err := s.ListClans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.ClanLeaderboardResponse{Category: category, Period: period, Entries: []domain.ClanLeaderboardEntry{}}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	for _, c := range clans {
+		if st, ok := stats[c.ID]; ok && st.Matches > 0 {
+			resp.Entries = append(resp.Entries, domain.ClanLeaderboardEntry{Clan: c, Stats: *st})
+		}
+	}
+	slices.SortStableFunc(resp.Entries, func(a, b domain.ClanLeaderboardEntry) int {
+		return cmp.Or(
+			cmp.Compare(value(b.Stats), value(a.Stats)),
+			cmp.Compare(b.Stats.CompletedMatches, a.Stats.CompletedMatches),
+		)
+	})
+	if len(resp.Entries) > limit {
+		resp.Entries = resp.Entries[:limit]
+	}
+	for i := range resp.Entries {
+		resp.Entries[i].Rank = i + 1
+	}
+	return resp, nil
+}
+
+// GetClanMatches returns the clan's latest limit clan matches, newest
+// first.
+func (s *Store) GetClanMatches(ctx context.Context, clanID int64, limit int) ([]domain.ClanMatch, error) {
+	start, end := getTimePeriodBounds("all", time.Time{})
+	matches, err := s.clanMatches(ctx, clanID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.ClanMatch, 0, min(len(matches), limit))
+	for _, m := range matches[:min(len(matches), limit)] {
+		out = append(out, m.ClanMatch)
+	}
+	return out, nil
+}
+
+type clanMatchRow struct {
+	clanID int64
+	domain.ClanMatch
+}
+
+// clanMatches finds finished team games started in [start, end) where
+// two or more of a clan's current members made up most of one team and
+// another clan's did the same on the other, newest first. Each match
+// appears once per clan. clanID 0 means every clan.
+func (s *Store) clanMatches(ctx context.Context, clanID int64, start, end time.Time) ([]clanMatchRow, error) {
+	query := `
+		WITH roster AS (
+			SELECT DISTINCT mps.match_id, mps.team, pg.player_id
+			FROM match_player_stats mps
+			JOIN player_guids pg ON pg.id = mps.player_guid_id
+			JOIN players p ON p.id = pg.player_id
+			JOIN matches m ON m.id = mps.match_id
+			WHERE mps.team IN (1, 2) AND p.is_bot = FALSE
+			  AND m.red_score IS NOT NULL AND m.blue_score IS NOT NULL
+			  AND m.started_at >= ? AND m.started_at < ?
+		),
+		sizes AS (
+			SELECT match_id, team, COUNT(*) AS size FROM roster GROUP BY match_id, team
+		),
+		sides AS (
+			SELECT r.match_id, r.team, cm.clan_id, COUNT(*) AS n
+			FROM roster r
+			JOIN clan_members cm ON cm.player_id = r.player_id AND cm.left_at IS NULL
+			GROUP BY r.match_id, r.team, cm.clan_id
+		),
+		owned AS (
+			SELECT s.match_id, s.team, s.clan_id, s.n
+			FROM sides s JOIN sizes z ON z.match_id = s.match_id AND z.team = s.team
+			WHERE s.n >= 2 AND s.n * 2 > z.size
+		)
+		SELECT a.clan_id, a.match_id, a.team, a.n, b.n, m.map_name, m.game_type, m.ended_at,
+			m.red_score, m.blue_score, ` + clanColumns + `
+		FROM owned a
+		JOIN owned b ON b.match_id = a.match_id AND b.team != a.team AND b.clan_id != a.clan_id
+		JOIN matches m ON m.id = a.match_id
+		JOIN clans c ON c.id = b.clan_id
+		WHERE m.ended_at IS NOT NULL`
+	args := []any{formatTimestamp(start), formatTimestamp(end)}
+	if clanID != 0 {
+		query += ` AND a.clan_id = ?`
+		args = append(args, clanID)
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, query+` ORDER BY m.ended_at DESC, m.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []clanMatchRow
+	for rows.Next() {
+		var r clanMatchRow
+		var red, blue int
+		var createdBy sql.NullInt64
+		o := &r.Opponent
+		if err := rows.Scan(&r.clanID, &r.MatchID, &r.Team, &r.Players, &r.OpponentPlayers, &r.MapName, &r.GameType, &r.EndedAt,
+			&red, &blue, &o.ID, &o.Name, &o.Tag, &createdBy, &o.CreatedAt, &o.MemberCount); err != nil {
+			return nil, err
+		}
+		o.CreatedBy = scanNullInt64Ptr(createdBy)
+		score, opp := red, blue
+		if r.Team == domain.TeamBlue {
+			score, opp = blue, red
+		}
+		r.Score, r.OpponentScore = &score, &opp
+		switch {
+		case score > opp:
+			r.Result = domain.ClanResultWin
+		case score < opp:
+			r.Result = domain.ClanResultLoss
+		default:
+			r.Result = domain.ClanResultTie
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestClans(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	player := func(guid, name string) *domain.PlayerGUID {
+		pg, err := s.UpsertPlayerGUID(ctx, guid, name, name, base, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		return pg
+	}
+	alice, bob := player("AAAA", "[TA]alice"), player("BBBB", "bob[ta]")
+	carol, dave := player("CCCC", "[XY]carol"), player("DDDD", "dave")
+	bot, err := s.UpsertBotPlayerGUID(ctx, "[TA]Sarge", "[TA]Sarge", base)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+
+	// The tag picks up alice and bob, but not the bot.
+	ta := &domain.Clan{Name: "Team Alpha", Tag: "[TA]"}
+	if err := s.CreateClan(ctx, ta, nil); err != nil {
+		t.Fatalf("CreateClan: %v", err)
+	}
+	if ta.MemberCount != 2 {
+		t.Errorf("tagged members: got %d, want 2", ta.MemberCount)
+	}
+	xy := &domain.Clan{Name: "XY", Tag: "[XY]"}
+	if err := s.CreateClan(ctx, xy, &dave.PlayerID); err != nil {
+		t.Fatalf("CreateClan: %v", err)
+	}
+	if err := s.CreateClan(ctx, &domain.Clan{Name: "team alpha", Tag: "[TB]"}, nil); err == nil {
+		t.Error("CreateClan with a taken name: want error")
+	}
+	if role, _ := s.GetClanRole(ctx, xy.ID, dave.PlayerID); role != domain.ClanRoleLeader {
+		t.Errorf("dave's role: got %q", role)
+	}
+	if err := s.JoinClan(ctx, ta.ID, carol.PlayerID, domain.ClanSourceJoined, base); !errors.Is(err, ErrAlreadyInClan) {
+		t.Errorf("JoinClan from another clan: got %v", err)
+	}
+
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3ctf1", GameType: "ctf", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	red, blue := domain.TeamRed, domain.TeamBlue
+	for clientID, pg := range []*domain.PlayerGUID{alice, bob, carol, dave, bot} {
+		team := &red
+		if pg == carol || pg == dave {
+			team = &blue
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, clientID, 10, 5, true, nil, team, "", 0, team == &red,
+			1, 0, 0, 0, 0, 0, 0, pg == bot, false, base, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+	}
+	redScore, blueScore := 3, 1
+	if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "capturelimit", &redScore, &blueScore); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	matches, err := s.GetClanMatches(ctx, ta.ID, 10)
+	if err != nil {
+		t.Fatalf("GetClanMatches: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Opponent.ID != xy.ID || matches[0].Result != domain.ClanResultWin ||
+		*matches[0].Score != 3 || matches[0].Players != 2 || matches[0].OpponentPlayers != 2 {
+		t.Errorf("clan matches: got %+v", matches)
+	}
+
+	resp, err := s.GetClanStats(ctx, ta.ID, "all")
+	if err != nil {
+		t.Fatalf("GetClanStats: %v", err)
+	}
+	if len(resp.Members) != 2 || resp.Stats.Frags != 20 || resp.Stats.Captures != 2 || resp.Stats.ClanWins != 1 {
+		t.Errorf("clan stats: got %+v", resp)
+	}
+
+	lb, err := s.GetClanLeaderboard(ctx, "clan_wins", "all", 10)
+	if err != nil {
+		t.Fatalf("GetClanLeaderboard: %v", err)
+	}
+	if len(lb.Entries) != 2 || lb.Entries[0].Clan.ID != ta.ID || lb.Entries[1].Stats.ClanLosses != 1 {
+		t.Errorf("leaderboard: got %+v", lb.Entries)
+	}
+
+	// A player who leaves isn't re-added by their tag, and the leader's
+	// place passes on.
+	if err := s.LeaveClan(ctx, ta.ID, alice.PlayerID, base); err != nil {
+		t.Fatalf("LeaveClan: %v", err)
+	}
+	if n, err := s.DetectClanMembers(ctx, base); err != nil || n != 0 {
+		t.Errorf("DetectClanMembers after leaving: got %d, %v", n, err)
+	}
+	if err := s.LeaveClan(ctx, xy.ID, dave.PlayerID, base); err != nil {
+		t.Fatalf("LeaveClan: %v", err)
+	}
+	if role, _ := s.GetClanRole(ctx, xy.ID, carol.PlayerID); role != domain.ClanRoleLeader {
+		t.Errorf("carol's role after dave left: got %q", role)
+	}
+	if err := s.LeaveClan(ctx, xy.ID, dave.PlayerID, base); !errors.Is(err, ErrNotInClan) {
+		t.Errorf("LeaveClan twice: got %v", err)
+	}
+	if err := s.JoinClan(ctx, ta.ID, alice.PlayerID, domain.ClanSourceJoined, base); err != nil {
+		t.Errorf("rejoining: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// CorrectableStatFields are the match_player_stats columns an admin
+// may adjust. Identity and timing columns (guid, client, team,
+// joined_at) are off limits; fixing those is a merge or split.
+var CorrectableStatFields = map[string]bool{
+	"frags":        true,
+	"deaths":       true,
+	"score":        true,
+	"captures":     true,
+	"flag_returns": true,
+	"assists":      true,
+	"impressives":  true,
+	"excellents":   true,
+	"humiliations": true,
+	"defends":      true,
+	"victories":    true,
+	"best_spree":   true,
+}
+
+// Correction errors. Handlers map ErrCorrectionTargetNotFound to 404
+// and the rest to 400.
+var (
+	ErrCorrectionTargetNotFound = errors.New("player did not play in this match")
+	ErrCorrectionAmbiguous      = errors.New("player has several rows in this match; client_id is required")
+	ErrCorrectionUnknownField   = errors.New("field cannot be corrected")
+	ErrCorrectionMatchLive      = errors.New("match has not ended yet")
+)
+
+// StatCorrection is one requested change to a player's match stats.
+// A nil Value clears the field (only meaningful for score).
+type StatCorrection struct {
+	Field string
+	Value *int64
+}
+
+// CorrectMatchPlayerStats applies changes to playerID's stats in
+// matchID and records each changed field, with its previous value, in
+// match_stat_corrections. clientID picks the row when the player has
+// more than one in the match (a reconnect under a new client slot).
+// Fields already holding the requested value are skipped. Only ended
+// matches can be corrected; a live match's rows are still being added
+// to by the collector. Returns the corrections that were recorded.
+func (s *Store) CorrectMatchPlayerStats(ctx context.Context, matchID, playerID int64, clientID *int, changes []StatCorrection, reason string, userID int64, at time.Time) ([]domain.MatchStatCorrection, error) {
+	for _, c := range changes {
+		if !CorrectableStatFields[c.Field] {
+			return nil, fmt.Errorf("%w: %s", ErrCorrectionUnknownField, c.Field)
+		}
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var endedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT ended_at FROM matches WHERE id = ?`, matchID).Scan(&endedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCorrectionTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !endedAt.Valid {
+		return nil, ErrCorrectionMatchLive
+	}
+
+	query := `
+		SELECT mps.player_guid_id, mps.client_id
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		WHERE mps.match_id = ? AND pg.player_id = ?`
+	args := []interface{}{matchID, playerID}
+	if clientID != nil {
+		query += ` AND mps.client_id = ?`
+		args = append(args, *clientID)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		guidID   int64
+		clientID int
+	}
+	var targets []key
+	for rows.Next() {
+		var k key
+		if err := rows.Scan(&k.guidID, &k.clientID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	switch {
+	case len(targets) == 0:
+		return nil, ErrCorrectionTargetNotFound
+	case len(targets) > 1:
+		return nil, ErrCorrectionAmbiguous
+	}
+	target := targets[0]
+
+	var out []domain.MatchStatCorrection
+	for _, c := range changes {
+		// c.Field is whitelisted above, so it's safe to splice in.
+		var old sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `
+			SELECT `+c.Field+` FROM match_player_stats
+			WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+		`, matchID, target.guidID, target.clientID).Scan(&old); err != nil {
+			return nil, err
+		}
+		oldValue := scanNullInt64Ptr(old)
+		if equalInt64Ptr(oldValue, c.Value) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE match_player_stats SET `+c.Field+` = ?
+			WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+		`, c.Value, matchID, target.guidID, target.clientID); err != nil {
+			return nil, err
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO match_stat_corrections
+				(match_id, player_guid_id, client_id, field, old_value, new_value, reason, corrected_by, corrected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, matchID, target.guidID, target.clientID, c.Field, oldValue, c.Value, reason, userID, formatTimestamp(at))
+		if err != nil {
+			return nil, err
+		}
+		id, _ := res.LastInsertId()
+		by := userID
+		out = append(out, domain.MatchStatCorrection{
+			ID:          id,
+			MatchID:     matchID,
+			PlayerID:    playerID,
+			ClientID:    target.clientID,
+			Field:       c.Field,
+			OldValue:    oldValue,
+			NewValue:    c.Value,
+			Reason:      reason,
+			CorrectedBy: &by,
+			CorrectedAt: at.UTC(),
+		})
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	// Totals, trends and records read these rows; bring them back in line
+	s.InvalidateLeaderboardCache()
+	if err := s.rebuildPlayerSnapshots(ctx, playerID); err != nil {
+		return out, err
+	}
+	var mapName, gameType string
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT map_name, game_type FROM matches WHERE id = ?`, matchID).Scan(&mapName, &gameType); err != nil {
+		return out, err
+	}
+	return out, s.RebuildRecords(ctx, mapName, gameType)
+}
+
+// GetMatchStatCorrections returns every correction made to matchID,
+// oldest first.
+func (s *Store) GetMatchStatCorrections(ctx context.Context, matchID int64) ([]domain.MatchStatCorrection, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT c.id, c.match_id, pg.player_id, c.client_id, c.field,
+			c.old_value, c.new_value, c.reason, c.corrected_by, c.corrected_at
+		FROM match_stat_corrections c
+		JOIN player_guids pg ON pg.id = c.player_guid_id
+		WHERE c.match_id = ?
+		ORDER BY c.id
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.MatchStatCorrection{}
+	for rows.Next() {
+		var c domain.MatchStatCorrection
+		var oldValue, newValue, by sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.MatchID, &c.PlayerID, &c.ClientID, &c.Field,
+			&oldValue, &newValue, &c.Reason, &by, &c.CorrectedAt); err != nil {
+			return nil, err
+		}
+		c.OldValue = scanNullInt64Ptr(oldValue)
+		c.NewValue = scanNullInt64Ptr(newValue)
+		c.CorrectedBy = scanNullInt64Ptr(by)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// CorrectableFieldNames lists CorrectableStatFields in a stable order,
+// for error messages and docs.
+func CorrectableFieldNames() []string {
+	names := make([]string, 0, len(CorrectableStatFields))
+	for f := range CorrectableStatFields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func equalInt64Ptr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
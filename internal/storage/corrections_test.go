@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestCorrectMatchPlayerStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	// The same person under two GUIDs, merged after the fact
+	alt, err := s.UpsertPlayerGUID(ctx, "BBBB", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	if err := s.CreateUser(ctx, "admin", "x", true, nil); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	admin, err := s.GetUserByUsername(ctx, "admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base.Add(-10 * time.Minute)}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	score := 7
+	for clientID, pg := range map[int]int64{2: alice.ID, 5: alt.ID} {
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, pg, clientID, 7, 3, true, &score, nil, "", 0, false,
+			0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+	}
+
+	twelve, three := int64(12), int64(3)
+	changes := []StatCorrection{{Field: "frags", Value: &twelve}, {Field: "deaths", Value: &three}}
+	if _, err := s.CorrectMatchPlayerStats(ctx, m.ID, alice.PlayerID, nil, changes, "too early", admin.ID, base); !errors.Is(err, ErrCorrectionMatchLive) {
+		t.Fatalf("live match: got %v", err)
+	}
+	if err := s.MergePlayers(ctx, alice.PlayerID, alt.PlayerID); err != nil {
+		t.Fatalf("MergePlayers: %v", err)
+	}
+	if err := s.EndMatch(ctx, m.ID, base, "fraglimit", nil, nil); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	// Two rows for one player: which is meant?
+	if _, err := s.CorrectMatchPlayerStats(ctx, m.ID, alice.PlayerID, nil, changes, "log parser dropped kills", admin.ID, base); !errors.Is(err, ErrCorrectionAmbiguous) {
+		t.Fatalf("ambiguous: got %v", err)
+	}
+	client := 5
+	got, err := s.CorrectMatchPlayerStats(ctx, m.ID, alice.PlayerID, &client, changes, "log parser dropped kills", admin.ID, base)
+	if err != nil {
+		t.Fatalf("CorrectMatchPlayerStats: %v", err)
+	}
+	// deaths already was 3, so only frags changed
+	if len(got) != 1 || got[0].Field != "frags" || *got[0].OldValue != 7 || *got[0].NewValue != 12 {
+		t.Fatalf("corrections: got %+v", got)
+	}
+
+	// A second pass keeps the chain: its old value is the first's new one
+	fifteen := int64(15)
+	if _, err := s.CorrectMatchPlayerStats(ctx, m.ID, alice.PlayerID, &client, []StatCorrection{{Field: "frags", Value: &fifteen}}, "recount", admin.ID, base.Add(time.Minute)); err != nil {
+		t.Fatalf("second correction: %v", err)
+	}
+	history, err := s.GetMatchStatCorrections(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchStatCorrections: %v", err)
+	}
+	if len(history) != 2 || *history[0].OldValue != 7 || *history[1].OldValue != 12 || *history[1].NewValue != 15 {
+		t.Fatalf("history: got %+v", history)
+	}
+	if history[0].PlayerID != alice.PlayerID || history[0].Reason != "log parser dropped kills" {
+		t.Errorf("history[0]: got %+v", history[0])
+	}
+
+	summary, err := s.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	var marked int
+	for _, p := range summary.Players {
+		if p.Corrected {
+			marked++
+			if p.Frags != 15 {
+				t.Errorf("corrected row frags: got %d, want 15", p.Frags)
+			}
+		}
+	}
+	if marked != 1 {
+		t.Errorf("corrected rows: got %d, want 1", marked)
+	}
+
+	if _, err := s.CorrectMatchPlayerStats(ctx, m.ID, alice.PlayerID, &client, []StatCorrection{{Field: "team", Value: &three}}, "x", admin.ID, base); !errors.Is(err, ErrCorrectionUnknownField) {
+		t.Errorf("unknown field: got %v", err)
+	}
+	if _, err := s.CorrectMatchPlayerStats(ctx, m.ID, 9999, nil, changes, "x", admin.ID, base); !errors.Is(err, ErrCorrectionTargetNotFound) {
+		t.Errorf("missing player: got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+const matchDemoColumns = `id, match_id, filename, size_bytes, uploaded_by, uploaded_at, last_downloaded_at`
+
+func scanMatchDemo(s scanner) (*domain.MatchDemo, error) {
+	var d domain.MatchDemo
+	var uploadedBy sql.NullInt64
+	var lastDownloaded sql.NullTime
+	if err := s.Scan(&d.ID, &d.MatchID, &d.Filename, &d.SizeBytes, &uploadedBy, &d.UploadedAt, &lastDownloaded); err != nil {
+		return nil, err
+	}
+	d.UploadedBy = scanNullInt64Ptr(uploadedBy)
+	d.LastDownloadedAt = scanNullTime(lastDownloaded)
+	return &d, nil
+}
+
+func collectMatchDemos(rows *sql.Rows) ([]domain.MatchDemo, error) {
+	defer rows.Close()
+	out := []domain.MatchDemo{}
+	for rows.Next() {
+		d, err := scanMatchDemo(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// CreateMatchDemo records an uploaded demo. The caller moves the file
+// into place under the returned ID. userID is nil for harvested demos.
+func (s *Store) CreateMatchDemo(ctx context.Context, matchID int64, filename string, size int64, userID *int64, at time.Time) (*domain.MatchDemo, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO match_demos (match_id, filename, size_bytes, uploaded_by, uploaded_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, matchID, filename, size, userID, formatTimestamp(at))
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMatchDemo(ctx, id)
+}
+
+// GetMatchDemo returns a demo by ID, or nil if there is none.
+func (s *Store) GetMatchDemo(ctx context.Context, id int64) (*domain.MatchDemo, error) {
+	d, err := scanMatchDemo(s.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+matchDemoColumns+` FROM match_demos WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// ListMatchDemos returns a match's demos, newest first.
+func (s *Store) ListMatchDemos(ctx context.Context, matchID int64) ([]domain.MatchDemo, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT `+matchDemoColumns+` FROM match_demos WHERE match_id = ? ORDER BY id DESC`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	return collectMatchDemos(rows)
+}
+
+// ListRecentDemos returns the newest uploads across all matches.
+func (s *Store) ListRecentDemos(ctx context.Context, limit int) ([]domain.MatchDemo, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx,
+		`SELECT `+matchDemoColumns+` FROM match_demos ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectMatchDemos(rows)
+}
+
+// LatestMatchDemoIDs maps each of matchIDs that has an uploaded demo to
+// its newest one.
+func (s *Store) LatestMatchDemoIDs(ctx context.Context, matchIDs []int64) (map[int64]int64, error) {
+	out := make(map[int64]int64)
+	if len(matchIDs) == 0 {
+		return out, nil
+	}
+	placeholders := make([]string, len(matchIDs))
+	args := make([]interface{}, len(matchIDs))
+	for i, id := range matchIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT match_id, MAX(id) FROM match_demos
+		WHERE match_id IN (`+strings.Join(placeholders, ",")+`)
+		GROUP BY match_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var matchID, demoID int64
+		if err := rows.Scan(&matchID, &demoID); err != nil {
+			return nil, err
+		}
+		out[matchID] = demoID
+	}
+	return out, rows.Err()
+}
+
+// TouchMatchDemo notes a download, which keeps the demo from being the
+// first to go when the quota is enforced.
+func (s *Store) TouchMatchDemo(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx,
+		`UPDATE match_demos SET last_downloaded_at = ? WHERE id = ?`, formatTimestamp(at), id)
+	return err
+}
+
+// DeleteMatchDemo removes a demo's row. The caller removes the file.
+func (s *Store) DeleteMatchDemo(ctx context.Context, id int64) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM match_demos WHERE id = ?`, id)
+	return err
+}
+
+// DemoStorageBytes is the total size of every uploaded demo.
+func (s *Store) DemoStorageBytes(ctx context.Context) (int64, error) {
+	var total int64
+	err := s.conn(ctx).QueryRowContext(ctx, `SELECT COALESCE(SUM(size_bytes), 0) FROM match_demos`).Scan(&total)
+	return total, err
+}
+
+// DemosOverQuota returns the demos to delete to bring the total under
+// quota bytes, least recently used (downloaded, else uploaded) first.
+// keepID is never chosen, so a fresh upload isn't evicted by itself.
+func (s *Store) DemosOverQuota(ctx context.Context, quota, keepID int64) ([]domain.MatchDemo, error) {
+	total, err := s.DemoStorageBytes(ctx)
+	if err != nil || total <= quota {
+		return nil, err
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT `+matchDemoColumns+` FROM match_demos
+		WHERE id != ?
+		ORDER BY COALESCE(last_downloaded_at, uploaded_at), id
+	`, keepID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := collectMatchDemos(rows)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.MatchDemo
+	for _, d := range all {
+		if total <= quota {
+			break
+		}
+		out = append(out, d)
+		total -= d.SizeBytes
+	}
+	return out, nil
+}
+
+// IsDemoHarvested reports whether the harvester already handled the
+// file at path with this size and modification time.
+func (s *Store) IsDemoHarvested(ctx context.Context, path string, size int64, modTime time.Time) (bool, error) {
+	var n int
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM harvested_demos
+		WHERE path = ? AND size_bytes = ? AND mod_time = ?
+	`, path, size, formatTimestamp(modTime)).Scan(&n)
+	return n > 0, err
+}
+
+// RecordHarvestedDemo marks the file at path as handled. demoID is nil
+// when no match fit it.
+func (s *Store) RecordHarvestedDemo(ctx context.Context, path string, size int64, modTime time.Time, demoID *int64, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO harvested_demos (path, size_bytes, mod_time, demo_id, harvested_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			size_bytes = excluded.size_bytes,
+			mod_time = excluded.mod_time,
+			demo_id = excluded.demo_id,
+			harvested_at = excluded.harvested_at
+	`, path, size, formatTimestamp(modTime), demoID, formatTimestamp(at))
+	return err
+}
+
+// FindDemoMatch picks the finished match on the (source, key) server
+// that a recorded demo belongs to: the match whose UUID is the file's
+// name, else the latest one running at recordedAt. Recording stops
+// shortly after the match ends, so recordedAt may trail ended_at by up
+// to slack. Returns nil when nothing fits.
+func (s *Store) FindDemoMatch(ctx context.Context, source, key, uuid string, recordedAt time.Time, slack time.Duration) (*int64, error) {
+	var id int64
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT m.id FROM matches m
+		JOIN servers sv ON sv.id = m.server_id
+		WHERE sv.source = ? AND sv.key = ? COLLATE NOCASE
+		  AND m.ended_at IS NOT NULL
+		  AND (m.uuid = ? OR (m.started_at <= ? AND m.ended_at >= ?))
+		ORDER BY m.uuid = ? DESC, m.started_at DESC
+		LIMIT 1
+	`, source, key, uuid, formatTimestamp(recordedAt), formatTimestamp(recordedAt.Add(-slack)), uuid).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// Demo sort orders for ListDemos. Each sorts largest or newest first
+// unless DemoFilter.Ascending is set.
+const (
+	DemoSortDate   = "date"
+	DemoSortSize   = "size"
+	DemoSortLength = "length"
+)
+
+// DemoFilter narrows ListDemos. Zero fields don't filter.
+type DemoFilter struct {
+	MapName   string
+	GameType  string
+	PlayerID  *int64 // demos of matches the player took part in
+	StartDate *time.Time
+	EndDate   *time.Time
+	Sort      string // a DemoSort* constant; "" means DemoSortDate
+	Ascending bool
+	Limit     int
+	Offset    int
+}
+
+// demoSortColumns maps each DemoSort* to its ORDER BY expression.
+var demoSortColumns = map[string]string{
+	DemoSortDate:   "m.started_at",
+	DemoSortSize:   "d.size_bytes",
+	DemoSortLength: "duration",
+}
+
+// ListDemos returns a page of demos matching filter along with their
+// matches, and how many demos match in all.
+func (s *Store) ListDemos(ctx context.Context, filter DemoFilter) ([]domain.DemoListing, int, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	orderBy, ok := demoSortColumns[filter.Sort]
+	if !ok {
+		orderBy = demoSortColumns[DemoSortDate]
+	}
+	dir := "DESC"
+	if filter.Ascending {
+		dir = "ASC"
+	}
+
+	where := ` WHERE 1=1`
+	var args []interface{}
+	if filter.MapName != "" {
+		where += ` AND m.map_name = ? COLLATE NOCASE`
+		args = append(args, filter.MapName)
+	}
+	if filter.GameType != "" {
+		where += ` AND m.game_type = ?`
+		args = append(args, filter.GameType)
+	}
+	if filter.PlayerID != nil {
+		where += ` AND EXISTS (
+			SELECT 1 FROM match_player_stats mps
+			JOIN player_guids pg ON pg.id = mps.player_guid_id
+			WHERE mps.match_id = m.id AND pg.player_id = ?)`
+		args = append(args, *filter.PlayerID)
+	}
+	if filter.StartDate != nil {
+		where += ` AND m.started_at >= ?`
+		args = append(args, formatTimestamp(*filter.StartDate))
+	}
+	if filter.EndDate != nil {
+		where += ` AND m.started_at <= ?`
+		args = append(args, formatTimestamp(*filter.EndDate))
+	}
+	const from = ` FROM match_demos d
+		JOIN matches m ON m.id = d.match_id
+		JOIN servers sv ON sv.id = m.server_id`
+
+	var total int
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT d.id, d.match_id, d.filename, d.size_bytes, d.uploaded_by, d.uploaded_at, d.last_downloaded_at,
+			m.server_id, sv.key, sv.source, COALESCE(m.map_name, ''), COALESCE(m.game_type, ''), m.started_at, m.ended_at,
+			COALESCE(CAST(ROUND((julianday(m.ended_at) - julianday(m.started_at)) * 86400) AS INTEGER), 0) AS duration,
+			(SELECT COUNT(DISTINCT mps.player_guid_id) FROM match_player_stats mps WHERE mps.match_id = m.id)`+from+where+`
+		ORDER BY `+orderBy+` `+dir+`, d.id `+dir+`
+		LIMIT ? OFFSET ?`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	out := []domain.DemoListing{}
+	for rows.Next() {
+		var l domain.DemoListing
+		var uploadedBy sql.NullInt64
+		var lastDownloaded, endedAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.MatchID, &l.Filename, &l.SizeBytes, &uploadedBy, &l.UploadedAt, &lastDownloaded,
+			&l.ServerID, &l.ServerKey, &l.Source, &l.MapName, &l.GameType, &l.StartedAt, &endedAt,
+			&l.DurationSeconds, &l.PlayerCount); err != nil {
+			return nil, 0, err
+		}
+		l.UploadedBy = scanNullInt64Ptr(uploadedBy)
+		l.LastDownloadedAt = scanNullTime(lastDownloaded)
+		l.EndedAt = scanNullTime(endedAt)
+		out = append(out, l)
+	}
+	return out, total, rows.Err()
+}
@@ -27,7 +27,7 @@ type DirectoryRegistration struct {
 // Order is unspecified — the directory restores into a map keyed by
 // addr, so order doesn't matter to the caller.
 func (s *Store) ListDirectoryRegistrations(ctx context.Context) ([]DirectoryRegistration, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT addr, server_id, protocol, gamename, engine,
 		       clients, max_clients, gametype, validated_at, expires_at
 		FROM directory_registrations
@@ -59,7 +59,7 @@ func (s *Store) ListDirectoryRegistrations(ctx context.Context) ([]DirectoryRegi
 // transaction. Passing an empty slice leaves the table empty (same as
 // ClearDirectoryRegistrations).
 func (s *Store) ReplaceDirectoryRegistrations(ctx context.Context, rows []DirectoryRegistration) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -94,7 +94,7 @@ func (s *Store) ReplaceDirectoryRegistrations(ctx context.Context, rows []Direct
 // ClearDirectoryRegistrations empties the table. Called on startup
 // when the persisted snapshot is older than the freshness window.
 func (s *Store) ClearDirectoryRegistrations(ctx context.Context) error {
-	if _, err := s.db.ExecContext(ctx, "DELETE FROM directory_registrations"); err != nil {
+	if _, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM directory_registrations"); err != nil {
 		return fmt.Errorf("storage: ClearDirectoryRegistrations: %w", err)
 	}
 	return nil
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetRecentDuels returns the latest limit duels the ladder counts,
+// newest first, as of asOf (zero means now). A non-zero playerID keeps
+// only theirs.
+func (s *Store) GetRecentDuels(ctx context.Context, asOf time.Time, limit int, playerID int64) (*domain.DuelListResponse, error) {
+	asOf, _, challenges, err := s.duelHistory(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.DuelListResponse{AsOf: asOf, Duels: latestDuels(challenges, limit, playerID)}, nil
+}
+
+// GetDuelRankings returns the top limit duellers by record as of asOf
+// (zero means now).
+func (s *Store) GetDuelRankings(ctx context.Context, asOf time.Time, limit int) (*domain.DuelRankingsResponse, error) {
+	asOf, entries, challenges, err := s.duelHistory(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	records := duelRecords(entries, challenges)
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return &domain.DuelRankingsResponse{AsOf: asOf, Entries: records}, nil
+}
+
+// GetPlayerDuels returns a player's duel record, their head-to-head
+// table and their latest limit duels, as of asOf (zero means now).
+func (s *Store) GetPlayerDuels(ctx context.Context, playerID int64, asOf time.Time, limit int) (*domain.PlayerDuelsResponse, error) {
+	asOf, entries, challenges, err := s.duelHistory(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	resp := &domain.PlayerDuelsResponse{
+		AsOf:      asOf,
+		Opponents: headToHead(playerID, challenges),
+		Recent:    latestDuels(challenges, limit, playerID),
+	}
+	for _, r := range duelRecords(entries, challenges) {
+		if r.Player.ID == playerID {
+			resp.Record = &r
+			break
+		}
+	}
+	return resp, nil
+}
+
+// latestDuels picks the last limit of challenges (oldest first),
+// newest first, keeping only playerID's if it's non-zero.
+func latestDuels(challenges []domain.DuelChallenge, limit int, playerID int64) []domain.DuelChallenge {
+	out := []domain.DuelChallenge{}
+	for i := len(challenges) - 1; i >= 0 && len(out) < limit; i-- {
+		c := challenges[i]
+		if playerID != 0 && c.Winner.ID != playerID && c.Loser.ID != playerID {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// duelRecords totals each ladder player's challenges, most wins first,
+// then best win rate, then highest on the ladder.
+func duelRecords(entries []domain.DuelLadderEntry, challenges []domain.DuelChallenge) []domain.DuelRecord {
+	records := make([]domain.DuelRecord, len(entries))
+	byID := make(map[int64]*domain.DuelRecord, len(entries))
+	for i, e := range entries {
+		records[i] = domain.DuelRecord{
+			Player:     e.Player,
+			Position:   e.Position,
+			Wins:       e.Wins,
+			Losses:     e.Losses,
+			WinRate:    float64(e.Wins) / float64(e.Wins+e.Losses),
+			LastDuelAt: e.LastDuelAt,
+		}
+		byID[e.Player.ID] = &records[i]
+	}
+	for _, c := range challenges {
+		w, l := byID[c.Winner.ID], byID[c.Loser.ID]
+		w.FragsFor += c.WinnerFrags
+		w.FragsAgainst += c.LoserFrags
+		l.FragsFor += c.LoserFrags
+		l.FragsAgainst += c.WinnerFrags
+	}
+	slices.SortStableFunc(records, func(a, b domain.DuelRecord) int {
+		return cmp.Or(
+			cmp.Compare(b.Wins, a.Wins),
+			cmp.Compare(b.WinRate, a.WinRate),
+			cmp.Compare(a.Position, b.Position),
+		)
+	})
+	return records
+}
+
+// headToHead is playerID's record against each opponent they've met,
+// most duels first, then most recent.
+func headToHead(playerID int64, challenges []domain.DuelChallenge) []domain.DuelHeadToHead {
+	out := []domain.DuelHeadToHead{}
+	index := make(map[int64]int)
+	for _, c := range challenges {
+		var opponent domain.Player
+		var won bool
+		var forFrags, againstFrags int
+		switch playerID {
+		case c.Winner.ID:
+			opponent, won, forFrags, againstFrags = c.Loser, true, c.WinnerFrags, c.LoserFrags
+		case c.Loser.ID:
+			opponent, won, forFrags, againstFrags = c.Winner, false, c.LoserFrags, c.WinnerFrags
+		default:
+			continue
+		}
+		i, ok := index[opponent.ID]
+		if !ok {
+			i = len(out)
+			index[opponent.ID] = i
+			out = append(out, domain.DuelHeadToHead{})
+		}
+		h := &out[i]
+		// The latest duel has the opponent's current name.
+		h.Opponent = opponent
+		if won {
+			h.Wins++
+		} else {
+			h.Losses++
+		}
+		h.FragsFor += forFrags
+		h.FragsAgainst += againstFrags
+		h.LastDuelAt = c.PlayedAt
+	}
+	slices.SortStableFunc(out, func(a, b domain.DuelHeadToHead) int {
+		return cmp.Or(
+			cmp.Compare(b.Wins+b.Losses, a.Wins+a.Losses),
+			b.LastDuelAt.Compare(a.LastDuelAt),
+		)
+	})
+	return out
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestDuelRecordsAndHeadToHead(t *testing.T) {
+	base := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	p := func(id int64) domain.Player { return domain.Player{ID: id} }
+	duel := func(day int, w, l int64, wf, lf int) duelResult {
+		return duelResult{matchID: int64(day), playedAt: base.Add(time.Duration(day) * 24 * time.Hour),
+			winner: p(w), loser: p(l), winnerFrags: wf, loserFrags: lf}
+	}
+
+	// 1 beats 2 twice and loses to 3 once; 3 beats 2.
+	duels := []duelResult{duel(0, 1, 2, 20, 10), duel(1, 1, 2, 20, 15), duel(2, 3, 1, 20, 19), duel(3, 3, 2, 20, 5)}
+	entries, challenges := buildDuelLadder(duels, base.Add(4*24*time.Hour))
+
+	records := duelRecords(entries, challenges)
+	if len(records) != 3 {
+		t.Fatalf("records: got %+v", records)
+	}
+	// 1 and 3 both have two wins; 3 has the better win rate.
+	if r := records[0]; r.Player.ID != 3 || r.Wins != 2 || r.Losses != 0 || r.WinRate != 1 || r.FragsFor != 40 || r.FragsAgainst != 24 {
+		t.Errorf("first: got %+v", r)
+	}
+	if r := records[1]; r.Player.ID != 1 || r.Wins != 2 || r.Losses != 1 || r.FragsFor != 59 || r.FragsAgainst != 45 {
+		t.Errorf("second: got %+v", r)
+	}
+	if r := records[2]; r.Player.ID != 2 || r.Wins != 0 || r.Losses != 3 || r.Position != 3 {
+		t.Errorf("third: got %+v", r)
+	}
+
+	h2h := headToHead(1, challenges)
+	if len(h2h) != 2 {
+		t.Fatalf("head to head: got %+v", h2h)
+	}
+	if h := h2h[0]; h.Opponent.ID != 2 || h.Wins != 2 || h.Losses != 0 || h.FragsFor != 40 || h.FragsAgainst != 25 {
+		t.Errorf("vs 2: got %+v", h)
+	}
+	if h := h2h[1]; h.Opponent.ID != 3 || h.Wins != 0 || h.Losses != 1 || !h.LastDuelAt.Equal(base.Add(2*24*time.Hour)) {
+		t.Errorf("vs 3: got %+v", h)
+	}
+
+	if got := latestDuels(challenges, 2, 2); len(got) != 2 || got[0].MatchID != 3 || got[1].MatchID != 1 {
+		t.Errorf("latest duels for 2: got %+v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import "time"
+
+// LeaderboardRules decide who places on the leaderboards. MinMatches
+// and MinPlaytime apply to GetLeaderboard: completed matches in the
+// board's period, and total time on the servers. The bot and
+// "[VR] Player#" placeholder filters apply to the weapon and accuracy
+// boards too, and so does leaving out abandoned matches' stats.
+// Admin-excluded players never place.
+type LeaderboardRules struct {
+	MinMatches            int
+	MinPlaytime           time.Duration
+	IncludeBots           bool
+	IncludeVRPlaceholders bool
+	IncludeAbandoned      bool
+}
+
+// DefaultLeaderboardRules apply until SetLeaderboardRules is called.
+var DefaultLeaderboardRules = LeaderboardRules{MinMatches: 5}
+
+// SetLeaderboardRules replaces the rules every leaderboard uses unless
+// a query brings its own, and drops cached results.
+func (s *Store) SetLeaderboardRules(r LeaderboardRules) {
+	s.lbCache.mu.Lock()
+	defer s.lbCache.mu.Unlock()
+	s.lbCache.rules = &r
+	s.lbCache.gen++
+	s.lbCache.entries = nil
+}
+
+// LeaderboardRules returns the rules in effect.
+func (s *Store) LeaderboardRules() LeaderboardRules {
+	s.lbCache.mu.Lock()
+	defer s.lbCache.mu.Unlock()
+	if s.lbCache.rules == nil {
+		return DefaultLeaderboardRules
+	}
+	return *s.lbCache.rules
+}
+
+// playerFilter is the WHERE condition on players p for these rules.
+func (r LeaderboardRules) playerFilter() string {
+	where := "COALESCE(p.exclude_from_leaderboards, 0) = 0"
+	if !r.IncludeBots {
+		where += " AND p.is_bot = FALSE"
+	}
+	if !r.IncludeVRPlaceholders {
+		where += " AND p.clean_name NOT LIKE '[VR] Player#%'"
+	}
+	return where
+}
+
+// matchFilter is a condition, starting with AND, that leaves
+// abandoned matches out of rows whose match_id is alias.match_id.
+// Empty when the rules include them.
+func (r LeaderboardRules) matchFilter(alias string) string {
+	if r.IncludeAbandoned {
+		return ""
+	}
+	return " AND " + alias + ".match_id NOT IN (SELECT id FROM matches WHERE abandoned = TRUE)"
+}
+
+// having is GetLeaderboard's HAVING clause for these rules, with its
+// arguments.
+func (r LeaderboardRules) having() (string, []any) {
+	return "HAVING completed_matches >= ? AND total_playtime_seconds >= ?",
+		[]any{r.MinMatches, int64(r.MinPlaytime / time.Second)}
+}
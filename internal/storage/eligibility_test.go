@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestLeaderboardRules(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bot, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", base)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+	// One ten-minute match each.
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	for c, pg := range []int64{alice.ID, bot.ID} {
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, pg, c, 20-c*10, 5, true, nil, nil, "", 0, c == 0,
+			0, 0, 0, 0, 0, 0, 0, c == 1, false, base, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+	}
+	if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	board := func(rules LeaderboardRules) int {
+		t.Helper()
+		resp, err := s.GetLeaderboardWithRules(ctx, rules, "frags", "all", 10, "", time.Time{})
+		if err != nil {
+			t.Fatalf("GetLeaderboardWithRules: %v", err)
+		}
+		return len(resp.Entries)
+	}
+	if n := board(DefaultLeaderboardRules); n != 0 {
+		t.Errorf("default rules: got %d entries, want 0", n)
+	}
+	if n := board(LeaderboardRules{MinMatches: 1}); n != 1 {
+		t.Errorf("one match: got %d entries, want 1", n)
+	}
+	if n := board(LeaderboardRules{MinMatches: 1, IncludeBots: true}); n != 2 {
+		t.Errorf("with bots: got %d entries, want 2", n)
+	}
+	if n := board(LeaderboardRules{MinMatches: 1, MinPlaytime: time.Hour}); n != 0 {
+		t.Errorf("an hour's playtime: got %d entries, want 0", n)
+	}
+
+	// The configured rules drive GetLeaderboard.
+	s.SetLeaderboardRules(LeaderboardRules{MinMatches: 1})
+	resp, err := s.GetLeaderboard(ctx, "frags", "all", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Player.ID != alice.PlayerID {
+		t.Errorf("configured rules: got %+v", resp.Entries)
+	}
+}
+
+func TestAbandonedMatchesOffLeaderboards(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	end := domain.MatchEndData{MatchUUID: "m1", EndedAt: base.Add(10 * time.Minute), ExitReason: "shutdown", Abandoned: true}
+	if _, err := s.FlushMatch(ctx, m, end, func(tx *MatchTx) {
+		if err := tx.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 20, 5, true, nil, nil, "", 0, false,
+			0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+	}); err != nil {
+		t.Fatalf("FlushMatch: %v", err)
+	}
+
+	summary, err := s.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	if !summary.Abandoned {
+		t.Error("summary isn't flagged abandoned")
+	}
+
+	board := func(rules LeaderboardRules) int {
+		t.Helper()
+		resp, err := s.GetLeaderboardWithRules(ctx, rules, "frags", "all", 10, "", time.Time{})
+		if err != nil {
+			t.Fatalf("GetLeaderboardWithRules: %v", err)
+		}
+		return len(resp.Entries)
+	}
+	if n := board(LeaderboardRules{MinMatches: 1}); n != 0 {
+		t.Errorf("abandoned match counted: got %d entries, want 0", n)
+	}
+	if n := board(LeaderboardRules{MinMatches: 1, IncludeAbandoned: true}); n != 1 {
+		t.Errorf("include abandoned: got %d entries, want 1", n)
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// SetLeaderboardExclusion flags or clears playerID's exclusion.
+// Clearing also drops the reason, and either way every map record is
+// recomputed. Returns sql.ErrNoRows if there is no such player.
+func (s *Store) SetLeaderboardExclusion(ctx context.Context, playerID int64, excluded bool, reason string) error {
+	var reasonArg interface{}
+	if excluded {
+		reasonArg = reason
+	}
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE players SET exclude_from_leaderboards = ?, leaderboard_exclusion_reason = ?
+		WHERE id = ?
+	`, excluded, reasonArg, playerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	s.InvalidateLeaderboardCache()
+	// The player may hold, or have been keeping someone from, a record
+	return s.RebuildRecords(ctx, "", "")
+}
+
+// ListLeaderboardExclusions returns every excluded player by name.
+func (s *Store) ListLeaderboardExclusions(ctx context.Context) ([]domain.LeaderboardExclusion, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, name, clean_name, COALESCE(leaderboard_exclusion_reason, '')
+		FROM players
+		WHERE exclude_from_leaderboards = 1
+		ORDER BY clean_name COLLATE NOCASE, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.LeaderboardExclusion{}
+	for rows.Next() {
+		var e domain.LeaderboardExclusion
+		if err := rows.Scan(&e.PlayerID, &e.Name, &e.CleanName, &e.Reason); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestLeaderboardExclusion(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	mallory, err := s.UpsertPlayerGUID(ctx, "MMMM", "mallory", "mallory", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		ended := base.Add(time.Duration(i-5) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: ended.Add(-10 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for c, pg := range []int64{alice.ID, mallory.ID} {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg, c, 10+c*40, 5, true, nil, nil, "", 0, c == 1,
+				0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, ended, "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	leaders := func() []string {
+		t.Helper()
+		resp, err := s.GetLeaderboard(ctx, "frags", "all", 10, "", base)
+		if err != nil {
+			t.Fatalf("GetLeaderboard: %v", err)
+		}
+		var names []string
+		for _, e := range resp.Entries {
+			names = append(names, e.Player.CleanName)
+		}
+		return names
+	}
+	if got := leaders(); len(got) != 2 || got[0] != "mallory" {
+		t.Fatalf("before: got %v", got)
+	}
+
+	if err := s.SetLeaderboardExclusion(ctx, mallory.PlayerID, true, "aimbot"); err != nil {
+		t.Fatalf("SetLeaderboardExclusion: %v", err)
+	}
+	if got := leaders(); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("excluded: got %v, want [alice]", got)
+	}
+	list, err := s.ListLeaderboardExclusions(ctx)
+	if err != nil {
+		t.Fatalf("ListLeaderboardExclusions: %v", err)
+	}
+	if len(list) != 1 || list[0].PlayerID != mallory.PlayerID || list[0].Reason != "aimbot" {
+		t.Errorf("list: got %+v", list)
+	}
+	// Match history is untouched.
+	last, err := s.LastMatchEndSeq(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("LastMatchEndSeq: %v", err)
+	}
+	rows, err := s.GetMatchPlayerRows(ctx, 0, last)
+	if err != nil {
+		t.Fatalf("GetMatchPlayerRows: %v", err)
+	}
+	if len(rows) != 10 {
+		t.Errorf("match rows: got %d, want 10", len(rows))
+	}
+
+	if err := s.SetLeaderboardExclusion(ctx, mallory.PlayerID, false, ""); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if got := leaders(); len(got) != 2 {
+		t.Errorf("cleared: got %v", got)
+	}
+	if err := s.SetLeaderboardExclusion(ctx, 9999, true, "x"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing player: got %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MatchPlayerRow is one player's line in one finished match, flattened
+// for export. Matches and players are denormalized into every row so
+// the file stands on its own in a spreadsheet or warehouse.
+type MatchPlayerRow struct {
+	MatchID      int64     `json:"match_id"`
+	MatchUUID    string    `json:"match_uuid"`
+	ServerSource string    `json:"server_source"`
+	ServerKey    string    `json:"server_key"`
+	MapName      string    `json:"map"`
+	GameType     string    `json:"game_type"`
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	ExitReason   string    `json:"exit_reason"`
+	RedScore     *int      `json:"red_score"`
+	BlueScore    *int      `json:"blue_score"`
+	PlayerID     int64     `json:"player_id"`
+	PlayerName   string    `json:"player_name"`
+	IsBot        bool      `json:"is_bot"`
+	IsVR         bool      `json:"is_vr"`
+	Team         *int      `json:"team"`
+	Score        *int      `json:"score"`
+	Frags        int64     `json:"frags"`
+	Deaths       int64     `json:"deaths"`
+	Completed    bool      `json:"completed"`
+	Victory      bool      `json:"victory"`
+	Captures     int64     `json:"captures"`
+	FlagReturns  int64     `json:"flag_returns"`
+	Assists      int64     `json:"assists"`
+	Impressives  int64     `json:"impressives"`
+	Excellents   int64     `json:"excellents"`
+	Humiliations int64     `json:"humiliations"`
+	Defends      int64     `json:"defends"`
+}
+
+// matchPlayerRowSelect is the column list and joins shared by the
+// match-row exports; scanMatchPlayerRow reads it back.
+const matchPlayerRowSelect = `
+	SELECT m.id, m.uuid, srv.source, srv.key,
+		COALESCE(m.map_name, ''), COALESCE(m.game_type, ''),
+		m.started_at, m.ended_at, COALESCE(m.exit_reason, ''),
+		m.red_score, m.blue_score,
+		p.id, p.clean_name, p.is_bot, mps.is_vr,
+		mps.team, mps.score, mps.frags, mps.deaths, mps.completed, mps.victories > 0,
+		mps.captures, mps.flag_returns, mps.assists,
+		mps.impressives, mps.excellents, mps.humiliations, mps.defends
+	FROM matches m
+	JOIN servers srv ON srv.id = m.server_id
+	JOIN match_player_stats mps ON mps.match_id = m.id
+	JOIN player_guids pg ON pg.id = mps.player_guid_id
+	JOIN players p ON p.id = pg.player_id`
+
+func scanMatchPlayerRow(rows *sql.Rows) (MatchPlayerRow, error) {
+	var r MatchPlayerRow
+	var startedAt, endedAt sql.NullTime
+	var redScore, blueScore, team, score sql.NullInt64
+	if err := rows.Scan(
+		&r.MatchID, &r.MatchUUID, &r.ServerSource, &r.ServerKey,
+		&r.MapName, &r.GameType,
+		&startedAt, &endedAt, &r.ExitReason,
+		&redScore, &blueScore,
+		&r.PlayerID, &r.PlayerName, &r.IsBot, &r.IsVR,
+		&team, &score, &r.Frags, &r.Deaths, &r.Completed, &r.Victory,
+		&r.Captures, &r.FlagReturns, &r.Assists,
+		&r.Impressives, &r.Excellents, &r.Humiliations, &r.Defends,
+	); err != nil {
+		return r, err
+	}
+	if startedAt.Valid {
+		r.StartedAt = startedAt.Time
+	}
+	if endedAt.Valid {
+		r.EndedAt = endedAt.Time
+	}
+	r.RedScore = scanNullInt64ToIntPtr(redScore)
+	r.BlueScore = scanNullInt64ToIntPtr(blueScore)
+	r.Team = scanNullInt64ToIntPtr(team)
+	r.Score = scanNullInt64ToIntPtr(score)
+	return r, nil
+}
+
+// LastMatchEndSeq returns the end_seq of the last match the hub
+// recorded as ended, or 0 if none has. With a non-zero endedBy, only
+// matches that ended by then count.
+func (s *Store) LastMatchEndSeq(ctx context.Context, endedBy time.Time) (int64, error) {
+	var seq int64
+	query, args := `SELECT COALESCE(MAX(end_seq), 0) FROM matches`, []any{}
+	if !endedBy.IsZero() {
+		query += ` WHERE ended_at <= ?`
+		args = append(args, formatTimestamp(endedBy))
+	}
+	err := s.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&seq)
+	return seq, err
+}
+
+// GetMatchPlayerRows returns a row per (match, player) for matches
+// whose end_seq is in (afterSeq, throughSeq], in the order the hub
+// recorded their ends. This is the order they reached the hub, not
+// the order they ended, so a match a collector sends late is still
+// after the last export. Matches that ended as "crashed" are
+// included; their exit_reason says so.
+func (s *Store) GetMatchPlayerRows(ctx context.Context, afterSeq, throughSeq int64) ([]MatchPlayerRow, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, matchPlayerRowSelect+`
+		WHERE m.end_seq > ? AND m.end_seq <= ?
+		ORDER BY m.end_seq ASC, m.id ASC, mps.score DESC
+	`, afterSeq, throughSeq)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MatchPlayerRow
+	for rows.Next() {
+		r, err := scanMatchPlayerRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// exportPageSize bounds how many matches (or players) each query in
+// the Each* walkers reads. The store has a single connection, so a
+// long export is done as a series of short queries rather than one
+// cursor held open for as long as a slow client takes to download.
+var exportPageSize = 500
+
+// EachMatchPlayerRow walks the same rows as GetMatchPlayerRows for
+// matches that ended in [from, to), a page of matches at a time.
+// fn runs with no query open, so it may block on I/O; returning an
+// error stops the walk.
+func (s *Store) EachMatchPlayerRow(ctx context.Context, from, to time.Time, fn func([]MatchPlayerRow) error) error {
+	afterEnded, afterID := formatTimestamp(from), int64(0)
+	first := true
+	for {
+		// Keyset over (ended_at, id). The first page includes from
+		// itself; later pages resume strictly after the last match.
+		rows, err := s.conn(ctx).QueryContext(ctx, `
+			WITH page AS (
+				SELECT id FROM matches
+				WHERE ended_at < ?
+				  AND (ended_at > ? OR (ended_at = ? AND (? OR id > ?)))
+				ORDER BY ended_at, id
+				LIMIT ?
+			)`+matchPlayerRowSelect+`
+			WHERE m.id IN (SELECT id FROM page)
+			ORDER BY m.ended_at ASC, m.id ASC, mps.score DESC
+		`, formatTimestamp(to), afterEnded, afterEnded, first, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		var page []MatchPlayerRow
+		matches := 0
+		for rows.Next() {
+			r, err := scanMatchPlayerRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if len(page) == 0 || page[len(page)-1].MatchID != r.MatchID {
+				matches++
+			}
+			page = append(page, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if matches < exportPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		afterEnded, afterID, first = formatTimestamp(last.EndedAt), last.MatchID, false
+	}
+}
+
+// PlayerExportRow is one player's totals over the matches in an
+// export's date range.
+type PlayerExportRow struct {
+	PlayerID         int64      `json:"player_id"`
+	Name             string     `json:"name"`
+	CleanName        string     `json:"clean_name"`
+	FirstSeen        *time.Time `json:"first_seen"`
+	LastSeen         *time.Time `json:"last_seen"`
+	IsBot            bool       `json:"is_bot"`
+	IsVR             bool       `json:"is_vr"`
+	Matches          int64      `json:"matches"`
+	CompletedMatches int64      `json:"completed_matches"`
+	Frags            int64      `json:"frags"`
+	Deaths           int64      `json:"deaths"`
+	KDRatio          float64    `json:"kd_ratio"`
+	Captures         int64      `json:"captures"`
+	FlagReturns      int64      `json:"flag_returns"`
+	Assists          int64      `json:"assists"`
+	Impressives      int64      `json:"impressives"`
+	Excellents       int64      `json:"excellents"`
+	Humiliations     int64      `json:"humiliations"`
+	Defends          int64      `json:"defends"`
+	Victories        int64      `json:"victories"`
+	BestSpree        int64      `json:"best_spree"`
+}
+
+// EachPlayerExportRow walks every player who played in a match that
+// ended in [from, to), in player id order, a page at a time. Like
+// EachMatchPlayerRow, fn runs with no query open.
+func (s *Store) EachPlayerExportRow(ctx context.Context, from, to time.Time, fn func([]PlayerExportRow) error) error {
+	var afterID int64
+	for {
+		rows, err := s.conn(ctx).QueryContext(ctx, `
+			SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
+				COALESCE(p.is_bot, 0), COALESCE(p.is_vr, 0),
+				COUNT(DISTINCT mps.match_id),
+				COUNT(DISTINCT CASE WHEN mps.completed = 1 THEN mps.match_id END),
+				COALESCE(SUM(mps.frags), 0), COALESCE(SUM(mps.deaths), 0),
+				COALESCE(SUM(mps.captures), 0), COALESCE(SUM(mps.flag_returns), 0),
+				COALESCE(SUM(mps.assists), 0), COALESCE(SUM(mps.impressives), 0),
+				COALESCE(SUM(mps.excellents), 0), COALESCE(SUM(mps.humiliations), 0),
+				COALESCE(SUM(mps.defends), 0), COALESCE(SUM(mps.victories), 0),
+				COALESCE(MAX(mps.best_spree), 0)
+			FROM players p
+			JOIN player_guids pg ON pg.player_id = p.id
+			JOIN match_player_stats mps ON mps.player_guid_id = pg.id
+			JOIN matches m ON m.id = mps.match_id
+			WHERE p.id > ? AND m.ended_at >= ? AND m.ended_at < ?
+			GROUP BY p.id
+			ORDER BY p.id
+			LIMIT ?
+		`, afterID, formatTimestamp(from), formatTimestamp(to), exportPageSize)
+		if err != nil {
+			return err
+		}
+		var page []PlayerExportRow
+		for rows.Next() {
+			var r PlayerExportRow
+			var firstSeen, lastSeen sql.NullTime
+			if err := rows.Scan(
+				&r.PlayerID, &r.Name, &r.CleanName, &firstSeen, &lastSeen,
+				&r.IsBot, &r.IsVR,
+				&r.Matches, &r.CompletedMatches,
+				&r.Frags, &r.Deaths,
+				&r.Captures, &r.FlagReturns,
+				&r.Assists, &r.Impressives,
+				&r.Excellents, &r.Humiliations,
+				&r.Defends, &r.Victories,
+				&r.BestSpree,
+			); err != nil {
+				rows.Close()
+				return err
+			}
+			r.FirstSeen = scanNullTime(firstSeen)
+			r.LastSeen = scanNullTime(lastSeen)
+			if r.Deaths > 0 {
+				r.KDRatio = float64(r.Frags) / float64(r.Deaths)
+			} else {
+				r.KDRatio = float64(r.Frags)
+			}
+			page = append(page, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].PlayerID
+	}
+}
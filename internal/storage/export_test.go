@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestGetMatchPlayerRowsWindow(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	play := func(uuid string, ended time.Time, frags int) {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: ended.Add(-10 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		score := frags
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, frags, 3, true, &score, nil, "", 0, true,
+			0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.EndMatch(ctx, m.ID, ended, "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+	play("m-old", base.Add(-2*time.Hour), 5)
+	after, err := s.LastMatchEndSeq(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("LastMatchEndSeq: %v", err)
+	}
+	play("m-new", base.Add(-30*time.Minute), 20)
+	through, err := s.LastMatchEndSeq(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("LastMatchEndSeq: %v", err)
+	}
+	if old, err := s.LastMatchEndSeq(ctx, base.Add(-time.Hour)); err != nil || old != after {
+		t.Errorf("LastMatchEndSeq(an hour ago) = %d, %v; want %d", old, err, after)
+	}
+
+	rows, err := s.GetMatchPlayerRows(ctx, after, through)
+	if err != nil {
+		t.Fatalf("GetMatchPlayerRows: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1: %+v", len(rows), rows)
+	}
+	r := rows[0]
+	if r.MatchUUID != "m-new" || r.PlayerName != "alice" || r.Frags != 20 || !r.Victory || !r.Completed {
+		t.Errorf("row: got %+v", r)
+	}
+	if r.Score == nil || *r.Score != 20 || r.Team != nil {
+		t.Errorf("score/team: got %v/%v, want 20/nil", r.Score, r.Team)
+	}
+}
+
+func TestEachExportRowPages(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	prev := exportPageSize
+	exportPageSize = 2
+	t.Cleanup(func() { exportPageSize = prev })
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	var guids []int64
+	for i := 0; i < 3; i++ {
+		pg, err := s.UpsertPlayerGUID(ctx, "GUID"+strconv.Itoa(i), "p"+strconv.Itoa(i), "p"+strconv.Itoa(i), base, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		guids = append(guids, pg.ID)
+	}
+
+	// Five matches, the middle three ending at the same instant so the
+	// keyset has to break ties on id; everyone plays each one.
+	ends := []time.Time{base.Add(-3 * time.Hour), base.Add(-2 * time.Hour), base.Add(-2 * time.Hour), base.Add(-2 * time.Hour), base.Add(-time.Hour)}
+	for i, ended := range ends {
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: ended.Add(-10 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for c, g := range guids {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, g, c, 10, 5, true, nil, nil, "", 0, c == 0,
+				0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, ended, "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	// from is inclusive, to exclusive: the first match is right on from,
+	// the last right on to.
+	var pages int
+	seen := map[string]int{}
+	err := s.EachMatchPlayerRow(ctx, ends[0], ends[4], func(page []MatchPlayerRow) error {
+		pages++
+		for _, r := range page {
+			seen[r.MatchUUID]++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachMatchPlayerRow: %v", err)
+	}
+	if len(seen) != 4 || seen["m4"] != 0 {
+		t.Fatalf("matches: got %v, want m0..m3", seen)
+	}
+	for uuid, n := range seen {
+		if n != 3 {
+			t.Errorf("match %s: got %d rows, want 3", uuid, n)
+		}
+	}
+	if pages != 2 {
+		t.Errorf("pages: got %d, want 2", pages)
+	}
+
+	var players []PlayerExportRow
+	err = s.EachPlayerExportRow(ctx, ends[0], base, func(page []PlayerExportRow) error {
+		players = append(players, page...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachPlayerExportRow: %v", err)
+	}
+	if len(players) != 3 {
+		t.Fatalf("players: got %d, want 3", len(players))
+	}
+	p := players[0]
+	if p.Name != "p0" || p.Matches != 5 || p.Frags != 50 || p.Victories != 5 || p.KDRatio != 2 {
+		t.Errorf("player: got %+v", p)
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// ExternalSource is the source query-only servers added from the
+// master-server browser belong to. It has no collector.
+const ExternalSource = "external"
+
+var (
+	ErrServerTracked  = errors.New("a server with that address is already tracked")
+	ErrServerKeyTaken = errors.New("server key is already in use")
+)
+
+// AddExternalServer starts tracking a server the hub doesn't run, as a
+// query-only server of ExternalSource.
+func (s *Store) AddExternalServer(ctx context.Context, key, address string) (*domain.Server, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM servers WHERE address = ?`, address).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, ErrServerTracked
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO sources (source, is_remote) VALUES (?, 0)
+		ON CONFLICT (source) DO NOTHING
+	`, ExternalSource); err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO servers (source, key, address, query_only) VALUES (?, ?, ?, 1)
+	`, ExternalSource, key, address)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			return nil, ErrServerKeyTaken
+		}
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetServerByID(ctx, id)
+}
+
+// ServerAddresses returns the address of every servers row, so the
+// browser can mark the ones already tracked.
+func (s *Store) ServerAddresses(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `SELECT address FROM servers WHERE address <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]bool)
+	for rows.Next() {
+		var addr string
+		if err := rows.Scan(&addr); err != nil {
+			return nil, err
+		}
+		out[addr] = true
+	}
+	return out, rows.Err()
+}
+
+// RecordPopulation stores one population sample for a server.
+func (s *Store) RecordPopulation(ctx context.Context, serverID int64, at time.Time, humans, bots, maxClients int) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO server_population (server_id, sampled_at, humans, bots, max_clients)
+		VALUES (?, ?, ?, ?, ?)
+	`, serverID, formatTimestamp(at), humans, bots, maxClients)
+	return err
+}
+
+// GetServerPopulation averages the server's population samples by UTC
+// hour over a leaderboard period, oldest first. Hours without samples
+// (the server was offline or not yet tracked) are left out.
+func (s *Store) GetServerPopulation(ctx context.Context, serverID int64, period string) (*domain.PopulationResponse, error) {
+	start, end := getTimePeriodBounds(period, time.Time{})
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT strftime('%Y-%m-%dT%H:00:00Z', sampled_at) as hour,
+			AVG(humans), MAX(humans), AVG(bots), MAX(max_clients)
+		FROM server_population
+		WHERE server_id = ? AND sampled_at >= ? AND sampled_at < ?
+		GROUP BY hour
+		ORDER BY hour
+	`, serverID, formatTimestamp(start), formatTimestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.PopulationResponse{
+		ServerID: serverID,
+		Period:   period,
+		Hours:    []domain.PopulationHour{},
+	}
+	for rows.Next() {
+		var h domain.PopulationHour
+		var hour string
+		if err := rows.Scan(&hour, &h.AvgHumans, &h.PeakHumans, &h.AvgBots, &h.MaxClients); err != nil {
+			return nil, err
+		}
+		if h.Hour, err = time.Parse(time.RFC3339, hour); err != nil {
+			return nil, err
+		}
+		resp.Hours = append(resp.Hours, h)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestAddExternalServer(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	local := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", local); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+
+	srv, err := s.AddExternalServer(ctx, "1-2-3-4-27960", "1.2.3.4:27960")
+	if err != nil {
+		t.Fatalf("AddExternalServer: %v", err)
+	}
+	if !srv.QueryOnly || srv.Source != ExternalSource || srv.Address != "1.2.3.4:27960" {
+		t.Errorf("got %+v", srv)
+	}
+
+	if _, err := s.AddExternalServer(ctx, "other", "1.2.3.4:27960"); !errors.Is(err, ErrServerTracked) {
+		t.Errorf("same address: got %v, want ErrServerTracked", err)
+	}
+	if _, err := s.AddExternalServer(ctx, "ffa", "127.0.0.1:27960"); !errors.Is(err, ErrServerTracked) {
+		t.Errorf("local address: got %v, want ErrServerTracked", err)
+	}
+	if _, err := s.AddExternalServer(ctx, "1-2-3-4-27960", "5.6.7.8:27960"); !errors.Is(err, ErrServerKeyTaken) {
+		t.Errorf("same key: got %v, want ErrServerKeyTaken", err)
+	}
+
+	// The local row hasn't proved the handshake, so only the external
+	// one is pollable.
+	pollable, err := s.ListPollableServers(ctx)
+	if err != nil {
+		t.Fatalf("ListPollableServers: %v", err)
+	}
+	if len(pollable) != 1 || pollable[0].ID != srv.ID || !pollable[0].QueryOnly {
+		t.Errorf("pollable = %+v", pollable)
+	}
+
+	tracked, err := s.ServerAddresses(ctx)
+	if err != nil {
+		t.Fatalf("ServerAddresses: %v", err)
+	}
+	if !tracked["1.2.3.4:27960"] || !tracked["127.0.0.1:27960"] || len(tracked) != 2 {
+		t.Errorf("tracked = %v", tracked)
+	}
+}
+
+func TestServerPopulation(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	srv, err := s.AddExternalServer(ctx, "community", "1.2.3.4:27960")
+	if err != nil {
+		t.Fatalf("AddExternalServer: %v", err)
+	}
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	for _, p := range []struct {
+		at          time.Time
+		humans, bot int
+	}{
+		{hour, 2, 4},
+		{hour.Add(20 * time.Minute), 6, 2},
+		{hour.Add(time.Hour), 3, 0},
+		{hour.Add(-48 * time.Hour), 10, 0}, // outside the day
+	} {
+		if err := s.RecordPopulation(ctx, srv.ID, p.at, p.humans, p.bot, 16); err != nil {
+			t.Fatalf("RecordPopulation: %v", err)
+		}
+	}
+
+	resp, err := s.GetServerPopulation(ctx, srv.ID, "day")
+	if err != nil {
+		t.Fatalf("GetServerPopulation: %v", err)
+	}
+	if len(resp.Hours) != 2 {
+		t.Fatalf("got %d hours, want 2: %+v", len(resp.Hours), resp.Hours)
+	}
+	first := resp.Hours[0]
+	if !first.Hour.Equal(hour) || first.AvgHumans != 4 || first.PeakHumans != 6 || first.AvgBots != 3 || first.MaxClients != 16 {
+		t.Errorf("first hour = %+v", first)
+	}
+	if second := resp.Hours[1]; !second.Hour.Equal(hour.Add(time.Hour)) || second.PeakHumans != 3 {
+		t.Errorf("second hour = %+v", second)
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordFlagCarry adds ms to the player's flag carry time for matchID.
+// Call after FlushMatchPlayerStats so the row exists.
+func (s *Store) RecordFlagCarry(ctx context.Context, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	return recordFlagCarry(ctx, s.conn(ctx), matchID, playerGUIDID, clientID, ms)
+}
+
+func recordFlagCarry(ctx context.Context, db execer, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE match_player_stats SET flag_carry_ms = COALESCE(flag_carry_ms, 0) + ?
+		WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+	`, ms, matchID, playerGUIDID, clientID)
+	return err
+}
+
+// recordFlagEvents saves a match's flag events in order. GUIDs become
+// player_guid_ids; a GUID that doesn't resolve leaves it NULL.
+func recordFlagEvents(ctx context.Context, db execer, matchID int64, events []domain.MatchEndFlagEvent) error {
+	values := make([]string, len(events))
+	args := make([]any, 0, len(events)*7)
+	for i, e := range events {
+		values[i] = "(?, ?, ?, ?, ?, ?, (SELECT id FROM player_guids WHERE guid = ? AND guid != ''))"
+		args = append(args, matchID, i, formatTimestamp(e.At), e.Event, e.Team, e.ClientID, e.GUID)
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO match_flag_events (match_id, seq, occurred_at, event, team, client_id, player_guid_id)
+		VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
+
+// GetFlagTimeline returns a match's flag events in order, and how
+// long each player carried the flag, longest first. Both are empty for
+// matches without flags or played before they were recorded. Returns
+// sql.ErrNoRows if there's no such match.
+func (s *Store) GetFlagTimeline(ctx context.Context, matchID int64) (*domain.FlagTimelineResponse, error) {
+	var startedAt sql.NullTime
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT started_at FROM matches WHERE id = ?`, matchID).Scan(&startedAt); err != nil {
+		return nil, err
+	}
+
+	resp := &domain.FlagTimelineResponse{
+		MatchID:  matchID,
+		Events:   []domain.FlagTimelineEvent{},
+		Carriers: []domain.FlagCarrier{},
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT fe.occurred_at, fe.event, fe.team, fe.client_id, p.id, p.name
+		FROM match_flag_events fe
+		LEFT JOIN player_guids pg ON pg.id = fe.player_guid_id
+		LEFT JOIN players p ON p.id = pg.player_id
+		WHERE fe.match_id = ?
+		ORDER BY fe.seq
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e domain.FlagTimelineEvent
+		var playerID sql.NullInt64
+		var name sql.NullString
+		if err := rows.Scan(&e.At, &e.Event, &e.Team, &e.ClientID, &playerID, &name); err != nil {
+			return nil, err
+		}
+		e.PlayerID = scanNullInt64Ptr(playerID)
+		e.Name = scanNullStringValue(name)
+		if startedAt.Valid {
+			e.OffsetMs = max(e.At.Sub(startedAt.Time).Milliseconds(), 0)
+		}
+		resp.Events = append(resp.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, MAX(mps.team), SUM(COALESCE(mps.flag_carry_ms, 0)) AS carry_ms, SUM(mps.captures)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		JOIN players p ON p.id = pg.player_id
+		WHERE mps.match_id = ?
+		GROUP BY p.id
+		HAVING carry_ms > 0
+		ORDER BY carry_ms DESC, p.id
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c domain.FlagCarrier
+		var team sql.NullInt64
+		if err := rows.Scan(&c.PlayerID, &c.Name, &team, &c.CarryMs, &c.Captures); err != nil {
+			return nil, err
+		}
+		c.Team = scanNullInt64ToIntPtr(team)
+		resp.Carriers = append(resp.Carriers, c)
+	}
+	return resp, rows.Err()
+}
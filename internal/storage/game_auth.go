@@ -19,7 +19,7 @@ func (s *Store) RotateGameToken(ctx context.Context, userID int64) (string, erro
 	if err != nil {
 		return "", err
 	}
-	_, err = s.db.ExecContext(ctx,
+	_, err = s.conn(ctx).ExecContext(ctx,
 		`UPDATE users SET game_token = ? WHERE id = ?`,
 		token, userID)
 	if err != nil {
@@ -30,7 +30,7 @@ func (s *Store) RotateGameToken(ctx context.Context, userID int64) (string, erro
 
 func (s *Store) EnsureGameToken(ctx context.Context, userID int64) (string, error) {
 	var existing string
-	err := s.db.QueryRowContext(ctx,
+	err := s.conn(ctx).QueryRowContext(ctx,
 		`SELECT game_token FROM users WHERE id = ?`,
 		userID).Scan(&existing)
 	if err != nil {
@@ -44,7 +44,7 @@ func (s *Store) EnsureGameToken(ctx context.Context, userID int64) (string, erro
 
 func (s *Store) GetGameTokenByGUID(ctx context.Context, guid string) (string, error) {
 	var token string
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT u.game_token
 		FROM users u
 		JOIN players p ON p.id = u.player_id
@@ -57,7 +57,7 @@ func (s *Store) GetGameTokenByGUID(ctx context.Context, guid string) (string, er
 func (s *Store) GetGameTokenByUsername(ctx context.Context, username string) (int64, string, error) {
 	var playerID int64
 	var token string
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT player_id, game_token FROM users
 		WHERE username = ? AND game_token != '' AND player_id IS NOT NULL
 	`, username).Scan(&playerID, &token)
@@ -70,7 +70,7 @@ func (s *Store) GetGameTokenByUsername(ctx context.Context, username string) (in
 func (s *Store) AssociateGUIDWithPlayer(ctx context.Context, guid string, targetPlayerID int64) (bool, error) {
 	// Look up GUID's current player
 	var currentPlayerID int64
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT player_id FROM player_guids WHERE guid = ?
 	`, guid).Scan(&currentPlayerID)
 	if err != nil {
@@ -83,7 +83,7 @@ func (s *Store) AssociateGUIDWithPlayer(ctx context.Context, guid string, target
 
 	// Check if the GUID's current player is linked to a different user account
 	var ownerCount int
-	err = s.db.QueryRowContext(ctx, `
+	err = s.conn(ctx).QueryRowContext(ctx, `
 		SELECT COUNT(*) FROM users WHERE player_id = ?
 	`, currentPlayerID).Scan(&ownerCount)
 	if err != nil {
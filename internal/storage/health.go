@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ping checks that the database answers a query.
+func (s *Store) Ping(ctx context.Context) error {
+	var n int
+	return s.conn(ctx).QueryRowContext(ctx, `SELECT 1`).Scan(&n)
+}
+
+// StaleSources returns the active sources whose collector has
+// heartbeated before but not since before. Sources that have never
+// heartbeated are left out: they're approved but not set up yet.
+func (s *Store) StaleSources(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT source FROM sources
+		WHERE active = 1 AND status = 'active'
+		  AND last_heartbeat_at IS NOT NULL AND last_heartbeat_at < ?
+		ORDER BY source
+	`, formatTimestamp(before))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, err
+		}
+		out = append(out, source)
+	}
+	return out, rows.Err()
+}
+
+// MissingColumns lists the schema.sql columns, as "table.column", that
+// the database lacks. New tables are created on open, but a column
+// added to an existing table needs a manual ALTER; this finds the
+// ones that were skipped.
+func (s *Store) MissingColumns(ctx context.Context) ([]string, error) {
+	want, err := openSchemaReference()
+	if err != nil {
+		return nil, err
+	}
+	defer want.Close()
+	wantCols, err := tableColumns(ctx, want)
+	if err != nil {
+		return nil, err
+	}
+	haveCols, err := tableColumns(ctx, s.conn(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for table, cols := range wantCols {
+		for col := range cols {
+			if !haveCols[table][col] {
+				missing = append(missing, table+"."+col)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// openSchemaReference returns an in-memory database built from
+// schema.sql alone.
+func openSchemaReference() (*sql.DB, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("building reference schema: %w", err)
+	}
+	return db, nil
+}
+
+// tableColumns returns each table's column names.
+func tableColumns(ctx context.Context, db querier) (map[string]map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.name, p.name
+		FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]map[string]bool{}
+	for rows.Next() {
+		var table, col string
+		if err := rows.Scan(&table, &col); err != nil {
+			return nil, err
+		}
+		if out[table] == nil {
+			out[table] = map[string]bool{}
+		}
+		out[table][col] = true
+	}
+	return out, rows.Err()
+}
+
+// QuickCheck runs SQLite's quick_check and returns its first complaint.
+func (s *Store) QuickCheck(ctx context.Context) error {
+	var result string
+	if err := s.conn(ctx).QueryRowContext(ctx, `PRAGMA quick_check(1)`).Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("quick_check: %s", result)
+	}
+	return nil
+}
+
+// WriteCheck commits a throwaway table and drops it again, to show the
+// database file can be written.
+func (s *Store) WriteCheck(ctx context.Context) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE trinity_write_check (x INTEGER)`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO trinity_write_check (x) VALUES (1)`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE trinity_write_check`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
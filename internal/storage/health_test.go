@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMissingColumns(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(filepath.Join(t.TempDir(), "trinity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	missing, err := s.MissingColumns(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("fresh database missing %v", missing)
+	}
+
+	// An upgrade whose ALTER was skipped.
+	if _, err := s.db.ExecContext(ctx, `ALTER TABLE matches DROP COLUMN gameplay`); err != nil {
+		t.Fatal(err)
+	}
+	missing, err = s.MissingColumns(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"matches.gameplay"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %v, want %v", missing, want)
+	}
+}
+
+func TestQuickAndWriteCheck(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(filepath.Join(t.TempDir(), "trinity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.QuickCheck(ctx); err != nil {
+		t.Errorf("QuickCheck: %v", err)
+	}
+	if err := s.WriteCheck(ctx); err != nil {
+		t.Errorf("WriteCheck: %v", err)
+	}
+	// The scratch table is gone, so it can run again.
+	if err := s.WriteCheck(ctx); err != nil {
+		t.Errorf("second WriteCheck: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Impersonation is one "view as user" token issued to an admin.
+// AdminUserID and UserID are null once that account is deleted; the
+// usernames are kept regardless.
+type Impersonation struct {
+	ID            int64
+	AdminUserID   sql.NullInt64
+	AdminUsername string
+	UserID        sql.NullInt64
+	Username      string
+	Reason        string
+	RemoteAddr    string
+	StartedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// RecordImpersonation writes e to the impersonation log and returns
+// its id. The API records before handing out the token, so a token
+// never exists without its row.
+func (s *Store) RecordImpersonation(ctx context.Context, e Impersonation) (int64, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO impersonations (admin_user_id, admin_username, user_id, username, reason, remote_addr, started_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.AdminUserID, e.AdminUsername, e.UserID, e.Username, e.Reason, e.RemoteAddr,
+		formatTimestamp(e.StartedAt), formatTimestamp(e.ExpiresAt))
+	if err != nil {
+		return 0, fmt.Errorf("storage.RecordImpersonation(%s as %s): %w", e.AdminUsername, e.Username, err)
+	}
+	return res.LastInsertId()
+}
+
+// ListImpersonations returns the impersonation log, newest first.
+func (s *Store) ListImpersonations(ctx context.Context, limit int) ([]Impersonation, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, admin_user_id, admin_username, user_id, username, reason, remote_addr, started_at, expires_at
+		FROM impersonations
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage.ListImpersonations: %w", err)
+	}
+	defer rows.Close()
+	var out []Impersonation
+	for rows.Next() {
+		var e Impersonation
+		if err := rows.Scan(&e.ID, &e.AdminUserID, &e.AdminUsername, &e.UserID, &e.Username,
+			&e.Reason, &e.RemoteAddr, &e.StartedAt, &e.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// duelResult is one finished 1v1 between two ladder-eligible players.
+type duelResult struct {
+	matchID                 int64
+	playedAt                time.Time
+	mapName                 string
+	winner, loser           domain.Player
+	winnerFrags, loserFrags int
+}
+
+// GetDuelLadder replays every 1v1 match that ended by asOf into the
+// duel ladder. Only matches both players completed, with exactly one
+// winner and no bots or leaderboard-excluded players, count as
+// challenges. Challenges holds the latest limit of them, newest first;
+// a non-zero playerID keeps only theirs. A zero asOf means now, and may
+// be served from the cache (see duelHistory).
+func (s *Store) GetDuelLadder(ctx context.Context, asOf time.Time, limit int, playerID int64) (*domain.DuelLadderResponse, error) {
+	asOf, entries, challenges, err := s.duelHistory(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.DuelLadderResponse{
+		AsOf:       asOf,
+		DecayDays:  int(domain.DuelLadderDecay / (24 * time.Hour)),
+		Entries:    entries,
+		Challenges: latestDuels(challenges, limit, playerID),
+	}
+	return resp, nil
+}
+
+// duelLadderCache is a replayed live ladder, kept in the leaderboard
+// cache under its TTL and generation, so a match ending drops it too.
+type duelLadderCache struct {
+	gen        uint64
+	expires    time.Time
+	asOf       time.Time
+	entries    []domain.DuelLadderEntry
+	challenges []domain.DuelChallenge
+}
+
+// duelHistory replays the duel ladder as of asOf, or now if asOf is
+// zero, and returns the asOf it used. The live ladder may come from
+// the cache (see SetLeaderboardCacheTTL), as of when it was replayed,
+// and is shared between callers, so treat it as read-only.
+func (s *Store) duelHistory(ctx context.Context, asOf time.Time) (time.Time, []domain.DuelLadderEntry, []domain.DuelChallenge, error) {
+	live := asOf.IsZero()
+	c := &s.lbCache
+	c.mu.Lock()
+	ttl, gen := c.ttl, c.gen
+	if d := c.duel; live && ttl > 0 && d != nil && d.gen == gen && time.Now().Before(d.expires) {
+		c.mu.Unlock()
+		return d.asOf, d.entries, d.challenges, nil
+	}
+	c.mu.Unlock()
+
+	if live {
+		asOf = time.Now().UTC()
+	}
+	duels, err := s.duelResults(ctx, asOf)
+	if err != nil {
+		return asOf, nil, nil, err
+	}
+	entries, challenges := buildDuelLadder(duels, asOf)
+	if live && ttl > 0 {
+		c.mu.Lock()
+		if c.gen == gen {
+			c.duel = &duelLadderCache{
+				gen: gen, expires: time.Now().Add(ttl),
+				asOf: asOf, entries: entries, challenges: challenges,
+			}
+		}
+		c.mu.Unlock()
+	}
+	return asOf, entries, challenges, nil
+}
+
+// duelResults loads eligible 1v1 results ended by asOf, oldest first.
+func (s *Store) duelResults(ctx context.Context, asOf time.Time) ([]duelResult, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT m.id, m.ended_at, m.map_name, p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
+			COALESCE(u.is_admin, 0) as is_admin,
+			SUM(mps.frags), MAX(mps.victories > 0)
+		FROM matches m
+		JOIN match_player_stats mps ON mps.match_id = m.id
+		JOIN player_guids pg ON mps.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE m.game_type = ? AND m.ended_at IS NOT NULL AND m.ended_at <= ?
+		  AND mps.completed = TRUE
+		  AND p.is_bot = FALSE AND p.clean_name NOT LIKE '[VR] Player#%'
+		  AND COALESCE(p.exclude_from_leaderboards, 0) = 0
+		GROUP BY m.id, p.id
+		ORDER BY m.ended_at, m.id
+	`, domain.GameType1v1, formatTimestamp(asOf))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type side struct {
+		player domain.Player
+		frags  int
+		won    bool
+	}
+	var (
+		out     []duelResult
+		curID   int64
+		curAt   time.Time
+		curMap  string
+		players []side
+	)
+	flush := func() {
+		if len(players) != 2 || players[0].won == players[1].won {
+			return
+		}
+		w, l := players[0], players[1]
+		if l.won {
+			w, l = l, w
+		}
+		out = append(out, duelResult{
+			matchID: curID, playedAt: curAt, mapName: curMap,
+			winner: w.player, loser: l.player,
+			winnerFrags: w.frags, loserFrags: l.frags,
+		})
+	}
+	for rows.Next() {
+		var matchID int64
+		var endedAt time.Time
+		var mapName string
+		var sd side
+		if err := rows.Scan(&matchID, &endedAt, &mapName, &sd.player.ID, &sd.player.Name, &sd.player.CleanName,
+			&sd.player.FirstSeen, &sd.player.LastSeen, &sd.player.IsVR, &sd.player.IsVerified, &sd.player.IsAdmin,
+			&sd.frags, &sd.won); err != nil {
+			return nil, err
+		}
+		if matchID != curID {
+			flush()
+			curID, curAt, curMap, players = matchID, endedAt, mapName, players[:0]
+		}
+		players = append(players, sd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return out, nil
+}
+
+// duelLadder is the ladder mid-replay.
+type duelLadder struct {
+	order   []int64 // index 0 is position 1
+	players map[int64]*domain.DuelLadderEntry
+	decayed map[int64]int // rungs lost since the player's last duel
+}
+
+func (l *duelLadder) position(id int64) int {
+	return slices.Index(l.order, id) + 1
+}
+
+// join puts a newcomer at the bottom.
+func (l *duelLadder) join(p domain.Player) {
+	if _, ok := l.players[p.ID]; ok {
+		return
+	}
+	l.players[p.ID] = &domain.DuelLadderEntry{Player: p}
+	l.order = append(l.order, p.ID)
+}
+
+// decay drops each player a rung for every DuelLadderDecay since their
+// last duel that hasn't been charged yet. A drop swaps them with the
+// player below, unless that player has been idle at least as long, so
+// a quiet week doesn't reshuffle the whole ladder. Working up from the
+// bottom means a drop only moves players already handled.
+func (l *duelLadder) decay(now time.Time) {
+	for i := len(l.order) - 1; i >= 0; i-- {
+		id := l.order[i]
+		last := l.players[id].LastDuelAt
+		due := int(now.Sub(last) / domain.DuelLadderDecay)
+		drop := due - l.decayed[id]
+		if drop <= 0 {
+			continue
+		}
+		l.decayed[id] = due
+		for j := i; drop > 0 && j+1 < len(l.order); j, drop = j+1, drop-1 {
+			if !l.players[l.order[j+1]].LastDuelAt.After(last) {
+				break
+			}
+			l.order[j], l.order[j+1] = l.order[j+1], l.order[j]
+		}
+	}
+}
+
+// buildDuelLadder replays duels (oldest first) and returns the ladder
+// as of asOf along with every challenge in order.
+func buildDuelLadder(duels []duelResult, asOf time.Time) ([]domain.DuelLadderEntry, []domain.DuelChallenge) {
+	l := &duelLadder{
+		players: make(map[int64]*domain.DuelLadderEntry),
+		decayed: make(map[int64]int),
+	}
+	challenges := make([]domain.DuelChallenge, 0, len(duels))
+	for _, d := range duels {
+		l.decay(d.playedAt)
+		c := domain.DuelChallenge{
+			MatchID:     d.matchID,
+			PlayedAt:    d.playedAt,
+			MapName:     d.mapName,
+			Winner:      d.winner,
+			Loser:       d.loser,
+			WinnerFrags: d.winnerFrags,
+			LoserFrags:  d.loserFrags,
+			WinnerFrom:  l.position(d.winner.ID),
+			LoserFrom:   l.position(d.loser.ID),
+		}
+		l.join(d.winner)
+		l.join(d.loser)
+
+		wi, li := l.position(d.winner.ID)-1, l.position(d.loser.ID)-1
+		if wi > li {
+			// Leapfrog: the winner takes the loser's rung and everyone
+			// from there down to the winner's old rung slips one.
+			copy(l.order[li+1:wi+1], l.order[li:wi])
+			l.order[li] = d.winner.ID
+			c.Upset = true
+		}
+		c.WinnerTo = l.position(d.winner.ID)
+
+		l.players[d.winner.ID].Wins++
+		l.players[d.loser.ID].Losses++
+		for _, id := range []int64{d.winner.ID, d.loser.ID} {
+			l.players[id].LastDuelAt = d.playedAt
+			l.decayed[id] = 0
+		}
+		challenges = append(challenges, c)
+	}
+	l.decay(asOf)
+
+	entries := make([]domain.DuelLadderEntry, len(l.order))
+	for i, id := range l.order {
+		entries[i] = *l.players[id]
+		entries[i].Position = i + 1
+	}
+	return entries, challenges
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func ladderOrder(entries []domain.DuelLadderEntry) []int64 {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Player.ID
+	}
+	return ids
+}
+
+func sameOrder(got, want []int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuildDuelLadder(t *testing.T) {
+	base := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	p := func(id int64) domain.Player { return domain.Player{ID: id} }
+	duel := func(day int, w, l int64) duelResult {
+		return duelResult{matchID: int64(day), playedAt: base.Add(time.Duration(day) * 24 * time.Hour), winner: p(w), loser: p(l)}
+	}
+
+	// 1 beats 2, 3 beats 4: ladder 1,2,3,4. Then 4 beats 1 and
+	// leapfrogs to the top; 3 beats 2, who's above, and takes their rung.
+	duels := []duelResult{duel(0, 1, 2), duel(0, 3, 4), duel(1, 4, 1), duel(2, 3, 2), duel(3, 1, 3)}
+	entries, challenges := buildDuelLadder(duels, base.Add(4*24*time.Hour))
+	if got, want := ladderOrder(entries), []int64{4, 1, 3, 2}; !sameOrder(got, want) {
+		t.Fatalf("order: got %v, want %v", got, want)
+	}
+	up := challenges[2]
+	if !up.Upset || up.WinnerFrom != 4 || up.LoserFrom != 1 || up.WinnerTo != 1 {
+		t.Errorf("4 beats 1: got %+v", up)
+	}
+	if c := challenges[4]; c.Upset || c.WinnerTo != 2 {
+		t.Errorf("1 beats 3 from above: got %+v", c)
+	}
+	if e := entries[1]; e.Wins != 2 || e.Losses != 1 {
+		t.Errorf("player 1 record: got %d-%d, want 2-1", e.Wins, e.Losses)
+	}
+
+	// Player 4 then sits out while 1 and 2 keep playing. Each decay
+	// period they slip below whoever has played since, but never below
+	// someone idle longer than they are.
+	duels = append(duels, duel(20, 1, 2), duel(30, 3, 2))
+	entries, _ = buildDuelLadder(duels, base.Add(31*24*time.Hour))
+	if got, want := ladderOrder(entries), []int64{1, 3, 4, 2}; !sameOrder(got, want) {
+		t.Errorf("after decay: got %v, want %v", got, want)
+	}
+}
+
+func TestGetDuelLadder(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "duel", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	guid := func(g, name string) *domain.PlayerGUID {
+		pg, err := s.UpsertPlayerGUID(ctx, g, name, name, base, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		return pg
+	}
+	alice, bob := guid("AAAA", "alice"), guid("BBBB", "bob")
+	sarge, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", base)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+
+	type side struct {
+		pg    *domain.PlayerGUID
+		frags int
+		won   bool
+	}
+	n := 0
+	play := func(gameType string, a, b side) {
+		n++
+		started := base.Add(time.Duration(n) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(n), ServerID: srv.ID, MapName: "q3dm17", GameType: gameType, StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for i, sd := range []side{a, b} {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, sd.pg.ID, i, sd.frags, 0, true, nil, nil, "", 0, sd.won,
+				0, 0, 0, 0, 0, 0, 0, sd.pg == sarge, false, started, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, started.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	play(domain.GameType1v1, side{alice, 20, true}, side{bob, 12, false})
+	play(domain.GameType1v1, side{bob, 20, true}, side{alice, 17, false})
+	play(domain.GameType1v1, side{alice, 20, true}, side{sarge, 3, false}) // bots don't count
+	play(domain.GameTypeFFA, side{alice, 20, true}, side{bob, 2, false})   // nor other game types
+
+	resp, err := s.GetDuelLadder(ctx, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetDuelLadder: %v", err)
+	}
+	if got, want := ladderOrder(resp.Entries), []int64{bob.PlayerID, alice.PlayerID}; !sameOrder(got, want) {
+		t.Fatalf("order: got %v, want %v", got, want)
+	}
+	if len(resp.Challenges) != 2 {
+		t.Fatalf("challenges: got %d, want 2", len(resp.Challenges))
+	}
+	last := resp.Challenges[0]
+	if last.Winner.Name != "bob" || last.WinnerFrags != 20 || last.LoserFrags != 17 || !last.Upset {
+		t.Errorf("latest challenge: got %+v", last)
+	}
+
+	resp, err = s.GetDuelLadder(ctx, time.Time{}, 1, alice.PlayerID)
+	if err != nil {
+		t.Fatalf("GetDuelLadder: %v", err)
+	}
+	if len(resp.Challenges) != 1 || resp.Challenges[0].MatchID != last.MatchID {
+		t.Errorf("limited challenges: got %+v", resp.Challenges)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// leaderboardKey identifies one cacheable leaderboard query. Pinned
+// (as_of) queries bypass the cache, so the key has no time in it.
+type leaderboardKey struct {
+	category string
+	period   string
+	gameType string
+	limit    int
+}
+
+type leaderboardEntry struct {
+	resp    *domain.LeaderboardResponse
+	expires time.Time
+}
+
+// leaderboardCache memoizes live leaderboard results for a short TTL.
+// gen is bumped on every invalidation; a query that started before an
+// invalidation doesn't get to store its (possibly stale) result.
+type leaderboardCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	gen     uint64
+	entries map[leaderboardKey]leaderboardEntry
+	rules   *LeaderboardRules // nil for DefaultLeaderboardRules
+	// duel is the live duel ladder, good while gen is unchanged.
+	duel *duelLadderCache
+}
+
+// SetLeaderboardCacheTTL turns on leaderboard caching with the given
+// lifetime; zero (the default) turns it off. Entries are also dropped
+// whenever a match ends or players are merged or split. The live duel
+// ladder is cached the same way.
+func (s *Store) SetLeaderboardCacheTTL(ttl time.Duration) {
+	s.lbCache.mu.Lock()
+	defer s.lbCache.mu.Unlock()
+	s.lbCache.ttl = ttl
+	s.lbCache.gen++
+	s.lbCache.entries = nil
+}
+
+// InvalidateLeaderboardCache drops every cached leaderboard.
+func (s *Store) InvalidateLeaderboardCache() {
+	s.lbCache.mu.Lock()
+	defer s.lbCache.mu.Unlock()
+	s.lbCache.gen++
+	s.lbCache.entries = nil
+}
+
+// GetLeaderboard returns top players ranked by the specified category and time period.
+// asOf pins the period's upper bound for reproducible snapshots; pass
+// time.Time{} for "live" (now-anchored) results. Live results may be
+// served from the cache (see SetLeaderboardCacheTTL) and are shared
+// between callers, so treat them as read-only.
+func (s *Store) GetLeaderboard(ctx context.Context, category, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
+	c := &s.lbCache
+	c.mu.Lock()
+	ttl, gen := c.ttl, c.gen
+	key := leaderboardKey{category: category, period: period, gameType: gameType, limit: limit}
+	if ttl > 0 && asOf.IsZero() {
+		if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+			c.mu.Unlock()
+			return e.resp, nil
+		}
+	}
+	c.mu.Unlock()
+
+	resp, err := s.queryLeaderboard(ctx, s.LeaderboardRules(), category, period, limit, gameType, asOf)
+	if err != nil || ttl <= 0 || !asOf.IsZero() {
+		return resp, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		if c.entries == nil {
+			c.entries = make(map[leaderboardKey]leaderboardEntry)
+		}
+		c.entries[key] = leaderboardEntry{resp: resp, expires: time.Now().Add(ttl)}
+	}
+	return resp, nil
+}
+
+// GetLeaderboardWithRules is GetLeaderboard under rules other than the
+// configured ones. Results are never cached.
+func (s *Store) GetLeaderboardWithRules(ctx context.Context, rules LeaderboardRules, category, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
+	return s.queryLeaderboard(ctx, rules, category, period, limit, gameType, asOf)
+}
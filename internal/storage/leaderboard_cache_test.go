@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestLeaderboardCacheInvalidatedOnMatchEnd(t *testing.T) {
+	s := newTestStore(t)
+	s.SetLeaderboardCacheTTL(time.Hour)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// flush writes a match's stats without ending it.
+	flush := func(uuid string, frags int) int64 {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, frags, 1, true, nil, nil, "", 0, false,
+			0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		return m.ID
+	}
+	end := func(matchID int64) {
+		t.Helper()
+		if err := s.EndMatch(ctx, matchID, base.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+	play := func(uuid string, frags int) { end(flush(uuid, frags)) }
+	frags := func() int64 {
+		t.Helper()
+		resp, err := s.GetLeaderboard(ctx, "frags", "all", 10, "", time.Time{})
+		if err != nil {
+			t.Fatalf("GetLeaderboard: %v", err)
+		}
+		if len(resp.Entries) == 0 {
+			return 0
+		}
+		return resp.Entries[0].TotalFrags
+	}
+
+	// The leaderboard only lists players with five completed matches.
+	for _, uuid := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		play(uuid, 10)
+	}
+	if got := frags(); got != 50 {
+		t.Fatalf("frags: got %d, want 50", got)
+	}
+
+	// Stats written mid-match are held back by the cache...
+	m6 := flush("m6", 5)
+	if got := frags(); got != 50 {
+		t.Errorf("cached frags: got %d, want 50", got)
+	}
+	// ...until the match ends and evicts it.
+	end(m6)
+	if got := frags(); got != 55 {
+		t.Errorf("frags after match end: got %d, want 55", got)
+	}
+}
+
+func TestDuelLadderCacheInvalidatedOnMatchEnd(t *testing.T) {
+	s := newTestStore(t)
+	s.SetLeaderboardCacheTTL(time.Hour)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "duel", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	play := func(uuid string, winner, loser *domain.PlayerGUID) {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: domain.GameType1v1, StartedAt: base}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for i, pg := range []*domain.PlayerGUID{winner, loser} {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, i, 20-i*5, 0, true, nil, nil, "", 0, i == 0,
+				0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+	challenges := func() int {
+		t.Helper()
+		resp, err := s.GetDuelLadder(ctx, time.Time{}, 10, 0)
+		if err != nil {
+			t.Fatalf("GetDuelLadder: %v", err)
+		}
+		return len(resp.Challenges)
+	}
+
+	play("m1", alice, bob)
+	if got := challenges(); got != 1 {
+		t.Fatalf("challenges: got %d, want 1", got)
+	}
+
+	// A change that doesn't end a match is held back by the cache...
+	if _, err := s.conn(ctx).ExecContext(ctx, `UPDATE match_player_stats SET victories = 0`); err != nil {
+		t.Fatal(err)
+	}
+	if got := challenges(); got != 1 {
+		t.Errorf("cached challenges: got %d, want 1", got)
+	}
+	// ...until a match ends and evicts it.
+	play("m2", bob, alice)
+	if got := challenges(); got != 1 {
+		t.Errorf("challenges after match end: got %d, want 1 (m2 only)", got)
+	}
+	resp, err := s.GetDuelLadder(ctx, time.Time{}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetDuelLadder: %v", err)
+	}
+	if resp.Challenges[0].Winner.ID != bob.PlayerID {
+		t.Errorf("latest challenge: got %+v", resp.Challenges[0])
+	}
+}
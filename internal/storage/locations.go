@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetCountryBreakdown counts distinct human players and sessions per
+// country for sessions opened inside the period. Sessions without a
+// country (no GeoIP on the collector, private IPs, lookup misses) are
+// left out rather than bucketed as "unknown" — the breakdown only
+// covers what we actually know.
+func (s *Store) GetCountryBreakdown(ctx context.Context, period string, asOf time.Time) (*domain.CountryBreakdownResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT s.country_code, MAX(s.country),
+			COUNT(DISTINCT pg.player_id) AS players,
+			COUNT(*) AS sessions
+		FROM sessions s
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		JOIN players p ON p.id = pg.player_id
+		WHERE p.is_bot = FALSE
+			AND s.country_code != ''
+			AND s.joined_at >= ? AND s.joined_at < ?
+		GROUP BY s.country_code
+		ORDER BY players DESC, sessions DESC, s.country_code ASC
+	`, formatTimestamp(start), formatTimestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countries := []domain.CountryCount{}
+	for rows.Next() {
+		var c domain.CountryCount
+		var country sql.NullString
+		if err := rows.Scan(&c.CountryCode, &country, &c.Players, &c.Sessions); err != nil {
+			return nil, err
+		}
+		c.Country = country.String
+		countries = append(countries, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &domain.CountryBreakdownResponse{
+		Period:    period,
+		Countries: countries,
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	return resp, nil
+}
+
+// GetPlayerLastLocation returns the location of the player's most
+// recent session that has one, across all their GUIDs. Returns nil
+// when none of their sessions were geolocated.
+func (s *Store) GetPlayerLastLocation(ctx context.Context, playerID int64) (*domain.PlayerLocation, error) {
+	var loc domain.PlayerLocation
+	var city sql.NullString
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT s.country_code, s.country, s.city, s.joined_at, s.server_id
+		FROM sessions s
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		WHERE pg.player_id = ? AND s.country_code != ''
+		ORDER BY s.joined_at DESC
+		LIMIT 1
+	`, playerID).Scan(&loc.CountryCode, &loc.Country, &city, &loc.SeenAt, &loc.ServerID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	loc.City = city.String
+	return &loc, nil
+}
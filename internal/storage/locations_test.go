@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestCountryBreakdownAndLastLocation(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	asOf := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", asOf, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", asOf, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	open := func(pg *domain.PlayerGUID, joined time.Time, cc, country, city string) {
+		t.Helper()
+		sess := &domain.Session{
+			PlayerGUIDID: pg.ID, ServerID: srv.ID, JoinedAt: joined,
+			CountryCode: cc, Country: country, City: city,
+		}
+		if err := s.CreateSession(ctx, sess); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+	open(alice, asOf.Add(-48*time.Hour), "DE", "Germany", "Berlin")
+	open(alice, asOf.Add(-24*time.Hour), "FR", "France", "Paris")
+	open(bob, asOf.Add(-36*time.Hour), "DE", "Germany", "")
+	open(bob, asOf.Add(-12*time.Hour), "", "", "") // no GeoIP on that collector
+
+	resp, err := s.GetCountryBreakdown(ctx, "week", asOf)
+	if err != nil {
+		t.Fatalf("GetCountryBreakdown: %v", err)
+	}
+	if len(resp.Countries) != 2 {
+		t.Fatalf("got %d countries, want 2: %+v", len(resp.Countries), resp.Countries)
+	}
+	if c := resp.Countries[0]; c.CountryCode != "DE" || c.Players != 2 || c.Sessions != 2 {
+		t.Errorf("first row: got %+v, want DE with 2 players / 2 sessions", c)
+	}
+	if c := resp.Countries[1]; c.CountryCode != "FR" || c.Players != 1 {
+		t.Errorf("second row: got %+v, want FR with 1 player", c)
+	}
+
+	loc, err := s.GetPlayerLastLocation(ctx, alice.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerLastLocation: %v", err)
+	}
+	if loc == nil || loc.CountryCode != "FR" || loc.City != "Paris" {
+		t.Errorf("alice last location: got %+v, want FR/Paris", loc)
+	}
+
+	// Bob's newest session has no location; fall back to the newest
+	// one that does.
+	loc, err = s.GetPlayerLastLocation(ctx, bob.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerLastLocation: %v", err)
+	}
+	if loc == nil || loc.CountryCode != "DE" {
+		t.Errorf("bob last location: got %+v, want DE", loc)
+	}
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// execer is a *sql.DB or a transaction, so the match_end writes can run
+// alone or inside FlushMatch's transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// MatchTx writes a match's stats inside FlushMatch's transaction. Its
+// methods match the Store methods of the same names.
+type MatchTx struct {
+	tx txn
+}
+
+// FlushMatchPlayerStats is Store.FlushMatchPlayerStats in the transaction.
+func (t *MatchTx) FlushMatchPlayerStats(ctx context.Context, matchID, playerGUIDID int64, clientID int,
+	frags, deaths int, completed bool, score *int, team *int, model string, skill float64, victory bool,
+	captures, flagReturns, assists, impressives, excellents, humiliations, defends int,
+	isBot bool, joinedLate bool, joinedAt time.Time, isVR bool) error {
+	return flushMatchPlayerStats(ctx, t.tx, matchID, playerGUIDID, clientID, frags, deaths, completed, score, team, model, skill, victory,
+		captures, flagReturns, assists, impressives, excellents, humiliations, defends, isBot, joinedLate, joinedAt, isVR)
+}
+
+// RecordBestSpree is Store.RecordBestSpree in the transaction.
+func (t *MatchTx) RecordBestSpree(ctx context.Context, matchID, playerGUIDID int64, clientID, spree int) error {
+	return recordBestSpree(ctx, t.tx, matchID, playerGUIDID, clientID, spree)
+}
+
+// RecordFastestCap is Store.RecordFastestCap in the transaction.
+func (t *MatchTx) RecordFastestCap(ctx context.Context, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	return recordFastestCap(ctx, t.tx, matchID, playerGUIDID, clientID, ms)
+}
+
+// RecordTeamArenaStats is Store.RecordTeamArenaStats in the transaction.
+func (t *MatchTx) RecordTeamArenaStats(ctx context.Context, matchID, playerGUIDID int64, clientID, skulls, obelisks int) error {
+	return recordTeamArenaStats(ctx, t.tx, matchID, playerGUIDID, clientID, skulls, obelisks)
+}
+
+// RecordFlagCarry is Store.RecordFlagCarry in the transaction.
+func (t *MatchTx) RecordFlagCarry(ctx context.Context, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	return recordFlagCarry(ctx, t.tx, matchID, playerGUIDID, clientID, ms)
+}
+
+// RecordWeaponFrags is Store.RecordWeaponFrags in the transaction.
+func (t *MatchTx) RecordWeaponFrags(ctx context.Context, matchID, playerGUIDID int64, frags map[string]int) error {
+	return recordWeaponFrags(ctx, t.tx, matchID, playerGUIDID, frags)
+}
+
+// RecordWeaponAccuracy is Store.RecordWeaponAccuracy in the transaction.
+func (t *MatchTx) RecordWeaponAccuracy(ctx context.Context, matchID, playerGUIDID int64, accuracy map[string]domain.WeaponShots) error {
+	return recordWeaponAccuracy(ctx, t.tx, matchID, playerGUIDID, accuracy)
+}
+
+// FlushMatch records a match's end in one transaction: flush writes
+// the players' stats through tx, the match is closed as EndMatch does
+// with its abandoned and overtime flags, its final scoreboard and
+// flag events are saved, and the server's log cursor moves to
+// end.Cursor. A crash part way leaves none of it, so the collector's
+// resent match_end starts over. If the match has already ended, flush
+// isn't called and FlushMatch returns false, so a match_end that
+// arrives twice can't add its stats twice; the cursor still moves.
+//
+// flush must only write through tx: the store has one connection, and
+// the transaction holds it.
+func (s *Store) FlushMatch(ctx context.Context, match *domain.Match, end domain.MatchEndData, flush func(tx *MatchTx)) (bool, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var ended bool
+	if err := tx.QueryRowContext(ctx, `SELECT ended_at IS NOT NULL FROM matches WHERE id = ?`, match.ID).Scan(&ended); err != nil {
+		return false, err
+	}
+	if !ended {
+		flush(&MatchTx{tx: tx})
+		if err := endMatch(ctx, tx, match.ID, end.EndedAt, end.ExitReason, end.RedScore, end.BlueScore); err != nil {
+			return false, err
+		}
+		if end.Abandoned || end.Overtime != nil {
+			if _, err := tx.ExecContext(ctx, `UPDATE matches SET abandoned = ?, overtime_seconds = ? WHERE id = ?`,
+				end.Abandoned, end.Overtime, match.ID); err != nil {
+				return false, err
+			}
+		}
+		if len(end.Scoreboard) > 0 {
+			if err := recordScoreboard(ctx, tx, match.ID, end.Scoreboard); err != nil {
+				return false, err
+			}
+		}
+		if len(end.FlagEvents) > 0 {
+			if err := recordFlagEvents(ctx, tx, match.ID, end.FlagEvents); err != nil {
+				return false, err
+			}
+		}
+	}
+	if end.Cursor != nil {
+		if err := setLogCursor(ctx, tx, match.ServerID, *end.Cursor); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if !ended {
+		s.InvalidateLeaderboardCache()
+	}
+	return !ended, nil
+}
+
+// recordScoreboard saves a match's final scoreboard. GUIDs become
+// player_guid_ids, which follow the player through merges, and aren't
+// stored themselves.
+func recordScoreboard(ctx context.Context, db execer, matchID int64, lines []domain.MatchEndScore) error {
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		UPDATE matches SET scoreboard = (
+			SELECT json_group_array(json_object(
+				'client_id', l.value ->> '$.client_id',
+				'player_guid_id', pg.id,
+				'name', l.value ->> '$.name',
+				'score', l.value ->> '$.score',
+				'ping', l.value ->> '$.ping',
+				'team', l.value ->> '$.team'
+			) ORDER BY l.key)
+			FROM json_each(?) l
+			LEFT JOIN player_guids pg ON pg.guid = l.value ->> '$.guid'
+		)
+		WHERE id = ?
+	`, string(data), matchID)
+	return err
+}
+
+// setLogCursor moves a server's log cursor to c, unless it's already
+// at a later line.
+func setLogCursor(ctx context.Context, db execer, serverID int64, c domain.LogCursor) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO log_cursors (server_id, inode, byte_offset, log_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(server_id) DO UPDATE SET
+			inode = excluded.inode,
+			byte_offset = excluded.byte_offset,
+			log_at = excluded.log_at,
+			updated_at = excluded.updated_at
+		WHERE excluded.log_at >= log_cursors.log_at
+	`, serverID, int64(c.Inode), c.Offset, formatTimestamp(c.At), formatTimestamp(time.Now()))
+	return err
+}
+
+// GetLogCursor returns a server's log cursor, or nil if no match with
+// one has been flushed.
+func (s *Store) GetLogCursor(ctx context.Context, serverID int64) (*domain.LogCursor, error) {
+	var c domain.LogCursor
+	var inode int64
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT inode, byte_offset, log_at FROM log_cursors WHERE server_id = ?
+	`, serverID).Scan(&inode, &c.Offset, &c.At)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Inode = uint64(inode)
+	return &c, nil
+}
@@ -0,0 +1,335 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// The merge analysis looks back over mergeAnalysisWindow of sessions.
+// Names and IP ranges shared by more than mergeGroupMax players say
+// nothing about any one pair (a LAN, a carrier NAT, "Player"), so those
+// groups are skipped.
+const (
+	mergeAnalysisWindow = 90 * 24 * time.Hour
+	mergeGroupMax       = 4
+	mergeHandoffGap     = 10 * time.Minute
+	mergeMinHandoffs    = 2
+)
+
+// Signal weights; a pair needs mergeMinScore, so any one signal alone
+// isn't enough.
+var mergeReasonWeights = map[string]int{
+	domain.MergeReasonSharedIPRange:       40,
+	domain.MergeReasonSharedName:          30,
+	domain.MergeReasonAlternatingSessions: 30,
+}
+
+const mergeMinScore = 60
+
+type playerPair struct{ a, b int64 }
+
+func newPlayerPair(x, y int64) playerPair {
+	if x > y {
+		x, y = y, x
+	}
+	return playerPair{x, y}
+}
+
+type sessionSpan struct {
+	serverID     int64
+	joined, left time.Time
+}
+
+// RefreshMergeSuggestions reruns the merge analysis as of now. New and
+// still-matching pairs are written with their current score; pairs
+// that no longer match are dropped unless an admin dismissed them,
+// which keeps them from coming back. Returns how many pairs are
+// suggested.
+func (s *Store) RefreshMergeSuggestions(ctx context.Context, now time.Time) (int, error) {
+	since := now.Add(-mergeAnalysisWindow)
+	reasons := make(map[playerPair][]string)
+
+	byRange, err := s.mergeGroupsByIPRange(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	addGroupPairs(reasons, byRange, domain.MergeReasonSharedIPRange)
+
+	byName, err := s.mergeGroupsByName(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	addGroupPairs(reasons, byName, domain.MergeReasonSharedName)
+
+	for pair := range reasons {
+		alternating, err := s.sessionsAlternate(ctx, pair, since)
+		if err != nil {
+			return 0, err
+		}
+		if alternating {
+			reasons[pair] = append(reasons[pair], domain.MergeReasonAlternatingSessions)
+		}
+	}
+
+	var kept []playerPair
+	for pair, rs := range reasons {
+		score := 0
+		for _, r := range rs {
+			score += mergeReasonWeights[r]
+		}
+		if score < mergeMinScore {
+			continue
+		}
+		if _, err := s.conn(ctx).ExecContext(ctx, `
+			INSERT INTO merge_suggestions (player_id, other_player_id, score, reasons, detected_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(player_id, other_player_id) DO UPDATE SET
+				score = excluded.score,
+				reasons = excluded.reasons
+			WHERE dismissed_at IS NULL
+		`, pair.a, pair.b, score, strings.Join(rs, ","), formatTimestamp(now)); err != nil {
+			return 0, err
+		}
+		kept = append(kept, pair)
+	}
+
+	// Everything not re-confirmed this run (and not dismissed) goes.
+	keep := make(map[playerPair]bool, len(kept))
+	for _, p := range kept {
+		keep[p] = true
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `SELECT id, player_id, other_player_id FROM merge_suggestions WHERE dismissed_at IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		var p playerPair
+		if err := rows.Scan(&id, &p.a, &p.b); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !keep[p] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range stale {
+		if _, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM merge_suggestions WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(kept), nil
+}
+
+// addGroupPairs notes reason against every pair within each group of
+// at most mergeGroupMax players.
+func addGroupPairs(reasons map[playerPair][]string, groups map[string]map[int64]bool, reason string) {
+	for _, members := range groups {
+		if len(members) < 2 || len(members) > mergeGroupMax {
+			continue
+		}
+		ids := make([]int64, 0, len(members))
+		for id := range members {
+			ids = append(ids, id)
+		}
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				p := newPlayerPair(ids[i], ids[j])
+				if !slices.Contains(reasons[p], reason) {
+					reasons[p] = append(reasons[p], reason)
+				}
+			}
+		}
+	}
+}
+
+// mergeGroupsByIPRange groups human players by the /24 (IPv4) or /48
+// (IPv6) they've played from since since.
+func (s *Store) mergeGroupsByIPRange(ctx context.Context, since time.Time) (map[string]map[int64]bool, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT DISTINCT pg.player_id, se.ip_address
+		FROM sessions se
+		JOIN player_guids pg ON se.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE se.joined_at >= ? AND se.ip_address != '' AND p.is_bot = FALSE
+	`, formatTimestamp(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[string]map[int64]bool)
+	for rows.Next() {
+		var playerID int64
+		var ip string
+		if err := rows.Scan(&playerID, &ip); err != nil {
+			return nil, err
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		bits := 48
+		if addr.Is4() {
+			bits = 24
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		key := prefix.String()
+		if groups[key] == nil {
+			groups[key] = make(map[int64]bool)
+		}
+		groups[key][playerID] = true
+	}
+	return groups, rows.Err()
+}
+
+// mergeGroupsByName groups human players by every clean name (case
+// folded) their GUIDs have used, among players seen since since.
+func (s *Store) mergeGroupsByName(ctx context.Context, since time.Time) (map[string]map[int64]bool, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT DISTINCT pg.player_id, LOWER(pn.clean_name)
+		FROM player_names pn
+		JOIN player_guids pg ON pn.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE p.is_bot = FALSE AND p.last_seen >= ? AND pn.clean_name != ''
+		  AND pn.clean_name NOT LIKE '[VR] Player#%'
+	`, formatTimestamp(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[string]map[int64]bool)
+	for rows.Next() {
+		var playerID int64
+		var name string
+		if err := rows.Scan(&playerID, &name); err != nil {
+			return nil, err
+		}
+		if groups[name] == nil {
+			groups[name] = make(map[int64]bool)
+		}
+		groups[name][playerID] = true
+	}
+	return groups, rows.Err()
+}
+
+// sessionsAlternate reports whether the pair's sessions since since
+// never overlap and one of them has joined a server within
+// mergeHandoffGap of the other leaving it at least mergeMinHandoffs
+// times.
+func (s *Store) sessionsAlternate(ctx context.Context, pair playerPair, since time.Time) (bool, error) {
+	a, err := s.playerSessionSpans(ctx, pair.a, since)
+	if err != nil {
+		return false, err
+	}
+	b, err := s.playerSessionSpans(ctx, pair.b, since)
+	if err != nil {
+		return false, err
+	}
+	handoffs := 0
+	for _, x := range a {
+		for _, y := range b {
+			if x.joined.Before(y.left) && y.joined.Before(x.left) {
+				return false, nil
+			}
+			if x.serverID != y.serverID {
+				continue
+			}
+			if gap := y.joined.Sub(x.left); gap >= 0 && gap <= mergeHandoffGap {
+				handoffs++
+			} else if gap := x.joined.Sub(y.left); gap >= 0 && gap <= mergeHandoffGap {
+				handoffs++
+			}
+		}
+	}
+	return handoffs >= mergeMinHandoffs, nil
+}
+
+// playerSessionSpans returns a player's closed sessions since since.
+func (s *Store) playerSessionSpans(ctx context.Context, playerID int64, since time.Time) ([]sessionSpan, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT se.server_id, se.joined_at, se.left_at
+		FROM sessions se
+		JOIN player_guids pg ON se.player_guid_id = pg.id
+		WHERE pg.player_id = ? AND se.joined_at >= ? AND se.left_at IS NOT NULL
+	`, playerID, formatTimestamp(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var spans []sessionSpan
+	for rows.Next() {
+		var sp sessionSpan
+		if err := rows.Scan(&sp.serverID, &sp.joined, &sp.left); err != nil {
+			return nil, err
+		}
+		spans = append(spans, sp)
+	}
+	return spans, rows.Err()
+}
+
+// ListMergeSuggestions returns the pairs awaiting an admin, likeliest
+// first.
+func (s *Store) ListMergeSuggestions(ctx context.Context, limit int) ([]domain.MergeSuggestion, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT ms.id, ms.score, ms.reasons, ms.detected_at,
+			p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
+			o.id, o.name, o.clean_name, o.first_seen, o.last_seen
+		FROM merge_suggestions ms
+		JOIN players p ON ms.player_id = p.id
+		JOIN players o ON ms.other_player_id = o.id
+		WHERE ms.dismissed_at IS NULL
+		ORDER BY ms.score DESC, ms.detected_at DESC, ms.id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.MergeSuggestion{}
+	for rows.Next() {
+		var m domain.MergeSuggestion
+		var reasons string
+		if err := rows.Scan(&m.ID, &m.Score, &reasons, &m.DetectedAt,
+			&m.Player.ID, &m.Player.Name, &m.Player.CleanName, &m.Player.FirstSeen, &m.Player.LastSeen,
+			&m.Other.ID, &m.Other.Name, &m.Other.CleanName, &m.Other.FirstSeen, &m.Other.LastSeen); err != nil {
+			return nil, err
+		}
+		m.Reasons = strings.Split(reasons, ",")
+		sort.Strings(m.Reasons)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// DismissMergeSuggestion marks a suggestion as not the same person so
+// the analysis won't raise it again. Returns sql.ErrNoRows if there's
+// no such suggestion.
+func (s *Store) DismissMergeSuggestion(ctx context.Context, id int64, at time.Time) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE merge_suggestions SET dismissed_at = ? WHERE id = ?`, formatTimestamp(at), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// mustSessionFrom is mustSession with a client IP.
+func mustSessionFrom(t *testing.T, s *Store, pg *domain.PlayerGUID, serverID int64, ip string, joined, left time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	sess := &domain.Session{PlayerGUIDID: pg.ID, ServerID: serverID, JoinedAt: joined, IPAddress: ip}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.EndSession(ctx, sess.ID, left); err != nil {
+		t.Fatalf("EndSession: %v", err)
+	}
+}
+
+func TestMergeSuggestions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	upsert := func(guid, name string) *domain.PlayerGUID {
+		pg, err := s.UpsertPlayerGUID(ctx, guid, name, name, now.Add(-30*24*time.Hour), false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		return pg
+	}
+	primary := upsert("AAAA", "Sniper")
+	smurf := upsert("BBBB", "Sniper")
+	roommate := upsert("CCCC", "Roomie")
+
+	// primary and smurf take turns from the same /24 and never overlap.
+	day := now.Add(-5 * 24 * time.Hour)
+	for i := range 3 {
+		start := day.Add(time.Duration(i) * 24 * time.Hour)
+		mustSessionFrom(t, s, primary, srv.ID, "203.0.113.7", start, start.Add(time.Hour))
+		mustSessionFrom(t, s, smurf, srv.ID, "203.0.113.42", start.Add(time.Hour+2*time.Minute), start.Add(2*time.Hour))
+	}
+	// roommate shares the range but plays alongside primary, under their
+	// own name: one signal, not enough.
+	mustSessionFrom(t, s, roommate, srv.ID, "203.0.113.9", day.Add(10*time.Minute), day.Add(50*time.Minute))
+
+	n, err := s.RefreshMergeSuggestions(ctx, now)
+	if err != nil {
+		t.Fatalf("RefreshMergeSuggestions: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("got %d suggestions, want 1", n)
+	}
+	list, err := s.ListMergeSuggestions(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListMergeSuggestions: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("listed %d suggestions, want 1: %+v", len(list), list)
+	}
+	got := list[0]
+	if got.Player.ID != primary.PlayerID || got.Other.ID != smurf.PlayerID {
+		t.Errorf("pair: got %d/%d, want %d/%d", got.Player.ID, got.Other.ID, primary.PlayerID, smurf.PlayerID)
+	}
+	if got.Score != 100 {
+		t.Errorf("score: got %d, want 100", got.Score)
+	}
+	want := []string{domain.MergeReasonAlternatingSessions, domain.MergeReasonSharedIPRange, domain.MergeReasonSharedName}
+	if len(got.Reasons) != len(want) {
+		t.Fatalf("reasons: got %v, want %v", got.Reasons, want)
+	}
+	for i := range want {
+		if got.Reasons[i] != want[i] {
+			t.Errorf("reasons: got %v, want %v", got.Reasons, want)
+			break
+		}
+	}
+
+	// A dismissed pair stays dismissed across refreshes.
+	if err := s.DismissMergeSuggestion(ctx, got.ID, now); err != nil {
+		t.Fatalf("DismissMergeSuggestion: %v", err)
+	}
+	if _, err := s.RefreshMergeSuggestions(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatalf("RefreshMergeSuggestions: %v", err)
+	}
+	list, err = s.ListMergeSuggestions(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListMergeSuggestions: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("dismissed pair came back: %+v", list)
+	}
+	if err := s.DismissMergeSuggestion(ctx, got.ID+100, now); err == nil {
+		t.Error("dismissing an unknown suggestion: want error")
+	}
+}
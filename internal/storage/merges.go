@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// Errors from UndoPlayerMerge.
+var (
+	ErrMergeAlreadyUndone = errors.New("merge has already been undone")
+	ErrMergeTargetGone    = errors.New("merged-into player no longer exists")
+	ErrMergeSourceTaken   = errors.New("merged player's id is in use again")
+)
+
+// mergeRefs are the columns MergePlayers repoints from source to
+// target, as recorded in player_merge_refs.ref. The keys are spliced
+// into SQL, so only these are ever used.
+var mergeRefs = map[string]struct{ table, column string }{
+	"admin_commands.admin_player_id":  {"admin_commands", "admin_player_id"},
+	"admin_commands.target_player_id": {"admin_commands", "target_player_id"},
+	"match_chat.player_id":            {"match_chat", "player_id"},
+	"match_chat.to_player_id":         {"match_chat", "to_player_id"},
+	"moderation_incidents.player_id":  {"moderation_incidents", "player_id"},
+	"season_standings.player_id":      {"season_standings", "player_id"},
+	"tournament_matches.player1_id":   {"tournament_matches", "player1_id"},
+	"tournament_matches.player2_id":   {"tournament_matches", "player2_id"},
+	"tournament_matches.winner_id":    {"tournament_matches", "winner_id"},
+	"tournaments.winner_id":           {"tournaments", "winner_id"},
+	"vip_kicks.vip_player_id":         {"vip_kicks", "vip_player_id"},
+	"vip_kicks.kicked_player_id":      {"vip_kicks", "kicked_player_id"},
+}
+
+// sqlStep is one statement in a run executed in order.
+type sqlStep struct {
+	query string
+	args  []any
+}
+
+// recordPlayerMerge writes the undo record for merging source into
+// target. Call inside the merge's transaction before anything moves.
+func recordPlayerMerge(ctx context.Context, tx txn, targetPlayerID, sourcePlayerID int64, at time.Time) (int64, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO player_merges (
+			target_player_id, source_player_id,
+			source_name, source_clean_name, source_first_seen, source_last_seen,
+			source_total_playtime_seconds, source_is_vr,
+			source_exclude_from_leaderboards, source_leaderboard_exclusion_reason, source_user_id,
+			target_exclude_from_leaderboards, target_leaderboard_exclusion_reason, merged_at)
+		SELECT ?, p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
+			COALESCE(p.total_playtime_seconds, 0), COALESCE(p.is_vr, 0),
+			COALESCE(p.exclude_from_leaderboards, 0), p.leaderboard_exclusion_reason,
+			(SELECT id FROM users WHERE player_id = p.id),
+			COALESCE(t.exclude_from_leaderboards, 0), t.leaderboard_exclusion_reason, ?
+		FROM players p
+		JOIN players t ON t.id = ?
+		WHERE p.id = ?
+	`, targetPlayerID, formatTimestamp(at), targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	mergeID, _ := res.LastInsertId()
+
+	steps := []sqlStep{
+		{`INSERT INTO player_merge_guids (merge_id, player_guid_id)
+			SELECT ?, id FROM player_guids WHERE player_id = ?`, []any{mergeID, sourcePlayerID}},
+		{`INSERT INTO player_merge_achievements (merge_id, achievement, match_id, earned_at, copied)
+			SELECT ?, a.achievement, a.match_id, a.earned_at,
+				NOT EXISTS(SELECT 1 FROM player_achievements t WHERE t.player_id = ? AND t.achievement = a.achievement)
+			FROM player_achievements a WHERE a.player_id = ?`, []any{mergeID, targetPlayerID, sourcePlayerID}},
+	}
+	for ref, rc := range mergeRefs {
+		steps = append(steps, sqlStep{`INSERT INTO player_merge_refs (merge_id, ref, row_id)
+			SELECT ?, ?, id FROM ` + rc.table + ` WHERE ` + rc.column + ` = ?`, []any{mergeID, ref, sourcePlayerID}})
+	}
+	for _, st := range steps {
+		if _, err := tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			return 0, err
+		}
+	}
+	return mergeID, nil
+}
+
+// refreshPlayerFromGUIDs recomputes a player's first_seen, last_seen
+// and is_vr from the GUIDs they own. A player left with no GUIDs keeps
+// their dates.
+func refreshPlayerFromGUIDs(ctx context.Context, tx txn, playerID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE players SET
+			first_seen = COALESCE((SELECT MIN(first_seen) FROM player_guids WHERE player_id = ?), first_seen),
+			last_seen = COALESCE((SELECT MAX(last_seen) FROM player_guids WHERE player_id = ?), last_seen),
+			is_vr = EXISTS(SELECT 1 FROM player_guids WHERE player_id = ? AND is_vr = TRUE)
+		WHERE id = ?
+	`, playerID, playerID, playerID, playerID)
+	return err
+}
+
+// ListPlayerMerges returns recorded merges, newest first.
+func (s *Store) ListPlayerMerges(ctx context.Context, limit int) ([]domain.PlayerMerge, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT m.id, m.target_player_id, COALESCE(t.name, ''), m.source_player_id, m.source_name,
+			(SELECT COUNT(*) FROM player_merge_guids g WHERE g.merge_id = m.id),
+			m.merged_at, m.undone_at
+		FROM player_merges m
+		LEFT JOIN players t ON t.id = m.target_player_id
+		ORDER BY m.merged_at DESC, m.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.PlayerMerge{}
+	for rows.Next() {
+		var m domain.PlayerMerge
+		var undoneAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.TargetPlayerID, &m.TargetName, &m.SourcePlayerID, &m.SourceName,
+			&m.GUIDCount, &m.MergedAt, &undoneAt); err != nil {
+			return nil, err
+		}
+		if undoneAt.Valid {
+			m.UndoneAt = &undoneAt.Time
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// UndoPlayerMerge reverses merge mergeID: the source player comes back
+// under its old id with its GUIDs, badges, chat, and linked account,
+// and the target gets back its own exclusion. GUIDs that have since
+// been split off the target stay where they are. Returns the restored
+// player's id, or sql.ErrNoRows for an unknown merge.
+func (s *Store) UndoPlayerMerge(ctx context.Context, mergeID int64, at time.Time) (int64, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		targetID, sourceID           int64
+		name, cleanName              string
+		firstSeen, lastSeen          sql.NullTime
+		playtime                     int64
+		isVR, sourceExcl, targetExcl bool
+		sourceReason, targetReason   sql.NullString
+		userID                       sql.NullInt64
+		undoneAt                     sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT target_player_id, source_player_id, source_name, source_clean_name,
+			source_first_seen, source_last_seen, source_total_playtime_seconds, source_is_vr,
+			source_exclude_from_leaderboards, source_leaderboard_exclusion_reason, source_user_id,
+			target_exclude_from_leaderboards, target_leaderboard_exclusion_reason, undone_at
+		FROM player_merges WHERE id = ?
+	`, mergeID).Scan(&targetID, &sourceID, &name, &cleanName, &firstSeen, &lastSeen, &playtime, &isVR,
+		&sourceExcl, &sourceReason, &userID, &targetExcl, &targetReason, &undoneAt)
+	if err != nil {
+		return 0, err
+	}
+	if undoneAt.Valid {
+		return 0, ErrMergeAlreadyUndone
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM players WHERE id = ?`, targetID).Scan(&n); err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrMergeTargetGone
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM players WHERE id = ?`, sourceID).Scan(&n); err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		return 0, ErrMergeSourceTaken
+	}
+
+	timestamp := func(t sql.NullTime) any {
+		if !t.Valid {
+			return nil
+		}
+		return formatTimestamp(t.Time)
+	}
+	steps := []sqlStep{
+		{`INSERT INTO players (id, name, clean_name, first_seen, last_seen, total_playtime_seconds,
+				is_bot, is_vr, exclude_from_leaderboards, leaderboard_exclusion_reason)
+			VALUES (?, ?, ?, ?, ?, ?, FALSE, ?, ?, ?)`,
+			[]any{sourceID, name, cleanName, timestamp(firstSeen), timestamp(lastSeen), playtime, isVR, sourceExcl, sourceReason}},
+		{`UPDATE player_guids SET player_id = ?
+			WHERE player_id = ? AND id IN (SELECT player_guid_id FROM player_merge_guids WHERE merge_id = ?)`,
+			[]any{sourceID, targetID, mergeID}},
+		{`INSERT OR IGNORE INTO player_achievements (player_id, achievement, match_id, earned_at)
+			SELECT ?, achievement, match_id, earned_at FROM player_merge_achievements WHERE merge_id = ?`,
+			[]any{sourceID, mergeID}},
+		{`DELETE FROM player_achievements WHERE player_id = ?
+			AND achievement IN (SELECT achievement FROM player_merge_achievements WHERE merge_id = ? AND copied = TRUE)`,
+			[]any{targetID, mergeID}},
+		{`UPDATE users SET player_id = ? WHERE id = ? AND player_id IS NULL`, []any{sourceID, userID}},
+		{`UPDATE player_merges SET undone_at = ? WHERE id = ?`, []any{formatTimestamp(at), mergeID}},
+	}
+	// The target only had an exclusion because of the source
+	if sourceExcl && !targetExcl {
+		steps = append(steps, sqlStep{`UPDATE players SET exclude_from_leaderboards = ?, leaderboard_exclusion_reason = ? WHERE id = ?`,
+			[]any{targetExcl, targetReason, targetID}})
+	}
+	for ref, rc := range mergeRefs {
+		steps = append(steps, sqlStep{`UPDATE ` + rc.table + ` SET ` + rc.column + ` = ?
+			WHERE ` + rc.column + ` = ? AND id IN (SELECT row_id FROM player_merge_refs WHERE merge_id = ? AND ref = ?)`,
+			[]any{sourceID, targetID, mergeID, ref}})
+	}
+	for _, st := range steps {
+		if _, err := tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			return 0, err
+		}
+	}
+	for _, id := range []int64{targetID, sourceID} {
+		if err := refreshPlayerFromGUIDs(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.InvalidateLeaderboardCache()
+	if err := s.rebuildPlayerSnapshots(ctx, targetID); err != nil {
+		return 0, err
+	}
+	return sourceID, s.rebuildPlayerSnapshots(ctx, sourceID)
+}
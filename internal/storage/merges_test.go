@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestMergeAndUndo(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	target, err := s.UpsertPlayerGUID(ctx, "AAAA", "main", "main", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	source, err := s.UpsertPlayerGUID(ctx, "BBBB", "alt", "alt", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// The source has a badge, a chat line, an exclusion and an account,
+	// all of which the merge moves or drops.
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	if _, err := s.AwardAchievement(ctx, source.PlayerID, domain.AchievementThousandFrags, m.ID, base); err != nil {
+		t.Fatalf("AwardAchievement: %v", err)
+	}
+	if err := s.InsertMatchChat(ctx, m.ID, domain.ChatLine{Kind: "say", PlayerID: &source.PlayerID, Name: "alt", Message: "gg", SentAt: base}); err != nil {
+		t.Fatalf("InsertMatchChat: %v", err)
+	}
+	if err := s.SetLeaderboardExclusion(ctx, source.PlayerID, true, "cheating"); err != nil {
+		t.Fatalf("SetLeaderboardExclusion: %v", err)
+	}
+	userID := mustCreateUser(t, s, "alt")
+	if err := s.UpdateUserPlayerLink(ctx, userID, &source.PlayerID); err != nil {
+		t.Fatalf("UpdateUserPlayerLink: %v", err)
+	}
+
+	if err := s.MergePlayers(ctx, target.PlayerID, source.PlayerID); err != nil {
+		t.Fatalf("MergePlayers: %v", err)
+	}
+	if p, _ := s.GetPlayerByID(ctx, target.PlayerID); p == nil || !p.LeaderboardExcluded {
+		t.Fatalf("target after merge: got %+v, want excluded", p)
+	}
+	merges, err := s.ListPlayerMerges(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListPlayerMerges: %v", err)
+	}
+	if len(merges) != 1 || merges[0].SourcePlayerID != source.PlayerID || merges[0].GUIDCount != 1 {
+		t.Fatalf("merges: got %+v", merges)
+	}
+
+	restored, err := s.UndoPlayerMerge(ctx, merges[0].ID, time.Now())
+	if err != nil {
+		t.Fatalf("UndoPlayerMerge: %v", err)
+	}
+	if restored != source.PlayerID {
+		t.Errorf("restored player: got %d, want %d", restored, source.PlayerID)
+	}
+	p, err := s.GetPlayerByID(ctx, source.PlayerID)
+	if err != nil || p.Name != "alt" || !p.LeaderboardExcluded {
+		t.Fatalf("restored player: got %+v, %v", p, err)
+	}
+	guids, err := s.GetPlayerGUIDs(ctx, source.PlayerID)
+	if err != nil || len(guids) != 1 || guids[0].GUID != "BBBB" {
+		t.Errorf("restored GUIDs: got %+v, %v", guids, err)
+	}
+	if p, _ := s.GetPlayerByID(ctx, target.PlayerID); p.LeaderboardExcluded {
+		t.Error("target still excluded after undo")
+	}
+	for id, want := range map[int64]int{source.PlayerID: 1, target.PlayerID: 0} {
+		got, err := s.GetPlayerAchievements(ctx, id)
+		if err != nil || len(got) != want {
+			t.Errorf("player %d achievements: got %d, want %d (%v)", id, len(got), want, err)
+		}
+	}
+	chat, err := s.GetMatchChat(ctx, m.ID, false)
+	if err != nil || len(chat) != 1 || chat[0].PlayerID == nil || *chat[0].PlayerID != source.PlayerID {
+		t.Errorf("chat: got %+v, %v", chat, err)
+	}
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil || u.PlayerID == nil || *u.PlayerID != source.PlayerID {
+		t.Errorf("user link: got %+v, %v", u, err)
+	}
+
+	if _, err := s.UndoPlayerMerge(ctx, merges[0].ID, time.Now()); !errors.Is(err, ErrMergeAlreadyUndone) {
+		t.Errorf("second undo: got %v, want ErrMergeAlreadyUndone", err)
+	}
+}
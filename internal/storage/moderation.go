@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordModerationIncident stores a chat filter hit.
+func (s *Store) RecordModerationIncident(ctx context.Context, inc domain.ModerationIncident) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO moderation_incidents (server_id, match_id, player_id, name, message, matched, action, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, inc.ServerID, inc.MatchID, inc.PlayerID, inc.Name, inc.Message, inc.Matched, inc.Action, formatTimestamp(inc.CreatedAt))
+	return err
+}
+
+// ModerationFilter narrows ListModerationIncidents. Zero fields match
+// everything.
+type ModerationFilter struct {
+	ServerID int64
+	PlayerID int64
+	Limit    int
+}
+
+// ListModerationIncidents returns incidents newest first.
+func (s *Store) ListModerationIncidents(ctx context.Context, f ModerationFilter) ([]domain.ModerationIncident, error) {
+	var conds []string
+	var args []interface{}
+	if f.ServerID != 0 {
+		conds = append(conds, "mi.server_id = ?")
+		args = append(args, f.ServerID)
+	}
+	if f.PlayerID != 0 {
+		conds = append(conds, "mi.player_id = ?")
+		args = append(args, f.PlayerID)
+	}
+	query := `
+		SELECT mi.id, mi.server_id, sv.key, mi.match_id, mi.player_id, mi.name, mi.message, mi.matched, mi.action, mi.created_at
+		FROM moderation_incidents mi
+		JOIN servers sv ON sv.id = mi.server_id`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY mi.created_at DESC, mi.id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.ModerationIncident{}
+	for rows.Next() {
+		var inc domain.ModerationIncident
+		var matchID, playerID sql.NullInt64
+		if err := rows.Scan(&inc.ID, &inc.ServerID, &inc.ServerKey, &matchID, &playerID, &inc.Name, &inc.Message, &inc.Matched, &inc.Action, &inc.CreatedAt); err != nil {
+			return nil, err
+		}
+		inc.MatchID = scanNullInt64Ptr(matchID)
+		inc.PlayerID = scanNullInt64Ptr(playerID)
+		out = append(out, inc)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestOvertimeMatches(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	overtime := 95
+	ids := map[string]int64{}
+	for i, uuid := range []string{"regulation", "overtime"} {
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		end := domain.MatchEndData{MatchUUID: uuid, EndedAt: m.StartedAt.Add(10 * time.Minute), ExitReason: "Timelimit hit"}
+		if uuid == "overtime" {
+			end.Overtime = &overtime
+		}
+		if _, err := s.FlushMatch(ctx, m, end, func(tx *MatchTx) {
+			if err := tx.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 20, 5, true, nil, nil, "", 0, false,
+				0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}); err != nil {
+			t.Fatalf("FlushMatch: %v", err)
+		}
+		ids[uuid] = m.ID
+	}
+
+	summary, err := s.GetMatchSummaryByID(ctx, ids["overtime"])
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	if !summary.Overtime || summary.OvertimeSeconds == nil || *summary.OvertimeSeconds != 95 {
+		t.Errorf("overtime = %v, %v; want true, 95", summary.Overtime, summary.OvertimeSeconds)
+	}
+	if summary, err = s.GetMatchSummaryByID(ctx, ids["regulation"]); err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	if summary.Overtime || summary.OvertimeSeconds != nil {
+		t.Errorf("regulation match flagged overtime: %+v", summary)
+	}
+
+	yes, no := true, false
+	for _, tc := range []struct {
+		overtime *bool
+		want     []int64
+	}{
+		{nil, []int64{ids["overtime"], ids["regulation"]}},
+		{&yes, []int64{ids["overtime"]}},
+		{&no, []int64{ids["regulation"]}},
+	} {
+		got, err := s.GetFilteredMatchSummaries(ctx, MatchFilter{Overtime: tc.overtime})
+		if err != nil {
+			t.Fatalf("GetFilteredMatchSummaries: %v", err)
+		}
+		if len(got) != len(tc.want) || got[0].ID != tc.want[0] {
+			t.Errorf("overtime filter %v: got %d matches, want %v", tc.overtime, len(got), tc.want)
+		}
+	}
+}
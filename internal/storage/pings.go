@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// lagSpikeMs is the least a ping sample needs to count as a lag spike,
+// on top of being at least double the player's median.
+const lagSpikeMs = 250
+
+// maxLagSpikes caps the spikes GetPlayerPingStats returns.
+const maxLagSpikes = 20
+
+// RecordPingSamples stores one ping sample per GUID against its open
+// session on the server. GUIDs without an open session are skipped.
+func (s *Store) RecordPingSamples(ctx context.Context, serverID int64, at time.Time, pings map[string]int) error {
+	if len(pings) == 0 {
+		return nil
+	}
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for guid, ping := range pings {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO session_pings (session_id, sampled_at, ping)
+			SELECT s.id, ?, ?
+			FROM sessions s
+			JOIN player_guids pg ON pg.id = s.player_guid_id
+			WHERE s.server_id = ? AND pg.guid = ? AND s.left_at IS NULL
+			ORDER BY s.joined_at DESC
+			LIMIT 1
+		`, formatTimestamp(at), ping, serverID, guid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// pingSample is one row of session_pings with what it's grouped by.
+type pingSample struct {
+	serverID    int64
+	serverKey   string
+	source      string
+	playerID    int64
+	countryCode string
+	country     string
+	sampledAt   time.Time
+	ping        int
+}
+
+// pingSamples returns the period's samples, for one player if
+// playerID is non-zero.
+func (s *Store) pingSamples(ctx context.Context, period string, playerID int64) ([]pingSample, error) {
+	start, end := getTimePeriodBounds(period, time.Time{})
+	where := "sp.sampled_at >= ? AND sp.sampled_at < ?"
+	args := []any{formatTimestamp(start), formatTimestamp(end)}
+	if playerID != 0 {
+		where += " AND pg.player_id = ?"
+		args = append(args, playerID)
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT s.server_id, srv.key, srv.source, pg.player_id,
+			COALESCE(s.country_code, ''), COALESCE(s.country, ''),
+			sp.sampled_at, sp.ping
+		FROM session_pings sp
+		JOIN sessions s ON s.id = sp.session_id
+		JOIN servers srv ON srv.id = s.server_id
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		WHERE `+where+`
+		ORDER BY sp.sampled_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []pingSample
+	for rows.Next() {
+		var p pingSample
+		if err := rows.Scan(&p.serverID, &p.serverKey, &p.source, &p.playerID,
+			&p.countryCode, &p.country, &p.sampledAt, &p.ping); err != nil {
+			return nil, err
+		}
+		samples = append(samples, p)
+	}
+	return samples, rows.Err()
+}
+
+// GetPlayerPingStats summarizes the player's ping samples over a
+// leaderboard period, overall and per server, with their most recent
+// lag spikes.
+func (s *Store) GetPlayerPingStats(ctx context.Context, playerID int64, period string) (*domain.PlayerPingResponse, error) {
+	samples, err := s.pingSamples(ctx, period, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.PlayerPingResponse{
+		PlayerID: playerID,
+		Period:   period,
+		Servers:  []domain.ServerPing{},
+		Spikes:   []domain.PingSpike{},
+	}
+	all := make([]int, 0, len(samples))
+	byServer := map[int64][]int{}
+	servers := map[int64]domain.ServerPing{}
+	for _, p := range samples {
+		all = append(all, p.ping)
+		byServer[p.serverID] = append(byServer[p.serverID], p.ping)
+		servers[p.serverID] = domain.ServerPing{ServerID: p.serverID, ServerKey: p.serverKey, Source: p.source}
+	}
+	resp.Samples, resp.AvgPing, resp.MedianPing = summarizePings(all)
+	for id, sp := range servers {
+		sp.Samples, sp.AvgPing, sp.MedianPing = summarizePings(byServer[id])
+		resp.Servers = append(resp.Servers, sp)
+	}
+	slices.SortFunc(resp.Servers, func(a, b domain.ServerPing) int {
+		return cmp.Or(cmp.Compare(b.Samples, a.Samples), cmp.Compare(a.ServerID, b.ServerID))
+	})
+
+	threshold := max(lagSpikeMs, 2*resp.MedianPing)
+	for i := len(samples) - 1; i >= 0 && len(resp.Spikes) < maxLagSpikes; i-- {
+		if p := samples[i]; p.ping >= threshold {
+			resp.Spikes = append(resp.Spikes, domain.PingSpike{
+				SampledAt: p.sampledAt, Ping: p.ping, ServerID: p.serverID, ServerKey: p.serverKey,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// GetServerPingStats summarizes every server's ping samples over a
+// leaderboard period, overall and by the country players connected
+// from.
+func (s *Store) GetServerPingStats(ctx context.Context, period string) (*domain.ServerPingResponse, error) {
+	samples, err := s.pingSamples(ctx, period, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	type regionKey struct {
+		serverID    int64
+		countryCode string
+	}
+	servers := map[int64]*domain.ServerPingStats{}
+	byServer := map[int64][]int{}
+	byRegion := map[regionKey][]int{}
+	regionPlayers := map[regionKey]map[int64]bool{}
+	countries := map[string]string{}
+	for _, p := range samples {
+		if servers[p.serverID] == nil {
+			servers[p.serverID] = &domain.ServerPingStats{ServerID: p.serverID, ServerKey: p.serverKey, Source: p.source}
+		}
+		byServer[p.serverID] = append(byServer[p.serverID], p.ping)
+		k := regionKey{p.serverID, p.countryCode}
+		byRegion[k] = append(byRegion[k], p.ping)
+		if regionPlayers[k] == nil {
+			regionPlayers[k] = map[int64]bool{}
+		}
+		regionPlayers[k][p.playerID] = true
+		if p.country != "" {
+			countries[p.countryCode] = p.country
+		}
+	}
+
+	regions := map[int64][]domain.RegionPing{}
+	for k, pings := range byRegion {
+		r := domain.RegionPing{
+			CountryCode: k.countryCode,
+			Country:     countries[k.countryCode],
+			Players:     int64(len(regionPlayers[k])),
+		}
+		r.Samples, r.AvgPing, r.MedianPing = summarizePings(pings)
+		regions[k.serverID] = append(regions[k.serverID], r)
+	}
+	resp := &domain.ServerPingResponse{Period: period, Servers: []domain.ServerPingStats{}}
+	for id, srv := range servers {
+		srv.Samples, srv.AvgPing, srv.MedianPing = summarizePings(byServer[id])
+		srv.Regions = regions[id]
+		slices.SortFunc(srv.Regions, func(a, b domain.RegionPing) int {
+			return cmp.Or(cmp.Compare(b.Samples, a.Samples), cmp.Compare(a.CountryCode, b.CountryCode))
+		})
+		resp.Servers = append(resp.Servers, *srv)
+	}
+	slices.SortFunc(resp.Servers, func(a, b domain.ServerPingStats) int {
+		return cmp.Or(cmp.Compare(b.Samples, a.Samples), cmp.Compare(a.ServerID, b.ServerID))
+	})
+	return resp, nil
+}
+
+// summarizePings counts, averages and takes the median of pings,
+// sorting them in place.
+func summarizePings(pings []int) (samples int64, avg float64, median int) {
+	if len(pings) == 0 {
+		return 0, 0, 0
+	}
+	slices.Sort(pings)
+	total := 0
+	for _, p := range pings {
+		total += p
+	}
+	n := len(pings)
+	median = pings[n/2]
+	if n%2 == 0 {
+		median = (pings[n/2-1] + pings[n/2]) / 2
+	}
+	return int64(n), float64(total) / float64(n), median
+}
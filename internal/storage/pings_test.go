@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestPingStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", now, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	for _, sess := range []*domain.Session{
+		{PlayerGUIDID: alice.ID, ServerID: srv.ID, JoinedAt: now.Add(-time.Hour), CountryCode: "DE", Country: "Germany"},
+		{PlayerGUIDID: bob.ID, ServerID: srv.ID, JoinedAt: now.Add(-time.Hour), CountryCode: "US", Country: "United States"},
+	} {
+		if err := s.CreateSession(ctx, sess); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+
+	// Alice sits around 40 ms with one spike; bob is across the ocean.
+	for i, alicePing := range []int{40, 42, 38, 300} {
+		at := now.Add(time.Duration(i-4) * time.Minute)
+		if err := s.RecordPingSamples(ctx, srv.ID, at, map[string]int{"AAAA": alicePing, "BBBB": 120, "CCCC": 10}); err != nil {
+			t.Fatalf("RecordPingSamples: %v", err)
+		}
+	}
+
+	resp, err := s.GetPlayerPingStats(ctx, alice.PlayerID, "day")
+	if err != nil {
+		t.Fatalf("GetPlayerPingStats: %v", err)
+	}
+	if resp.Samples != 4 || resp.AvgPing != 105 || resp.MedianPing != 41 {
+		t.Errorf("alice: got %d samples, avg %v, median %d", resp.Samples, resp.AvgPing, resp.MedianPing)
+	}
+	if len(resp.Servers) != 1 || resp.Servers[0].ServerID != srv.ID || resp.Servers[0].Samples != 4 {
+		t.Errorf("alice servers: got %+v", resp.Servers)
+	}
+	if len(resp.Spikes) != 1 || resp.Spikes[0].Ping != 300 {
+		t.Errorf("alice spikes: got %+v", resp.Spikes)
+	}
+
+	servers, err := s.GetServerPingStats(ctx, "day")
+	if err != nil {
+		t.Fatalf("GetServerPingStats: %v", err)
+	}
+	if len(servers.Servers) != 1 || servers.Servers[0].Samples != 8 {
+		t.Fatalf("servers: got %+v", servers.Servers)
+	}
+	regions := servers.Servers[0].Regions
+	if len(regions) != 2 || regions[0].CountryCode != "DE" || regions[1].Country != "United States" ||
+		regions[1].MedianPing != 120 || regions[1].Players != 1 {
+		t.Errorf("regions: got %+v", regions)
+	}
+}
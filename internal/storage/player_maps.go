@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// playerMapSorts are the orderings GetPlayerMapStats accepts, each
+// broken by matches played and then map name.
+var playerMapSorts = map[string]string{
+	"matches":     "matches",
+	"kd_ratio":    "kd_ratio",
+	"win_rate":    "win_rate",
+	"last_played": "last_played",
+}
+
+// ValidPlayerMapSort reports whether GetPlayerMapStats accepts sort.
+func ValidPlayerMapSort(sort string) bool {
+	_, ok := playerMapSorts[sort]
+	return ok
+}
+
+// GetPlayerMapStats breaks the player's finished matches down by map,
+// over a leaderboard period and optionally one game type. sort is one
+// of matches, kd_ratio, win_rate or last_played; desc puts the highest
+// first.
+func (s *Store) GetPlayerMapStats(ctx context.Context, playerID int64, period, gameType, sort string, desc bool) (*domain.PlayerMapsResponse, error) {
+	start, end := getTimePeriodBounds(period, time.Time{})
+
+	where := "pg.player_id = ? AND m.ended_at IS NOT NULL"
+	args := []any{playerID}
+	if period != "all" {
+		where += " AND m.started_at >= ? AND m.started_at < ?"
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	if gameType != "" {
+		where += " AND m.game_type = ?"
+		args = append(args, gameType)
+	}
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	orderBy := playerMapSorts[sort] + " " + dir
+	if sort != "matches" {
+		orderBy += ", matches DESC"
+	}
+
+	// A reconnect leaves several rows in one match; count it once.
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT m.map_name,
+			COUNT(DISTINCT CASE WHEN mps.completed = 1 THEN m.id END) as matches,
+			COUNT(DISTINCT CASE WHEN mps.completed = 1 AND mps.victories > 0 THEN m.id END) as victories,
+			COALESCE(CAST(COUNT(DISTINCT CASE WHEN mps.completed = 1 AND mps.victories > 0 THEN m.id END) AS REAL)
+				/ NULLIF(COUNT(DISTINCT CASE WHEN mps.completed = 1 THEN m.id END), 0), 0) as win_rate,
+			SUM(mps.frags), SUM(mps.deaths),
+			CASE WHEN SUM(mps.deaths) > 0
+				THEN CAST(SUM(mps.frags) AS REAL) / SUM(mps.deaths)
+				ELSE SUM(mps.frags) END as kd_ratio,
+			MAX(m.ended_at) as last_played
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		JOIN matches m ON m.id = mps.match_id
+		WHERE `+where+` AND COALESCE(m.map_name, '') != ''
+		GROUP BY m.map_name
+		ORDER BY `+orderBy+`, m.map_name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.PlayerMapsResponse{
+		PlayerID: playerID,
+		Period:   period,
+		Maps:     []domain.PlayerMapStats{},
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	for rows.Next() {
+		var ms domain.PlayerMapStats
+		var lastPlayed string
+		if err := rows.Scan(&ms.MapName, &ms.Matches, &ms.Victories, &ms.WinRate,
+			&ms.Frags, &ms.Deaths, &ms.KDRatio, &lastPlayed); err != nil {
+			return nil, err
+		}
+		if ms.LastPlayed, err = time.Parse(time.RFC3339, lastPlayed); err != nil {
+			return nil, err
+		}
+		resp.Maps = append(resp.Maps, ms)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestPlayerMapStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	// Alice wins twice of three on q3dm17 and loses once on q3dm6.
+	games := []struct {
+		mapName       string
+		frags, deaths int
+		won           bool
+	}{
+		{"q3dm17", 20, 5, true},
+		{"q3dm17", 10, 10, false},
+		{"q3dm17", 15, 5, true},
+		{"q3dm6", 5, 10, false},
+	}
+	for i, g := range games {
+		started := base.Add(time.Duration(i) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: g.mapName, GameType: "ffa", StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, g.frags, g.deaths, true, nil, nil, "", 0, g.won,
+			0, 0, 0, 0, 0, 0, 0, false, false, started, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.EndMatch(ctx, m.ID, started.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	resp, err := s.GetPlayerMapStats(ctx, alice.PlayerID, "all", "", "matches", true)
+	if err != nil {
+		t.Fatalf("GetPlayerMapStats: %v", err)
+	}
+	if len(resp.Maps) != 2 {
+		t.Fatalf("got %d maps, want 2", len(resp.Maps))
+	}
+	dm17 := resp.Maps[0]
+	if dm17.MapName != "q3dm17" || dm17.Matches != 3 || dm17.Victories != 2 || dm17.Frags != 45 || dm17.KDRatio != 2.25 {
+		t.Errorf("q3dm17: got %+v", dm17)
+	}
+	if dm17.WinRate < 0.66 || dm17.WinRate > 0.67 {
+		t.Errorf("q3dm17 win rate: got %v", dm17.WinRate)
+	}
+	if !dm17.LastPlayed.Equal(base.Add(2*time.Hour + 10*time.Minute)) {
+		t.Errorf("q3dm17 last played: got %v", dm17.LastPlayed)
+	}
+
+	// Worst K/D first.
+	resp, err = s.GetPlayerMapStats(ctx, alice.PlayerID, "all", "", "kd_ratio", false)
+	if err != nil {
+		t.Fatalf("GetPlayerMapStats: %v", err)
+	}
+	if resp.Maps[0].MapName != "q3dm6" || resp.Maps[0].WinRate != 0 {
+		t.Errorf("worst map: got %+v", resp.Maps[0])
+	}
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// SetUserProfile replaces the profile of userID's account. It shows on
+// whichever player the account is linked to.
+func (s *Store) SetUserProfile(ctx context.Context, userID int64, p domain.Profile) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE users SET avatar_url = ?, preferred_model = ?, bio = ?, country_code = ?
+		WHERE id = ?
+	`, p.AvatarURL, p.PreferredModel, p.Bio, p.CountryCode, userID)
+	if err != nil {
+		return err
+	}
+	s.InvalidateLeaderboardCache()
+	return nil
+}
+
+// attachProfiles fills in Profile for each of players whose linked
+// account has set one, in a single query.
+func (s *Store) attachProfiles(ctx context.Context, players ...*domain.Player) error {
+	if len(players) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*domain.Player, len(players))
+	ids := make([]int64, len(players))
+	for i, p := range players {
+		byID[p.ID] = p
+		ids[i] = p.ID
+	}
+	in, args := idPlaceholders(ids)
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT player_id, avatar_url, preferred_model, bio, country_code
+		FROM users
+		WHERE player_id IN (`+in+`)
+		  AND (avatar_url != '' OR preferred_model != '' OR bio != '' OR country_code != '')
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var p domain.Profile
+		if err := rows.Scan(&id, &p.AvatarURL, &p.PreferredModel, &p.Bio, &p.CountryCode); err != nil {
+			return err
+		}
+		byID[id].Profile = &p
+	}
+	return rows.Err()
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestProfileShowsOnPlayerAndLeaderboard(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	// Five matches each to qualify for the leaderboard.
+	for i := 0; i < 5; i++ {
+		started := base.Add(time.Duration(i) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for c, pg := range []int64{alice.ID, bob.ID} {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg, c, 20-c*10, 5, true, nil, nil, "", 0, c == 0,
+				0, 0, 0, 0, 0, 0, 0, false, false, started, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, started.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	userID := mustCreateUser(t, s, "alice")
+	if err := s.UpdateUserPlayerLink(ctx, userID, &alice.PlayerID); err != nil {
+		t.Fatalf("UpdateUserPlayerLink: %v", err)
+	}
+	want := domain.Profile{PreferredModel: "sarge/krusade", Bio: "rail or nothing", CountryCode: "NZ"}
+	if err := s.SetUserProfile(ctx, userID, want); err != nil {
+		t.Fatalf("SetUserProfile: %v", err)
+	}
+
+	p, err := s.GetPlayerByID(ctx, alice.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerByID: %v", err)
+	}
+	if p.Profile == nil || *p.Profile != want {
+		t.Errorf("player profile = %+v, want %+v", p.Profile, want)
+	}
+
+	resp, err := s.GetLeaderboard(ctx, "frags", "all", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(resp.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(resp.Entries))
+	}
+	if e := resp.Entries[0]; e.Player.Profile == nil || e.Player.Profile.CountryCode != "NZ" {
+		t.Errorf("alice's entry profile = %+v", e.Player.Profile)
+	}
+	if e := resp.Entries[1]; e.Player.Profile != nil {
+		t.Errorf("bob has no account, got profile %+v", e.Player.Profile)
+	}
+}
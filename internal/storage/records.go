@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// recordColumns maps each record category to the match_player_stats
+// column it ranks.
+var recordColumns = map[string]string{
+	domain.RecordFrags:      "mps.frags",
+	domain.RecordScore:      "mps.score",
+	domain.RecordCaptures:   "mps.captures",
+	domain.RecordBestSpree:  "mps.best_spree",
+	domain.RecordFastestCap: "mps.fastest_cap_ms",
+}
+
+// RecordFastestCap lowers the player's fastest flag capture for
+// matchID to ms if it beats what's stored. Call after
+// FlushMatchPlayerStats so the row exists.
+func (s *Store) RecordFastestCap(ctx context.Context, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	return recordFastestCap(ctx, s.conn(ctx), matchID, playerGUIDID, clientID, ms)
+}
+
+func recordFastestCap(ctx context.Context, db execer, matchID, playerGUIDID int64, clientID int, ms int64) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE match_player_stats SET fastest_cap_ms = MIN(COALESCE(fastest_cap_ms, ?), ?)
+		WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+	`, ms, ms, matchID, playerGUIDID, clientID)
+	return err
+}
+
+// recordRow is a record candidate with the GUID row that set it.
+type recordRow struct {
+	domain.Record
+	guidID int64
+}
+
+// bestPerformances returns, for each map and gametype among finished
+// matches satisfying where, the best value of category by a human who
+// counts for leaderboards. Ties go to the earliest match.
+func (s *Store) bestPerformances(ctx context.Context, category, where string, args ...interface{}) ([]recordRow, error) {
+	col := recordColumns[category]
+	order := col + " DESC"
+	if c, _ := domain.RecordCategoryByID(category); c.LowerIsBetter {
+		order = col + " ASC"
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT map_name, game_type, value, player_guid_id, player_id, name, clean_name, match_id, ended_at FROM (
+			SELECT m.map_name, m.game_type, `+col+` AS value, mps.player_guid_id,
+				p.id AS player_id, p.name, p.clean_name, m.id AS match_id, m.ended_at,
+				ROW_NUMBER() OVER (
+					PARTITION BY m.map_name, m.game_type
+					ORDER BY `+order+`, m.ended_at, m.id
+				) AS rn
+			FROM match_player_stats mps
+			JOIN matches m ON m.id = mps.match_id
+			JOIN player_guids pg ON pg.id = mps.player_guid_id
+			JOIN players p ON p.id = pg.player_id
+			WHERE m.ended_at IS NOT NULL AND p.is_bot = FALSE
+			  AND COALESCE(p.exclude_from_leaderboards, 0) = 0
+			  AND `+col+` > 0 AND (`+where+`)
+		) WHERE rn = 1
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []recordRow
+	for rows.Next() {
+		r := recordRow{Record: domain.Record{Category: category}}
+		if err := rows.Scan(&r.MapName, &r.GameType, &r.Value, &r.guidID, &r.Player.ID, &r.Player.Name, &r.Player.CleanName, &r.MatchID, &r.SetAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// upsertRecord stores r as the holder of its category. The holder is
+// kept as the GUID row that set it, so merges and splits carry it.
+func (s *Store) upsertRecord(ctx context.Context, r recordRow) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO records (map_name, game_type, category, value, player_guid_id, match_id, set_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(map_name, game_type, category) DO UPDATE SET
+			value = excluded.value,
+			player_guid_id = excluded.player_guid_id,
+			match_id = excluded.match_id,
+			set_at = excluded.set_at
+	`, r.MapName, r.GameType, r.Category, r.Value, r.guidID, r.MatchID, formatTimestamp(r.SetAt))
+	return err
+}
+
+// RebuildRecords recomputes the records for a map and gametype from
+// match history, after stats or leaderboard exclusions change. Empty
+// mapName and gameType rebuild every record.
+func (s *Store) RebuildRecords(ctx context.Context, mapName, gameType string) error {
+	return s.rebuildRecords(ctx, mapName, gameType, 0)
+}
+
+// rebuildRecords is RebuildRecords leaving out skipMatch (0 for none).
+func (s *Store) rebuildRecords(ctx context.Context, mapName, gameType string, skipMatch int64) error {
+	del, delArgs := "1 = 1", []interface{}{}
+	where, args := "m.id != ?", []interface{}{skipMatch}
+	if mapName != "" || gameType != "" {
+		del, delArgs = "map_name = ? AND game_type = ?", []interface{}{mapName, gameType}
+		where += " AND m.map_name = ? AND m.game_type = ?"
+		args = append(args, mapName, gameType)
+	}
+	if _, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM records WHERE `+del, delArgs...); err != nil {
+		return err
+	}
+	for _, c := range domain.RecordCategories {
+		best, err := s.bestPerformances(ctx, c.ID, where, args...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.ID, err)
+		}
+		for _, r := range best {
+			if err := s.upsertRecord(ctx, r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateRecordsForMatch checks a just-finished match against the
+// records for its map and gametype and stores any it beat. Returns the
+// records broken; a category's first record doesn't count as broken.
+// The first match on a map since records began backfills them from
+// history before comparing.
+func (s *Store) UpdateRecordsForMatch(ctx context.Context, matchID int64) ([]domain.BrokenRecord, error) {
+	var mapName, gameType string
+	err := s.conn(ctx).QueryRowContext(ctx, `SELECT map_name, game_type FROM matches WHERE id = ?`, matchID).Scan(&mapName, &gameType)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	err = s.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM records WHERE map_name = ? AND game_type = ?`, mapName, gameType).Scan(&n)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if err := s.rebuildRecords(ctx, mapName, gameType, matchID); err != nil {
+			return nil, err
+		}
+	}
+
+	var broken []domain.BrokenRecord
+	for _, c := range domain.RecordCategories {
+		best, err := s.bestPerformances(ctx, c.ID, "m.id = ?", matchID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.ID, err)
+		}
+		for _, r := range best {
+			prev, err := s.getRecord(ctx, r.MapName, r.GameType, r.Category)
+			if err != nil {
+				return nil, err
+			}
+			if prev != nil && (prev.Value == r.Value || (prev.Value < r.Value) == c.LowerIsBetter) {
+				continue
+			}
+			if err := s.upsertRecord(ctx, r); err != nil {
+				return nil, err
+			}
+			if prev != nil {
+				broken = append(broken, domain.BrokenRecord{Record: r.Record, Previous: *prev})
+			}
+		}
+	}
+	return broken, nil
+}
+
+const recordSelect = `
+	SELECT r.map_name, r.game_type, r.category, r.value, p.id, p.name, p.clean_name, p.is_vr, r.match_id, r.set_at
+	FROM records r
+	JOIN player_guids pg ON pg.id = r.player_guid_id
+	JOIN players p ON p.id = pg.player_id
+`
+
+func scanRecord(sc scanner) (*domain.Record, error) {
+	var r domain.Record
+	err := sc.Scan(&r.MapName, &r.GameType, &r.Category, &r.Value, &r.Player.ID, &r.Player.Name, &r.Player.CleanName, &r.Player.IsVR, &r.MatchID, &r.SetAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Store) getRecord(ctx context.Context, mapName, gameType, category string) (*domain.Record, error) {
+	r, err := scanRecord(s.conn(ctx).QueryRowContext(ctx, recordSelect+`
+		WHERE r.map_name = ? AND r.game_type = ? AND r.category = ?
+	`, mapName, gameType, category))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// RecordFilter narrows GetRecords. Zero fields match everything.
+type RecordFilter struct {
+	MapName  string
+	GameType string
+	Category string
+	PlayerID int64
+}
+
+// GetRecords lists current records by map, gametype, then category in
+// display order.
+func (s *Store) GetRecords(ctx context.Context, f RecordFilter) ([]domain.Record, error) {
+	var conds []string
+	var args []interface{}
+	if f.MapName != "" {
+		conds = append(conds, "r.map_name = ?")
+		args = append(args, f.MapName)
+	}
+	if f.GameType != "" {
+		conds = append(conds, "r.game_type = ?")
+		args = append(args, f.GameType)
+	}
+	if f.Category != "" {
+		conds = append(conds, "r.category = ?")
+		args = append(args, f.Category)
+	}
+	if f.PlayerID != 0 {
+		conds = append(conds, "p.id = ?")
+		args = append(args, f.PlayerID)
+	}
+	query := recordSelect
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	order := "CASE r.category"
+	for i, c := range domain.RecordCategories {
+		order += fmt.Sprintf(" WHEN '%s' THEN %d", c.ID, i)
+	}
+	query += " ORDER BY r.map_name, r.game_type, " + order + " END"
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.Record{}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestUpdateRecordsForMatch(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	type line struct {
+		pg          *domain.PlayerGUID
+		frags, caps int
+		capMs       int64
+	}
+	n := 0
+	play := func(lines ...line) int64 {
+		t.Helper()
+		n++
+		ended := base.Add(time.Duration(n) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(n), ServerID: srv.ID, MapName: "q3ctf4", GameType: "ctf", StartedAt: ended.Add(-15 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for _, l := range lines {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, l.pg.ID, 0, l.frags, 3, true, nil, nil, "", 0, false,
+				l.caps, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+			if l.capMs > 0 {
+				if err := s.RecordFastestCap(ctx, m.ID, l.pg.ID, 0, l.capMs); err != nil {
+					t.Fatalf("RecordFastestCap: %v", err)
+				}
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, ended, "capturelimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+		return m.ID
+	}
+	update := func(matchID int64) []domain.BrokenRecord {
+		t.Helper()
+		broken, err := s.UpdateRecordsForMatch(ctx, matchID)
+		if err != nil {
+			t.Fatalf("UpdateRecordsForMatch: %v", err)
+		}
+		return broken
+	}
+	holder := func(category string) domain.Record {
+		t.Helper()
+		got, err := s.GetRecords(ctx, RecordFilter{MapName: "q3ctf4", GameType: "ctf", Category: category})
+		if err != nil || len(got) != 1 {
+			t.Fatalf("GetRecords %s: got %+v, %v", category, got, err)
+		}
+		return got[0]
+	}
+
+	// Played before records existed: the next match backfills from it.
+	first := play(line{alice, 20, 2, 30000})
+	if broken := update(play(line{bob, 10, 1, 25000})); len(broken) != 1 || broken[0].Category != domain.RecordFastestCap || broken[0].Previous.Value != 30000 {
+		t.Fatalf("after backfill: got %+v", broken)
+	}
+	if r := holder(domain.RecordFrags); r.Value != 20 || r.MatchID != first || r.Player.ID != alice.PlayerID {
+		t.Errorf("frags record: got %+v", r)
+	}
+
+	// A tie doesn't take the record; beating it does.
+	if broken := update(play(line{bob, 20, 0, 0})); len(broken) != 0 {
+		t.Errorf("tie should not break: got %+v", broken)
+	}
+	broken := update(play(line{bob, 21, 0, 0}))
+	if len(broken) != 1 || broken[0].Value != 21 || broken[0].Previous.Player.ID != alice.PlayerID {
+		t.Fatalf("beat frags: got %+v", broken)
+	}
+
+	// Excluding bob hands his records back.
+	if err := s.SetLeaderboardExclusion(ctx, bob.PlayerID, true, "test account"); err != nil {
+		t.Fatalf("SetLeaderboardExclusion: %v", err)
+	}
+	if r := holder(domain.RecordFrags); r.Player.ID != alice.PlayerID || r.Value != 20 {
+		t.Errorf("frags after exclusion: got %+v", r)
+	}
+	if r := holder(domain.RecordFastestCap); r.Value != 30000 {
+		t.Errorf("fastest cap after exclusion: got %+v", r)
+	}
+}
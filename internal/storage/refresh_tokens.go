@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Errors from RotateRefreshToken.
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// CreateRefreshToken stores the hash of a new refresh token for
+// userID, clearing out the user's expired ones while it's there.
+func (s *Store) CreateRefreshToken(ctx context.Context, userID int64, tokenHash string, createdAt, expiresAt time.Time) error {
+	if _, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = ? AND expires_at < ?`,
+		userID, formatTimestamp(createdAt)); err != nil {
+		return err
+	}
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, tokenHash, formatTimestamp(createdAt), formatTimestamp(expiresAt))
+	return err
+}
+
+// RotateRefreshToken trades the token hashed oldHash for a new one
+// hashed newHash, and returns the user it belongs to. A token that's
+// unknown, expired, or revoked gets ErrRefreshTokenInvalid. One that
+// was already traded in has been copied, so every token the user has
+// is revoked and ErrRefreshTokenReused returned.
+func (s *Store) RotateRefreshToken(ctx context.Context, oldHash, newHash string, now, expiresAt time.Time) (int64, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id, userID        int64
+		expires           time.Time
+		usedAt, revokedAt sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = ?
+	`, oldHash).Scan(&id, &userID, &expires, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if revokedAt.Valid || !now.Before(expires) {
+		return 0, ErrRefreshTokenInvalid
+	}
+	if usedAt.Valid {
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+			formatTimestamp(now), userID); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+		return userID, ErrRefreshTokenReused
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = ? WHERE id = ?`, formatTimestamp(now), id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, newHash, formatTimestamp(now), formatTimestamp(expiresAt)); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// RevokeRefreshToken revokes one refresh token, as on logout. Unknown
+// tokens are ignored.
+func (s *Store) RevokeRefreshToken(ctx context.Context, tokenHash string, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL`,
+		formatTimestamp(at), tokenHash)
+	return err
+}
+
+// RevokeUserTokens logs a user out everywhere: their refresh tokens
+// are revoked and access tokens issued before at stop being accepted.
+// at is kept to the millisecond, as token times are, so a login right
+// after it still works.
+func (s *Store) RevokeUserTokens(ctx context.Context, userID int64, at time.Time) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		formatTimestamp(at), userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO token_revocations (user_id, revoked_at) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET revoked_at = excluded.revoked_at
+	`, userID, at.UTC().Format("2006-01-02T15:04:05.000Z")); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TokensRevokedAt returns when the user's access tokens were last
+// revoked, or the zero time if never.
+func (s *Store) TokensRevokedAt(ctx context.Context, userID int64) (time.Time, error) {
+	var at time.Time
+	err := s.conn(ctx).QueryRowContext(ctx, `SELECT revoked_at FROM token_revocations WHERE user_id = ?`, userID).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return at, err
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// GetPlayerLastSessionEnd returns when the player's most recent closed
+// session ended, across all their GUIDs. Open sessions are ignored so
+// the session the player is opening right now (which may or may not
+// have been written yet, depending on fact-event ordering) never
+// masks the gap. Returns the zero time when the player has no closed
+// sessions.
+func (s *Store) GetPlayerLastSessionEnd(ctx context.Context, playerID int64) (time.Time, error) {
+	var leftAt sql.NullString
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT MAX(s.left_at)
+		FROM sessions s
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		WHERE pg.player_id = ? AND s.left_at IS NOT NULL
+	`, playerID).Scan(&leftAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !leftAt.Valid || leftAt.String == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, leftAt.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing left_at %q: %w", leftAt.String, err)
+	}
+	return t.UTC(), nil
+}
+
+// GetReturningPlayers lists human players whose first session inside
+// the period came at least domain.ReturningPlayerThreshold after the
+// end of their previous session. Each player appears at most once
+// (their earliest comeback in the window), longest absence first.
+func (s *Store) GetReturningPlayers(ctx context.Context, period string, limit int, asOf time.Time) (*domain.ReturningPlayersResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+	thresholdDays := domain.ReturningPlayerThreshold.Hours() / 24
+
+	// prev_seen is the latest activity across every earlier session of
+	// the same player, not just the immediately preceding row — two
+	// overlapping sessions on different servers would otherwise make
+	// a short break look like a long one.
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		WITH human_sessions AS (
+			SELECT pg.player_id, s.joined_at,
+				MAX(COALESCE(s.left_at, s.joined_at)) OVER (
+					PARTITION BY pg.player_id ORDER BY s.joined_at
+					ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+				) AS prev_seen
+			FROM sessions s
+			JOIN player_guids pg ON pg.id = s.player_guid_id
+			JOIN players p ON p.id = pg.player_id
+			WHERE p.is_bot = FALSE
+		),
+		returns AS (
+			SELECT player_id, MIN(joined_at) AS returned_at, prev_seen
+			FROM human_sessions
+			WHERE joined_at >= ? AND joined_at < ?
+				AND prev_seen IS NOT NULL
+				AND julianday(joined_at) - julianday(prev_seen) >= ?
+			GROUP BY player_id
+		)
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_vr,
+			r.returned_at, r.prev_seen
+		FROM returns r
+		JOIN players p ON p.id = r.player_id
+		ORDER BY julianday(r.returned_at) - julianday(r.prev_seen) DESC, r.returned_at ASC
+		LIMIT ?
+	`, formatTimestamp(start), formatTimestamp(end), thresholdDays, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := []domain.ReturningPlayer{}
+	for rows.Next() {
+		var rp domain.ReturningPlayer
+		var firstSeen, lastSeen sql.NullTime
+		var returnedAt, prevSeen string
+		if err := rows.Scan(&rp.Player.ID, &rp.Player.Name, &rp.Player.CleanName,
+			&firstSeen, &lastSeen, &rp.Player.IsVR, &returnedAt, &prevSeen); err != nil {
+			return nil, err
+		}
+		if firstSeen.Valid {
+			rp.Player.FirstSeen = firstSeen.Time
+		}
+		if lastSeen.Valid {
+			rp.Player.LastSeen = lastSeen.Time
+		}
+		if rp.ReturnedAt, err = time.Parse(time.RFC3339, returnedAt); err != nil {
+			return nil, fmt.Errorf("parsing returned_at %q: %w", returnedAt, err)
+		}
+		if rp.LastSeenAt, err = time.Parse(time.RFC3339, prevSeen); err != nil {
+			return nil, fmt.Errorf("parsing prev_seen %q: %w", prevSeen, err)
+		}
+		rp.DaysAway = int(rp.ReturnedAt.Sub(rp.LastSeenAt).Hours() / 24)
+		players = append(players, rp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &domain.ReturningPlayersResponse{
+		Period:  period,
+		Players: players,
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// mustSession opens and closes a session for pg on srv.
+func mustSession(t *testing.T, s *Store, pg *domain.PlayerGUID, serverID int64, joined, left time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	sess := &domain.Session{PlayerGUIDID: pg.ID, ServerID: serverID, JoinedAt: joined}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.EndSession(ctx, sess.ID, left); err != nil {
+		t.Fatalf("EndSession: %v", err)
+	}
+}
+
+func TestGetReturningPlayers(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	asOf := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+
+	returner, err := s.UpsertPlayerGUID(ctx, "AAAA", "Returner", "Returner", asOf.Add(-100*24*time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	regular, err := s.UpsertPlayerGUID(ctx, "BBBB", "Regular", "Regular", asOf.Add(-100*24*time.Hour), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// Returner: played 95 days ago, then came back twice this week.
+	// Only the first comeback counts.
+	mustSession(t, s, returner, srv.ID, asOf.Add(-95*24*time.Hour), asOf.Add(-95*24*time.Hour+time.Hour))
+	mustSession(t, s, returner, srv.ID, asOf.Add(-3*24*time.Hour), asOf.Add(-3*24*time.Hour+time.Hour))
+	mustSession(t, s, returner, srv.ID, asOf.Add(-1*24*time.Hour), asOf.Add(-1*24*time.Hour+time.Hour))
+
+	// Regular: plays every couple of weeks, never gone long enough.
+	mustSession(t, s, regular, srv.ID, asOf.Add(-20*24*time.Hour), asOf.Add(-20*24*time.Hour+time.Hour))
+	mustSession(t, s, regular, srv.ID, asOf.Add(-2*24*time.Hour), asOf.Add(-2*24*time.Hour+time.Hour))
+
+	resp, err := s.GetReturningPlayers(ctx, "week", 10, asOf)
+	if err != nil {
+		t.Fatalf("GetReturningPlayers: %v", err)
+	}
+	if len(resp.Players) != 1 {
+		t.Fatalf("got %d returning players, want 1: %+v", len(resp.Players), resp.Players)
+	}
+	got := resp.Players[0]
+	if got.Player.ID != returner.PlayerID {
+		t.Errorf("player: got %d, want %d", got.Player.ID, returner.PlayerID)
+	}
+	if got.DaysAway != 91 {
+		t.Errorf("days away: got %d, want 91", got.DaysAway)
+	}
+	if !got.ReturnedAt.Equal(asOf.Add(-3 * 24 * time.Hour)) {
+		t.Errorf("returned_at: got %v, want %v", got.ReturnedAt, asOf.Add(-3*24*time.Hour))
+	}
+
+	last, err := s.GetPlayerLastSessionEnd(ctx, returner.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerLastSessionEnd: %v", err)
+	}
+	if want := asOf.Add(-1*24*time.Hour + time.Hour); !last.Equal(want) {
+		t.Errorf("last session end: got %v, want %v", last, want)
+	}
+}
+
+func TestGetPlayerLastSessionEnd_NoSessions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	pg, err := s.UpsertPlayerGUID(ctx, "CCCC", "New", "New", time.Now(), false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	last, err := s.GetPlayerLastSessionEnd(ctx, pg.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerLastSessionEnd: %v", err)
+	}
+	if !last.IsZero() {
+		t.Errorf("got %v, want zero", last)
+	}
+}
@@ -81,9 +81,10 @@ func scanMatchSummaryRow(s scanner) (*domain.MatchSummary, error) {
 	var redScore, blueScore sql.NullInt64
 	var movement, gameplay sql.NullString
 	var source sql.NullString
+	var overtime sql.NullInt64
 
 	err := s.Scan(&m.ID, &m.UUID, &m.ServerID, &m.ServerKey, &m.ServerActive, &source, &m.MapName, &gameType,
-		&m.StartedAt, &endedAt, &exitReason, &redScore, &blueScore, &movement, &gameplay, &m.DemoAvailable)
+		&m.StartedAt, &endedAt, &exitReason, &redScore, &blueScore, &movement, &gameplay, &m.DemoAvailable, &m.Abandoned, &overtime)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +97,8 @@ func scanMatchSummaryRow(s scanner) (*domain.MatchSummary, error) {
 	m.BlueScore = scanNullInt64ToIntPtr(blueScore)
 	m.Movement = scanNullStringValue(movement)
 	m.Gameplay = scanNullStringValue(gameplay)
+	m.OvertimeSeconds = scanNullInt64ToIntPtr(overtime)
+	m.Overtime = m.OvertimeSeconds != nil
 
 	return &m, nil
 }
@@ -112,13 +115,13 @@ func scanMatchPlayerSummary(s scanner, includeMatchID bool) (int64, *domain.Matc
 	if includeMatchID {
 		err = s.Scan(&matchID, &ps.PlayerID, &ps.Name, &ps.CleanName, &ps.Frags, &ps.Deaths,
 			&ps.Completed, &ps.IsBot, &skill, &score, &team, &model,
-			&ps.Impressives, &ps.Excellents, &ps.Humiliations, &ps.Defends, &ps.Victories, &ps.Captures, &ps.Assists, &ps.IsVR,
-			&ps.IsVerified, &ps.IsAdmin)
+			&ps.Impressives, &ps.Excellents, &ps.Humiliations, &ps.Defends, &ps.Victories, &ps.Captures, &ps.Assists, &ps.SkullsScored, &ps.ObeliskDestroys, &ps.IsVR,
+			&ps.IsVerified, &ps.IsAdmin, &ps.Corrected)
 	} else {
 		err = s.Scan(&ps.PlayerID, &ps.Name, &ps.CleanName, &ps.Frags, &ps.Deaths,
 			&ps.Completed, &ps.IsBot, &skill, &score, &team, &model,
-			&ps.Impressives, &ps.Excellents, &ps.Humiliations, &ps.Defends, &ps.Victories, &ps.Captures, &ps.Assists, &ps.IsVR,
-			&ps.IsVerified, &ps.IsAdmin)
+			&ps.Impressives, &ps.Excellents, &ps.Humiliations, &ps.Defends, &ps.Victories, &ps.Captures, &ps.Assists, &ps.SkullsScored, &ps.ObeliskDestroys, &ps.IsVR,
+			&ps.IsVerified, &ps.IsAdmin, &ps.Corrected)
 	}
 	if err != nil {
 		return 0, nil, err
@@ -42,6 +42,18 @@ CREATE TABLE IF NOT EXISTS servers (
     -- and refuses proxy requests not matching its current state. Owners
     -- of the source can RCON regardless of this flag.
     admin_delegation_enabled INTEGER NOT NULL DEFAULT 0,
+    -- Set by a hub admin to stop the collector answering in-game chat
+    -- commands (!link, !stats, ...) on this server. The collector reads
+    -- it when it registers the server and is told when it changes.
+    commands_disabled INTEGER NOT NULL DEFAULT 0,
+    -- How far ahead of its collector's clock the game server's clock
+    -- is, as detected from its log. Reported on every heartbeat; the
+    -- collector has already corrected the timestamps it sends.
+    clock_offset_ms INTEGER NOT NULL DEFAULT 0,
+    -- 1 for a server the hub only polls over UDP, with no collector
+    -- reading its log: population and live status only, no matches.
+    -- Not gated on handshake_required.
+    query_only INTEGER NOT NULL DEFAULT 0,
     UNIQUE(source, key COLLATE NOCASE)
 );
 
@@ -56,7 +68,9 @@ CREATE TABLE IF NOT EXISTS players (
     last_seen TIMESTAMP,
     total_playtime_seconds INTEGER DEFAULT 0,
     is_bot BOOLEAN DEFAULT FALSE,
-    is_vr BOOLEAN DEFAULT FALSE
+    is_vr BOOLEAN DEFAULT FALSE,
+    exclude_from_leaderboards BOOLEAN DEFAULT FALSE,  -- admin flag: cheaters, staff test accounts
+    leaderboard_exclusion_reason TEXT
 );
 
 CREATE INDEX IF NOT EXISTS idx_players_clean_name ON players(clean_name);
@@ -92,6 +106,52 @@ CREATE TABLE IF NOT EXISTS player_names (
 );
 
 CREATE INDEX IF NOT EXISTS idx_player_names_player_guid_id ON player_names(player_guid_id);
+CREATE INDEX IF NOT EXISTS idx_player_names_clean_name ON player_names(clean_name);
+
+-- Player search index: every clean name a player, GUID or name history
+-- row has had, once each, with an FTS5 trigram index over them. Names
+-- are indexed padded with spaces so a name's first and last letters
+-- make trigrams too, and short queries can match a name's start. The
+-- triggers keep it current; SearchPlayers maps the names it finds back
+-- to players, so a name left behind by a rename or merge finds nothing.
+CREATE TABLE IF NOT EXISTS search_names (
+    name TEXT PRIMARY KEY
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_names_fts USING fts5(name, tokenize = 'trigram');
+
+CREATE TRIGGER IF NOT EXISTS search_names_insert AFTER INSERT ON search_names BEGIN
+    INSERT INTO search_names_fts (rowid, name) VALUES (NEW.rowid, '  ' || NEW.name || ' ');
+END;
+
+CREATE TRIGGER IF NOT EXISTS search_names_delete AFTER DELETE ON search_names BEGIN
+    DELETE FROM search_names_fts WHERE rowid = OLD.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS players_search_insert AFTER INSERT ON players BEGIN
+    INSERT INTO search_names (name) SELECT NEW.clean_name
+    WHERE NOT EXISTS (SELECT 1 FROM search_names WHERE name = NEW.clean_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS players_search_update AFTER UPDATE OF clean_name ON players BEGIN
+    INSERT INTO search_names (name) SELECT NEW.clean_name
+    WHERE NOT EXISTS (SELECT 1 FROM search_names WHERE name = NEW.clean_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS player_guids_search_insert AFTER INSERT ON player_guids BEGIN
+    INSERT INTO search_names (name) SELECT NEW.clean_name
+    WHERE NOT EXISTS (SELECT 1 FROM search_names WHERE name = NEW.clean_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS player_guids_search_update AFTER UPDATE OF clean_name ON player_guids BEGIN
+    INSERT INTO search_names (name) SELECT NEW.clean_name
+    WHERE NOT EXISTS (SELECT 1 FROM search_names WHERE name = NEW.clean_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS player_names_search_insert AFTER INSERT ON player_names BEGIN
+    INSERT INTO search_names (name) SELECT NEW.clean_name
+    WHERE NOT EXISTS (SELECT 1 FROM search_names WHERE name = NEW.clean_name);
+END;
 
 -- Player sessions (joins/leaves) - linked to specific GUID
 CREATE TABLE IF NOT EXISTS sessions (
@@ -103,13 +163,43 @@ CREATE TABLE IF NOT EXISTS sessions (
     duration_seconds INTEGER,
     ip_address TEXT DEFAULT '',
     client_engine TEXT DEFAULT '',
-    client_version TEXT DEFAULT ''
+    client_version TEXT DEFAULT '',
+    country_code TEXT DEFAULT '',    -- ISO 3166-1 alpha-2, from collector GeoIP (optional)
+    country TEXT DEFAULT '',
+    city TEXT DEFAULT '',
+    ip_scrubbed_at TIMESTAMP         -- set once ip_address has been truncated/hashed/blanked per tracker.hub.session_ips
 );
 
 CREATE INDEX IF NOT EXISTS idx_sessions_player_guid_id ON sessions(player_guid_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_server_id ON sessions(server_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_joined_at ON sessions(joined_at);
 
+-- Ping samples the status poller takes from each human's open
+-- session, about once a minute.
+CREATE TABLE IF NOT EXISTS session_pings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    sampled_at TIMESTAMP NOT NULL,
+    ping INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_pings_session ON session_pings(session_id);
+CREATE INDEX IF NOT EXISTS idx_session_pings_sampled_at ON session_pings(sampled_at);
+
+-- How many players a query-only server had, sampled by the status
+-- poller about once a minute. Servers with a collector have sessions
+-- instead.
+CREATE TABLE IF NOT EXISTS server_population (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id INTEGER NOT NULL REFERENCES servers(id),
+    sampled_at TIMESTAMP NOT NULL,
+    humans INTEGER NOT NULL,
+    bots INTEGER NOT NULL,
+    max_clients INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_population_server ON server_population(server_id, sampled_at);
+
 -- Match/game records
 CREATE TABLE IF NOT EXISTS matches (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -127,12 +217,27 @@ CREATE TABLE IF NOT EXISTS matches (
     gameplay TEXT,
     -- Flipped to 1 by FactDemoFinalized; the UI uses this to decide
     -- whether to render a "play demo" button for the match.
-    demo_available INTEGER NOT NULL DEFAULT 0
+    demo_available INTEGER NOT NULL DEFAULT 0,
+    -- The final scoreboard as the server logged it after Exit: a JSON
+    -- array of {client_id, player_guid_id, name, score, ping, team}.
+    -- NULL for matches that didn't exit normally.
+    scoreboard TEXT,
+    -- Set when the humans left and the match ran on without them; its
+    -- stats stay off the leaderboards by default.
+    abandoned BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Seconds the match ran past regulation; NULL if it didn't go to
+    -- overtime.
+    overtime_seconds INTEGER,
+    -- Counts up each time the hub records a match's end, so exports
+    -- can pick up where they left off even when a match arrives long
+    -- after it ended.
+    end_seq INTEGER
 );
 
 CREATE INDEX IF NOT EXISTS idx_matches_server_id ON matches(server_id);
 CREATE INDEX IF NOT EXISTS idx_matches_started_at ON matches(started_at);
 CREATE INDEX IF NOT EXISTS idx_matches_ended_at ON matches(ended_at);
+CREATE INDEX IF NOT EXISTS idx_matches_end_seq ON matches(end_seq);
 CREATE INDEX IF NOT EXISTS idx_matches_uuid ON matches(uuid);
 CREATE INDEX IF NOT EXISTS idx_matches_has_human_player ON matches(has_human_player);
 
@@ -160,12 +265,89 @@ CREATE TABLE IF NOT EXISTS match_player_stats (
     model TEXT,
     skill REAL,
     is_vr BOOLEAN DEFAULT FALSE,
+    best_spree INTEGER DEFAULT 0,
+    fastest_cap_ms INTEGER,
+    skulls_scored INTEGER DEFAULT 0,
+    obelisk_destroys INTEGER DEFAULT 0,
+    flag_carry_ms INTEGER DEFAULT 0,
     PRIMARY KEY (match_id, player_guid_id, client_id)
 );
 
 CREATE INDEX IF NOT EXISTS idx_match_player_stats_player_guid_id ON match_player_stats(player_guid_id);
 CREATE INDEX IF NOT EXISTS idx_match_player_stats_completed ON match_player_stats(completed);
 CREATE INDEX IF NOT EXISTS idx_match_player_stats_covering ON match_player_stats(player_guid_id, match_id, frags, deaths);
+-- Covers the per-map breakdown on the player page, which only needs
+-- the match id to look up the map.
+CREATE INDEX IF NOT EXISTS idx_match_player_stats_map_record ON match_player_stats(player_guid_id, match_id, completed, victories, frags, deaths);
+
+-- Admin fixes to match_player_stats values a parsing bug got wrong.
+-- One row per changed field; the first row for a field holds the
+-- value as the collector originally reported it.
+CREATE TABLE IF NOT EXISTS match_stat_corrections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL REFERENCES matches(id),
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    client_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    old_value INTEGER,
+    new_value INTEGER,
+    reason TEXT NOT NULL,
+    corrected_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    corrected_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_stat_corrections_match ON match_stat_corrections(match_id);
+
+-- Match performances the hub's anomaly analysis found improbable, for
+-- admins to review. kind is a domain.StatFlag* value; weapon is set
+-- for the accuracy kinds and '' otherwise. value is what the player
+-- did and baseline their usual, NULL for kinds judged against a fixed
+-- ceiling. verdict, reviewed_by and reviewed_at are set once an admin
+-- has looked.
+CREATE TABLE IF NOT EXISTS stat_flags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    weapon TEXT NOT NULL DEFAULT '',
+    value REAL NOT NULL,
+    baseline REAL,
+    detected_at TIMESTAMP NOT NULL,
+    verdict TEXT,
+    note TEXT NOT NULL DEFAULT '',
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    UNIQUE(match_id, player_guid_id, kind, weapon)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stat_flags_player_guid_id ON stat_flags(player_guid_id);
+
+-- Client demos (.dm_68), uploaded by admins or harvested from game
+-- servers' demo directories. The file lives in the hub's demo_uploads
+-- dir as <id>.dm_68; filename is the original name.
+CREATE TABLE IF NOT EXISTS match_demos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL REFERENCES matches(id),
+    filename TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    uploaded_at TIMESTAMP NOT NULL,
+    last_downloaded_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_demos_match ON match_demos(match_id);
+
+-- Files the demo harvester has already looked at in a game server's
+-- demo directory, keyed by path. A file that is rewritten (new size or
+-- mtime) is looked at again. demo_id is NULL when no match fit, or once
+-- the copy has been evicted.
+CREATE TABLE IF NOT EXISTS harvested_demos (
+    path TEXT PRIMARY KEY,
+    size_bytes INTEGER NOT NULL,
+    mod_time TIMESTAMP NOT NULL,
+    demo_id INTEGER REFERENCES match_demos(id) ON DELETE SET NULL,
+    harvested_at TIMESTAMP NOT NULL
+);
 
 -- Users for authentication
 CREATE TABLE IF NOT EXISTS users (
@@ -177,7 +359,13 @@ CREATE TABLE IF NOT EXISTS users (
     password_change_required BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     last_login TIMESTAMP,
-    game_token TEXT NOT NULL DEFAULT ''
+    game_token TEXT NOT NULL DEFAULT '',
+    is_vip BOOLEAN DEFAULT FALSE,      -- reserved slots on servers that keep them
+    -- Profile shown on the linked player; see PATCH /api/account/profile
+    avatar_url TEXT NOT NULL DEFAULT '',
+    preferred_model TEXT NOT NULL DEFAULT '',
+    bio TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT ''
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
@@ -301,3 +489,487 @@ CREATE TABLE IF NOT EXISTS directory_registrations (
     validated_at  INTEGER NOT NULL,
     expires_at    INTEGER NOT NULL
 );
+
+-- Map pick/ban sequences recorded by referees for competitive matches.
+-- A veto is entered before (or during) play and linked to the match
+-- row once the game has been tracked; match_id stays NULL until then.
+CREATE TABLE IF NOT EXISTS map_vetoes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    title       TEXT NOT NULL,
+    team_a      TEXT NOT NULL,
+    team_b      TEXT NOT NULL,
+    match_id    INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+    created_by  INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_map_vetoes_match_id ON map_vetoes(match_id);
+
+CREATE TABLE IF NOT EXISTS map_veto_steps (
+    veto_id   INTEGER NOT NULL REFERENCES map_vetoes(id) ON DELETE CASCADE,
+    seq       INTEGER NOT NULL,
+    side      TEXT NOT NULL,   -- 'a', 'b', or '' for a decider
+    action    TEXT NOT NULL,   -- 'ban', 'pick', or 'decider'
+    map_name  TEXT NOT NULL,
+    PRIMARY KEY (veto_id, seq)
+);
+
+-- Badges earned by players. One row per (player, achievement): each is
+-- awarded once, by the hub writer at the end of the match that earned it.
+CREATE TABLE IF NOT EXISTS player_achievements (
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    achievement TEXT NOT NULL,
+    match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+    earned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (player_id, achievement)
+);
+
+-- Best single-match performance per map, gametype and category (see
+-- domain.RecordCategories), kept current by the hub writer at match
+-- end. The holder is the GUID row that set it; ties stay with whoever
+-- set the value first.
+CREATE TABLE IF NOT EXISTS records (
+    map_name TEXT NOT NULL,
+    game_type TEXT NOT NULL,
+    category TEXT NOT NULL,
+    value INTEGER NOT NULL,
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    set_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (map_name, game_type, category)
+);
+
+-- Per-player daily aggregates, written by the hub's snapshot job so
+-- trend charts don't re-scan match_player_stats. day is the UTC date
+-- the match started (YYYY-MM-DD); only finished matches are counted.
+CREATE TABLE IF NOT EXISTS stats_snapshots (
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    matches INTEGER NOT NULL DEFAULT 0,
+    completed_matches INTEGER NOT NULL DEFAULT 0,
+    frags INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    captures INTEGER NOT NULL DEFAULT 0,
+    victories INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (player_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stats_snapshots_day ON stats_snapshots(day);
+
+-- Self-service "forget me" requests. A row stays pending until
+-- execute_after passes and the hub's sweep anonymizes the player,
+-- which stamps completed_at and drops user_id so the account no
+-- longer points at the anonymized player. Deleting a pending row
+-- cancels the request.
+CREATE TABLE IF NOT EXISTS player_anonymizations (
+    player_id INTEGER PRIMARY KEY REFERENCES players(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    requested_at TIMESTAMP NOT NULL,
+    execute_after TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_anonymizations_execute_after ON player_anonymizations(execute_after);
+
+-- In-match chat, written from chat_message facts when chat persistence
+-- is on and pruned by the hub after tracker.hub.chat_retention. Names
+-- are kept as spoken; player ids are NULL for senders the hub never
+-- resolved. to_* columns are set only for tells.
+CREATE TABLE IF NOT EXISTS match_chat (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    team INTEGER NOT NULL DEFAULT 0,
+    to_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    to_name TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    sent_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_chat_match_id ON match_chat(match_id, sent_at);
+CREATE INDEX IF NOT EXISTS idx_match_chat_sent_at ON match_chat(sent_at);
+
+-- Chat lines that tripped a server's moderation word filter, written
+-- from moderation_incident facts. action is what the collector did
+-- (log, warn, mute, kick); the message is kept for review.
+CREATE TABLE IF NOT EXISTS moderation_incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id INTEGER NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+    player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    message TEXT NOT NULL,
+    matched TEXT NOT NULL,
+    action TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_incidents_created_at ON moderation_incidents(created_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_incidents_player_id ON moderation_incidents(player_id);
+
+-- Players kicked to keep a reserved slot free for a VIP, written from
+-- vip_kick facts.
+CREATE TABLE IF NOT EXISTS vip_kicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id INTEGER NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    vip_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    vip_name TEXT NOT NULL,
+    kicked_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    kicked_name TEXT NOT NULL,
+    kicked_is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_vip_kicks_created_at ON vip_kicks(created_at);
+
+-- Chat commands admins ran in game (kick, mute, map), written from
+-- admin_command facts. target is the player's name or the map.
+CREATE TABLE IF NOT EXISTS admin_commands (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id INTEGER NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    admin_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    admin_name TEXT NOT NULL,
+    command TEXT NOT NULL,
+    target TEXT NOT NULL,
+    target_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_commands_created_at ON admin_commands(created_at);
+
+-- Game server crashes reported by collectors, for post-mortems.
+-- reason is no_shutdown or unit_failed; log_lines holds the server's
+-- last log lines before it went down, newline-separated.
+CREATE TABLE IF NOT EXISTS server_incidents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id INTEGER NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL,
+    log_lines TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_incidents_server_id ON server_incidents(server_id, occurred_at);
+
+-- How far into each server's log the hub has flushed match stats: the
+-- byte offset just past the line that ended the last match, in the log
+-- file with inode inode, and that line's time. Written in the same
+-- transaction as the match's stats, so a collector that restarts
+-- resumes exactly after the last flushed match.
+CREATE TABLE IF NOT EXISTS log_cursors (
+    server_id INTEGER PRIMARY KEY REFERENCES servers(id) ON DELETE CASCADE,
+    inode INTEGER NOT NULL,
+    byte_offset INTEGER NOT NULL,
+    log_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Frags on other players by weapon, per match and GUID, from the
+-- match_end fact. weapon is a domain.WeaponSlug. Stints of one GUID in
+-- a match add up.
+CREATE TABLE IF NOT EXISTS match_weapon_frags (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    weapon TEXT NOT NULL,
+    frags INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (match_id, player_guid_id, weapon)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_weapon_frags_player_guid_id ON match_weapon_frags(player_guid_id);
+CREATE INDEX IF NOT EXISTS idx_match_weapon_frags_weapon ON match_weapon_frags(weapon);
+
+-- Shots fired and hits by weapon, per match and GUID, from the
+-- Weapon_Stats lines our mod logs at Exit. weapon is a
+-- domain.WeaponSlug. Servers running a mod without them add no rows.
+CREATE TABLE IF NOT EXISTS match_weapon_accuracy (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    weapon TEXT NOT NULL,
+    shots INTEGER NOT NULL DEFAULT 0,
+    hits INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (match_id, player_guid_id, weapon)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_weapon_accuracy_player_guid_id ON match_weapon_accuracy(player_guid_id);
+CREATE INDEX IF NOT EXISTS idx_match_weapon_accuracy_weapon ON match_weapon_accuracy(weapon);
+
+-- Flag pickups, drops, captures and returns in a match, in log order.
+-- event is a domain.FlagEvent* value and team the team of the flag.
+-- player_guid_id is NULL for a flag that returned on its own.
+CREATE TABLE IF NOT EXISTS match_flag_events (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    event TEXT NOT NULL,
+    team INTEGER NOT NULL,
+    client_id INTEGER NOT NULL,
+    player_guid_id INTEGER REFERENCES player_guids(id) ON DELETE SET NULL,
+    PRIMARY KEY (match_id, seq)
+);
+
+-- Pairs of players that look like one person, found by the hub's
+-- periodic merge analysis. player_id is always the lower id. reasons
+-- is a comma-separated list of domain.MergeReason* values. Dismissed
+-- pairs stay so the analysis doesn't suggest them again.
+CREATE TABLE IF NOT EXISTS merge_suggestions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    other_player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    score INTEGER NOT NULL,
+    reasons TEXT NOT NULL,
+    detected_at TIMESTAMP NOT NULL,
+    dismissed_at TIMESTAMP,
+    UNIQUE(player_id, other_player_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_merge_suggestions_other_player_id ON merge_suggestions(other_player_id);
+
+-- Player merges, with what's needed to undo one: the source player as
+-- it was and the target's exclusion before the merge. No foreign keys
+-- on the player ids, since the source is deleted by the merge and the
+-- history should outlive both. undone_at is set by an unmerge.
+CREATE TABLE IF NOT EXISTS player_merges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_player_id INTEGER NOT NULL,
+    source_player_id INTEGER NOT NULL,
+    source_name TEXT NOT NULL,
+    source_clean_name TEXT NOT NULL,
+    source_first_seen TIMESTAMP,
+    source_last_seen TIMESTAMP,
+    source_total_playtime_seconds INTEGER DEFAULT 0,
+    source_is_vr BOOLEAN DEFAULT FALSE,
+    source_exclude_from_leaderboards BOOLEAN DEFAULT FALSE,
+    source_leaderboard_exclusion_reason TEXT,
+    source_user_id INTEGER,          -- account linked to the source, unlinked by the merge
+    target_exclude_from_leaderboards BOOLEAN DEFAULT FALSE,
+    target_leaderboard_exclusion_reason TEXT,
+    merged_at TIMESTAMP NOT NULL,
+    undone_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_merges_target_player_id ON player_merges(target_player_id);
+
+-- The GUIDs a merge moved from source to target.
+CREATE TABLE IF NOT EXISTS player_merge_guids (
+    merge_id INTEGER NOT NULL REFERENCES player_merges(id) ON DELETE CASCADE,
+    player_guid_id INTEGER NOT NULL,
+    PRIMARY KEY (merge_id, player_guid_id)
+);
+
+-- The source's badges at merge time. copied marks the ones the target
+-- didn't have and was given by the merge.
+CREATE TABLE IF NOT EXISTS player_merge_achievements (
+    merge_id INTEGER NOT NULL REFERENCES player_merges(id) ON DELETE CASCADE,
+    achievement TEXT NOT NULL,
+    match_id INTEGER,
+    earned_at TIMESTAMP NOT NULL,
+    copied BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (merge_id, achievement)
+);
+
+-- Rows a merge repointed from source to target. ref names the table
+-- and column, e.g. match_chat.player_id.
+CREATE TABLE IF NOT EXISTS player_merge_refs (
+    merge_id INTEGER NOT NULL REFERENCES player_merges(id) ON DELETE CASCADE,
+    ref TEXT NOT NULL,
+    row_id INTEGER NOT NULL,
+    PRIMARY KEY (merge_id, ref, row_id)
+);
+
+-- Every "view as user" token an admin has been issued. Usernames are
+-- copied so the trail survives either account being deleted.
+CREATE TABLE IF NOT EXISTS impersonations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    admin_username TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    username TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    remote_addr TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_started_at ON impersonations(started_at);
+
+-- Refresh tokens for web logins. Only a hash of each token is kept.
+-- Refreshing marks the old token used and issues a new one; a used
+-- token coming back means it was copied, so the user's tokens are all
+-- revoked.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- Access tokens issued to a user before revoked_at are rejected, so an
+-- admin can force-logout an account.
+CREATE TABLE IF NOT EXISTS token_revocations (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    revoked_at TIMESTAMP NOT NULL
+);
+
+-- External sign-in identities (Discord, Google, ...) linked to local
+-- users. subject is the provider's stable user id; a user can link one
+-- identity per provider.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    linked_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP,
+    UNIQUE(provider, subject),
+    UNIQUE(user_id, provider)
+);
+
+-- Failed logins and claim-code guesses, counted per client IP and per
+-- username. Past a threshold each failure locks the key out for longer;
+-- a success or an admin clears it.
+CREATE TABLE IF NOT EXISTS auth_lockouts (
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (kind, key)
+);
+
+-- API keys for bots and scripts. Only a hash of each key is kept;
+-- prefix is its first few characters, to tell keys apart in a list.
+-- scope is 'read' or 'admin'.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+-- Clans. Tags are matched case-insensitively against the start or end
+-- of players' clean names to add members automatically.
+CREATE TABLE IF NOT EXISTS clans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    tag TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Clan memberships. role is 'leader' or 'member'; source is 'joined',
+-- 'added' or 'tag'. A member who leaves keeps their row with left_at
+-- set, so the tag doesn't add them back. A player is in at most one
+-- clan at a time.
+CREATE TABLE IF NOT EXISTS clan_members (
+    clan_id INTEGER NOT NULL REFERENCES clans(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    source TEXT NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    left_at TIMESTAMP,
+    PRIMARY KEY (clan_id, player_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clan_members_current ON clan_members(player_id) WHERE left_at IS NULL;
+
+-- Tournaments. format is 'single_elimination', 'round_robin' or
+-- 'pickup'; status is 'signup', 'in_progress' or 'completed'. map_pool
+-- is a JSON array of map names, played one per round.
+CREATE TABLE IF NOT EXISTS tournaments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    format TEXT NOT NULL,
+    game_type TEXT NOT NULL,
+    map_pool TEXT NOT NULL DEFAULT '[]',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'signup',
+    winner_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    winning_team INTEGER,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Players signed up for a tournament. seed is their sign-up order;
+-- team is the side a pickup drafted them to.
+CREATE TABLE IF NOT EXISTS tournament_entrants (
+    tournament_id INTEGER NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    seed INTEGER NOT NULL,
+    team INTEGER,
+    signed_up_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (tournament_id, player_id)
+);
+
+-- A tournament's pairings, drawn when it starts. Later bracket rounds
+-- fill in player1_id/player2_id as results come in. match_id is the
+-- game that settled the pairing.
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tournament_id INTEGER NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round INTEGER NOT NULL,
+    slot INTEGER NOT NULL,
+    map_name TEXT NOT NULL DEFAULT '',
+    player1_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    player2_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    winner_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    winning_team INTEGER,
+    match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+    UNIQUE (tournament_id, round, slot)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_matches_match_id ON tournament_matches(match_id);
+
+-- Seasons. Leaderboards can be scoped to one; category is the board
+-- that decides it. Seasons don't overlap. When ends_at passes the
+-- hub freezes the standings and sets finalized_at.
+CREATE TABLE IF NOT EXISTS seasons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    category TEXT NOT NULL DEFAULT 'frags',
+    finalized_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- A finalized season's frozen standings. entry is the player's
+-- domain.LeaderboardEntry as JSON, less the player; rank 1 won.
+CREATE TABLE IF NOT EXISTS season_standings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    season_id INTEGER NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    rank INTEGER NOT NULL,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    entry TEXT NOT NULL,
+    UNIQUE (season_id, rank)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_standings_player_id ON season_standings(player_id);
+
+-- Frags and deaths from a match's warmup, which the collector sends
+-- with match_start. Warmup play is for fun: these never count toward
+-- the match's stats or any leaderboard.
+CREATE TABLE IF NOT EXISTS match_warmup_stats (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    player_guid_id INTEGER NOT NULL REFERENCES player_guids(id) ON DELETE CASCADE,
+    frags INTEGER NOT NULL DEFAULT 0,
+    deaths INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (match_id, player_guid_id)
+);
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// Player search looks names up in search_names_fts (see schema.sql),
+// then ranks the players holding them. A query finds names it is, that
+// start with it, or that contain it, and, from four letters on, names
+// within a typo or two of it. Players are ranked by the best way one
+// of their names matched, then by who was seen most recently.
+
+// Search match tiers, best first.
+const (
+	searchExact = iota
+	searchPrefix
+	searchSubstring
+	searchFuzzy
+)
+
+// searchCandidates caps the names each index lookup returns.
+const searchCandidates = 200
+
+// SearchPlayers returns the players whose current name, GUID name or
+// past names match query, best match first. Color codes in query are
+// ignored. With includeGUID (admins), a GUID containing query matches
+// too.
+func (s *Store) SearchPlayers(ctx context.Context, query string, limit int, includeGUID bool) ([]domain.Player, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query = strings.TrimSpace(domain.CleanQ3Name(query))
+	if query == "" {
+		return nil, nil
+	}
+
+	names, err := s.searchNames(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	type hit struct {
+		Name string `json:"n"`
+		Tier int    `json:"t"`
+	}
+	hits := []hit{}
+	for name, tier := range names {
+		hits = append(hits, hit{name, tier})
+	}
+	hitsJSON, err := json.Marshal(hits)
+	if err != nil {
+		return nil, err
+	}
+	guidPattern := ""
+	if includeGUID {
+		guidPattern = "%" + query + "%"
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		WITH hits(name, tier) AS (
+			SELECT value ->> '$.n', value ->> '$.t' FROM json_each(?)
+		),
+		matched(player_id, tier) AS (
+			SELECT p.id, h.tier FROM hits h JOIN players p ON p.clean_name = h.name
+			UNION ALL
+			SELECT pg.player_id, h.tier FROM hits h JOIN player_guids pg ON pg.clean_name = h.name
+			UNION ALL
+			SELECT pg.player_id, h.tier FROM hits h
+			JOIN player_names pn ON pn.clean_name = h.name
+			JOIN player_guids pg ON pg.id = pn.player_guid_id
+			UNION ALL
+			SELECT player_id, ? FROM player_guids WHERE ? != '' AND guid LIKE ?
+		)
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
+			COALESCE((
+				SELECT SUM(s.duration_seconds)
+				FROM sessions s
+				JOIN player_guids pg ON s.player_guid_id = pg.id
+				WHERE pg.player_id = p.id AND s.left_at IS NOT NULL
+			), 0) as total_playtime_seconds,
+			p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
+			COALESCE(u.is_admin, 0) as is_admin
+		FROM (SELECT player_id, MIN(tier) AS tier FROM matched GROUP BY player_id) m
+		JOIN players p ON p.id = m.player_id
+		LEFT JOIN users u ON u.player_id = p.id
+		ORDER BY m.tier, p.last_seen DESC
+		LIMIT ?
+	`, string(hitsJSON), searchSubstring, guidPattern, guidPattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []domain.Player
+	for rows.Next() {
+		var p domain.Player
+		if err := rows.Scan(&p.ID, &p.Name, &p.CleanName, &p.FirstSeen, &p.LastSeen, &p.TotalPlaytimeSeconds, &p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// searchNames returns the indexed names that match query, with how.
+// Two lookups find the candidates: names containing query outright,
+// and names sharing the most trigrams with it, which is where typos
+// turn up. Each candidate is then checked in Go.
+func (s *Store) searchNames(ctx context.Context, query string) (map[string]int, error) {
+	lookups := []string{ftsTrigrams(query)}
+	if utf8.RuneCountInString(query) >= 3 {
+		lookups = append(lookups, ftsPhrase(query))
+	}
+	q := strings.ToLower(query)
+	names := map[string]int{}
+	for _, match := range lookups {
+		rows, err := s.conn(ctx).QueryContext(ctx, `
+			SELECT sn.name FROM search_names_fts f
+			JOIN search_names sn ON sn.rowid = f.rowid
+			WHERE search_names_fts MATCH ?
+			ORDER BY f.rank
+			LIMIT ?
+		`, match, searchCandidates)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if tier, ok := nameMatch(q, strings.ToLower(name)); ok {
+				names[name] = tier
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// nameMatch reports how lowercase name matches lowercase query.
+func nameMatch(query, name string) (int, bool) {
+	switch {
+	case name == query:
+		return searchExact, true
+	case strings.HasPrefix(name, query):
+		return searchPrefix, true
+	case strings.Contains(name, query):
+		return searchSubstring, true
+	}
+	q, n := []rune(query), []rune(name)
+	allowed := typoAllowance(len(q))
+	if allowed == 0 {
+		return 0, false
+	}
+	// Against the whole name, or a typo in what the user has typed of it
+	// so far.
+	if editDistance(q, n) <= allowed || (len(n) > len(q) && editDistance(q, n[:len(q)]) <= allowed) {
+		return searchFuzzy, true
+	}
+	return 0, false
+}
+
+// typoAllowance is how many edits a query of n letters may be off by.
+func typoAllowance(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// editDistance is the optimal string alignment distance between a and
+// b: insertions, deletions, substitutions, and swaps of neighbouring
+// letters each count one.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// ftsTrigrams is an FTS5 query for names sharing any trigram with
+// query, padded as the index is so that its start counts too.
+func ftsTrigrams(query string) string {
+	padded := []rune("  " + query + " ")
+	var terms []string
+	for i := 0; i+3 <= len(padded); i++ {
+		terms = append(terms, ftsPhrase(string(padded[i:i+3])))
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// ftsPhrase quotes s as an FTS5 string, which the trigram tokenizer
+// matches anywhere in a name.
+func ftsPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// backfillSearchNames fills search_names from the player tables if
+// it's empty, as it is the first time a database that predates player
+// search is opened. From then on the schema's triggers keep it current.
+func backfillSearchNames(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM search_names)`).Scan(&n); err != nil || n == 1 {
+		return err
+	}
+	_, err := db.Exec(`
+		INSERT INTO search_names (name)
+		SELECT clean_name FROM players
+		UNION SELECT clean_name FROM player_guids
+		UNION SELECT clean_name FROM player_names
+	`)
+	return err
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func searchIDs(t *testing.T, s *Store, query string, includeGUID bool) []int64 {
+	t.Helper()
+	players, err := s.SearchPlayers(context.Background(), query, 20, includeGUID)
+	if err != nil {
+		t.Fatalf("SearchPlayers(%q): %v", query, err)
+	}
+	var ids []int64
+	for _, p := range players {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestSearchPlayers(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	upsert := func(guid, name, clean string, seen time.Time) int64 {
+		pg, err := s.UpsertPlayerGUID(ctx, guid, name, clean, seen, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		return pg.PlayerID
+	}
+	alice := upsert("AAAA", "^1Ali^7ce", "Alice", now.Add(-48*time.Hour))
+	alicia := upsert("BBBB", "Alicia", "Alicia", now)
+	malice := upsert("CCCC", "Malice", "Malice", now.Add(-time.Hour))
+	bob := upsert("DDDD", "Bob", "Bob", now.Add(-time.Hour))
+	// bob used to be called Sarge
+	if _, err := s.UpsertPlayerGUID(ctx, "DDDD", "Sarge", "Sarge", now.Add(-30*time.Minute), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpsertPlayerGUID(ctx, "DDDD", "Bob", "Bob", now, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		// Exact first, then prefix by recency, then substring, then typos.
+		{"alice", []int64{alice, malice, alicia}},
+		{"ali", []int64{alicia, alice, malice}},
+		{"^1ali^7", []int64{alicia, alice, malice}},
+		{"al", []int64{alicia, alice}},
+		// Typos: a swap, and a slip in a prefix.
+		{"alcie", []int64{alice}},
+		{"alicai", []int64{alicia}},
+		{"sarge", []int64{bob}},
+		{"zzz", nil},
+	}
+	for _, tt := range tests {
+		got := searchIDs(t, s, tt.query, false)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if got := searchIDs(t, s, "CCC", false); len(got) != 0 {
+		t.Errorf("GUID matched without includeGUID: %v", got)
+	}
+	if got := searchIDs(t, s, "CCC", true); len(got) != 1 || got[0] != malice {
+		t.Errorf("GUID search = %v, want [%d]", got, malice)
+	}
+
+	// Anonymized names can't be found, and leave the index.
+	if err := s.AnonymizePlayer(ctx, bob, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, s, "sarge", false); len(got) != 0 {
+		t.Errorf("anonymized player found by old name: %v", got)
+	}
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM search_names_fts WHERE search_names_fts MATCH '"Sarge"'`).Scan(&n)
+	if n != 0 {
+		t.Errorf("Sarge still indexed %d times", n)
+	}
+}
+
+func TestSearchNamesBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pg, err := s.UpsertPlayerGUID(context.Background(), "AAAA", "Alice", "Alice", time.Now(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As a database from before player search would be.
+	if _, err := s.db.Exec(`DELETE FROM search_names`); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := searchIDs(t, s, "alice", false); len(got) != 1 || got[0] != pg.PlayerID {
+		t.Errorf("after reopening, search = %v", got)
+	}
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM search_names`).Scan(&count); err != nil && err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("search_names has %d names, want 1", count)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"alice", "alice", 0},
+		{"alcie", "alice", 1},
+		{"alice", "alce", 1},
+		{"alice", "malice", 1},
+		{"kitten", "sitting", 3},
+	} {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+var (
+	ErrSeasonOverlap   = errors.New("season overlaps another season")
+	ErrSeasonFinalized = errors.New("season has ended and its standings are final")
+)
+
+// seasonStandingsSize is how many places a finalized season keeps.
+const seasonStandingsSize = 100
+
+// seasonColumns selects a domain.Season for scanSeason.
+const seasonColumns = `se.id, se.name, se.starts_at, se.ends_at, se.category, se.finalized_at,
+	(SELECT ss.player_id FROM season_standings ss WHERE ss.season_id = se.id AND ss.rank = 1),
+	se.created_by, se.created_at`
+
+func scanSeason(s scanner) (domain.Season, error) {
+	var se domain.Season
+	var finalizedAt sql.NullTime
+	var winnerID, createdBy sql.NullInt64
+	if err := s.Scan(&se.ID, &se.Name, &se.StartsAt, &se.EndsAt, &se.Category, &finalizedAt,
+		&winnerID, &createdBy, &se.CreatedAt); err != nil {
+		return se, err
+	}
+	se.FinalizedAt = scanNullTime(finalizedAt)
+	se.WinnerID = scanNullInt64Ptr(winnerID)
+	se.CreatedBy = scanNullInt64Ptr(createdBy)
+	return se, nil
+}
+
+// seasonOverlaps reports whether [startsAt, endsAt) overlaps a season
+// other than id.
+func seasonOverlaps(ctx context.Context, db querier, id int64, startsAt, endsAt time.Time) (bool, error) {
+	var overlaps bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM seasons WHERE id != ? AND starts_at < ? AND ends_at > ?)
+	`, id, formatTimestamp(endsAt), formatTimestamp(startsAt)).Scan(&overlaps)
+	return overlaps, err
+}
+
+// CreateSeason inserts se, filling in se.ID and se.CreatedAt. Fails
+// with ErrSeasonOverlap if it overlaps another season.
+func (s *Store) CreateSeason(ctx context.Context, se *domain.Season) error {
+	if se.CreatedAt.IsZero() {
+		se.CreatedAt = time.Now().UTC()
+	}
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if overlaps, err := seasonOverlaps(ctx, tx, 0, se.StartsAt, se.EndsAt); err != nil {
+		return err
+	} else if overlaps {
+		return ErrSeasonOverlap
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO seasons (name, starts_at, ends_at, category, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, se.Name, formatTimestamp(se.StartsAt), formatTimestamp(se.EndsAt), se.Category,
+		se.CreatedBy, formatTimestamp(se.CreatedAt))
+	if err != nil {
+		return err
+	}
+	se.ID, _ = res.LastInsertId()
+	return tx.Commit()
+}
+
+// UpdateSeason changes a season's name, dates and category. Fails with
+// ErrSeasonFinalized once its standings are frozen, ErrSeasonOverlap if
+// the new dates overlap another season, and sql.ErrNoRows if it
+// doesn't exist.
+func (s *Store) UpdateSeason(ctx context.Context, se *domain.Season) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var finalizedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx, `SELECT finalized_at FROM seasons WHERE id = ?`, se.ID).Scan(&finalizedAt); err != nil {
+		return err
+	}
+	if finalizedAt.Valid {
+		return ErrSeasonFinalized
+	}
+	if overlaps, err := seasonOverlaps(ctx, tx, se.ID, se.StartsAt, se.EndsAt); err != nil {
+		return err
+	} else if overlaps {
+		return ErrSeasonOverlap
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE seasons SET name = ?, starts_at = ?, ends_at = ?, category = ? WHERE id = ?
+	`, se.Name, formatTimestamp(se.StartsAt), formatTimestamp(se.EndsAt), se.Category, se.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteSeason removes the season and its standings. Returns
+// sql.ErrNoRows if it doesn't exist.
+func (s *Store) DeleteSeason(ctx context.Context, id int64) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM seasons WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetSeason returns the season, or nil if it doesn't exist.
+func (s *Store) GetSeason(ctx context.Context, id int64) (*domain.Season, error) {
+	se, err := scanSeason(s.conn(ctx).QueryRowContext(ctx, `SELECT `+seasonColumns+` FROM seasons se WHERE se.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &se, nil
+}
+
+// CurrentSeason returns the season running at at, or nil if none is.
+func (s *Store) CurrentSeason(ctx context.Context, at time.Time) (*domain.Season, error) {
+	se, err := scanSeason(s.conn(ctx).QueryRowContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons se
+		WHERE se.starts_at <= ? AND se.ends_at > ?
+		ORDER BY se.starts_at DESC LIMIT 1
+	`, formatTimestamp(at), formatTimestamp(at)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &se, nil
+}
+
+// ListSeasons returns every season, latest first.
+func (s *Store) ListSeasons(ctx context.Context) ([]domain.Season, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons se ORDER BY se.starts_at DESC, se.id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.Season{}
+	for rows.Next() {
+		se, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, se)
+	}
+	return out, rows.Err()
+}
+
+// GetSeasonLeaderboard ranks players over the season's matches. A
+// finalized season's own category with no game type is read from its
+// frozen standings; anything else is ranked live over the season's
+// dates, up to now while it is running. Results are never cached.
+func (s *Store) GetSeasonLeaderboard(ctx context.Context, se *domain.Season, category string, limit int, gameType string, now time.Time) (*domain.LeaderboardResponse, error) {
+	start, end := se.StartsAt, se.EndsAt
+	if now.Before(end) {
+		end = now
+	}
+	if se.FinalizedAt != nil && category == se.Category && gameType == "" {
+		entries, err := s.seasonStandings(ctx, se.ID, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LeaderboardResponse{
+			Category: category, Period: "season", PeriodStart: &start, PeriodEnd: &end,
+			Season: se, Entries: entries,
+		}, nil
+	}
+	resp, err := s.leaderboardBetween(ctx, s.LeaderboardRules(), category, "season", start, end, limit, gameType)
+	if err != nil {
+		return nil, err
+	}
+	resp.Period = "season"
+	resp.PeriodStart, resp.PeriodEnd = &start, &end
+	resp.Season = se
+	return resp, nil
+}
+
+// GetSeasonDetails returns the season with its standings, or nil if it
+// doesn't exist.
+func (s *Store) GetSeasonDetails(ctx context.Context, id int64, now time.Time) (*domain.SeasonResponse, error) {
+	se, err := s.GetSeason(ctx, id)
+	if err != nil || se == nil {
+		return nil, err
+	}
+	board, err := s.GetSeasonLeaderboard(ctx, se, se.Category, seasonStandingsSize, "", now)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.SeasonResponse{Season: *se, Standings: board.Entries}, nil
+}
+
+// seasonStandings reads up to limit places of a finalized season.
+// Players are as they are now, so renames and anonymization show.
+func (s *Store) seasonStandings(ctx context.Context, seasonID int64, limit int) ([]domain.LeaderboardEntry, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT ss.rank, ss.entry, p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END, COALESCE(u.is_admin, 0)
+		FROM season_standings ss
+		JOIN players p ON p.id = ss.player_id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE ss.season_id = ?
+		ORDER BY ss.rank
+		LIMIT ?
+	`, seasonID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.LeaderboardEntry{}
+	for rows.Next() {
+		var rank int
+		var entry string
+		var p domain.Player
+		if err := rows.Scan(&rank, &entry, &p.ID, &p.Name, &p.CleanName, &p.FirstSeen, &p.LastSeen,
+			&p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		var e domain.LeaderboardEntry
+		if err := json.Unmarshal([]byte(entry), &e); err != nil {
+			return nil, fmt.Errorf("season %d rank %d: %w", seasonID, rank, err)
+		}
+		e.Rank, e.Player = rank, p
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	players := make([]*domain.Player, len(entries))
+	for i := range entries {
+		players[i] = &entries[i].Player
+	}
+	return entries, s.attachProfiles(ctx, players...)
+}
+
+// FinalizeEndedSeasons freezes the standings of every season that has
+// ended by now and isn't finalized yet, under the leaderboard rules in
+// effect. Returns the seasons it finalized.
+func (s *Store) FinalizeEndedSeasons(ctx context.Context, now time.Time) ([]domain.Season, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons se
+		WHERE se.finalized_at IS NULL AND se.ends_at <= ?
+		ORDER BY se.ends_at
+	`, formatTimestamp(now))
+	if err != nil {
+		return nil, err
+	}
+	var ended []domain.Season
+	for rows.Next() {
+		se, err := scanSeason(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ended = append(ended, se)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var finalized []domain.Season
+	for _, se := range ended {
+		board, err := s.leaderboardBetween(ctx, s.LeaderboardRules(), se.Category, "season", se.StartsAt, se.EndsAt, seasonStandingsSize, "")
+		if err != nil {
+			return finalized, fmt.Errorf("season %d: %w", se.ID, err)
+		}
+		if err := s.freezeSeason(ctx, &se, board.Entries, now); err != nil {
+			return finalized, fmt.Errorf("season %d: %w", se.ID, err)
+		}
+		finalized = append(finalized, se)
+	}
+	return finalized, nil
+}
+
+// freezeSeason stores entries as the season's standings and marks it
+// finalized at now.
+func (s *Store) freezeSeason(ctx context.Context, se *domain.Season, entries []domain.LeaderboardEntry, now time.Time) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM season_standings WHERE season_id = ?`, se.ID); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		playerID := e.Player.ID
+		e.Rank, e.Player = 0, domain.Player{}
+		entry, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO season_standings (season_id, rank, player_id, entry) VALUES (?, ?, ?, ?)
+		`, se.ID, i+1, playerID, string(entry))
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE seasons SET finalized_at = ? WHERE id = ?`, formatTimestamp(now), se.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	se.FinalizedAt = &now
+	if len(entries) > 0 {
+		se.WinnerID = &entries[0].Player.ID
+	}
+	return nil
+}
+
+// attachSeasonWins fills in the seasons p finished first in.
+func (s *Store) attachSeasonWins(ctx context.Context, p *domain.Player) error {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT se.id, se.name, se.category, se.ends_at
+		FROM season_standings ss
+		JOIN seasons se ON se.id = ss.season_id
+		WHERE ss.player_id = ? AND ss.rank = 1
+		ORDER BY se.ends_at
+	`, p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w domain.SeasonWin
+		if err := rows.Scan(&w.SeasonID, &w.Name, &w.Category, &w.EndsAt); err != nil {
+			return err
+		}
+		p.SeasonWins = append(p.SeasonWins, w)
+	}
+	return rows.Err()
+}
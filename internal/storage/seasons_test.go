@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestSeasonStandingsFreezeAtEnd(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s.SetLeaderboardRules(LeaderboardRules{MinMatches: 1})
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	n := 0
+	play := func(at time.Time, winner, loser *domain.PlayerGUID) {
+		t.Helper()
+		n++
+		m := &domain.Match{UUID: "m" + strconv.Itoa(n), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: at}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for c, pg := range []*domain.PlayerGUID{winner, loser} {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, c, 20-c*10, 5, true, nil, nil, "", 0, c == 0,
+				0, 0, 0, 0, 0, 0, 0, false, false, at, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, at.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	season := &domain.Season{Name: "Spring", StartsAt: base, EndsAt: base.AddDate(0, 1, 0), Category: "frags"}
+	if err := s.CreateSeason(ctx, season); err != nil {
+		t.Fatalf("CreateSeason: %v", err)
+	}
+	overlapping := &domain.Season{Name: "Late spring", StartsAt: base.AddDate(0, 0, 20), EndsAt: base.AddDate(0, 2, 0), Category: "frags"}
+	if err := s.CreateSeason(ctx, overlapping); err != ErrSeasonOverlap {
+		t.Errorf("overlapping season: got %v", err)
+	}
+
+	// Alice wins before the season, bob wins twice during it.
+	play(base.Add(-time.Hour), alice, bob)
+	play(base.Add(time.Hour), bob, alice)
+	play(base.Add(2*time.Hour), bob, alice)
+
+	if got, err := s.CurrentSeason(ctx, base.AddDate(0, 0, 3)); err != nil || got == nil || got.ID != season.ID {
+		t.Fatalf("CurrentSeason: got %+v, %v", got, err)
+	}
+	if done, err := s.FinalizeEndedSeasons(ctx, base.AddDate(0, 0, 3)); err != nil || len(done) != 0 {
+		t.Fatalf("finalizing mid-season: got %v, %v", done, err)
+	}
+	end := season.EndsAt
+	done, err := s.FinalizeEndedSeasons(ctx, end)
+	if err != nil || len(done) != 1 || done[0].WinnerID == nil || *done[0].WinnerID != bob.PlayerID {
+		t.Fatalf("FinalizeEndedSeasons: got %+v, %v", done, err)
+	}
+	if err := s.UpdateSeason(ctx, season); err != ErrSeasonFinalized {
+		t.Errorf("changing a finalized season: got %v", err)
+	}
+
+	// A game that arrives late doesn't move the frozen standings.
+	play(base.Add(3*time.Hour), alice, bob)
+	season, err = s.GetSeason(ctx, season.ID)
+	if err != nil {
+		t.Fatalf("GetSeason: %v", err)
+	}
+	resp, err := s.GetSeasonLeaderboard(ctx, season, "frags", 10, "", end.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetSeasonLeaderboard: %v", err)
+	}
+	if len(resp.Entries) != 2 || resp.Entries[0].Player.ID != bob.PlayerID || resp.Entries[0].TotalFrags != 40 {
+		t.Errorf("frozen standings: got %+v", resp.Entries)
+	}
+
+	p, err := s.GetPlayerByID(ctx, bob.PlayerID)
+	if err != nil {
+		t.Fatalf("GetPlayerByID: %v", err)
+	}
+	if len(p.SeasonWins) != 1 || p.SeasonWins[0].Name != "Spring" {
+		t.Errorf("season wins: got %+v", p.SeasonWins)
+	}
+
+	// The win follows bob into a merge.
+	if err := s.MergePlayers(ctx, alice.PlayerID, bob.PlayerID); err != nil {
+		t.Fatalf("MergePlayers: %v", err)
+	}
+	if p, err = s.GetPlayerByID(ctx, alice.PlayerID); err != nil || len(p.SeasonWins) != 1 {
+		t.Errorf("merged season wins: got %+v, %v", p, err)
+	}
+}
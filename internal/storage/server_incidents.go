@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordServerIncident stores a game server crash.
+func (s *Store) RecordServerIncident(ctx context.Context, inc domain.ServerIncident) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO server_incidents (server_id, reason, detail, occurred_at, log_lines, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, inc.ServerID, inc.Reason, inc.Detail, formatTimestamp(inc.OccurredAt), strings.Join(inc.LogLines, "\n"), formatTimestamp(time.Now()))
+	return err
+}
+
+// ListServerIncidents returns up to limit of a server's incidents,
+// newest first.
+func (s *Store) ListServerIncidents(ctx context.Context, serverID int64, limit int) ([]domain.ServerIncident, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, server_id, reason, detail, occurred_at, log_lines, created_at
+		FROM server_incidents
+		WHERE server_id = ?
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT ?
+	`, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.ServerIncident{}
+	for rows.Next() {
+		var inc domain.ServerIncident
+		var lines string
+		if err := rows.Scan(&inc.ID, &inc.ServerID, &inc.Reason, &inc.Detail, &inc.OccurredAt, &lines, &inc.CreatedAt); err != nil {
+			return nil, err
+		}
+		inc.LogLines = []string{}
+		if lines != "" {
+			inc.LogLines = strings.Split(lines, "\n")
+		}
+		out = append(out, inc)
+	}
+	return out, rows.Err()
+}
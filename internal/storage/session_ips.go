@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// sessionIPScrubBatch is how many sessions ScrubSessionIPs reads at a
+// time, so a first run over years of sessions doesn't load them all.
+const sessionIPScrubBatch = 500
+
+// ScrubSessionIPs replaces the IP of every session that joined before
+// cutoff with scrub(ip), and marks the session so later runs skip it.
+// Returns how many sessions were scrubbed.
+func (s *Store) ScrubSessionIPs(ctx context.Context, cutoff, at time.Time, scrub func(ip string) string) (int64, error) {
+	var total int64
+	for {
+		rows, err := s.conn(ctx).QueryContext(ctx, `
+			SELECT id, ip_address FROM sessions
+			WHERE joined_at < ? AND ip_scrubbed_at IS NULL AND ip_address != ''
+			ORDER BY id
+			LIMIT ?
+		`, formatTimestamp(cutoff), sessionIPScrubBatch)
+		if err != nil {
+			return total, err
+		}
+		type sessionIP struct {
+			id int64
+			ip string
+		}
+		var batch []sessionIP
+		for rows.Next() {
+			var r sessionIP
+			if err := rows.Scan(&r.id, &r.ip); err != nil {
+				rows.Close()
+				return total, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return total, err
+		}
+		for _, r := range batch {
+			if _, err := tx.ExecContext(ctx, `UPDATE sessions SET ip_address = ?, ip_scrubbed_at = ? WHERE id = ?`,
+				scrub(r.ip), formatTimestamp(at), r.id); err != nil {
+				tx.Rollback()
+				return total, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return total, err
+		}
+		total += int64(len(batch))
+		if len(batch) < sessionIPScrubBatch {
+			return total, nil
+		}
+	}
+}
+
+// ClearSessionIPs blanks every stored session IP, scrubbed or not.
+// Returns how many sessions had one.
+func (s *Store) ClearSessionIPs(ctx context.Context, at time.Time) (int64, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE sessions SET ip_address = '', ip_scrubbed_at = COALESCE(ip_scrubbed_at, ?)
+		WHERE ip_address != ''
+	`, formatTimestamp(at))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// retentionWindow is how soon a player has to come back to count as
+// retained.
+const retentionWindow = 7 * 24 * time.Hour
+
+// GetSessionStats summarizes humans' finished sessions that began in
+// the period: how many and how long, unique players overall and per
+// UTC day, and how many first-time-in-period players came back within
+// a week.
+func (s *Store) GetSessionStats(ctx context.Context, period string, asOf time.Time) (*domain.SessionStatsResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+	if period == "all" {
+		end = asOf
+		if end.IsZero() {
+			end = time.Now()
+		}
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT pg.player_id, s.joined_at, s.left_at
+		FROM sessions s
+		JOIN player_guids pg ON pg.id = s.player_guid_id
+		JOIN players p ON p.id = pg.player_id
+		WHERE s.left_at IS NOT NULL AND p.is_bot = FALSE
+		  AND s.joined_at >= ? AND s.joined_at < ?
+		ORDER BY s.joined_at
+	`, formatTimestamp(start), formatTimestamp(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.SessionStatsResponse{
+		Period: period,
+		Daily:  []domain.DailyPlayers{},
+	}
+	first := map[int64]time.Time{}
+	returned := map[int64]bool{}
+	dayPlayers := map[string]map[int64]bool{}
+	for rows.Next() {
+		var playerID int64
+		var joinedAt, leftAt time.Time
+		if err := rows.Scan(&playerID, &joinedAt, &leftAt); err != nil {
+			return nil, err
+		}
+		resp.Sessions++
+		resp.TotalSeconds += int64(leftAt.Sub(joinedAt).Seconds())
+
+		day := joinedAt.UTC().Format(time.DateOnly)
+		if n := len(resp.Daily); n == 0 || resp.Daily[n-1].Day != day {
+			resp.Daily = append(resp.Daily, domain.DailyPlayers{Day: day})
+			dayPlayers[day] = map[int64]bool{}
+		}
+		resp.Daily[len(resp.Daily)-1].Sessions++
+		dayPlayers[day][playerID] = true
+
+		// Rows come in join order, so the first one seen is the first session
+		f, ok := first[playerID]
+		if !ok {
+			first[playerID] = joinedAt
+			continue
+		}
+		if joinedAt.UTC().Format(time.DateOnly) != f.UTC().Format(time.DateOnly) &&
+			joinedAt.Sub(f) <= retentionWindow {
+			returned[playerID] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range resp.Daily {
+		resp.Daily[i].Players = int64(len(dayPlayers[resp.Daily[i].Day]))
+	}
+	resp.UniquePlayers = int64(len(first))
+	if resp.Sessions > 0 {
+		resp.AverageSeconds = float64(resp.TotalSeconds) / float64(resp.Sessions)
+	}
+
+	// Only players who have had a full week to come back
+	cutoff := end.Add(-retentionWindow)
+	for playerID, f := range first {
+		if f.After(cutoff) {
+			continue
+		}
+		resp.RetentionCohort++
+		if returned[playerID] {
+			resp.RetentionReturned++
+		}
+	}
+	if resp.RetentionCohort > 0 {
+		rate := float64(resp.RetentionReturned) / float64(resp.RetentionCohort)
+		resp.RetentionRate = &rate
+	}
+
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	return resp, nil
+}
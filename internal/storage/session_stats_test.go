@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestSessionStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	asOf := time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)
+	day := func(d, h int) time.Time { return time.Date(2026, 5, d, h, 0, 0, 0, time.UTC) }
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	var guids []int64
+	for _, name := range []string{"alice", "bob", "carol"} {
+		pg, err := s.UpsertPlayerGUID(ctx, name, name, name, day(1, 0), false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		guids = append(guids, pg.ID)
+	}
+	bot, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", day(1, 0))
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+	alice, bob, carol := guids[0], guids[1], guids[2]
+	// Alice comes back two days later, and again the same day as bob;
+	// bob plays twice in one day; carol starts too late to be judged.
+	for _, sess := range []struct {
+		pg       int64
+		from, to time.Time
+	}{
+		{alice, day(3, 20), day(3, 21)},
+		{alice, day(5, 20), day(5, 22)},
+		{bob, day(10, 18), day(10, 19)},
+		{bob, day(10, 21), day(10, 22)},
+		{carol, day(28, 20), day(28, 21)},
+		{bot.ID, day(3, 20), day(3, 23)},
+	} {
+		ds := &domain.Session{PlayerGUIDID: sess.pg, ServerID: srv.ID, JoinedAt: sess.from}
+		if err := s.CreateSession(ctx, ds); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if err := s.EndSession(ctx, ds.ID, sess.to); err != nil {
+			t.Fatalf("EndSession: %v", err)
+		}
+	}
+
+	resp, err := s.GetSessionStats(ctx, "month", asOf)
+	if err != nil {
+		t.Fatalf("GetSessionStats: %v", err)
+	}
+	if resp.Sessions != 5 || resp.UniquePlayers != 3 || resp.TotalSeconds != 6*3600 {
+		t.Errorf("totals: got %d sessions, %d players, %ds", resp.Sessions, resp.UniquePlayers, resp.TotalSeconds)
+	}
+	if resp.AverageSeconds != 6*3600/5.0 {
+		t.Errorf("average: got %v", resp.AverageSeconds)
+	}
+	if len(resp.Daily) != 4 || resp.Daily[2] != (domain.DailyPlayers{Day: "2026-05-10", Players: 1, Sessions: 2}) {
+		t.Errorf("daily: got %+v", resp.Daily)
+	}
+	if resp.RetentionCohort != 2 || resp.RetentionReturned != 1 || resp.RetentionRate == nil || *resp.RetentionRate != 0.5 {
+		t.Errorf("retention: got %d of %d, rate %v", resp.RetentionReturned, resp.RetentionCohort, resp.RetentionRate)
+	}
+
+	resp, err = s.GetSessionStats(ctx, "day", asOf)
+	if err != nil {
+		t.Fatalf("GetSessionStats: %v", err)
+	}
+	if resp.Sessions != 0 || resp.RetentionRate != nil || resp.Daily == nil {
+		t.Errorf("empty day: got %+v", resp)
+	}
+}
+
+func TestPlaytimeLeaderboard(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+	asOf := base.Add(24 * time.Hour)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	// Alice has more frags but bob has been on longer, some of it a
+	// month ago.
+	for c, sess := range []struct {
+		name  string
+		frags int
+		from  time.Time
+		hours int
+	}{
+		{"alice", 20, base, 1},
+		{"bob", 5, base, 2},
+		{"bob", 5, base.Add(-30 * 24 * time.Hour), 3},
+	} {
+		pg, err := s.UpsertPlayerGUID(ctx, sess.name, sess.name, sess.name, base, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		ds := &domain.Session{PlayerGUIDID: pg.ID, ServerID: srv.ID, JoinedAt: sess.from}
+		if err := s.CreateSession(ctx, ds); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if err := s.EndSession(ctx, ds.ID, sess.from.Add(time.Duration(sess.hours)*time.Hour)); err != nil {
+			t.Fatalf("EndSession: %v", err)
+		}
+		if c < 2 {
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, c, sess.frags, 5, true, nil, nil, "", 0, false,
+				0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+	}
+	if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	// duration_seconds can round a second short
+	rules := LeaderboardRules{MinMatches: 1}
+	for period, want := range map[string]int64{"week": 2 * 3600, "all": 5 * 3600} {
+		resp, err := s.GetLeaderboardWithRules(ctx, rules, "playtime", period, 10, "", asOf)
+		if err != nil {
+			t.Fatalf("GetLeaderboardWithRules: %v", err)
+		}
+		if len(resp.Entries) != 2 || resp.Entries[0].Player.Name != "bob" || want-resp.Entries[0].PlaytimeSeconds > 1 {
+			t.Errorf("%s: got %+v", period, resp.Entries)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// snapshotDayFormat is the stats_snapshots.day layout.
+const snapshotDayFormat = "2006-01-02"
+
+// snapshotSelect aggregates finished matches per player per UTC day.
+// Callers append the WHERE clause's remaining conditions.
+const snapshotSelect = `
+	SELECT pg.player_id, date(m.started_at) AS day,
+		COUNT(DISTINCT mps.match_id),
+		COUNT(DISTINCT CASE WHEN mps.completed = 1 THEN mps.match_id END),
+		COALESCE(SUM(mps.frags), 0),
+		COALESCE(SUM(mps.deaths), 0),
+		COALESCE(SUM(mps.captures), 0),
+		COALESCE(SUM(mps.victories), 0)
+	FROM match_player_stats mps
+	JOIN player_guids pg ON pg.id = mps.player_guid_id
+	JOIN matches m ON m.id = mps.match_id
+	WHERE m.ended_at IS NOT NULL AND m.started_at IS NOT NULL`
+
+// SnapshotStats rewrites stats_snapshots for every UTC day in
+// [from, to). Days are replaced wholesale, so re-running over a
+// window picks up late-arriving matches. Returns the rows written.
+func (s *Store) SnapshotStats(ctx context.Context, from, to time.Time) (int64, error) {
+	fromDay := from.UTC().Format(snapshotDayFormat)
+	toDay := to.UTC().Format(snapshotDayFormat)
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stats_snapshots WHERE day >= ? AND day < ?`, fromDay, toDay); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO stats_snapshots (player_id, day, matches, completed_matches, frags, deaths, captures, victories)
+	`+snapshotSelect+`
+		AND date(m.started_at) >= ? AND date(m.started_at) < ?
+		GROUP BY pg.player_id, day
+	`, fromDay, toDay)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// rebuildPlayerSnapshots recomputes one player's whole snapshot
+// history. Called after merges and splits move GUIDs, and with them
+// match rows, between players.
+func (s *Store) rebuildPlayerSnapshots(ctx context.Context, playerID int64) error {
+	if _, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM stats_snapshots WHERE player_id = ?`, playerID); err != nil {
+		return err
+	}
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO stats_snapshots (player_id, day, matches, completed_matches, frags, deaths, captures, victories)
+	`+snapshotSelect+`
+		AND pg.player_id = ?
+		GROUP BY pg.player_id, day
+	`, playerID)
+	return err
+}
+
+// LatestSnapshotDay returns the most recent day with snapshot rows, or
+// the zero time if the table is empty.
+func (s *Store) LatestSnapshotDay(ctx context.Context) (time.Time, error) {
+	var day sql.NullString
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT MAX(day) FROM stats_snapshots`).Scan(&day); err != nil {
+		return time.Time{}, err
+	}
+	if !day.Valid || day.String == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(snapshotDayFormat, day.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing snapshot day %q: %w", day.String, err)
+	}
+	return t, nil
+}
+
+// EarliestMatchDay returns the UTC day of the first finished match, or
+// the zero time if there are none. The snapshot job backfills from here
+// on its first run.
+func (s *Store) EarliestMatchDay(ctx context.Context) (time.Time, error) {
+	var day sql.NullString
+	if err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT date(MIN(started_at)) FROM matches WHERE ended_at IS NOT NULL
+	`).Scan(&day); err != nil {
+		return time.Time{}, err
+	}
+	if !day.Valid || day.String == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(snapshotDayFormat, day.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing match day %q: %w", day.String, err)
+	}
+	return t, nil
+}
+
+// GetPlayerTrends returns the player's snapshot series since the given
+// day, bucketed by "day" or "week" (weeks start Monday). Days with no
+// matches are omitted. Today's matches only appear once the snapshot
+// job has run.
+func (s *Store) GetPlayerTrends(ctx context.Context, playerID int64, interval string, since time.Time) (*domain.PlayerTrendsResponse, error) {
+	bucket := "day"
+	if interval == "week" {
+		// Forward to the week's Sunday ('weekday 0' leaves a Sunday
+		// alone), then back six days to its Monday.
+		bucket = "date(day, 'weekday 0', '-6 days')"
+	}
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT `+bucket+` AS bucket,
+			SUM(matches), SUM(completed_matches), SUM(frags), SUM(deaths), SUM(captures), SUM(victories)
+		FROM stats_snapshots
+		WHERE player_id = ? AND day >= ?
+		GROUP BY bucket
+		ORDER BY bucket
+	`, playerID, since.UTC().Format(snapshotDayFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.PlayerTrendsResponse{PlayerID: playerID, Interval: interval, Points: []domain.TrendPoint{}}
+	var totalFrags, totalDeaths int64
+	for rows.Next() {
+		var p domain.TrendPoint
+		if err := rows.Scan(&p.Start, &p.Matches, &p.CompletedMatches, &p.Frags, &p.Deaths, &p.Captures, &p.Victories); err != nil {
+			return nil, err
+		}
+		totalFrags += p.Frags
+		totalDeaths += p.Deaths
+		p.KDRatio = kdRatio(p.Frags, p.Deaths)
+		p.CumulativeKDRatio = kdRatio(totalFrags, totalDeaths)
+		resp.Points = append(resp.Points, p)
+	}
+	return resp, rows.Err()
+}
+
+// kdRatio matches the player stats convention: with no deaths the
+// ratio is just the frag count.
+func kdRatio(frags, deaths int64) float64 {
+	if deaths > 0 {
+		return float64(frags) / float64(deaths)
+	}
+	return float64(frags)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestSnapshotStatsAndTrends(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	// Monday 2026-05-04 through Tuesday 2026-05-12.
+	mon := time.Date(2026, 5, 4, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", mon, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	n := 0
+	play := func(started time.Time, frags, deaths int) {
+		t.Helper()
+		n++
+		m := &domain.Match{UUID: started.Format(time.RFC3339), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, frags, deaths, true, nil, nil, "", 0, false,
+			0, 0, 0, 0, 0, 0, 0, false, false, started, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		if err := s.EndMatch(ctx, m.ID, started.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+	play(mon, 10, 5)
+	play(mon.Add(time.Hour), 10, 5)
+	play(mon.AddDate(0, 0, 2), 6, 6)
+	play(mon.AddDate(0, 0, 8), 4, 0)
+
+	first, err := s.EarliestMatchDay(ctx)
+	if err != nil || !first.Equal(time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("EarliestMatchDay: got %v, %v", first, err)
+	}
+	if _, err := s.SnapshotStats(ctx, first, mon.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("SnapshotStats: %v", err)
+	}
+	// Re-running a window replaces rather than doubles.
+	if _, err := s.SnapshotStats(ctx, first, mon.AddDate(0, 0, 30)); err != nil {
+		t.Fatalf("SnapshotStats again: %v", err)
+	}
+
+	days, err := s.GetPlayerTrends(ctx, alice.PlayerID, "day", first)
+	if err != nil {
+		t.Fatalf("GetPlayerTrends day: %v", err)
+	}
+	if len(days.Points) != 3 {
+		t.Fatalf("day points: got %+v", days.Points)
+	}
+	if p := days.Points[0]; p.Start != "2026-05-04" || p.Matches != 2 || p.Frags != 20 || p.KDRatio != 2 {
+		t.Errorf("first day: got %+v", p)
+	}
+	if p := days.Points[1]; p.KDRatio != 1 || p.CumulativeKDRatio != 26.0/16.0 {
+		t.Errorf("second day: got %+v", p)
+	}
+
+	weeks, err := s.GetPlayerTrends(ctx, alice.PlayerID, "week", first)
+	if err != nil {
+		t.Fatalf("GetPlayerTrends week: %v", err)
+	}
+	if len(weeks.Points) != 2 || weeks.Points[0].Start != "2026-05-04" || weeks.Points[0].Matches != 3 ||
+		weeks.Points[1].Start != "2026-05-11" || weeks.Points[1].Frags != 4 {
+		t.Errorf("week points: got %+v", weeks.Points)
+	}
+}
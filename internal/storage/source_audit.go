@@ -28,7 +28,7 @@ func (s *Store) WriteSourceAudit(ctx context.Context, source string, actorUserID
 	if actorUserID != nil {
 		actor = sql.NullInt64{Int64: *actorUserID, Valid: true}
 	}
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO source_audit (source, actor_user_id, action, detail)
 		VALUES (?, ?, ?, ?)
 	`, source, actor, action, detail)
@@ -103,7 +103,7 @@ func (s *Store) ListAllAudit(ctx context.Context, filters AuditFilters) ([]Audit
 		LIMIT ?
 	`, where)
 
-	rows, err := s.db.QueryContext(ctx, q, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("storage.ListAllAudit: %w", err)
 	}
@@ -129,7 +129,7 @@ func (s *Store) ListSourceAudit(ctx context.Context, source string, limit int) (
 	if limit <= 0 {
 		limit = 50
 	}
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT id, source, actor_user_id, action, COALESCE(detail, ''), created_at
 		FROM source_audit
 		WHERE source = ?
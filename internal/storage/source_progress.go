@@ -22,7 +22,7 @@ type SourceProgress struct {
 func (s *Store) GetSourceProgress(ctx context.Context, source string) (SourceProgress, error) {
 	var sp SourceProgress
 	var tsStr string
-	err := s.db.QueryRowContext(ctx,
+	err := s.conn(ctx).QueryRowContext(ctx,
 		"SELECT consumed_seq, last_consumed_ts FROM source_progress WHERE source = ?",
 		source,
 	).Scan(&sp.ConsumedSeq, &tsStr)
@@ -47,7 +47,7 @@ func (s *Store) GetSourceProgress(ctx context.Context, source string) (SourcePro
 // envelope dedup check.
 func (s *Store) AdvanceSourceProgress(ctx context.Context, source string, seq uint64, ts time.Time) error {
 	tsStr := formatTimestamp(ts)
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO source_progress (source, consumed_seq, last_consumed_ts, updated_at)
 		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT (source) DO UPDATE SET
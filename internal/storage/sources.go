@@ -77,7 +77,7 @@ func (s *Store) CreateSource(ctx context.Context, source string, isRemote bool,
 	if ownerUserID != nil {
 		owner = sql.NullInt64{Int64: *ownerUserID, Valid: true}
 	}
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO sources (source, is_remote, owner_user_id)
 		VALUES (?, ?, ?)
 	`, source, remoteInt, owner)
@@ -94,7 +94,7 @@ func (s *Store) UpsertLocalSource(ctx context.Context, source string) error {
 	if err := ValidateSource(source); err != nil {
 		return fmt.Errorf("storage.UpsertLocalSource: %w", err)
 	}
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO sources (source, is_remote)
 		VALUES (?, 0)
 		ON CONFLICT (source) DO UPDATE SET is_remote = 0
@@ -113,7 +113,7 @@ func (s *Store) DeactivateSource(ctx context.Context, source string) error {
 	if source == "" {
 		return fmt.Errorf("storage.DeactivateSource: source is required")
 	}
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -137,7 +137,7 @@ func (s *Store) ReactivateSource(ctx context.Context, source string) error {
 	if source == "" {
 		return fmt.Errorf("storage.ReactivateSource: source is required")
 	}
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -157,7 +157,7 @@ func (s *Store) ReactivateSource(ctx context.Context, source string) error {
 // unknown or deactivated sources at the broker.
 func (s *Store) IsSourceApproved(ctx context.Context, source string) (bool, error) {
 	var dummy int
-	err := s.db.QueryRowContext(ctx,
+	err := s.conn(ctx).QueryRowContext(ctx,
 		"SELECT 1 FROM sources WHERE source = ? AND active = 1 LIMIT 1", source,
 	).Scan(&dummy)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -173,7 +173,7 @@ func (s *Store) IsSourceApproved(ctx context.Context, source string) (bool, erro
 // servers row. Returns the zero time if the column is NULL.
 func (s *Store) GetServerLastHeartbeat(ctx context.Context, serverID int64) (time.Time, error) {
 	var t sql.NullTime
-	err := s.db.QueryRowContext(ctx, "SELECT last_heartbeat_at FROM servers WHERE id = ?", serverID).Scan(&t)
+	err := s.conn(ctx).QueryRowContext(ctx, "SELECT last_heartbeat_at FROM servers WHERE id = ?", serverID).Scan(&t)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -189,7 +189,7 @@ func (s *Store) GetServerLastHeartbeat(ctx context.Context, serverID int64) (tim
 // demoBaseURL is authoritative (operator config is the source of
 // truth), so an empty value clears whatever was there.
 func (s *Store) TouchSourceHeartbeat(ctx context.Context, source string, at time.Time, version, demoBaseURL string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -214,7 +214,7 @@ func (s *Store) TouchSourceHeartbeat(ctx context.Context, source string, at time
 // servers row. Used by main.go to wire up a local collector's source
 // to the pre-existing local servers (hub+collector deployment).
 func (s *Store) TagLocalServerSource(ctx context.Context, serverID int64, source string, localID int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE servers SET source = ?, local_id = ? WHERE id = ?
 	`, source, localID, serverID)
 	if err != nil {
@@ -232,7 +232,7 @@ func (s *Store) TagLocalServerSource(ctx context.Context, serverID int64, source
 // matches.map_name is stored as the engine reports it.
 func (s *Store) FindSourcePublicURLForMap(ctx context.Context, mapName string) (string, error) {
 	var url string
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT s.demo_base_url
 		FROM matches m
 		JOIN servers sv ON sv.id = m.server_id
@@ -255,7 +255,7 @@ func (s *Store) FindSourcePublicURLForMap(ctx context.Context, mapName string) (
 // nil error if none found.
 func (s *Store) FindSourcePublicURLForDemo(ctx context.Context, uuid string) (string, error) {
 	var url string
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT s.demo_base_url
 		FROM matches m
 		JOIN servers sv ON sv.id = m.server_id
@@ -277,7 +277,7 @@ func (s *Store) FindSourcePublicURLForDemo(ctx context.Context, uuid string) (st
 // dispatch.
 func (s *Store) ResolveServerIDForSource(ctx context.Context, source string, localID int64) (int64, error) {
 	var id int64
-	err := s.db.QueryRowContext(ctx,
+	err := s.conn(ctx).QueryRowContext(ctx,
 		"SELECT id FROM servers WHERE source = ? AND local_id = ? LIMIT 1",
 		source, localID,
 	).Scan(&id)
@@ -302,6 +302,7 @@ type RemoteServer struct {
 	Address    string
 	IsRemote   bool
 	UserPubKey string
+	QueryOnly  bool
 }
 
 // ListPollableServers returns every servers row the hub poller should
@@ -310,17 +311,18 @@ type RemoteServer struct {
 // (the hub's local collector) and is_remote=1 rows (remote
 // collectors). Inactive rows and rows that haven't proved they enforce
 // the handshake are skipped — neither should show up in live status.
-// Ordered by id.
+// Query-only rows have no collector to prove the handshake, so they're
+// polled regardless. Ordered by id.
 func (s *Store) ListPollableServers(ctx context.Context) ([]RemoteServer, error) {
 	// is_remote and user_pubkey are joined from sources. Sources is
 	// the single source of truth for both — the directory gate and
 	// the poller use user_pubkey to identify which live NATS
 	// connection belongs to this source (no DNS, no filesystem).
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT s.id, s.source, s.key, s.address, src.is_remote, src.user_pubkey
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT s.id, s.source, s.key, s.address, src.is_remote, src.user_pubkey, s.query_only
 		FROM servers s
 		JOIN sources src ON src.source = s.source
-		WHERE s.active = 1 AND s.handshake_required = 1 AND s.address <> ''
+		WHERE s.active = 1 AND (s.handshake_required = 1 OR s.query_only = 1) AND s.address <> ''
 		ORDER BY s.id
 	`)
 	if err != nil {
@@ -330,7 +332,7 @@ func (s *Store) ListPollableServers(ctx context.Context) ([]RemoteServer, error)
 	var out []RemoteServer
 	for rows.Next() {
 		var r RemoteServer
-		if err := rows.Scan(&r.ID, &r.Source, &r.Key, &r.Address, &r.IsRemote, &r.UserPubKey); err != nil {
+		if err := rows.Scan(&r.ID, &r.Source, &r.Key, &r.Address, &r.IsRemote, &r.UserPubKey, &r.QueryOnly); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
@@ -345,7 +347,7 @@ func (s *Store) ListPollableServers(ctx context.Context) ([]RemoteServer, error)
 // intentionally not part of the filter here.
 func (s *Store) ListDirectoryGateEntries(ctx context.Context) ([]RemoteServer, error) {
 	// is_remote and user_pubkey come from sources (see ListPollableServers note).
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT s.id, s.source, s.key, s.address, src.is_remote, src.user_pubkey
 		FROM servers s
 		JOIN sources src ON src.source = s.source
@@ -370,7 +372,7 @@ func (s *Store) ListDirectoryGateEntries(ctx context.Context) ([]RemoteServer, e
 // ListRemoteServers returns every servers row whose source is remote.
 // Ordered by id.
 func (s *Store) ListRemoteServers(ctx context.Context) ([]RemoteServer, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT s.id, s.source, s.key, s.address
 		FROM servers s
 		JOIN sources src ON src.source = s.source
@@ -399,7 +401,7 @@ func (s *Store) ListRemoteServers(ctx context.Context) ([]RemoteServer, error) {
 // any DB internals.
 func (s *Store) GetSourceUserPubKey(ctx context.Context, source string) (string, error) {
 	var pub string
-	err := s.db.QueryRowContext(ctx,
+	err := s.conn(ctx).QueryRowContext(ctx,
 		`SELECT user_pubkey FROM sources WHERE source = ?`, source,
 	).Scan(&pub)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -416,7 +418,7 @@ func (s *Store) GetSourceUserPubKey(ctx context.Context, source string) (string,
 // Errors if source has no row in sources (mint should only happen for
 // pre-registered sources).
 func (s *Store) SetSourceUserPubKey(ctx context.Context, source, pubkey string) error {
-	res, err := s.db.ExecContext(ctx,
+	res, err := s.conn(ctx).ExecContext(ctx,
 		`UPDATE sources SET user_pubkey = ? WHERE source = ?`,
 		pubkey, source,
 	)
@@ -463,7 +465,7 @@ type ApprovedSourceServer struct {
 // registration yet shows up with an empty Servers slice. Ordered by
 // source for stable UI rendering.
 func (s *Store) ListApprovedSources(ctx context.Context) ([]ApprovedSource, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT
 			s.source,
 			s.version,
@@ -558,7 +560,7 @@ func (s *Store) TransferSourceOwner(ctx context.Context, source string, newOwner
 	if newOwnerUserID <= 0 {
 		return fmt.Errorf("storage.TransferSourceOwner: owner_user_id is required")
 	}
-	res, err := s.db.ExecContext(ctx, `
+	res, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sources
 		   SET owner_user_id = ?
 		 WHERE source = ? AND is_remote = 1
@@ -586,7 +588,7 @@ func (s *Store) TransferSourceOwner(ctx context.Context, source string, newOwner
 // wrote it to sources earlier in the same handler, so this keeps the
 // two rows in lockstep.
 func (s *Store) UpsertRemoteServers(ctx context.Context, reg domain.Registration) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -604,21 +606,25 @@ func (s *Store) UpsertRemoteServers(ctx context.Context, reg domain.Registration
 		if rs.AdminDelegationEnabled {
 			delegate = 1
 		}
+		queryOnly := 0
+		if rs.QueryOnly {
+			queryOnly = 1
+		}
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			if _, err := tx.ExecContext(ctx, `
-				INSERT INTO servers (key, address, source, local_id, active, last_heartbeat_at, demo_base_url, source_version, admin_delegation_enabled)
-				VALUES (?, ?, ?, ?, 1, CURRENT_TIMESTAMP, ?, ?, ?)
-			`, rs.Key, rs.Address, reg.Source, rs.LocalID, reg.DemoBaseURL, reg.Version, delegate); err != nil {
+				INSERT INTO servers (key, address, source, local_id, active, last_heartbeat_at, demo_base_url, source_version, admin_delegation_enabled, clock_offset_ms, query_only)
+				VALUES (?, ?, ?, ?, 1, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?)
+			`, rs.Key, rs.Address, reg.Source, rs.LocalID, reg.DemoBaseURL, reg.Version, delegate, rs.ClockOffsetMs, queryOnly); err != nil {
 				return fmt.Errorf("storage: insert remote server: %w", err)
 			}
 		case err != nil:
 			return err
 		default:
 			if _, err := tx.ExecContext(ctx, `
-				UPDATE servers SET key = ?, address = ?, active = 1, last_heartbeat_at = CURRENT_TIMESTAMP, demo_base_url = ?, source_version = ?, admin_delegation_enabled = ?
+				UPDATE servers SET key = ?, address = ?, active = 1, last_heartbeat_at = CURRENT_TIMESTAMP, demo_base_url = ?, source_version = ?, admin_delegation_enabled = ?, clock_offset_ms = ?, query_only = ?
 				WHERE id = ?
-			`, rs.Key, rs.Address, reg.DemoBaseURL, reg.Version, delegate, existingID); err != nil {
+			`, rs.Key, rs.Address, reg.DemoBaseURL, reg.Version, delegate, rs.ClockOffsetMs, queryOnly, existingID); err != nil {
 				return fmt.Errorf("storage: update remote server: %w", err)
 			}
 		}
@@ -634,7 +640,7 @@ func (s *Store) UpsertRemoteServers(ctx context.Context, reg domain.Registration
 // deactivateMissing flips active=0 on every active server tied to
 // `source` whose key isn't in `keep` (case-insensitive). Used inside
 // the UpsertRemoteServers transaction.
-func deactivateMissing(ctx context.Context, tx *sql.Tx, source string, keep []string) error {
+func deactivateMissing(ctx context.Context, tx txn, source string, keep []string) error {
 	if len(keep) == 0 {
 		_, err := tx.ExecContext(ctx,
 			"UPDATE servers SET active = 0 WHERE source = ? AND active = 1", source)
@@ -51,7 +51,7 @@ func (s *Store) CreateSourceRequest(ctx context.Context, a CreateSourceRequestAr
 	if a.OwnerUserID == 0 {
 		return fmt.Errorf("storage.CreateSourceRequest: owner_user_id is required")
 	}
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -177,7 +177,7 @@ const sourceSelectColumns = `
 // recency. Used by GET /api/sources/mine to drive the My Servers
 // drawer (which renders one card per source).
 func (s *Store) ListSourcesByOwner(ctx context.Context, ownerUserID int64) ([]Source, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT `+sourceSelectColumns+`
 		FROM sources
 		WHERE owner_user_id = ?
@@ -209,7 +209,7 @@ func (s *Store) ListSourcesByOwner(ctx context.Context, ownerUserID int64) ([]So
 // admin handlers (approve/reject) and by handlers that already know
 // the source token (rotate/leave) to look up status + ownership.
 func (s *Store) GetSourceByName(ctx context.Context, source string) (*Source, error) {
-	row := s.db.QueryRowContext(ctx, `
+	row := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT `+sourceSelectColumns+`
 		FROM sources WHERE source = ?
 	`, source)
@@ -227,7 +227,7 @@ func (s *Store) GetSourceByName(ctx context.Context, source string) (*Source, er
 // ErrSourceNotPending if the row doesn't exist or isn't pending — the
 // admin endpoint maps that to 404/409.
 func (s *Store) ApproveSource(ctx context.Context, source string) error {
-	res, err := s.db.ExecContext(ctx, `
+	res, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sources
 		SET status='active', active=1, status_changed_at=CURRENT_TIMESTAMP
 		WHERE source = ? AND status = 'pending'
@@ -247,7 +247,7 @@ func (s *Store) RejectSource(ctx context.Context, source, reason string) error {
 	if strings.TrimSpace(reason) == "" {
 		return errors.New("storage.RejectSource: reason is required")
 	}
-	res, err := s.db.ExecContext(ctx, `
+	res, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sources
 		SET status='rejected', active=0, rejection_reason=?,
 		    status_changed_at=CURRENT_TIMESTAMP
@@ -266,7 +266,7 @@ func (s *Store) RejectSource(ctx context.Context, source, reason string) error {
 // only (idempotent on already-left). Cascades server rows inactive
 // to mirror DeactivateSource's behavior.
 func (s *Store) LeaveSource(ctx context.Context, source string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -309,7 +309,7 @@ func (s *Store) RenamePendingSource(ctx context.Context, oldName, newName string
 	if oldName == newName {
 		return nil // no-op
 	}
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -379,7 +379,7 @@ func (s *Store) RenamePendingSource(ctx context.Context, oldName, newName string
 // The cascade to servers is handled by the existing DeactivateSource
 // call path; this only updates the status column for audit/UI clarity.
 func (s *Store) RevokeSourceStatus(ctx context.Context, source string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sources
 		SET status='revoked', status_changed_at=CURRENT_TIMESTAMP
 		WHERE source = ?
@@ -404,7 +404,7 @@ type PendingRequest struct {
 // users to expose the owner's username for the admin UI. Oldest-first
 // so admins triage in submission order.
 func (s *Store) ListPendingRequests(ctx context.Context) ([]PendingRequest, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT s.source, s.owner_user_id, u.username,
 		       COALESCE(s.requested_purpose, ''),
 		       s.status_changed_at
@@ -448,7 +448,7 @@ type SourceServer struct {
 // Empty slice if the source exists but has no registered servers yet
 // (e.g. just-approved, collector hasn't connected).
 func (s *Store) ListServersForSource(ctx context.Context, source string) ([]SourceServer, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT key, address, active
 		FROM servers
 		WHERE source = ?
@@ -478,7 +478,7 @@ func (s *Store) ListServersForSource(ctx context.Context, source string) ([]Sour
 // this map. Used by the live-server endpoint to compute manageable_by_me
 // without N+1 queries.
 func (s *Store) SourceOwners(ctx context.Context) (map[string]int64, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT source, owner_user_id FROM sources WHERE owner_user_id IS NOT NULL
 	`)
 	if err != nil {
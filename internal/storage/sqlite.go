@@ -26,6 +26,8 @@ var schema string
 // Store provides database access
 type Store struct {
 	db *sql.DB
+
+	lbCache leaderboardCache
 }
 
 // New creates a new Store with the given database path
@@ -53,6 +55,10 @@ func New(dbPath string) (*Store, error) {
 		db.Close()
 		return nil, fmt.Errorf("creating schema: %w", err)
 	}
+	if err := backfillSearchNames(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("indexing player names: %w", err)
+	}
 
 	return &Store{db: db}, nil
 }
@@ -62,8 +68,6 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-
-
 // --- Server methods ---
 
 // UpsertServer creates or updates a server scoped to source. Identity
@@ -74,7 +78,7 @@ func (s *Store) UpsertServer(ctx context.Context, source string, srv *domain.Ser
 	if source == "" {
 		return fmt.Errorf("storage.UpsertServer: source is required")
 	}
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO servers (key, address, source)
 		VALUES (?, ?, ?)
 		ON CONFLICT(source, key) DO UPDATE SET
@@ -85,7 +89,7 @@ func (s *Store) UpsertServer(ctx context.Context, source string, srv *domain.Ser
 	}
 
 	// Always query for the ID (LastInsertId unreliable with ON CONFLICT)
-	return s.db.QueryRowContext(ctx,
+	return s.conn(ctx).QueryRowContext(ctx,
 		"SELECT id FROM servers WHERE source = ? AND key = ? COLLATE NOCASE",
 		source, srv.Key,
 	).Scan(&srv.ID)
@@ -93,8 +97,8 @@ func (s *Store) UpsertServer(ctx context.Context, source string, srv *domain.Ser
 
 // GetServers returns all servers
 func (s *Store) GetServers(ctx context.Context) ([]domain.Server, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, source, key, address, active, handshake_required, last_match_uuid, last_match_ended_at, last_heartbeat_at, admin_delegation_enabled, created_at FROM servers ORDER BY id
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, source, key, address, active, handshake_required, last_match_uuid, last_match_ended_at, last_heartbeat_at, admin_delegation_enabled, commands_disabled, clock_offset_ms, query_only, created_at FROM servers ORDER BY id
 	`)
 	if err != nil {
 		return nil, err
@@ -107,7 +111,7 @@ func (s *Store) GetServers(ctx context.Context) ([]domain.Server, error) {
 		var lastMatchUUID sql.NullString
 		var lastMatchEndedAt sql.NullTime
 		var lastHeartbeatAt sql.NullTime
-		if err := rows.Scan(&srv.ID, &srv.Source, &srv.Key, &srv.Address, &srv.Active, &srv.HandshakeRequired, &lastMatchUUID, &lastMatchEndedAt, &lastHeartbeatAt, &srv.AdminDelegationEnabled, &srv.CreatedAt); err != nil {
+		if err := rows.Scan(&srv.ID, &srv.Source, &srv.Key, &srv.Address, &srv.Active, &srv.HandshakeRequired, &lastMatchUUID, &lastMatchEndedAt, &lastHeartbeatAt, &srv.AdminDelegationEnabled, &srv.CommandsDisabled, &srv.ClockOffsetMs, &srv.QueryOnly, &srv.CreatedAt); err != nil {
 			return nil, err
 		}
 		if lastMatchUUID.Valid {
@@ -130,9 +134,9 @@ func (s *Store) GetServerByID(ctx context.Context, id int64) (*domain.Server, er
 	var lastMatchUUID sql.NullString
 	var lastMatchEndedAt sql.NullTime
 	var lastHeartbeatAt sql.NullTime
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, source, key, address, active, handshake_required, last_match_uuid, last_match_ended_at, last_heartbeat_at, admin_delegation_enabled, created_at FROM servers WHERE id = ?
-	`, id).Scan(&srv.ID, &srv.Source, &srv.Key, &srv.Address, &srv.Active, &srv.HandshakeRequired, &lastMatchUUID, &lastMatchEndedAt, &lastHeartbeatAt, &srv.AdminDelegationEnabled, &srv.CreatedAt)
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT id, source, key, address, active, handshake_required, last_match_uuid, last_match_ended_at, last_heartbeat_at, admin_delegation_enabled, commands_disabled, clock_offset_ms, query_only, created_at FROM servers WHERE id = ?
+	`, id).Scan(&srv.ID, &srv.Source, &srv.Key, &srv.Address, &srv.Active, &srv.HandshakeRequired, &lastMatchUUID, &lastMatchEndedAt, &lastHeartbeatAt, &srv.AdminDelegationEnabled, &srv.CommandsDisabled, &srv.ClockOffsetMs, &srv.QueryOnly, &srv.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -157,10 +161,31 @@ func (s *Store) SetServerHandshakeRequired(ctx context.Context, serverID int64,
 	if required {
 		v = 1
 	}
-	_, err := s.db.ExecContext(ctx, `UPDATE servers SET handshake_required = ? WHERE id = ?`, v, serverID)
+	_, err := s.conn(ctx).ExecContext(ctx, `UPDATE servers SET handshake_required = ? WHERE id = ?`, v, serverID)
 	return err
 }
 
+// SetServerCommandsDisabled sets servers.commands_disabled for a single
+// row, returning sql.ErrNoRows if there's no such server.
+func (s *Store) SetServerCommandsDisabled(ctx context.Context, serverID int64, disabled bool) error {
+	v := 0
+	if disabled {
+		v = 1
+	}
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE servers SET commands_disabled = ? WHERE id = ?`, v, serverID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // --- Player GUID methods ---
 
 // UpsertPlayerGUID creates or updates a player GUID, creating a new player if needed
@@ -171,7 +196,7 @@ func (s *Store) UpsertPlayerGUID(ctx context.Context, guid, name, cleanName stri
 		now = time.Now().UTC()
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("starting transaction: %w", err)
 	}
@@ -214,6 +239,10 @@ func (s *Store) UpsertPlayerGUID(ctx context.Context, guid, name, cleanName stri
 		}
 	} else if err != nil {
 		return nil, err
+	} else if now.Before(pg.LastSeen) {
+		if err := seenEarlier(ctx, tx, &pg, now, isVR); err != nil {
+			return nil, err
+		}
 	} else {
 		// Sticky VR: once set to true, never reset to false
 		_, err = tx.ExecContext(ctx, `
@@ -240,8 +269,9 @@ func (s *Store) UpsertPlayerGUID(ctx context.Context, guid, name, cleanName stri
 		INSERT INTO player_names (player_guid_id, name, clean_name, first_seen, last_seen)
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(player_guid_id, clean_name) DO UPDATE SET
-			name = excluded.name,
-			last_seen = excluded.last_seen
+			name = CASE WHEN excluded.last_seen >= last_seen THEN excluded.name ELSE name END,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen)
 	`, pg.ID, name, cleanName, formatTimestamp(now), formatTimestamp(now))
 	if err != nil {
 		return nil, fmt.Errorf("recording player name: %w", err)
@@ -263,7 +293,7 @@ func (s *Store) UpsertBotPlayerGUID(ctx context.Context, name, cleanName string,
 		now = time.Now().UTC()
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("starting transaction: %w", err)
 	}
@@ -306,6 +336,10 @@ func (s *Store) UpsertBotPlayerGUID(ctx context.Context, name, cleanName string,
 		}
 	} else if err != nil {
 		return nil, err
+	} else if now.Before(pg.LastSeen) {
+		if err := seenEarlier(ctx, tx, &pg, now, false); err != nil {
+			return nil, err
+		}
 	} else {
 		_, err = tx.ExecContext(ctx, `
 			UPDATE player_guids SET name = ?, clean_name = ?, last_seen = ?
@@ -331,8 +365,9 @@ func (s *Store) UpsertBotPlayerGUID(ctx context.Context, name, cleanName string,
 		INSERT INTO player_names (player_guid_id, name, clean_name, first_seen, last_seen)
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(player_guid_id, clean_name) DO UPDATE SET
-			name = excluded.name,
-			last_seen = excluded.last_seen
+			name = CASE WHEN excluded.last_seen >= last_seen THEN excluded.name ELSE name END,
+			first_seen = MIN(first_seen, excluded.first_seen),
+			last_seen = MAX(last_seen, excluded.last_seen)
 	`, pg.ID, name, cleanName, formatTimestamp(now), formatTimestamp(now))
 	if err != nil {
 		return nil, fmt.Errorf("recording player name: %w", err)
@@ -348,7 +383,7 @@ func (s *Store) UpsertBotPlayerGUID(ctx context.Context, name, cleanName string,
 // GetPlayerGUIDByGUID finds a player_guid by GUID string
 func (s *Store) GetPlayerGUIDByGUID(ctx context.Context, guid string) (*domain.PlayerGUID, error) {
 	var pg domain.PlayerGUID
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, player_id, guid, name, clean_name, first_seen, last_seen
 		FROM player_guids WHERE guid = ?
 	`, guid).Scan(&pg.ID, &pg.PlayerID, &pg.GUID, &pg.Name, &pg.CleanName, &pg.FirstSeen, &pg.LastSeen)
@@ -360,7 +395,7 @@ func (s *Store) GetPlayerGUIDByGUID(ctx context.Context, guid string) (*domain.P
 
 // GetPlayerGUIDs returns all GUIDs for a player
 func (s *Store) GetPlayerGUIDs(ctx context.Context, playerID int64) ([]domain.PlayerGUID, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT id, player_id, guid, name, clean_name, first_seen, last_seen, is_vr
 		FROM player_guids WHERE player_id = ?
 		ORDER BY last_seen DESC
@@ -386,7 +421,7 @@ func (s *Store) GetPlayerGUIDs(ctx context.Context, playerID int64) ([]domain.Pl
 // GetPlayerByID finds a player by their ID (includes GUIDs)
 func (s *Store) GetPlayerByID(ctx context.Context, id int64) (*domain.Player, error) {
 	var p domain.Player
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT
 			p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
 			COALESCE((
@@ -397,11 +432,13 @@ func (s *Store) GetPlayerByID(ctx context.Context, id int64) (*domain.Player, er
 			), 0) as total_playtime_seconds,
 			p.is_bot, p.is_vr,
 			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
-			COALESCE(u.is_admin, 0) as is_admin
+			COALESCE(u.is_admin, 0) as is_admin,
+			COALESCE(p.exclude_from_leaderboards, 0),
+			COALESCE(u.is_vip, 0)
 		FROM players p
 		LEFT JOIN users u ON u.player_id = p.id
 		WHERE p.id = ?
-	`, id).Scan(&p.ID, &p.Name, &p.CleanName, &p.FirstSeen, &p.LastSeen, &p.TotalPlaytimeSeconds, &p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin)
+	`, id).Scan(&p.ID, &p.Name, &p.CleanName, &p.FirstSeen, &p.LastSeen, &p.TotalPlaytimeSeconds, &p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin, &p.LeaderboardExcluded, &p.IsVIP)
 	if err != nil {
 		return nil, err
 	}
@@ -409,7 +446,7 @@ func (s *Store) GetPlayerByID(ctx context.Context, id int64) (*domain.Player, er
 	// Get most recent model and skill from match_player_stats
 	var model sql.NullString
 	var skill sql.NullFloat64
-	_ = s.db.QueryRowContext(ctx, `
+	_ = s.conn(ctx).QueryRowContext(ctx, `
 		SELECT mps.model, mps.skill
 		FROM match_player_stats mps
 		JOIN player_guids pg ON mps.player_guid_id = pg.id
@@ -424,6 +461,12 @@ func (s *Store) GetPlayerByID(ctx context.Context, id int64) (*domain.Player, er
 	if skill.Valid {
 		p.Skill = skill.Float64
 	}
+	if err := s.attachProfiles(ctx, &p); err != nil {
+		return nil, err
+	}
+	if err := s.attachSeasonWins(ctx, &p); err != nil {
+		return nil, err
+	}
 
 	// Get all GUIDs for this player
 	guids, err := s.GetPlayerGUIDs(ctx, p.ID)
@@ -435,72 +478,6 @@ func (s *Store) GetPlayerByID(ctx context.Context, id int64) (*domain.Player, er
 	return &p, nil
 }
 
-// SearchPlayers searches for players by name (and optionally by GUID for admins)
-func (s *Store) SearchPlayers(ctx context.Context, query string, limit int, includeGUID bool) ([]domain.Player, error) {
-	if limit <= 0 {
-		limit = 20
-	}
-	searchPattern := "%" + query + "%"
-
-	var rows *sql.Rows
-	var err error
-
-	if includeGUID {
-		// Search by name OR by GUID (admin feature)
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT DISTINCT p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
-				COALESCE((
-					SELECT SUM(s.duration_seconds)
-					FROM sessions s
-					JOIN player_guids pg2 ON s.player_guid_id = pg2.id
-					WHERE pg2.player_id = p.id AND s.left_at IS NOT NULL
-				), 0) as total_playtime_seconds,
-				p.is_bot, p.is_vr,
-				CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
-				COALESCE(u.is_admin, 0) as is_admin
-			FROM players p
-			LEFT JOIN player_guids pg ON pg.player_id = p.id
-			LEFT JOIN users u ON u.player_id = p.id
-			WHERE p.clean_name LIKE ? OR p.name LIKE ? OR pg.guid LIKE ?
-			ORDER BY p.last_seen DESC
-			LIMIT ?
-		`, searchPattern, searchPattern, searchPattern, limit)
-	} else {
-		// Search by name only
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
-				COALESCE((
-					SELECT SUM(s.duration_seconds)
-					FROM sessions s
-					JOIN player_guids pg ON s.player_guid_id = pg.id
-					WHERE pg.player_id = p.id AND s.left_at IS NOT NULL
-				), 0) as total_playtime_seconds,
-				p.is_bot, p.is_vr,
-				CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
-				COALESCE(u.is_admin, 0) as is_admin
-			FROM players p
-			LEFT JOIN users u ON u.player_id = p.id
-			WHERE p.clean_name LIKE ? OR p.name LIKE ?
-			ORDER BY p.last_seen DESC
-			LIMIT ?
-		`, searchPattern, searchPattern, limit)
-	}
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var players []domain.Player
-	for rows.Next() {
-		var p domain.Player
-		if err := rows.Scan(&p.ID, &p.Name, &p.CleanName, &p.FirstSeen, &p.LastSeen, &p.TotalPlaytimeSeconds, &p.IsBot, &p.IsVR, &p.IsVerified, &p.IsAdmin); err != nil {
-			return nil, err
-		}
-		players = append(players, p)
-	}
-	return players, rows.Err()
-}
-
 // GetPlayers returns players with pagination support
 func (s *Store) GetPlayers(ctx context.Context, limit, offset int) ([]domain.Player, int, error) {
 	if limit <= 0 || limit > 100 {
@@ -511,11 +488,11 @@ func (s *Store) GetPlayers(ctx context.Context, limit, offset int) ([]domain.Pla
 	}
 
 	var total int
-	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM players`).Scan(&total); err != nil {
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM players`).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen,
 			COALESCE((
 				SELECT SUM(s.duration_seconds)
@@ -550,7 +527,7 @@ func (s *Store) GetPlayers(ctx context.Context, limit, offset int) ([]domain.Pla
 // RecordPlayerName records a name in the history for a player_guid
 func (s *Store) RecordPlayerName(ctx context.Context, playerGUIDID int64, name, cleanName string) error {
 	now := time.Now().UTC()
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO player_names (player_guid_id, name, clean_name, first_seen, last_seen)
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(player_guid_id, clean_name) DO UPDATE SET
@@ -562,7 +539,7 @@ func (s *Store) RecordPlayerName(ctx context.Context, playerGUIDID int64, name,
 
 // GetPlayerNames returns all known names for a player (across all GUIDs)
 func (s *Store) GetPlayerNames(ctx context.Context, playerID int64) ([]domain.PlayerName, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT pn.name, pn.clean_name, pn.first_seen, pn.last_seen
 		FROM player_names pn
 		JOIN player_guids pg ON pn.player_guid_id = pg.id
@@ -587,10 +564,24 @@ func (s *Store) GetPlayerNames(ctx context.Context, playerID int64) ([]domain.Pl
 
 // --- Player Merge/Link methods ---
 
-// MergePlayers moves all GUIDs from sourcePlayerID to targetPlayerID, then deletes source
+// MergePlayers moves all GUIDs from sourcePlayerID to targetPlayerID, then deletes source.
+// The whole merge is one transaction and is recorded in player_merges
+// so UndoPlayerMerge can reverse it. Returns sql.ErrNoRows if the source
+// player doesn't exist.
 func (s *Store) MergePlayers(ctx context.Context, targetPlayerID, sourcePlayerID int64) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Note what's about to change before changing it
+	if _, err := recordPlayerMerge(ctx, tx, targetPlayerID, sourcePlayerID, time.Now().UTC()); err != nil {
+		return err
+	}
+
 	// Move all GUIDs to target player
-	_, err := s.db.ExecContext(ctx, `
+	_, err = tx.ExecContext(ctx, `
 		UPDATE player_guids SET player_id = ? WHERE player_id = ?
 	`, targetPlayerID, sourcePlayerID)
 	if err != nil {
@@ -600,27 +591,111 @@ func (s *Store) MergePlayers(ctx context.Context, targetPlayerID, sourcePlayerID
 	// Update target player's first_seen, last_seen, and recompute is_vr
 	// Note: name/clean_name are NOT updated here - we preserve the target player's name
 	// The name will update naturally when any of the merged GUIDs become active again
-	_, err = s.db.ExecContext(ctx, `
+	if err := refreshPlayerFromGUIDs(ctx, tx, targetPlayerID); err != nil {
+		return err
+	}
+
+	// An exclusion follows the merged identity, so an admin can't lose
+	// a cheater's flag by merging their alt into the clean account
+	_, err = tx.ExecContext(ctx, `
 		UPDATE players SET
-			first_seen = (SELECT MIN(first_seen) FROM player_guids WHERE player_id = ?),
-			last_seen = (SELECT MAX(last_seen) FROM player_guids WHERE player_id = ?),
-			is_vr = EXISTS(SELECT 1 FROM player_guids WHERE player_id = ? AND is_vr = TRUE)
-		WHERE id = ?
-	`, targetPlayerID, targetPlayerID, targetPlayerID, targetPlayerID)
+			exclude_from_leaderboards = 1,
+			leaderboard_exclusion_reason = (SELECT leaderboard_exclusion_reason FROM players WHERE id = ?)
+		WHERE id = ? AND COALESCE(exclude_from_leaderboards, 0) = 0
+		  AND EXISTS(SELECT 1 FROM players WHERE id = ? AND exclude_from_leaderboards = 1)
+	`, sourcePlayerID, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+
+	// Carry badges over; the target keeps its own date for any both earned
+	_, err = tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO player_achievements (player_id, achievement, match_id, earned_at)
+		SELECT ?, achievement, match_id, earned_at FROM player_achievements WHERE player_id = ?
+	`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+
+	// Chat, moderation incidents, VIP kicks, and admin commands keep
+	// pointing at whoever was involved
+	_, err = tx.ExecContext(ctx, `UPDATE match_chat SET player_id = ? WHERE player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE match_chat SET to_player_id = ? WHERE to_player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE moderation_incidents SET player_id = ? WHERE player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE vip_kicks SET vip_player_id = ? WHERE vip_player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE vip_kicks SET kicked_player_id = ? WHERE kicked_player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE admin_commands SET admin_player_id = ? WHERE admin_player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE admin_commands SET target_player_id = ? WHERE target_player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+
+	// The source's clan membership moves over unless the target already
+	// has one; otherwise it goes with the source player
+	_, err = tx.ExecContext(ctx, `UPDATE OR IGNORE clan_members SET player_id = ? WHERE player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+
+	// Likewise tournament sign-ups; pairings and results follow the player
+	_, err = tx.ExecContext(ctx, `UPDATE OR IGNORE tournament_entrants SET player_id = ? WHERE player_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	for _, column := range []string{"player1_id", "player2_id", "winner_id"} {
+		_, err = tx.ExecContext(ctx, `UPDATE tournament_matches SET `+column+` = ? WHERE `+column+` = ?`, targetPlayerID, sourcePlayerID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE tournaments SET winner_id = ? WHERE winner_id = ?`, targetPlayerID, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+
+	// Frozen season standings keep the place the source earned
+	_, err = tx.ExecContext(ctx, `UPDATE season_standings SET player_id = ? WHERE player_id = ?`, targetPlayerID, sourcePlayerID)
 	if err != nil {
 		return err
 	}
 
 	// Delete the source player (CASCADE will handle if any orphaned refs)
-	_, err = s.db.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, sourcePlayerID)
-	return err
+	_, err = tx.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, sourcePlayerID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The target now owns the source's match rows; redo its trend history
+	s.InvalidateLeaderboardCache()
+	return s.rebuildPlayerSnapshots(ctx, targetPlayerID)
 }
 
 // SplitGUID creates a new player from a GUID (for unlinking)
 func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Player, error) {
 	// Get the GUID info
 	var pg domain.PlayerGUID
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, player_id, guid, name, clean_name, first_seen, last_seen, is_vr
 		FROM player_guids WHERE id = ?
 	`, playerGUIDID).Scan(&pg.ID, &pg.PlayerID, &pg.GUID, &pg.Name, &pg.CleanName, &pg.FirstSeen, &pg.LastSeen, &pg.IsVR)
@@ -630,7 +705,7 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 
 	// Check if this is the only GUID for the player
 	var guidCount int
-	err = s.db.QueryRowContext(ctx, `
+	err = s.conn(ctx).QueryRowContext(ctx, `
 		SELECT COUNT(*) FROM player_guids WHERE player_id = ?
 	`, pg.PlayerID).Scan(&guidCount)
 	if err != nil {
@@ -641,7 +716,7 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 	}
 
 	// Create new player (inherit is_vr from the GUID being split)
-	result, err := s.db.ExecContext(ctx, `
+	result, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO players (name, clean_name, first_seen, last_seen, is_vr)
 		VALUES (?, ?, ?, ?, ?)
 	`, pg.Name, pg.CleanName, formatTimestamp(pg.FirstSeen), formatTimestamp(pg.LastSeen), pg.IsVR)
@@ -651,7 +726,7 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 	newPlayerID, _ := result.LastInsertId()
 
 	// Move the GUID to new player
-	_, err = s.db.ExecContext(ctx, `
+	_, err = s.conn(ctx).ExecContext(ctx, `
 		UPDATE player_guids SET player_id = ? WHERE id = ?
 	`, newPlayerID, playerGUIDID)
 	if err != nil {
@@ -659,7 +734,7 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 	}
 
 	// Recompute source player's is_vr from remaining GUIDs
-	_, err = s.db.ExecContext(ctx, `
+	_, err = s.conn(ctx).ExecContext(ctx, `
 		UPDATE players SET is_vr = EXISTS(
 			SELECT 1 FROM player_guids WHERE player_id = ? AND is_vr = TRUE
 		) WHERE id = ?
@@ -668,6 +743,15 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 		return nil, err
 	}
 
+	// The GUID's match rows moved with it; redo both trend histories
+	s.InvalidateLeaderboardCache()
+	if err := s.rebuildPlayerSnapshots(ctx, pg.PlayerID); err != nil {
+		return nil, err
+	}
+	if err := s.rebuildPlayerSnapshots(ctx, newPlayerID); err != nil {
+		return nil, err
+	}
+
 	// Return the new player
 	return s.GetPlayerByID(ctx, newPlayerID)
 }
@@ -676,10 +760,11 @@ func (s *Store) SplitGUID(ctx context.Context, playerGUIDID int64) (*domain.Play
 
 // CreateSession starts a new player session
 func (s *Store) CreateSession(ctx context.Context, sess *domain.Session) error {
-	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO sessions (player_guid_id, server_id, joined_at, ip_address)
-		VALUES (?, ?, ?, ?)
-	`, sess.PlayerGUIDID, sess.ServerID, formatTimestamp(sess.JoinedAt), sess.IPAddress)
+	result, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO sessions (player_guid_id, server_id, joined_at, ip_address, country_code, country, city)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, sess.PlayerGUIDID, sess.ServerID, formatTimestamp(sess.JoinedAt), sess.IPAddress,
+		sess.CountryCode, sess.Country, sess.City)
 	if err != nil {
 		return err
 	}
@@ -689,7 +774,7 @@ func (s *Store) CreateSession(ctx context.Context, sess *domain.Session) error {
 
 // UpdateSessionClientInfo sets the client engine and version from the Trinity handshake.
 func (s *Store) UpdateSessionClientInfo(ctx context.Context, sessionID int64, engine, version string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sessions SET client_engine = ?, client_version = ? WHERE id = ?
 	`, engine, version, sessionID)
 	return err
@@ -698,7 +783,7 @@ func (s *Store) UpdateSessionClientInfo(ctx context.Context, sessionID int64, en
 // EndSession closes a session with the leave time (idempotent - no-op if already closed)
 func (s *Store) EndSession(ctx context.Context, sessionID int64, leftAt time.Time) error {
 	formattedLeftAt := formatTimestamp(leftAt)
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sessions SET
 			left_at = ?,
 			duration_seconds = CAST((julianday(?) - julianday(joined_at)) * 86400 AS INTEGER)
@@ -707,12 +792,34 @@ func (s *Store) EndSession(ctx context.Context, sessionID int64, leftAt time.Tim
 	return err
 }
 
+// seenEarlier records a sighting of pg from before its last_seen, as
+// from an imported log. It can only move first_seen back; the current
+// name stays.
+func seenEarlier(ctx context.Context, tx txn, pg *domain.PlayerGUID, at time.Time, isVR bool) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE player_guids SET first_seen = MIN(first_seen, ?), is_vr = is_vr OR ?
+		WHERE id = ?
+	`, formatTimestamp(at), isVR, pg.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE players SET first_seen = MIN(first_seen, ?), is_vr = is_vr OR ?
+		WHERE id = ?
+	`, formatTimestamp(at), isVR, pg.PlayerID); err != nil {
+		return err
+	}
+	if at.Before(pg.FirstSeen) {
+		pg.FirstSeen = at
+	}
+	return nil
+}
+
 // GetSessionByPlayerAndJoinTime finds a session by exact start time (for replay idempotency)
 func (s *Store) GetSessionByPlayerAndJoinTime(ctx context.Context, playerGUIDID, serverID int64, joinedAt time.Time) (*domain.Session, error) {
 	var sess domain.Session
 	var leftAt sql.NullTime
 	var durationSeconds sql.NullInt64
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, player_guid_id, server_id, joined_at, left_at, duration_seconds
 		FROM sessions
 		WHERE player_guid_id = ? AND server_id = ? AND joined_at = ?
@@ -739,7 +846,7 @@ func (s *Store) GetSessionActiveAt(ctx context.Context, playerGUIDID, serverID i
 	var leftAt sql.NullTime
 	var durationSeconds sql.NullInt64
 	ts := formatTimestamp(timestamp)
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, player_guid_id, server_id, joined_at, left_at, duration_seconds
 		FROM sessions
 		WHERE player_guid_id = ? AND server_id = ? AND joined_at <= ? AND left_at >= ?
@@ -763,7 +870,7 @@ func (s *Store) GetSessionActiveAt(ctx context.Context, playerGUIDID, serverID i
 // GetOpenSessionForPlayer finds an open session (left_at IS NULL) for a player on a server
 func (s *Store) GetOpenSessionForPlayer(ctx context.Context, playerGUIDID, serverID int64) (*domain.Session, error) {
 	var sess domain.Session
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, player_guid_id, server_id, joined_at
 		FROM sessions
 		WHERE player_guid_id = ? AND server_id = ? AND left_at IS NULL
@@ -778,11 +885,31 @@ func (s *Store) GetOpenSessionForPlayer(ctx context.Context, playerGUIDID, serve
 	return &sess, nil
 }
 
+// GetOpenSessionAt returns the player's latest open session on the
+// server that began by at, or nil. A leave read from an imported log
+// closes that one rather than the player's live session.
+func (s *Store) GetOpenSessionAt(ctx context.Context, playerGUIDID, serverID int64, at time.Time) (*domain.Session, error) {
+	var sess domain.Session
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT id, player_guid_id, server_id, joined_at
+		FROM sessions
+		WHERE player_guid_id = ? AND server_id = ? AND left_at IS NULL AND joined_at <= ?
+		ORDER BY joined_at DESC LIMIT 1
+	`, playerGUIDID, serverID, formatTimestamp(at)).Scan(&sess.ID, &sess.PlayerGUIDID, &sess.ServerID, &sess.JoinedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
 // EndOpenSessionsBefore closes all open sessions that started before a timestamp
 // Used for both clean shutdown and crash recovery to avoid closing sessions from later events during replay
 func (s *Store) EndOpenSessionsBefore(ctx context.Context, serverID int64, before, leftAt time.Time) error {
 	formattedLeftAt := formatTimestamp(leftAt)
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE sessions SET
 			left_at = ?,
 			duration_seconds = CAST((julianday(?) - julianday(joined_at)) * 86400 AS INTEGER)
@@ -793,7 +920,7 @@ func (s *Store) EndOpenSessionsBefore(ctx context.Context, serverID int64, befor
 
 // GetActiveSessions returns sessions without a leave time for a server
 func (s *Store) GetActiveSessions(ctx context.Context, serverID int64) ([]domain.Session, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT id, player_guid_id, server_id, joined_at
 		FROM sessions WHERE server_id = ? AND left_at IS NULL
 	`, serverID)
@@ -817,7 +944,7 @@ func (s *Store) GetActiveSessions(ctx context.Context, serverID int64) ([]domain
 
 // CreateMatch starts a new match
 func (s *Store) CreateMatch(ctx context.Context, m *domain.Match) error {
-	result, err := s.db.ExecContext(ctx, `
+	result, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO matches (uuid, server_id, map_name, game_type, started_at, movement, gameplay)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`, m.UUID, m.ServerID, m.MapName, m.GameType, formatTimestamp(m.StartedAt), m.Movement, m.Gameplay)
@@ -836,7 +963,7 @@ func (s *Store) GetMatchByUUID(ctx context.Context, uuid string) (*domain.Match,
 	var m domain.Match
 	var endedAt sql.NullTime
 	var exitReason sql.NullString
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, uuid, server_id, map_name, game_type, started_at, ended_at, exit_reason, movement, gameplay
 		FROM matches WHERE uuid = ?
 	`, uuid).Scan(&m.ID, &m.UUID, &m.ServerID, &m.MapName, &m.GameType, &m.StartedAt, &endedAt, &exitReason, &m.Movement, &m.Gameplay)
@@ -855,18 +982,29 @@ func (s *Store) GetMatchByUUID(ctx context.Context, uuid string) (*domain.Match,
 	return &m, nil
 }
 
+// nextEndSeq is the end_seq for matches ended by the current
+// statement. Matches ended together share one.
+const nextEndSeq = `(SELECT COALESCE(MAX(end_seq), 0) + 1 FROM matches)`
+
 // EndMatch closes a match and updates the server's last match tracking for log replay
 func (s *Store) EndMatch(ctx context.Context, matchID int64, endedAt time.Time, exitReason string, redScore, blueScore *int) error {
+	err := endMatch(ctx, s.conn(ctx), matchID, endedAt, exitReason, redScore, blueScore)
+	s.InvalidateLeaderboardCache()
+	return err
+}
+
+func endMatch(ctx context.Context, db execer, matchID int64, endedAt time.Time, exitReason string, redScore, blueScore *int) error {
 	formattedEndedAt := formatTimestamp(endedAt)
-	_, err := s.db.ExecContext(ctx, `
-		UPDATE matches SET ended_at = ?, exit_reason = ?, red_score = ?, blue_score = ?
+	_, err := db.ExecContext(ctx, `
+		UPDATE matches SET ended_at = ?, exit_reason = ?, red_score = ?, blue_score = ?,
+			end_seq = `+nextEndSeq+`
 		WHERE id = ? AND ended_at IS NULL
 	`, formattedEndedAt, exitReason, redScore, blueScore, matchID)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	_, err = db.ExecContext(ctx, `
 		UPDATE servers
 		SET last_match_uuid = (SELECT uuid FROM matches WHERE id = ?),
 		    last_match_ended_at = ?
@@ -877,7 +1015,7 @@ func (s *Store) EndMatch(ctx context.Context, matchID int64, endedAt time.Time,
 
 // UpdateMatchMovement updates the movement mode for a match
 func (s *Store) UpdateMatchMovement(ctx context.Context, matchID int64, movement string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE matches SET movement = ? WHERE id = ?
 	`, movement, matchID)
 	return err
@@ -885,7 +1023,7 @@ func (s *Store) UpdateMatchMovement(ctx context.Context, matchID int64, movement
 
 // UpdateMatchGameplay updates the gameplay mode for a match
 func (s *Store) UpdateMatchGameplay(ctx context.Context, matchID int64, gameplay string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE matches SET gameplay = ? WHERE id = ?
 	`, gameplay, matchID)
 	return err
@@ -899,14 +1037,14 @@ func (s *Store) MarkMatchDemoAvailable(ctx context.Context, uuid string) error {
 	if uuid == "" {
 		return fmt.Errorf("storage.MarkMatchDemoAvailable: uuid is required")
 	}
-	_, err := s.db.ExecContext(ctx,
+	_, err := s.conn(ctx).ExecContext(ctx,
 		"UPDATE matches SET demo_available = 1 WHERE uuid = ?", uuid)
 	return err
 }
 
 // UpdateMatchStartTime updates the match start time (used when warmup ends, only if not already ended)
 func (s *Store) UpdateMatchStartTime(ctx context.Context, matchID int64, startedAt time.Time) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE matches SET started_at = ?
 		WHERE id = ? AND ended_at IS NULL
 	`, formatTimestamp(startedAt), matchID)
@@ -915,8 +1053,8 @@ func (s *Store) UpdateMatchStartTime(ctx context.Context, matchID int64, started
 
 // EndAllOpenMatches closes all open matches for a server, optionally excluding a match in progress
 func (s *Store) EndAllOpenMatches(ctx context.Context, serverID int64, endedAt time.Time, exitReason string, matchInProgressID int64) error {
-	_, err := s.db.ExecContext(ctx, `
-		UPDATE matches SET ended_at = ?, exit_reason = ?
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE matches SET ended_at = ?, exit_reason = ?, end_seq = `+nextEndSeq+`
 		WHERE server_id = ? AND ended_at IS NULL AND id != ?
 	`, formatTimestamp(endedAt), exitReason, serverID, matchInProgressID)
 	return err
@@ -926,7 +1064,7 @@ func (s *Store) EndAllOpenMatches(ctx context.Context, serverID int64, endedAt t
 func (s *Store) GetCurrentMatch(ctx context.Context, serverID int64) (*domain.Match, error) {
 	var m domain.Match
 	var gameType sql.NullString
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, server_id, map_name, game_type, started_at
 		FROM matches
 		WHERE server_id = ? AND ended_at IS NULL
@@ -945,7 +1083,7 @@ func (s *Store) GetCurrentMatch(ctx context.Context, serverID int64) (*domain.Ma
 
 // GetRecentMatches returns recent completed matches
 func (s *Store) GetRecentMatches(ctx context.Context, limit int) ([]domain.Match, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT id, server_id, map_name, game_type, started_at, ended_at, exit_reason
 		FROM matches WHERE ended_at IS NOT NULL ORDER BY ended_at DESC LIMIT ?
 	`, limit)
@@ -974,7 +1112,7 @@ func (s *Store) GetRecentMatches(ctx context.Context, limit int) ([]domain.Match
 // GetActiveMatch returns the current match for a server (no end time)
 func (s *Store) GetActiveMatch(ctx context.Context, serverID int64) (*domain.Match, error) {
 	var m domain.Match
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, server_id, map_name, game_type, started_at
 		FROM matches WHERE server_id = ? AND ended_at IS NULL
 		ORDER BY started_at DESC LIMIT 1
@@ -998,10 +1136,18 @@ func (s *Store) FlushMatchPlayerStats(ctx context.Context, matchID, playerGUIDID
 	frags, deaths int, completed bool, score *int, team *int, model string, skill float64, victory bool,
 	captures, flagReturns, assists, impressives, excellents, humiliations, defends int,
 	isBot bool, joinedLate bool, joinedAt time.Time, isVR bool) error {
+	return flushMatchPlayerStats(ctx, s.conn(ctx), matchID, playerGUIDID, clientID, frags, deaths, completed, score, team, model, skill, victory,
+		captures, flagReturns, assists, impressives, excellents, humiliations, defends, isBot, joinedLate, joinedAt, isVR)
+}
+
+func flushMatchPlayerStats(ctx context.Context, db execer, matchID, playerGUIDID int64, clientID int,
+	frags, deaths int, completed bool, score *int, team *int, model string, skill float64, victory bool,
+	captures, flagReturns, assists, impressives, excellents, humiliations, defends int,
+	isBot bool, joinedLate bool, joinedAt time.Time, isVR bool) error {
 
 	if isBot {
 		// Bots: upsert by full primary key (allows multiple same-GUID bots)
-		_, err := s.db.ExecContext(ctx, `
+		_, err := db.ExecContext(ctx, `
 			INSERT INTO match_player_stats (
 				match_id, player_guid_id, client_id, frags, deaths, completed, score, team,
 				model, skill, victories, captures, flag_returns, assists, impressives,
@@ -1032,7 +1178,7 @@ func (s *Store) FlushMatchPlayerStats(ctx context.Context, matchID, playerGUIDID
 
 	// Humans: one row per player, update client_id on reconnect
 	// First try to update existing row (handles reconnects with different client_id)
-	result, err := s.db.ExecContext(ctx, `
+	result, err := db.ExecContext(ctx, `
 		UPDATE match_player_stats SET
 			client_id = ?,
 			frags = frags + ?,
@@ -1063,7 +1209,7 @@ func (s *Store) FlushMatchPlayerStats(ctx context.Context, matchID, playerGUIDID
 	}
 
 	// No existing row - insert new
-	_, err = s.db.ExecContext(ctx, `
+	_, err = db.ExecContext(ctx, `
 		INSERT INTO match_player_stats (
 			match_id, player_guid_id, client_id, frags, deaths, completed, score, team,
 			model, skill, victories, captures, flag_returns, assists, impressives,
@@ -1077,18 +1223,18 @@ func (s *Store) FlushMatchPlayerStats(ctx context.Context, matchID, playerGUIDID
 	}
 
 	// Mark match as having a human player
-	_, err = s.db.ExecContext(ctx, `UPDATE matches SET has_human_player = TRUE WHERE id = ? AND has_human_player = FALSE`, matchID)
+	_, err = db.ExecContext(ctx, `UPDATE matches SET has_human_player = TRUE WHERE id = ? AND has_human_player = FALSE`, matchID)
 	if err != nil {
 		return err
 	}
 
 	// Propagate VR status to player_guids and players (sticky: never reset to false)
 	if isVR {
-		_, err = s.db.ExecContext(ctx, `UPDATE player_guids SET is_vr = TRUE WHERE id = ? AND is_vr = FALSE`, playerGUIDID)
+		_, err = db.ExecContext(ctx, `UPDATE player_guids SET is_vr = TRUE WHERE id = ? AND is_vr = FALSE`, playerGUIDID)
 		if err != nil {
 			return err
 		}
-		_, err = s.db.ExecContext(ctx, `
+		_, err = db.ExecContext(ctx, `
 			UPDATE players SET is_vr = TRUE
 			WHERE id = (SELECT player_id FROM player_guids WHERE id = ?) AND is_vr = FALSE
 		`, playerGUIDID)
@@ -1109,11 +1255,19 @@ func boolToInt(b bool) int {
 
 // --- Stats methods ---
 
-// GetLeaderboard returns top players ranked by the specified category and time period.
-// asOf pins the period's upper bound for reproducible snapshots; pass
-// time.Time{} for "live" (now-anchored) results.
-func (s *Store) GetLeaderboard(ctx context.Context, category, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
+// queryLeaderboard runs the leaderboard aggregate; GetLeaderboard
+// wraps it with the cache.
+func (s *Store) queryLeaderboard(ctx context.Context, rules LeaderboardRules, category, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
 	start, end := getTimePeriodBounds(period, asOf)
+	return s.leaderboardBetween(ctx, rules, category, period, start, end, limit, gameType)
+}
+
+// leaderboardBetween ranks matches started in [start, end), or all of
+// them when period is "all".
+func (s *Store) leaderboardBetween(ctx context.Context, rules LeaderboardRules, category, period string, start, end time.Time, limit int, gameType string) (*domain.LeaderboardResponse, error) {
+	if category == "accuracy" {
+		return s.accuracyLeaderboardBetween(ctx, "", period, start, end, limit, gameType)
+	}
 
 	// Determine ORDER BY clause based on category
 	var orderBy string
@@ -1140,11 +1294,37 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 		orderBy = "total_flag_returns DESC"
 	case "victories":
 		orderBy = "total_victories DESC"
+	case "sprees":
+		orderBy = "best_spree DESC"
+	case "skulls":
+		orderBy = "total_skulls_scored DESC"
+	case "obelisks":
+		orderBy = "total_obelisk_destroys DESC"
+	case "playtime":
+		orderBy = "total_playtime_seconds DESC"
+		if period != "all" {
+			orderBy = "period_playtime_seconds DESC"
+		}
 	default: // "frags"
 		orderBy = "total_frags DESC"
 	}
 
-	havingClause := "HAVING completed_matches >= 5"
+	// Time on the servers in the period; all-time boards fill it in
+	// from total_playtime_seconds after the scan.
+	periodPlaytime := "0"
+	var periodPlaytimeArgs []any
+	if period != "all" {
+		periodPlaytime = `COALESCE((
+					SELECT SUM(s.duration_seconds)
+					FROM sessions s
+					JOIN player_guids pg4 ON s.player_guid_id = pg4.id
+					WHERE pg4.player_id = p.id AND s.left_at IS NOT NULL
+					  AND s.joined_at >= ? AND s.joined_at < ?
+				), 0)`
+		periodPlaytimeArgs = []any{formatTimestamp(start), formatTimestamp(end)}
+	}
+
+	havingClause, havingArgs := rules.having()
 
 	var query string
 	var args []interface{}
@@ -1162,6 +1342,7 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 					JOIN player_guids pg3 ON s.player_guid_id = pg3.id
 					WHERE pg3.player_id = p.id AND s.left_at IS NOT NULL
 				), 0) as total_playtime_seconds,
+				` + periodPlaytime + ` as period_playtime_seconds,
 				p.is_bot, p.is_vr,
 				CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
 				COALESCE(u.is_admin, 0) as is_admin,
@@ -1178,6 +1359,9 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 				COALESCE(SUM(mps.humiliations), 0) as total_humiliations,
 				COALESCE(SUM(mps.defends), 0) as total_defends,
 				COALESCE(SUM(mps.victories), 0) as total_victories,
+				COALESCE(MAX(mps.best_spree), 0) as best_spree,
+				COALESCE(SUM(mps.skulls_scored), 0) as total_skulls_scored,
+				COALESCE(SUM(mps.obelisk_destroys), 0) as total_obelisk_destroys,
 				CASE WHEN SUM(mps.deaths) > 0
 					THEN CAST(SUM(mps.frags) AS REAL) / SUM(mps.deaths)
 					ELSE COALESCE(SUM(mps.frags), 0) END as kd_ratio,
@@ -1193,17 +1377,19 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 					ORDER BY m2.ended_at DESC LIMIT 1) as skill
 			FROM players p
 			JOIN player_guids pg ON p.id = pg.player_id
-			LEFT JOIN match_player_stats mps ON pg.id = mps.player_guid_id
+			LEFT JOIN match_player_stats mps ON pg.id = mps.player_guid_id` + rules.matchFilter("mps") + `
 			LEFT JOIN users u ON u.player_id = p.id
-			WHERE p.is_bot = FALSE AND p.clean_name NOT LIKE '[VR] Player#%'
+			WHERE ` + rules.playerFilter() + `
 			GROUP BY p.id
 			` + havingClause + `
 			ORDER BY ` + orderBy + `
 			LIMIT ?`
-		args = []interface{}{limit}
+		args = append(periodPlaytimeArgs, havingArgs...)
+		args = append(args, limit)
 	} else {
 		// Build WHERE conditions
-		whereConditions := "p.is_bot = FALSE AND p.clean_name NOT LIKE '[VR] Player#%'"
+		whereConditions := rules.playerFilter()
+		args = append(args, periodPlaytimeArgs...)
 
 		if period != "all" {
 			whereConditions += " AND m.started_at >= ? AND m.started_at < ?"
@@ -1215,6 +1401,7 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 			args = append(args, gameType)
 		}
 
+		args = append(args, havingArgs...)
 		args = append(args, limit)
 
 		query = `
@@ -1226,6 +1413,7 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 					JOIN player_guids pg3 ON s.player_guid_id = pg3.id
 					WHERE pg3.player_id = p.id AND s.left_at IS NOT NULL
 				), 0) as total_playtime_seconds,
+				` + periodPlaytime + ` as period_playtime_seconds,
 				p.is_bot, p.is_vr,
 				CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
 				COALESCE(u.is_admin, 0) as is_admin,
@@ -1242,6 +1430,9 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 				COALESCE(SUM(mps.humiliations), 0) as total_humiliations,
 				COALESCE(SUM(mps.defends), 0) as total_defends,
 				COALESCE(SUM(mps.victories), 0) as total_victories,
+				COALESCE(MAX(mps.best_spree), 0) as best_spree,
+				COALESCE(SUM(mps.skulls_scored), 0) as total_skulls_scored,
+				COALESCE(SUM(mps.obelisk_destroys), 0) as total_obelisk_destroys,
 				CASE WHEN SUM(mps.deaths) > 0
 					THEN CAST(SUM(mps.frags) AS REAL) / SUM(mps.deaths)
 					ELSE COALESCE(SUM(mps.frags), 0) END as kd_ratio,
@@ -1257,7 +1448,7 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 					ORDER BY m2.ended_at DESC LIMIT 1) as skill
 			FROM players p
 			JOIN player_guids pg ON p.id = pg.player_id
-			LEFT JOIN match_player_stats mps ON pg.id = mps.player_guid_id
+			LEFT JOIN match_player_stats mps ON pg.id = mps.player_guid_id` + rules.matchFilter("mps") + `
 			LEFT JOIN matches m ON mps.match_id = m.id
 			LEFT JOIN users u ON u.player_id = p.id
 			WHERE ` + whereConditions + `
@@ -1267,7 +1458,7 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 			LIMIT ?`
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1282,11 +1473,12 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 		var skill sql.NullFloat64
 		if err := rows.Scan(
 			&e.Player.ID, &e.Player.Name, &e.Player.CleanName,
-			&e.Player.FirstSeen, &e.Player.LastSeen, &e.Player.TotalPlaytimeSeconds, &e.Player.IsBot, &e.Player.IsVR,
+			&e.Player.FirstSeen, &e.Player.LastSeen, &e.Player.TotalPlaytimeSeconds, &e.PlaytimeSeconds, &e.Player.IsBot, &e.Player.IsVR,
 			&e.Player.IsVerified, &e.Player.IsAdmin,
 			&e.TotalFrags, &e.TotalDeaths, &e.TotalMatches, &e.CompletedMatches, &e.UncompletedMatches,
 			&e.Captures, &e.FlagReturns, &e.Assists, &e.Impressives, &e.Excellents,
-			&e.Humiliations, &e.Defends, &e.Victories,
+			&e.Humiliations, &e.Defends, &e.Victories, &e.BestSpree,
+			&e.SkullsScored, &e.ObeliskDestroys,
 			&e.KDRatio, &model, &skill,
 		); err != nil {
 			return nil, err
@@ -1297,12 +1489,22 @@ func (s *Store) GetLeaderboard(ctx context.Context, category, period string, lim
 		if skill.Valid {
 			e.Player.Skill = skill.Float64
 		}
+		if period == "all" {
+			e.PlaytimeSeconds = e.Player.TotalPlaytimeSeconds
+		}
 		e.Rank = rank
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	players := make([]*domain.Player, len(entries))
+	for i := range entries {
+		players[i] = &entries[i].Player
+	}
+	if err := s.attachProfiles(ctx, players...); err != nil {
+		return nil, err
+	}
 
 	response := &domain.LeaderboardResponse{
 		Category: category,
@@ -1376,7 +1578,10 @@ func (s *Store) getPlayerStats(ctx context.Context, playerID int64, period strin
 				COALESCE(SUM(mps.excellents), 0) as excellents,
 				COALESCE(SUM(mps.humiliations), 0) as humiliations,
 				COALESCE(SUM(mps.defends), 0) as defends,
-				COALESCE(SUM(mps.victories), 0) as victories
+				COALESCE(SUM(mps.victories), 0) as victories,
+				COALESCE(MAX(mps.best_spree), 0) as best_spree,
+				COALESCE(SUM(mps.skulls_scored), 0) as skulls_scored,
+				COALESCE(SUM(mps.obelisk_destroys), 0) as obelisk_destroys
 			FROM match_player_stats mps
 			JOIN player_guids pg ON mps.player_guid_id = pg.id
 			WHERE pg.player_id = ?
@@ -1397,7 +1602,10 @@ func (s *Store) getPlayerStats(ctx context.Context, playerID int64, period strin
 				COALESCE(SUM(mps.excellents), 0) as excellents,
 				COALESCE(SUM(mps.humiliations), 0) as humiliations,
 				COALESCE(SUM(mps.defends), 0) as defends,
-				COALESCE(SUM(mps.victories), 0) as victories
+				COALESCE(SUM(mps.victories), 0) as victories,
+				COALESCE(MAX(mps.best_spree), 0) as best_spree,
+				COALESCE(SUM(mps.skulls_scored), 0) as skulls_scored,
+				COALESCE(SUM(mps.obelisk_destroys), 0) as obelisk_destroys
 			FROM match_player_stats mps
 			JOIN player_guids pg ON mps.player_guid_id = pg.id
 			JOIN matches m ON mps.match_id = m.id
@@ -1408,16 +1616,21 @@ func (s *Store) getPlayerStats(ctx context.Context, playerID int64, period strin
 		args = []interface{}{playerID, formatTimestamp(start), formatTimestamp(end)}
 	}
 
-	err = s.db.QueryRowContext(ctx, query, args...).Scan(
+	err = s.conn(ctx).QueryRowContext(ctx, query, args...).Scan(
 		&stats.Matches, &stats.CompletedMatches, &stats.UncompletedMatches,
 		&stats.Frags, &stats.Deaths,
 		&stats.Captures, &stats.FlagReturns, &stats.Assists,
 		&stats.Impressives, &stats.Excellents,
 		&stats.Humiliations, &stats.Defends, &stats.Victories,
+		&stats.BestSpree, &stats.SkullsScored, &stats.ObeliskDestroys,
 	)
 	if err != nil {
 		return nil, err
 	}
+	stats.LongestWinStreak, err = s.longestWinStreak(ctx, playerID, start, end)
+	if err != nil {
+		return nil, err
+	}
 
 	// Calculate K/D ratio
 	if stats.Deaths > 0 {
@@ -1432,11 +1645,25 @@ func (s *Store) getPlayerStats(ctx context.Context, playerID int64, period strin
 		return nil, err
 	}
 
+	weapons, err := s.getPlayerWeaponUsage(ctx, playerID, period == "all", start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	accuracy, err := s.getPlayerAccuracy(ctx, playerID, period == "all", start, end)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &domain.PlayerStatsResponse{
-		Player: *player,
-		Period: period,
-		Stats:  stats,
-		Names:  names,
+		Player:          *player,
+		Period:          period,
+		Stats:           stats,
+		Names:           names,
+		Weapons:         weapons,
+		SignatureWeapon: signatureWeapon(weapons),
+		Accuracy:        accuracy,
+		OverallAccuracy: overallAccuracy(accuracy),
 	}
 
 	// Include period bounds for non-"all" periods
@@ -1465,7 +1692,7 @@ type User struct {
 
 // CreateUser creates a new user account
 func (s *Store) CreateUser(ctx context.Context, username, passwordHash string, isAdmin bool, playerID *int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		INSERT INTO users (username, password_hash, is_admin, player_id, password_change_required)
 		VALUES (?, ?, ?, ?, TRUE)
 	`, username, passwordHash, isAdmin, playerID)
@@ -1474,7 +1701,7 @@ func (s *Store) CreateUser(ctx context.Context, username, passwordHash string, i
 
 // GetUserByUsername retrieves a user by username
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-	row := s.db.QueryRowContext(ctx, `
+	row := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, username, password_hash, is_admin, player_id, password_change_required, created_at, last_login, game_token
 		FROM users WHERE username = ?
 	`, username)
@@ -1483,7 +1710,7 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (*User,
 
 // GetUserByID retrieves a user by ID
 func (s *Store) GetUserByID(ctx context.Context, id int64) (*User, error) {
-	row := s.db.QueryRowContext(ctx, `
+	row := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, username, password_hash, is_admin, player_id, password_change_required, created_at, last_login, game_token
 		FROM users WHERE id = ?
 	`, id)
@@ -1492,7 +1719,7 @@ func (s *Store) GetUserByID(ctx context.Context, id int64) (*User, error) {
 
 // DeleteUser removes a user by username
 func (s *Store) DeleteUser(ctx context.Context, username string) error {
-	result, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE username = ?`, username)
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM users WHERE username = ?`, username)
 	if err != nil {
 		return err
 	}
@@ -1505,7 +1732,7 @@ func (s *Store) DeleteUser(ctx context.Context, username string) error {
 
 // ListUsers returns all users with details
 func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT id, username, password_hash, is_admin, player_id, password_change_required, created_at, last_login, game_token
 		FROM users ORDER BY username
 	`)
@@ -1533,7 +1760,7 @@ type UserWithPlayer struct {
 
 // ListUsersWithPlayer returns all users, joined with the linked player's name.
 func (s *Store) ListUsersWithPlayer(ctx context.Context) ([]UserWithPlayer, error) {
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT u.id, u.username, u.password_hash, u.is_admin, u.player_id,
 		       u.password_change_required, u.created_at, u.last_login, u.game_token,
 		       p.name
@@ -1582,7 +1809,7 @@ func (s *Store) SearchUsersWithPlayer(ctx context.Context, query string, limit i
 		limit = 10
 	}
 	pattern := "%" + query + "%"
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT u.id, u.username, u.password_hash, u.is_admin, u.player_id,
 		       u.password_change_required, u.created_at, u.last_login, u.game_token,
 		       p.name
@@ -1628,7 +1855,7 @@ func (s *Store) SearchUsersWithPlayer(ctx context.Context, query string, limit i
 
 // UpdateUserLastLogin updates the last login timestamp
 func (s *Store) UpdateUserLastLogin(ctx context.Context, userID int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE users SET last_login = CURRENT_TIMESTAMP WHERE id = ?
 	`, userID)
 	return err
@@ -1636,7 +1863,7 @@ func (s *Store) UpdateUserLastLogin(ctx context.Context, userID int64) error {
 
 // UpdateUserPassword updates a user's password and clears the password_change_required flag
 func (s *Store) UpdateUserPassword(ctx context.Context, userID int64, newPasswordHash string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE users SET password_hash = ?, password_change_required = FALSE WHERE id = ?
 	`, newPasswordHash, userID)
 	return err
@@ -1644,7 +1871,7 @@ func (s *Store) UpdateUserPassword(ctx context.Context, userID int64, newPasswor
 
 // ResetUserPassword sets a new temporary password (admin action)
 func (s *Store) ResetUserPassword(ctx context.Context, userID int64, newPasswordHash string) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE users SET password_hash = ?, password_change_required = TRUE WHERE id = ?
 	`, newPasswordHash, userID)
 	return err
@@ -1653,7 +1880,7 @@ func (s *Store) ResetUserPassword(ctx context.Context, userID int64, newPassword
 // IsPlayerClaimed checks if a player is already linked to a user
 func (s *Store) IsPlayerClaimed(ctx context.Context, playerID int64) (bool, error) {
 	var count int
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT COUNT(*) FROM users WHERE player_id = ?
 	`, playerID).Scan(&count)
 	return count > 0, err
@@ -1662,7 +1889,7 @@ func (s *Store) IsPlayerClaimed(ctx context.Context, playerID int64) (bool, erro
 // GetPlayerVerifiedStatus returns whether a player is verified (linked to a user) and whether they are an admin
 func (s *Store) GetPlayerVerifiedStatus(ctx context.Context, playerID int64) (isVerified, isAdmin bool) {
 	var admin bool
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT is_admin FROM users WHERE player_id = ?
 	`, playerID).Scan(&admin)
 	if err != nil {
@@ -1673,7 +1900,7 @@ func (s *Store) GetPlayerVerifiedStatus(ctx context.Context, playerID int64) (is
 
 // UpdateUserPlayerLink links or unlinks a player to a user
 func (s *Store) UpdateUserPlayerLink(ctx context.Context, userID int64, playerID *int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE users SET player_id = ? WHERE id = ?
 	`, playerID, userID)
 	return err
@@ -1681,7 +1908,7 @@ func (s *Store) UpdateUserPlayerLink(ctx context.Context, userID int64, playerID
 
 // UpdateUserAdmin updates the admin status of a user
 func (s *Store) UpdateUserAdmin(ctx context.Context, userID int64, isAdmin bool) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE users SET is_admin = ? WHERE id = ?
 	`, isAdmin, userID)
 	return err
@@ -1702,10 +1929,12 @@ func (s *Store) attachPlayersToMatches(ctx context.Context, matches []domain.Mat
 	}
 
 	// Get player stats for all matches
-	playerRows, err := s.db.QueryContext(ctx, `
-		SELECT mps.match_id, p.id, pg.name, pg.clean_name, mps.frags, mps.deaths, mps.completed, p.is_bot, mps.skill, mps.score, mps.team, mps.model, mps.impressives, mps.excellents, mps.humiliations, mps.defends, mps.victories, mps.captures, mps.assists, mps.is_vr,
+	playerRows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT mps.match_id, p.id, pg.name, pg.clean_name, mps.frags, mps.deaths, mps.completed, p.is_bot, mps.skill, mps.score, mps.team, mps.model, mps.impressives, mps.excellents, mps.humiliations, mps.defends, mps.victories, mps.captures, mps.assists, COALESCE(mps.skulls_scored, 0), COALESCE(mps.obelisk_destroys, 0), mps.is_vr,
 			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
-			COALESCE(u.is_admin, 0) as is_admin
+			COALESCE(u.is_admin, 0) as is_admin,
+			EXISTS(SELECT 1 FROM match_stat_corrections c
+				WHERE c.match_id = mps.match_id AND c.player_guid_id = mps.player_guid_id AND c.client_id = mps.client_id) as corrected
 		FROM match_player_stats mps
 		JOIN player_guids pg ON mps.player_guid_id = pg.id
 		JOIN players p ON pg.player_id = p.id
@@ -1742,10 +1971,10 @@ func (s *Store) attachPlayersToMatches(ctx context.Context, matches []domain.Mat
 // GetRecentMatchSummaries returns recent finished matches with server and player info
 func (s *Store) GetRecentMatchSummaries(ctx context.Context, limit int) ([]domain.MatchSummary, error) {
 	// Get finished matches that have at least one player
-	rows, err := s.db.QueryContext(ctx, `
+	rows, err := s.conn(ctx).QueryContext(ctx, `
 		SELECT DISTINCT
 			m.id, m.uuid, m.server_id, s.key, s.active, s.source, m.map_name, m.game_type, m.started_at, m.ended_at, m.exit_reason,
-			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available
+			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available, m.abandoned, m.overtime_seconds
 		FROM matches m
 		JOIN servers s ON m.server_id = s.id
 		JOIN match_player_stats mps ON m.id = mps.match_id
@@ -1780,7 +2009,7 @@ func (s *Store) GetPlayerRecentMatches(ctx context.Context, playerID int64, limi
 	query := `
 		SELECT DISTINCT
 			m.id, m.uuid, m.server_id, s.key, s.active, s.source, m.map_name, m.game_type, m.started_at, m.ended_at, m.exit_reason,
-			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available
+			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available, m.abandoned, m.overtime_seconds
 		FROM matches m
 		JOIN servers s ON m.server_id = s.id
 		JOIN match_player_stats mps ON m.id = mps.match_id
@@ -1797,7 +2026,7 @@ func (s *Store) GetPlayerRecentMatches(ctx context.Context, playerID int64, limi
 	query += ` ORDER BY m.ended_at DESC LIMIT ?`
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1857,7 +2086,7 @@ func (s *Store) CreateLinkCode(ctx context.Context, userID, playerID int64, expi
 			return nil, fmt.Errorf("generating code: %w", err)
 		}
 
-		result, err := s.db.ExecContext(ctx, `
+		result, err := s.conn(ctx).ExecContext(ctx, `
 			INSERT INTO link_codes (code, user_id, player_id, expires_at)
 			VALUES (?, ?, ?, ?)
 		`, code, userID, playerID, expiresAt.UTC().Format("2006-01-02 15:04:05"))
@@ -1882,7 +2111,7 @@ func (s *Store) CreateLinkCode(ctx context.Context, userID, playerID int64, expi
 // GetValidLinkCode retrieves a valid (unexpired, unused) link code (user-initiated, user_id IS NOT NULL)
 func (s *Store) GetValidLinkCode(ctx context.Context, code string) (*LinkCode, error) {
 	var lc LinkCode
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, code, user_id, player_id, created_at, expires_at
 		FROM link_codes
 		WHERE code = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND user_id IS NOT NULL
@@ -1895,7 +2124,7 @@ func (s *Store) GetValidLinkCode(ctx context.Context, code string) (*LinkCode, e
 
 // MarkLinkCodeUsed marks a link code as used (atomically)
 func (s *Store) MarkLinkCodeUsed(ctx context.Context, codeID int64, usedByGUID string) error {
-	result, err := s.db.ExecContext(ctx, `
+	result, err := s.conn(ctx).ExecContext(ctx, `
 		UPDATE link_codes
 		SET used_at = CURRENT_TIMESTAMP, used_by_guid = ?
 		WHERE id = ? AND used_at IS NULL
@@ -1912,7 +2141,7 @@ func (s *Store) MarkLinkCodeUsed(ctx context.Context, codeID int64, usedByGUID s
 
 // CleanupExpiredLinkCodes removes expired codes
 func (s *Store) CleanupExpiredLinkCodes(ctx context.Context) (int64, error) {
-	result, err := s.db.ExecContext(ctx, `
+	result, err := s.conn(ctx).ExecContext(ctx, `
 		DELETE FROM link_codes WHERE expires_at < CURRENT_TIMESTAMP
 	`)
 	if err != nil {
@@ -1923,7 +2152,7 @@ func (s *Store) CleanupExpiredLinkCodes(ctx context.Context) (int64, error) {
 
 // InvalidateUserLinkCodes invalidates all pending codes for a user
 func (s *Store) InvalidateUserLinkCodes(ctx context.Context, userID int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		DELETE FROM link_codes WHERE user_id = ? AND used_at IS NULL
 	`, userID)
 	return err
@@ -1948,7 +2177,7 @@ func (s *Store) CreateClaimCode(ctx context.Context, playerID int64, expiresAt t
 			return nil, fmt.Errorf("generating code: %w", err)
 		}
 
-		result, err := s.db.ExecContext(ctx, `
+		result, err := s.conn(ctx).ExecContext(ctx, `
 			INSERT INTO link_codes (code, user_id, player_id, expires_at)
 			VALUES (?, NULL, ?, ?)
 		`, code, playerID, expiresAt.UTC().Format("2006-01-02 15:04:05"))
@@ -1972,7 +2201,7 @@ func (s *Store) CreateClaimCode(ctx context.Context, playerID int64, expiresAt t
 // GetValidClaimCode retrieves a valid claim code (user_id IS NULL)
 func (s *Store) GetValidClaimCode(ctx context.Context, code string) (*ClaimCode, error) {
 	var cc ClaimCode
-	err := s.db.QueryRowContext(ctx, `
+	err := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT id, code, player_id, created_at, expires_at
 		FROM link_codes
 		WHERE code = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND user_id IS NULL
@@ -1985,7 +2214,7 @@ func (s *Store) GetValidClaimCode(ctx context.Context, code string) (*ClaimCode,
 
 // InvalidatePlayerClaimCodes deletes all pending claim codes for a player
 func (s *Store) InvalidatePlayerClaimCodes(ctx context.Context, playerID int64) error {
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.conn(ctx).ExecContext(ctx, `
 		DELETE FROM link_codes WHERE player_id = ? AND user_id IS NULL AND used_at IS NULL
 	`, playerID)
 	return err
@@ -1994,7 +2223,7 @@ func (s *Store) InvalidatePlayerClaimCodes(ctx context.Context, playerID int64)
 // ClaimRegister creates a new user account and links the player, marking the claim code as used.
 // Returns the new user ID.
 func (s *Store) ClaimRegister(ctx context.Context, codeID, playerID int64, username, passwordHash string) (int64, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -2039,7 +2268,7 @@ func (s *Store) ClaimRegister(ctx context.Context, codeID, playerID int64, usern
 // ClaimLink links a claim code's player to an existing user account, marking the code as used.
 // If the user already has a player, the claim player is merged into it.
 func (s *Store) ClaimLink(ctx context.Context, codeID, claimPlayerID, userID int64) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -2113,11 +2342,12 @@ func (s *Store) ClaimLink(ctx context.Context, codeID, claimPlayerID, userID int
 	return tx.Commit()
 }
 
-// GetMatchSummaryByID returns a single match by ID with all player stats
+// GetMatchSummaryByID returns a single match by ID with all player
+// stats and the rollups the match page shows (see AddRollups).
 func (s *Store) GetMatchSummaryByID(ctx context.Context, matchID int64) (*domain.MatchSummary, error) {
-	row := s.db.QueryRowContext(ctx, `
+	row := s.conn(ctx).QueryRowContext(ctx, `
 		SELECT m.id, m.uuid, m.server_id, s.key, s.active, s.source, m.map_name, m.game_type, m.started_at, m.ended_at, m.exit_reason,
-		       m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available
+		       m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available, m.abandoned, m.overtime_seconds
 		FROM matches m
 		JOIN servers s ON m.server_id = s.id
 		WHERE m.id = ?
@@ -2129,10 +2359,12 @@ func (s *Store) GetMatchSummaryByID(ctx context.Context, matchID int64) (*domain
 	}
 
 	// Get player stats for this match
-	playerRows, err := s.db.QueryContext(ctx, `
-		SELECT p.id, pg.name, pg.clean_name, mps.frags, mps.deaths, mps.completed, p.is_bot, mps.skill, mps.score, mps.team, mps.model, mps.impressives, mps.excellents, mps.humiliations, mps.defends, mps.victories, mps.captures, mps.assists, mps.is_vr,
+	playerRows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, pg.name, pg.clean_name, mps.frags, mps.deaths, mps.completed, p.is_bot, mps.skill, mps.score, mps.team, mps.model, mps.impressives, mps.excellents, mps.humiliations, mps.defends, mps.victories, mps.captures, mps.assists, COALESCE(mps.skulls_scored, 0), COALESCE(mps.obelisk_destroys, 0), mps.is_vr,
 			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
-			COALESCE(u.is_admin, 0) as is_admin
+			COALESCE(u.is_admin, 0) as is_admin,
+			EXISTS(SELECT 1 FROM match_stat_corrections c
+				WHERE c.match_id = mps.match_id AND c.player_guid_id = mps.player_guid_id AND c.client_id = mps.client_id) as corrected
 		FROM match_player_stats mps
 		JOIN player_guids pg ON mps.player_guid_id = pg.id
 		JOIN players p ON pg.player_id = p.id
@@ -2155,10 +2387,46 @@ func (s *Store) GetMatchSummaryByID(ctx context.Context, matchID int64) (*domain
 	if err := playerRows.Err(); err != nil {
 		return nil, err
 	}
+	if m.Scoreboard, err = s.getScoreboard(ctx, matchID); err != nil {
+		return nil, err
+	}
+	if m.Warmup, err = s.getWarmup(ctx, matchID); err != nil {
+		return nil, err
+	}
 
+	m.AddRollups()
 	return m, nil
 }
 
+// getScoreboard returns a match's final scoreboard, or nil if none was
+// saved.
+func (s *Store) getScoreboard(ctx context.Context, matchID int64) ([]domain.ScoreboardLine, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT l.value ->> '$.client_id', pg.player_id, l.value ->> '$.name',
+			l.value ->> '$.score', l.value ->> '$.ping', l.value ->> '$.team'
+		FROM matches m, json_each(m.scoreboard) l
+		LEFT JOIN player_guids pg ON pg.id = l.value ->> '$.player_guid_id'
+		WHERE m.id = ?
+		ORDER BY l.key
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []domain.ScoreboardLine
+	for rows.Next() {
+		var l domain.ScoreboardLine
+		var playerID sql.NullInt64
+		if err := rows.Scan(&l.ClientID, &playerID, &l.Name, &l.Score, &l.Ping, &l.Team); err != nil {
+			return nil, err
+		}
+		l.PlayerID = scanNullInt64Ptr(playerID)
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
 // MatchFilter defines filters for querying matches
 type MatchFilter struct {
 	GameType       string
@@ -2169,7 +2437,8 @@ type MatchFilter struct {
 	EndDate        *time.Time
 	BeforeID       *int64
 	Limit          int
-	IncludeBotOnly bool // when false, filter to has_human_player = TRUE
+	IncludeBotOnly bool  // when false, filter to has_human_player = TRUE
+	Overtime       *bool // only matches that did, or didn't, go to overtime; nil = any
 }
 
 // GetFilteredMatchSummaries returns matches filtered by the given criteria
@@ -2181,7 +2450,7 @@ func (s *Store) GetFilteredMatchSummaries(ctx context.Context, filter MatchFilte
 	query := `
 		SELECT DISTINCT
 			m.id, m.uuid, m.server_id, s.key, s.active, s.source, m.map_name, m.game_type, m.started_at, m.ended_at, m.exit_reason,
-			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available
+			m.red_score, m.blue_score, m.movement, m.gameplay, m.demo_available, m.abandoned, m.overtime_seconds
 		FROM matches m
 		JOIN servers s ON m.server_id = s.id
 		JOIN match_player_stats mps ON m.id = mps.match_id
@@ -2220,11 +2489,18 @@ func (s *Store) GetFilteredMatchSummaries(ctx context.Context, filter MatchFilte
 	if !filter.IncludeBotOnly {
 		query += ` AND m.has_human_player = TRUE`
 	}
+	if filter.Overtime != nil {
+		if *filter.Overtime {
+			query += ` AND m.overtime_seconds IS NOT NULL`
+		} else {
+			query += ` AND m.overtime_seconds IS NULL`
+		}
+	}
 
 	query += ` ORDER BY m.ended_at DESC LIMIT ?`
 	args = append(args, filter.Limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -2254,7 +2530,8 @@ func (s *Store) GetPlayerSessions(ctx context.Context, playerID int64, limit int
 	}
 
 	query := `
-		SELECT s.id, s.server_id, srv.source, srv.key, s.joined_at, s.left_at, s.duration_seconds, s.ip_address, s.client_engine, s.client_version
+		SELECT s.id, s.server_id, srv.source, srv.key, s.joined_at, s.left_at, s.duration_seconds, s.ip_address, s.client_engine, s.client_version,
+		       s.country_code, s.country, s.city
 		FROM sessions s
 		JOIN player_guids pg ON s.player_guid_id = pg.id
 		JOIN servers srv ON s.server_id = srv.id
@@ -2270,7 +2547,7 @@ func (s *Store) GetPlayerSessions(ctx context.Context, playerID int64, limit int
 	query += ` ORDER BY s.joined_at DESC LIMIT ?`
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -2282,7 +2559,9 @@ func (s *Store) GetPlayerSessions(ctx context.Context, playerID int64, limit int
 		var leftAt sql.NullTime
 		var durationSeconds sql.NullInt64
 		var ipAddress, clientEngine, clientVersion sql.NullString
-		if err := rows.Scan(&ps.ID, &ps.ServerID, &ps.ServerSource, &ps.ServerKey, &ps.JoinedAt, &leftAt, &durationSeconds, &ipAddress, &clientEngine, &clientVersion); err != nil {
+		var countryCode, country, city sql.NullString
+		if err := rows.Scan(&ps.ID, &ps.ServerID, &ps.ServerSource, &ps.ServerKey, &ps.JoinedAt, &leftAt, &durationSeconds, &ipAddress, &clientEngine, &clientVersion,
+			&countryCode, &country, &city); err != nil {
 			return nil, err
 		}
 		if leftAt.Valid {
@@ -2300,6 +2579,9 @@ func (s *Store) GetPlayerSessions(ctx context.Context, playerID int64, limit int
 		if clientVersion.Valid {
 			ps.ClientVersion = clientVersion.String
 		}
+		ps.CountryCode = countryCode.String
+		ps.Country = country.String
+		ps.City = city.String
 		sessions = append(sessions, ps)
 	}
 	return sessions, rows.Err()
@@ -2322,7 +2604,8 @@ func (s *Store) GetRecentSessions(ctx context.Context, filter SessionFilter, lim
 		SELECT s.id, s.server_id, srv.source, srv.key,
 		       p.id, p.name, p.clean_name,
 		       s.joined_at, s.left_at, s.duration_seconds,
-		       s.ip_address, s.client_engine, s.client_version
+		       s.ip_address, s.client_engine, s.client_version,
+		       s.country_code, s.country, s.city
 		FROM sessions s
 		JOIN player_guids pg ON s.player_guid_id = pg.id
 		JOIN players p ON pg.player_id = p.id
@@ -2347,7 +2630,7 @@ func (s *Store) GetRecentSessions(ctx context.Context, filter SessionFilter, lim
 	query += ` ORDER BY s.id DESC LIMIT ?`
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -2359,11 +2642,13 @@ func (s *Store) GetRecentSessions(ctx context.Context, filter SessionFilter, lim
 		var leftAt sql.NullTime
 		var durationSeconds sql.NullInt64
 		var ipAddress, clientEngine, clientVersion sql.NullString
+		var countryCode, country, city sql.NullString
 		if err := rows.Scan(
 			&as.ID, &as.ServerID, &as.ServerSource, &as.ServerKey,
 			&as.PlayerID, &as.PlayerName, &as.PlayerCleanName,
 			&as.JoinedAt, &leftAt, &durationSeconds,
 			&ipAddress, &clientEngine, &clientVersion,
+			&countryCode, &country, &city,
 		); err != nil {
 			return nil, err
 		}
@@ -2382,6 +2667,9 @@ func (s *Store) GetRecentSessions(ctx context.Context, filter SessionFilter, lim
 		if clientVersion.Valid {
 			as.ClientVersion = clientVersion.String
 		}
+		as.CountryCode = countryCode.String
+		as.Country = country.String
+		as.City = city.String
 		sessions = append(sessions, as)
 	}
 	return sessions, rows.Err()
@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// The anomaly analysis checks matches that ended within anomalyWindow.
+// Only matches with at least two humans are checked: farming bots
+// isn't cheating, and it's what most improbable numbers against bots
+// come from.
+const (
+	anomalyWindow = 7 * 24 * time.Hour
+
+	// A spike is judged against the player's own other matches, so
+	// they need anomalyMinMatches of them first.
+	anomalyMinMatches = 10
+
+	// accuracy_spike: at least anomalyMinShots with the weapon in the
+	// match, anomalyAccuracyJump over their usual accuracy with it,
+	// and anomalyAccuracyRatio times it. Their usual needs
+	// accuracyMinShots behind it.
+	anomalyMinShots       = 30
+	anomalyAccuracyJump   = 0.25
+	anomalyAccuracyRatio  = 1.75
+	anomalyMinCeilingShot = 20 // the fewest shots any accuracy ceiling needs
+
+	// kd_spike: at least anomalyMinFrags, a K/D of anomalyMinKD, and
+	// anomalyKDRatio times their usual.
+	anomalyMinFrags = 20
+	anomalyMinKD    = 3
+	anomalyKDRatio  = 4
+
+	// frag_rate: anomalyFragRate frags a minute over at least
+	// anomalyMinMinutes of play to the end of the match.
+	anomalyFragRate   = 6
+	anomalyMinMinutes = 5
+)
+
+// accuracyCeilings are hitscan accuracies top human play doesn't
+// reach over that many shots, for accuracy_extreme.
+var accuracyCeilings = map[string]struct {
+	accuracy float64
+	shots    int
+}{
+	"railgun":    {0.80, 20},
+	"lightning":  {0.60, 150},
+	"machinegun": {0.70, 150},
+}
+
+// anomalyHumans limits a query over matches m to those with at least
+// two humans.
+const anomalyHumans = `
+	(SELECT COUNT(DISTINCT h.player_guid_id)
+	 FROM match_player_stats h
+	 JOIN player_guids hg ON h.player_guid_id = hg.id
+	 JOIN players hp ON hg.player_id = hp.id
+	 WHERE h.match_id = m.id AND hp.is_bot = FALSE) >= 2`
+
+// statFlag is a flag found by one run, before it's stored.
+type statFlag struct {
+	matchID, guidID int64
+	kind, weapon    string
+	value           float64
+	baseline        *float64
+}
+
+// RefreshStatFlags runs the anomaly analysis over matches that ended
+// since now less anomalyWindow. Flags already raised, reviewed or not,
+// are left as they are. Returns how many flags are new.
+func (s *Store) RefreshStatFlags(ctx context.Context, now time.Time) (int, error) {
+	since := formatTimestamp(now.Add(-anomalyWindow))
+	var flags []statFlag
+
+	accuracy, err := s.accuracyFlags(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	flags = append(flags, accuracy...)
+
+	kd, err := s.kdFlags(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	flags = append(flags, kd...)
+
+	rate, err := s.fragRateFlags(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	flags = append(flags, rate...)
+
+	added := 0
+	for _, f := range flags {
+		var baseline any
+		if f.baseline != nil {
+			baseline = *f.baseline
+		}
+		res, err := s.conn(ctx).ExecContext(ctx, `
+			INSERT INTO stat_flags (match_id, player_guid_id, kind, weapon, value, baseline, detected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(match_id, player_guid_id, kind, weapon) DO NOTHING
+		`, f.matchID, f.guidID, f.kind, f.weapon, f.value, baseline, formatTimestamp(now))
+		if err != nil {
+			return added, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// accuracyFlags finds accuracy_extreme and accuracy_spike flags.
+func (s *Store) accuracyFlags(ctx context.Context, since string) ([]statFlag, error) {
+	type shots struct{ shots, hits int }
+	type playerWeapon struct {
+		playerID int64
+		weapon   string
+	}
+	// Every human's shots by weapon, to judge spikes against.
+	usual := make(map[playerWeapon]shots)
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT pg.player_id, wa.weapon, SUM(wa.shots), SUM(wa.hits)
+		FROM match_weapon_accuracy wa
+		JOIN player_guids pg ON wa.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE p.is_bot = FALSE
+		GROUP BY pg.player_id, wa.weapon
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var k playerWeapon
+		var n shots
+		if err := rows.Scan(&k.playerID, &k.weapon, &n.shots, &n.hits); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		usual[k] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.conn(ctx).QueryContext(ctx, `
+		SELECT wa.match_id, wa.player_guid_id, pg.player_id, wa.weapon, wa.shots, wa.hits
+		FROM match_weapon_accuracy wa
+		JOIN matches m ON wa.match_id = m.id
+		JOIN player_guids pg ON wa.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE m.ended_at >= ? AND p.is_bot = FALSE AND wa.shots >= ? AND`+anomalyHumans,
+		since, anomalyMinCeilingShot)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []statFlag
+	for rows.Next() {
+		var matchID, guidID, playerID int64
+		var weapon string
+		var n shots
+		if err := rows.Scan(&matchID, &guidID, &playerID, &weapon, &n.shots, &n.hits); err != nil {
+			return nil, err
+		}
+		acc := float64(n.hits) / float64(n.shots)
+		if c, ok := accuracyCeilings[weapon]; ok && n.shots >= c.shots && acc >= c.accuracy {
+			flags = append(flags, statFlag{matchID: matchID, guidID: guidID, kind: domain.StatFlagAccuracyExtreme, weapon: weapon, value: acc})
+		}
+		if n.shots < anomalyMinShots {
+			continue
+		}
+		other := usual[playerWeapon{playerID, weapon}]
+		other.shots -= n.shots
+		other.hits -= n.hits
+		if other.shots < accuracyMinShots {
+			continue
+		}
+		before := float64(other.hits) / float64(other.shots)
+		if acc >= before+anomalyAccuracyJump && acc >= before*anomalyAccuracyRatio {
+			flags = append(flags, statFlag{matchID: matchID, guidID: guidID, kind: domain.StatFlagAccuracySpike, weapon: weapon, value: acc, baseline: &before})
+		}
+	}
+	return flags, rows.Err()
+}
+
+// kdFlags finds kd_spike flags.
+func (s *Store) kdFlags(ctx context.Context, since string) ([]statFlag, error) {
+	type record struct{ matches, frags, deaths int }
+	usual := make(map[int64]record)
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT pg.player_id, COUNT(DISTINCT mps.match_id), SUM(mps.frags), SUM(mps.deaths)
+		FROM match_player_stats mps
+		JOIN matches m ON mps.match_id = m.id
+		JOIN player_guids pg ON mps.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE m.ended_at IS NOT NULL AND p.is_bot = FALSE
+		GROUP BY pg.player_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var r record
+		if err := rows.Scan(&id, &r.matches, &r.frags, &r.deaths); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		usual[id] = r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.conn(ctx).QueryContext(ctx, `
+		SELECT mps.match_id, mps.player_guid_id, pg.player_id, SUM(mps.frags), SUM(mps.deaths)
+		FROM match_player_stats mps
+		JOIN matches m ON mps.match_id = m.id
+		JOIN player_guids pg ON mps.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE m.ended_at >= ? AND p.is_bot = FALSE AND`+anomalyHumans+`
+		GROUP BY mps.match_id, mps.player_guid_id
+		HAVING SUM(mps.frags) >= ?
+	`, since, anomalyMinFrags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []statFlag
+	for rows.Next() {
+		var matchID, guidID, playerID int64
+		var frags, deaths int
+		if err := rows.Scan(&matchID, &guidID, &playerID, &frags, &deaths); err != nil {
+			return nil, err
+		}
+		other := usual[playerID]
+		other.matches--
+		other.frags -= frags
+		other.deaths -= deaths
+		if other.matches < anomalyMinMatches {
+			continue
+		}
+		kd := float64(frags) / float64(max(deaths, 1))
+		before := float64(other.frags) / float64(max(other.deaths, 1))
+		if kd >= anomalyMinKD && kd >= before*anomalyKDRatio {
+			flags = append(flags, statFlag{matchID: matchID, guidID: guidID, kind: domain.StatFlagKDSpike, value: kd, baseline: &before})
+		}
+	}
+	return flags, rows.Err()
+}
+
+// fragRateFlags finds frag_rate flags. Only the stint that played to
+// the end of the match counts, since earlier stints' ends aren't kept.
+func (s *Store) fragRateFlags(ctx context.Context, since string) ([]statFlag, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT mps.match_id, mps.player_guid_id, SUM(mps.frags),
+			SUM((julianday(m.ended_at) - julianday(COALESCE(mps.joined_at, m.started_at))) * 1440) AS minutes
+		FROM match_player_stats mps
+		JOIN matches m ON mps.match_id = m.id
+		JOIN player_guids pg ON mps.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		WHERE m.ended_at >= ? AND p.is_bot = FALSE AND mps.completed = TRUE AND`+anomalyHumans+`
+		GROUP BY mps.match_id, mps.player_guid_id
+		HAVING minutes >= ?
+	`, since, anomalyMinMinutes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []statFlag
+	for rows.Next() {
+		var f statFlag
+		var frags int
+		var minutes float64
+		if err := rows.Scan(&f.matchID, &f.guidID, &frags, &minutes); err != nil {
+			return nil, err
+		}
+		if rate := float64(frags) / minutes; rate >= anomalyFragRate {
+			f.kind, f.value = domain.StatFlagFragRate, rate
+			flags = append(flags, f)
+		}
+	}
+	return flags, rows.Err()
+}
+
+// StatFlagFilter narrows ListStatFlags. Status is "open" (the
+// default), "reviewed", or "all"; PlayerID and Kind are ignored when
+// zero.
+type StatFlagFilter struct {
+	Status   string
+	PlayerID int64
+	Kind     string
+	Limit    int
+}
+
+const statFlagColumns = `
+	sf.id, sf.kind, pg.player_id, p.name, sf.match_id, m.map_name, m.started_at,
+	sf.weapon, sf.value, sf.baseline, sf.detected_at, sf.verdict, sf.note, sf.reviewed_by, sf.reviewed_at
+	FROM stat_flags sf
+	JOIN player_guids pg ON sf.player_guid_id = pg.id
+	JOIN players p ON pg.player_id = p.id
+	JOIN matches m ON sf.match_id = m.id`
+
+// ListStatFlags returns flags newest first.
+func (s *Store) ListStatFlags(ctx context.Context, f StatFlagFilter) ([]domain.StatFlag, error) {
+	query := `SELECT` + statFlagColumns + `
+		WHERE 1 = 1`
+	var args []any
+	switch f.Status {
+	case "", "open":
+		query += ` AND sf.verdict IS NULL`
+	case "reviewed":
+		query += ` AND sf.verdict IS NOT NULL`
+	}
+	if f.PlayerID != 0 {
+		query += ` AND pg.player_id = ?`
+		args = append(args, f.PlayerID)
+	}
+	if f.Kind != "" {
+		query += ` AND sf.kind = ?`
+		args = append(args, f.Kind)
+	}
+	query += ` ORDER BY sf.detected_at DESC, sf.id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.StatFlag{}
+	for rows.Next() {
+		flag, err := scanStatFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *flag)
+	}
+	return out, rows.Err()
+}
+
+// ReviewStatFlag records an admin's verdict on a flag, replacing any
+// earlier one, and returns the flag. Returns sql.ErrNoRows if there's
+// no such flag.
+func (s *Store) ReviewStatFlag(ctx context.Context, id int64, verdict, note string, by int64, at time.Time) (*domain.StatFlag, error) {
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE stat_flags SET verdict = ?, note = ?, reviewed_by = ?, reviewed_at = ? WHERE id = ?
+	`, verdict, note, by, formatTimestamp(at), id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return scanStatFlag(s.conn(ctx).QueryRowContext(ctx, `SELECT`+statFlagColumns+` WHERE sf.id = ?`, id))
+}
+
+func scanStatFlag(row scanner) (*domain.StatFlag, error) {
+	var f domain.StatFlag
+	var baseline sql.NullFloat64
+	var verdict sql.NullString
+	var by sql.NullInt64
+	var reviewedAt sql.NullTime
+	if err := row.Scan(&f.ID, &f.Kind, &f.PlayerID, &f.PlayerName, &f.MatchID, &f.MapName, &f.StartedAt,
+		&f.Weapon, &f.Value, &baseline, &f.DetectedAt, &verdict, &f.Note, &by, &reviewedAt); err != nil {
+		return nil, err
+	}
+	f.Baseline = scanNullFloat64(baseline)
+	f.Verdict = scanNullStringValue(verdict)
+	f.ReviewedBy = scanNullInt64Ptr(by)
+	f.ReviewedAt = scanNullTime(reviewedAt)
+	return &f, nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestStatFlags(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 12, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// Ten ordinary matches, then one where alice rails at 90% with a
+	// K/D of 15 and bob frags 7 a minute.
+	for i := 0; i <= anomalyMinMatches; i++ {
+		last := i == anomalyMinMatches
+		m := &domain.Match{UUID: "match-" + string(rune('a'+i)), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa",
+			StartedAt: base.Add(time.Duration(i) * 30 * time.Minute)}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		aliceFrags, aliceDeaths, aliceHits, bobFrags, bobDeaths := 10, 10, 12, 10, 10
+		if last {
+			aliceFrags, aliceDeaths, aliceHits, bobFrags, bobDeaths = 30, 2, 36, 70, 30
+		}
+		end := domain.MatchEndData{MatchUUID: m.UUID, EndedAt: m.StartedAt.Add(10 * time.Minute), ExitReason: "Timelimit hit"}
+		if _, err := s.FlushMatch(ctx, m, end, func(tx *MatchTx) {
+			for _, p := range []struct {
+				id            int64
+				frags, deaths int
+			}{{alice.ID, aliceFrags, aliceDeaths}, {bob.ID, bobFrags, bobDeaths}} {
+				if err := tx.FlushMatchPlayerStats(ctx, m.ID, p.id, 0, p.frags, p.deaths, true, nil, nil, "", 0, false,
+					0, 0, 0, 0, 0, 0, 0, false, false, m.StartedAt, false); err != nil {
+					t.Fatalf("FlushMatchPlayerStats: %v", err)
+				}
+			}
+		}); err != nil {
+			t.Fatalf("FlushMatch: %v", err)
+		}
+		if err := s.RecordWeaponAccuracy(ctx, m.ID, alice.ID, map[string]domain.WeaponShots{"railgun": {Shots: 40, Hits: aliceHits}}); err != nil {
+			t.Fatalf("RecordWeaponAccuracy: %v", err)
+		}
+	}
+
+	now := base.Add(12 * time.Hour)
+	n, err := s.RefreshStatFlags(ctx, now)
+	if err != nil {
+		t.Fatalf("RefreshStatFlags: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("RefreshStatFlags = %d new, want 4", n)
+	}
+	if n, err = s.RefreshStatFlags(ctx, now); err != nil || n != 0 {
+		t.Errorf("second RefreshStatFlags = %d, %v; want 0", n, err)
+	}
+
+	flags, err := s.ListStatFlags(ctx, StatFlagFilter{Limit: 100})
+	if err != nil {
+		t.Fatalf("ListStatFlags: %v", err)
+	}
+	got := map[string]domain.StatFlag{}
+	for _, f := range flags {
+		got[f.Kind] = f
+	}
+	for kind, playerID := range map[string]int64{
+		domain.StatFlagAccuracyExtreme: alice.PlayerID,
+		domain.StatFlagAccuracySpike:   alice.PlayerID,
+		domain.StatFlagKDSpike:         alice.PlayerID,
+		domain.StatFlagFragRate:        bob.PlayerID,
+	} {
+		if f, ok := got[kind]; !ok || f.PlayerID != playerID {
+			t.Errorf("%s flag = %+v, want player %d", kind, f, playerID)
+		}
+	}
+	if f := got[domain.StatFlagAccuracySpike]; f.Weapon != "railgun" || f.Baseline == nil || *f.Baseline != 0.3 {
+		t.Errorf("accuracy_spike = %s %v, want railgun against 0.3", f.Weapon, f.Baseline)
+	}
+
+	admin := mustCreateUser(t, s, "admin")
+	reviewed, err := s.ReviewStatFlag(ctx, got[domain.StatFlagFragRate].ID, domain.StatFlagCleared, "bots left mid-match", admin, now)
+	if err != nil {
+		t.Fatalf("ReviewStatFlag: %v", err)
+	}
+	if reviewed.Verdict != domain.StatFlagCleared || reviewed.ReviewedBy == nil || *reviewed.ReviewedBy != admin {
+		t.Errorf("reviewed flag = %+v", reviewed)
+	}
+	for _, tc := range []struct {
+		filter StatFlagFilter
+		want   int
+	}{
+		{StatFlagFilter{}, 3},
+		{StatFlagFilter{Status: "reviewed"}, 1},
+		{StatFlagFilter{Status: "all"}, 4},
+		{StatFlagFilter{Status: "all", PlayerID: bob.PlayerID}, 1},
+		{StatFlagFilter{Kind: domain.StatFlagKDSpike}, 1},
+	} {
+		tc.filter.Limit = 100
+		flags, err := s.ListStatFlags(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("ListStatFlags: %v", err)
+		}
+		if len(flags) != tc.want {
+			t.Errorf("ListStatFlags(%+v) = %d flags, want %d", tc.filter, len(flags), tc.want)
+		}
+	}
+
+	if _, err := s.ReviewStatFlag(ctx, 9999, domain.StatFlagConfirmed, "", admin, now); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ReviewStatFlag(missing) = %v, want sql.ErrNoRows", err)
+	}
+}
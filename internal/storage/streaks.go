@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// RecordBestSpree raises the player's best spree for matchID to spree
+// if it beats what's stored. Call after FlushMatchPlayerStats so the
+// row exists; the match_end fact can carry several stints for one
+// GUID, so the larger always wins.
+func (s *Store) RecordBestSpree(ctx context.Context, matchID, playerGUIDID int64, clientID, spree int) error {
+	return recordBestSpree(ctx, s.conn(ctx), matchID, playerGUIDID, clientID, spree)
+}
+
+func recordBestSpree(ctx context.Context, db execer, matchID, playerGUIDID int64, clientID, spree int) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE match_player_stats SET best_spree = MAX(COALESCE(best_spree, 0), ?)
+		WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+	`, spree, matchID, playerGUIDID, clientID)
+	return err
+}
+
+// longestWinStreak returns the most consecutive completed matches the
+// player won among those started in [start, end).
+func (s *Store) longestWinStreak(ctx context.Context, playerID int64, start, end time.Time) (int64, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT MAX(mps.victories > 0)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		JOIN matches m ON m.id = mps.match_id
+		WHERE pg.player_id = ? AND mps.completed = TRUE AND m.ended_at IS NOT NULL
+		  AND m.started_at >= ? AND m.started_at < ?
+		GROUP BY m.id
+		ORDER BY m.ended_at, m.id
+	`, playerID, formatTimestamp(start), formatTimestamp(end))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var run, best int64
+	for rows.Next() {
+		var won bool
+		if err := rows.Scan(&won); err != nil {
+			return 0, err
+		}
+		if !won {
+			run = 0
+			continue
+		}
+		run++
+		best = max(best, run)
+	}
+	return best, rows.Err()
+}
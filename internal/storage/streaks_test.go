@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestSpreesAndWinStreaks(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	// Win, win, loss, win, win, win: the longest run is the last three.
+	results := []bool{true, true, false, true, true, true}
+	for i, won := range results {
+		started := base.Add(time.Duration(i) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, alice.ID, 0, 10, 2, true, nil, nil, "", 0, won,
+			0, 0, 0, 0, 0, 0, 0, false, false, started, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+		// Two stints in one match report separately; the longer counts.
+		for _, spree := range []int{i + 4, i + 2} {
+			if err := s.RecordBestSpree(ctx, m.ID, alice.ID, 0, spree); err != nil {
+				t.Fatalf("RecordBestSpree: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, started.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+	}
+
+	resp, err := s.GetPlayerStatsByID(ctx, alice.PlayerID, "all")
+	if err != nil {
+		t.Fatalf("GetPlayerStatsByID: %v", err)
+	}
+	if resp.Stats.BestSpree != 9 || resp.Stats.LongestWinStreak != 3 {
+		t.Errorf("stats: best_spree=%d longest_win_streak=%d, want 9 and 3", resp.Stats.BestSpree, resp.Stats.LongestWinStreak)
+	}
+
+	lb, err := s.GetLeaderboard(ctx, "sprees", "week", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(lb.Entries) != 1 || lb.Entries[0].BestSpree != 9 {
+		t.Errorf("sprees leaderboard: got %+v", lb.Entries)
+	}
+}
@@ -0,0 +1,20 @@
+package storage
+
+import "context"
+
+// RecordTeamArenaStats adds a player's skulls scored (Harvester) and
+// obelisks destroyed (Overload) to their stats for matchID. Call after
+// FlushMatchPlayerStats so the row exists.
+func (s *Store) RecordTeamArenaStats(ctx context.Context, matchID, playerGUIDID int64, clientID, skulls, obelisks int) error {
+	return recordTeamArenaStats(ctx, s.conn(ctx), matchID, playerGUIDID, clientID, skulls, obelisks)
+}
+
+func recordTeamArenaStats(ctx context.Context, db execer, matchID, playerGUIDID int64, clientID, skulls, obelisks int) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE match_player_stats SET
+			skulls_scored = COALESCE(skulls_scored, 0) + ?,
+			obelisk_destroys = COALESCE(obelisk_destroys, 0) + ?
+		WHERE match_id = ? AND player_guid_id = ? AND client_id = ?
+	`, skulls, obelisks, matchID, playerGUIDID, clientID)
+	return err
+}
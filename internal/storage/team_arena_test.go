@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestTeamArenaStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "harv", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "mpteam6", GameType: "harvester", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+	red, blue := domain.TeamRed, domain.TeamBlue
+	for clientID, pg := range map[int]*domain.PlayerGUID{0: alice, 1: bob} {
+		team := &red
+		if pg == bob {
+			team = &blue
+		}
+		if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, clientID, 5, 3, true, nil, team, "", 0, false,
+			0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+			t.Fatalf("FlushMatchPlayerStats: %v", err)
+		}
+	}
+	// Repeated calls add up.
+	for _, n := range []int{4, 3} {
+		if err := s.RecordTeamArenaStats(ctx, m.ID, alice.ID, 0, n, 0); err != nil {
+			t.Fatalf("RecordTeamArenaStats: %v", err)
+		}
+	}
+	if err := s.RecordTeamArenaStats(ctx, m.ID, bob.ID, 1, 2, 1); err != nil {
+		t.Fatalf("RecordTeamArenaStats: %v", err)
+	}
+	if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "capturelimit", &red, &blue); err != nil {
+		t.Fatalf("EndMatch: %v", err)
+	}
+
+	summary, err := s.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	summary.AddRollups()
+	if len(summary.Teams) != 2 || summary.Teams[0].SkullsScored != 7 || summary.Teams[1].SkullsScored != 2 || summary.Teams[1].ObeliskDestroys != 1 {
+		t.Errorf("teams: got %+v", summary.Teams)
+	}
+
+	resp, err := s.GetPlayerStatsByID(ctx, alice.PlayerID, "all")
+	if err != nil {
+		t.Fatalf("GetPlayerStatsByID: %v", err)
+	}
+	if resp.Stats.SkullsScored != 7 || resp.Stats.ObeliskDestroys != 0 {
+		t.Errorf("alice stats: got %+v", resp.Stats)
+	}
+}
@@ -0,0 +1,839 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+var (
+	ErrTournamentClosed        = errors.New("tournament is not open for sign-ups")
+	ErrTournamentNotStarted    = errors.New("tournament has not started")
+	ErrAlreadySignedUp         = errors.New("player is already signed up")
+	ErrNotSignedUp             = errors.New("player is not signed up")
+	ErrTooFewEntrants          = errors.New("a tournament needs at least two entrants")
+	ErrInvalidTournamentResult = errors.New("winner must be one of the pairing's players, or a pickup's team 1 or 2")
+	ErrTournamentResultLocked  = errors.New("the next round's pairing has already been decided")
+)
+
+// tournamentColumns selects a domain.Tournament for scanTournament.
+const tournamentColumns = `t.id, t.name, t.format, t.game_type, t.map_pool, t.starts_at, t.ends_at, t.status,
+	t.winner_id, t.winning_team, t.created_by, t.created_at,
+	(SELECT COUNT(*) FROM tournament_entrants e WHERE e.tournament_id = t.id)`
+
+func scanTournament(s scanner) (domain.Tournament, error) {
+	var t domain.Tournament
+	var mapPool string
+	var winnerID, winningTeam, createdBy sql.NullInt64
+	if err := s.Scan(&t.ID, &t.Name, &t.Format, &t.GameType, &mapPool, &t.StartsAt, &t.EndsAt, &t.Status,
+		&winnerID, &winningTeam, &createdBy, &t.CreatedAt, &t.EntrantCount); err != nil {
+		return t, err
+	}
+	t.WinnerID = scanNullInt64Ptr(winnerID)
+	t.WinningTeam = scanNullInt64ToIntPtr(winningTeam)
+	t.CreatedBy = scanNullInt64Ptr(createdBy)
+	if err := json.Unmarshal([]byte(mapPool), &t.MapPool); err != nil {
+		return t, fmt.Errorf("tournament %d map pool: %w", t.ID, err)
+	}
+	return t, nil
+}
+
+// CreateTournament inserts t, open for sign-ups, filling in t.ID,
+// t.Status and t.CreatedAt.
+func (s *Store) CreateTournament(ctx context.Context, t *domain.Tournament) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	if t.MapPool == nil {
+		t.MapPool = []string{}
+	}
+	t.Status = domain.TournamentSignup
+	pool, err := json.Marshal(t.MapPool)
+	if err != nil {
+		return err
+	}
+	res, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO tournaments (name, format, game_type, map_pool, starts_at, ends_at, status, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.Name, t.Format, t.GameType, string(pool), formatTimestamp(t.StartsAt), formatTimestamp(t.EndsAt),
+		t.Status, t.CreatedBy, formatTimestamp(t.CreatedAt))
+	if err != nil {
+		return err
+	}
+	t.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetTournament returns the tournament, or nil if it doesn't exist.
+func (s *Store) GetTournament(ctx context.Context, id int64) (*domain.Tournament, error) {
+	t, err := scanTournament(s.conn(ctx).QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTournaments returns tournaments, latest start first. A non-empty
+// status keeps only those in it.
+func (s *Store) ListTournaments(ctx context.Context, status string) ([]domain.Tournament, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT `+tournamentColumns+` FROM tournaments t
+		WHERE ? = '' OR t.status = ?
+		ORDER BY t.starts_at DESC, t.id DESC
+	`, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.Tournament{}
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// DeleteTournament removes the tournament, its sign-ups and pairings.
+// Returns sql.ErrNoRows if it doesn't exist.
+func (s *Store) DeleteTournament(ctx context.Context, id int64) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM tournaments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// tournamentStatus returns the tournament's status, or sql.ErrNoRows.
+func tournamentStatus(ctx context.Context, db querier, id int64) (string, error) {
+	var status string
+	err := db.QueryRowContext(ctx, `SELECT status FROM tournaments WHERE id = ?`, id).Scan(&status)
+	return status, err
+}
+
+// SignUpForTournament enters the player, seeded after everyone already
+// signed up. Fails with ErrTournamentClosed once the tournament has
+// started, and sql.ErrNoRows if it doesn't exist.
+func (s *Store) SignUpForTournament(ctx context.Context, id, playerID int64, at time.Time) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := tournamentStatus(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if status != domain.TournamentSignup {
+		return ErrTournamentClosed
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tournament_entrants (tournament_id, player_id, seed, signed_up_at)
+		SELECT ?, ?, COALESCE(MAX(seed), 0) + 1, ? FROM tournament_entrants WHERE tournament_id = ?
+	`, id, playerID, formatTimestamp(at), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			return ErrAlreadySignedUp
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithdrawFromTournament takes the player out before the tournament
+// starts. Fails with ErrTournamentClosed once it has, and
+// ErrNotSignedUp if they weren't entered.
+func (s *Store) WithdrawFromTournament(ctx context.Context, id, playerID int64) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := tournamentStatus(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if status != domain.TournamentSignup {
+		return ErrTournamentClosed
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM tournament_entrants WHERE tournament_id = ? AND player_id = ?`, id, playerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotSignedUp
+	}
+	return tx.Commit()
+}
+
+// GetTournamentDetails returns the tournament with its entrants and
+// pairings, and a round robin's standings, or nil if it doesn't exist.
+func (s *Store) GetTournamentDetails(ctx context.Context, id int64) (*domain.TournamentResponse, error) {
+	t, err := s.GetTournament(ctx, id)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	entrants, err := s.tournamentEntrants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.tournamentMatches(ctx, id, entrants)
+	if err != nil {
+		return nil, err
+	}
+	resp := &domain.TournamentResponse{Tournament: *t, Entrants: entrants, Matches: matches}
+	if t.Format == domain.TournamentRoundRobin && t.Status != domain.TournamentSignup {
+		resp.Standings = roundRobinStandings(entrants, matches)
+	}
+	return resp, nil
+}
+
+func (s *Store) tournamentEntrants(ctx context.Context, id int64) ([]domain.TournamentEntrant, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END, COALESCE(u.is_admin, 0),
+			e.seed, e.team, e.signed_up_at
+		FROM tournament_entrants e
+		JOIN players p ON p.id = e.player_id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE e.tournament_id = ?
+		ORDER BY e.seed
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.TournamentEntrant{}
+	for rows.Next() {
+		var e domain.TournamentEntrant
+		var team sql.NullInt64
+		if err := rows.Scan(&e.Player.ID, &e.Player.Name, &e.Player.CleanName, &e.Player.FirstSeen, &e.Player.LastSeen,
+			&e.Player.IsBot, &e.Player.IsVR, &e.Player.IsVerified, &e.Player.IsAdmin,
+			&e.Seed, &team, &e.SignedUpAt); err != nil {
+			return nil, err
+		}
+		e.Team = scanNullInt64ToIntPtr(team)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// tournamentMatches returns the tournament's pairings in round and slot
+// order, their players filled in from entrants.
+func (s *Store) tournamentMatches(ctx context.Context, id int64, entrants []domain.TournamentEntrant) ([]domain.TournamentMatch, error) {
+	players := make(map[int64]domain.Player, len(entrants))
+	for _, e := range entrants {
+		players[e.Player.ID] = e.Player
+	}
+	player := func(id sql.NullInt64) *domain.Player {
+		if !id.Valid {
+			return nil
+		}
+		p, ok := players[id.Int64]
+		if !ok {
+			p = domain.Player{ID: id.Int64}
+		}
+		return &p
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, round, slot, map_name, player1_id, player2_id, winner_id, winning_team, match_id
+		FROM tournament_matches
+		WHERE tournament_id = ?
+		ORDER BY round, slot
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.TournamentMatch{}
+	for rows.Next() {
+		var m domain.TournamentMatch
+		var p1, p2, winnerID, winningTeam, matchID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.Round, &m.Slot, &m.MapName, &p1, &p2, &winnerID, &winningTeam, &matchID); err != nil {
+			return nil, err
+		}
+		m.Player1, m.Player2 = player(p1), player(p2)
+		m.WinnerID = scanNullInt64Ptr(winnerID)
+		m.WinningTeam = scanNullInt64ToIntPtr(winningTeam)
+		m.MatchID = scanNullInt64Ptr(matchID)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// roundRobinStandings tallies each entrant's decided pairings, most
+// wins first, then fewest losses, then seed.
+func roundRobinStandings(entrants []domain.TournamentEntrant, matches []domain.TournamentMatch) []domain.TournamentStanding {
+	out := make([]domain.TournamentStanding, len(entrants))
+	index := make(map[int64]int, len(entrants))
+	for i, e := range entrants {
+		out[i].Player = e.Player
+		index[e.Player.ID] = i
+	}
+	for _, m := range matches {
+		if m.WinnerID == nil || m.Player1 == nil || m.Player2 == nil {
+			continue
+		}
+		loser := m.Player1.ID
+		if loser == *m.WinnerID {
+			loser = m.Player2.ID
+		}
+		if i, ok := index[*m.WinnerID]; ok {
+			out[i].Wins++
+		}
+		if i, ok := index[loser]; ok {
+			out[i].Losses++
+		}
+	}
+	slices.SortStableFunc(out, func(a, b domain.TournamentStanding) int {
+		return cmp.Or(cmp.Compare(b.Wins, a.Wins), cmp.Compare(a.Losses, b.Losses))
+	})
+	return out
+}
+
+// pairing is a drawn tournament match. seed1 and seed2 are entrant
+// seeds, 0 for nobody: a bye, or a later round not yet known.
+type pairing struct {
+	round, slot  int
+	seed1, seed2 int
+}
+
+// singleEliminationBracket draws every round of a knockout for n
+// entrants. The field is padded to a power of two with byes, which go
+// to the top seeds, and seeds are placed so the top two can only meet
+// in the final.
+func singleEliminationBracket(n int) []pairing {
+	size := 2
+	for size < n {
+		size *= 2
+	}
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, s := range order {
+			next = append(next, s, len(order)*2+1-s)
+		}
+		order = next
+	}
+	var out []pairing
+	for i := 0; i < size/2; i++ {
+		p := pairing{round: 1, slot: i + 1, seed1: order[2*i], seed2: order[2*i+1]}
+		if p.seed2 > n {
+			p.seed2 = 0
+		}
+		out = append(out, p)
+	}
+	for round, slots := 2, size/4; slots >= 1; round, slots = round+1, slots/2 {
+		for slot := 1; slot <= slots; slot++ {
+			out = append(out, pairing{round: round, slot: slot})
+		}
+	}
+	return out
+}
+
+// roundRobinSchedule pairs each of n entrants with every other once, by
+// the circle method. With an odd n, one entrant sits out each round.
+func roundRobinSchedule(n int) []pairing {
+	seeds := make([]int, 0, n+1)
+	for s := 1; s <= n; s++ {
+		seeds = append(seeds, s)
+	}
+	if n%2 == 1 {
+		seeds = append(seeds, 0)
+	}
+	m := len(seeds)
+	var out []pairing
+	for round := 1; round < m; round++ {
+		slot := 0
+		for i := 0; i < m/2; i++ {
+			a, b := seeds[i], seeds[m-1-i]
+			if a == 0 || b == 0 {
+				continue
+			}
+			slot++
+			out = append(out, pairing{round: round, slot: slot, seed1: a, seed2: b})
+		}
+		// Keep the first seat and rotate the rest one place.
+		seeds = append([]int{seeds[0], seeds[m-1]}, seeds[1:m-1]...)
+	}
+	return out
+}
+
+// snakeDraft assigns n players, strongest first, to teams 1 and 2 in a
+// 1-2-2-1 pattern.
+func snakeDraft(n int) []int {
+	teams := make([]int, n)
+	for i := range teams {
+		teams[i] = domain.TeamRed
+		if i%4 == 1 || i%4 == 2 {
+			teams[i] = domain.TeamBlue
+		}
+	}
+	return teams
+}
+
+// StartTournament closes sign-ups and draws the tournament. Seeds are
+// renumbered in sign-up order. Bracket byes are settled straight away.
+// A pickup's entrants are drafted into two teams by all-time K/D.
+// Fails with ErrTournamentClosed if it has already started, and
+// ErrTooFewEntrants with fewer than two entrants.
+func (s *Store) StartTournament(ctx context.Context, id int64) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTournament(tx.QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = ?`, id))
+	if err != nil {
+		return err
+	}
+	if t.Status != domain.TournamentSignup {
+		return ErrTournamentClosed
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT e.player_id, COALESCE(SUM(mps.frags), 0), COALESCE(SUM(mps.deaths), 0)
+		FROM tournament_entrants e
+		LEFT JOIN player_guids pg ON pg.player_id = e.player_id
+		LEFT JOIN match_player_stats mps ON mps.player_guid_id = pg.id
+		WHERE e.tournament_id = ?
+		GROUP BY e.player_id
+		ORDER BY e.seed
+	`, id)
+	if err != nil {
+		return err
+	}
+	type entrant struct {
+		playerID int64
+		kd       float64
+	}
+	var entrants []entrant
+	for rows.Next() {
+		var e entrant
+		var frags, deaths int64
+		if err := rows.Scan(&e.playerID, &frags, &deaths); err != nil {
+			rows.Close()
+			return err
+		}
+		e.kd = kdRatio(frags, deaths)
+		entrants = append(entrants, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(entrants) < 2 {
+		return ErrTooFewEntrants
+	}
+	for i, e := range entrants {
+		if _, err := tx.ExecContext(ctx, `UPDATE tournament_entrants SET seed = ? WHERE tournament_id = ? AND player_id = ?`,
+			i+1, id, e.playerID); err != nil {
+			return err
+		}
+	}
+
+	var pairings []pairing
+	switch t.Format {
+	case domain.TournamentSingleElimination:
+		pairings = singleEliminationBracket(len(entrants))
+	case domain.TournamentRoundRobin:
+		pairings = roundRobinSchedule(len(entrants))
+	case domain.TournamentPickup:
+		pairings = []pairing{{round: 1, slot: 1}}
+		drafted := slices.Clone(entrants)
+		slices.SortStableFunc(drafted, func(a, b entrant) int { return cmp.Compare(b.kd, a.kd) })
+		for i, team := range snakeDraft(len(drafted)) {
+			if _, err := tx.ExecContext(ctx, `UPDATE tournament_entrants SET team = ? WHERE tournament_id = ? AND player_id = ?`,
+				team, id, drafted[i].playerID); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown tournament format %q", t.Format)
+	}
+
+	seedPlayer := func(seed int) *int64 {
+		if seed == 0 {
+			return nil
+		}
+		return &entrants[seed-1].playerID
+	}
+	for _, p := range pairings {
+		mapName := ""
+		if len(t.MapPool) > 0 {
+			mapName = t.MapPool[(p.round-1)%len(t.MapPool)]
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO tournament_matches (tournament_id, round, slot, map_name, player1_id, player2_id)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, id, p.round, p.slot, mapName, seedPlayer(p.seed1), seedPlayer(p.seed2)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE tournaments SET status = ? WHERE id = ?`, domain.TournamentInProgress, id); err != nil {
+		return err
+	}
+	t.Status = domain.TournamentInProgress
+
+	for _, p := range pairings {
+		if p.round == 1 && p.seed1 != 0 && p.seed2 == 0 && t.Format == domain.TournamentSingleElimination {
+			tm, err := getTournamentPairing(ctx, tx, id, p.round, p.slot)
+			if err != nil {
+				return err
+			}
+			if err := settleTournamentPairing(ctx, tx, &t, tm, tm.player1, nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// tournamentPairing is a tournament_matches row as settling needs it.
+type tournamentPairing struct {
+	id, tournamentID int64
+	round, slot      int
+	player1, player2 *int64
+	decided          bool
+}
+
+const tournamentPairingColumns = `id, tournament_id, round, slot, player1_id, player2_id,
+	winner_id IS NOT NULL OR winning_team IS NOT NULL`
+
+func scanTournamentPairing(s scanner) (*tournamentPairing, error) {
+	var p tournamentPairing
+	var p1, p2 sql.NullInt64
+	if err := s.Scan(&p.id, &p.tournamentID, &p.round, &p.slot, &p1, &p2, &p.decided); err != nil {
+		return nil, err
+	}
+	p.player1, p.player2 = scanNullInt64Ptr(p1), scanNullInt64Ptr(p2)
+	return &p, nil
+}
+
+func getTournamentPairing(ctx context.Context, db querier, tournamentID int64, round, slot int) (*tournamentPairing, error) {
+	return scanTournamentPairing(db.QueryRowContext(ctx, `
+		SELECT `+tournamentPairingColumns+` FROM tournament_matches WHERE tournament_id = ? AND round = ? AND slot = ?
+	`, tournamentID, round, slot))
+}
+
+// SetTournamentResult records a pairing's result by hand: winnerID for
+// a bracket pairing, winningTeam for a pickup, and optionally the game
+// that settled it. A result can be corrected until the pairing the
+// winner went on to has been decided. Fails with
+// ErrTournamentNotStarted before the draw, and sql.ErrNoRows if the
+// pairing isn't in the tournament.
+func (s *Store) SetTournamentResult(ctx context.Context, tournamentID, pairingID int64, winnerID *int64, winningTeam *int, matchID *int64) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTournament(tx.QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = ?`, tournamentID))
+	if err != nil {
+		return err
+	}
+	if t.Status == domain.TournamentSignup {
+		return ErrTournamentNotStarted
+	}
+	tm, err := scanTournamentPairing(tx.QueryRowContext(ctx, `
+		SELECT `+tournamentPairingColumns+` FROM tournament_matches WHERE id = ? AND tournament_id = ?
+	`, pairingID, tournamentID))
+	if err != nil {
+		return err
+	}
+	if err := settleTournamentPairing(ctx, tx, &t, tm, winnerID, winningTeam, matchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// settleTournamentPairing records tm's result, moves a knockout winner
+// on to the next round, and completes the tournament once its last
+// result is in.
+func settleTournamentPairing(ctx context.Context, tx txn, t *domain.Tournament, tm *tournamentPairing, winnerID *int64, winningTeam *int, matchID *int64) error {
+	if t.Format == domain.TournamentPickup {
+		if winningTeam == nil || (*winningTeam != domain.TeamRed && *winningTeam != domain.TeamBlue) {
+			return ErrInvalidTournamentResult
+		}
+		winnerID = nil
+	} else {
+		if winnerID == nil || tm.player1 == nil ||
+			(*winnerID != *tm.player1 && (tm.player2 == nil || *winnerID != *tm.player2)) {
+			return ErrInvalidTournamentResult
+		}
+		winningTeam = nil
+	}
+
+	var next *tournamentPairing
+	if t.Format == domain.TournamentSingleElimination {
+		var err error
+		next, err = getTournamentPairing(ctx, tx, t.ID, tm.round+1, (tm.slot+1)/2)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if next != nil && next.decided {
+			return ErrTournamentResultLocked
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tournament_matches SET winner_id = ?, winning_team = ?, match_id = ? WHERE id = ?
+	`, winnerID, winningTeam, matchID, tm.id); err != nil {
+		return err
+	}
+	if next != nil {
+		column := "player1_id"
+		if tm.slot%2 == 0 {
+			column = "player2_id"
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE tournament_matches SET `+column+` = ? WHERE id = ?`, *winnerID, next.id)
+		return err
+	}
+
+	var undecided int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tournament_matches
+		WHERE tournament_id = ? AND winner_id IS NULL AND winning_team IS NULL
+	`, t.ID).Scan(&undecided); err != nil {
+		return err
+	}
+	if undecided > 0 {
+		return nil
+	}
+	if t.Format == domain.TournamentRoundRobin {
+		// Most wins takes it, the higher seed on a tie.
+		if err := tx.QueryRowContext(ctx, `
+			SELECT tm.winner_id FROM tournament_matches tm
+			JOIN tournament_entrants e ON e.tournament_id = tm.tournament_id AND e.player_id = tm.winner_id
+			WHERE tm.tournament_id = ?
+			GROUP BY tm.winner_id
+			ORDER BY COUNT(*) DESC, MIN(e.seed)
+			LIMIT 1
+		`, t.ID).Scan(&winnerID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE tournaments SET status = ?, winner_id = ?, winning_team = ? WHERE id = ?
+	`, domain.TournamentCompleted, winnerID, winningTeam, t.ID)
+	return err
+}
+
+// tournamentParticipant is a player's part in a finished game.
+type tournamentParticipant struct {
+	team      int
+	frags     int
+	won       bool
+	completed bool
+}
+
+// AttachTournamentMatch settles the first open pairing the finished
+// game decides, if any, and returns its tournament and pairing ids. A
+// game counts for a tournament in progress on the same gametype whose
+// window it started in. A bracket pairing needs both players to have
+// finished the game, and goes to the winner or, failing that, the one
+// with more frags. A pickup needs at least half of each drafted team
+// present, mostly on opposite sides. Draws settle nothing.
+func (s *Store) AttachTournamentMatch(ctx context.Context, matchID int64) (tournamentID, pairingID int64, err error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var startedAt time.Time
+	var gameType string
+	var redScore, blueScore sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT started_at, game_type, red_score, blue_score FROM matches
+		WHERE id = ? AND ended_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM tournament_matches WHERE match_id = matches.id)
+	`, matchID).Scan(&startedAt, &gameType, &redScore, &blueScore)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT pg.player_id, COALESCE(MAX(mps.team), 0), SUM(mps.frags), MAX(mps.victories > 0), MAX(mps.completed)
+		FROM match_player_stats mps
+		JOIN player_guids pg ON pg.id = mps.player_guid_id
+		WHERE mps.match_id = ?
+		GROUP BY pg.player_id
+	`, matchID)
+	if err != nil {
+		return 0, 0, err
+	}
+	participants := make(map[int64]tournamentParticipant)
+	for rows.Next() {
+		var id int64
+		var p tournamentParticipant
+		if err := rows.Scan(&id, &p.team, &p.frags, &p.won, &p.completed); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		participants[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT tm.id, tm.tournament_id, tm.round, tm.slot, tm.player1_id, tm.player2_id, FALSE
+		FROM tournament_matches tm
+		JOIN tournaments t ON t.id = tm.tournament_id
+		WHERE t.status = ? AND t.game_type = ? AND t.starts_at <= ? AND t.ends_at >= ?
+		  AND tm.winner_id IS NULL AND tm.winning_team IS NULL AND tm.match_id IS NULL
+		ORDER BY t.starts_at, t.id, tm.round, tm.slot
+	`, domain.TournamentInProgress, gameType, formatTimestamp(startedAt), formatTimestamp(startedAt))
+	if err != nil {
+		return 0, 0, err
+	}
+	var open []*tournamentPairing
+	for rows.Next() {
+		tm, err := scanTournamentPairing(rows)
+		if err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		open = append(open, tm)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, tm := range open {
+		t, err := scanTournament(tx.QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = ?`, tm.tournamentID))
+		if err != nil {
+			return 0, 0, err
+		}
+		var winnerID *int64
+		var winningTeam *int
+		if t.Format == domain.TournamentPickup {
+			if !redScore.Valid || !blueScore.Valid {
+				continue
+			}
+			teams, err := pickupTeams(ctx, tx, t.ID)
+			if err != nil {
+				return 0, 0, err
+			}
+			winningTeam = pickupResult(teams, participants, int(redScore.Int64), int(blueScore.Int64))
+			if winningTeam == nil {
+				continue
+			}
+		} else {
+			if tm.player1 == nil || tm.player2 == nil {
+				continue
+			}
+			winnerID = duelWinner(*tm.player1, *tm.player2, participants)
+			if winnerID == nil {
+				continue
+			}
+		}
+		if err := settleTournamentPairing(ctx, tx, &t, tm, winnerID, winningTeam, &matchID); err != nil {
+			return 0, 0, err
+		}
+		return t.ID, tm.id, tx.Commit()
+	}
+	return 0, 0, nil
+}
+
+// pickupTeams returns the drafted team of each of a pickup's entrants.
+func pickupTeams(ctx context.Context, db querier, tournamentID int64) (map[int64]int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT player_id, team FROM tournament_entrants WHERE tournament_id = ? AND team IS NOT NULL
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	teams := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var team int
+		if err := rows.Scan(&id, &team); err != nil {
+			return nil, err
+		}
+		teams[id] = team
+	}
+	return teams, rows.Err()
+}
+
+// duelWinner decides a bracket pairing from a game, or returns nil if
+// either player didn't finish it or it was drawn.
+func duelWinner(p1, p2 int64, participants map[int64]tournamentParticipant) *int64 {
+	a, okA := participants[p1]
+	b, okB := participants[p2]
+	if !okA || !okB || !a.completed || !b.completed {
+		return nil
+	}
+	switch {
+	case a.won && !b.won, a.won == b.won && a.frags > b.frags:
+		return &p1
+	case b.won && !a.won, a.won == b.won && b.frags > a.frags:
+		return &p2
+	}
+	return nil
+}
+
+// pickupResult decides a pickup from a game, or returns nil if the
+// drafted teams didn't face each other in it or it was drawn. Each
+// drafted team needs at least half its players present, and most of
+// those present on one side, opposite the other team's.
+func pickupResult(teams map[int64]int, participants map[int64]tournamentParticipant, redScore, blueScore int) *int {
+	var size [3]int
+	var sides [3][3]int // drafted team, then in-game team
+	for id, team := range teams {
+		size[team]++
+		if p, ok := participants[id]; ok && (p.team == domain.TeamRed || p.team == domain.TeamBlue) {
+			sides[team][p.team]++
+		}
+	}
+	side := func(team int) int {
+		present := sides[team][domain.TeamRed] + sides[team][domain.TeamBlue]
+		if present == 0 || present*2 < size[team] {
+			return 0
+		}
+		switch {
+		case sides[team][domain.TeamRed] > sides[team][domain.TeamBlue]:
+			return domain.TeamRed
+		case sides[team][domain.TeamBlue] > sides[team][domain.TeamRed]:
+			return domain.TeamBlue
+		}
+		return 0
+	}
+	side1, side2 := side(domain.TeamRed), side(domain.TeamBlue)
+	if side1 == 0 || side2 == 0 || side1 == side2 || redScore == blueScore {
+		return nil
+	}
+	winner := domain.TeamBlue
+	if (side1 == domain.TeamRed) == (redScore > blueScore) {
+		winner = domain.TeamRed
+	}
+	return &winner
+}
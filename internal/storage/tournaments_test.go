@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestTournamentDraws(t *testing.T) {
+	// Five entrants pad to eight: seeds 1-3 get byes, and 1 and 2 are
+	// in opposite halves.
+	bracket := singleEliminationBracket(5)
+	want := []pairing{
+		{1, 1, 1, 0}, {1, 2, 4, 5}, {1, 3, 2, 0}, {1, 4, 3, 0},
+		{2, 1, 0, 0}, {2, 2, 0, 0}, {3, 1, 0, 0},
+	}
+	if !slices.Equal(bracket, want) {
+		t.Errorf("bracket: got %v, want %v", bracket, want)
+	}
+
+	// Everyone meets everyone once; with five, one sits out each round.
+	schedule := roundRobinSchedule(5)
+	met := make(map[[2]int]bool)
+	for _, p := range schedule {
+		a, b := min(p.seed1, p.seed2), max(p.seed1, p.seed2)
+		if met[[2]int{a, b}] {
+			t.Errorf("%d and %d meet twice", a, b)
+		}
+		met[[2]int{a, b}] = true
+	}
+	if len(met) != 10 || schedule[len(schedule)-1].round != 5 {
+		t.Errorf("schedule: got %v", schedule)
+	}
+
+	if got := snakeDraft(6); !slices.Equal(got, []int{1, 2, 2, 1, 1, 2}) {
+		t.Errorf("snake draft: got %v", got)
+	}
+
+	// Drafted team 1 played blue, team 2 red; one of team 2 didn't show.
+	teams := map[int64]int{1: 1, 2: 1, 3: 2, 4: 2}
+	played := map[int64]tournamentParticipant{1: {team: 2}, 2: {team: 2}, 3: {team: 1}}
+	if w := pickupResult(teams, played, 3, 5); w == nil || *w != 1 {
+		t.Errorf("pickup won by team 1 on blue: got %v", w)
+	}
+	if w := pickupResult(teams, played, 5, 5); w != nil {
+		t.Errorf("drawn pickup: got %v", *w)
+	}
+	delete(played, 3)
+	if w := pickupResult(teams, played, 3, 5); w != nil {
+		t.Errorf("pickup without team 2: got %v", *w)
+	}
+}
+
+func TestTournamentAttachesMatches(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "duel", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	var players []*domain.PlayerGUID
+	for _, name := range []string{"alice", "bob", "carol"} {
+		pg, err := s.UpsertPlayerGUID(ctx, name+"-guid", name, name, base, false)
+		if err != nil {
+			t.Fatalf("UpsertPlayerGUID: %v", err)
+		}
+		players = append(players, pg)
+	}
+	alice, bob, carol := players[0], players[1], players[2]
+
+	tour := &domain.Tournament{Name: "Cup", Format: domain.TournamentSingleElimination, GameType: domain.GameType1v1,
+		MapPool: []string{"q3dm17", "q3tourney2"}, StartsAt: base.Add(-time.Minute), EndsAt: base.Add(time.Hour)}
+	if err := s.CreateTournament(ctx, tour); err != nil {
+		t.Fatalf("CreateTournament: %v", err)
+	}
+	for _, pg := range players {
+		if err := s.SignUpForTournament(ctx, tour.ID, pg.PlayerID, base); err != nil {
+			t.Fatalf("SignUpForTournament: %v", err)
+		}
+	}
+	if err := s.StartTournament(ctx, tour.ID); err != nil {
+		t.Fatalf("StartTournament: %v", err)
+	}
+	if err := s.SignUpForTournament(ctx, tour.ID, alice.PlayerID, base); err != ErrTournamentClosed {
+		t.Errorf("sign-up after start: got %v", err)
+	}
+
+	// Seed 1 has a bye into the final; 2 and 3 play for the other place.
+	resp, err := s.GetTournamentDetails(ctx, tour.ID)
+	if err != nil {
+		t.Fatalf("GetTournamentDetails: %v", err)
+	}
+	if m := resp.Matches; len(m) != 3 || m[0].WinnerID == nil || *m[0].WinnerID != alice.PlayerID ||
+		m[2].Player1 == nil || m[2].Player1.ID != alice.PlayerID || m[2].MapName != "q3tourney2" {
+		t.Fatalf("draw: got %+v", resp.Matches)
+	}
+
+	play := func(uuid string, winner, loser *domain.PlayerGUID) int64 {
+		t.Helper()
+		m := &domain.Match{UUID: uuid, ServerID: srv.ID, MapName: "q3dm17", GameType: domain.GameType1v1, StartedAt: base}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		for clientID, pg := range []*domain.PlayerGUID{winner, loser} {
+			frags := 10 - 5*clientID
+			if err := s.FlushMatchPlayerStats(ctx, m.ID, pg.ID, clientID, frags, 5, true, nil, nil, "", 0, pg == winner,
+				0, 0, 0, 0, 0, 0, 0, false, false, base, false); err != nil {
+				t.Fatalf("FlushMatchPlayerStats: %v", err)
+			}
+		}
+		if err := s.EndMatch(ctx, m.ID, base.Add(10*time.Minute), "fraglimit", nil, nil); err != nil {
+			t.Fatalf("EndMatch: %v", err)
+		}
+		return m.ID
+	}
+
+	// A game between players with no open pairing settles nothing.
+	if _, pairingID, err := s.AttachTournamentMatch(ctx, play("m0", alice, bob)); err != nil || pairingID != 0 {
+		t.Errorf("unpaired game: got %d, %v", pairingID, err)
+	}
+	matchID := play("m1", carol, bob)
+	if _, pairingID, err := s.AttachTournamentMatch(ctx, matchID); err != nil || pairingID != resp.Matches[1].ID {
+		t.Fatalf("semi-final: got %d, %v", pairingID, err)
+	}
+	if _, pairingID, _ := s.AttachTournamentMatch(ctx, matchID); pairingID != 0 {
+		t.Errorf("attaching the same game twice: got %d", pairingID)
+	}
+	if _, pairingID, err := s.AttachTournamentMatch(ctx, play("m2", carol, alice)); err != nil || pairingID != resp.Matches[2].ID {
+		t.Fatalf("final: got %d, %v", pairingID, err)
+	}
+
+	got, err := s.GetTournament(ctx, tour.ID)
+	if err != nil {
+		t.Fatalf("GetTournament: %v", err)
+	}
+	if got.Status != domain.TournamentCompleted || got.WinnerID == nil || *got.WinnerID != carol.PlayerID {
+		t.Errorf("finished tournament: got %+v", got)
+	}
+	// The semi-final can't be overturned once the final is decided.
+	if err := s.SetTournamentResult(ctx, tour.ID, resp.Matches[1].ID, &bob.PlayerID, nil, nil); err != ErrTournamentResultLocked {
+		t.Errorf("overturning the semi-final: got %v", err)
+	}
+}
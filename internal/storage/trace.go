@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"strings"
+
+	"github.com/ernie/trinity-tracker/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// With tracing on, each statement a store method runs gets a span
+// named for the method ("storage.GetLeaderboard"). The span covers the
+// call that runs the statement, not reading its rows; SQLite does a
+// sorted or grouped query's work before returning the first row, so
+// that's most of it for the queries worth looking at.
+
+// maxTracedQuery caps the query text kept on a span.
+const maxTracedQuery = 2000
+
+// traced wraps q in spans named for the store method that called conn
+// or beginTx, skip frames up.
+func traced(q querier, skip int) *tracedQuerier {
+	op := "storage"
+	if pc, _, _, ok := runtime.Caller(skip + 1); ok {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			op = "storage." + methodName(fn.Name())
+		}
+	}
+	return &tracedQuerier{q: q, op: op}
+}
+
+// methodName returns the bare method of a qualified function name:
+// "GetLeaderboard" for ".../storage.(*Store).GetLeaderboard.func1".
+func methodName(fn string) string {
+	fn = fn[strings.LastIndex(fn, "/")+1:]
+	parts := strings.Split(fn, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		if !strings.HasPrefix(parts[i], "func") && !strings.HasPrefix(parts[i], "gowrap") {
+			return parts[i]
+		}
+	}
+	return fn
+}
+
+type tracedQuerier struct {
+	q  querier
+	op string
+}
+
+func (t *tracedQuerier) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, t.op, trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		query = strings.Join(strings.Fields(query), " ")
+		if len(query) > maxTracedQuery {
+			query = query[:maxTracedQuery] + "…"
+		}
+		span.SetAttributes(
+			attribute.String("db.system.name", "sqlite"),
+			attribute.String("db.query.text", query),
+		)
+	}
+	return ctx, span
+}
+
+func (t *tracedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	res, err := t.q.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t *tracedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (t *tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+	// ErrNoRows only surfaces at Scan, so Err reports real failures.
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (t *tracedQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.q.PrepareContext(ctx, query)
+}
+
+// tracedTxn is a traced transaction. Its commit gets a span too: it's
+// where the WAL is synced. ctx is the one the transaction began with,
+// which Commit doesn't take.
+type tracedTxn struct {
+	*tracedQuerier
+	tx  txn
+	ctx context.Context
+}
+
+func (t *tracedTxn) Commit() error {
+	_, span := tracing.Tracer().Start(t.ctx, t.op+" commit", trace.WithSpanKind(trace.SpanKindClient))
+	err := t.tx.Commit()
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedTxn) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedQueries(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tracing.Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	defer tracing.Use(nil)
+
+	s, err := New(filepath.Join(t.TempDir(), "trinity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+	if err := s.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteCheck(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	counts := map[string]int{}
+	for _, span := range spans.Ended() {
+		counts[span.Name()]++
+		if span.Name() == "request" {
+			continue
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s isn't a child of the request span", span.Name())
+		}
+		if span.Name() == "storage.Ping" {
+			for _, a := range span.Attributes() {
+				if a.Key == "db.query.text" && a.Value.AsString() != "SELECT 1" {
+					t.Errorf("db.query.text = %q", a.Value.AsString())
+				}
+			}
+		}
+	}
+	want := map[string]int{"request": 1, "storage.Ping": 1, "storage.WriteCheck": 3, "storage.WriteCheck commit": 1}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("%d %q spans, want %d (all: %v)", counts[name], name, n, counts)
+		}
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	for fn, want := range map[string]string{
+		"github.com/ernie/trinity-tracker/internal/storage.(*Store).GetLeaderboard":       "GetLeaderboard",
+		"github.com/ernie/trinity-tracker/internal/storage.(*Store).GetLeaderboard.func1": "GetLeaderboard",
+		"github.com/ernie/trinity-tracker/internal/storage.recordWeaponFrags":             "recordWeaponFrags",
+	} {
+		if got := methodName(fn); got != want {
+			t.Errorf("methodName(%q) = %q, want %q", fn, got, want)
+		}
+	}
+}
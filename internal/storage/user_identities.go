@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Errors from LinkUserIdentity.
+var (
+	ErrIdentityLinked = errors.New("identity is linked to another account")
+	ErrProviderLinked = errors.New("account already has an identity from this provider")
+)
+
+// UserIdentity is an external sign-in identity linked to a user.
+type UserIdentity struct {
+	Provider    string
+	Subject     string
+	DisplayName string
+	LinkedAt    time.Time
+	LastLoginAt *time.Time
+}
+
+// LinkUserIdentity links the provider's subject to userID. Linking the
+// same identity to the same user again just refreshes its name.
+func (s *Store) LinkUserIdentity(ctx context.Context, userID int64, provider, subject, displayName string, at time.Time) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?`,
+		provider, subject).Scan(&owner)
+	switch {
+	case err == nil && owner != userID:
+		return ErrIdentityLinked
+	case err == nil:
+		if _, err := tx.ExecContext(ctx, `UPDATE user_identities SET display_name = ? WHERE provider = ? AND subject = ?`,
+			displayName, provider, subject); err != nil {
+			return err
+		}
+		return tx.Commit()
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_identities WHERE user_id = ? AND provider = ?`,
+		userID, provider).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrProviderLinked
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, display_name, linked_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, provider, subject, displayName, formatTimestamp(at)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetUserByIdentity returns the user the provider's subject is linked
+// to, or sql.ErrNoRows.
+func (s *Store) GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	row := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT u.id, u.username, u.password_hash, u.is_admin, u.player_id, u.password_change_required, u.created_at, u.last_login, u.game_token
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.provider = ? AND i.subject = ?
+	`, provider, subject)
+	return scanUser(row)
+}
+
+// TouchUserIdentity records a sign-in with the identity and keeps its
+// display name current.
+func (s *Store) TouchUserIdentity(ctx context.Context, provider, subject, displayName string, at time.Time) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		UPDATE user_identities SET last_login_at = ?, display_name = ?
+		WHERE provider = ? AND subject = ?
+	`, formatTimestamp(at), displayName, provider, subject)
+	return err
+}
+
+// ListUserIdentities returns a user's linked identities by provider.
+func (s *Store) ListUserIdentities(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT provider, subject, display_name, linked_at, last_login_at
+		FROM user_identities WHERE user_id = ?
+		ORDER BY provider
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserIdentity{}
+	for rows.Next() {
+		var id UserIdentity
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&id.Provider, &id.Subject, &id.DisplayName, &id.LinkedAt, &lastLogin); err != nil {
+			return nil, err
+		}
+		id.LastLoginAt = scanNullTime(lastLogin)
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// UnlinkUserIdentity removes a user's identity from provider. Returns
+// sql.ErrNoRows if they hadn't linked one.
+func (s *Store) UnlinkUserIdentity(ctx context.Context, userID int64, provider string) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = ? AND provider = ?`, userID, provider)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// CreateMapVeto inserts v and its steps in one transaction, filling in
+// v.ID and v.CreatedAt. Steps are renumbered 1..n in slice order.
+func (s *Store) CreateMapVeto(ctx context.Context, v *domain.MapVeto) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now().UTC()
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO map_vetoes (title, team_a, team_b, match_id, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, v.Title, v.TeamA, v.TeamB, v.MatchID, v.CreatedBy, formatTimestamp(v.CreatedAt))
+	if err != nil {
+		return err
+	}
+	v.ID, _ = res.LastInsertId()
+
+	for i := range v.Steps {
+		v.Steps[i].Seq = i + 1
+		st := v.Steps[i]
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO map_veto_steps (veto_id, seq, side, action, map_name)
+			VALUES (?, ?, ?, ?, ?)
+		`, v.ID, st.Seq, st.Side, st.Action, st.MapName); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetMapVeto returns the veto with its steps, or nil if it doesn't exist.
+func (s *Store) GetMapVeto(ctx context.Context, id int64) (*domain.MapVeto, error) {
+	return s.getMapVetoWhere(ctx, `v.id = ?`, id)
+}
+
+// GetMapVetoForMatch returns the most recent veto linked to matchID,
+// or nil if none is.
+func (s *Store) GetMapVetoForMatch(ctx context.Context, matchID int64) (*domain.MapVeto, error) {
+	return s.getMapVetoWhere(ctx, `v.match_id = ?`, matchID)
+}
+
+func (s *Store) getMapVetoWhere(ctx context.Context, where string, arg int64) (*domain.MapVeto, error) {
+	var v domain.MapVeto
+	var matchID, createdBy sql.NullInt64
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT v.id, v.title, v.team_a, v.team_b, v.match_id, v.created_by, v.created_at
+		FROM map_vetoes v
+		WHERE `+where+`
+		ORDER BY v.id DESC
+		LIMIT 1
+	`, arg).Scan(&v.ID, &v.Title, &v.TeamA, &v.TeamB, &matchID, &createdBy, &v.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.MatchID = scanNullInt64Ptr(matchID)
+	v.CreatedBy = scanNullInt64Ptr(createdBy)
+	if v.Steps, err = s.getMapVetoSteps(ctx, v.ID); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (s *Store) getMapVetoSteps(ctx context.Context, vetoID int64) ([]domain.MapVetoStep, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT seq, side, action, map_name
+		FROM map_veto_steps
+		WHERE veto_id = ?
+		ORDER BY seq
+	`, vetoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := []domain.MapVetoStep{}
+	for rows.Next() {
+		var st domain.MapVetoStep
+		if err := rows.Scan(&st.Seq, &st.Side, &st.Action, &st.MapName); err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+	return steps, rows.Err()
+}
+
+// ListMapVetoes returns the newest vetoes first, steps included.
+func (s *Store) ListMapVetoes(ctx context.Context, limit int) ([]domain.MapVeto, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT id, title, team_a, team_b, match_id, created_by, created_at
+		FROM map_vetoes
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	vetoes := []domain.MapVeto{}
+	for rows.Next() {
+		var v domain.MapVeto
+		var matchID, createdBy sql.NullInt64
+		if err := rows.Scan(&v.ID, &v.Title, &v.TeamA, &v.TeamB, &matchID, &createdBy, &v.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		v.MatchID = scanNullInt64Ptr(matchID)
+		v.CreatedBy = scanNullInt64Ptr(createdBy)
+		vetoes = append(vetoes, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Steps are fetched after the outer cursor is closed: the store
+	// runs on a single connection.
+	for i := range vetoes {
+		steps, err := s.getMapVetoSteps(ctx, vetoes[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		vetoes[i].Steps = steps
+	}
+	return vetoes, nil
+}
+
+// SetMapVetoMatch links the veto to a match, or unlinks it when
+// matchID is nil. Returns sql.ErrNoRows if the veto doesn't exist.
+func (s *Store) SetMapVetoMatch(ctx context.Context, vetoID int64, matchID *int64) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `UPDATE map_vetoes SET match_id = ? WHERE id = ?`, matchID, vetoID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteMapVeto removes the veto and its steps. Returns sql.ErrNoRows
+// if it doesn't exist.
+func (s *Store) DeleteMapVeto(ctx context.Context, id int64) error {
+	res, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM map_vetoes WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestMapVetoLifecycle(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	v := &domain.MapVeto{
+		Title: "Cup #3 final", TeamA: "Alpha", TeamB: "Bravo",
+		Steps: []domain.MapVetoStep{
+			{Side: "a", Action: domain.VetoBan, MapName: "q3ctf1"},
+			{Side: "b", Action: domain.VetoBan, MapName: "q3ctf2"},
+			{Side: "", Action: domain.VetoDecider, MapName: "q3ctf4"},
+		},
+	}
+	if err := s.CreateMapVeto(ctx, v); err != nil {
+		t.Fatalf("CreateMapVeto: %v", err)
+	}
+
+	srv := &domain.Server{Key: "ctf", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	m := &domain.Match{UUID: "m-1", ServerID: srv.ID, MapName: "q3ctf4", GameType: "ctf", StartedAt: time.Now()}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	if got, err := s.GetMapVetoForMatch(ctx, m.ID); err != nil || got != nil {
+		t.Fatalf("before link: got %+v, %v; want nil", got, err)
+	}
+	if err := s.SetMapVetoMatch(ctx, v.ID, &m.ID); err != nil {
+		t.Fatalf("SetMapVetoMatch: %v", err)
+	}
+	got, err := s.GetMapVetoForMatch(ctx, m.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetMapVetoForMatch: %+v, %v", got, err)
+	}
+	if got.ID != v.ID || len(got.Steps) != 3 || got.Steps[2].Seq != 3 || got.Steps[2].Action != domain.VetoDecider {
+		t.Errorf("linked veto: got %+v", got)
+	}
+
+	list, err := s.ListMapVetoes(ctx, 10)
+	if err != nil || len(list) != 1 || len(list[0].Steps) != 3 {
+		t.Fatalf("ListMapVetoes: %+v, %v", list, err)
+	}
+
+	if err := s.DeleteMapVeto(ctx, v.ID); err != nil {
+		t.Fatalf("DeleteMapVeto: %v", err)
+	}
+	if err := s.DeleteMapVeto(ctx, v.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second delete: got %v, want sql.ErrNoRows", err)
+	}
+	steps, err := s.getMapVetoSteps(ctx, v.ID)
+	if err != nil || len(steps) != 0 {
+		t.Errorf("steps after delete: %+v, %v", steps, err)
+	}
+}
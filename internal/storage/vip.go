@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// ErrPlayerNotLinked is returned by SetPlayerVIP for a player with no
+// account: only linked players can be VIPs.
+var ErrPlayerNotLinked = errors.New("player is not linked to an account")
+
+// SetPlayerVIP flags or clears the VIP status of the account linked to
+// playerID. Returns sql.ErrNoRows if there is no such player.
+func (s *Store) SetPlayerVIP(ctx context.Context, playerID int64, vip bool) error {
+	var linked bool
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE player_id = p.id) FROM players p WHERE p.id = ?
+	`, playerID).Scan(&linked)
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return ErrPlayerNotLinked
+	}
+	_, err = s.conn(ctx).ExecContext(ctx, `UPDATE users SET is_vip = ? WHERE player_id = ?`, vip, playerID)
+	return err
+}
+
+// IsPlayerVIP reports whether playerID's linked account is a VIP.
+func (s *Store) IsPlayerVIP(ctx context.Context, playerID int64) bool {
+	var vip bool
+	err := s.conn(ctx).QueryRowContext(ctx, `
+		SELECT COALESCE(is_vip, 0) FROM users WHERE player_id = ?
+	`, playerID).Scan(&vip)
+	return err == nil && vip
+}
+
+// ListVIPs returns every VIP by name.
+func (s *Store) ListVIPs(ctx context.Context) ([]domain.VIP, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name, u.username
+		FROM users u
+		JOIN players p ON p.id = u.player_id
+		WHERE u.is_vip = 1
+		ORDER BY p.clean_name COLLATE NOCASE, p.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.VIP{}
+	for rows.Next() {
+		var v domain.VIP
+		if err := rows.Scan(&v.PlayerID, &v.Name, &v.CleanName, &v.Username); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// RecordVIPKick stores a reserved-slot kick.
+func (s *Store) RecordVIPKick(ctx context.Context, k domain.VIPKick) error {
+	_, err := s.conn(ctx).ExecContext(ctx, `
+		INSERT INTO vip_kicks (server_id, vip_player_id, vip_name, kicked_player_id, kicked_name, kicked_is_bot, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, k.ServerID, k.VIPPlayerID, k.VIPName, k.KickedPlayerID, k.KickedName, k.KickedIsBot, formatTimestamp(k.CreatedAt))
+	return err
+}
+
+// ListVIPKicks returns reserved-slot kicks newest first, optionally
+// for one server.
+func (s *Store) ListVIPKicks(ctx context.Context, serverID int64, limit int) ([]domain.VIPKick, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT vk.id, vk.server_id, sv.key, vk.vip_player_id, vk.vip_name,
+			vk.kicked_player_id, vk.kicked_name, vk.kicked_is_bot, vk.created_at
+		FROM vip_kicks vk
+		JOIN servers sv ON sv.id = vk.server_id
+		WHERE ? = 0 OR vk.server_id = ?
+		ORDER BY vk.created_at DESC, vk.id DESC
+		LIMIT ?
+	`, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.VIPKick{}
+	for rows.Next() {
+		var k domain.VIPKick
+		var vipID, kickedID sql.NullInt64
+		if err := rows.Scan(&k.ID, &k.ServerID, &k.ServerKey, &vipID, &k.VIPName,
+			&kickedID, &k.KickedName, &k.KickedIsBot, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		k.VIPPlayerID = scanNullInt64Ptr(vipID)
+		k.KickedPlayerID = scanNullInt64Ptr(kickedID)
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
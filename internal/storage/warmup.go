@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// RecordWarmupStats saves what players scored in a match's warmup.
+// Players whose GUID isn't known are skipped, and a player's warmup is
+// only recorded once, so a resent match_start can't change it.
+func (s *Store) RecordWarmupStats(ctx context.Context, matchID int64, players []domain.WarmupPlayer) error {
+	if len(players) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(players)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn(ctx).ExecContext(ctx, `
+		INSERT OR IGNORE INTO match_warmup_stats (match_id, player_guid_id, frags, deaths)
+		SELECT ?, pg.id, l.value ->> '$.frags', l.value ->> '$.deaths'
+		FROM json_each(?) l
+		JOIN player_guids pg ON pg.guid = l.value ->> '$.guid'
+	`, matchID, string(data))
+	return err
+}
+
+// getWarmup returns a match's warmup stats, most frags first, or nil
+// if none were recorded.
+func (s *Store) getWarmup(ctx context.Context, matchID int64) ([]domain.WarmupLine, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT pg.player_id, pg.name, w.frags, w.deaths
+		FROM match_warmup_stats w
+		JOIN player_guids pg ON pg.id = w.player_guid_id
+		WHERE w.match_id = ?
+		ORDER BY w.frags DESC, w.deaths, pg.player_id
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []domain.WarmupLine
+	for rows.Next() {
+		var l domain.WarmupLine
+		if err := rows.Scan(&l.PlayerID, &l.Name, &l.Frags, &l.Deaths); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestWarmupStats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 6, 20, 0, 0, 0, time.UTC)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bot, err := s.UpsertBotPlayerGUID(ctx, "Sarge", "Sarge", base)
+	if err != nil {
+		t.Fatalf("UpsertBotPlayerGUID: %v", err)
+	}
+	m := &domain.Match{UUID: "m1", ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: base}
+	if err := s.CreateMatch(ctx, m); err != nil {
+		t.Fatalf("CreateMatch: %v", err)
+	}
+
+	if err := s.RecordWarmupStats(ctx, m.ID, []domain.WarmupPlayer{
+		{GUID: "AAAA", Frags: 2, Deaths: 1},
+		{GUID: bot.GUID, Frags: 1, Deaths: 2},
+		{GUID: "UNKNOWN", Frags: 9},
+	}); err != nil {
+		t.Fatalf("RecordWarmupStats: %v", err)
+	}
+	// A resent match_start doesn't change it.
+	if err := s.RecordWarmupStats(ctx, m.ID, []domain.WarmupPlayer{{GUID: "AAAA", Frags: 50}}); err != nil {
+		t.Fatalf("RecordWarmupStats again: %v", err)
+	}
+
+	summary, err := s.GetMatchSummaryByID(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMatchSummaryByID: %v", err)
+	}
+	want := []domain.WarmupLine{
+		{PlayerID: alice.PlayerID, Name: "alice", Frags: 2, Deaths: 1},
+		{PlayerID: bot.PlayerID, Name: "Sarge", Frags: 1, Deaths: 2},
+	}
+	if got := summary.Warmup; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("warmup = %+v, want %+v", got, want)
+	}
+	// Warmup play isn't match play.
+	if len(summary.Players) != 0 {
+		t.Errorf("players = %+v, want none", summary.Players)
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+// signatureWeaponMinFrags is how many weapon frags a player needs
+// before their most used weapon is called their signature.
+const signatureWeaponMinFrags = 25
+
+// RecordWeaponFrags adds a player's per-weapon frags for matchID.
+// Stints of one GUID arrive as separate calls and add up.
+func (s *Store) RecordWeaponFrags(ctx context.Context, matchID, playerGUIDID int64, frags map[string]int) error {
+	return recordWeaponFrags(ctx, s.conn(ctx), matchID, playerGUIDID, frags)
+}
+
+// recordWeaponFrags writes all of a player's weapons in one statement.
+func recordWeaponFrags(ctx context.Context, db execer, matchID, playerGUIDID int64, frags map[string]int) error {
+	var values []string
+	var args []any
+	for weapon, n := range frags {
+		if n <= 0 {
+			continue
+		}
+		values = append(values, "(?, ?, ?, ?)")
+		args = append(args, matchID, playerGUIDID, weapon, n)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO match_weapon_frags (match_id, player_guid_id, weapon, frags)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT(match_id, player_guid_id, weapon) DO UPDATE SET
+			frags = frags + excluded.frags
+	`, args...)
+	return err
+}
+
+// getPlayerWeaponUsage returns a player's frags by weapon, most used
+// first, for matches started in [start, end). all ignores the bounds.
+func (s *Store) getPlayerWeaponUsage(ctx context.Context, playerID int64, all bool, start, end time.Time) ([]domain.WeaponUsage, error) {
+	query := `
+		SELECT wf.weapon, SUM(wf.frags) AS frags
+		FROM match_weapon_frags wf
+		JOIN player_guids pg ON wf.player_guid_id = pg.id`
+	args := []any{playerID}
+	if all {
+		query += `
+		WHERE pg.player_id = ?`
+	} else {
+		query += `
+		JOIN matches m ON wf.match_id = m.id
+		WHERE pg.player_id = ? AND m.started_at >= ? AND m.started_at < ?`
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	query += `
+		GROUP BY wf.weapon
+		ORDER BY frags DESC, wf.weapon`
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.WeaponUsage{}
+	var total int64
+	for rows.Next() {
+		var u domain.WeaponUsage
+		if err := rows.Scan(&u.Weapon, &u.Frags); err != nil {
+			return nil, err
+		}
+		total += u.Frags
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Share = float64(out[i].Frags) / float64(total)
+	}
+	return out, nil
+}
+
+// signatureWeapon picks the most used weapon from usage (sorted most
+// used first), or "" if the player hasn't enough weapon frags yet.
+func signatureWeapon(usage []domain.WeaponUsage) string {
+	var total int64
+	for _, u := range usage {
+		total += u.Frags
+	}
+	if len(usage) == 0 || total < signatureWeaponMinFrags {
+		return ""
+	}
+	return usage[0].Weapon
+}
+
+// GetWeaponLeaderboard ranks players by frags with one weapon. Period,
+// game type, and asOf filter as they do for GetLeaderboard, and the
+// same players are left off. TotalFrags is the weapon's frags and
+// TotalMatches the matches they got any with it.
+func (s *Store) GetWeaponLeaderboard(ctx context.Context, weapon, period string, limit int, gameType string, asOf time.Time) (*domain.LeaderboardResponse, error) {
+	start, end := getTimePeriodBounds(period, asOf)
+
+	rules := s.LeaderboardRules()
+	where := "wf.weapon = ? AND " + rules.playerFilter() + rules.matchFilter("wf")
+	args := []any{weapon}
+	if period != "all" {
+		where += " AND m.started_at >= ? AND m.started_at < ?"
+		args = append(args, formatTimestamp(start), formatTimestamp(end))
+	}
+	if gameType != "" {
+		where += " AND m.game_type = ?"
+		args = append(args, gameType)
+	}
+	args = append(args, limit)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT p.id, p.name, p.clean_name, p.first_seen, p.last_seen, p.is_bot, p.is_vr,
+			CASE WHEN u.id IS NOT NULL THEN 1 ELSE 0 END as is_verified,
+			COALESCE(u.is_admin, 0) as is_admin,
+			SUM(wf.frags) as total_frags,
+			COUNT(DISTINCT wf.match_id) as total_matches
+		FROM match_weapon_frags wf
+		JOIN player_guids pg ON wf.player_guid_id = pg.id
+		JOIN players p ON pg.player_id = p.id
+		JOIN matches m ON wf.match_id = m.id
+		LEFT JOIN users u ON u.player_id = p.id
+		WHERE `+where+`
+		GROUP BY p.id
+		ORDER BY total_frags DESC, p.id
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := &domain.LeaderboardResponse{
+		Category: "frags",
+		Weapon:   weapon,
+		Period:   period,
+		Entries:  []domain.LeaderboardEntry{},
+	}
+	if period != "all" {
+		resp.PeriodStart = &start
+		resp.PeriodEnd = &end
+	}
+	for rows.Next() {
+		var e domain.LeaderboardEntry
+		if err := rows.Scan(&e.Player.ID, &e.Player.Name, &e.Player.CleanName, &e.Player.FirstSeen, &e.Player.LastSeen,
+			&e.Player.IsBot, &e.Player.IsVR, &e.Player.IsVerified, &e.Player.IsAdmin,
+			&e.TotalFrags, &e.TotalMatches); err != nil {
+			return nil, err
+		}
+		e.Rank = len(resp.Entries) + 1
+		resp.Entries = append(resp.Entries, e)
+	}
+	return resp, rows.Err()
+}
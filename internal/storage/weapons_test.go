@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernie/trinity-tracker/internal/domain"
+)
+
+func TestWeaponUsageAndLeaderboard(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+
+	srv := &domain.Server{Key: "ffa", Address: "127.0.0.1:27960"}
+	if err := s.UpsertServer(ctx, "test", srv); err != nil {
+		t.Fatalf("UpsertServer: %v", err)
+	}
+	alice, err := s.UpsertPlayerGUID(ctx, "AAAA", "alice", "alice", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+	bob, err := s.UpsertPlayerGUID(ctx, "BBBB", "bob", "bob", base, false)
+	if err != nil {
+		t.Fatalf("UpsertPlayerGUID: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		started := base.Add(time.Duration(i) * time.Hour)
+		m := &domain.Match{UUID: "m" + strconv.Itoa(i), ServerID: srv.ID, MapName: "q3dm17", GameType: "ffa", StartedAt: started}
+		if err := s.CreateMatch(ctx, m); err != nil {
+			t.Fatalf("CreateMatch: %v", err)
+		}
+		// Two stints of alice's add up.
+		for _, frags := range []map[string]int{{"railgun": 10, "rocket": 3}, {"railgun": 2}} {
+			if err := s.RecordWeaponFrags(ctx, m.ID, alice.ID, frags); err != nil {
+				t.Fatalf("RecordWeaponFrags: %v", err)
+			}
+		}
+		if err := s.RecordWeaponFrags(ctx, m.ID, bob.ID, map[string]int{"railgun": 5, "rocket": 8}); err != nil {
+			t.Fatalf("RecordWeaponFrags: %v", err)
+		}
+	}
+
+	resp, err := s.GetPlayerStatsByID(ctx, alice.PlayerID, "all")
+	if err != nil {
+		t.Fatalf("GetPlayerStatsByID: %v", err)
+	}
+	if len(resp.Weapons) != 2 || resp.Weapons[0].Weapon != "railgun" || resp.Weapons[0].Frags != 24 {
+		t.Fatalf("weapons: got %+v", resp.Weapons)
+	}
+	if resp.Weapons[0].Share != 24.0/30.0 {
+		t.Errorf("railgun share = %v, want %v", resp.Weapons[0].Share, 24.0/30.0)
+	}
+	if resp.SignatureWeapon != "railgun" {
+		t.Errorf("signature weapon = %q, want railgun", resp.SignatureWeapon)
+	}
+
+	// Bob has 26 weapon frags, just over the signature threshold.
+	resp, err = s.GetPlayerStatsByID(ctx, bob.PlayerID, "all")
+	if err != nil {
+		t.Fatalf("GetPlayerStatsByID: %v", err)
+	}
+	if resp.SignatureWeapon != "rocket" {
+		t.Errorf("bob signature weapon = %q, want rocket", resp.SignatureWeapon)
+	}
+
+	lb, err := s.GetWeaponLeaderboard(ctx, "rocket", "week", 10, "", time.Time{})
+	if err != nil {
+		t.Fatalf("GetWeaponLeaderboard: %v", err)
+	}
+	if len(lb.Entries) != 2 || lb.Entries[0].Player.ID != bob.PlayerID || lb.Entries[0].TotalFrags != 16 || lb.Entries[0].Rank != 1 {
+		t.Errorf("rocket leaderboard: got %+v", lb.Entries)
+	}
+	if lb.Entries[1].TotalMatches != 2 {
+		t.Errorf("alice rocket matches = %d, want 2", lb.Entries[1].TotalMatches)
+	}
+}
@@ -0,0 +1,112 @@
+// Package tracing exports OpenTelemetry traces over OTLP/HTTP when the
+// config has a tracing block. Until Setup installs a provider, the
+// global one is OpenTelemetry's no-op, so the spans started across
+// Trinity cost next to nothing when tracing is off.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const instrumentation = "github.com/ernie/trinity-tracker"
+
+var enabled atomic.Bool
+
+// Enabled reports whether Setup installed an exporter. Spans that are
+// expensive to describe (a call site, a query's text) check it first.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Tracer returns the tracer Trinity's spans come from.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs a tracer provider exporting to cfg.Endpoint. The
+// returned shutdown flushes the spans still queued; call it on exit.
+func Setup(ctx context.Context, cfg *config.TracingConfig, version string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(cfg.Endpoint),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", version),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*cfg.SampleRatio))),
+	)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	Use(provider)
+	return provider.Shutdown, nil
+}
+
+// Use makes tp the provider of Trinity's spans, or, if tp is nil, turns
+// tracing back off. Setup calls it; tests pass a provider that records.
+func Use(tp trace.TracerProvider) {
+	if tp == nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		enabled.Store(false)
+		return
+	}
+	otel.SetTracerProvider(tp)
+	enabled.Store(true)
+}
+
+// StartRequest starts the server span for an HTTP request, joining
+// the caller's trace if it sent a traceparent. The span is named for
+// the method until the mux has routed the request; EndRequest renames
+// it after the route.
+func StartRequest(req *http.Request) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, span := Tracer().Start(ctx, req.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	return req.WithContext(ctx), span
+}
+
+// EndRequest names span after the mux pattern the request matched
+// ("GET /api/players/{id}"), records its response status, and ends it.
+func EndRequest(span trace.Span, pattern string, status int) {
+	span.SetName(pattern)
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if _, route, ok := strings.Cut(pattern, " "); ok && strings.HasPrefix(route, "/") {
+		span.SetAttributes(attribute.String("http.route", route))
+	}
+	if status >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ernie/trinity-tracker/internal/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupExports(t *testing.T) {
+	var posts atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" && r.Header.Get("X-Api-Key") == "k" {
+			posts.Add(1)
+		}
+	}))
+	defer collector.Close()
+
+	ratio := 1.0
+	shutdown, err := Setup(context.Background(), &config.TracingConfig{
+		Endpoint:    collector.URL + "/v1/traces",
+		Headers:     map[string]string{"X-Api-Key": "k"},
+		ServiceName: "trinity",
+		SampleRatio: &ratio,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Use(nil)
+	if !Enabled() {
+		t.Error("Enabled() = false after Setup")
+	}
+	_, span := Tracer().Start(context.Background(), "work")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if posts.Load() == 0 {
+		t.Error("no spans reached the collector")
+	}
+}
+
+func TestRequestSpan(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	defer Use(nil)
+
+	req := httptest.NewRequest("GET", "/api/players/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req, span := StartRequest(req)
+	EndRequest(span, "GET /api/players/{id}", 500)
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("%d spans, want 1", len(ended))
+	}
+	s := ended[0]
+	if s.Name() != "GET /api/players/{id}" {
+		t.Errorf("name = %q", s.Name())
+	}
+	if got := s.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the caller's", got)
+	}
+	attrs := map[string]string{}
+	for _, a := range s.Attributes() {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs["http.route"] != "/api/players/{id}" || attrs["http.response.status_code"] != "500" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if s.Status().Code.String() != "Error" {
+		t.Errorf("status = %v, want Error", s.Status())
+	}
+	if req.Context() == context.Background() {
+		t.Error("request context doesn't carry the span")
+	}
+}
@@ -0,0 +1,11 @@
+-- Add per-player flag carry time for the CTF flag timeline. The
+-- timeline's match_flag_events table is created by trinity itself on
+-- start, from its schema. Filled in by collectors from this release
+-- on; older matches have no timeline and a carry time of 0.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-match-flag-events.sql
+
+ALTER TABLE match_player_stats ADD COLUMN flag_carry_ms INTEGER DEFAULT 0;
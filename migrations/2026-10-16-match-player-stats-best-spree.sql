@@ -0,0 +1,10 @@
+-- Add the longest kill streak (kills without dying) to per-match
+-- player stats. Filled in by collectors from this release on; older
+-- rows keep 0 and simply don't place on the sprees leaderboard.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-match-player-stats-best-spree.sql
+
+ALTER TABLE match_player_stats ADD COLUMN best_spree INTEGER DEFAULT 0;
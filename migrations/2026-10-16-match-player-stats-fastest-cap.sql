@@ -0,0 +1,11 @@
+-- Add each player's fastest flag capture (milliseconds from picking up
+-- the flag to capturing it) to per-match player stats, for the fastest
+-- capture record. Filled in by collectors from this release on; older
+-- rows stay NULL and don't place on the record boards.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-match-player-stats-fastest-cap.sql
+
+ALTER TABLE match_player_stats ADD COLUMN fastest_cap_ms INTEGER;
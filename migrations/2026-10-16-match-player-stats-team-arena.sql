@@ -0,0 +1,11 @@
+-- Add Team Arena objective stats to per-match player stats: skulls
+-- scored in Harvester and enemy obelisks destroyed in Overload. Filled
+-- in by collectors from this release on; older rows keep 0.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-match-player-stats-team-arena.sql
+
+ALTER TABLE match_player_stats ADD COLUMN skulls_scored INTEGER DEFAULT 0;
+ALTER TABLE match_player_stats ADD COLUMN obelisk_destroys INTEGER DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Flag matches the humans walked out of, so their stats can be left
+-- off the leaderboards. Set by the hub from this release on; older
+-- matches stay unflagged.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-matches-abandoned.sql
+
+ALTER TABLE matches ADD COLUMN abandoned BOOLEAN NOT NULL DEFAULT FALSE;
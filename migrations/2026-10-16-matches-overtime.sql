@@ -0,0 +1,10 @@
+-- Record how long each match ran past regulation, so matches that went
+-- to overtime can be flagged and filtered. Set by the hub from this
+-- release on; older matches stay NULL, as if they ended in regulation.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-matches-overtime.sql
+
+ALTER TABLE matches ADD COLUMN overtime_seconds INTEGER;
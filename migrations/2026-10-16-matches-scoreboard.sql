@@ -0,0 +1,11 @@
+-- Keep each match's final scoreboard as the server logged it at Exit,
+-- so the match page can show what players saw at intermission.
+-- Filled in by the hub from this release on; older matches keep NULL
+-- and show their stats rows as before.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-matches-scoreboard.sql
+
+ALTER TABLE matches ADD COLUMN scoreboard TEXT;
@@ -0,0 +1,10 @@
+-- Let admins keep a player off every leaderboard (confirmed cheaters,
+-- staff test accounts) without touching their match history.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-players-leaderboard-exclusion.sql
+
+ALTER TABLE players ADD COLUMN exclude_from_leaderboards BOOLEAN DEFAULT FALSE;
+ALTER TABLE players ADD COLUMN leaderboard_exclusion_reason TEXT;
@@ -0,0 +1,10 @@
+-- Record each game server's clock offset, as detected by its
+-- collector from the log and reported on every heartbeat. Existing
+-- servers start at 0 until their collector's next heartbeat.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-servers-clock-offset.sql
+
+ALTER TABLE servers ADD COLUMN clock_offset_ms INTEGER NOT NULL DEFAULT 0;
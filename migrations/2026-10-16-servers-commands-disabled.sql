@@ -0,0 +1,10 @@
+-- Per-server switch for in-game chat commands, flipped by hub admins
+-- with PUT /api/admin/servers/{id}/commands. Existing servers keep
+-- answering commands.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-servers-commands-disabled.sql
+
+ALTER TABLE servers ADD COLUMN commands_disabled INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Mark servers the hub only polls over UDP, such as community servers
+-- added from the master-server browser. Existing servers all have a
+-- collector.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-servers-query-only.sql
+
+ALTER TABLE servers ADD COLUMN query_only INTEGER NOT NULL DEFAULT 0;
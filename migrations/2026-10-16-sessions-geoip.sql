@@ -0,0 +1,13 @@
+-- Add coarse GeoIP location to sessions. Filled in by collectors that
+-- have tracker.collector.geoip_database configured (MaxMind GeoLite2
+-- City); sessions from collectors without it keep the empty defaults.
+-- Existing rows are not backfilled.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-sessions-geoip.sql
+
+ALTER TABLE sessions ADD COLUMN country_code TEXT DEFAULT '';
+ALTER TABLE sessions ADD COLUMN country TEXT DEFAULT '';
+ALTER TABLE sessions ADD COLUMN city TEXT DEFAULT '';
@@ -0,0 +1,10 @@
+-- Track which sessions have had their IP address scrubbed under
+-- tracker.hub.session_ips, so the hourly job skips them. Existing rows
+-- start unscrubbed and are picked up on the hub's first run.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-sessions-ip-scrubbed.sql
+
+ALTER TABLE sessions ADD COLUMN ip_scrubbed_at TIMESTAMP;
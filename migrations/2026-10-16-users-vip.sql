@@ -0,0 +1,9 @@
+-- Let admins flag linked accounts as VIPs, who get a reserved slot on
+-- servers that keep them.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-16-users-vip.sql
+
+ALTER TABLE users ADD COLUMN is_vip BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,12 @@
+-- Number match ends in the order the hub records them, so scheduled
+-- exports pick up where they left off by that number instead of by
+-- ended_at, which skipped matches a collector sent late. Matches that
+-- have already ended are numbered in id order.
+--
+-- Run with the trinity service stopped:
+--   sudo systemctl stop trinity
+--   sudo cp /var/lib/trinity/trinity.db /var/lib/trinity/trinity.db.bak.$(date +%Y%m%d-%H%M%S)
+--   sudo -u quake sqlite3 /var/lib/trinity/trinity.db < migrations/2026-10-17-matches-end-seq.sql
+
+ALTER TABLE matches ADD COLUMN end_seq INTEGER;
+UPDATE matches SET end_seq = id WHERE ended_at IS NOT NULL;
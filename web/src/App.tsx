@@ -42,6 +42,8 @@ import type {
   TellData,
   SayRconData,
   AwardData,
+  PlayerReturnData,
+  TeamBalanceData,
 } from "./types";
 
 // Q3 color reset code
@@ -52,6 +54,14 @@ function cleanQ3Name(name: string): string {
   return name.replace(/\^[0-9]/g, "");
 }
 
+// Mirrors domain.HumanizeDaysAway: "45 days", "3 months", "2 years"
+function humanizeDaysAway(days: number): string {
+  const plural = (n: number, unit: string) => (n === 1 ? `1 ${unit}` : `${n} ${unit}s`);
+  if (days >= 365) return plural(Math.floor(days / 365), "year");
+  if (days >= 60) return plural(Math.floor(days / 30), "month");
+  return plural(days, "day");
+}
+
 function App() {
   const { hasMultiple: hasMultipleSources } = useSources();
   const [servers, setServers] = useState<Map<number, ServerStatus>>(new Map());
@@ -261,6 +271,26 @@ function App() {
           break;
         }
 
+        case "player_return": {
+          const data = event.data as PlayerReturnData;
+          const serverName = getServerName(event.server_id);
+          const cleanName = cleanQ3Name(data.player_name);
+          const botInfo = getPlayerBotInfo(event.server_id, cleanName);
+          const player = {
+            name: data.player_name,
+            cleanName,
+            playerId: data.player_id,
+            ...botInfo,
+          };
+          addActivity("info", `${data.player_name}${COLOR_RESET} is back after ${humanizeDaysAway(data.days_away)}!`, {
+            player,
+            serverId: event.server_id,
+            serverName,
+            activityType: "player_return",
+          });
+          break;
+        }
+
         case "match_start": {
           const data = event.data as MatchStartData;
           const serverName = getServerName(event.server_id);
@@ -457,6 +487,22 @@ function App() {
           break;
         }
 
+        case "team_balance": {
+          const data = event.data as TeamBalanceData;
+          const serverName = getServerName(event.server_id);
+          const teamNames = ["Free", "Red", "Blue", "Spectator"];
+          const moves = data.moves
+            .map((m) => `${m.player_name}${COLOR_RESET} → ${teamNames[m.to_team] || "Unknown"}`)
+            .join(", ");
+          const verb = data.enforced ? "Balanced teams" : "Teams are uneven, suggested";
+          addActivity("info", `${verb}: ${moves}`, {
+            serverId: event.server_id,
+            serverName,
+            activityType: "team_balance",
+          });
+          break;
+        }
+
         case "say": {
           const data = event.data as SayData;
           const serverName = getServerName(event.server_id);
@@ -605,7 +651,7 @@ function App() {
     [addActivity, getServerName, getServerGameType, getPlayerBotInfo],
   );
 
-  const { isConnected } = useWebSocket(wsUrl, handleEvent);
+  const { isConnected } = useWebSocket(wsUrl, handleEvent, "/api/events/poll", "/api/events");
 
   // Fetch server list periodically. /api/servers carries liveness +
   // an implicit visibility filter (servers fall off when the
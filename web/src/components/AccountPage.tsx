@@ -6,10 +6,14 @@ import { PlayerRecentMatches } from './PlayerRecentMatches'
 import { Header } from './Header'
 import { StatItem } from './StatItem'
 import { PeriodSelector } from './PeriodSelector'
+import { AnonymizeSection } from './AnonymizeSection'
+import { SignInMethods } from './SignInMethods'
+import { ApiKeysSection } from './ApiKeysSection'
 import { useAuth } from '../hooks/useAuth'
 import { usePlayerStats } from '../hooks/usePlayerStats'
 import { formatDate, formatDateTime, formatDuration } from '../utils/formatters'
-import type { AccountProfile, TimePeriod } from '../types'
+import { startOAuth, takeOAuthFragment } from '../utils/oauth'
+import type { AccountProfile, TimePeriod, UserIdentity } from '../types'
 
 export function AccountPage() {
   const navigate = useNavigate()
@@ -43,8 +47,10 @@ export function AccountPage() {
   const [gameTokenVisible, setGameTokenVisible] = useState(false)
   const [showRotateConfirm, setShowRotateConfirm] = useState(false)
 
-  // Password change state
-  const [showPasswordForm, setShowPasswordForm] = useState(false)
+  // Password change state. An account without a password signs in
+  // with its provider again first and comes back with a reauth token.
+  const [reauthToken, setReauthToken] = useState(() => takeOAuthFragment('oauth_reauth')?.get('oauth_reauth') || '')
+  const [showPasswordForm, setShowPasswordForm] = useState(reauthToken !== '')
   const [currentPassword, setCurrentPassword] = useState('')
   const [newPassword, setNewPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
@@ -259,13 +265,16 @@ export function AccountPage() {
     }
 
     setChangingPassword(true)
-    const result = await changePassword(currentPassword, newPassword)
+    const result = await changePassword(currentPassword, newPassword, reauthToken || undefined)
     setChangingPassword(false)
+    // The hub spends a reauth token on any attempt.
+    setReauthToken('')
 
     if (!result.success) {
       setPasswordError(result.error || 'Failed to change password')
     } else {
       setPasswordSuccess(true)
+      setProfile((p) => (p ? { ...p, has_password: true } : p))
       setCurrentPassword('')
       setNewPassword('')
       setConfirmPassword('')
@@ -273,6 +282,26 @@ export function AccountPage() {
     }
   }
 
+  const startSetPassword = async () => {
+    if (profile?.has_password || reauthToken) {
+      setShowPasswordForm(true)
+      return
+    }
+    setPasswordError('')
+    try {
+      const res = await fetch('/api/account/identities', { headers: { Authorization: `Bearer ${auth.token}` } })
+      const identities: UserIdentity[] = res.ok ? await res.json() : []
+      if (identities.length === 0) {
+        setPasswordError('Link a sign-in method first')
+        return
+      }
+      const err = await startOAuth(`/api/account/reauth/${identities[0].provider}`, auth.token)
+      if (err) setPasswordError(err)
+    } catch {
+      setPasswordError('Network error')
+    }
+  }
+
   if (authLoading || !auth.isAuthenticated) {
     return (
       <div className="account-page">
@@ -325,7 +354,7 @@ export function AccountPage() {
                           ? `${stats.stats.completed_matches} completed, ${stats.stats.uncompleted_matches} incomplete`
                           : undefined}
                       />
-                      <StatItem label="K/D" value={stats.stats.kd_ratio.toFixed(2)} />
+                      <StatItem label="K/D" value={stats.stats.kd_ratio?.toFixed(2)} />
                       <StatItem label="Frags" value={stats.stats.frags} className="frags" />
                       <StatItem label="Deaths" value={stats.stats.deaths} className="deaths" />
                       <StatItem label="Victories" value={stats.stats.victories} backgroundIcon="/assets/medals/medal_victory.png" />
@@ -336,6 +365,8 @@ export function AccountPage() {
                       <StatItem label="Returns" value={stats.stats.flag_returns} backgroundIcon="/assets/flags/flag_in_base_red.png" />
                       <StatItem label="Assists" value={stats.stats.assists} backgroundIcon="/assets/medals/medal_assist.png" />
                       <StatItem label="Defense" value={stats.stats.defends} backgroundIcon="/assets/medals/medal_defend.png" />
+                      <StatItem label="Best Spree" value={stats.stats.best_spree} />
+                      <StatItem label="Win Streak" value={stats.stats.longest_win_streak} />
                     </div>
                   )}
                 </div>
@@ -389,24 +420,28 @@ export function AccountPage() {
               </dl>
             </section>
 
-            {/* Change Password */}
+            {auth.token && <SignInMethods token={auth.token} />}
+
+            {/* Change Password; accounts made by signing in elsewhere set one here */}
             <section className="account-section account-password">
-              <h2>Change Password</h2>
+              <h2>{profile.has_password ? 'Change Password' : 'Set Password'}</h2>
               {passwordSuccess && (
                 <div className="success-message">Password changed successfully!</div>
               )}
               {showPasswordForm ? (
                 <form onSubmit={handlePasswordSubmit} className="password-form">
-                  <div className="form-group">
-                    <label>Current Password</label>
-                    <input
-                      type="password"
-                      value={currentPassword}
-                      onChange={(e) => setCurrentPassword(e.target.value)}
-                      disabled={changingPassword}
-                      autoComplete="current-password"
-                    />
-                  </div>
+                  {profile.has_password && (
+                    <div className="form-group">
+                      <label>Current Password</label>
+                      <input
+                        type="password"
+                        value={currentPassword}
+                        onChange={(e) => setCurrentPassword(e.target.value)}
+                        disabled={changingPassword}
+                        autoComplete="current-password"
+                      />
+                    </div>
+                  )}
                   <div className="form-group">
                     <label>New Password</label>
                     <input
@@ -431,7 +466,7 @@ export function AccountPage() {
                   <div className="form-actions">
                     <button
                       type="submit"
-                      disabled={changingPassword || !currentPassword || !newPassword || !confirmPassword}
+                      disabled={changingPassword || (profile.has_password && !currentPassword) || !newPassword || !confirmPassword}
                     >
                       {changingPassword ? 'Changing...' : 'Change Password'}
                     </button>
@@ -451,9 +486,15 @@ export function AccountPage() {
                   </div>
                 </form>
               ) : (
-                <button onClick={() => setShowPasswordForm(true)} className="change-password-btn">
-                  Change Password
-                </button>
+                <>
+                  {passwordError && <div className="error-message">{passwordError}</div>}
+                  {!profile.has_password && !reauthToken && (
+                    <p>You'll sign in with your linked account again before choosing a password.</p>
+                  )}
+                  <button onClick={startSetPassword} className="change-password-btn">
+                    {profile.has_password ? 'Change Password' : 'Set Password'}
+                  </button>
+                </>
               )}
             </section>
 
@@ -516,6 +557,10 @@ export function AccountPage() {
               ) : null}
             </section>
 
+            {auth.token && <ApiKeysSection token={auth.token} isAdmin={auth.isAdmin} />}
+
+            {profile.player && auth.token && <AnonymizeSection token={auth.token} />}
+
             {/* Link Game Identity */}
             <section className="account-section account-link">
               <h2>Link Game Identity</h2>
@@ -0,0 +1,118 @@
+import { useState, useEffect, FormEvent } from 'react'
+import { formatDateTime } from '../utils/formatters'
+import type { AnonymizeStatus } from '../types'
+
+interface AnonymizeSectionProps {
+  token: string
+}
+
+// AnonymizeSection lets a linked user ask for their player to be
+// forgotten. The request waits out a cooling-off period on the hub
+// and can be cancelled until then.
+export function AnonymizeSection({ token }: AnonymizeSectionProps) {
+  const [status, setStatus] = useState<AnonymizeStatus | null>(null)
+  const [showForm, setShowForm] = useState(false)
+  const [password, setPassword] = useState('')
+  const [busy, setBusy] = useState(false)
+  const [error, setError] = useState('')
+
+  useEffect(() => {
+    fetch('/api/account/anonymize', { headers: { Authorization: `Bearer ${token}` } })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => setStatus(data))
+      .catch(() => setStatus(null))
+  }, [token])
+
+  const send = async (method: 'POST' | 'DELETE', body?: object) => {
+    setBusy(true)
+    setError('')
+    try {
+      const res = await fetch('/api/account/anonymize', {
+        method,
+        headers: {
+          Authorization: `Bearer ${token}`,
+          'Content-Type': 'application/json',
+        },
+        body: body ? JSON.stringify(body) : undefined,
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        setError(data.error || 'Request failed')
+        return
+      }
+      setStatus(data)
+      setShowForm(false)
+      setPassword('')
+    } catch {
+      setError('Network error')
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const handleSubmit = (e: FormEvent) => {
+    e.preventDefault()
+    send('POST', { password })
+  }
+
+  if (!status) return null
+
+  return (
+    <section className="account-section account-anonymize">
+      <h2>Forget My Player</h2>
+      {error && <div className="error-message">{error}</div>}
+      {status.status === 'pending' && status.execute_after ? (
+        <>
+          <p className="link-explanation-text">
+            Your player will be anonymized after {formatDateTime(status.execute_after)}.
+            Until then you can change your mind.
+          </p>
+          <button className="cancel-btn" onClick={() => send('DELETE')} disabled={busy}>
+            {busy ? 'Cancelling...' : 'Cancel Request'}
+          </button>
+        </>
+      ) : showForm ? (
+        <form onSubmit={handleSubmit} className="password-form">
+          <div className="form-group">
+            <label>Password</label>
+            <input
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              disabled={busy}
+              autoComplete="current-password"
+            />
+          </div>
+          <div className="form-actions">
+            <button type="submit" disabled={busy || !password}>
+              {busy ? 'Requesting...' : 'Forget Me'}
+            </button>
+            <button
+              type="button"
+              className="cancel-btn"
+              onClick={() => {
+                setShowForm(false)
+                setPassword('')
+                setError('')
+              }}
+            >
+              Cancel
+            </button>
+          </div>
+        </form>
+      ) : (
+        <>
+          <p className="link-explanation-text">
+            Replaces your player's name everywhere with an anonymous one, unlinks it from this
+            account, and deletes your name history and connection IPs. Your past matches stay in
+            the record under the anonymous name. This can't be undone once the 7-day waiting
+            period ends.
+          </p>
+          <button onClick={() => setShowForm(true)} className="change-password-btn">
+            Forget My Player
+          </button>
+        </>
+      )}
+    </section>
+  )
+}
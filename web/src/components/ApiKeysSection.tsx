@@ -0,0 +1,127 @@
+import { useState, useEffect, FormEvent } from 'react'
+import { formatDateTime } from '../utils/formatters'
+import type { ApiKey } from '../types'
+
+interface ApiKeysSectionProps {
+  token: string
+  isAdmin: boolean
+}
+
+// ApiKeysSection lets a user make keys for bots and scripts. A new key
+// is shown once, right after it's made; after that only its prefix is.
+export function ApiKeysSection({ token, isAdmin }: ApiKeysSectionProps) {
+  const [keys, setKeys] = useState<ApiKey[]>([])
+  const [name, setName] = useState('')
+  const [scope, setScope] = useState<'read' | 'admin'>('read')
+  const [created, setCreated] = useState<ApiKey | null>(null)
+  const [busy, setBusy] = useState(false)
+  const [error, setError] = useState('')
+
+  useEffect(() => {
+    fetch('/api/account/api-keys', { headers: { Authorization: `Bearer ${token}` } })
+      .then((res) => (res.ok ? res.json() : []))
+      .then((data) => setKeys(data))
+      .catch(() => setKeys([]))
+  }, [token])
+
+  const send = async (method: 'POST' | 'DELETE', path: string, body?: object) => {
+    setBusy(true)
+    setError('')
+    try {
+      const res = await fetch(path, {
+        method,
+        headers: {
+          Authorization: `Bearer ${token}`,
+          'Content-Type': 'application/json',
+        },
+        body: body ? JSON.stringify(body) : undefined,
+      })
+      const data = await res.json()
+      if (!res.ok) {
+        setError(data.error || 'Request failed')
+        return null
+      }
+      return data
+    } catch {
+      setError('Network error')
+      return null
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const handleCreate = async (e: FormEvent) => {
+    e.preventDefault()
+    const key: ApiKey | null = await send('POST', '/api/account/api-keys', { name, scope })
+    if (!key) return
+    setCreated(key)
+    setKeys((ks) => [{ ...key, key: undefined }, ...ks])
+    setName('')
+  }
+
+  const handleRevoke = async (id: number) => {
+    if (!(await send('DELETE', `/api/account/api-keys/${id}`))) return
+    const now = new Date().toISOString()
+    setKeys((ks) => ks.map((k) => (k.id === id ? { ...k, revoked_at: now } : k)))
+    if (created?.id === id) setCreated(null)
+  }
+
+  return (
+    <section className="account-section account-api-keys">
+      <h2>API Keys</h2>
+      <p className="link-explanation-text">
+        Let a bot or script use the API as you, with <code>Authorization: Bearer &lt;key&gt;</code>.
+        Read keys can only look things up.
+      </p>
+      {error && <div className="error-message">{error}</div>}
+      {created?.key && (
+        <div className="success-message">
+          Copy this key now; it won't be shown again: <code>{created.key}</code>
+        </div>
+      )}
+      {keys.length > 0 && (
+        <dl className="info-list">
+          {keys.map((k) => (
+            <div key={k.id}>
+              <dt>
+                {k.name} <code>{k.prefix}…</code> ({k.scope})
+              </dt>
+              <dd>
+                {k.revoked_at ? (
+                  <>Revoked {formatDateTime(k.revoked_at)}</>
+                ) : (
+                  <>
+                    {k.last_used_at ? `Last used ${formatDateTime(k.last_used_at)}` : 'Never used'}{' '}
+                    <button className="cancel-btn" onClick={() => handleRevoke(k.id)} disabled={busy}>
+                      Revoke
+                    </button>
+                  </>
+                )}
+              </dd>
+            </div>
+          ))}
+        </dl>
+      )}
+      <form onSubmit={handleCreate} className="password-form">
+        <div className="form-group">
+          <label>Name</label>
+          <input type="text" value={name} onChange={(e) => setName(e.target.value)} maxLength={64} disabled={busy} />
+        </div>
+        {isAdmin && (
+          <div className="form-group">
+            <label>Scope</label>
+            <select value={scope} onChange={(e) => setScope(e.target.value as 'read' | 'admin')} disabled={busy}>
+              <option value="read">Read only</option>
+              <option value="admin">Admin</option>
+            </select>
+          </div>
+        )}
+        <div className="form-actions">
+          <button type="submit" disabled={busy || !name.trim()}>
+            {busy ? 'Creating...' : 'Create Key'}
+          </button>
+        </div>
+      </form>
+    </section>
+  )
+}
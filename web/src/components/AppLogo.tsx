@@ -1,11 +1,19 @@
 import { Link } from "react-router-dom";
+import { DEFAULT_SITE_NAME, useBranding } from "../hooks/useBranding";
 
 interface AppLogoProps {
   linkToHome?: boolean;
 }
 
 export function AppLogo({ linkToHome = true }: AppLogoProps) {
-  const img = <img src="/assets/icon-128.png" alt="Trinity" className="app-logo" />;
+  const { site_name, logo_url } = useBranding();
+  const img = (
+    <img
+      src={logo_url || "/assets/icon-128.png"}
+      alt={site_name || DEFAULT_SITE_NAME}
+      className="app-logo"
+    />
+  );
 
   if (linkToHome) {
     return <Link to="/">{img}</Link>;
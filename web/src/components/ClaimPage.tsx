@@ -6,6 +6,8 @@ import { PlayerBadge } from './PlayerBadge'
 import { StatItem } from './StatItem'
 import { Header } from './Header'
 import { useAuth } from '../hooks/useAuth'
+import { useFeatures } from '../hooks/useFeatures'
+import { startOAuth, takeOAuthFragment } from '../utils/oauth'
 import { formatDate, formatDuration } from '../utils/formatters'
 import type { PlayerProfile, AggregatedStats } from '../types'
 
@@ -40,7 +42,7 @@ function ClaimPlayerCard({ player, stats }: { player: PlayerProfile; stats?: Agg
       {stats && stats.completed_matches > 0 && (
         <div className="stats-grid">
           <StatItem label="Matches" value={stats.completed_matches} />
-          <StatItem label="K/D" value={stats.kd_ratio.toFixed(2)} />
+          <StatItem label="K/D" value={stats.kd_ratio?.toFixed(2)} />
           <StatItem label="Frags" value={stats.frags} className="frags" />
           <StatItem label="Deaths" value={stats.deaths} className="deaths" />
           <StatItem label="Victories" value={stats.victories} backgroundIcon="/assets/medals/medal_victory.png" />
@@ -60,11 +62,13 @@ function ClaimPlayerCard({ player, stats }: { player: PlayerProfile; stats?: Agg
 export function ClaimPage() {
   const navigate = useNavigate()
   const { auth, login } = useAuth()
+  const { registration, oauth_providers } = useFeatures()
 
   const [step, setStep] = useState<ClaimStep>('code_entry')
   const [code, setCode] = useState('')
   const [claimInfo, setClaimInfo] = useState<ClaimInfo | null>(null)
-  const [error, setError] = useState('')
+  // Signing up with a provider that failed comes back here with why
+  const [error, setError] = useState(() => takeOAuthFragment('oauth_error')?.get('oauth_error') || '')
   const [loading, setLoading] = useState(false)
 
   // Register form
@@ -105,6 +109,18 @@ export function ClaimPage() {
     }
   }
 
+  // Creating the account by signing in elsewhere claims the player the
+  // same way; the hub sends the browser back signed in.
+  const handleOAuthRegister = async (provider: string) => {
+    setError('')
+    setLoading(true)
+    const err = await startOAuth(`/api/auth/oauth/${provider}/start`, null, { claim_code: code })
+    if (err) {
+      setError(err)
+      setLoading(false)
+    }
+  }
+
   const handleRegister = async (e: FormEvent) => {
     e.preventDefault()
     setError('')
@@ -281,15 +297,17 @@ export function ClaimPage() {
             ) : (
               <div className="claim-choice-section">
                 <div className="claim-actions">
-                  <button
-                    onClick={() => { setStep('register'); setError(''); }}
-                    className="claim-primary-btn"
-                  >
-                    Create Account
-                  </button>
+                  {registration && (
+                    <button
+                      onClick={() => { setStep('register'); setError(''); }}
+                      className="claim-primary-btn"
+                    >
+                      Create Account
+                    </button>
+                  )}
                   <button
                     onClick={() => { setStep('login'); setError(''); }}
-                    className="claim-secondary-btn"
+                    className={registration ? 'claim-secondary-btn' : 'claim-primary-btn'}
                   >
                     I already have an account
                   </button>
@@ -342,6 +360,17 @@ export function ClaimPage() {
                 <button type="submit" disabled={loading} className="claim-primary-btn">
                   {loading ? 'Creating...' : 'Create Account'}
                 </button>
+                {oauth_providers.map((p) => (
+                  <button
+                    key={p.name}
+                    type="button"
+                    disabled={loading}
+                    onClick={() => handleOAuthRegister(p.name)}
+                    className="claim-secondary-btn"
+                  >
+                    Sign up with {p.label}
+                  </button>
+                ))}
                 <button
                   type="button"
                   onClick={() => { setStep('validated'); setError(''); }}
@@ -5,6 +5,7 @@ import { LoginForm } from "./LoginForm";
 import { MySourceButton } from "./MySourceButton";
 import { DiscordButton } from "./DiscordButton";
 import { useAuth } from "../hooks/useAuth";
+import { useBranding } from "../hooks/useBranding";
 
 interface HeaderProps {
   title: string;
@@ -15,6 +16,7 @@ interface HeaderProps {
 
 export function Header({ title, className, linkToHome, showDiscord }: HeaderProps) {
   const { auth, login, logout } = useAuth();
+  const { community_links } = useBranding();
 
   return (
     <header className={className}>
@@ -22,6 +24,17 @@ export function Header({ title, className, linkToHome, showDiscord }: HeaderProp
         <AppLogo linkToHome={linkToHome} />
         {title}
         {showDiscord && <DiscordButton />}
+        {community_links.map((l) => (
+          <a
+            key={l.url}
+            href={l.url}
+            target="_blank"
+            rel="noopener noreferrer"
+            className="community-link"
+          >
+            {l.label}
+          </a>
+        ))}
       </h1>
       <PageNav />
       <div className="auth-section">
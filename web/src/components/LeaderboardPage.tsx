@@ -11,6 +11,7 @@ import { PeriodSelector } from "./PeriodSelector";
 import { GAME_TYPES, type GameTypeFilter } from "../constants/labels";
 import { formatGameType } from "./MatchCard";
 import { formatNumber, stripVRPrefix } from "../utils";
+import { useFeatures } from "../hooks/useFeatures";
 import type {
   LeaderboardResponse,
   LeaderboardCategory,
@@ -61,6 +62,10 @@ const CATEGORY_LABELS: Record<LeaderboardCategory, string> = {
   flag_returns: "Returns",
   assists: "Assists",
   defends: "Defense",
+  sprees: "Spree",
+  accuracy: "Accuracy",
+  skulls: "Skulls",
+  obelisks: "Obelisks",
 };
 
 // Base categories available for all game types
@@ -73,6 +78,8 @@ const BASE_CATEGORIES: LeaderboardCategory[] = [
   "excellents",
   "impressives",
   "humiliations",
+  "sprees",
+  "accuracy",
 ];
 
 // CTF-specific categories
@@ -90,11 +97,15 @@ const ONE_FLAG_CTF_CATEGORIES: LeaderboardCategory[] = [
   "defends",
 ];
 
-// Overload categories (defense only)
-const OVERLOAD_CATEGORIES: LeaderboardCategory[] = ["defends"];
+// Overload categories (obelisks + defense)
+const OVERLOAD_CATEGORIES: LeaderboardCategory[] = ["obelisks", "defends"];
 
-// Harvester categories (assists + defense)
-const HARVESTER_CATEGORIES: LeaderboardCategory[] = ["assists", "defends"];
+// Harvester categories (skulls + assists + defense)
+const HARVESTER_CATEGORIES: LeaderboardCategory[] = [
+  "skulls",
+  "assists",
+  "defends",
+];
 
 function getCategoriesForGameType(
   gameType: GameTypeFilter,
@@ -141,7 +152,14 @@ export function LeaderboardPage() {
   const [searchParams, setSearchParams] = useSearchParams();
   const asOf = searchParams.get("as_of") ?? "";
 
-  const availableCategories = getCategoriesForGameType(gameType);
+  // Hidden stats aren't served by the hub; hiding deaths hides K/D too
+  const { hidden_stats } = useFeatures();
+  const hidden = new Set(hidden_stats);
+  if (hidden.has("deaths")) hidden.add("kd_ratio");
+
+  const availableCategories = getCategoriesForGameType(gameType).filter(
+    (cat) => !hidden.has(cat),
+  );
 
   // Effective category — when a game type doesn't support the persisted
   // category, fall back to "frags" without ever storing the bad value.
@@ -230,6 +248,7 @@ export function LeaderboardPage() {
           <LeaderboardTable
             entries={data.entries}
             category={effectiveCategory}
+            hidden={hidden}
           />
         ) : (
           <div className="leaderboard-empty">
@@ -245,6 +264,7 @@ export function LeaderboardPage() {
 interface LeaderboardTableProps {
   entries: LeaderboardEntry[];
   category: LeaderboardCategory;
+  hidden: Set<string>;
 }
 
 const CORE_STATS_CATEGORIES = [
@@ -257,34 +277,51 @@ const CORE_STATS_CATEGORIES = [
 function LeaderboardTable({
   entries,
   category,
+  hidden,
 }: LeaderboardTableProps) {
   const isCoreStats = CORE_STATS_CATEGORIES.includes(
     category as (typeof CORE_STATS_CATEGORIES)[number],
   );
 
-  const getAwardValue = (entry: LeaderboardEntry): string => {
+  const awardValue = (entry: LeaderboardEntry): number | undefined => {
     switch (category) {
       case "captures":
-        return formatNumber(entry.captures);
+        return entry.captures;
       case "flag_returns":
-        return formatNumber(entry.flag_returns);
+        return entry.flag_returns;
       case "assists":
-        return formatNumber(entry.assists);
+        return entry.assists;
       case "impressives":
-        return formatNumber(entry.impressives);
+        return entry.impressives;
       case "excellents":
-        return formatNumber(entry.excellents);
+        return entry.excellents;
       case "humiliations":
-        return formatNumber(entry.humiliations);
+        return entry.humiliations;
       case "defends":
-        return formatNumber(entry.defends);
+        return entry.defends;
       case "victories":
-        return formatNumber(entry.victories);
+        return entry.victories;
+      case "sprees":
+        return entry.best_spree;
+      case "skulls":
+        return entry.skulls_scored;
+      case "obelisks":
+        return entry.obelisk_destroys;
       default:
-        return "";
+        return undefined;
     }
   };
 
+  const getAwardValue = (entry: LeaderboardEntry): string => {
+    if (category === "accuracy") {
+      return entry.accuracy === undefined
+        ? ""
+        : `${(entry.accuracy * 100).toFixed(1)}%`;
+    }
+    const value = awardValue(entry);
+    return value === undefined ? "" : formatNumber(value);
+  };
+
   const colClass = (col: string) =>
     `stat-col ${category === col ? "sorted-col" : ""}`;
 
@@ -296,9 +333,9 @@ function LeaderboardTable({
             <th className="rank-col">#</th>
             <th className="player-col">Player</th>
             <th className={colClass("matches")}>Matches</th>
-            <th className={colClass("kd_ratio")}>K/D</th>
+            {!hidden.has("kd_ratio") && <th className={colClass("kd_ratio")}>K/D</th>}
             <th className={colClass("frags")}>Frags</th>
-            <th className={colClass("deaths")}>Deaths</th>
+            {!hidden.has("deaths") && <th className={colClass("deaths")}>Deaths</th>}
           </tr>
         </thead>
         <tbody>
@@ -324,11 +361,15 @@ function LeaderboardTable({
               }>
                 {formatNumber(entry.completed_matches)}
               </td>
-              <td className={colClass("kd_ratio")}>
-                {entry.kd_ratio.toFixed(2)}
-              </td>
+              {entry.kd_ratio !== undefined && (
+                <td className={colClass("kd_ratio")}>
+                  {entry.kd_ratio.toFixed(2)}
+                </td>
+              )}
               <td className={colClass("frags")}>{formatNumber(entry.total_frags)}</td>
-              <td className={colClass("deaths")}>{formatNumber(entry.total_deaths)}</td>
+              {entry.total_deaths !== undefined && (
+                <td className={colClass("deaths")}>{formatNumber(entry.total_deaths)}</td>
+              )}
             </tr>
           ))}
         </tbody>
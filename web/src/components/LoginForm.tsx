@@ -1,5 +1,7 @@
 import { useState, FormEvent } from 'react'
 import { Link } from 'react-router-dom'
+import { useFeatures } from '../hooks/useFeatures'
+import { startOAuth, takeOAuthFragment } from '../utils/oauth'
 
 interface LoginFormProps {
   onLogin: (username: string, password: string) => Promise<boolean>
@@ -8,9 +10,22 @@ interface LoginFormProps {
 export function LoginForm({ onLogin }: LoginFormProps) {
   const [username, setUsername] = useState('')
   const [password, setPassword] = useState('')
-  const [error, setError] = useState('')
+  // A failed OAuth sign-in comes back to the home page with its error
+  const [oauthError] = useState(() => takeOAuthFragment('oauth_error')?.get('oauth_error') || '')
+  const [error, setError] = useState(oauthError)
   const [loading, setLoading] = useState(false)
-  const [expanded, setExpanded] = useState(false)
+  const [expanded, setExpanded] = useState(!!oauthError)
+  const { oauth_providers } = useFeatures()
+
+  const handleOAuth = async (provider: string) => {
+    setError('')
+    setLoading(true)
+    const err = await startOAuth(`/api/auth/oauth/${provider}/start`)
+    if (err) {
+      setError(err)
+      setLoading(false)
+    }
+  }
 
   const handleSubmit = async (e: FormEvent) => {
     e.preventDefault()
@@ -57,6 +72,11 @@ export function LoginForm({ onLogin }: LoginFormProps) {
       <button type="submit" disabled={loading || !username || !password}>
         {loading ? '...' : 'Login'}
       </button>
+      {oauth_providers.map((p) => (
+        <button key={p.name} type="button" onClick={() => handleOAuth(p.name)} disabled={loading}>
+          {p.label}
+        </button>
+      ))}
       <button type="button" className="cancel-btn" onClick={() => setExpanded(false)}>
         Cancel
       </button>
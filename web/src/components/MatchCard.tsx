@@ -81,7 +81,8 @@ function demoFilename(match: MatchSummary): string {
   const pad = (n: number) => String(n).padStart(2, '0')
   const date = `${d.getFullYear()}${pad(d.getMonth() + 1)}${pad(d.getDate())}`
   const time = `${pad(d.getHours())}${pad(d.getMinutes())}${pad(d.getSeconds())}`
-  return `${date}_${time}_${match.map_name}.tvd`
+  const ext = match.demo_url?.endsWith('.dm_68') ? '.dm_68' : '.tvd'
+  return `${date}_${time}_${match.map_name}${ext}`
 }
 
 export function MatchCard({ match, onPlayerClick, highlightPlayerId, showPermalink = false }: MatchCardProps) {
@@ -334,6 +335,9 @@ function MatchPlayerRow({ player, showTeam, isWinner, highlightPlayerId, onPlaye
         </span>
       ) : (
         <span className="player-stats">
+          {player.corrected && (
+            <span className="corrected-marker" title="An admin corrected these stats">corrected</span>
+          )}
           <span className="kd">
             <span className="frags">{formatNumber(player.frags)}</span>
             <span className="sep">/</span>
@@ -2,15 +2,26 @@ import { useState, useEffect } from 'react'
 import { Link, useParams, useNavigate } from 'react-router-dom'
 import { MatchCard } from './MatchCard'
 import { Header } from './Header'
-import type { MatchSummary } from '../types'
+import { ColoredText } from './ColoredText'
+import { useAuth } from '../hooks/useAuth'
+import { useFeatures } from '../hooks/useFeatures'
+import { formatNumber } from '../utils'
+import type { MatchSummary, MatchTeam, ScoreboardLine, MapVeto, MatchDemo, ChatLine, FlagTimelineResponse, FlagEventKind } from '../types'
 
 export function MatchDetailPage() {
   const { id } = useParams<{ id: string }>()
   const navigate = useNavigate()
+  const { auth } = useAuth()
+  const { chat_persistence } = useFeatures()
   const [match, setMatch] = useState<MatchSummary | null>(null)
+  const [veto, setVeto] = useState<MapVeto | null>(null)
+  const [chat, setChat] = useState<ChatLine[]>([])
+  const [flags, setFlags] = useState<FlagTimelineResponse | null>(null)
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState<string | null>(null)
 
+  const [reloadKey, setReloadKey] = useState(0)
+
   useEffect(() => {
     async function fetchMatch() {
       if (!id) return
@@ -38,8 +49,40 @@ export function MatchDetailPage() {
     }
 
     fetchMatch()
+  }, [id, reloadKey])
+
+  // Veto history is optional; a 404 just means none was recorded.
+  useEffect(() => {
+    if (!id) return
+    setVeto(null)
+    fetch(`/api/matches/${id}/veto`)
+      .then(res => (res.ok ? res.json() : null))
+      .then(data => setVeto(data))
+      .catch(() => setVeto(null))
   }, [id])
 
+  // Only flag modes have a timeline.
+  const gameType = match?.game_type
+  useEffect(() => {
+    setFlags(null)
+    if (!id || (gameType !== 'ctf' && gameType !== '1fctf')) return
+    fetch(`/api/matches/${id}/flag-timeline`)
+      .then(res => (res.ok ? res.json() : null))
+      .then(data => setFlags(data))
+      .catch(() => setFlags(null))
+  }, [id, gameType])
+
+  // Admins also get tells, so send the token when there is one.
+  useEffect(() => {
+    if (!id || !chat_persistence) return
+    setChat([])
+    const headers: HeadersInit = auth.token ? { Authorization: `Bearer ${auth.token}` } : {}
+    fetch(`/api/matches/${id}/chat`, { headers })
+      .then(res => (res.ok ? res.json() : []))
+      .then(data => setChat(data))
+      .catch(() => setChat([]))
+  }, [id, chat_persistence, auth.token])
+
   const handlePlayerClick = (_playerName: string, _cleanName: string, playerId?: number) => {
     if (playerId) {
       navigate(`/players/${playerId}`)
@@ -65,9 +108,259 @@ export function MatchDetailPage() {
               match={match}
               onPlayerClick={handlePlayerClick}
             />
+            {match.teams && <TeamTotals teams={match.teams} />}
+            {match.scoreboard && <FinalScoreboard lines={match.scoreboard} />}
+            {flags && flags.events.length > 0 && <FlagTimeline timeline={flags} />}
+            {veto && <VetoHistory veto={veto} />}
+            {chat.length > 0 && <MatchChat lines={chat} />}
+            {auth.isAdmin && auth.token && (
+              <DemoUpload
+                matchId={match.id}
+                token={auth.token}
+                onUploaded={() => setReloadKey(k => k + 1)}
+              />
+            )}
           </div>
         ) : null}
       </div>
     </div>
   )
 }
+
+const TEAM_STATS: { key: keyof MatchTeam; label: string }[] = [
+  { key: 'frags', label: 'Frags' },
+  { key: 'deaths', label: 'Deaths' },
+  { key: 'captures', label: 'Captures' },
+  { key: 'assists', label: 'Assists' },
+  { key: 'defends', label: 'Defends' },
+]
+
+// TeamTotals compares the teams' summed stats, leaving out any stat
+// neither team recorded.
+function TeamTotals({ teams }: { teams: MatchTeam[] }) {
+  const [red, blue] = teams
+  const rows = TEAM_STATS.filter(({ key }) => (red[key] as number) > 0 || (blue[key] as number) > 0)
+  if (rows.length === 0) return null
+  return (
+    <div className="team-totals">
+      <h3>Team totals</h3>
+      <table>
+        <thead>
+          <tr>
+            <th />
+            <th className="team-red">Red</th>
+            <th className="team-blue">Blue</th>
+          </tr>
+        </thead>
+        <tbody>
+          {rows.map(({ key, label }) => (
+            <tr key={key}>
+              <td>{label}</td>
+              <td>{formatNumber(red[key] as number)}</td>
+              <td>{formatNumber(blue[key] as number)}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  )
+}
+
+const TEAM_CLASSES: Record<number, string> = { 1: 'team-red', 2: 'team-blue', 3: 'team-spectator' }
+
+// FinalScoreboard is the scoreboard as the server printed it at
+// intermission, names as players had them then.
+function FinalScoreboard({ lines }: { lines: ScoreboardLine[] }) {
+  return (
+    <div className="final-scoreboard">
+      <h3>Final scoreboard</h3>
+      <table>
+        <thead>
+          <tr>
+            <th>Player</th>
+            <th>Score</th>
+            <th>Ping</th>
+          </tr>
+        </thead>
+        <tbody>
+          {lines.map(line => (
+            <tr key={line.client_id} className={TEAM_CLASSES[line.team]}>
+              <td>
+                {line.player_id ? (
+                  <Link to={`/players/${line.player_id}`}><ColoredText text={line.name} /></Link>
+                ) : (
+                  <ColoredText text={line.name} />
+                )}
+              </td>
+              <td>{line.score}</td>
+              <td>{line.ping}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  )
+}
+
+const FLAG_EVENT_VERBS: Record<FlagEventKind, string> = {
+  taken: 'took',
+  dropped: 'dropped',
+  captured: 'captured',
+  returned: 'returned',
+}
+
+// flagName names a flag by its team; One Flag CTF's is neutral.
+function flagName(team: number): string {
+  return team === 1 ? 'the red flag' : team === 2 ? 'the blue flag' : 'the flag'
+}
+
+// formatClock renders a match offset as m:ss.
+function formatClock(ms: number): string {
+  const seconds = Math.floor(ms / 1000)
+  return `${Math.floor(seconds / 60)}:${String(seconds % 60).padStart(2, '0')}`
+}
+
+// FlagTimeline lists who carried the flag longest, then every flag
+// event in order.
+function FlagTimeline({ timeline }: { timeline: FlagTimelineResponse }) {
+  return (
+    <div className="flag-timeline">
+      <h3>Flag timeline</h3>
+      {timeline.carriers.length > 0 && (
+        <table>
+          <thead>
+            <tr>
+              <th>Carrier</th>
+              <th>Carry time</th>
+              <th>Captures</th>
+            </tr>
+          </thead>
+          <tbody>
+            {timeline.carriers.map(c => (
+              <tr key={c.player_id} className={c.team ? TEAM_CLASSES[c.team] : undefined}>
+                <td><Link to={`/players/${c.player_id}`}><ColoredText text={c.name} /></Link></td>
+                <td>{formatClock(c.carry_ms)}</td>
+                <td>{c.captures}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+      <ol>
+        {timeline.events.map((e, i) => (
+          <li key={i} className={`flag-event flag-${e.event} ${TEAM_CLASSES[e.team] ?? ''}`}>
+            <span className="flag-event-time">{formatClock(e.offset_ms)}</span>{' '}
+            {e.client_id < 0 ? (
+              `${flagName(e.team).replace(/^t/, 'T')} returned`
+            ) : (
+              <>
+                {e.player_id ? (
+                  <Link to={`/players/${e.player_id}`}><ColoredText text={e.name ?? ''} /></Link>
+                ) : (
+                  'Someone'
+                )}{' '}
+                {FLAG_EVENT_VERBS[e.event]} {flagName(e.team)}
+              </>
+            )}
+          </li>
+        ))}
+      </ol>
+    </div>
+  )
+}
+
+function VetoHistory({ veto }: { veto: MapVeto }) {
+  const teamName = (side: string) =>
+    side === 'a' ? veto.team_a : side === 'b' ? veto.team_b : ''
+  return (
+    <div className="veto-history">
+      <h3>Map veto: {veto.title}</h3>
+      <ol>
+        {veto.steps.map(step => (
+          <li key={step.seq} className={`veto-step veto-${step.action}`}>
+            {step.action === 'decider' ? (
+              <span className="veto-team">Decider</span>
+            ) : (
+              <span className="veto-team">
+                {teamName(step.side)} {step.action === 'ban' ? 'bans' : 'picks'}
+              </span>
+            )}
+            <span className="veto-map">{step.map_name}</span>
+          </li>
+        ))}
+      </ol>
+    </div>
+  )
+}
+
+function MatchChat({ lines }: { lines: ChatLine[] }) {
+  return (
+    <div className="match-chat">
+      <h3>Chat</h3>
+      <ul>
+        {lines.map(line => (
+          <li key={line.id} className={`chat-line chat-${line.kind}`}>
+            <span className="chat-name">
+              {line.player_id ? (
+                <Link to={`/players/${line.player_id}`}><ColoredText text={line.name} /></Link>
+              ) : (
+                <ColoredText text={line.name} />
+              )}
+              {line.kind === 'say_team' && ' (team)'}
+              {line.kind === 'tell' && <> &rarr; <ColoredText text={line.to_name ?? ''} /></>}
+              :
+            </span>{' '}
+            <ColoredText text={line.message} />
+          </li>
+        ))}
+      </ul>
+    </div>
+  )
+}
+
+// DemoUpload lets an admin attach a client-recorded .dm_68 to a match
+// the server didn't record.
+function DemoUpload({ matchId, token, onUploaded }: { matchId: number; token: string; onUploaded: () => void }) {
+  const [file, setFile] = useState<File | null>(null)
+  const [uploading, setUploading] = useState(false)
+  const [message, setMessage] = useState('')
+
+  const upload = async () => {
+    if (!file) return
+    const form = new FormData()
+    form.append('file', file)
+    setUploading(true)
+    setMessage('')
+    try {
+      const res = await fetch(`/api/admin/matches/${matchId}/demo`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${token}` },
+        body: form,
+      })
+      if (!res.ok) {
+        const body = await res.json().catch(() => null)
+        setMessage(body?.error || `Upload failed (${res.status})`)
+        return
+      }
+      const demo: MatchDemo = await res.json()
+      setMessage(`Uploaded ${demo.filename}`)
+      setFile(null)
+      onUploaded()
+    } catch (e) {
+      setMessage(`Upload failed: ${(e as Error).message}`)
+    } finally {
+      setUploading(false)
+    }
+  }
+
+  return (
+    <div className="demo-upload">
+      <h3>Upload demo</h3>
+      <input type="file" accept=".dm_68" onChange={e => setFile(e.target.files?.[0] ?? null)} />
+      <button onClick={upload} disabled={!file || uploading}>
+        {uploading ? 'Uploading...' : 'Upload'}
+      </button>
+      {message && <span className="demo-upload-message">{message}</span>}
+    </div>
+  )
+}
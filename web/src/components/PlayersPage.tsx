@@ -121,7 +121,7 @@ export function PlayersPage() {
                     ? `${stats.stats.completed_matches} completed, ${stats.stats.uncompleted_matches} incomplete`
                     : undefined}
                 />
-                <StatItem label="K/D" value={stats.stats.kd_ratio.toFixed(2)} />
+                <StatItem label="K/D" value={stats.stats.kd_ratio?.toFixed(2)} />
                 <StatItem label="Frags" value={stats.stats.frags} className="frags" />
                 <StatItem label="Deaths" value={stats.stats.deaths} className="deaths" />
                 <StatItem label="Victories" value={stats.stats.victories} backgroundIcon="/assets/medals/medal_victory.png" />
@@ -132,6 +132,10 @@ export function PlayersPage() {
                 <StatItem label="Returns" value={stats.stats.flag_returns} backgroundIcon="/assets/flags/flag_in_base_red.png" />
                 <StatItem label="Assists" value={stats.stats.assists} backgroundIcon="/assets/medals/medal_assist.png" />
                 <StatItem label="Defense" value={stats.stats.defends} backgroundIcon="/assets/medals/medal_defend.png" />
+                <StatItem label="Best Spree" value={stats.stats.best_spree} />
+                <StatItem label="Win Streak" value={stats.stats.longest_win_streak} />
+                {(stats.stats.skulls_scored ?? 0) > 0 && <StatItem label="Skulls" value={stats.stats.skulls_scored} />}
+                {(stats.stats.obelisk_destroys ?? 0) > 0 && <StatItem label="Obelisks" value={stats.stats.obelisk_destroys} />}
               </div>
 
               {stats.names && (() => {
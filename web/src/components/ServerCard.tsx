@@ -170,7 +170,7 @@ function useInterpolatedTime(server: ServerStatus, timeLimitMinutes: number | nu
   // (offset past warmup_remaining) for a smooth handoff.
   let gameTimeMs: number
   if (server.match_state === 'active' || server.match_state === 'overtime') {
-    gameTimeMs = server.game_time_ms + offset
+    gameTimeMs = (server.match_clock_ms ?? server.game_time_ms) + offset
   } else if (server.match_state === 'warmup' && server.warmup_remaining !== undefined) {
     gameTimeMs = Math.max(0, offset - server.warmup_remaining)
   } else {
@@ -0,0 +1,94 @@
+import { Fragment, useState, useEffect } from 'react'
+import { useFeatures } from '../hooks/useFeatures'
+import { formatDate } from '../utils/formatters'
+import { startOAuth, takeOAuthFragment } from '../utils/oauth'
+import type { UserIdentity } from '../types'
+
+interface SignInMethodsProps {
+  token: string
+}
+
+// SignInMethods lists the external providers the hub offers and lets
+// the user link or unlink one. Linking leaves for the provider and
+// comes back to the account page with the result in the fragment.
+export function SignInMethods({ token }: SignInMethodsProps) {
+  const { oauth_providers } = useFeatures()
+  const [identities, setIdentities] = useState<UserIdentity[]>([])
+  const [result] = useState(() => takeOAuthFragment('oauth_linked', 'oauth_error'))
+  const [busy, setBusy] = useState(false)
+  const [error, setError] = useState(result?.get('oauth_error') || '')
+
+  useEffect(() => {
+    fetch('/api/account/identities', { headers: { Authorization: `Bearer ${token}` } })
+      .then((res) => (res.ok ? res.json() : []))
+      .then((data) => setIdentities(data))
+      .catch(() => setIdentities([]))
+  }, [token])
+
+  const link = async (provider: string) => {
+    setBusy(true)
+    setError('')
+    const err = await startOAuth(`/api/account/identities/${provider}`, token)
+    if (err) {
+      setError(err)
+      setBusy(false)
+    }
+  }
+
+  const unlink = async (provider: string) => {
+    setBusy(true)
+    setError('')
+    try {
+      const res = await fetch(`/api/account/identities/${provider}`, {
+        method: 'DELETE',
+        headers: { Authorization: `Bearer ${token}` },
+      })
+      if (!res.ok) {
+        const data = await res.json()
+        setError(data.error || 'Failed to unlink')
+        return
+      }
+      setIdentities((ids) => ids.filter((id) => id.provider !== provider))
+    } catch {
+      setError('Network error')
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  if (oauth_providers.length === 0 && identities.length === 0) return null
+
+  const linkedLabel = oauth_providers.find((p) => p.name === result?.get('oauth_linked'))?.label
+
+  return (
+    <section className="account-section account-sign-in-methods">
+      <h2>Sign-in Methods</h2>
+      {linkedLabel && <div className="success-message">Linked your {linkedLabel} account.</div>}
+      {error && <div className="error-message">{error}</div>}
+      <dl className="info-list">
+        {oauth_providers.map((p) => {
+          const identity = identities.find((id) => id.provider === p.name)
+          return (
+            <Fragment key={p.name}>
+              <dt>{p.label}</dt>
+              <dd>
+                {identity ? (
+                  <>
+                    {identity.display_name || 'Linked'} since {formatDate(identity.linked_at)}{' '}
+                    <button className="cancel-btn" onClick={() => unlink(p.name)} disabled={busy}>
+                      Unlink
+                    </button>
+                  </>
+                ) : (
+                  <button onClick={() => link(p.name)} disabled={busy}>
+                    Link
+                  </button>
+                )}
+              </dd>
+            </Fragment>
+          )
+        })}
+      </dl>
+    </section>
+  )
+}
@@ -0,0 +1,18 @@
+import { useBranding } from "../hooks/useBranding";
+
+// SiteFooter shows the operator's footer links from the branding
+// config. Renders nothing when none are configured.
+export function SiteFooter() {
+  const { footer_links } = useBranding();
+  if (footer_links.length === 0) return null;
+
+  return (
+    <footer className="site-footer">
+      {footer_links.map((l) => (
+        <a key={l.url} href={l.url} target="_blank" rel="noopener noreferrer">
+          {l.label}
+        </a>
+      ))}
+    </footer>
+  );
+}
@@ -2,7 +2,8 @@ import { formatNumber } from "../utils"
 
 export interface StatItemProps {
   label: string
-  value: number | string
+  // Nothing renders when undefined, e.g. a stat the hub hides
+  value: number | string | undefined
   className?: string
   subscript?: number
   title?: string
@@ -10,6 +11,7 @@ export interface StatItemProps {
 }
 
 export function StatItem({ label, value, className, subscript, title, backgroundIcon }: StatItemProps) {
+  if (value === undefined) return null
   const displayValue = typeof value === 'number' ? formatNumber(value) : value
   const displaySubscript = subscript !== undefined && subscript > 0 ? formatNumber(subscript) : null
 
@@ -4,7 +4,7 @@ import { useAuth } from '../../hooks/useAuth'
 import { useDebouncedValue } from '../../hooks/useDebouncedValue'
 import { ColoredText } from '../ColoredText'
 import { formatDate } from '../../utils/formatters'
-import type { PlayerProfile, PlayerGUID } from '../../types'
+import type { PlayerProfile, PlayerGUID, LeaderboardExclusion } from '../../types'
 
 export function AdminPlayers() {
   const { auth } = useAuth()
@@ -28,8 +28,30 @@ export function AdminPlayers() {
   const [splitting, setSplitting] = useState<number | null>(null)
   const [error, setError] = useState('')
 
+  // Leaderboard exclusions
+  const [exclusions, setExclusions] = useState<LeaderboardExclusion[]>([])
+  const [exclusionReason, setExclusionReason] = useState('')
+  const [savingExclusion, setSavingExclusion] = useState(false)
+
   const headers = { Authorization: `Bearer ${token}` }
 
+  const fetchExclusions = useCallback(
+    () => {
+      fetch('/api/admin/leaderboard-exclusions', { headers })
+        .then((res) => (res.ok ? res.json() : []))
+        .then((data: LeaderboardExclusion[]) => setExclusions(data ?? []))
+        .catch(() => setExclusions([]))
+    },
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+    [token],
+  )
+
+  useEffect(() => {
+    fetchExclusions()
+  }, [fetchExclusions])
+
+  const selectedExclusion = selected ? exclusions.find((e) => e.player_id === selected.id) : undefined
+
   useEffect(() => {
     if (debouncedSearchQuery.trim().length < 2) {
       setSearchResults([])
@@ -73,6 +95,7 @@ export function AdminPlayers() {
     setSearchQuery('')
     setMergeQuery('')
     setMergeResults([])
+    setExclusionReason('')
     setError('')
   }
 
@@ -151,6 +174,30 @@ export function AdminPlayers() {
     }
   }
 
+  const handleExclusion = async (excluded: boolean) => {
+    if (!selected) return
+
+    setSavingExclusion(true)
+    setError('')
+    try {
+      const res = await fetch(`/api/admin/players/${selected.id}/leaderboard-exclusion`, {
+        method: 'PUT',
+        headers: { ...headers, 'Content-Type': 'application/json' },
+        body: JSON.stringify({ excluded, reason: exclusionReason }),
+      })
+      if (!res.ok) {
+        const data = await res.json()
+        throw new Error(data.error || 'Update failed')
+      }
+      setExclusionReason('')
+      fetchExclusions()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Update failed')
+    } finally {
+      setSavingExclusion(false)
+    }
+  }
+
   return (
     <div className="admin-players">
       <div className="admin-section-header">
@@ -217,6 +264,35 @@ export function AdminPlayers() {
             )}
           </div>
 
+          <div className="admin-subsection">
+            <h4>Leaderboards</h4>
+            {selectedExclusion ? (
+              <div className="merge-search-input exclusion-status">
+                <span className="exclusion-reason">Excluded: {selectedExclusion.reason}</span>
+                <button onClick={() => handleExclusion(false)} disabled={savingExclusion}>
+                  {savingExclusion ? 'Saving…' : 'Restore'}
+                </button>
+              </div>
+            ) : (
+              <div className="merge-search-input exclusion-status">
+                <input
+                  type="text"
+                  placeholder="Reason (e.g. confirmed cheater, staff test account)"
+                  value={exclusionReason}
+                  maxLength={500}
+                  onChange={(e) => setExclusionReason(e.target.value)}
+                />
+                <button
+                  onClick={() => handleExclusion(true)}
+                  disabled={savingExclusion || exclusionReason.trim() === ''}
+                >
+                  {savingExclusion ? 'Saving…' : 'Exclude'}
+                </button>
+              </div>
+            )}
+            <p className="admin-hint">Excluded players are left off every leaderboard. Their matches stay visible.</p>
+          </div>
+
           <div className="admin-subsection">
             <h4>Merge Another Player Into This One</h4>
             <div className="merge-search-input">
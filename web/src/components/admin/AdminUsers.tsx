@@ -112,6 +112,24 @@ export function AdminUsers() {
     }
   }
 
+  const handleRevokeTokens = async (userId: number, username: string) => {
+    if (!confirm(`Log "${username}" out on every device?`)) return
+
+    try {
+      const res = await fetch(`/api/users/${userId}/revoke-tokens`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${token}` },
+      })
+
+      if (!res.ok) {
+        const data = await res.json()
+        setError(data.error || 'Failed to log user out')
+      }
+    } catch {
+      setError('Network error')
+    }
+  }
+
   const handleResetPassword = async (userId: number) => {
     if (newPassword.length < 8) {
       setError('Password must be at least 8 characters')
@@ -414,6 +432,9 @@ export function AdminUsers() {
                 ) : (
                   <>
                     <button onClick={() => setResetPasswordUserId(user.id)}>Reset Pwd</button>
+                    {user.username !== currentUsername && (
+                      <button onClick={() => handleRevokeTokens(user.id, user.username)}>Log Out</button>
+                    )}
                     {user.username !== currentUsername && (
                       <button
                         className="delete-btn"
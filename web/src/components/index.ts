@@ -16,6 +16,7 @@ export { PlayerBadge } from './PlayerBadge'
 export { PageNav } from './PageNav'
 export { FlagIcon } from './FlagIcon'
 export { AppLogo } from './AppLogo'
+export { SiteFooter } from './SiteFooter'
 export { MatchCard } from './MatchCard'
 export { MatchesPage } from './MatchesPage'
 export { MatchDetailPage } from './MatchDetailPage'
@@ -1,7 +1,9 @@
 import { useState, useEffect, useCallback, createContext, useContext, createElement, type ReactNode } from 'react'
 import type { AuthState, LoginCredentials } from '../types'
+import { takeOAuthFragment } from '../utils/oauth'
 
 const TOKEN_KEY = 'q3a_auth_token'
+const REFRESH_KEY = 'q3a_refresh_token'
 
 interface AuthContextType {
   auth: AuthState
@@ -36,32 +38,69 @@ export function AuthProvider({ children }: AuthProviderProps) {
   })
   const [loading, setLoading] = useState(true)
 
-  // Check existing token on mount
+  // Check existing token on mount, falling back to the refresh token
+  // once the access token has expired or been revoked
   useEffect(() => {
+    // Coming back from an OAuth sign-in, the session is in the fragment
+    const oauth = takeOAuthFragment('oauth_token')
+    if (oauth) {
+      localStorage.setItem(TOKEN_KEY, oauth.get('oauth_token') || '')
+      localStorage.setItem(REFRESH_KEY, oauth.get('oauth_refresh_token') || '')
+    }
     const token = localStorage.getItem(TOKEN_KEY)
-    if (!token) {
+    const refreshToken = localStorage.getItem(REFRESH_KEY)
+    if (!token && !refreshToken) {
       // eslint-disable-next-line react-hooks/set-state-in-effect
       setLoading(false)
       return
     }
     let cancelled = false
-    fetch('/api/auth/check', {
-      headers: { Authorization: `Bearer ${token}` },
-    })
-      .then(res => res.json())
-      .then(data => {
-        if (cancelled) return
-        if (data.authenticated) {
-          setAuth({
-            isAuthenticated: true,
-            username: data.username,
-            token,
-            isAdmin: data.is_admin || false,
-            playerId: data.player_id || null,
-            passwordChangeRequired: data.password_change_required || false,
-          })
-        } else {
+    const refresh = async () => {
+      if (!refreshToken) return false
+      const res = await fetch('/api/auth/refresh', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      })
+      if (!res.ok) return false
+      const data = await res.json()
+      if (cancelled) return true
+      localStorage.setItem(TOKEN_KEY, data.token)
+      localStorage.setItem(REFRESH_KEY, data.refresh_token)
+      setAuth({
+        isAuthenticated: true,
+        username: data.username,
+        token: data.token,
+        isAdmin: data.is_admin || false,
+        playerId: data.player_id || null,
+        passwordChangeRequired: data.password_change_required || false,
+      })
+      return true
+    }
+    const check = async () => {
+      if (!token) return false
+      const res = await fetch('/api/auth/check', {
+        headers: { Authorization: `Bearer ${token}` },
+      })
+      const data = await res.json()
+      if (!data.authenticated) return false
+      if (cancelled) return true
+      setAuth({
+        isAuthenticated: true,
+        username: data.username,
+        token,
+        isAdmin: data.is_admin || false,
+        playerId: data.player_id || null,
+        passwordChangeRequired: data.password_change_required || false,
+      })
+      return true
+    }
+    check()
+      .then(ok => ok || refresh())
+      .then(ok => {
+        if (!ok && !cancelled) {
           localStorage.removeItem(TOKEN_KEY)
+          localStorage.removeItem(REFRESH_KEY)
         }
       })
       .catch(() => {
@@ -85,6 +124,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
 
       const data = await res.json()
       localStorage.setItem(TOKEN_KEY, data.token)
+      if (data.refresh_token) localStorage.setItem(REFRESH_KEY, data.refresh_token)
       setAuth({
         isAuthenticated: true,
         username: data.username,
@@ -100,7 +140,16 @@ export function AuthProvider({ children }: AuthProviderProps) {
   }, [])
 
   const logout = useCallback(() => {
+    const refreshToken = localStorage.getItem(REFRESH_KEY)
+    if (refreshToken) {
+      fetch('/api/auth/logout', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      }).catch(() => {})
+    }
     localStorage.removeItem(TOKEN_KEY)
+    localStorage.removeItem(REFRESH_KEY)
     setAuth({
       isAuthenticated: false,
       username: null,
@@ -111,7 +160,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
     })
   }, [])
 
-  const changePassword = useCallback(async (currentPassword: string, newPassword: string): Promise<{ success: boolean; error?: string }> => {
+  const changePassword = useCallback(async (currentPassword: string, newPassword: string, reauthToken?: string): Promise<{ success: boolean; error?: string }> => {
     try {
       const res = await fetch('/api/auth/change-password', {
         method: 'POST',
@@ -122,6 +171,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
         body: JSON.stringify({
           current_password: currentPassword,
           new_password: newPassword,
+          reauth_token: reauthToken,
         }),
       })
 
@@ -133,6 +183,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
       // Update token after password change
       if (data.token) {
         localStorage.setItem(TOKEN_KEY, data.token)
+        if (data.refresh_token) localStorage.setItem(REFRESH_KEY, data.refresh_token)
         setAuth(prev => ({
           ...prev,
           token: data.token,
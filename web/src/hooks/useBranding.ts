@@ -0,0 +1,58 @@
+import { useEffect, useState } from 'react'
+import type { Branding } from '../types'
+
+const DEFAULT_BRANDING: Branding = {
+  footer_links: [],
+  community_links: [],
+}
+
+export const DEFAULT_SITE_NAME = 'Trinity'
+
+let cached: Branding | null = null
+let inflight: Promise<Branding> | null = null
+
+// Theme keys map onto the CSS variables in index.css.
+const THEME_VARS: Record<string, string> = {
+  background: '--bg',
+  card: '--bg-card',
+  text: '--text',
+  accent: '--accent',
+}
+
+function applyBranding(b: Branding) {
+  if (b.site_name) document.title = b.site_name
+  const root = document.documentElement
+  for (const [key, value] of Object.entries(b.theme ?? {})) {
+    if (value && THEME_VARS[key]) root.style.setProperty(THEME_VARS[key], value)
+  }
+}
+
+// useBranding fetches /api/config/branding once per page load and
+// applies the title and theme colors. Like features, branding only
+// changes on a restart, so there's no refresh.
+export function useBranding(): Branding {
+  const [branding, setBranding] = useState<Branding>(cached ?? DEFAULT_BRANDING)
+
+  useEffect(() => {
+    if (cached) return
+    let cancelled = false
+    if (!inflight) {
+      inflight = fetch('/api/config/branding')
+        .then(r => (r.ok ? r.json() : DEFAULT_BRANDING))
+        .catch(() => DEFAULT_BRANDING)
+        .then((b: Branding) => {
+          cached = b
+          applyBranding(b)
+          return b
+        })
+    }
+    inflight.then(b => {
+      if (!cancelled) setBranding(b)
+    })
+    return () => {
+      cancelled = true
+    }
+  }, [])
+
+  return branding
+}
@@ -0,0 +1,45 @@
+import { useEffect, useState } from 'react'
+import type { Features } from '../types'
+
+// Everything optional defaults on until the hub says otherwise, so a
+// slow or failed fetch never hides a working page.
+const DEFAULT_FEATURES: Features = {
+  demos: true,
+  registration: true,
+  ratings: false,
+  chat_persistence: false,
+  hidden_stats: [],
+  oauth_providers: [],
+}
+
+let cached: Features | null = null
+let inflight: Promise<Features> | null = null
+
+// useFeatures fetches /api/config/features once per page load. The
+// switches only change when the operator edits config and restarts,
+// so there's no refresh.
+export function useFeatures(): Features {
+  const [features, setFeatures] = useState<Features>(cached ?? DEFAULT_FEATURES)
+
+  useEffect(() => {
+    if (cached) return
+    let cancelled = false
+    if (!inflight) {
+      inflight = fetch('/api/config/features')
+        .then(r => (r.ok ? r.json() : DEFAULT_FEATURES))
+        .catch(() => DEFAULT_FEATURES)
+        .then((f: Features) => {
+          cached = f
+          return f
+        })
+    }
+    inflight.then(f => {
+      if (!cancelled) setFeatures(f)
+    })
+    return () => {
+      cancelled = true
+    }
+  }, [])
+
+  return features
+}
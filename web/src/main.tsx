@@ -2,7 +2,7 @@ import { StrictMode } from 'react'
 import { createRoot } from 'react-dom/client'
 import { BrowserRouter, Routes, Route, Navigate } from 'react-router-dom'
 import App from './App'
-import { PlayersPage, AccountPage, LeaderboardPage, MatchesPage, MatchDetailPage, DemoPlayerPage, PlayPage, DocsPage, ClaimPage } from './components'
+import { PlayersPage, AccountPage, LeaderboardPage, MatchesPage, MatchDetailPage, DemoPlayerPage, PlayPage, DocsPage, ClaimPage, SiteFooter } from './components'
 import { Quake3EulaPage } from './components/Quake3EulaPage'
 import { DocsGettingStarted } from './components/docs/DocsGettingStarted'
 import { DocsFeatures } from './components/docs/DocsFeatures'
@@ -51,6 +51,7 @@ createRoot(document.getElementById('root')!).render(
           <Route path="/claim" element={<ClaimPage />} />
           <Route path="/quake3-eula" element={<Quake3EulaPage />} />
         </Routes>
+        <SiteFooter />
       </BrowserRouter>
     </AuthProvider>
   </StrictMode>,
@@ -57,6 +57,10 @@ export interface ServerStatus {
   server_vars?: Record<string, string>
   match_state?: 'waiting' | 'warmup' | 'active' | 'overtime' | 'intermission'
   warmup_remaining?: number // milliseconds remaining in warmup
+  match_clock_ms?: number // milliseconds into the match, during play
+  time_remaining_ms?: number // milliseconds left before the timelimit
+  clock_source?: 'server' | 'log'
+  query_only?: boolean // polled without a log; players aren't identified
 }
 
 export interface Server {
@@ -74,6 +78,10 @@ export interface Server {
   // Always false for unauthenticated callers.
   manageable_by_me?: boolean
   admin_delegation_enabled?: boolean
+  commands_disabled?: boolean
+  // Polled for status and population only; no matches are recorded.
+  query_only?: boolean
+  clock_offset_ms?: number
 }
 
 export type EventType =
@@ -95,12 +103,16 @@ export type EventType =
   | 'tell'
   | 'say_rcon'
   | 'award'
+  | 'player_return'
+  | 'team_balance'
 
 export interface WSEvent {
   event: EventType
   server_id: number
   timestamp: string
   data: unknown
+  // Numbers broadcast events so /api/events/poll can pick up after it
+  seq?: number
 }
 
 export interface PlayerJoinData {
@@ -118,6 +130,19 @@ export interface MatchStartData {
   game_type: string
 }
 
+export interface FragData {
+  fragger: string
+  victim: string
+  weapon: string
+  weapon_slug: string
+  fragger_team?: number
+  victim_team?: number
+  revenge?: boolean
+  ended_spree?: number
+  fragger_player_id?: number
+  victim_player_id?: number
+}
+
 export interface FlagCaptureData {
   client_num: number
   player_name: string
@@ -204,6 +229,30 @@ export interface AwardData {
   victim_player_id?: number
 }
 
+export interface PlayerReturnData {
+  client_num: number
+  player_name: string
+  days_away: number
+  player_id?: number
+}
+
+export interface TeamBalanceMove {
+  client_num: number
+  player_name: string
+  to_team: number // 1=Red, 2=Blue
+  player_id?: number
+}
+
+export interface TeamBalanceData {
+  reason: 'size' | 'score'
+  red_count: number
+  blue_count: number
+  red_score: number
+  blue_score: number
+  moves: TeamBalanceMove[]
+  enforced: boolean
+}
+
 export interface ActivityPlayer {
   name: string
   cleanName: string
@@ -225,6 +274,8 @@ export type ActivityType =
   | 'obelisk_destroy'
   | 'skull_score'
   | 'team_change'
+  | 'player_return'
+  | 'team_balance'
 
 export interface ActivityItem {
   id: number
@@ -263,6 +314,9 @@ export interface MatchPlayerSummary {
   victories?: number
   captures?: number
   assists?: number
+  skulls_scored?: number
+  obelisk_destroys?: number
+  corrected?: boolean
 }
 
 export interface MatchSummary {
@@ -276,12 +330,158 @@ export interface MatchSummary {
   started_at: string
   ended_at?: string
   exit_reason?: string
+  abandoned?: boolean
+  overtime?: boolean
+  overtime_seconds?: number
   players: MatchPlayerSummary[]
   red_score?: number
   blue_score?: number
   demo_url?: string
   movement?: string
   gameplay?: string
+  // Only on a single match, from /api/matches/{id}
+  duration_seconds?: number
+  teams?: MatchTeam[]
+  winning_team?: number
+  winner_ids?: number[]
+  scoreboard?: ScoreboardLine[]
+  warmup?: WarmupLine[]
+}
+
+export type FlagEventKind = 'taken' | 'dropped' | 'captured' | 'returned'
+
+export interface FlagTimelineEvent {
+  at: string
+  offset_ms: number
+  event: FlagEventKind
+  team: number
+  client_id: number
+  player_id?: number
+  name?: string
+}
+
+export interface FlagCarrier {
+  player_id: number
+  name: string
+  team?: number
+  carry_ms: number
+  captures: number
+}
+
+// GET /api/matches/{id}/flag-timeline
+export interface FlagTimelineResponse {
+  match_id: number
+  events: FlagTimelineEvent[]
+  carriers: FlagCarrier[]
+}
+
+export interface ScoreboardLine {
+  client_id: number
+  player_id?: number
+  name: string
+  score: number
+  ping: number
+  team: number
+}
+
+// Warmup frags are for fun; they never count toward the match.
+export interface WarmupLine {
+  player_id: number
+  name: string
+  frags: number
+  deaths: number
+}
+
+export interface MatchTeam {
+  team: number
+  score?: number
+  player_ids: number[]
+  frags: number
+  deaths: number
+  captures: number
+  assists: number
+  defends: number
+  skulls_scored: number
+  obelisk_destroys: number
+}
+
+export interface MapVetoStep {
+  seq: number
+  side: '' | 'a' | 'b'
+  action: 'ban' | 'pick' | 'decider'
+  map_name: string
+}
+
+export interface MapVeto {
+  id: number
+  title: string
+  team_a: string
+  team_b: string
+  match_id?: number
+  created_at: string
+  steps: MapVetoStep[]
+}
+
+// Optional-feature switches from /api/config/features
+export interface Features {
+  demos: boolean
+  registration: boolean
+  ratings: boolean
+  chat_persistence: boolean
+  // Leaderboard categories the hub doesn't serve
+  hidden_stats: string[]
+  // External identity providers users can sign in with
+  oauth_providers: OAuthProviderInfo[]
+}
+
+export interface OAuthProviderInfo {
+  name: string
+  label: string
+}
+
+export interface ApiKey {
+  id: number
+  user_id: number
+  username: string
+  name: string
+  prefix: string
+  scope: 'read' | 'admin'
+  created_at: string
+  last_used_at?: string
+  revoked_at?: string
+  // Only on the response that creates it
+  key?: string
+}
+
+export interface UserIdentity {
+  provider: string
+  display_name: string
+  linked_at: string
+  last_login_at?: string
+}
+
+export interface BrandingLink {
+  label: string
+  url: string
+}
+
+export interface Branding {
+  site_name?: string
+  logo_url?: string
+  theme?: {
+    background?: string
+    card?: string
+    text?: string
+    accent?: string
+  }
+  footer_links: BrandingLink[]
+  community_links: BrandingLink[]
+}
+
+export interface AnonymizeStatus {
+  status: 'none' | 'pending'
+  requested_at?: string
+  execute_after?: string
 }
 
 // Auth types
@@ -310,10 +510,23 @@ export interface User {
   last_login: string | null
 }
 
+export interface Impersonation {
+  id: number
+  admin_user_id?: number
+  admin_username: string
+  user_id?: number
+  username: string
+  reason: string
+  remote_addr: string
+  started_at: string
+  expires_at: string
+}
+
 export interface AccountProfile {
   user: User
   player?: PlayerProfile
   guids?: PlayerGUID[]
+  has_password: boolean
 }
 
 export interface RconCommand {
@@ -325,21 +538,26 @@ export interface RconCommand {
 }
 
 // Player stats types
+// Stats the hub hides (features.hidden_stats) are left out entirely
 export interface AggregatedStats {
   matches: number
   completed_matches: number
   uncompleted_matches: number
   frags: number
-  deaths: number
-  kd_ratio: number
-  captures: number
-  flag_returns: number
-  assists: number
-  impressives: number
-  excellents: number
-  humiliations: number
-  defends: number
-  victories: number
+  deaths?: number
+  kd_ratio?: number
+  captures?: number
+  flag_returns?: number
+  assists?: number
+  impressives?: number
+  excellents?: number
+  humiliations?: number
+  defends?: number
+  victories?: number
+  best_spree?: number
+  longest_win_streak?: number
+  skulls_scored?: number
+  obelisk_destroys?: number
 }
 
 export interface PlayerGUID {
@@ -389,6 +607,14 @@ export interface PlayerName {
   last_seen: string
 }
 
+// Set by the account owner with PATCH /api/account/profile.
+export interface ProfileCustomization {
+  avatar_url?: string
+  preferred_model?: string
+  bio?: string
+  country_code?: string
+}
+
 export interface PlayerProfile {
   id: number
   name: string
@@ -402,9 +628,430 @@ export interface PlayerProfile {
   is_admin?: boolean
   model?: string
   skill?: number
+  leaderboard_excluded?: boolean
+  is_vip?: boolean
+  profile?: ProfileCustomization
+  season_wins?: SeasonWin[]
   guids?: PlayerGUID[]
 }
 
+export interface MatchDemo {
+  id: number
+  match_id: number
+  filename: string
+  size_bytes: number
+  uploaded_by?: number
+  uploaded_at: string
+  last_downloaded_at?: string
+  url: string
+}
+
+export interface DemoListing extends MatchDemo {
+  server_id: number
+  server_key: string
+  source: string
+  map_name: string
+  game_type: string
+  started_at: string
+  ended_at?: string
+  duration_seconds: number
+  player_count: number
+}
+
+export interface DemoListResponse {
+  total: number
+  demos: DemoListing[]
+}
+
+export interface ChatLine {
+  id: number
+  kind: 'say' | 'say_team' | 'tell'
+  player_id?: number
+  name: string
+  team?: number
+  to_player_id?: number
+  to_name?: string
+  message: string
+  sent_at: string
+}
+
+export interface DuelLadderEntry {
+  position: number
+  player: Player
+  wins: number
+  losses: number
+  last_duel_at: string
+}
+
+export interface DuelChallenge {
+  match_id: number
+  played_at: string
+  map_name: string
+  winner: Player
+  loser: Player
+  winner_frags: number
+  loser_frags: number
+  winner_from: number
+  loser_from: number
+  winner_to: number
+  upset: boolean
+}
+
+export interface DuelLadderResponse {
+  as_of: string
+  decay_days: number
+  entries: DuelLadderEntry[]
+  challenges: DuelChallenge[]
+}
+
+export interface DuelRecord {
+  player: Player
+  position: number
+  wins: number
+  losses: number
+  win_rate: number
+  frags_for: number
+  frags_against: number
+  last_duel_at: string
+}
+
+export interface DuelHeadToHead {
+  opponent: Player
+  wins: number
+  losses: number
+  frags_for: number
+  frags_against: number
+  last_duel_at: string
+}
+
+export interface DuelListResponse {
+  as_of: string
+  duels: DuelChallenge[]
+}
+
+export interface DuelRankingsResponse {
+  as_of: string
+  entries: DuelRecord[]
+}
+
+export interface PlayerDuelsResponse {
+  as_of: string
+  record: DuelRecord | null
+  opponents: DuelHeadToHead[]
+  recent: DuelChallenge[]
+}
+
+export interface Clan {
+  id: number
+  name: string
+  tag: string
+  created_by?: number
+  created_at: string
+  member_count: number
+}
+
+export interface ClanMember {
+  player: PlayerProfile
+  role: 'leader' | 'member'
+  source: 'joined' | 'added' | 'tag'
+  joined_at: string
+}
+
+export interface ClanStats {
+  matches: number
+  completed_matches: number
+  frags: number
+  deaths: number
+  kd_ratio: number
+  captures: number
+  assists: number
+  defends: number
+  victories: number
+  clan_wins: number
+  clan_losses: number
+}
+
+export interface ClanResponse {
+  clan: Clan
+  members: ClanMember[]
+  period: string
+  stats: ClanStats
+}
+
+export interface ClanLeaderboardEntry {
+  rank: number
+  clan: Clan
+  stats: ClanStats
+}
+
+export interface ClanLeaderboardResponse {
+  category: string
+  period: string
+  period_start?: string
+  period_end?: string
+  entries: ClanLeaderboardEntry[]
+}
+
+export interface ClanMatch {
+  match_id: number
+  map_name: string
+  game_type: string
+  ended_at: string
+  team: number
+  opponent: Clan
+  score?: number
+  opponent_score?: number
+  result: 'win' | 'loss' | 'tie'
+  players: number
+  opponent_players: number
+}
+
+export interface PlayerMapStats {
+  map_name: string
+  matches: number
+  victories: number
+  win_rate: number
+  frags: number
+  deaths: number
+  kd_ratio: number
+  last_played: string
+}
+
+export interface PlayerMapsResponse {
+  player_id: number
+  period: TimePeriod
+  period_start?: string
+  period_end?: string
+  maps: PlayerMapStats[]
+}
+
+// hours is [weekday][hour], Sunday first: average players online.
+export interface ActivityHeatmap {
+  server_id?: number
+  server_key?: string
+  source?: string
+  hours: number[][]
+  peak?: { weekday: number; hour: number; players: number }
+}
+
+export interface ActivityResponse {
+  period: TimePeriod
+  period_start?: string
+  period_end?: string
+  timezone: string
+  humans_only: boolean
+  overall: ActivityHeatmap
+  servers: ActivityHeatmap[]
+}
+
+export interface SessionStatsResponse {
+  period: TimePeriod
+  period_start?: string
+  period_end?: string
+  sessions: number
+  unique_players: number
+  total_seconds: number
+  average_seconds: number
+  daily: { day: string; players: number; sessions: number }[]
+  retention_cohort: number
+  retention_returned: number
+  retention_rate?: number
+}
+
+export interface BotStats {
+  player_id: number
+  name: string
+  clean_name: string
+  matches: number
+  victories: number
+  win_rate: number
+  avg_frags: number
+  kd_ratio: number
+  skill: number
+}
+
+export interface BotSkillStats {
+  skill: number
+  matches: number
+  human_matches: number
+  human_victories: number
+  human_win_rate: number
+  human_avg_frags: number
+  human_kd_ratio: number
+}
+
+export interface ServerPing {
+  server_id: number
+  server_key: string
+  source: string
+  samples: number
+  avg_ping: number
+  median_ping: number
+}
+
+export interface PlayerPingResponse {
+  player_id: number
+  period: TimePeriod
+  samples: number
+  avg_ping: number
+  median_ping: number
+  servers: ServerPing[]
+  spikes: { sampled_at: string; ping: number; server_id: number; server_key: string }[]
+}
+
+export interface RegionPing {
+  country_code: string
+  country?: string
+  players: number
+  samples: number
+  avg_ping: number
+  median_ping: number
+}
+
+export interface ServerPingStats extends ServerPing {
+  regions: RegionPing[]
+}
+
+export interface ServerPingResponse {
+  period: TimePeriod
+  servers: ServerPingStats[]
+}
+
+export interface PopulationHour {
+  hour: string
+  avg_humans: number
+  peak_humans: number
+  avg_bots: number
+  max_clients: number
+}
+
+export interface PopulationResponse {
+  server_id: number
+  period: TimePeriod
+  hours: PopulationHour[]
+}
+
+export interface BrowserServer {
+  address: string
+  hostname: string
+  clean_name: string
+  map: string
+  game_type: string
+  humans: number
+  bots: number
+  max_clients: number
+  tracked: boolean
+}
+
+export interface BotStatsResponse {
+  period: TimePeriod
+  period_start?: string
+  period_end?: string
+  game_type?: string
+  bots: BotStats[]
+  skills: BotSkillStats[]
+}
+
+export interface Tournament {
+  id: number
+  name: string
+  format: 'single_elimination' | 'round_robin' | 'pickup'
+  game_type: string
+  map_pool: string[]
+  starts_at: string
+  ends_at: string
+  status: 'signup' | 'in_progress' | 'completed'
+  winner_id?: number
+  winning_team?: number
+  created_by?: number
+  created_at: string
+  entrant_count: number
+}
+
+export interface TournamentEntrant {
+  player: PlayerProfile
+  seed: number
+  team?: number
+  signed_up_at: string
+}
+
+export interface TournamentMatch {
+  id: number
+  round: number
+  slot: number
+  map_name?: string
+  player1?: PlayerProfile
+  player2?: PlayerProfile
+  winner_id?: number
+  winning_team?: number
+  match_id?: number
+}
+
+export interface TournamentStanding {
+  player: PlayerProfile
+  wins: number
+  losses: number
+}
+
+export interface TournamentResponse {
+  tournament: Tournament
+  entrants: TournamentEntrant[]
+  matches: TournamentMatch[]
+  standings?: TournamentStanding[]
+}
+
+export interface Season {
+  id: number
+  name: string
+  starts_at: string
+  ends_at: string
+  category: LeaderboardCategory
+  finalized_at?: string
+  winner_id?: number
+  created_by?: number
+  created_at: string
+}
+
+// A badge for a season the player finished first in.
+export interface SeasonWin {
+  season_id: number
+  name: string
+  category: LeaderboardCategory
+  ends_at: string
+}
+
+export interface SeasonResponse {
+  season: Season
+  standings: LeaderboardEntry[]
+}
+
+export interface LeaderboardExclusion {
+  player_id: number
+  name: string
+  clean_name: string
+  reason: string
+}
+
+export interface PlayerMerge {
+  id: number
+  target_player_id: number
+  target_name: string
+  source_player_id: number
+  source_name: string
+  guid_count: number
+  merged_at: string
+  undone_at?: string
+}
+
+export interface MergeSuggestion {
+  id: number
+  player: Player
+  other: Player
+  score: number
+  reasons: ('shared_ip_range' | 'shared_name' | 'alternating_sessions')[]
+  detected_at: string
+}
+
 export interface PlayerStatsResponse {
   player: PlayerProfile
   period: string
@@ -412,6 +1059,23 @@ export interface PlayerStatsResponse {
   period_end?: string
   stats: AggregatedStats
   names: PlayerName[]
+  weapons: WeaponUsage[]
+  signature_weapon?: string
+  accuracy?: WeaponAccuracy[]
+  overall_accuracy?: number
+}
+
+export interface WeaponUsage {
+  weapon: string
+  frags: number
+  share: number
+}
+
+export interface WeaponAccuracy {
+  weapon: string
+  shots: number
+  hits: number
+  accuracy: number
 }
 
 export type TimePeriod = 'all' | 'day' | 'week' | 'month' | 'year'
@@ -429,31 +1093,45 @@ export type LeaderboardCategory =
   | 'humiliations'
   | 'defends'
   | 'victories'
+  | 'sprees'
+  | 'accuracy'
+  | 'skulls'
+  | 'obelisks'
+  | 'playtime'
 
 export interface LeaderboardEntry {
   rank: number
   player: PlayerProfile
   total_frags: number
-  total_deaths: number
+  total_deaths?: number
   total_matches: number
   completed_matches: number
   uncompleted_matches: number
-  kd_ratio: number
-  captures: number
-  flag_returns: number
-  assists: number
-  impressives: number
-  excellents: number
-  humiliations: number
-  defends: number
-  victories: number
+  kd_ratio?: number
+  captures?: number
+  flag_returns?: number
+  assists?: number
+  impressives?: number
+  excellents?: number
+  humiliations?: number
+  defends?: number
+  victories?: number
+  best_spree?: number
+  skulls_scored?: number
+  obelisk_destroys?: number
+  playtime_seconds?: number
+  shots?: number
+  hits?: number
+  accuracy?: number
 }
 
 export interface LeaderboardResponse {
   category: LeaderboardCategory
-  period: TimePeriod
+  weapon?: string
+  period: TimePeriod | 'season'
   period_start?: string
   period_end?: string
+  season?: Season
   entries: LeaderboardEntry[]
 }
 
@@ -1,11 +1,20 @@
 import { useEffect, useRef, useState, useCallback } from 'react'
 import type { WSEvent } from './types'
 
-export function useWebSocket(url: string, onEvent?: (event: WSEvent) => void) {
+// After this many connections in a row fail without ever opening,
+// assume WebSockets are blocked and stream sseUrl instead, or long-poll
+// pollUrl if that can't connect either.
+const FAILURES_BEFORE_FALLBACK = 3
+
+export function useWebSocket(url: string, onEvent?: (event: WSEvent) => void, pollUrl?: string, sseUrl?: string) {
   const [isConnected, setIsConnected] = useState(false)
   const wsRef = useRef<WebSocket | null>(null)
   const reconnectTimeoutRef = useRef<number | null>(null)
   const onEventRef = useRef(onEvent)
+  const failuresRef = useRef(0)
+  const seqRef = useRef<number | null>(null)
+  const pollAbortRef = useRef<AbortController | null>(null)
+  const eventSourceRef = useRef<EventSource | null>(null)
   // Held in a ref so the reconnect setTimeout always invokes the latest
   // closure (e.g. after url changes), not the one captured at construction.
   const connectRef = useRef<() => void>(() => {})
@@ -15,19 +24,92 @@ export function useWebSocket(url: string, onEvent?: (event: WSEvent) => void) {
     onEventRef.current = onEvent
   }, [onEvent])
 
+  const deliver = useCallback((data: WSEvent) => {
+    if (data.seq) seqRef.current = data.seq
+    onEventRef.current?.(data)
+  }, [])
+
+  // poll loops on /api/events/poll, carrying on from the last event the
+  // WebSocket delivered, until the component goes away.
+  const poll = useCallback(async (pollUrl: string) => {
+    const abort = new AbortController()
+    pollAbortRef.current = abort
+    console.log('WebSocket unavailable, falling back to polling')
+    while (!abort.signal.aborted) {
+      try {
+        const since = seqRef.current === null ? '' : `?since_seq=${seqRef.current}`
+        const res = await fetch(pollUrl + since, { signal: abort.signal })
+        if (!res.ok) throw new Error(`poll failed: ${res.status}`)
+        const body = await res.json() as { seq: number; events: WSEvent[] }
+        setIsConnected(true)
+        body.events.forEach(deliver)
+        seqRef.current = body.seq
+      } catch {
+        if (abort.signal.aborted) return
+        setIsConnected(false)
+        await new Promise(resolve => setTimeout(resolve, 3000))
+      }
+    }
+  }, [deliver])
+
+  // stream reads the same events as Server-Sent Events, carrying on
+  // from the last event the WebSocket delivered. EventSource reconnects
+  // on its own and resumes from the last event id; a stream that never
+  // opens means the proxy blocks those too, so it falls back to polling.
+  const stream = useCallback((sseUrl: string) => {
+    console.log('WebSocket unavailable, falling back to Server-Sent Events')
+    const since = seqRef.current === null ? '' : `?since_seq=${seqRef.current}`
+    const es = new EventSource(sseUrl + since)
+    eventSourceRef.current = es
+    let opened = false
+
+    es.onopen = () => {
+      opened = true
+      setIsConnected(true)
+    }
+
+    es.onerror = () => {
+      setIsConnected(false)
+      if (!opened) {
+        es.close()
+        eventSourceRef.current = null
+        if (pollUrl) poll(pollUrl)
+      }
+    }
+
+    es.onmessage = (event) => {
+      try {
+        deliver(JSON.parse(event.data) as WSEvent)
+      } catch (e) {
+        console.error('Failed to parse event:', e)
+      }
+    }
+  }, [pollUrl, poll, deliver])
+
   const connect = useCallback(() => {
     if (wsRef.current?.readyState === WebSocket.OPEN) return
 
     const ws = new WebSocket(url)
     wsRef.current = ws
+    let opened = false
 
     ws.onopen = () => {
+      opened = true
+      failuresRef.current = 0
       setIsConnected(true)
       console.log('WebSocket connected')
     }
 
     ws.onclose = () => {
       setIsConnected(false)
+      if (!opened && (sseUrl || pollUrl) && ++failuresRef.current >= FAILURES_BEFORE_FALLBACK) {
+        if (sseUrl) {
+          stream(sseUrl)
+        } else if (pollUrl) {
+          poll(pollUrl)
+        }
+        return
+      }
       console.log('WebSocket disconnected, reconnecting in 3s...')
       reconnectTimeoutRef.current = window.setTimeout(() => connectRef.current(), 3000)
     }
@@ -43,13 +125,13 @@ export function useWebSocket(url: string, onEvent?: (event: WSEvent) => void) {
         if (!line.trim()) continue
         try {
           const data = JSON.parse(line) as WSEvent
-          onEventRef.current?.(data)
+          deliver(data)
         } catch (e) {
           console.error('Failed to parse WebSocket message:', e)
         }
       }
     }
-  }, [url])
+  }, [url, pollUrl, sseUrl, poll, stream, deliver])
 
   useEffect(() => {
     connectRef.current = connect
@@ -62,7 +144,13 @@ export function useWebSocket(url: string, onEvent?: (event: WSEvent) => void) {
       if (reconnectTimeoutRef.current) {
         clearTimeout(reconnectTimeoutRef.current)
       }
-      wsRef.current?.close()
+      pollAbortRef.current?.abort()
+      eventSourceRef.current?.close()
+      if (wsRef.current) {
+        // Unmounting isn't a failed connection
+        wsRef.current.onclose = null
+        wsRef.current.close()
+      }
     }
   }, [connect])
 
@@ -0,0 +1,29 @@
+// The hub hands OAuth results back in the URL fragment so tokens never
+// reach a server log. takeOAuthFragment returns them, clearing the hash,
+// if the fragment has any of keys.
+export function takeOAuthFragment(...keys: string[]): URLSearchParams | null {
+  const params = new URLSearchParams(window.location.hash.replace(/^#/, ''))
+  if (!keys.some(k => params.has(k))) return null
+  window.history.replaceState(null, '', window.location.pathname + window.location.search)
+  return params
+}
+
+// startOAuth asks the hub for the provider's sign-in URL and sends the
+// browser there. It resolves to an error message if it couldn't.
+export async function startOAuth(path: string, token?: string | null, body?: object): Promise<string> {
+  try {
+    const headers: Record<string, string> = { 'Content-Type': 'application/json' }
+    if (token) headers.Authorization = `Bearer ${token}`
+    const res = await fetch(path, {
+      method: 'POST',
+      headers,
+      body: body ? JSON.stringify(body) : undefined,
+    })
+    const data = await res.json()
+    if (!res.ok) return data.error || 'Sign-in failed'
+    window.location.href = data.url
+    return ''
+  } catch {
+    return 'Network error'
+  }
+}